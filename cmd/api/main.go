@@ -4,7 +4,11 @@ import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/handler"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/notify"
 	"apidian-go/internal/middleware"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/crypto"
 	"log"
 	"time"
 
@@ -29,6 +33,18 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Configurar proveedor de llaves maestras (cifrado de passwords y llaves de datos)
+	keyProvider, err := crypto.NewKeyProvider(cfg.Encryption.KeyProvider, cfg.Encryption.KeysFile, cfg.Encryption.KMSURL, cfg.Encryption.KMSToken)
+	if err != nil {
+		log.Fatalf("Failed to configure encryption keys: %v", err)
+	}
+	crypto.SetKeyProvider(keyProvider)
+	activeKeyID, err := crypto.ActiveKeyID()
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	log.Printf("✓ Encryption keys loaded (provider: %s, active key: %s)", cfg.Encryption.KeyProvider, activeKeyID)
+
 	// Conectar a base de datos
 	db, err := database.NewPostgresConnection(&cfg.Database)
 	if err != nil {
@@ -38,18 +54,55 @@ func main() {
 
 	log.Println("✓ Database connected successfully")
 
+	// Activación de certificados programados y alertas de vencimiento (30, 15 y 7 días, y vencido)
+	if cfg.Alerts.CertificateCheckHours > 0 {
+		expiryService := service.NewCertificateExpiryService(
+			repository.NewCertificateRepository(db),
+			repository.NewCompanyRepository(db),
+			notify.New(&cfg.Alerts),
+		)
+		go expiryService.Run(time.Duration(cfg.Alerts.CertificateCheckHours) * time.Hour)
+		log.Printf("✓ Certificate expiry check every %dh", cfg.Alerts.CertificateCheckHours)
+	}
+
+	// Cambio a la resolución de continuación y alertas de agotamiento/vencimiento de resoluciones
+	if cfg.Alerts.ResolutionCheckHours > 0 {
+		monitorService := service.NewResolutionMonitorService(
+			repository.NewResolutionRepository(db),
+			repository.NewCompanyRepository(db),
+			notify.New(&cfg.Alerts),
+			cfg.Alerts.ResolutionRemaining,
+			cfg.Alerts.ResolutionDays,
+		)
+		go monitorService.Run(time.Duration(cfg.Alerts.ResolutionCheckHours) * time.Hour)
+		log.Printf("✓ Resolution check every %dh", cfg.Alerts.ResolutionCheckHours)
+	}
+
+	// Trabajos de importación interrumpidos por un reinicio quedan como failed, no running para siempre
+	go service.NewImportJobMonitor(repository.NewInvoiceImportRepository(db)).Run(time.Minute)
+
 	// Crear aplicación Fiber
-	app := fiber.New(fiber.Config{
+	fiberConfig := fiber.Config{
 		AppName:      "APIDIAN API v0.1.0",
 		ErrorHandler: customErrorHandler,
-	})
+	}
+	// Detrás de un balanceador, la IP del cliente (límites de login, sesiones) viene en X-Forwarded-For
+	if len(cfg.Server.TrustedProxies) > 0 {
+		fiberConfig.ProxyHeader = fiber.HeaderXForwardedFor
+		fiberConfig.EnableTrustedProxyCheck = true
+		fiberConfig.TrustedProxies = cfg.Server.TrustedProxies
+	}
+	app := fiber.New(fiberConfig)
+
+	// Límites de peticiones compartidos entre réplicas (contadores en Postgres)
+	rateLimiter := middleware.NewRateLimiter(db, &cfg.RateLimit)
+	go rateLimiter.RunCleanup(5 * time.Minute)
 
 	// Middleware globales
 	app.Use(recover.New())
 	app.Use(middleware.Logger())
 	app.Use(middleware.SecurityHeaders())
 	app.Use(middleware.CORS(cfg.Server.AllowOrigins))
-	app.Use(middleware.RateLimiter(cfg.Server.Env))
 	app.Use(middleware.ErrorHandler())
 
 	// Rutas de sistema (health, metrics, etc.)
@@ -58,12 +111,16 @@ func main() {
 	// Rutas API
 	api := app.Group("/api/v1")
 
-	// Rutas públicas
-	handler.SetupPublicRoutes(api, db, cfg)
+	// Rutas públicas (límite por IP)
+	handler.SetupPublicRoutes(api, db, cfg, rateLimiter.ByIP())
 
-	// Rutas protegidas (requieren autenticación)
-	protected := api.Group("", middleware.AuthMiddleware(&cfg.JWT, db))
-	handler.SetupProtectedRoutes(protected, db, cfg)
+	// Rutas protegidas (requieren autenticación); por IP solo se limitan las no autenticadas
+	protected := api.Group("",
+		rateLimiter.ByIPUntilAuthenticated(),
+		middleware.AuthMiddleware(&cfg.JWT, db),
+		rateLimiter.ByTenant(),
+	)
+	handler.SetupProtectedRoutes(protected, db, cfg, rateLimiter)
 
 	// Iniciar servidor
 	port := ":" + cfg.Server.Port
@@ -0,0 +1,52 @@
+// Comando platform-admin: otorga o retira el rol de administrador de la plataforma a un
+// usuario existente. Es la forma de crear el primer administrador; los siguientes se pueden
+// administrar con PUT /api/v1/admin/users/:id.
+//
+//	go run ./cmd/platform-admin --email ops@example.com
+//	go run ./cmd/platform-admin --email ops@example.com --revoke
+package main
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"flag"
+	"log"
+)
+
+func main() {
+	email := flag.String("email", "", "email del usuario")
+	revoke := flag.Bool("revoke", false, "retira el rol en lugar de otorgarlo")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("--email is required")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.NewPostgresConnection(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	userRepo := repository.NewUserRepository(db)
+	user, err := userRepo.GetByEmail(*email)
+	if err != nil {
+		log.Fatalf("❌ %s: %v", *email, err)
+	}
+
+	if err := userRepo.SetPlatformAdmin(user.ID, !*revoke); err != nil {
+		log.Fatalf("❌ Failed to update user %d: %v", user.ID, err)
+	}
+
+	if *revoke {
+		log.Printf("✓ %s (user %d) is no longer a platform admin", user.Email, user.ID)
+		return
+	}
+	log.Printf("✓ %s (user %d) is now a platform admin", user.Email, user.ID)
+}
@@ -0,0 +1,68 @@
+// Comando rekey: re-cifra certificates.password, las llaves de datos de empresa y los secretos
+// TOTP de 2FA (users.totp_secret) con la llave maestra activa. Uso típico al rotar ENCRYPTION_KEY:
+//
+//  1. Agregar la llave nueva y conservar la anterior: ENCRYPTION_KEYS=2026a:<hex>,default:<hex>
+//  2. Activar la nueva: ENCRYPTION_KEY_ID=2026a (y reiniciar la API)
+//  3. go run ./cmd/rekey --dry-run && go run ./cmd/rekey
+//  4. Retirar la llave anterior cuando el reporte no muestre errores
+package main
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/crypto"
+	"flag"
+	"log"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "solo reporta lo que se re-cifraría, sin escribir")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	keyProvider, err := crypto.NewKeyProvider(cfg.Encryption.KeyProvider, cfg.Encryption.KeysFile, cfg.Encryption.KMSURL, cfg.Encryption.KMSToken)
+	if err != nil {
+		log.Fatalf("Failed to configure encryption keys: %v", err)
+	}
+	crypto.SetKeyProvider(keyProvider)
+
+	db, err := database.NewPostgresConnection(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	rotation := service.NewKeyRotationService(
+		repository.NewCertificateRepository(db),
+		repository.NewDataKeyRepository(db),
+		repository.NewTwoFactorRepository(db),
+	)
+
+	report, err := rotation.Reencrypt(*dryRun)
+	if err != nil {
+		log.Fatalf("❌ Re-encryption failed: %v", err)
+	}
+
+	mode := "applied"
+	if report.DryRun {
+		mode = "dry-run"
+	}
+	log.Printf("Active key: %s (%s)", report.ActiveKeyID, mode)
+	log.Printf("Certificates: %d/%d re-encrypted", report.CertificatesReencrypted, report.CertificatesTotal)
+	log.Printf("Company data keys: %d/%d re-wrapped", report.DataKeysRewrapped, report.DataKeysTotal)
+	log.Printf("Two-factor secrets: %d/%d re-encrypted", report.TwoFactorSecretsReencrypted, report.TwoFactorSecretsTotal)
+
+	for _, e := range report.Errors {
+		log.Printf("❌ %s", e)
+	}
+	if len(report.Errors) > 0 {
+		log.Fatalf("❌ %d value(s) could not be re-encrypted; keep the old keys configured", len(report.Errors))
+	}
+	log.Println("✓ Re-encryption completed")
+}
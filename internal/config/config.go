@@ -1,27 +1,37 @@
 package config
 
 import (
+	"apidian-go/pkg/crypto"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Storage  StorageConfig
-	Invoice  InvoiceConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Auth       AuthConfig
+	RateLimit  RateLimitConfig
+	Portal     PortalConfig
+	Mail       MailConfig
+	Storage    StorageConfig
+	Invoice    InvoiceConfig
+	Encryption EncryptionConfig
+	Alerts     AlertsConfig
 }
 
 type ServerConfig struct {
-	Port         string
-	Env          string
-	AllowOrigins string
-	Timezone     string
+	Port           string
+	Env            string
+	AllowOrigins   string
+	Timezone       string
+	TrustedProxies []string // Balanceadores cuya cabecera X-Forwarded-For se usa como IP del cliente
 }
 
 type DatabaseConfig struct {
@@ -34,16 +44,104 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret     string
-	Expiration int
+	Secret             string
+	AccessTokenMinutes int // Vigencia del access token (JWT)
+	RefreshTokenDays   int // Vigencia del refresh token; se extiende en cada rotación
 }
 
+// AuthConfig configura la verificación de email, el restablecimiento de contraseña y la
+// protección del login contra fuerza bruta
+type AuthConfig struct {
+	UnverifiedLogin      string // allow | limited | block: login de cuentas con email sin verificar
+	UnverifiedGraceHours int    // Con limited, horas desde el registro en que se permite el login sin verificar
+	VerifyTokenHours     int    // Vigencia del enlace de verificación de email
+	ResetTokenMinutes    int    // Vigencia del enlace de restablecimiento de contraseña
+	LoginMaxFailures     int    // Fallos seguidos de una cuenta antes de bloquearla (0 desactiva el bloqueo)
+	LoginIPMaxFailures   int    // Fallos desde una IP antes de bloquearla (0 desactiva el bloqueo)
+	LoginLockMinutes     int    // Duración del bloqueo de una cuenta o IP
+}
+
+const (
+	UnverifiedLoginAllow   = "allow"
+	UnverifiedLoginLimited = "limited"
+	UnverifiedLoginBlock   = "block"
+)
+
+// MailConfig configura el envío de correos (verificación de email, restablecimiento de contraseña)
+type MailConfig struct {
+	Driver   string // log | smtp
+	Host     string
+	Port     int
+	Username string // Opcional; sin usuario no se autentica (MailHog, Mailpit)
+	Password string
+	From     string
+	AppURL   string // URL base de los enlaces enviados por correo
+}
+
+const (
+	MailDriverLog  = "log"
+	MailDriverSMTP = "smtp"
+)
+
 type StorageConfig struct {
-	Path string // Ruta base de storage (./storage)
+	Path   string // Ruta base de storage (./storage)
+	Driver string // local | s3
+	S3     S3Config
+}
+
+// S3Config configura un backend compatible con S3 (AWS, MinIO, etc.)
+type S3Config struct {
+	Endpoint        string // https://s3.us-east-1.amazonaws.com | http://localhost:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Prefix          string // Prefijo opcional dentro del bucket
+	ForcePathStyle  bool   // true para MinIO y la mayoría de compatibles
+}
+
+// RateLimitConfig configura los límites de peticiones por minuto que no dependen del plan de la
+// empresa (los de las API keys vienen del plan y de cada key)
+type RateLimitConfig struct {
+	IPPerMinute   int  // Peticiones a rutas públicas o sin credencial válida por IP (0 desactiva)
+	UserPerMinute int  // Peticiones con JWT por usuario (0 desactiva)
+	FailOpen      bool // Si los contadores fallan (Postgres) la petición pasa sin límite; false responde 503
+}
+
+// PortalConfig configura los enlaces firmados con los que el comprador ve y descarga sus documentos
+type PortalConfig struct {
+	LinkSecret string // Secreto HMAC de los enlaces (derivado de JWT_SECRET si falta); cambiarlo invalida los enlaces emitidos
+	LinkHours  int    // Vigencia por defecto de los enlaces
+	BaseURL    string // URL pública de la API para armar los enlaces (requerida fuera de development)
 }
 
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+)
+
 type InvoiceConfig struct {
 	KeepUnsignedXML bool
+	XSDPath         string // Raíz de los XSD de UBL 2.1 para la prevalidación; vacío = solo reglas DIAN
+}
+
+// EncryptionConfig selecciona el proveedor de llaves maestras (env | file | kms).
+// Las llaves en sí (ENCRYPTION_KEY, ENCRYPTION_KEYS, ENCRYPTION_KEY_ID) las lee pkg/crypto.
+type EncryptionConfig struct {
+	KeyProvider string
+	KeysFile    string
+	KMSURL      string
+	KMSToken    string
+}
+
+// AlertsConfig configura las alertas operativas (vencimiento de certificados, resoluciones, etc.)
+type AlertsConfig struct {
+	WebhookURL            string // Opcional; si está vacío las alertas solo van al log
+	WebhookSecret         string // Secreto HMAC para X-Apidian-Signature
+	CertificateCheckHours int    // Intervalo de revisión de vencimientos; 0 desactiva
+	ResolutionCheckHours  int    // Intervalo de revisión de resoluciones (alertas y cambio a la continuación); 0 desactiva
+	ResolutionRemaining   int    // Consecutivos restantes que disparan la alerta (por defecto de cada resolución)
+	ResolutionDays        int    // Días antes de date_to que disparan la alerta (por defecto de cada resolución)
 }
 
 func Load() (*Config, error) {
@@ -56,17 +154,140 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid DB_PORT: %w", err)
 	}
 
-	jwtExpiration, err := strconv.Atoi(getEnv("JWT_EXPIRATION", "24"))
+	jwtAccessMinutes, err := strconv.Atoi(getEnv("JWT_ACCESS_MINUTES", "15"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_ACCESS_MINUTES: %w", err)
+	}
+	jwtRefreshDays, err := strconv.Atoi(getEnv("JWT_REFRESH_DAYS", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_REFRESH_DAYS: %w", err)
+	}
+
+	unverifiedGraceHours, err := strconv.Atoi(getEnv("UNVERIFIED_GRACE_HOURS", "72"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid UNVERIFIED_GRACE_HOURS: %w", err)
+	}
+	verifyTokenHours, err := strconv.Atoi(getEnv("VERIFY_TOKEN_HOURS", "48"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VERIFY_TOKEN_HOURS: %w", err)
+	}
+	resetTokenMinutes, err := strconv.Atoi(getEnv("RESET_TOKEN_MINUTES", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RESET_TOKEN_MINUTES: %w", err)
+	}
+	loginMaxFailures, err := strconv.Atoi(getEnv("LOGIN_MAX_FAILURES", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_MAX_FAILURES: %w", err)
+	}
+	loginIPMaxFailures, err := strconv.Atoi(getEnv("LOGIN_IP_MAX_FAILURES", "50"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_IP_MAX_FAILURES: %w", err)
+	}
+	loginLockMinutes, err := strconv.Atoi(getEnv("LOGIN_LOCK_MINUTES", "15"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_LOCK_MINUTES: %w", err)
+	}
+	auth := AuthConfig{
+		UnverifiedLogin:      getEnv("UNVERIFIED_LOGIN", UnverifiedLoginAllow),
+		UnverifiedGraceHours: unverifiedGraceHours,
+		VerifyTokenHours:     verifyTokenHours,
+		ResetTokenMinutes:    resetTokenMinutes,
+		LoginMaxFailures:     loginMaxFailures,
+		LoginIPMaxFailures:   loginIPMaxFailures,
+		LoginLockMinutes:     loginLockMinutes,
+	}
+	if err := auth.validate(); err != nil {
+		return nil, err
+	}
+
+	// Más permisivo en desarrollo
+	defaultIPLimit := "100"
+	if getEnv("APP_ENV", "development") == "development" {
+		defaultIPLimit = "1000"
+	}
+	ipPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_IP_PER_MINUTE", defaultIPLimit))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_IP_PER_MINUTE: %w", err)
+	}
+	userPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_USER_PER_MINUTE", "600"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_USER_PER_MINUTE: %w", err)
+	}
+	if ipPerMinute < 0 || userPerMinute < 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_IP_PER_MINUTE and RATE_LIMIT_USER_PER_MINUTE must not be negative")
+	}
+
+	portalLinkHours, err := strconv.Atoi(getEnv("PORTAL_LINK_HOURS", "720"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PORTAL_LINK_HOURS: %w", err)
+	}
+	if portalLinkHours <= 0 {
+		return nil, fmt.Errorf("PORTAL_LINK_HOURS must be greater than 0")
+	}
+	portalBaseURL, err := portalBaseURL(getEnv("APP_ENV", "development"), getEnv("SERVER_PORT", "3000"))
+	if err != nil {
+		return nil, err
+	}
+	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
+
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "1025"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+	}
+	mail := MailConfig{
+		Driver:   getEnv("MAIL_DRIVER", MailDriverLog),
+		Host:     getEnv("SMTP_HOST", "localhost"),
+		Port:     smtpPort,
+		Username: getEnv("SMTP_USERNAME", ""),
+		Password: getEnv("SMTP_PASSWORD", ""),
+		From:     getEnv("MAIL_FROM", "APIDIAN <no-reply@localhost>"),
+		AppURL:   strings.TrimRight(getEnv("APP_URL", "http://localhost:3000"), "/"),
+	}
+	if err := mail.validate(); err != nil {
+		return nil, err
+	}
+
+	certCheckHours, err := strconv.Atoi(getEnv("CERT_EXPIRY_CHECK_HOURS", "12"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CERT_EXPIRY_CHECK_HOURS: %w", err)
+	}
+	resolutionCheckHours, err := strconv.Atoi(getEnv("RESOLUTION_CHECK_HOURS", "6"))
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT_EXPIRATION: %w", err)
+		return nil, fmt.Errorf("invalid RESOLUTION_CHECK_HOURS: %w", err)
+	}
+	resolutionRemaining, err := strconv.Atoi(getEnv("RESOLUTION_ALERT_REMAINING", "100"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RESOLUTION_ALERT_REMAINING: %w", err)
+	}
+	resolutionDays, err := strconv.Atoi(getEnv("RESOLUTION_ALERT_DAYS", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RESOLUTION_ALERT_DAYS: %w", err)
+	}
+
+	storage := StorageConfig{
+		Path:   getEnv("STORAGE_PATH", "./storage"),
+		Driver: getEnv("STORAGE_DRIVER", StorageDriverLocal),
+		S3: S3Config{
+			Endpoint:        getEnv("S3_ENDPOINT", ""),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          getEnv("S3_BUCKET", ""),
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			Prefix:          getEnv("S3_PREFIX", ""),
+			ForcePathStyle:  getEnvBool("S3_FORCE_PATH_STYLE", true),
+		},
+	}
+	if err := storage.validate(); err != nil {
+		return nil, err
 	}
 
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "3000"),
-			Env:          getEnv("APP_ENV", "development"),
-			AllowOrigins: getEnv("CORS_ALLOW_ORIGINS", "*"),
-			Timezone:     getEnv("TZ", "America/Bogota"),
+			Port:           getEnv("SERVER_PORT", "3000"),
+			Env:            getEnv("APP_ENV", "development"),
+			AllowOrigins:   getEnv("CORS_ALLOW_ORIGINS", "*"),
+			Timezone:       getEnv("TZ", "America/Bogota"),
+			TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -77,18 +298,94 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-secret-key"),
-			Expiration: jwtExpiration,
+			Secret:             jwtSecret,
+			AccessTokenMinutes: jwtAccessMinutes,
+			RefreshTokenDays:   jwtRefreshDays,
 		},
-		Storage: StorageConfig{
-			Path: getEnv("STORAGE_PATH", "./storage"),
+		Auth: auth,
+		RateLimit: RateLimitConfig{
+			IPPerMinute:   ipPerMinute,
+			UserPerMinute: userPerMinute,
+			FailOpen:      getEnvBool("RATE_LIMIT_FAIL_OPEN", true),
 		},
+		Portal: PortalConfig{
+			LinkSecret: portalLinkSecret(jwtSecret),
+			LinkHours:  portalLinkHours,
+			BaseURL:    portalBaseURL,
+		},
+		Mail:    mail,
+		Storage: storage,
 		Invoice: InvoiceConfig{
 			KeepUnsignedXML: getEnvBool("KEEP_UNSIGNED_XML", false),
+			XSDPath:         getEnv("UBL_XSD_PATH", ""),
+		},
+		Encryption: EncryptionConfig{
+			KeyProvider: getEnv("ENCRYPTION_KEY_PROVIDER", "env"),
+			KeysFile:    getEnv("ENCRYPTION_KEYS_FILE", ""),
+			KMSURL:      getEnv("KMS_URL", ""),
+			KMSToken:    getEnv("KMS_TOKEN", ""),
+		},
+		Alerts: AlertsConfig{
+			WebhookURL:            getEnv("ALERT_WEBHOOK_URL", ""),
+			WebhookSecret:         getEnv("ALERT_WEBHOOK_SECRET", ""),
+			CertificateCheckHours: certCheckHours,
+			ResolutionCheckHours:  resolutionCheckHours,
+			ResolutionRemaining:   resolutionRemaining,
+			ResolutionDays:        resolutionDays,
 		},
 	}, nil
 }
 
+func (a AuthConfig) validate() error {
+	switch a.UnverifiedLogin {
+	case UnverifiedLoginAllow, UnverifiedLoginLimited, UnverifiedLoginBlock:
+	default:
+		return fmt.Errorf("invalid UNVERIFIED_LOGIN: %q (use allow, limited or block)", a.UnverifiedLogin)
+	}
+	if a.VerifyTokenHours <= 0 || a.ResetTokenMinutes <= 0 {
+		return fmt.Errorf("VERIFY_TOKEN_HOURS and RESET_TOKEN_MINUTES must be greater than 0")
+	}
+	if a.LoginMaxFailures < 0 || a.LoginIPMaxFailures < 0 {
+		return fmt.Errorf("LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must not be negative")
+	}
+	if a.LoginLockMinutes <= 0 {
+		return fmt.Errorf("LOGIN_LOCK_MINUTES must be greater than 0")
+	}
+	return nil
+}
+
+func (m MailConfig) validate() error {
+	switch m.Driver {
+	case MailDriverLog:
+		return nil
+	case MailDriverSMTP:
+		if m.Host == "" || m.From == "" {
+			return fmt.Errorf("MAIL_DRIVER=smtp requires SMTP_HOST and MAIL_FROM")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid MAIL_DRIVER: %q (use log or smtp)", m.Driver)
+	}
+}
+
+func (s StorageConfig) validate() error {
+	switch s.Driver {
+	case StorageDriverLocal:
+		return nil
+	case StorageDriverS3:
+		if s.S3.Endpoint == "" || s.S3.Bucket == "" || s.S3.AccessKeyID == "" || s.S3.SecretAccessKey == "" {
+			return fmt.Errorf("STORAGE_DRIVER=s3 requires S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+		}
+		u, err := url.Parse(s.S3.Endpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid S3_ENDPOINT: %q", s.S3.Endpoint)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid STORAGE_DRIVER: %q (use local or s3)", s.Driver)
+	}
+}
+
 // AppRoot retorna la ruta de storage/app donde se guardan todos los archivos
 func (s StorageConfig) AppRoot() string {
 	return filepath.Join(s.Path, "app")
@@ -109,72 +406,6 @@ func (s StorageConfig) TempUploadsPath() string {
 	return filepath.Join(s.TempPath(), "uploads")
 }
 
-// === Compañía ===
-
-// CompanyPath retorna la ruta de una empresa específica
-func (s StorageConfig) CompanyPath(nit string) string {
-	return filepath.Join(s.AppRoot(), "companies", nit)
-}
-
-// CompanyProfilePath retorna la ruta del perfil de una empresa
-func (s StorageConfig) CompanyProfilePath(nit string) string {
-	return filepath.Join(s.CompanyPath(nit), "profile")
-}
-
-// CompanyLogoPath retorna la ruta del logo de una empresa
-func (s StorageConfig) CompanyLogoPath(nit string) string {
-	return filepath.Join(s.CompanyProfilePath(nit), "logo.png")
-}
-
-// === Certificados ===
-
-// CertificatesPath retorna la ruta de certificados de una empresa
-func (s StorageConfig) CertificatesPath(nit string) string {
-	return filepath.Join(s.CompanyPath(nit), "certificates")
-}
-
-// CertificatePath retorna la ruta de un certificado específico
-func (s StorageConfig) CertificatePath(nit, filename string) string {
-	return filepath.Join(s.CertificatesPath(nit), filename)
-}
-
-// === Documentos ===
-
-// DocumentsPath retorna la ruta de documentos de una empresa
-func (s StorageConfig) DocumentsPath(nit string) string {
-	return filepath.Join(s.CompanyPath(nit), "documents")
-}
-
-// InvoicesPath retorna la ruta de facturas de una empresa
-func (s StorageConfig) InvoicesPath(nit string) string {
-	return filepath.Join(s.DocumentsPath(nit), "invoices")
-}
-
-// InvoicePath retorna la ruta de una factura específica
-func (s StorageConfig) InvoicePath(nit, numero string) string {
-	return filepath.Join(s.InvoicesPath(nit), numero)
-}
-
-// InvoiceXMLPath retorna la ruta del XML sin firmar de una factura
-func (s StorageConfig) InvoiceXMLPath(nit, numero string) string {
-	return filepath.Join(s.InvoicePath(nit, numero), numero+".xml")
-}
-
-// InvoiceSignedXMLPath retorna la ruta del XML firmado de una factura
-func (s StorageConfig) InvoiceSignedXMLPath(nit, numero string) string {
-	return filepath.Join(s.InvoicePath(nit, numero), numero+"_signed.xml")
-}
-
-// InvoiceZIPPath retorna la ruta del ZIP de una factura
-func (s StorageConfig) InvoiceZIPPath(nit, numero string) string {
-	return filepath.Join(s.InvoicePath(nit, numero), numero+".zip")
-}
-
-// InvoiceApplicationResponsePath retorna la ruta del ApplicationResponse de una factura
-func (s StorageConfig) InvoiceApplicationResponsePath(nit, numero string) string {
-	return filepath.Join(s.InvoicePath(nit, numero), "ApplicationResponse-"+numero+".xml")
-}
-
 // === Logs ===
 
 // DebugSoapPath retorna la ruta de debug SOAP
@@ -182,23 +413,50 @@ func (s StorageConfig) DebugSoapPath() string {
 	return filepath.Join(s.LogsRoot(), "debug", "soap")
 }
 
-// === Assets (privados) ===
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
 
-// AssetsPath retorna la ruta de assets de la aplicación (logo por defecto, etc)
-func (s StorageConfig) AssetsPath() string {
-	return filepath.Join(s.AppRoot(), "assets")
+// portalLinkSecret retorna PORTAL_LINK_SECRET o, si está vacío, una clave derivada del secreto JWT
+// (HMAC con la etiqueta "portal-link"): los enlaces del portal nunca se firman con la misma clave
+// que los tokens de sesión
+func portalLinkSecret(jwtSecret string) string {
+	if secret := getEnv("PORTAL_LINK_SECRET", ""); secret != "" {
+		return secret
+	}
+	return crypto.SignHMAC(jwtSecret, "portal-link")
 }
 
-// DefaultLogoPath retorna la ruta del logo por defecto
-func (s StorageConfig) DefaultLogoPath() string {
-	return filepath.Join(s.AssetsPath(), "logo_default.png")
+// portalBaseURL retorna PORTAL_BASE_URL, la URL pública de la API con la que se arman los
+// enlaces del portal. No se toma de la petición: el Host lo controla el cliente, y un enlace
+// enviado por correo apuntaría a donde él quiera. Solo en development vale localhost por defecto.
+func portalBaseURL(env, serverPort string) (string, error) {
+	value := strings.TrimRight(getEnv("PORTAL_BASE_URL", ""), "/")
+	if value == "" {
+		if env != "development" {
+			return "", fmt.Errorf("PORTAL_BASE_URL is required outside development")
+		}
+		return "http://localhost:" + serverPort, nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("PORTAL_BASE_URL must be an absolute http(s) URL")
+	}
+	return value, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// splitList separa una lista por comas ignorando espacios y elementos vacíos
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return defaultValue
+	return items
 }
 
 func getEnvBool(key string, defaultValue bool) bool {
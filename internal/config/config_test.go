@@ -0,0 +1,51 @@
+package config
+
+import "testing"
+
+func TestPortalLinkSecret(t *testing.T) {
+	t.Run("explicit secret", func(t *testing.T) {
+		t.Setenv("PORTAL_LINK_SECRET", "portal-secret")
+		if got := portalLinkSecret("jwt-secret"); got != "portal-secret" {
+			t.Errorf("portalLinkSecret = %q, want portal-secret", got)
+		}
+	})
+
+	t.Run("derived from the JWT secret", func(t *testing.T) {
+		t.Setenv("PORTAL_LINK_SECRET", "")
+		// HMAC-SHA256("your-secret-key", "portal-link")
+		const want = "456da72ea8fa474e5ab5ad1e070685fe403983bb0b8e47274fc9028f8e95dafa"
+		got := portalLinkSecret("your-secret-key")
+		if got != want {
+			t.Errorf("portalLinkSecret = %q, want %q", got, want)
+		}
+		if got == "your-secret-key" {
+			t.Error("portal links must not be signed with the JWT secret")
+		}
+	})
+}
+
+func TestPortalBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "configured", env: "production", value: "https://api.example.com/", want: "https://api.example.com"},
+		{name: "required outside development", env: "production", wantErr: true},
+		{name: "localhost in development", env: "development", want: "http://localhost:3000"},
+		{name: "relative URL", env: "production", value: "api.example.com", wantErr: true},
+		{name: "unsupported scheme", env: "development", value: "ftp://api.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORTAL_BASE_URL", tt.value)
+			got, err := portalBaseURL(tt.env, "3000")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("portalBaseURL = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
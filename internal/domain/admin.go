@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Acciones registradas en la bitácora de administración
+const (
+	AdminActionUserUpdate          = "user.update"
+	AdminActionUserDelete          = "user.delete"
+	AdminActionUserImpersonate     = "user.impersonate"
+	AdminActionImpersonatedRequest = "user.impersonated_request" // Petición de escritura hecha suplantando
+	AdminActionCompanySuspend      = "company.suspend"
+	AdminActionCompanyUnsuspend    = "company.unsuspend"
+	AdminActionCompanyPlan         = "company.plan"
+)
+
+// Tipos de objetivo de una acción de administración
+const (
+	AdminTargetUser    = "user"
+	AdminTargetCompany = "company"
+)
+
+// ImpersonationMinutes es la duración máxima de una sesión de suplantación (sin renovación)
+const ImpersonationMinutes = 30
+
+// AdminAction es una entrada de la bitácora de administración
+type AdminAction struct {
+	ID         int64           `json:"id"`
+	AdminID    int64           `json:"admin_id"`
+	AdminEmail string          `json:"admin_email,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int64           `json:"target_id"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	IPAddress  *string         `json:"ip_address,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AdminActionFilter filtra la bitácora de administración
+type AdminActionFilter struct {
+	AdminID    int64
+	Action     string
+	TargetType string
+	TargetID   int64
+}
+
+// AdminActionListResponse representa la respuesta paginada de la bitácora
+type AdminActionListResponse struct {
+	Actions  []AdminAction `json:"actions"`
+	Total    int           `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// CompanyUsage resume el uso de la plataforma por una empresa
+type CompanyUsage struct {
+	CompanyID          int64      `json:"company_id"`
+	NIT                string     `json:"nit"`
+	Name               string     `json:"name"`
+	OwnerID            int64      `json:"owner_id"`
+	OwnerEmail         string     `json:"owner_email"`
+	SuspendedAt        *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason    *string    `json:"suspended_reason,omitempty"`
+	Members            int        `json:"members"`
+	ActiveAPIKeys      int        `json:"active_api_keys"`
+	Documents          int        `json:"documents"`
+	DocumentsThisMonth int        `json:"documents_this_month"`
+	DocumentsAccepted  int        `json:"documents_accepted"` // Aceptados por DIAN
+	DocumentsRejected  int        `json:"documents_rejected"`
+	LastDocumentAt     *time.Time `json:"last_document_at,omitempty"`
+	Plan               string     `json:"plan"`
+	DocumentQuota      *int       `json:"document_quota"`      // Documentos por mes del plan; nil = sin límite
+	DocumentQuotaUsed  int        `json:"document_quota_used"` // Documentos creados este mes, incluidos los borradores eliminados
+	APICallsPerMinute  *int       `json:"api_calls_per_minute"`
+	APICallsThisMonth  int64      `json:"api_calls_this_month"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// CompanyUsageListResponse representa la respuesta paginada del uso por empresa
+type CompanyUsageListResponse struct {
+	Companies []CompanyUsage `json:"companies"`
+	Total     int            `json:"total"`
+	Page      int            `json:"page"`
+	PageSize  int            `json:"page_size"`
+}
+
+// SuspendCompanyRequest representa la solicitud para suspender una empresa
+type SuspendCompanyRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// ImpersonateRequest representa la solicitud para suplantar a un usuario; el motivo queda en
+// la bitácora
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
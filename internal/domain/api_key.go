@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+// APIKeyPrefix identifica las API keys en el header Authorization (Bearer adk_...)
+const APIKeyPrefix = "adk_"
+
+// APIKeyPermissions lista los permisos que se pueden otorgar a una API key
+var APIKeyPermissions = []string{
+	PermissionCustomersRead,
+	PermissionCustomersWrite,
+	PermissionProductsRead,
+	PermissionProductsWrite,
+	PermissionInvoicesRead,
+	PermissionInvoicesWrite,
+	PermissionQuotationsRead,
+	PermissionQuotationsWrite,
+	PermissionPaymentsRead,
+	PermissionPaymentsWrite,
+	PermissionReportsRead,
+	PermissionResolutionsRead,
+}
+
+// APIKey es una credencial de una empresa para integraciones (ERP, POS). Actúa en nombre del
+// usuario que la creó, limitada a su empresa y a sus permisos.
+type APIKey struct {
+	ID          int64      `json:"id"`
+	CompanyID   int64      `json:"company_id"`
+	UserID      int64      `json:"user_id"`
+	Name        string     `json:"name"`
+	KeyPrefix   string     `json:"key_prefix"` // Primeros caracteres de la key, para identificarla
+	Permissions []string   `json:"permissions"`
+	RateLimit   *int       `json:"rate_limit_per_minute,omitempty"` // Además del límite del plan para toda la empresa
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  *string    `json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// HasPermission indica si la key tiene el permiso
+func (k *APIKey) HasPermission(permission string) bool {
+	for _, p := range k.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKeyPermission indica si el permiso se puede otorgar a una API key
+func IsAPIKeyPermission(permission string) bool {
+	for _, p := range APIKeyPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyRequest representa la solicitud para crear una API key
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name" validate:"required"`
+	Permissions []string   `json:"permissions" validate:"required"`
+	RateLimit   *int       `json:"rate_limit_per_minute"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse incluye la key en claro; es la única vez que se muestra
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
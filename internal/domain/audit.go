@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditTableAuth agrupa en audit_log los eventos de autenticación, que no corresponden a una tabla
+const AuditTableAuth = "auth"
+
+// Operaciones de autenticación registradas en audit_log
+const (
+	AuditOpAccountLocked   = "LOCK"     // Cuenta bloqueada por logins fallidos
+	AuditOpIPBlocked       = "IP_BLOCK" // IP bloqueada por logins fallidos
+	AuditOpAccountUnlocked = "UNLOCK"   // Cuenta desbloqueada con el enlace del correo
+	AuditOpLoginAfterFails = "LOGIN"    // Login correcto después de varios fallos seguidos
+)
+
+// Operaciones que registran los triggers de auditoría (audit_row_change)
+const (
+	AuditOpInsert = "INSERT"
+	AuditOpUpdate = "UPDATE"
+	AuditOpDelete = "DELETE"
+)
+
+// AuditedTables son las tablas de negocio con trigger de auditoría (migración 129). Los borrados
+// lógicos (is_active = false) quedan como UPDATE.
+var AuditedTables = []string{
+	"companies",
+	"customers",
+	"products",
+	"resolutions",
+	"software",
+	"certificates",
+	"documents",
+}
+
+// IsAuditedTable indica si la tabla tiene trigger de auditoría
+func IsAuditedTable(table string) bool {
+	for _, t := range AuditedTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditEntry es una entrada de audit_log. Las columnas sensibles (contraseña del certificado,
+// PIN del software) no se copian a old_data/new_data.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	TableName string          `json:"table_name"`
+	Operation string          `json:"operation"`
+	RecordID  *int64          `json:"record_id,omitempty"`
+	CompanyID *int64          `json:"company_id,omitempty"`
+	UserID    *int64          `json:"user_id,omitempty"` // NULL si el cambio lo hizo un proceso del sistema
+	UserEmail *string         `json:"user_email,omitempty"`
+	OldData   json.RawMessage `json:"old_data,omitempty"`
+	NewData   json.RawMessage `json:"new_data,omitempty"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// AuditFilter filtra audit_log; los campos vacíos no filtran
+type AuditFilter struct {
+	CompanyID int64
+	TableName string
+	RecordID  int64
+	Operation string
+	UserID    int64
+	From      *time.Time // Incluido
+	To        *time.Time // Excluido
+}
+
+// AuditListResponse representa la respuesta paginada de audit_log
+type AuditListResponse struct {
+	Entries  []AuditEntry `json:"entries"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
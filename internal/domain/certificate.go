@@ -1,16 +1,61 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 // Certificate represents a digital certificate (PKCS12) for signing electronic documents
 type Certificate struct {
-	ID        int64     `json:"id"`
-	CompanyID int64     `json:"company_id"`
-	Name      string    `json:"name"`
-	Password  string    `json:"-"` // Never expose in JSON
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 int64      `json:"id"`
+	CompanyID          int64      `json:"company_id"`
+	Name               string     `json:"name"`
+	Password           string     `json:"-"` // Never expose in JSON
+	IsActive           bool       `json:"is_active"`
+	Status             string     `json:"status"`
+	ActivatesAt        *time.Time `json:"activates_at,omitempty"`
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty"`
+	Subject            *string    `json:"subject,omitempty"`
+	Issuer             *string    `json:"issuer,omitempty"`
+	SerialNumber       *string    `json:"serial_number,omitempty"`
+	SubjectNIT         *string    `json:"subject_nit,omitempty"`
+	NITMatches         *bool      `json:"nit_matches,omitempty"`
+	NotBefore          *time.Time `json:"not_before,omitempty"`
+	NotAfter           *time.Time `json:"not_after,omitempty"`
+	ExpiryNotifiedDays *int       `json:"-"` // Último umbral de alerta de vencimiento enviado
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// Certificate statuses. Only one certificate per company is active at a time; the rest are kept
+// so old signatures can still be verified.
+const (
+	CertificateStatusScheduled  = "scheduled"  // Uploaded in advance, activates at activates_at
+	CertificateStatusActive     = "active"     // Used for signing
+	CertificateStatusSuperseded = "superseded" // Replaced by a newer certificate
+	CertificateStatusDisabled   = "disabled"   // Deleted by the user
+)
+
+// CheckValidity returns an error if the certificate is expired or not yet valid at t.
+// Certificates uploaded before their dates were tracked are accepted.
+func (c *Certificate) CheckValidity(t time.Time) error {
+	if c.NotAfter != nil && t.After(*c.NotAfter) {
+		return fmt.Errorf("certificate expired on %s", c.NotAfter.Format("2006-01-02"))
+	}
+	if c.NotBefore != nil && t.Before(*c.NotBefore) {
+		return fmt.Errorf("certificate not valid until %s", c.NotBefore.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// DaysToExpire returns the whole days left until not_after (negative once expired), or nil if unknown
+func (c *Certificate) DaysToExpire(t time.Time) *int {
+	if c.NotAfter == nil {
+		return nil
+	}
+	days := int(math.Ceil(c.NotAfter.Sub(t).Hours() / 24))
+	return &days
 }
 
 // CreateCertificateRequest represents the request to upload a new certificate
@@ -18,15 +63,61 @@ type CreateCertificateRequest struct {
 	CompanyID   int64  `json:"company_id" validate:"required"`
 	Certificate string `json:"certificate" validate:"required"` // Base64 encoded .p12 file
 	Password    string `json:"password" validate:"required"`
+	// Optional: activate the certificate automatically at this date instead of immediately
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`
 }
 
 // CertificateResponse represents the public certificate information
 type CertificateResponse struct {
-	ID        int64     `json:"id"`
-	CompanyID int64     `json:"company_id"`
-	Name      string    `json:"name"`
-	Path      string    `json:"path"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int64      `json:"id"`
+	CompanyID     int64      `json:"company_id"`
+	Name          string     `json:"name"`
+	Path          string     `json:"path"`
+	IsActive      bool       `json:"is_active"`
+	Status        string     `json:"status"`
+	ActivatesAt   *time.Time `json:"activates_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	Subject       *string    `json:"subject"`
+	Issuer        *string    `json:"issuer"`
+	SerialNumber  *string    `json:"serial_number"`
+	SubjectNIT    *string    `json:"subject_nit"`
+	NITMatches    *bool      `json:"nit_matches"`
+	NotBefore     *time.Time `json:"not_before"`
+	NotAfter      *time.Time `json:"not_after"`
+	DaysToExpire  *int       `json:"days_to_expire"`
+	Expired       bool       `json:"expired"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CertificateExpiryAlert is sent when a certificate crosses an expiry threshold (30, 15, 7 days or expired)
+type CertificateExpiryAlert struct {
+	CertificateID int64     `json:"certificate_id"`
+	CompanyID     int64     `json:"company_id"`
+	CompanyName   string    `json:"company_name"`
+	CompanyNIT    string    `json:"company_nit"`
+	Name          string    `json:"name"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysToExpire  int       `json:"days_to_expire"`
+	Threshold     int       `json:"threshold"`
+}
+
+// CompanyDataKey is the per-company data encryption key, wrapped with the master key
+type CompanyDataKey struct {
+	CompanyID  int64     `json:"company_id"`
+	WrappedKey string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// KeyRotationReport summarizes a re-encryption run under the active master key
+type KeyRotationReport struct {
+	ActiveKeyID                 string   `json:"active_key_id"`
+	DryRun                      bool     `json:"dry_run"`
+	CertificatesTotal           int      `json:"certificates_total"`
+	CertificatesReencrypted     int      `json:"certificates_reencrypted"`
+	DataKeysTotal               int      `json:"data_keys_total"`
+	DataKeysRewrapped           int      `json:"data_keys_rewrapped"`
+	TwoFactorSecretsTotal       int      `json:"two_factor_secrets_total"`
+	TwoFactorSecretsReencrypted int      `json:"two_factor_secrets_reencrypted"`
+	Errors                      []string `json:"errors,omitempty"`
 }
@@ -29,6 +29,10 @@ type Company struct {
 	Website             *string  `json:"website,omitempty"`
 	LogoPath            *string  `json:"logo_path"`
 	IsActive            bool     `json:"is_active"`
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"` // Suspendida por la plataforma: no firma ni envía a DIAN
+	SuspendedReason     *string    `json:"suspended_reason,omitempty"`
+	RequireTwoFactor    bool       `json:"require_two_factor"` // Exige 2FA para firmar, enviar a DIAN y administrar certificados
+	Role                string   `json:"role,omitempty"` // Rol del usuario autenticado en la empresa
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 }
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Eventos RADIAN que el comprador registra sobre una factura (catálogo events)
+const (
+	EventReceiptAck        = "030" // Acuse de recibo de la factura
+	EventClaim             = "031" // Reclamo de la factura
+	EventGoodsReceived     = "032" // Recibo del bien y/o prestación del servicio
+	EventExpressAcceptance = "033" // Aceptación expresa
+)
+
+// BuyerEvents son los eventos que se pueden registrar desde el portal, en el orden en que aplican
+var BuyerEvents = []string{EventReceiptAck, EventGoodsReceived, EventExpressAcceptance, EventClaim}
+
+// eventPrerequisites son los eventos que deben existir antes de registrar cada uno
+var eventPrerequisites = map[string][]string{
+	EventReceiptAck:        {},
+	EventGoodsReceived:     {EventReceiptAck},
+	EventExpressAcceptance: {EventReceiptAck, EventGoodsReceived},
+	EventClaim:             {EventReceiptAck, EventGoodsReceived},
+}
+
+// EventPrerequisites retorna los eventos previos que exige code; ok es false si el comprador no
+// puede registrar ese evento
+func EventPrerequisites(code string) (prerequisites []string, ok bool) {
+	prerequisites, ok = eventPrerequisites[code]
+	return prerequisites, ok
+}
+
+// CheckBuyerEvent valida que el comprador pueda registrar code sobre una factura aceptada por DIAN
+// en acceptedByDIANAt (nil si no lo está), dados los eventos ya registrados
+func CheckBuyerEvent(acceptedByDIANAt *time.Time, events []DocumentEvent, code string) error {
+	prerequisites, ok := EventPrerequisites(code)
+	if !ok {
+		return fmt.Errorf("invalid event")
+	}
+	if acceptedByDIANAt == nil {
+		return fmt.Errorf("invoice is not accepted by DIAN")
+	}
+
+	registered := map[string]bool{}
+	for _, event := range events {
+		registered[event.EventCode] = true
+	}
+	if registered[code] {
+		return fmt.Errorf("event already registered")
+	}
+	for _, prerequisite := range prerequisites {
+		if !registered[prerequisite] {
+			return fmt.Errorf("event %s requires event %s first", code, prerequisite)
+		}
+	}
+	if code == EventClaim && registered[EventExpressAcceptance] {
+		return fmt.Errorf("invoice already accepted, it cannot be claimed")
+	}
+	if code == EventExpressAcceptance && registered[EventClaim] {
+		return fmt.Errorf("invoice already claimed, it cannot be accepted")
+	}
+	return nil
+}
+
+// DocumentEvent es un evento RADIAN registrado sobre un documento (tabla application_responses)
+type DocumentEvent struct {
+	ID                         int64     `json:"id"`
+	DocumentID                 int64     `json:"document_id"`
+	EventCode                  string    `json:"event_code"`
+	EventName                  string    `json:"event_name"`
+	RejectionCode              *string   `json:"rejection_code,omitempty"` // Motivo del reclamo (rejection_types)
+	RejectionName              *string   `json:"rejection_name,omitempty"`
+	Notes                      *string   `json:"notes,omitempty"`
+	RegisteredByName           *string   `json:"registered_by_name,omitempty"`
+	RegisteredByIdentification *string   `json:"registered_by_identification,omitempty"`
+	IPAddress                  *string   `json:"ip_address,omitempty"`
+	ResponseDate               time.Time `json:"response_date"`
+}
+
+// RejectionType es un motivo de reclamo del catálogo rejection_types
+type RejectionType struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// RegisterDocumentEventRequest representa el registro de un evento desde el portal (JSON o formulario)
+type RegisterDocumentEventRequest struct {
+	Event                string `json:"event" form:"event"`
+	RejectionCode        string `json:"rejection_code" form:"rejection_code"` // Requerido para el reclamo (031)
+	Name                 string `json:"name" form:"name"`
+	IdentificationNumber string `json:"identification_number" form:"identification_number"`
+	Notes                string `json:"notes" form:"notes"`
+}
+
+// CreateDocumentLinkRequest representa la solicitud de un enlace firmado para el comprador
+type CreateDocumentLinkRequest struct {
+	ExpiresInHours int  `json:"expires_in_hours"` // Opcional; por defecto PORTAL_LINK_HOURS
+	SendEmail      bool `json:"send_email"`       // Enviar el enlace al email del cliente
+}
+
+// DocumentLink son las URLs firmadas de un documento; todas vencen en ExpiresAt
+type DocumentLink struct {
+	URL       string    `json:"url"` // Portal: ver el documento y registrar eventos
+	PDFURL    string    `json:"pdf_url"`
+	XMLURL    string    `json:"xml_url"`
+	ZIPURL    string    `json:"zip_url"` // AttachedDocument
+	ExpiresAt time.Time `json:"expires_at"`
+	EmailedTo *string   `json:"emailed_to,omitempty"`
+}
+
+// PortalDocument es lo que ve el comprador en el portal
+type PortalDocument struct {
+	Invoice         *Invoice
+	Events          []DocumentEvent
+	AvailableEvents []string // Eventos que el comprador puede registrar ahora
+	RejectionTypes  []RejectionType
+	HasXML          bool
+	HasZIP          bool
+}
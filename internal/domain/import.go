@@ -0,0 +1,42 @@
+package domain
+
+// Acciones de una fila en importaciones con upsert
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+)
+
+// ImportRowResult representa el resultado de una fila en importaciones de clientes/productos
+type ImportRowResult struct {
+	Row    int      `json:"row"`
+	Key    string   `json:"key"`              // identification_number o code
+	Action string   `json:"action,omitempty"` // create | update
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ImportReport es el reporte por fila de una importación de clientes/productos
+type ImportReport struct {
+	Filename  string            `json:"filename"`
+	DryRun    bool              `json:"dry_run"`
+	TotalRows int               `json:"total_rows"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// Add registra el resultado de una fila y actualiza los contadores
+func (r *ImportReport) Add(row ImportRowResult) {
+	row.Valid = len(row.Errors) == 0
+	switch {
+	case !row.Valid:
+		r.Failed++
+	case row.Action == ImportActionCreate:
+		r.Created++
+	case row.Action == ImportActionUpdate:
+		r.Updated++
+	}
+	r.TotalRows++
+	r.Rows = append(r.Rows, row)
+}
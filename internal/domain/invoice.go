@@ -23,11 +23,13 @@ type Invoice struct {
 	Subtotal               float64        `json:"subtotal"`
 	TaxTotal               float64        `json:"tax_total"`
 	Total                  float64        `json:"total"`
+	PrepaidAmount          float64        `json:"prepaid_amount"`
 	XMLPath                *string        `json:"xml_path,omitempty"`
 	PDFPath                *string        `json:"pdf_path,omitempty"`
 	ZipPath                *string        `json:"zip_path,omitempty"`
 	QRCodeURL              *string        `json:"qr_code_url,omitempty"`
 	TrackID                *string        `json:"track_id,omitempty"`
+	CertificateID          *int64         `json:"certificate_id,omitempty"` // Certificado con el que se firmó
 	Status                 string         `json:"status"`
 	DIANStatus             *string        `json:"dian_status,omitempty"`
 	DIANResponse           *string        `json:"dian_response,omitempty"`
@@ -52,6 +54,10 @@ type Invoice struct {
 	Resolution *ResolutionDetail    `json:"resolution,omitempty"`
 	Software   *SoftwareDetail      `json:"software,omitempty"`
 	Lines      []InvoiceLineDetail  `json:"lines,omitempty"`
+
+	// Cuotas y anticipos (PaymentMeans / PrepaidPayment)
+	Installments []InvoiceInstallment `json:"installments,omitempty"`
+	Prepayments  []InvoicePrepayment  `json:"prepayments,omitempty"`
 }
 
 // InvoiceLine representa una línea de detalle de una factura (tabla document_lines)
@@ -199,6 +205,13 @@ type CreateInvoiceRequest struct {
 	PaymentMethodID *int                      `json:"payment_method_id,omitempty"`
 	PaymentFormID   *int                      `json:"payment_form_id,omitempty"` // Opcional: se calcula automáticamente si no se envía
 	Lines           []CreateInvoiceLineRequest `json:"lines" validate:"required,min=1,dive"`
+	Installments    []CreateInstallmentRequest `json:"installments,omitempty"` // Crédito en cuotas: deben sumar total - anticipos
+	Prepayments     []CreatePrepaymentRequest  `json:"prepayments,omitempty"`  // Anticipos recibidos antes de facturar
+
+	// Motivo del consumo del consecutivo (quotation, import); lo asignan los servicios, vacío = invoice
+	Origin      string  `json:"-"`
+	OriginNotes *string `json:"-"`
+	QuotationID *int64  `json:"-"` // Cotización que se convierte (ver NumberOrigin)
 }
 
 // CreateInvoiceLineRequest representa la solicitud para crear una línea de factura
@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// Estados de un trabajo de importación masiva
+const (
+	ImportJobStatusPending   = "pending"
+	ImportJobStatusRunning   = "running"
+	ImportJobStatusCompleted = "completed"
+	ImportJobStatusFailed    = "failed"
+)
+
+// InvoiceImportRowResult representa la validación de una fila del archivo
+type InvoiceImportRowResult struct {
+	Row        int      `json:"row"`
+	InvoiceRef string   `json:"invoice_ref"`
+	Valid      bool     `json:"valid"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// InvoiceImportReport es el reporte de validación (dry-run) de un archivo
+type InvoiceImportReport struct {
+	Filename        string                   `json:"filename"`
+	TotalRows       int                      `json:"total_rows"`
+	ValidRows       int                      `json:"valid_rows"`
+	InvalidRows     int                      `json:"invalid_rows"`
+	TotalInvoices   int                      `json:"total_invoices"`
+	ValidInvoices   int                      `json:"valid_invoices"`
+	InvalidInvoices int                      `json:"invalid_invoices"`
+	Rows            []InvoiceImportRowResult `json:"rows"`
+}
+
+// InvoiceImportJob representa un trabajo de importación (tabla invoice_import_jobs)
+type InvoiceImportJob struct {
+	ID            int64                    `json:"id"`
+	CompanyID     int64                    `json:"company_id"`
+	UserID        int64                    `json:"user_id"`
+	Filename      string                   `json:"filename"`
+	Status        string                   `json:"status"`
+	TotalInvoices int                      `json:"total_invoices"`
+	Processed     int                      `json:"processed"`
+	Succeeded     int                      `json:"succeeded"`
+	Failed        int                      `json:"failed"`
+	Results       []InvoiceImportJobResult `json:"results"`
+	Error         *string                  `json:"error,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
+	StartedAt     *time.Time               `json:"started_at,omitempty"`
+	FinishedAt    *time.Time               `json:"finished_at,omitempty"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+}
+
+// InvoiceImportJobResult es el resultado de crear una factura del archivo
+type InvoiceImportJobResult struct {
+	InvoiceRef string  `json:"invoice_ref"`
+	Rows       []int   `json:"rows"`
+	InvoiceID  *int64  `json:"invoice_id,omitempty"`
+	Number     *string `json:"number,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+// InvoiceImportResponse es la respuesta de POST /invoices/import
+type InvoiceImportResponse struct {
+	DryRun bool                 `json:"dry_run"`
+	Report *InvoiceImportReport `json:"report"`
+	Job    *InvoiceImportJob    `json:"job,omitempty"`
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// InvoiceInstallment representa una cuota de una factura a crédito (tabla document_installments)
+type InvoiceInstallment struct {
+	ID                int64     `json:"id"`
+	DocumentID        int64     `json:"document_id"`
+	InstallmentNumber int       `json:"installment_number"`
+	DueDate           time.Time `json:"due_date"`
+	Amount            float64   `json:"amount"`
+	PaymentMethodID   *int      `json:"payment_method_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	// Códigos DIAN (de catálogos)
+	PaymentMethodCode *string `json:"payment_method_code,omitempty"`
+	PaymentMethodName *string `json:"payment_method_name,omitempty"`
+
+	// Calculados en cartera (pagos aplicados en orden de vencimiento)
+	AmountPaid  *float64 `json:"amount_paid,omitempty"`
+	Balance     *float64 `json:"balance,omitempty"`
+	DaysOverdue *int     `json:"days_overdue,omitempty"`
+}
+
+// InvoicePrepayment representa un anticipo recibido antes de facturar (tabla document_prepayments)
+type InvoicePrepayment struct {
+	ID           int64     `json:"id"`
+	DocumentID   int64     `json:"document_id"`
+	ReceivedDate time.Time `json:"received_date"`
+	Amount       float64   `json:"amount"`
+	Reference    *string   `json:"reference,omitempty"` // InstructionID en PrepaidPayment
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CreateInstallmentRequest representa una cuota en la solicitud de creación de factura
+type CreateInstallmentRequest struct {
+	DueDate         string  `json:"due_date" validate:"required"` // YYYY-MM-DD
+	Amount          float64 `json:"amount" validate:"required,gt=0"`
+	PaymentMethodID *int    `json:"payment_method_id,omitempty"` // Por defecto el medio de pago de la factura
+}
+
+// CreatePrepaymentRequest representa un anticipo en la solicitud de creación de factura
+type CreatePrepaymentRequest struct {
+	ReceivedDate string  `json:"received_date" validate:"required"` // YYYY-MM-DD
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	Reference    *string `json:"reference,omitempty"`
+}
+
+// PayableAmount es el valor a pagar de la factura (LegalMonetaryTotal/PayableAmount)
+func (i *Invoice) PayableAmount() float64 {
+	return i.Total - i.PrepaidAmount
+}
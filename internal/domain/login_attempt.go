@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Alcances de los contadores de logins fallidos
+const (
+	LoginScopeAccount = "account" // Por email, exista o no la cuenta, para no revelar cuáles existen
+	LoginScopeIP      = "ip"
+)
+
+// Parámetros fijos de la protección del login; los umbrales y la duración del bloqueo se
+// configuran con LOGIN_MAX_FAILURES, LOGIN_IP_MAX_FAILURES y LOGIN_LOCK_MINUTES
+const (
+	LoginFailureWindowMinutes = 60 // Un contador sin fallos durante este tiempo vuelve a cero
+	LoginDelayAfterFailures   = 3  // Fallos seguidos de una cuenta antes de exigir espera
+	LoginMaxDelaySeconds      = 60
+	UnlockTokenHours          = 24 // Vigencia del enlace de desbloqueo
+)
+
+// LoginFailure es el contador de logins fallidos de una cuenta o una IP
+type LoginFailure struct {
+	Scope         string
+	Subject       string // Email en minúsculas o IP
+	Failures      int
+	FirstFailedAt time.Time
+	LastFailedAt  time.Time
+	NextAttemptAt *time.Time // Espera progresiva: antes de esta hora no se verifica la contraseña
+	LockedUntil   *time.Time
+}
+
+// LoginLimits son los umbrales que aplica LoginAttemptRepository.Claim a un contador
+type LoginLimits struct {
+	Delay       bool // Espera progresiva de LoginDelay entre intentos (solo cuentas)
+	MaxFailures int  // Intentos seguidos antes del bloqueo; 0 lo desactiva
+	LockMinutes int
+}
+
+// LoginSubject normaliza el email con el que se cuentan los fallos de una cuenta
+func LoginSubject(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginDelay retorna la espera exigida después de n fallos seguidos de una cuenta: 1s, 2s, 4s...
+// hasta LoginMaxDelaySeconds
+func LoginDelay(failures int) time.Duration {
+	if failures < LoginDelayAfterFailures {
+		return 0
+	}
+	delay := time.Second << min(failures-LoginDelayAfterFailures, 6)
+	return min(delay, LoginMaxDelaySeconds*time.Second)
+}
+
+// LoginThrottleError indica que el login se rechazó sin verificar la contraseña porque la cuenta
+// o la IP están bloqueadas o en espera
+type LoginThrottleError struct {
+	Scope      string
+	Locked     bool // Bloqueo temporal; si es false solo falta esperar RetryAfter
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottleError) Error() string {
+	if e.Locked && e.Scope == LoginScopeAccount {
+		return "account temporarily locked"
+	}
+	return "too many failed login attempts"
+}
+
+// UnlockAccountRequest representa la solicitud para desbloquear la cuenta con el token recibido
+type UnlockAccountRequest struct {
+	Token string `json:"token" validate:"required"`
+}
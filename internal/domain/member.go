@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// Roles de los miembros de una empresa
+const (
+	MemberRoleOwner      = "owner"      // Dueño: todo, incluido eliminar la empresa
+	MemberRoleAdmin      = "admin"      // Administra la empresa, miembros, certificados y API keys
+	MemberRoleAccountant = "accountant" // Facturación, resoluciones, cartera y maestros
+	MemberRoleCashier    = "cashier"    // Factura, cotiza y registra pagos
+	MemberRoleReadOnly   = "read_only"  // Solo consulta
+)
+
+// MemberRoles lista los roles en orden de mayor a menor privilegio
+var MemberRoles = []string{MemberRoleOwner, MemberRoleAdmin, MemberRoleAccountant, MemberRoleCashier, MemberRoleReadOnly}
+
+// InvitationTTL es la vigencia de una invitación
+const InvitationTTL = 7 * 24 * time.Hour
+
+// Member es un usuario con acceso a una empresa
+type Member struct {
+	ID        int64     `json:"id"`
+	CompanyID int64     `json:"company_id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy *int64    `json:"invited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invitation es una invitación pendiente o resuelta a una empresa
+type Invitation struct {
+	ID          int64      `json:"id"`
+	CompanyID   int64      `json:"company_id"`
+	CompanyName string     `json:"company_name,omitempty"`
+	Email       string     `json:"email"`
+	Role        string     `json:"role"`
+	InvitedBy   *int64     `json:"invited_by,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy  *int64     `json:"accepted_by,omitempty"`
+	DeclinedAt  *time.Time `json:"declined_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// InviteMemberRequest representa la solicitud para invitar a un usuario a la empresa
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required"`
+}
+
+// UpdateMemberRequest representa la solicitud para cambiar el rol de un miembro
+type UpdateMemberRequest struct {
+	Role string `json:"role" validate:"required"`
+}
@@ -0,0 +1,99 @@
+package domain
+
+import "time"
+
+// Estados de un consecutivo registrado
+const (
+	NumberStatusIssued   = "issued"   // Asignado a un documento
+	NumberStatusDeleted  = "deleted"  // El documento (borrador) fue eliminado
+	NumberStatusAnnulled = "annulled" // Anulado explícitamente (número saltado)
+)
+
+// Motivos por los que se consume un consecutivo
+const (
+	NumberReasonInvoice   = "invoice"   // Factura creada por la API
+	NumberReasonQuotation = "quotation" // Conversión de cotización o pedido
+	NumberReasonImport    = "import"    // Importación masiva
+	NumberReasonUnknown   = "unknown"   // Número sin registro (hueco anterior al registro)
+)
+
+// Estado de cada número en el reporte de numeración
+const (
+	NumberingStateUsed     = "used"     // Documento emitido (firmado o enviado)
+	NumberingStateDraft    = "draft"    // Documento en borrador, aún no enviado
+	NumberingStateDeleted  = "deleted"  // Borrador eliminado; el número quedó sin documento
+	NumberingStateMissing  = "missing"  // Consecutivo consumido sin documento ni registro
+	NumberingStateAnnulled = "annulled" // Número saltado con anulación registrada
+)
+
+// NumberOrigin indica por qué se consume un consecutivo al crear un documento
+type NumberOrigin struct {
+	Reason string
+	Notes  *string
+	UserID int64
+
+	// Cotización que se convierte; se marca convertida en la misma transacción que crea la factura
+	QuotationID *int64
+}
+
+// NumberRecord es el registro de un consecutivo asignado
+type NumberRecord struct {
+	ID              int64      `json:"id"`
+	ResolutionID    int64      `json:"resolution_id"`
+	Consecutive     int64      `json:"consecutive"`
+	Number          string     `json:"number"`
+	Status          string     `json:"status"`
+	Reason          string     `json:"reason"`
+	Notes           *string    `json:"notes,omitempty"`
+	DocumentID      *int64     `json:"document_id,omitempty"`
+	UserID          *int64     `json:"user_id,omitempty"`
+	AnnulmentReason *string    `json:"annulment_reason,omitempty"`
+	AnnulledBy      *int64     `json:"annulled_by,omitempty"`
+	AnnulledAt      *time.Time `json:"annulled_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ConsecutiveRange es un rango continuo de consecutivos
+type ConsecutiveRange struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// NumberingEntry es el detalle de un número en el reporte de numeración
+type NumberingEntry struct {
+	Consecutive     int64      `json:"consecutive"`
+	Number          string     `json:"number"`
+	State           string     `json:"state"`
+	Reason          *string    `json:"reason,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+	DocumentID      *int64     `json:"document_id,omitempty"`
+	DocumentStatus  *string    `json:"document_status,omitempty"`
+	AnnulmentReason *string    `json:"annulment_reason,omitempty"`
+	RecordedAt      *time.Time `json:"recorded_at,omitempty"`
+}
+
+// NumberingReport resume el uso de los consecutivos asignados de una resolución
+// (de from_number hasta el último asignado)
+type NumberingReport struct {
+	ResolutionID int64              `json:"resolution_id"`
+	Prefix       string             `json:"prefix"`
+	Resolution   string             `json:"resolution"`
+	FromNumber   int64              `json:"from_number"`
+	ToNumber     int64              `json:"to_number"`
+	NextNumber   int64              `json:"next_number"`
+	Assigned     int64              `json:"assigned"`
+	Used         int                `json:"used"`
+	Draft        int                `json:"draft"`
+	Deleted      int                `json:"deleted"`
+	Missing      int                `json:"missing"`
+	Annulled     int                `json:"annulled"`
+	UsedRanges   []ConsecutiveRange `json:"used_ranges"`
+	Entries      []NumberingEntry   `json:"entries"` // Números que no están emitidos (o todos con include_used)
+}
+
+// AnnulNumberRequest representa la solicitud para anular un consecutivo saltado
+type AnnulNumberRequest struct {
+	Consecutive int64  `json:"consecutive" validate:"required"`
+	Reason      string `json:"reason" validate:"required"`
+}
@@ -0,0 +1,134 @@
+package domain
+
+import "time"
+
+// Estado de pago derivado de una factura (no se almacena, se calcula con total - pagos y due_date)
+const (
+	PaymentStatusUnpaid        = "unpaid"
+	PaymentStatusPartiallyPaid = "partially_paid"
+	PaymentStatusPaid          = "paid"
+	PaymentStatusOverdue       = "overdue"
+)
+
+// ReceivableStatuses son los estados de factura que generan cartera (emitidas)
+var ReceivableStatuses = []string{"signed", "sent", "accepted"}
+
+// Payment representa un pago recibido contra una factura (tabla payments)
+type Payment struct {
+	ID              int64     `json:"id"`
+	CompanyID       int64     `json:"company_id"`
+	DocumentID      int64     `json:"document_id"`
+	CustomerID      int64     `json:"customer_id"`
+	PaymentDate     time.Time `json:"payment_date"`
+	Amount          float64   `json:"amount"`
+	PaymentMethodID *int      `json:"payment_method_id,omitempty"`
+	Reference       *string   `json:"reference,omitempty"`
+	Notes           *string   `json:"notes,omitempty"`
+	CreatedBy       int64     `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Datos anidados (de JOINs)
+	DocumentNumber    string  `json:"document_number,omitempty"`
+	PaymentMethodName *string `json:"payment_method_name,omitempty"`
+}
+
+// CreatePaymentRequest representa la solicitud para registrar un pago
+type CreatePaymentRequest struct {
+	DocumentID      int64   `json:"document_id" validate:"required"`
+	PaymentDate     string  `json:"payment_date" validate:"required"` // YYYY-MM-DD
+	Amount          float64 `json:"amount" validate:"required,gt=0"`
+	PaymentMethodID *int    `json:"payment_method_id,omitempty"`
+	Reference       *string `json:"reference,omitempty"`
+	Notes           *string `json:"notes,omitempty"`
+}
+
+// PaymentListResponse representa la respuesta paginada de pagos
+type PaymentListResponse struct {
+	Payments []Payment `json:"payments"`
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+}
+
+// Receivable representa el saldo de una factura emitida
+type Receivable struct {
+	DocumentID           int64      `json:"document_id"`
+	CompanyID            int64      `json:"company_id"`
+	Number               string     `json:"number"`
+	CustomerID           int64      `json:"customer_id"`
+	CustomerName         string     `json:"customer_name"`
+	IdentificationNumber string     `json:"identification_number"`
+	Status               string     `json:"status"`
+	IssueDate            time.Time  `json:"issue_date"`
+	DueDate              *time.Time `json:"due_date,omitempty"`
+	Total                float64    `json:"total"`
+	PrepaidAmount        float64    `json:"prepaid_amount"`
+	AmountPaid           float64    `json:"amount_paid"`
+	Balance              float64    `json:"balance"`
+	PaymentStatus        string     `json:"payment_status"`
+	DaysOverdue          int        `json:"days_overdue"`
+
+	// Cuotas con su saldo (los pagos se aplican en orden de vencimiento)
+	Installments []InvoiceInstallment `json:"installments,omitempty"`
+}
+
+// InvoicePayments es el estado de cuenta de una factura con sus pagos
+type InvoicePayments struct {
+	Receivable
+	Payments []Payment `json:"payments"`
+}
+
+// AgingBuckets agrupa saldos por días de vencimiento
+type AgingBuckets struct {
+	NotDue     float64 `json:"not_due"`
+	Days0To30  float64 `json:"days_0_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"over_90"`
+	Total      float64 `json:"total"`
+}
+
+// AgingCustomer es la fila del reporte de antigüedad de cartera de un cliente
+type AgingCustomer struct {
+	CustomerID           int64  `json:"customer_id"`
+	IdentificationNumber string `json:"identification_number"`
+	Name                 string `json:"name"`
+	Invoices             int    `json:"invoices"`
+	AgingBuckets
+}
+
+// AgingReport es el reporte de antigüedad de cartera por cliente
+type AgingReport struct {
+	CompanyID int64           `json:"company_id"`
+	AsOf      string          `json:"as_of"`
+	Customers []AgingCustomer `json:"customers"`
+	Totals    AgingBuckets    `json:"totals"`
+}
+
+// StatementEntry es un movimiento del estado de cuenta (factura = débito, anticipo y pago = crédito)
+type StatementEntry struct {
+	Date      time.Time  `json:"date"`
+	Type      string     `json:"type"` // invoice | prepayment | payment
+	Number    string     `json:"number"`
+	Reference *string    `json:"reference,omitempty"`
+	DueDate   *time.Time `json:"due_date,omitempty"`
+	Debit     float64    `json:"debit"`
+	Credit    float64    `json:"credit"`
+	Balance   float64    `json:"balance"`
+}
+
+// AccountStatement es el estado de cuenta de un cliente en un periodo
+type AccountStatement struct {
+	Company        *CompanyDetail   `json:"company,omitempty"`
+	Customer       *Customer        `json:"customer"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	OpeningBalance float64          `json:"opening_balance"`
+	TotalDebit     float64          `json:"total_debit"`
+	TotalCredit    float64          `json:"total_credit"`
+	ClosingBalance float64          `json:"closing_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	OpenInvoices   []Receivable     `json:"open_invoices"`
+	Aging          AgingBuckets     `json:"aging"`
+}
@@ -0,0 +1,114 @@
+package domain
+
+// Permisos sobre los recursos de una empresa. Los roles de los miembros y las API keys se
+// autorizan con estos mismos permisos.
+const (
+	PermissionCompanyRead        = "company:read"
+	PermissionCompanyWrite       = "company:write"  // Datos de la empresa y logo
+	PermissionCompanyDelete      = "company:delete" // Solo el dueño
+	PermissionMembersManage      = "members:manage" // Invitar, cambiar rol y retirar miembros
+	PermissionAPIKeysManage      = "api_keys:manage"
+	PermissionCertificatesRead   = "certificates:read"
+	PermissionCertificatesManage = "certificates:manage"
+	PermissionSoftwareRead       = "software:read"
+	PermissionSoftwareManage     = "software:manage"
+	PermissionResolutionsRead    = "resolutions:read"
+	PermissionResolutionsWrite   = "resolutions:write" // Crear, sincronizar con DIAN y anular números
+	PermissionCustomersRead      = "customers:read"
+	PermissionCustomersWrite     = "customers:write"
+	PermissionProductsRead       = "products:read"
+	PermissionProductsWrite      = "products:write"
+	PermissionInvoicesRead       = "invoices:read"
+	PermissionInvoicesWrite      = "invoices:write" // Crear, firmar y enviar a DIAN
+	PermissionQuotationsRead     = "quotations:read"
+	PermissionQuotationsWrite    = "quotations:write"
+	PermissionPaymentsRead       = "payments:read"
+	PermissionPaymentsWrite      = "payments:write"
+	PermissionReportsRead        = "reports:read" // Cartera y estados de cuenta
+	PermissionAuditRead          = "audit:read"   // Historial de cambios (audit_log)
+)
+
+// readPermissions son los permisos de consulta, comunes a todos los roles
+var readPermissions = []string{
+	PermissionCompanyRead,
+	PermissionCertificatesRead,
+	PermissionSoftwareRead,
+	PermissionResolutionsRead,
+	PermissionCustomersRead,
+	PermissionProductsRead,
+	PermissionInvoicesRead,
+	PermissionQuotationsRead,
+	PermissionPaymentsRead,
+	PermissionReportsRead,
+}
+
+// rolePermissions es la matriz de permisos por rol (además de los de consulta)
+var rolePermissions = map[string][]string{
+	MemberRoleOwner: {
+		PermissionCompanyWrite, PermissionCompanyDelete, PermissionMembersManage, PermissionAPIKeysManage, PermissionAuditRead,
+		PermissionCertificatesManage, PermissionSoftwareManage, PermissionResolutionsWrite,
+		PermissionCustomersWrite, PermissionProductsWrite, PermissionInvoicesWrite,
+		PermissionQuotationsWrite, PermissionPaymentsWrite,
+	},
+	MemberRoleAdmin: {
+		PermissionCompanyWrite, PermissionMembersManage, PermissionAPIKeysManage, PermissionAuditRead,
+		PermissionCertificatesManage, PermissionSoftwareManage, PermissionResolutionsWrite,
+		PermissionCustomersWrite, PermissionProductsWrite, PermissionInvoicesWrite,
+		PermissionQuotationsWrite, PermissionPaymentsWrite,
+	},
+	MemberRoleAccountant: {
+		PermissionResolutionsWrite, PermissionCustomersWrite, PermissionProductsWrite,
+		PermissionInvoicesWrite, PermissionQuotationsWrite, PermissionPaymentsWrite,
+	},
+	MemberRoleCashier: {
+		PermissionCustomersWrite, PermissionInvoicesWrite, PermissionQuotationsWrite, PermissionPaymentsWrite,
+	},
+	MemberRoleReadOnly: {},
+}
+
+// RoleHasPermission indica si el rol tiene el permiso
+func RoleHasPermission(role, permission string) bool {
+	grants, ok := rolePermissions[role]
+	if !ok {
+		return false
+	}
+	for _, p := range readPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	for _, p := range grants {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions lista todos los permisos del rol
+func RolePermissions(role string) []string {
+	grants, ok := rolePermissions[role]
+	if !ok {
+		return []string{}
+	}
+	permissions := make([]string, 0, len(readPermissions)+len(grants))
+	permissions = append(permissions, readPermissions...)
+	return append(permissions, grants...)
+}
+
+// AccessError indica que el usuario no es miembro de la empresa o que su rol no tiene el
+// permiso. El mensaje nombra el recurso ("unauthorized access to invoice").
+type AccessError struct {
+	Resource   string
+	Role       string // Vacío si el usuario no es miembro
+	Permission string
+}
+
+func (e *AccessError) Error() string {
+	return "unauthorized access to " + e.Resource
+}
+
+// Forbidden indica que el usuario es miembro pero su rol no tiene el permiso
+func (e *AccessError) Forbidden() bool {
+	return e.Role != ""
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// DefaultPlan es el plan de las empresas nuevas y el que se aplica si el plan de una empresa no
+// existe, para que una empresa nunca quede sin límites por error
+const DefaultPlan = "free"
+
+// Plan define las cuotas de una empresa. Un límite nil significa sin límite.
+type Plan struct {
+	Code              string `json:"code"`
+	Name              string `json:"name"`
+	DocumentsPerMonth *int   `json:"documents_per_month"`  // Documentos creados por mes calendario
+	APICallsPerMinute *int   `json:"api_calls_per_minute"` // Peticiones por minuto de todas las API keys de la empresa
+}
+
+// UsagePeriod es el uso de una empresa en un mes
+type UsagePeriod struct {
+	Period    string    `json:"period"` // YYYY-MM
+	Documents int       `json:"documents"`
+	APICalls  int64     `json:"api_calls"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CompanyUsageHistory es el plan de una empresa con su uso de los últimos meses
+type CompanyUsageHistory struct {
+	CompanyID int64         `json:"company_id"`
+	Plan      Plan          `json:"plan"`
+	Periods   []UsagePeriod `json:"periods"` // El más reciente primero
+}
+
+// SetCompanyPlanRequest representa la solicitud para cambiar el plan de una empresa
+type SetCompanyPlanRequest struct {
+	Plan string `json:"plan" validate:"required"`
+}
+
+// UsagePeriodStart retorna el primer día del mes de t, que identifica el periodo de uso
+func UsagePeriodStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
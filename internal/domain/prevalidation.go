@@ -0,0 +1,29 @@
+package domain
+
+// Severidades de una regla de prevalidación: error bloquea el envío a DIAN, warning no
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue es un hallazgo de la prevalidación local del XML UBL
+type ValidationIssue struct {
+	Rule     string `json:"rule"`     // Código de la regla DIAN (FAD06, FAJ24...) o XSD
+	Severity string `json:"severity"` // error | warning
+	XPath    string `json:"xpath,omitempty"`
+	Line     int    `json:"line,omitempty"` // Solo errores de esquema
+	Message  string `json:"message"`
+}
+
+// PrevalidationReport es el resultado de validar el XML contra el XSD y las reglas DIAN
+type PrevalidationReport struct {
+	Valid         bool              `json:"valid"` // Sin errores (los warnings no bloquean)
+	DocumentType  string            `json:"document_type"`
+	Number        string            `json:"number"`
+	Signed        bool              `json:"signed"` // Se validó el XML firmado almacenado
+	SchemaChecked bool              `json:"schema_checked"`
+	RulesChecked  int               `json:"rules_checked"`
+	Errors        int               `json:"errors"`
+	Warnings      int               `json:"warnings"`
+	Issues        []ValidationIssue `json:"issues"`
+}
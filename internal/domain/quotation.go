@@ -0,0 +1,112 @@
+package domain
+
+import "time"
+
+// Tipos de documento comercial (tabla quotations.kind)
+const (
+	QuotationKindQuotation = "quotation" // Cotización
+	QuotationKindOrder     = "order"     // Pedido de venta
+)
+
+// Estados de una cotización/pedido
+const (
+	QuotationStatusDraft     = "draft"
+	QuotationStatusSent      = "sent"
+	QuotationStatusAccepted  = "accepted"
+	QuotationStatusRejected  = "rejected"
+	QuotationStatusExpired   = "expired"
+	QuotationStatusConverted = "converted"
+)
+
+// Quotation representa una cotización o pedido de venta (no se envía a la DIAN)
+type Quotation struct {
+	ID                 int64      `json:"id"`
+	CompanyID          int64      `json:"company_id"`
+	CustomerID         int64      `json:"customer_id"`
+	Kind               string     `json:"kind"`
+	Prefix             string     `json:"prefix"`
+	Number             string     `json:"number"`
+	Consecutive        int64      `json:"consecutive"`
+	IssueDate          time.Time  `json:"issue_date"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	CurrencyCodeID     int        `json:"currency_code_id"`
+	Notes              *string    `json:"notes,omitempty"`
+	PaymentMethodID    *int       `json:"payment_method_id,omitempty"`
+	PaymentFormID      *int       `json:"payment_form_id,omitempty"`
+	Subtotal           float64    `json:"subtotal"`
+	TaxTotal           float64    `json:"tax_total"`
+	Total              float64    `json:"total"`
+	Status             string     `json:"status"`
+	RespondedAt        *time.Time `json:"responded_at,omitempty"`
+	ConvertedInvoiceID *int64     `json:"converted_invoice_id,omitempty"`
+	ConvertedAt        *time.Time `json:"converted_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// Datos anidados (de JOINs) - Necesarios para el PDF
+	PaymentMethodName *string         `json:"payment_method_name,omitempty"`
+	PaymentFormName   *string         `json:"payment_form_name,omitempty"`
+	Company           *CompanyDetail  `json:"company,omitempty"`
+	Customer          *CustomerDetail `json:"customer,omitempty"`
+	Lines             []QuotationLine `json:"lines,omitempty"`
+}
+
+// QuotationLine representa una línea de detalle de una cotización (tabla quotation_lines)
+type QuotationLine struct {
+	ID                 int64     `json:"id"`
+	QuotationID        int64     `json:"quotation_id"`
+	ProductID          int64     `json:"product_id"`
+	LineNumber         int64     `json:"line_number"`
+	Description        string    `json:"description"`
+	Quantity           float64   `json:"quantity"`
+	UnitPrice          float64   `json:"unit_price"`
+	LineTotal          float64   `json:"line_total"`
+	TaxRate            float64   `json:"tax_rate"`
+	TaxAmount          float64   `json:"tax_amount"`
+	BrandName          *string   `json:"brand_name,omitempty"`
+	ModelName          *string   `json:"model_name,omitempty"`
+	StandardItemCode   *string   `json:"standard_item_code,omitempty"`
+	ClassificationCode *string   `json:"classification_code,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+
+	// Datos del producto (de JOINs)
+	ProductCode string `json:"product_code,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
+	UnitCode    string `json:"unit_code,omitempty"`
+	UnitName    string `json:"unit_name,omitempty"`
+}
+
+// CreateQuotationRequest representa la solicitud para crear una cotización o pedido
+type CreateQuotationRequest struct {
+	CompanyID       int64                      `json:"company_id" validate:"required"`
+	CustomerID      int64                      `json:"customer_id" validate:"required"`
+	Kind            string                     `json:"kind,omitempty"`                 // quotation (default) | order
+	IssueDate       string                     `json:"issue_date" validate:"required"` // YYYY-MM-DD
+	ExpiresAt       *string                    `json:"expires_at,omitempty"`           // YYYY-MM-DD
+	CurrencyCodeID  int                        `json:"currency_code_id" validate:"required"`
+	Notes           *string                    `json:"notes,omitempty"`
+	PaymentMethodID *int                       `json:"payment_method_id,omitempty"`
+	PaymentFormID   *int                       `json:"payment_form_id,omitempty"`
+	Lines           []CreateInvoiceLineRequest `json:"lines" validate:"required,min=1,dive"`
+}
+
+// UpdateQuotationStatusRequest representa el cambio de estado de una cotización
+type UpdateQuotationStatusRequest struct {
+	Status string `json:"status" validate:"required"` // sent | accepted | rejected
+}
+
+// ConvertQuotationRequest representa la solicitud para convertir una cotización en factura
+type ConvertQuotationRequest struct {
+	ResolutionID  int64   `json:"resolution_id" validate:"required"`
+	IssueDate     *string `json:"issue_date,omitempty"` // YYYY-MM-DD, por defecto hoy
+	DueDate       *string `json:"due_date,omitempty"`   // YYYY-MM-DD
+	PaymentFormID *int    `json:"payment_form_id,omitempty"`
+}
+
+// QuotationListResponse representa la respuesta paginada de cotizaciones
+type QuotationListResponse struct {
+	Quotations []Quotation `json:"quotations"`
+	Total      int         `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+}
@@ -18,6 +18,41 @@ type Resolution struct {
 	IsActive       bool      `json:"is_active"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Última sincronización con DIAN (GetNumberingRange)
+	SyncStatus *string    `json:"sync_status,omitempty"`
+	SyncedAt   *time.Time `json:"synced_at,omitempty"`
+	SyncNotes  *string    `json:"sync_notes,omitempty"`
+
+	// Continuidad de la numeración y alertas
+	Status              string     `json:"status"`
+	FollowsResolutionID *int64     `json:"follows_resolution_id,omitempty"`
+	ActivatedAt         *time.Time `json:"activated_at,omitempty"`
+	AlertRemaining      *int       `json:"alert_remaining,omitempty"`
+	AlertDays           *int       `json:"alert_days,omitempty"`
+}
+
+// Estados de una resolución
+const (
+	ResolutionStatusActive    = "active"    // En uso para numerar
+	ResolutionStatusPending   = "pending"   // Continuación registrada, espera a que la anterior termine
+	ResolutionStatusExhausted = "exhausted" // Sin consecutivos disponibles
+	ResolutionStatusExpired   = "expired"   // Vigencia (date_to) vencida
+)
+
+// Remaining retorna los consecutivos que quedan por asignar
+func (r *Resolution) Remaining() int64 {
+	if r.CurrentNumber >= r.ToNumber {
+		return 0
+	}
+	return r.ToNumber - r.CurrentNumber
+}
+
+// DaysToExpire retorna los días que faltan hasta date_to (último día de vigencia), negativo si venció
+func (r *Resolution) DaysToExpire(t time.Time) int {
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(r.DateTo.Year(), r.DateTo.Month(), r.DateTo.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dateTo.Sub(today).Hours() / 24)
 }
 
 // CreateResolutionRequest representa la solicitud para crear una resolución
@@ -31,6 +66,13 @@ type CreateResolutionRequest struct {
 	ToNumber       int64   `json:"to_number" validate:"required"`
 	DateFrom       string  `json:"date_from" validate:"required"` // Format: YYYY-MM-DD
 	DateTo         string  `json:"date_to" validate:"required"`   // Format: YYYY-MM-DD
+
+	// Opcional: resolución que esta continúa; queda pendiente y se activa sola cuando aquella
+	// se agota o vence
+	FollowsResolutionID *int64 `json:"follows_resolution_id,omitempty"`
+	// Opcional: umbrales de alerta propios (0 desactiva la alerta)
+	AlertRemaining *int `json:"alert_remaining,omitempty"`
+	AlertDays      *int `json:"alert_days,omitempty"`
 }
 
 // ResolutionListResponse representa la respuesta paginada de resoluciones
@@ -40,3 +82,63 @@ type ResolutionListResponse struct {
 	Page        int          `json:"page"`
 	PageSize    int          `json:"page_size"`
 }
+
+// Estados de la sincronización de resoluciones con DIAN
+const (
+	ResolutionSyncMatched  = "matched"   // Coincide con DIAN
+	ResolutionSyncCreated  = "created"   // Autorizada en DIAN y creada localmente
+	ResolutionSyncUpdated  = "updated"   // Corregida con los datos de DIAN
+	ResolutionSyncMismatch = "mismatch"  // Difiere de DIAN y no se corrigió (consecutivo fuera del rango autorizado)
+	ResolutionSyncNotFound = "not_found" // La resolución local no aparece autorizada en DIAN
+)
+
+// ResolutionDifference es un campo de la resolución local que no coincide con DIAN
+type ResolutionDifference struct {
+	Field string `json:"field"`
+	Local string `json:"local"`
+	DIAN  string `json:"dian"`
+}
+
+// ResolutionSyncItem es el resultado de sincronizar un prefijo
+type ResolutionSyncItem struct {
+	ResolutionID *int64                 `json:"resolution_id,omitempty"`
+	Prefix       string                 `json:"prefix"`
+	Resolution   string                 `json:"resolution"`
+	Status       string                 `json:"status"`
+	Differences  []ResolutionDifference `json:"differences,omitempty"`
+	// TechnicalKeyMatches compara la clave técnica sin exponerla (nil si no hay resolución local)
+	TechnicalKeyMatches *bool  `json:"technical_key_matches,omitempty"`
+	Message             string `json:"message,omitempty"`
+}
+
+// ResolutionSyncResult resume la sincronización de las resoluciones de una empresa con DIAN
+type ResolutionSyncResult struct {
+	CompanyID int64                `json:"company_id"`
+	DryRun    bool                 `json:"dry_run"`
+	SyncedAt  time.Time            `json:"synced_at"`
+	Matched   int                  `json:"matched"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Mismatch  int                  `json:"mismatch"`
+	NotFound  int                  `json:"not_found"`
+	Items     []ResolutionSyncItem `json:"items"`
+}
+
+// ResolutionAlert se envía cuando una resolución se acerca al agotamiento o al vencimiento,
+// termina, o cuando una resolución de continuación entra en uso
+type ResolutionAlert struct {
+	ResolutionID int64     `json:"resolution_id"`
+	CompanyID    int64     `json:"company_id"`
+	CompanyName  string    `json:"company_name"`
+	CompanyNIT   string    `json:"company_nit"`
+	Prefix       string    `json:"prefix"`
+	Resolution   string    `json:"resolution"`
+	Status       string    `json:"status"`
+	Remaining    int64     `json:"remaining"`
+	DateTo       time.Time `json:"date_to"`
+	DaysToExpire int       `json:"days_to_expire"`
+	Threshold    *int      `json:"threshold,omitempty"`
+	// Continuación registrada (si existe) o resolución anterior (al activarse una continuación)
+	FollowUpID          *int64 `json:"follow_up_id,omitempty"`
+	FollowsResolutionID *int64 `json:"follows_resolution_id,omitempty"`
+}
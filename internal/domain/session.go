@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// Motivos de revocación de una sesión
+const (
+	SessionRevokedLogout    = "logout"
+	SessionRevokedLogoutAll = "logout_all"
+	SessionRevokedByUser    = "revoked"        // Cerrada desde la lista de sesiones
+	SessionRevokedReuse     = "refresh_reuse"  // Se presentó un refresh token ya rotado
+	SessionRevokedByAdmin   = "admin"          // Usuario desactivado o modificado por un administrador
+	SessionRevokedReset     = "password_reset" // Contraseña restablecida con el enlace enviado por correo
+	SessionRevokedTwoFactor = "two_factor"     // 2FA activado: se cierran las demás sesiones
+)
+
+// Session es un inicio de sesión (dispositivo) con su refresh token rotativo
+type Session struct {
+	ID                       int64      `json:"id"`
+	UserID                   int64      `json:"user_id"`
+	RefreshTokenHash         string     `json:"-"`
+	PreviousRefreshTokenHash *string    `json:"-"`
+	AccessJTI                string     `json:"-"`
+	AccessExpiresAt          time.Time  `json:"-"`
+	UserAgent                *string    `json:"user_agent,omitempty"`
+	IPAddress                *string    `json:"ip_address,omitempty"`
+	CreatedAt                time.Time  `json:"created_at"`
+	LastUsedAt               time.Time  `json:"last_used_at"`
+	ExpiresAt                time.Time  `json:"expires_at"`
+	RevokedAt                *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason            *string    `json:"revoked_reason,omitempty"`
+	ImpersonatorID           *int64     `json:"impersonator_id,omitempty"` // Administrador que suplanta al usuario
+	Current                  bool       `json:"current"`
+}
+
+// SessionClient identifica el cliente que inicia o renueva la sesión
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// RefreshTokenRequest representa la solicitud para renovar el access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import "time"
+
+// Parámetros de la verificación en dos pasos
+const (
+	TwoFactorIssuer           = "APIDIAN" // Nombre que muestran las apps autenticadoras
+	TwoFactorChallengeMinutes = 5         // Tiempo para ingresar el código después de la contraseña
+	TwoFactorMaxAttempts      = 5         // Códigos erróneos permitidos por reto de login
+	RecoveryCodeCount         = 10
+)
+
+// RoleRequiresTwoFactor indica si la política de 2FA de la empresa aplica al rol: los roles que
+// pueden firmar y enviar a DIAN o administrar certificados
+func RoleRequiresTwoFactor(role string) bool {
+	return RoleHasPermission(role, PermissionInvoicesWrite) || RoleHasPermission(role, PermissionCertificatesManage)
+}
+
+// LoginChallenge es el segundo paso pendiente de un login con 2FA
+type LoginChallenge struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	Attempts  int
+	UserAgent *string
+	IPAddress *string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TwoFactorChallengeResponse es la respuesta del login cuando falta el código 2FA
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest representa el segundo paso del login: código TOTP o de recuperación
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// TwoFactorStatus resume la configuración 2FA del usuario
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	// Empresas cuya política exige 2FA para el rol del usuario
+	RequiredBy []string `json:"required_by"`
+}
+
+// TwoFactorSetupRequest inicia la inscripción; pide la contraseña para que un access token
+// robado no baste para activar 2FA
+type TwoFactorSetupRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// TwoFactorSetupResponse trae el secreto pendiente de confirmar y su código QR
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`      // Para ingresarlo a mano en la app
+	OTPAuthURL string `json:"otpauth_url"` // otpauth://totp/...
+	QRCode     string `json:"qr_code"`     // data:image/png;base64,...
+}
+
+// TwoFactorCodeRequest confirma la inscripción o regenera los códigos de recuperación
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// DisableTwoFactorRequest representa la solicitud para desactivar 2FA
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// RecoveryCodesResponse trae los códigos de recuperación; se muestran una sola vez
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorPolicyRequest activa o desactiva la exigencia de 2FA en una empresa
+type TwoFactorPolicyRequest struct {
+	RequireTwoFactor *bool `json:"require_two_factor" validate:"required"`
+}
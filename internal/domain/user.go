@@ -4,14 +4,16 @@ import "time"
 
 // User representa un usuario del sistema
 type User struct {
-	ID              int64      `json:"id"`
-	Name            string     `json:"name"`
-	Email           string     `json:"email"`
-	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
-	Password        string     `json:"-"` // No exponer en JSON
-	IsActive        bool       `json:"is_active"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	ID               int64      `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	Password         string     `json:"-"` // No exponer en JSON
+	IsActive         bool       `json:"is_active"`
+	IsPlatformAdmin  bool       `json:"is_platform_admin"` // Operador de la plataforma
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
 // RegisterRequest representa la solicitud de registro
@@ -29,8 +31,15 @@ type LoginRequest struct {
 
 // LoginResponse representa la respuesta del login
 type LoginResponse struct {
-	User  *User  `json:"user"`
-	Token string `json:"token"`
+	User             *User     `json:"user"`
+	Token            string    `json:"token"` // Access token (JWT de corta duración)
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token,omitempty"` // Vacío en sesiones de suplantación
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        int64     `json:"session_id"`
+	ImpersonatorID   *int64    `json:"impersonator_id,omitempty"`
+	// Con UNVERIFIED_LOGIN=limited, límite para verificar el email antes de que se bloquee el login
+	VerificationDeadline *time.Time `json:"verification_deadline,omitempty"`
 }
 
 // UpdateUserRequest representa la solicitud para actualizar un usuario
@@ -39,6 +48,15 @@ type UpdateUserRequest struct {
 	Email    *string `json:"email,omitempty" validate:"omitempty,email"`
 	Password *string `json:"password,omitempty" validate:"omitempty,min=8"`
 	IsActive *bool   `json:"is_active,omitempty"`
+	// Solo administradores de la plataforma (PUT /admin/users/:id)
+	IsPlatformAdmin *bool `json:"is_platform_admin,omitempty"`
+}
+
+// UpdateProfileRequest representa la solicitud del usuario para actualizar su propio perfil
+type UpdateProfileRequest struct {
+	Name            *string `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
+	Email           *string `json:"email,omitempty" validate:"omitempty,email"`
+	CurrentPassword *string `json:"current_password,omitempty"` // Requerida para cambiar el email
 }
 
 // ChangePasswordRequest representa la solicitud para cambiar contraseña
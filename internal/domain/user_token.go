@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// Propósitos de los tokens enviados por correo
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+	UserTokenUnlockAccount = "unlock_account"
+)
+
+// UserTokenResendSeconds es el tiempo mínimo entre dos correos del mismo tipo para un usuario
+const UserTokenResendSeconds = 60
+
+// UserToken es un token de un solo uso enviado por correo; solo se guarda su hash
+type UserToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Purpose   string     `json:"purpose"`
+	TokenHash string     `json:"-"`
+	Email     string     `json:"email"` // Dirección a la que se envió; si el usuario la cambia, el token deja de servir
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// VerifyEmailRequest representa la solicitud para verificar el email con el token recibido
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailRequest representa las solicitudes públicas que solo llevan un email (reenviar la
+// verificación, olvidé mi contraseña)
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest representa la solicitud para restablecer la contraseña con el token recibido
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// VerifyXMLRequest representa un XML firmado (propio o de un tercero) para verificar
+type VerifyXMLRequest struct {
+	XML          string  `json:"xml" validate:"required"` // Base64 encoded XML (Invoice, CreditNote, DebitNote o AttachedDocument)
+	TechnicalKey *string `json:"technical_key,omitempty"` // Clave técnica para recalcular el CUFE de terceros
+	SoftwarePIN  *string `json:"software_pin,omitempty"`  // PIN del software para recalcular el CUDE de terceros
+}
+
+// VerificationReport es el resultado de verificar la integridad de un documento firmado
+type VerificationReport struct {
+	Valid        bool   `json:"valid"`
+	Source       string `json:"source"` // stored | uploaded | attached_document
+	DocumentType string `json:"document_type"`
+	Number       string `json:"number"`
+	IssuerNIT    string `json:"issuer_nit"`
+
+	UUID                          string                 `json:"uuid"`
+	UUIDScheme                    string                 `json:"uuid_scheme"` // CUFE-SHA384 | CUDE-SHA384
+	UUIDCheck                     UUIDCheck              `json:"uuid_check"`
+	Signature                     SignatureVerification  `json:"signature"`
+	Certificate                   *CertificateSummary    `json:"certificate,omitempty"`
+	StoredUUIDMatches             *bool                  `json:"stored_uuid_matches,omitempty"` // Solo documentos propios
+	SignedWithRecordedCertificate *bool                  `json:"signed_with_recorded_certificate,omitempty"`
+	ContainerSignature            *SignatureVerification `json:"container_signature,omitempty"` // Firma del AttachedDocument
+	Errors                        []string               `json:"errors,omitempty"`
+}
+
+// UUIDCheck es el resultado de recalcular el CUFE/CUDE desde el contenido del XML
+type UUIDCheck struct {
+	Status   string `json:"status"` // valid | mismatch | skipped
+	Computed string `json:"computed,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Estados de UUIDCheck
+const (
+	UUIDCheckValid    = "valid"
+	UUIDCheckMismatch = "mismatch"
+	UUIDCheckSkipped  = "skipped"
+)
+
+// SignatureVerification resume la verificación XAdES-EPES
+type SignatureVerification struct {
+	Valid                     bool             `json:"valid"`
+	SignatureValid            bool             `json:"signature_value_valid"`
+	DigestsValid              bool             `json:"digests_valid"`
+	DocumentCovered           bool             `json:"document_covered"`
+	SignedPropertiesCovered   bool             `json:"signed_properties_covered"`
+	SigningCertificateValid   bool             `json:"signing_certificate_valid"`
+	CertificateValidAtSigning *bool            `json:"certificate_valid_at_signing,omitempty"`
+	SignatureMethod           string           `json:"signature_method,omitempty"`
+	SigningTime               *time.Time       `json:"signing_time,omitempty"`
+	PolicyIdentifier          string           `json:"policy_identifier,omitempty"`
+	References                []ReferenceCheck `json:"references"`
+	Errors                    []string         `json:"errors,omitempty"`
+}
+
+// ReferenceCheck es el resultado de comparar el digest de una referencia firmada
+type ReferenceCheck struct {
+	URI            string `json:"uri"`
+	Valid          bool   `json:"valid"`
+	ExpectedDigest string `json:"expected_digest"`
+	ComputedDigest string `json:"computed_digest,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// CertificateSummary identifica el certificado del firmante
+type CertificateSummary struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
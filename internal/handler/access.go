@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/pkg/response"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accessDenied answers an "unauthorized access to ..." error: 403 when the user is a member
+// of the company but their role lacks the permission, 401 with message otherwise
+func accessDenied(c *fiber.Ctx, err error, message string) error {
+	var accessErr *domain.AccessError
+	if errors.As(err, &accessErr) && accessErr.Forbidden() {
+		return response.Forbidden(c, "Role "+accessErr.Role+" lacks the "+accessErr.Permission+" permission")
+	}
+	return response.Unauthorized(c, message)
+}
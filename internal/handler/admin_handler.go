@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/mail"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminHandler exposes the platform admin endpoints (users, tenants, impersonation and audit trail)
+type AdminHandler struct {
+	service *service.AdminService
+}
+
+func NewAdminHandler(db *database.Database, cfg *config.Config) *AdminHandler {
+	userRepo := repository.NewUserRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	return &AdminHandler{
+		service: service.NewAdminService(
+			repository.NewAdminRepository(db),
+			repository.NewPlanRepository(db),
+			userRepo,
+			sessionRepo,
+			service.NewUserService(userRepo),
+			service.NewAuthService(
+				userRepo,
+				sessionRepo,
+				repository.NewUserTokenRepository(db),
+				repository.NewTwoFactorRepository(db),
+				repository.NewLoginAttemptRepository(db),
+				repository.NewAuditRepository(db),
+				mail.New(&cfg.Mail),
+				cfg,
+			),
+		),
+	}
+}
+
+// GetUsers lists all users with pagination
+func (h *AdminHandler) GetUsers(c *fiber.Ctx) error {
+	page, pageSize := utils.ParsePaginationParams(c)
+
+	result, err := h.service.GetUsers(page, pageSize)
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Users retrieved successfully", result)
+}
+
+// GetUser gets any user by ID
+func (h *AdminHandler) GetUser(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	user, err := h.service.GetUser(id)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "User retrieved successfully", user)
+}
+
+// UpdateUser updates any user, including the platform admin role
+func (h *AdminHandler) UpdateUser(c *fiber.Ctx) error {
+	adminID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req domain.UpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateUpdateUser(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	user, err := h.service.UpdateUser(adminID, id, &req, c.IP())
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "User updated successfully", user)
+}
+
+// DeleteUser deactivates a user (soft delete) and closes their sessions
+func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
+	adminID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.service.DeleteUser(adminID, id, c.IP()); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "User deleted successfully", nil)
+}
+
+// Impersonate opens a short, non-renewable session as the user for support
+func (h *AdminHandler) Impersonate(c *fiber.Ctx) error {
+	adminID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req domain.ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateImpersonate(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	result, err := h.service.Impersonate(adminID, id, &req, sessionClient(c))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Impersonation session started", result)
+}
+
+// GetCompanies lists the usage of every company (?suspended=true for suspended only)
+func (h *AdminHandler) GetCompanies(c *fiber.Ctx) error {
+	page, pageSize := utils.ParsePaginationParams(c)
+
+	result, err := h.service.GetCompaniesUsage(c.QueryBool("suspended"), page, pageSize)
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Companies retrieved successfully", result)
+}
+
+// GetCompany gets the usage of a company
+func (h *AdminHandler) GetCompany(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	usage, err := h.service.GetCompanyUsage(id)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Company retrieved successfully", usage)
+}
+
+// SuspendCompany blocks signing and sending documents to DIAN for a company
+func (h *AdminHandler) SuspendCompany(c *fiber.Ctx) error {
+	adminID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	var req domain.SuspendCompanyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateSuspendCompany(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	usage, err := h.service.SuspendCompany(adminID, id, &req, c.IP())
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Company suspended successfully", usage)
+}
+
+// UnsuspendCompany lifts the suspension of a company
+func (h *AdminHandler) UnsuspendCompany(c *fiber.Ctx) error {
+	adminID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	usage, err := h.service.UnsuspendCompany(adminID, id, c.IP())
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Company unsuspended successfully", usage)
+}
+
+// GetPlans lists the plans and their quotas
+func (h *AdminHandler) GetPlans(c *fiber.Ctx) error {
+	plans, err := h.service.GetPlans()
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Plans retrieved successfully", plans)
+}
+
+// SetCompanyPlan changes the plan (document quota and API rate limit) of a company
+func (h *AdminHandler) SetCompanyPlan(c *fiber.Ctx) error {
+	adminID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	var req domain.SetCompanyPlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateSetCompanyPlan(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	usage, err := h.service.SetCompanyPlan(adminID, id, &req, c.IP())
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Company plan updated successfully", usage)
+}
+
+// GetCompanyUsageHistory gets the monthly documents and API calls of a company (?months=12)
+func (h *AdminHandler) GetCompanyUsageHistory(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	history, err := h.service.GetCompanyUsageHistory(id, c.QueryInt("months", 12))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Company usage retrieved successfully", history)
+}
+
+// GetActions lists the admin audit trail (?admin_id, ?action, ?target_type, ?target_id)
+func (h *AdminHandler) GetActions(c *fiber.Ctx) error {
+	page, pageSize := utils.ParsePaginationParams(c)
+
+	filter := domain.AdminActionFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+	if v := c.Query("admin_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return response.BadRequest(c, "Invalid admin_id")
+		}
+		filter.AdminID = id
+	}
+	if v := c.Query("target_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return response.BadRequest(c, "Invalid target_id")
+		}
+		filter.TargetID = id
+	}
+
+	result, err := h.service.GetActions(filter, page, pageSize)
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Admin actions retrieved successfully", result)
+}
+
+func (h *AdminHandler) handleError(c *fiber.Ctx, err error) error {
+	msg := err.Error()
+	switch {
+	case msg == "user not found":
+		return response.NotFound(c, errors.ErrUserNotFound.Message)
+	case msg == "company not found":
+		return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+	case msg == "plan not found":
+		return response.BadRequest(c, "Invalid plan, see GET /admin/plans")
+	case msg == "email already exists":
+		return response.BadRequest(c, errors.ErrEmailExists.Message)
+	case strings.HasPrefix(msg, "cannot "):
+		return response.BadRequest(c, msg)
+	case strings.HasPrefix(msg, "company not found or "):
+		return response.Conflict(c, msg)
+	}
+	return response.InternalServerError(c, errors.ErrInternalServer.Message)
+}
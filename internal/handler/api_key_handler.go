@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+}
+
+func NewAPIKeyHandler(db *database.Database) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service.NewAPIKeyService(repository.NewAPIKeyRepository(db), repository.NewCompanyRepository(db)),
+	}
+}
+
+// GetAll lists the API keys of a company (the keys themselves are never returned)
+func (h *APIKeyHandler) GetAll(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	keys, err := h.service.GetByCompanyID(companyID, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "API keys retrieved successfully", keys)
+}
+
+// Create creates an API key; the key is only shown in this response
+func (h *APIKeyHandler) Create(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	var req domain.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateCreateAPIKey(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	key, err := h.service.Create(companyID, userID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Created(c, "API key created successfully; store it now, it will not be shown again", key)
+}
+
+// Revoke revokes an API key of a company
+func (h *APIKeyHandler) Revoke(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+	keyID, err := strconv.ParseInt(c.Params("key_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid API key ID")
+	}
+
+	if err := h.service.Revoke(companyID, keyID, userID); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "API key revoked successfully", nil)
+}
+
+func (h *APIKeyHandler) handleError(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "company not found":
+		return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+	case "unauthorized access to company":
+		return accessDenied(c, err, errors.ErrUnauthorized.Message)
+	case "api key not found":
+		return response.NotFound(c, "API key not found")
+	}
+	return response.InternalServerError(c, errors.ErrInternalServer.Message)
+}
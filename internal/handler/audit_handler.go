@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditHandler exposes the change history recorded by the database audit triggers
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+func NewAuditHandler(db *database.Database) *AuditHandler {
+	return &AuditHandler{
+		service: service.NewAuditService(repository.NewAuditRepository(db), repository.NewCompanyRepository(db)),
+	}
+}
+
+// GetAll lists the changes to a company's business records
+// (?company_id=1&table=documents&record_id=10&operation=UPDATE&user_id=2&from=2025-01-01&to=2025-01-31)
+func (h *AuditHandler) GetAll(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	if filter.CompanyID == 0 {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+
+	page, pageSize := utils.ParsePaginationParams(c)
+	result, err := h.service.GetByCompanyID(filter, userID, page, pageSize)
+	if err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case "company not found":
+			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+		case "unauthorized access to company":
+			return accessDenied(c, err, errMsg)
+		case "invalid table":
+			return response.BadRequest(c, "Invalid table, use one of: "+strings.Join(domain.AuditedTables, ", "))
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Audit entries retrieved successfully", result)
+}
+
+// GetAllPlatform lists the whole audit trail, including authentication events (platform admins)
+func (h *AuditHandler) GetAllPlatform(c *fiber.Ctx) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	page, pageSize := utils.ParsePaginationParams(c)
+	result, err := h.service.GetAll(filter, page, pageSize)
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Audit entries retrieved successfully", result)
+}
+
+// parseAuditFilter reads the audit filters from the query string; to is inclusive
+func parseAuditFilter(c *fiber.Ctx) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		TableName: c.Query("table"),
+		Operation: strings.ToUpper(c.Query("operation")),
+	}
+	ids := []struct {
+		name   string
+		target *int64
+	}{
+		{"company_id", &filter.CompanyID},
+		{"record_id", &filter.RecordID},
+		{"user_id", &filter.UserID},
+	}
+	for _, id := range ids {
+		value := c.Query(id.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return filter, fmt.Errorf("invalid %s", id.name)
+		}
+		*id.target = parsed
+	}
+
+	from, err := parseDateQuery(c, "from", time.Time{})
+	if err != nil {
+		return filter, err
+	}
+	if !from.IsZero() {
+		filter.From = &from
+	}
+	to, err := parseDateQuery(c, "to", time.Time{})
+	if err != nil {
+		return filter, err
+	}
+	if !to.IsZero() {
+		end := to.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	return filter, nil
+}
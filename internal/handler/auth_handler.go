@@ -4,12 +4,15 @@ import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/mail"
 	"apidian-go/internal/repository"
 	"apidian-go/internal/service"
 	"apidian-go/pkg/errors"
 	"apidian-go/pkg/response"
 	"apidian-go/pkg/utils"
 	"apidian-go/pkg/validator"
+	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,14 +24,25 @@ type AuthHandler struct {
 
 func NewAuthHandler(db *database.Database, cfg *config.Config) *AuthHandler {
 	userRepo := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepo, &cfg.JWT)
+	sessionRepo := repository.NewSessionRepository(db)
+	authService := service.NewAuthService(
+		userRepo,
+		sessionRepo,
+		repository.NewUserTokenRepository(db),
+		repository.NewTwoFactorRepository(db),
+		repository.NewLoginAttemptRepository(db),
+		repository.NewAuditRepository(db),
+		mail.New(&cfg.Mail),
+		cfg,
+	)
 	return &AuthHandler{
 		authService: authService,
 		userRepo:    userRepo,
 	}
 }
 
-// Register registers a new user
+// Register registers a new user and sends the email verification link. When unverified
+// accounts cannot log in, no session is opened.
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req domain.RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -41,7 +55,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	}
 
 	// Register user
-	loginResp, err := h.authService.Register(&req)
+	user, loginResp, err := h.authService.Register(&req, sessionClient(c))
 	if err != nil {
 		if err.Error() == "email already exists" {
 			return response.BadRequest(c, errors.ErrEmailExists.Message)
@@ -49,10 +63,17 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
 
+	if loginResp == nil {
+		return response.Success(c, "User registered successfully, verify your email to log in", fiber.Map{
+			"user":                        user,
+			"email_verification_required": true,
+		})
+	}
+
 	return response.Success(c, "User registered successfully", loginResp)
 }
 
-// Login authenticates a user
+// Login authenticates a user. Users with 2FA get a challenge token to complete at /auth/login/2fa
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req domain.LoginRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -65,21 +86,254 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// Login
-	loginResp, err := h.authService.Login(&req)
+	loginResp, challenge, err := h.authService.Login(&req, sessionClient(c))
 	if err != nil {
-		return response.Unauthorized(c, errors.ErrInvalidCredentials.Message)
+		if throttle, ok := err.(*domain.LoginThrottleError); ok {
+			return loginThrottled(c, throttle)
+		}
+		switch err.Error() {
+		case "email not verified":
+			return response.Forbidden(c, emailNotVerifiedMessage)
+		case "invalid credentials":
+			return response.Unauthorized(c, errors.ErrInvalidCredentials.Message)
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	if challenge != nil {
+		return response.Success(c, "Two-factor authentication required", challenge)
 	}
 
 	return response.Success(c, "Login successful", loginResp)
 }
 
-// Logout logs out the user
+// LoginTwoFactor completes a login with a TOTP or recovery code
+func (h *AuthHandler) LoginTwoFactor(c *fiber.Ctx) error {
+	var req domain.TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateTwoFactorLogin(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	loginResp, err := h.authService.LoginTwoFactor(&req, sessionClient(c))
+	if err != nil {
+		if throttle, ok := err.(*domain.LoginThrottleError); ok {
+			return loginThrottled(c, throttle)
+		}
+		switch err.Error() {
+		case "invalid two-factor code":
+			return response.Unauthorized(c, "Invalid two-factor code")
+		case "invalid or expired challenge":
+			return response.Unauthorized(c, "Invalid or expired challenge, log in again")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Login successful", loginResp)
+}
+
+// Refresh rotates the refresh token and issues a new access token
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req domain.RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	// Validate request
+	if err := validator.ValidateRefreshToken(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	loginResp, err := h.authService.Refresh(req.RefreshToken, sessionClient(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token":
+			return response.Unauthorized(c, "Invalid or expired refresh token")
+		case "email not verified":
+			return response.Forbidden(c, emailNotVerifiedMessage)
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Token refreshed successfully", loginResp)
+}
+
+// VerifyEmail marks the user's email as verified using the token sent by email
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req domain.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateVerifyEmail(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		if err.Error() == "invalid or expired token" {
+			return response.BadRequest(c, "Invalid or expired verification token")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Email verified successfully", nil)
+}
+
+// ResendVerification sends a new verification link. The response is the same whether or not
+// the account exists.
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var req domain.EmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateEmailRequest(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	h.authService.ResendVerification(req.Email)
+
+	return response.Success(c, "If the account exists and is not verified, a verification link has been sent", nil)
+}
+
+// ForgotPassword sends a password reset link. The response is the same whether or not the
+// account exists.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req domain.EmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateEmailRequest(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	h.authService.ForgotPassword(req.Email)
+
+	return response.Success(c, "If the account exists, a password reset link has been sent", nil)
+}
+
+// ResetPassword sets a new password using the token sent by email and closes every session
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req domain.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateResetPassword(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	if err := h.authService.ResetPassword(&req); err != nil {
+		if err.Error() == "invalid or expired token" {
+			return response.BadRequest(c, "Invalid or expired reset token")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Password reset successfully, log in with the new password", nil)
+}
+
+// UnlockAccount lifts a lockout caused by failed logins using the token sent by email
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	var req domain.UnlockAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateUnlockAccount(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	if err := h.authService.UnlockAccount(req.Token); err != nil {
+		if err.Error() == "invalid or expired token" {
+			return response.BadRequest(c, "Invalid or expired unlock token")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Account unlocked successfully", nil)
+}
+
+// Logout closes the current session and revokes its access token
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// With JWT we don't need to invalidate the token on the server
-	// The client simply needs to delete the token
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+	sessionID, err := utils.GetSessionID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	if err := h.authService.Logout(userID, sessionID); err != nil {
+		if err.Error() == "session not found" {
+			return response.Unauthorized(c, "Session already closed")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
 	return response.Success(c, "Logout successful", nil)
 }
 
+// LogoutAll closes every session of the user (all devices)
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	closed, err := h.authService.LogoutAll(userID)
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Logged out from all devices", fiber.Map{
+		"sessions_closed": closed,
+	})
+}
+
+// Sessions lists the active sessions of the user
+func (h *AuthHandler) Sessions(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+	sessionID, _ := utils.GetSessionID(c)
+
+	sessions, err := h.authService.ListSessions(userID, sessionID)
+	if err != nil {
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeSession closes one of the user's sessions
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid session ID")
+	}
+
+	if err := h.authService.RevokeSession(userID, id); err != nil {
+		if err.Error() == "session not found" {
+			return response.NotFound(c, "Session not found")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Session revoked successfully", nil)
+}
+
 // Me gets the authenticated user's profile
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	// Get user_id from context
@@ -96,3 +350,65 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 
 	return response.Success(c, "Profile retrieved successfully", user)
 }
+
+// UpdateProfile updates the authenticated user's name or email. Changing the email requires
+// current_password and sends a verification link to the new address.
+func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	var req domain.UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateUpdateProfile(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	user, err := h.authService.UpdateProfile(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			return response.NotFound(c, errors.ErrUserNotFound.Message)
+		case "email already exists":
+			return response.BadRequest(c, errors.ErrEmailExists.Message)
+		case "current password is required to change the email":
+			return response.BadRequest(c, "current_password is required to change the email")
+		case "current password is incorrect":
+			return response.BadRequest(c, "Current password is incorrect")
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Profile updated successfully", user)
+}
+
+// emailNotVerifiedMessage se retorna cuando UNVERIFIED_LOGIN impide el login de la cuenta
+const emailNotVerifiedMessage = "Email not verified, check your inbox or request a new link at /auth/resend-verification"
+
+// loginThrottled responde 429 con Retry-After cuando la cuenta o la IP tienen demasiados
+// logins fallidos
+func loginThrottled(c *fiber.Ctx, err *domain.LoginThrottleError) error {
+	seconds := int(math.Ceil(err.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	switch {
+	case err.Locked && err.Scope == domain.LoginScopeAccount:
+		return response.TooManyRequests(c, "Account temporarily locked after too many failed logins, "+
+			"use the link sent by email to unlock it or try again in "+strconv.Itoa(seconds)+" seconds")
+	case err.Locked:
+		return response.TooManyRequests(c, "Too many failed logins from this address, try again in "+
+			strconv.Itoa(seconds)+" seconds")
+	}
+	return response.TooManyRequests(c, "Too many failed logins, try again in "+strconv.Itoa(seconds)+" seconds")
+}
+
+// sessionClient identifica el dispositivo que inicia o renueva la sesión
+func sessionClient(c *fiber.Ctx) domain.SessionClient {
+	return domain.SessionClient{
+		UserAgent: c.Get("User-Agent"),
+		IPAddress: c.IP(),
+	}
+}
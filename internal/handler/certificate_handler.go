@@ -4,12 +4,15 @@ import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/internal/repository"
 	"apidian-go/internal/service"
+	"apidian-go/internal/service/keyring"
 	"apidian-go/pkg/errors"
 	"apidian-go/pkg/response"
 	"apidian-go/pkg/utils"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,7 +24,9 @@ type CertificateHandler struct {
 func NewCertificateHandler(db *database.Database, cfg *config.Config) *CertificateHandler {
 	certRepo := repository.NewCertificateRepository(db)
 	companyRepo := repository.NewCompanyRepository(db)
-	certService := service.NewCertificateService(certRepo, companyRepo, &cfg.Storage)
+	store := storage.New(&cfg.Storage)
+	keys := keyring.New(repository.NewDataKeyRepository(db), store)
+	certService := service.NewCertificateService(certRepo, companyRepo, store, keys)
 
 	return &CertificateHandler{
 		service: certService,
@@ -62,7 +67,10 @@ func (h *CertificateHandler) Create(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if errMsg == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
+		}
+		if errMsg == "two-factor authentication required" {
+			return response.Forbidden(c, twoFactorRequiredMessage)
 		}
 		if errMsg == "certificate must be valid base64 encoded data" {
 			return response.BadRequest(c, "Certificate must be valid base64 encoded data")
@@ -70,6 +78,15 @@ func (h *CertificateHandler) Create(c *fiber.Ctx) error {
 		if errMsg == "certificate size must not exceed 5MB" {
 			return response.BadRequest(c, "Certificate size must not exceed 5MB")
 		}
+		if errMsg == "invalid certificate or password" {
+			return response.BadRequest(c, "Invalid certificate or password")
+		}
+		if strings.HasPrefix(errMsg, "activation date") {
+			return response.BadRequest(c, errMsg)
+		}
+		if strings.HasPrefix(errMsg, "certificate expired") {
+			return response.BadRequest(c, "Certificate expired"+strings.TrimPrefix(errMsg, "certificate expired"))
+		}
 
 		return response.InternalServerError(c, "Error uploading certificate: "+errMsg)
 	}
@@ -103,7 +120,7 @@ func (h *CertificateHandler) GetByCompanyID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		if err.Error() == "certificate not found" {
 			return response.NotFound(c, "Certificate not found")
@@ -140,7 +157,7 @@ func (h *CertificateHandler) GetAllByCompanyID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -174,10 +191,13 @@ func (h *CertificateHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if err.Error() == "unauthorized access to certificate" {
-			return response.Unauthorized(c, "Unauthorized access to certificate")
+			return accessDenied(c, err, "Unauthorized access to certificate")
+		}
+		if err.Error() == "two-factor authentication required" {
+			return response.Forbidden(c, twoFactorRequiredMessage)
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
 
-	return response.Success(c, "Certificate deleted successfully", nil)
+	return response.Success(c, "Certificate disabled successfully", nil)
 }
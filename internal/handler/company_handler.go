@@ -4,6 +4,7 @@ import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/internal/repository"
 	"apidian-go/internal/service"
 	"apidian-go/pkg/errors"
@@ -11,7 +12,7 @@ import (
 	"apidian-go/pkg/utils"
 	"apidian-go/pkg/validator"
 	"encoding/json"
-	"os"
+	"io"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -21,13 +22,13 @@ import (
 
 type CompanyHandler struct {
 	service *service.CompanyService
-	storage *config.StorageConfig
+	storage storage.Storage
 }
 
 func NewCompanyHandler(db *database.Database, cfg *config.Config) *CompanyHandler {
 	repo := repository.NewCompanyRepository(db)
 	svc := service.NewCompanyService(repo)
-	return &CompanyHandler{service: svc, storage: &cfg.Storage}
+	return &CompanyHandler{service: svc, storage: storage.New(&cfg.Storage)}
 }
 
 func (h *CompanyHandler) parseBody(c *fiber.Ctx, out any) error {
@@ -60,21 +61,23 @@ func (h *CompanyHandler) saveCompanyLogo(c *fiber.Ctx, nit string) (*string, err
 		return nil, fiber.NewError(fiber.StatusBadRequest, "Unsupported logo file type. Use .png, .jpg, .jpeg")
 	}
 
-	// Guardar logo en storage/app/companies/{nit}/profile/logo.png
-	logoAbs := h.storage.CompanyLogoPath(nit)
-	// Cambiar extensión si no es .png
-	if ext != ".png" {
-		logoAbs = strings.TrimSuffix(logoAbs, ".png") + ext
+	// Guardar logo en companies/{nit}/profile/logo.{ext}
+	src, err := file.Open()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Failed to read logo file")
 	}
-	logoRel := filepath.ToSlash(filepath.Join("companies", nit, "profile", "logo"+ext))
-	
-	if err := os.MkdirAll(filepath.Dir(logoAbs), 0755); err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to create logo directory")
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Failed to read logo file")
 	}
-	if err := c.SaveFile(file, logoAbs); err != nil {
+
+	logoKey := storage.CompanyLogoKey(nit, ext)
+	if err := h.storage.Put(logoKey, data); err != nil {
 		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to save logo file")
 	}
-	return &logoRel, nil
+	return &logoKey, nil
 }
 
 // GetAll gets all companies for the authenticated user
@@ -118,7 +121,7 @@ func (h *CompanyHandler) GetByID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -214,13 +217,13 @@ func (h *CompanyHandler) Update(c *fiber.Ctx) error {
 	}
 
 	// Get company first to validate permissions and get NIT for logo path
-	company, err := h.service.GetByID(id, userID)
+	company, err := h.service.Authorize(id, userID, domain.PermissionCompanyWrite)
 	if err != nil {
 		if err.Error() == "company not found" {
 			return response.NotFound(c, "Company not found")
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -259,7 +262,7 @@ func (h *CompanyHandler) Update(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if errMsg == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		
 		// Foreign key errors
@@ -310,7 +313,7 @@ func (h *CompanyHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		return response.InternalServerError(c, err.Error())
 	}
@@ -318,6 +321,43 @@ func (h *CompanyHandler) Delete(c *fiber.Ctx) error {
 	return response.Success(c, "Company deleted successfully", nil)
 }
 
+// UpdateTwoFactorPolicy requires (or stops requiring) 2FA to sign, send to DIAN and manage certificates
+func (h *CompanyHandler) UpdateTwoFactorPolicy(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	var req domain.TwoFactorPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateTwoFactorPolicy(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	company, err := h.service.SetTwoFactorPolicy(id, userID, *req.RequireTwoFactor)
+	if err != nil {
+		switch {
+		case err.Error() == "company not found":
+			return response.NotFound(c, "Company not found")
+		case err.Error() == "unauthorized access to company":
+			return accessDenied(c, err, "Unauthorized access to company")
+		case strings.HasPrefix(err.Error(), "cannot "):
+			return response.BadRequest(c, err.Error())
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Two-factor policy updated successfully", company)
+}
+
 // UploadCertificate is deprecated - use POST /api/v1/certificates instead
 // This endpoint is kept for backward compatibility
 func (h *CompanyHandler) UploadCertificate(c *fiber.Ctx) error {
@@ -18,16 +18,19 @@ import (
 type CustomerHandler struct {
 	service        *service.CustomerService
 	companyService *service.CompanyService
+	importService  *service.CustomerImportService
 }
 
 func NewCustomerHandler(db *database.Database) *CustomerHandler {
 	customerRepo := repository.NewCustomerRepository(db)
 	companyRepo := repository.NewCompanyRepository(db)
-	customerService := service.NewCustomerService(customerRepo)
+	customerService := service.NewCustomerService(customerRepo, companyRepo)
 	companyService := service.NewCompanyService(companyRepo)
+	importService := service.NewCustomerImportService(customerRepo, companyRepo, repository.NewCatalogRepository(db))
 	return &CustomerHandler{
 		service:        customerService,
 		companyService: companyService,
+		importService:  importService,
 	}
 }
 
@@ -57,7 +60,7 @@ func (h *CustomerHandler) GetAll(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.BadRequest(c, err.Error())
 	}
@@ -93,7 +96,7 @@ func (h *CustomerHandler) GetByID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCustomerNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to customer" {
-			return response.Unauthorized(c, "Unauthorized access to customer")
+			return accessDenied(c, err, "Unauthorized access to customer")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -125,6 +128,13 @@ func (h *CustomerHandler) Create(c *fiber.Ctx) error {
 	if err != nil {
 		errMsg := err.Error()
 
+		if errMsg == "company not found" {
+			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+		}
+		if errMsg == "unauthorized access to company" {
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
+		}
+
 		// Duplicate customer error
 		if errMsg == "customer with identification "+req.IdentificationNumber+" already exists for this company" {
 			return response.Conflict(c, "A customer with identification "+req.IdentificationNumber+" already exists for this company")
@@ -199,7 +209,7 @@ func (h *CustomerHandler) Update(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCustomerNotFound.Message)
 		}
 		if errMsg == "unauthorized access to customer" {
-			return response.Unauthorized(c, "Unauthorized access to customer")
+			return accessDenied(c, err, "Unauthorized access to customer")
 		}
 
 		// Foreign key errors
@@ -243,10 +253,20 @@ func (h *CustomerHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCustomerNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to customer" {
-			return response.Unauthorized(c, "Unauthorized access to customer")
+			return accessDenied(c, err, "Unauthorized access to customer")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
 
 	return response.Success(c, "Customer deleted successfully", nil)
 }
+
+// Import creates or updates customers from a CSV/XLSX file (multipart "file")
+func (h *CustomerHandler) Import(c *fiber.Ctx) error {
+	return handleBulkImport(c, h.importService, "Customers")
+}
+
+// Export downloads all customers of a company in the import format
+func (h *CustomerHandler) Export(c *fiber.Ctx) error {
+	return handleBulkExport(c, h.importService, "clientes")
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/spreadsheet"
+	"apidian-go/pkg/utils"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxImportFileSize limita el tamaño de los archivos de importación (10MB)
+const maxImportFileSize = 10 * 1024 * 1024
+
+// readImportFile lee el archivo CSV/XLSX enviado en el campo multipart "file"
+func readImportFile(c *fiber.Ctx) (string, []byte, error) {
+	file, err := c.FormFile("file")
+	if err != nil || file == nil {
+		return "", nil, fmt.Errorf("The 'file' field is required")
+	}
+	if file.Size > maxImportFileSize {
+		return "", nil, fmt.Errorf("File size must not exceed 10MB")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("Unable to read file")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("Unable to read file")
+	}
+
+	return file.Filename, data, nil
+}
+
+// bulkImporter es implementado por los servicios de importación/exportación de clientes y productos
+type bulkImporter interface {
+	Import(companyID int64, filename string, data []byte, dryRun bool, userID int64) (*domain.ImportReport, error)
+	Export(companyID int64, format string, userID int64) ([]byte, error)
+}
+
+// handleBulkImport procesa POST /{entity}/import?company_id=1&dry_run=true
+func handleBulkImport(c *fiber.Ctx, svc bulkImporter, entity string) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Query("company_id", c.FormValue("company_id")), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "company_id is required")
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run", c.FormValue("dry_run")); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			return response.BadRequest(c, "Invalid dry_run, use true or false")
+		}
+	}
+
+	filename, data, err := readImportFile(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	report, err := svc.Import(companyID, filename, data, dryRun, userID)
+	if err != nil {
+		return handleBulkError(c, err)
+	}
+
+	if dryRun {
+		return response.Success(c, entity+" import validated", report)
+	}
+	return response.Success(c, entity+" import completed", report)
+}
+
+// handleBulkExport procesa GET /{entity}/export?company_id=1&format=csv|xlsx
+func handleBulkExport(c *fiber.Ctx, svc bulkImporter, basename string) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Query("company_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+
+	format := strings.ToLower(c.Query("format", spreadsheet.FormatCSV))
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		return response.BadRequest(c, "Invalid format, use csv or xlsx")
+	}
+
+	data, err := svc.Export(companyID, format, userID)
+	if err != nil {
+		return handleBulkError(c, err)
+	}
+
+	c.Set("Content-Type", spreadsheet.ContentType(format))
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%d.%s\"", basename, companyID, format))
+
+	return c.Send(data)
+}
+
+func handleBulkError(c *fiber.Ctx, err error) error {
+	errMsg := err.Error()
+	switch {
+	case errMsg == "company not found":
+		return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+	case errMsg == "unauthorized access to company":
+		return accessDenied(c, err, errors.ErrUnauthorized.Message)
+	case strings.HasPrefix(errMsg, "invalid file"):
+		return response.BadRequest(c, errMsg)
+	}
+	return response.InternalServerError(c, errMsg)
+}
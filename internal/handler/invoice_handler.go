@@ -4,13 +4,17 @@ import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/internal/repository"
 	"apidian-go/internal/service"
 	"apidian-go/internal/service/invoice"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/internal/service/prevalidation"
 	"apidian-go/pkg/errors"
 	"apidian-go/pkg/response"
 	"apidian-go/pkg/utils"
 	"apidian-go/pkg/validator"
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -20,6 +24,7 @@ import (
 type InvoiceHandler struct {
 	service        *invoice.InvoiceService
 	companyService *service.CompanyService
+	importService  *service.InvoiceImportService
 }
 
 // formatMoney formatea un valor float64 a string con 2 decimales
@@ -42,14 +47,27 @@ func NewInvoiceHandler(db *database.Database, cfg *config.Config) *InvoiceHandle
 		resolutionRepo,
 		productRepo,
 		certificateRepo,
-		&cfg.Storage,
+		keyring.New(repository.NewDataKeyRepository(db), storage.New(&cfg.Storage)),
+		prevalidation.New(cfg.Invoice.XSDPath),
 		cfg.Invoice.KeepUnsignedXML,
 	)
 	companyService := service.NewCompanyService(companyRepo)
 
+	// La importación masiva crea borradores a través de InvoiceService.Create
+	importService := service.NewInvoiceImportService(
+		repository.NewInvoiceImportRepository(db),
+		companyRepo,
+		customerRepo,
+		productRepo,
+		resolutionRepo,
+		repository.NewCatalogRepository(db),
+		invoiceService,
+	)
+
 	return &InvoiceHandler{
 		service:        invoiceService,
 		companyService: companyService,
+		importService:  importService,
 	}
 }
 
@@ -81,7 +99,16 @@ func (h *InvoiceHandler) Create(c *fiber.Ctx) error {
 		if err.Error() == "unauthorized access to company" || 
 		   err.Error() == "customer does not belong to company" ||
 		   err.Error() == "resolution does not belong to company" {
-			return response.Unauthorized(c, err.Error())
+			return accessDenied(c, err, err.Error())
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			return response.BadRequest(c, err.Error())
+		}
+		if strings.HasPrefix(err.Error(), "resolution unavailable") {
+			return response.UnprocessableEntity(c, err.Error(), nil)
+		}
+		if err.Error() == "monthly document quota exceeded" {
+			return response.TooManyRequests(c, documentQuotaMessage)
 		}
 		// TEMPORAL: Mostrar error completo para debugging
 		return response.InternalServerError(c, err.Error())
@@ -112,7 +139,7 @@ func (h *InvoiceHandler) GetByID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrInvoiceNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -146,7 +173,7 @@ func (h *InvoiceHandler) GetAll(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.BadRequest(c, err.Error())
 	}
@@ -163,7 +190,7 @@ func (h *InvoiceHandler) GetAll(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -202,7 +229,7 @@ func (h *InvoiceHandler) Update(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrInvoiceNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
 		}
 		if err.Error() == "only draft invoices can be updated" {
 			return response.BadRequest(c, "Only draft invoices can be updated")
@@ -234,7 +261,7 @@ func (h *InvoiceHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrInvoiceNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
 		}
 		if err.Error() == "only draft invoices can be deleted" {
 			return response.BadRequest(c, "Only draft invoices can be deleted")
@@ -266,11 +293,20 @@ func (h *InvoiceHandler) Sign(c *fiber.Ctx) error {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		if err.Error() == "company is suspended" {
+			return response.Forbidden(c, "Company is suspended: signing and sending to DIAN are disabled")
+		}
+		if err.Error() == "two-factor authentication required" {
+			return response.Forbidden(c, twoFactorRequiredMessage)
 		}
 		if err.Error() == "only draft invoices can be signed" {
 			return response.BadRequest(c, "Only draft invoices can be signed")
 		}
+		if isCertificateValidityError(err) {
+			return response.UnprocessableEntity(c, err.Error(), nil)
+		}
 		return response.InternalServerError(c, err.Error())
 	}
 
@@ -335,11 +371,23 @@ func (h *InvoiceHandler) SendToDIAN(c *fiber.Ctx) error {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		if err.Error() == "company is suspended" {
+			return response.Forbidden(c, "Company is suspended: signing and sending to DIAN are disabled")
+		}
+		if err.Error() == "two-factor authentication required" {
+			return response.Forbidden(c, twoFactorRequiredMessage)
 		}
 		if err.Error() == "only signed invoices can be sent to DIAN" {
 			return response.BadRequest(c, "Only signed invoices can be sent to DIAN")
 		}
+		if isCertificateValidityError(err) {
+			return response.UnprocessableEntity(c, err.Error(), nil)
+		}
+		if report, ok := prevalidation.ReportFrom(err); ok {
+			return response.UnprocessableEntity(c, err.Error(), report)
+		}
 		// Verificar si es un rechazo de DIAN (error de negocio)
 		if strings.HasPrefix(err.Error(), "DIAN_REJECTION:") {
 			// Extraer el mensaje después de "DIAN_REJECTION: "
@@ -357,6 +405,37 @@ func (h *InvoiceHandler) SendToDIAN(c *fiber.Ctx) error {
 	return response.Success(c, "Invoice sent to DIAN successfully", nil)
 }
 
+// Prevalidate validates the invoice XML locally (XSD + DIAN rules) without sending it
+func (h *InvoiceHandler) Prevalidate(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get invoice ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	report, err := h.service.Prevalidate(id, userID)
+	if err != nil {
+		if err.Error() == "invoice not found" {
+			return response.NotFound(c, "Invoice not found")
+		}
+		if err.Error() == "unauthorized access to invoice" {
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		if strings.HasPrefix(err.Error(), "invoice validation failed") {
+			return response.UnprocessableEntity(c, err.Error(), nil)
+		}
+		return response.InternalServerError(c, err.Error())
+	}
+
+	return response.Success(c, "Invoice prevalidated", report)
+}
+
 // GeneratePDF generates the PDF for a signed invoice
 func (h *InvoiceHandler) GeneratePDF(c *fiber.Ctx) error {
 	// Get user_id from context
@@ -378,7 +457,7 @@ func (h *InvoiceHandler) GeneratePDF(c *fiber.Ctx) error {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
 		}
 		return response.InternalServerError(c, err.Error())
 	}
@@ -407,7 +486,13 @@ func (h *InvoiceHandler) GenerateAttachedDocument(c *fiber.Ctx) error {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		if err.Error() == "company is suspended" {
+			return response.Forbidden(c, "Company is suspended: signing and sending to DIAN are disabled")
+		}
+		if err.Error() == "two-factor authentication required" {
+			return response.Forbidden(c, twoFactorRequiredMessage)
 		}
 		return response.InternalServerError(c, err.Error())
 	}
@@ -430,20 +515,25 @@ func (h *InvoiceHandler) DownloadZIP(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid ID")
 	}
 
-	// Get ZIP path
-	zipPath, err := h.service.DownloadInvoiceZip(id, userID)
+	// Get ZIP content
+	zipData, filename, err := h.service.DownloadInvoiceZip(id, userID)
 	if err != nil {
 		if err.Error() == "invoice not found" {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		if err.Error() == "ZIP file not found in storage" {
+			return response.NotFound(c, err.Error())
 		}
 		return response.InternalServerError(c, err.Error())
 	}
 
 	// Send file
-	return c.SendFile(zipPath)
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Send(zipData)
 }
 
 // GetXML returns the signed XML of an invoice
@@ -468,7 +558,7 @@ func (h *InvoiceHandler) GetXML(c *fiber.Ctx) error {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
 		}
 		return response.InternalServerError(c, err.Error())
 	}
@@ -478,8 +568,8 @@ func (h *InvoiceHandler) GetXML(c *fiber.Ctx) error {
 	return c.Send(xmlContent)
 }
 
-// GetPDF returns the PDF of an invoice
-func (h *InvoiceHandler) GetPDF(c *fiber.Ctx) error {
+// GetInvoiceStatus consulta el estado de una factura en DIAN
+func (h *InvoiceHandler) GetInvoiceStatus(c *fiber.Ctx) error {
 	// Get user_id from context
 	userID, err := utils.GetUserID(c)
 	if err != nil {
@@ -493,62 +583,145 @@ func (h *InvoiceHandler) GetPDF(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid ID")
 	}
 
-	// Get PDF path
-	pdfPath, err := h.service.GetInvoicePDF(id, userID)
-	if err != nil {
+	// Parse request body
+	var req struct {
+		TrackId string `json:"track_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if req.TrackId == "" {
+		return response.BadRequest(c, "track_id is required")
+	}
+
+	// Call service
+	if err := h.service.GetInvoiceStatus(id, req.TrackId, userID); err != nil {
 		if err.Error() == "invoice not found" {
 			return response.NotFound(c, "Invoice not found")
 		}
 		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		if strings.Contains(err.Error(), "DIAN rejected") {
+			return response.BadRequest(c, err.Error())
 		}
 		return response.InternalServerError(c, err.Error())
 	}
 
-	// Send file
-	return c.SendFile(pdfPath)
+	return response.Success(c, "Invoice status updated successfully", nil)
 }
 
-// GetInvoiceStatus consulta el estado de una factura en DIAN
-func (h *InvoiceHandler) GetInvoiceStatus(c *fiber.Ctx) error {
+// Import importa facturas borrador desde un archivo CSV/XLSX (multipart "file").
+// Por defecto es dry-run: solo retorna el reporte de validación por fila.
+// Con dry_run=false crea un trabajo asíncrono y retorna 202 con su id.
+func (h *InvoiceHandler) Import(c *fiber.Ctx) error {
 	// Get user_id from context
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return response.Unauthorized(c, "User not authenticated")
 	}
 
-	// Get invoice ID
-	idStr := c.Params("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	// company_id puede venir en query o en el formulario
+	companyIDStr := c.Query("company_id", c.FormValue("company_id"))
+	if companyIDStr == "" {
+		return response.BadRequest(c, "company_id is required")
+	}
+	companyID, err := strconv.ParseInt(companyIDStr, 10, 64)
 	if err != nil {
-		return response.BadRequest(c, "Invalid ID")
+		return response.BadRequest(c, "Invalid company_id")
 	}
 
-	// Parse request body
-	var req struct {
-		TrackId string `json:"track_id"`
+	dryRun := true
+	if value := c.Query("dry_run", c.FormValue("dry_run")); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return response.BadRequest(c, "Invalid dry_run, use true or false")
+		}
 	}
-	if err := c.BodyParser(&req); err != nil {
-		return response.BadRequest(c, "Invalid request body")
+	skipInvalid, _ := strconv.ParseBool(c.Query("skip_invalid", c.FormValue("skip_invalid")))
+
+	filename, data, err := readImportFile(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
 	}
 
-	if req.TrackId == "" {
-		return response.BadRequest(c, "track_id is required")
+	if dryRun {
+		report, err := h.importService.Validate(companyID, filename, data, userID)
+		if err != nil {
+			return h.handleImportError(c, err)
+		}
+		return response.Success(c, "Import file validated", domain.InvoiceImportResponse{
+			DryRun: true,
+			Report: report,
+		})
 	}
 
-	// Call service
-	if err := h.service.GetInvoiceStatus(id, req.TrackId, userID); err != nil {
-		if err.Error() == "invoice not found" {
-			return response.NotFound(c, "Invoice not found")
+	report, job, err := h.importService.Import(companyID, filename, data, skipInvalid, userID)
+	if err != nil {
+		if report != nil {
+			return response.UnprocessableEntity(c, err.Error(), domain.InvoiceImportResponse{
+				DryRun: false,
+				Report: report,
+			})
 		}
-		if err.Error() == "unauthorized access to invoice" {
-			return response.Unauthorized(c, "Unauthorized access to invoice")
+		return h.handleImportError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(response.Response{
+		Success: true,
+		Message: "Import job started",
+		Data: domain.InvoiceImportResponse{
+			DryRun: false,
+			Report: report,
+			Job:    job,
+		},
+	})
+}
+
+// GetImportJob consulta el progreso de un trabajo de importación
+func (h *InvoiceHandler) GetImportJob(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	jobID, err := strconv.ParseInt(c.Params("job_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid job ID")
+	}
+
+	job, err := h.importService.GetJob(jobID, userID)
+	if err != nil {
+		if err.Error() == "import job not found" || err.Error() == "company not found" {
+			return response.NotFound(c, "Import job not found")
 		}
-		if strings.Contains(err.Error(), "DIAN rejected") {
-			return response.BadRequest(c, err.Error())
+		if err.Error() == "unauthorized access to import job" {
+			return accessDenied(c, err, "Unauthorized access to import job")
 		}
 		return response.InternalServerError(c, err.Error())
 	}
 
-	return response.Success(c, "Invoice status updated successfully", nil)
+	return response.Success(c, "Import job retrieved successfully", job)
+}
+
+func (h *InvoiceHandler) handleImportError(c *fiber.Ctx, err error) error {
+	errMsg := err.Error()
+	switch {
+	case errMsg == "company not found":
+		return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+	case errMsg == "unauthorized access to company":
+		return accessDenied(c, err, errors.ErrUnauthorized.Message)
+	case strings.HasPrefix(errMsg, "invalid file"):
+		return response.BadRequest(c, errMsg)
+	}
+	return response.InternalServerError(c, "Error importing invoices: "+errMsg)
 }
+
+// isCertificateValidityError detecta el rechazo por certificado vencido o aún no vigente
+func isCertificateValidityError(err error) bool {
+	return strings.HasPrefix(err.Error(), "certificate expired") || strings.HasPrefix(err.Error(), "certificate not valid")
+}
+
+const documentQuotaMessage = "The company reached the monthly document quota of its plan, contact the platform administrator to upgrade it"
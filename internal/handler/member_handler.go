@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MemberHandler struct {
+	service *service.MemberService
+}
+
+func NewMemberHandler(db *database.Database) *MemberHandler {
+	return &MemberHandler{
+		service: service.NewMemberService(
+			repository.NewMemberRepository(db),
+			repository.NewCompanyRepository(db),
+			repository.NewUserRepository(db),
+		),
+	}
+}
+
+// GetAll lists the members of a company with their roles
+func (h *MemberHandler) GetAll(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	members, err := h.service.GetByCompanyID(companyID, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Members retrieved successfully", members)
+}
+
+// Update changes the role of a member
+func (h *MemberHandler) Update(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+	memberUserID, err := strconv.ParseInt(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req domain.UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateUpdateMember(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	member, err := h.service.UpdateRole(companyID, memberUserID, userID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Member updated successfully", member)
+}
+
+// Remove removes a member from a company; members may also remove themselves
+func (h *MemberHandler) Remove(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+	memberUserID, err := strconv.ParseInt(c.Params("user_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.service.Remove(companyID, memberUserID, userID); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Member removed successfully", nil)
+}
+
+// Invite invites an email to a company with a role
+func (h *MemberHandler) Invite(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	var req domain.InviteMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateInviteMember(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	invitation, err := h.service.Invite(companyID, userID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Created(c, "Invitation created successfully", invitation)
+}
+
+// GetInvitations lists the pending invitations of a company
+func (h *MemberHandler) GetInvitations(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+
+	invitations, err := h.service.GetInvitations(companyID, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Invitations retrieved successfully", invitations)
+}
+
+// RevokeInvitation revokes a pending invitation of a company
+func (h *MemberHandler) RevokeInvitation(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company ID")
+	}
+	invitationID, err := strconv.ParseInt(c.Params("invitation_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid invitation ID")
+	}
+
+	if err := h.service.RevokeInvitation(companyID, invitationID, userID); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Invitation revoked successfully", nil)
+}
+
+// GetMyInvitations lists the pending invitations addressed to the authenticated user
+func (h *MemberHandler) GetMyInvitations(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	invitations, err := h.service.GetMyInvitations(userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Invitations retrieved successfully", invitations)
+}
+
+// AcceptInvitation joins the company of an invitation addressed to the authenticated user
+func (h *MemberHandler) AcceptInvitation(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	invitationID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid invitation ID")
+	}
+
+	member, err := h.service.AcceptInvitation(invitationID, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Invitation accepted successfully", member)
+}
+
+// DeclineInvitation declines an invitation addressed to the authenticated user
+func (h *MemberHandler) DeclineInvitation(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	invitationID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid invitation ID")
+	}
+
+	if err := h.service.DeclineInvitation(invitationID, userID); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Invitation declined successfully", nil)
+}
+
+func (h *MemberHandler) handleError(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "company not found":
+		return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+	case "unauthorized access to company":
+		return accessDenied(c, err, errors.ErrUnauthorized.Message)
+	case "user not found":
+		return response.Unauthorized(c, "User not authenticated")
+	case "member not found":
+		return response.NotFound(c, "Member not found")
+	case "invitation not found":
+		return response.NotFound(c, "Invitation not found or expired")
+	case "cannot change the owner role", "cannot remove the company owner":
+		return response.BadRequest(c, err.Error())
+	case "user is already a member", "invitation already pending":
+		return response.Conflict(c, err.Error())
+	case "email not verified":
+		return response.Forbidden(c, "Verify your email first to see or answer invitations; request a new link at /auth/resend-verification")
+	}
+	return response.InternalServerError(c, errors.ErrInternalServer.Message)
+}
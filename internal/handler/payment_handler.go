@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/internal/service/pdf"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentHandler struct {
+	service    *service.PaymentService
+	pdfService *pdf.PDFInvoiceService
+}
+
+func NewPaymentHandler(db *database.Database, cfg *config.Config) *PaymentHandler {
+	paymentService := service.NewPaymentService(
+		repository.NewPaymentRepository(db),
+		repository.NewCompanyRepository(db),
+		repository.NewCustomerRepository(db),
+	)
+
+	return &PaymentHandler{
+		service:    paymentService,
+		pdfService: pdf.NewPDFInvoiceService(storage.New(&cfg.Storage)),
+	}
+}
+
+// Create registers a payment against an invoice
+func (h *PaymentHandler) Create(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	var req domain.CreatePaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	// Validate request
+	if err := validator.ValidateCreatePayment(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	payment, err := h.service.Create(&req, userID)
+	if err != nil {
+		errMsg := err.Error()
+		if strings.HasPrefix(errMsg, "payment exceeds invoice balance") ||
+			strings.HasSuffix(errMsg, "does not accept payments") {
+			return response.Conflict(c, errMsg)
+		}
+		if strings.HasPrefix(errMsg, "invalid ") || strings.HasPrefix(errMsg, "payment_date") {
+			return response.BadRequest(c, errMsg)
+		}
+		return h.handleError(c, err, "Error registering payment")
+	}
+
+	return response.Created(c, "Payment registered successfully", payment)
+}
+
+// GetAll gets the payments of a company (?company_id=1&customer_id=2)
+func (h *PaymentHandler) GetAll(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Query("company_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+
+	var customerID int64
+	if value := c.Query("customer_id"); value != "" {
+		customerID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return response.BadRequest(c, "Invalid customer_id")
+		}
+	}
+
+	// Get pagination parameters
+	page, pageSize := utils.ParsePaginationParams(c)
+
+	payments, err := h.service.GetByCompanyID(companyID, customerID, userID, page, pageSize)
+	if err != nil {
+		return h.handleError(c, err, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Payments retrieved successfully", payments)
+}
+
+// Delete removes a payment registered by mistake
+func (h *PaymentHandler) Delete(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	if err := h.service.Delete(id, userID); err != nil {
+		return h.handleError(c, err, "Error deleting payment")
+	}
+
+	return response.Success(c, "Payment deleted successfully", nil)
+}
+
+// GetInvoicePayments gets balance, payment status and payments of an invoice
+func (h *PaymentHandler) GetInvoicePayments(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	result, err := h.service.GetInvoicePayments(id, userID)
+	if err != nil {
+		return h.handleError(c, err, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Invoice payments retrieved successfully", result)
+}
+
+// Aging returns the accounts receivable aging report (?company_id=1&as_of=2025-01-31)
+func (h *PaymentHandler) Aging(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Query("company_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+
+	asOf, err := parseDateQuery(c, "as_of", time.Now())
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	report, err := h.service.Aging(companyID, asOf, userID)
+	if err != nil {
+		return h.handleError(c, err, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Aging report generated successfully", report)
+}
+
+// Statement returns a customer account statement as JSON or PDF
+// (?company_id=1&customer_id=2&from=2025-01-01&to=2025-01-31&format=json|pdf)
+func (h *PaymentHandler) Statement(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	companyID, err := strconv.ParseInt(c.Query("company_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+	customerID, err := strconv.ParseInt(c.Query("customer_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "customer_id query parameter is required")
+	}
+
+	// Por defecto: desde el inicio del mes actual hasta hoy
+	now := time.Now()
+	to, err := parseDateQuery(c, "to", now)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	from, err := parseDateQuery(c, "from", time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	format := strings.ToLower(c.Query("format", "json"))
+	if format != "json" && format != "pdf" {
+		return response.BadRequest(c, "Invalid format, use 'json' or 'pdf'")
+	}
+
+	statement, err := h.service.Statement(companyID, customerID, from, to, userID)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			return response.BadRequest(c, err.Error())
+		}
+		return h.handleError(c, err, errors.ErrInternalServer.Message)
+	}
+
+	if format == "json" {
+		return response.Success(c, "Account statement generated successfully", statement)
+	}
+
+	pdfBytes, err := h.pdfService.GenerateStatementPDF(statement)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to generate PDF: "+err.Error())
+	}
+
+	filename := fmt.Sprintf("estado-cuenta-%s-%s.pdf", statement.Customer.IdentificationNumber, statement.To)
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", "inline; filename=\""+filename+"\"")
+
+	return c.Send(pdfBytes)
+}
+
+// handleError maps the common service errors to HTTP responses
+func (h *PaymentHandler) handleError(c *fiber.Ctx, err error, fallback string) error {
+	errMsg := err.Error()
+	switch {
+	case errMsg == "company not found":
+		return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+	case errMsg == "invoice not found" || errMsg == "payment not found" || errMsg == "customer not found":
+		return response.NotFound(c, errMsg)
+	case errMsg == "unauthorized access to company" || errMsg == "customer does not belong to company":
+		return accessDenied(c, err, errMsg)
+	}
+	return response.InternalServerError(c, fallback)
+}
+
+// parseDateQuery reads a YYYY-MM-DD query param, returning def when it is empty
+func parseDateQuery(c *fiber.Ctx, name string, def time.Time) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s format, use YYYY-MM-DD", name)
+	}
+	return parsed, nil
+}
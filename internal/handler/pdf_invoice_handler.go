@@ -3,16 +3,20 @@ package handler
 import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/internal/repository"
 	"apidian-go/internal/service/invoice"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/internal/service/prevalidation"
 	"apidian-go/internal/service/pdf"
 	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type PDFHandler struct {
-	invoiceRepo    *repository.InvoiceRepository
 	invoiceService *invoice.InvoiceService
 	pdfService     *pdf.PDFInvoiceService
 }
@@ -24,6 +28,7 @@ func NewPDFHandler(db *database.Database, cfg *config.Config) *PDFHandler {
 	resolutionRepo := repository.NewResolutionRepository(db)
 	productRepo := repository.NewProductRepository(db)
 	certificateRepo := repository.NewCertificateRepository(db)
+	store := storage.New(&cfg.Storage)
 
 	invoiceService := invoice.NewInvoiceService(
 		invoiceRepo,
@@ -32,33 +37,44 @@ func NewPDFHandler(db *database.Database, cfg *config.Config) *PDFHandler {
 		resolutionRepo,
 		productRepo,
 		certificateRepo,
-		&cfg.Storage,
+		keyring.New(repository.NewDataKeyRepository(db), store),
+		prevalidation.New(cfg.Invoice.XSDPath),
 		cfg.Invoice.KeepUnsignedXML,
 	)
 	
 	// Usar Maroto v2 para generar PDFs con diseño profesional
-	pdfService := pdf.NewPDFInvoiceService(&cfg.Storage)
+	pdfService := pdf.NewPDFInvoiceService(store)
 
 	return &PDFHandler{
-		invoiceRepo:    invoiceRepo,
 		invoiceService: invoiceService,
 		pdfService:     pdfService,
 	}
 }
 
-// GenerateInvoicePDFByNumber genera el PDF usando el número de factura
-// URL: /api/v1/invoices/pdf/SETP-99000000
-func (h *PDFHandler) GenerateInvoicePDFByNumber(c *fiber.Ctx) error {
-	// Get number from URL
-	number := c.Params("number")
-	if number == "" {
-		return response.BadRequest(c, "Invoice number is required")
+// GenerateInvoicePDF generates the PDF of an invoice of the user's company
+// URL: /api/v1/invoices/:id/pdf
+func (h *PDFHandler) GenerateInvoicePDF(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
 	}
 
-	// Buscar factura por número
-	invoice, err := h.invoiceRepo.GetByNumber(number)
+	// Obtener la factura validando el acceso a su empresa
+	invoice, err := h.invoiceService.GetByID(id, userID)
 	if err != nil {
-		return response.NotFound(c, "Invoice not found")
+		if err.Error() == "invoice not found" {
+			return response.NotFound(c, "Invoice not found")
+		}
+		if err.Error() == "unauthorized access to invoice" {
+			return accessDenied(c, err, "Unauthorized access to invoice")
+		}
+		return response.InternalServerError(c, err.Error())
 	}
 
 	// Generar PDF dinámicamente con Maroto
@@ -69,7 +85,7 @@ func (h *PDFHandler) GenerateInvoicePDFByNumber(c *fiber.Ctx) error {
 
 	// Configurar headers para visualizar en navegador (inline)
 	c.Set("Content-Type", "application/pdf")
-	c.Set("Content-Disposition", "inline; filename=\""+number+".pdf\"")
-	
+	c.Set("Content-Disposition", "inline; filename=\""+invoice.Number+".pdf\"")
+
 	return c.Send(pdfBytes)
 }
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/mail"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service/invoice"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/internal/service/pdf"
+	"apidian-go/internal/service/prevalidation"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PortalHandler issues the signed document links and serves the buyer portal behind them
+type PortalHandler struct {
+	service *invoice.PortalService
+}
+
+func NewPortalHandler(db *database.Database, cfg *config.Config) *PortalHandler {
+	store := storage.New(&cfg.Storage)
+	invoiceService := invoice.NewInvoiceService(
+		repository.NewInvoiceRepository(db),
+		repository.NewCompanyRepository(db),
+		repository.NewCustomerRepository(db),
+		repository.NewResolutionRepository(db),
+		repository.NewProductRepository(db),
+		repository.NewCertificateRepository(db),
+		keyring.New(repository.NewDataKeyRepository(db), store),
+		prevalidation.New(cfg.Invoice.XSDPath),
+		cfg.Invoice.KeepUnsignedXML,
+	)
+
+	return &PortalHandler{
+		service: invoice.NewPortalService(
+			invoiceService,
+			repository.NewDocumentEventRepository(db),
+			pdf.NewPDFInvoiceService(store),
+			mail.New(&cfg.Mail),
+			&cfg.Portal,
+		),
+	}
+}
+
+// CreateLink issues an expiring signed link to the portal of an invoice, optionally emailed to the customer
+func (h *PortalHandler) CreateLink(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	var req domain.CreateDocumentLinkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	if err := validator.ValidateCreateDocumentLink(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	link, err := h.service.CreateLink(id, userID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Created(c, "Document link created successfully", link)
+}
+
+// GetEvents lists the RADIAN events the buyer registered on an invoice
+func (h *PortalHandler) GetEvents(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	events, err := h.service.GetEvents(id, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Document events retrieved successfully", events)
+}
+
+// Page renders the buyer portal of a document
+func (h *PortalHandler) Page(c *fiber.Ctx) error {
+	id, err := h.verifyLink(c)
+	if err != nil {
+		return renderPortalPage(c, fiber.StatusForbidden, portalPageData{Error: "El enlace no es válido o ya venció. Solicita uno nuevo al emisor de la factura."})
+	}
+	return h.renderDocument(c, id, fiber.StatusOK, "")
+}
+
+// PDF downloads the PDF of a document through its signed link
+func (h *PortalHandler) PDF(c *fiber.Ctx) error {
+	return h.sendFile(c, h.service.GetPDF, "application/pdf", "inline")
+}
+
+// XML downloads the signed XML of a document through its signed link
+func (h *PortalHandler) XML(c *fiber.Ctx) error {
+	return h.sendFile(c, h.service.GetXML, "application/xml", "attachment")
+}
+
+// AttachedDocument downloads the AttachedDocument ZIP of a document through its signed link
+func (h *PortalHandler) AttachedDocument(c *fiber.Ctx) error {
+	return h.sendFile(c, h.service.GetAttachedZIP, "application/zip", "attachment")
+}
+
+// RegisterEvent registers a RADIAN event from the portal form (redirects back to the page) or as JSON
+func (h *PortalHandler) RegisterEvent(c *fiber.Ctx) error {
+	fromForm := strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationForm)
+
+	id, err := h.verifyLink(c)
+	if err != nil {
+		if fromForm {
+			return renderPortalPage(c, fiber.StatusForbidden, portalPageData{Error: "El enlace no es válido o ya venció. Solicita uno nuevo al emisor de la factura."})
+		}
+		return response.Forbidden(c, "Invalid or expired link")
+	}
+
+	var req domain.RegisterDocumentEventRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateRegisterDocumentEvent(&req); err != nil {
+		if fromForm {
+			return h.renderDocument(c, id, fiber.StatusBadRequest, err.Error())
+		}
+		return response.BadRequest(c, err.Error())
+	}
+
+	event, err := h.service.RegisterEvent(id, &req, c.IP())
+	if err != nil {
+		if fromForm {
+			status, message := portalEventError(err)
+			return h.renderDocument(c, id, status, message)
+		}
+		return h.handleError(c, err)
+	}
+
+	if fromForm {
+		return c.Redirect(invoice.PortalPath(id)+"?"+h.linkQuery(c), fiber.StatusSeeOther)
+	}
+	return response.Created(c, "Document event registered successfully", event)
+}
+
+// verifyLink returns the document ID of a request whose link signature is valid and not expired
+func (h *PortalHandler) verifyLink(c *fiber.Ctx) (int64, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if err := h.service.Verify(id, expires, c.Query("signature")); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// linkQuery rebuilds the signed query string of the current (already verified) link
+func (h *PortalHandler) linkQuery(c *fiber.Ctx) string {
+	return fmt.Sprintf("expires=%s&signature=%s", c.Query("expires"), c.Query("signature"))
+}
+
+// renderDocument renders the portal page of a verified document, with message as the form error
+func (h *PortalHandler) renderDocument(c *fiber.Ctx, id int64, status int, message string) error {
+	document, err := h.service.GetDocument(id)
+	if err != nil {
+		if err.Error() == "invoice not found" {
+			return renderPortalPage(c, fiber.StatusNotFound, portalPageData{Error: "El documento no existe o ya no está disponible."})
+		}
+		return renderPortalPage(c, fiber.StatusInternalServerError, portalPageData{Error: "No fue posible cargar el documento, intenta más tarde."})
+	}
+
+	return renderPortalPage(c, status, portalPageData{
+		Document: document,
+		Path:     invoice.PortalPath(id),
+		Query:    template.URL(h.linkQuery(c)),
+		Error:    message,
+	})
+}
+
+// sendFile verifies the link and sends the file returned by load
+func (h *PortalHandler) sendFile(c *fiber.Ctx, load func(id int64) ([]byte, string, error), contentType, disposition string) error {
+	id, err := h.verifyLink(c)
+	if err != nil {
+		return response.Forbidden(c, "Invalid or expired link")
+	}
+
+	data, filename, err := load(id)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", disposition, filename))
+	return c.Send(data)
+}
+
+func (h *PortalHandler) handleError(c *fiber.Ctx, err error) error {
+	msg := err.Error()
+	switch {
+	case msg == "invoice not found":
+		return response.NotFound(c, "Invoice not found")
+	case msg == "unauthorized access to invoice":
+		return accessDenied(c, err, "Unauthorized access to invoice")
+	case msg == "ZIP file not found in storage" || strings.HasPrefix(msg, "invoice does not have"):
+		return response.NotFound(c, msg)
+	case msg == "event already registered" || strings.HasPrefix(msg, "invoice already ") ||
+		strings.HasPrefix(msg, "event ") && strings.Contains(msg, " requires event "):
+		return response.Conflict(c, msg)
+	case msg == "invoice must be signed to share it" || msg == "customer has no email" ||
+		msg == "invoice is not accepted by DIAN" || strings.HasPrefix(msg, "invalid "):
+		return response.BadRequest(c, msg)
+	}
+	return response.InternalServerError(c, errors.ErrInternalServer.Message)
+}
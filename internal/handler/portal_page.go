@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"apidian-go/internal/domain"
+	"bytes"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// portalPageData is what the portal page template renders
+type portalPageData struct {
+	Document *domain.PortalDocument
+	Path     string       // Portal path of the document, without query string
+	Query    template.URL // Signed query string (expires and signature)
+	Error    string       // Result of a rejected event registration
+}
+
+// portalEventLabels are the buyer facing names of the RADIAN events
+var portalEventLabels = map[string]string{
+	domain.EventReceiptAck:        "Acuse de recibo de la factura",
+	domain.EventGoodsReceived:     "Recibo del bien o prestación del servicio",
+	domain.EventExpressAcceptance: "Aceptación expresa",
+	domain.EventClaim:             "Reclamo",
+}
+
+// portalEventError translates the error of a rejected event registration for the portal form
+func portalEventError(err error) (int, string) {
+	msg := err.Error()
+	switch {
+	case msg == "event already registered":
+		return fiber.StatusConflict, "El evento ya está registrado."
+	case msg == "invoice is not accepted by DIAN":
+		return fiber.StatusBadRequest, "Los eventos se pueden registrar cuando DIAN acepte la factura."
+	case msg == "invoice already accepted, it cannot be claimed":
+		return fiber.StatusConflict, "La factura ya fue aceptada, no se puede reclamar."
+	case msg == "invoice already claimed, it cannot be accepted":
+		return fiber.StatusConflict, "La factura ya fue reclamada, no se puede aceptar."
+	case msg == "invalid event or rejection code":
+		return fiber.StatusBadRequest, "El evento o el motivo del reclamo no es válido."
+	case strings.Contains(msg, " requires event "):
+		return fiber.StatusConflict, "Primero registra los eventos anteriores: acuse de recibo y recibo del bien o servicio."
+	}
+	return fiber.StatusInternalServerError, "No fue posible registrar el evento, intenta más tarde."
+}
+
+var portalTemplate = template.Must(template.New("portal").Funcs(template.FuncMap{
+	"money": func(value float64) string { return strconv.FormatFloat(value, 'f', 2, 64) },
+	"event": func(code string) string { return portalEventLabels[code] },
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{with .Document}}Factura {{.Invoice.Number}}{{else}}Documento no disponible{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 880px; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { font-size: 1.4rem; margin-bottom: .2rem; }
+h2 { font-size: 1.1rem; margin-top: 2rem; border-bottom: 1px solid #ddd; padding-bottom: .3rem; }
+table { width: 100%; border-collapse: collapse; font-size: .9rem; }
+th, td { text-align: left; padding: .35rem .5rem; border-bottom: 1px solid #eee; }
+td.num, th.num { text-align: right; }
+.muted { color: #666; font-size: .9rem; }
+.error { background: #fdecea; color: #8a1c14; padding: .6rem .8rem; border-radius: 4px; }
+.downloads a { margin-right: 1rem; }
+form { display: grid; gap: .6rem; max-width: 480px; }
+label { display: grid; gap: .2rem; font-size: .9rem; }
+input, select, textarea, button { font: inherit; padding: .4rem; }
+button { cursor: pointer; }
+</style>
+</head>
+<body>
+{{if .Document}}{{with .Document.Invoice}}
+<h1>Factura electrónica {{.Number}}</h1>
+<p class="muted">{{if .Company}}{{.Company.Name}} · NIT {{.Company.NIT}}{{end}} · Emitida el {{.IssueDate.Format "2006-01-02"}}{{if .DueDate}} · Vence el {{.DueDate.Format "2006-01-02"}}{{end}}</p>
+{{if .UUID}}<p class="muted">CUFE: {{.UUID}}</p>{{end}}
+{{if .Customer}}<p>Adquiriente: <strong>{{.Customer.Name}}</strong> ({{.Customer.IdentificationNumber}})</p>{{end}}
+<p>Estado DIAN: {{if .AcceptedByDIANAt}}aceptada el {{.AcceptedByDIANAt.Format "2006-01-02"}}{{else}}pendiente de validación{{end}}</p>
+
+<h2>Detalle</h2>
+<table>
+<tr><th>Descripción</th><th class="num">Cantidad</th><th class="num">Valor unitario</th><th class="num">Impuesto</th><th class="num">Total</th></tr>
+{{range .Lines}}<tr><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{money .UnitPrice}}</td><td class="num">{{money .TaxAmount}}</td><td class="num">{{money .LineTotal}}</td></tr>
+{{end}}<tr><td colspan="4" class="num">Subtotal</td><td class="num">{{money .Subtotal}}</td></tr>
+<tr><td colspan="4" class="num">Impuestos</td><td class="num">{{money .TaxTotal}}</td></tr>
+<tr><td colspan="4" class="num"><strong>Total {{.CurrencyCode}}</strong></td><td class="num"><strong>{{money .Total}}</strong></td></tr>
+</table>
+{{end}}
+<h2>Descargas</h2>
+<p class="downloads">
+<a href="{{.Path}}/pdf?{{.Query}}">PDF</a>
+{{if .Document.HasXML}}<a href="{{.Path}}/xml?{{.Query}}">XML firmado</a>{{end}}
+{{if .Document.HasZIP}}<a href="{{.Path}}/attached?{{.Query}}">AttachedDocument (ZIP)</a>{{end}}
+</p>
+
+<h2>Eventos</h2>
+{{if .Document.Events}}<table>
+<tr><th>Fecha</th><th>Evento</th><th>Registrado por</th><th>Observaciones</th></tr>
+{{range .Document.Events}}<tr><td>{{.ResponseDate.Format "2006-01-02 15:04"}}</td><td>{{.EventCode}} - {{.EventName}}{{if .RejectionName}} ({{.RejectionName}}){{end}}</td><td>{{if .RegisteredByName}}{{.RegisteredByName}}{{end}}</td><td>{{if .Notes}}{{.Notes}}{{end}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">Aún no hay eventos registrados.</p>{{end}}
+
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Document.AvailableEvents}}
+<form method="post" action="{{.Path}}/events?{{.Query}}">
+<label>Evento
+<select name="event" required>
+{{range .Document.AvailableEvents}}<option value="{{.}}">{{.}} - {{event .}}</option>
+{{end}}</select>
+</label>
+<label>Motivo del reclamo (solo para reclamos)
+<select name="rejection_code">
+<option value=""></option>
+{{range .Document.RejectionTypes}}<option value="{{.Code}}">{{.Code}} - {{.Name}}</option>
+{{end}}</select>
+</label>
+<label>Nombre de quien registra <input name="name" maxlength="255" required></label>
+<label>Número de identificación <input name="identification_number" maxlength="50" required></label>
+<label>Observaciones <textarea name="notes" maxlength="1000" rows="3"></textarea></label>
+<button type="submit">Registrar evento</button>
+</form>
+{{else if not .Document.Invoice.AcceptedByDIANAt}}<p class="muted">Los eventos se pueden registrar cuando DIAN acepte la factura.</p>{{end}}
+{{else}}
+<h1>Documento no disponible</h1>
+<p>{{.Error}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// renderPortalPage writes the portal page; the page only loads its inline styles and posts to itself
+func renderPortalPage(c *fiber.Ctx, status int, data portalPageData) error {
+	var buf bytes.Buffer
+	if err := portalTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).Send(buf.Bytes())
+}
@@ -18,16 +18,19 @@ import (
 type ProductHandler struct {
 	service        *service.ProductService
 	companyService *service.CompanyService
+	importService  *service.ProductImportService
 }
 
 func NewProductHandler(db *database.Database) *ProductHandler {
 	productRepo := repository.NewProductRepository(db)
 	companyRepo := repository.NewCompanyRepository(db)
-	productService := service.NewProductService(productRepo)
+	productService := service.NewProductService(productRepo, companyRepo)
 	companyService := service.NewCompanyService(companyRepo)
+	importService := service.NewProductImportService(productRepo, companyRepo, repository.NewCatalogRepository(db))
 	return &ProductHandler{
 		service:        productService,
 		companyService: companyService,
+		importService:  importService,
 	}
 }
 
@@ -57,7 +60,7 @@ func (h *ProductHandler) GetAll(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.BadRequest(c, err.Error())
 	}
@@ -93,7 +96,7 @@ func (h *ProductHandler) GetByID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrProductNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to product" {
-			return response.Unauthorized(c, "Unauthorized access to product")
+			return accessDenied(c, err, "Unauthorized access to product")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -125,6 +128,13 @@ func (h *ProductHandler) Create(c *fiber.Ctx) error {
 	if err != nil {
 		errMsg := err.Error()
 
+		if errMsg == "company not found" {
+			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+		}
+		if errMsg == "unauthorized access to company" {
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
+		}
+
 		// Duplicate code error
 		if strings.Contains(errMsg, "already exists for this company") {
 			return response.Conflict(c, "A product with code "+req.Code+" already exists for this company")
@@ -175,15 +185,21 @@ func (h *ProductHandler) Update(c *fiber.Ctx) error {
 		return response.BadRequest(c, err.Error())
 	}
 
-	// Update product
-	if err := h.service.Update(id, 0, &req); err != nil {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Update product (service verifies access internally)
+	if err := h.service.Update(id, userID, &req); err != nil {
 		errMsg := err.Error()
 
 		if errMsg == "product not found" {
 			return response.NotFound(c, "Product not found")
 		}
 		if errMsg == "unauthorized access to product" {
-			return response.Unauthorized(c, "Unauthorized access to product")
+			return accessDenied(c, err, "Unauthorized access to product")
 		}
 
 		// Foreign key errors
@@ -228,10 +244,20 @@ func (h *ProductHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrProductNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to product" {
-			return response.Unauthorized(c, "Unauthorized access to product")
+			return accessDenied(c, err, "Unauthorized access to product")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
 
 	return response.Success(c, "Product deleted successfully", nil)
 }
+
+// Import creates or updates products from a CSV/XLSX file (multipart "file")
+func (h *ProductHandler) Import(c *fiber.Ctx) error {
+	return handleBulkImport(c, h.importService, "Products")
+}
+
+// Export downloads all products of a company in the import format
+func (h *ProductHandler) Export(c *fiber.Ctx) error {
+	return handleBulkExport(c, h.importService, "productos")
+}
@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/internal/service/invoice"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/internal/service/prevalidation"
+	"apidian-go/internal/service/pdf"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type QuotationHandler struct {
+	service    *service.QuotationService
+	pdfService *pdf.PDFInvoiceService
+}
+
+func NewQuotationHandler(db *database.Database, cfg *config.Config) *QuotationHandler {
+	quotationRepo := repository.NewQuotationRepository(db)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	companyRepo := repository.NewCompanyRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	resolutionRepo := repository.NewResolutionRepository(db)
+	productRepo := repository.NewProductRepository(db)
+	certificateRepo := repository.NewCertificateRepository(db)
+	store := storage.New(&cfg.Storage)
+
+	// La conversión a factura pasa por InvoiceService.Create (mismas validaciones y consecutivo DIAN)
+	invoiceService := invoice.NewInvoiceService(
+		invoiceRepo,
+		companyRepo,
+		customerRepo,
+		resolutionRepo,
+		productRepo,
+		certificateRepo,
+		keyring.New(repository.NewDataKeyRepository(db), store),
+		prevalidation.New(cfg.Invoice.XSDPath),
+		cfg.Invoice.KeepUnsignedXML,
+	)
+
+	quotationService := service.NewQuotationService(
+		quotationRepo,
+		companyRepo,
+		customerRepo,
+		productRepo,
+		invoiceService,
+	)
+
+	return &QuotationHandler{
+		service:    quotationService,
+		pdfService: pdf.NewPDFInvoiceService(store),
+	}
+}
+
+// Create creates a new quotation or sales order
+func (h *QuotationHandler) Create(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	var req domain.CreateQuotationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	// Validate request
+	if err := validator.ValidateCreateQuotation(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	// Create quotation
+	quotation, err := h.service.Create(&req, userID)
+	if err != nil {
+		errMsg := err.Error()
+		if errMsg == "company not found" || errMsg == "customer not found" ||
+			strings.HasPrefix(errMsg, "product not found") {
+			return response.NotFound(c, errMsg)
+		}
+		if errMsg == "unauthorized access to company" ||
+			errMsg == "customer does not belong to company" ||
+			strings.HasSuffix(errMsg, "does not belong to company") {
+			return accessDenied(c, err, errMsg)
+		}
+		if strings.HasPrefix(errMsg, "invalid ") || strings.HasPrefix(errMsg, "expires_at") {
+			return response.BadRequest(c, errMsg)
+		}
+		return response.InternalServerError(c, "Error creating quotation")
+	}
+
+	return response.Created(c, "Quotation created successfully", quotation)
+}
+
+// GetAll gets all quotations for a company
+func (h *QuotationHandler) GetAll(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get company_id from query params
+	companyIDStr := c.Query("company_id")
+	if companyIDStr == "" {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+
+	companyID, err := strconv.ParseInt(companyIDStr, 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid company_id")
+	}
+
+	// Get pagination parameters
+	page, pageSize := utils.ParsePaginationParams(c)
+
+	// Get quotations (?kind=quotation|order&status=accepted)
+	quotations, err := h.service.GetByCompanyID(companyID, userID, c.Query("kind"), c.Query("status"), page, pageSize)
+	if err != nil {
+		if err.Error() == "company not found" {
+			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+		}
+		if err.Error() == "unauthorized access to company" {
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Quotations retrieved successfully", quotations)
+}
+
+// GetByID gets a quotation by ID
+func (h *QuotationHandler) GetByID(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get quotation ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	quotation, err := h.service.GetByID(id, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Quotation retrieved successfully", quotation)
+}
+
+// UpdateStatus marks a quotation as sent, accepted or rejected
+func (h *QuotationHandler) UpdateStatus(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get quotation ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	var req domain.UpdateQuotationStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := h.service.UpdateStatus(id, req.Status, userID); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Quotation status updated successfully", fiber.Map{
+		"id":     id,
+		"status": req.Status,
+	})
+}
+
+// Convert creates a draft invoice from a quotation
+func (h *QuotationHandler) Convert(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get quotation ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	var req domain.ConvertQuotationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateConvertQuotation(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	invoice, err := h.service.Convert(id, &req, userID)
+	if err != nil {
+		errMsg := err.Error()
+		if errMsg == "resolution not found" {
+			return response.NotFound(c, errMsg)
+		}
+		if errMsg == "resolution does not belong to company" {
+			return response.Unauthorized(c, errMsg)
+		}
+		if errMsg == "resolution is not active" || strings.HasPrefix(errMsg, "invalid ") {
+			return response.BadRequest(c, errMsg)
+		}
+		if strings.HasPrefix(errMsg, "resolution unavailable") {
+			return response.UnprocessableEntity(c, errMsg, nil)
+		}
+		if errMsg == "monthly document quota exceeded" {
+			return response.TooManyRequests(c, documentQuotaMessage)
+		}
+		return h.handleError(c, err)
+	}
+
+	return response.Created(c, "Quotation converted to invoice successfully", fiber.Map{
+		"quotation_id": id,
+		"invoice":      invoice,
+	})
+}
+
+// GeneratePDF renders the quotation PDF inline
+func (h *QuotationHandler) GeneratePDF(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get quotation ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	quotation, err := h.service.GetByID(id, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	pdfBytes, err := h.pdfService.GenerateQuotationPDF(quotation)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to generate PDF: "+err.Error())
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", "inline; filename=\""+quotation.Number+".pdf\"")
+
+	return c.Send(pdfBytes)
+}
+
+// Delete deletes a draft quotation
+func (h *QuotationHandler) Delete(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get quotation ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	if err := h.service.Delete(id, userID); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Quotation deleted successfully", nil)
+}
+
+// handleError traduce los errores comunes del servicio de cotizaciones a respuestas HTTP
+func (h *QuotationHandler) handleError(c *fiber.Ctx, err error) error {
+	errMsg := err.Error()
+	switch {
+	case errMsg == "quotation not found" || errMsg == "company not found":
+		return response.NotFound(c, "Quotation not found")
+	case errMsg == "unauthorized access to quotation" || errMsg == "unauthorized access to company":
+		return accessDenied(c, err, "Unauthorized access to quotation")
+	case errMsg == "quotation already converted":
+		return response.Conflict(c, "Quotation already converted")
+	case strings.HasPrefix(errMsg, "only "),
+		strings.HasPrefix(errMsg, "invalid status"),
+		strings.HasPrefix(errMsg, "quotation cannot be"):
+		return response.BadRequest(c, errMsg)
+	}
+	return response.InternalServerError(c, errMsg)
+}
@@ -14,14 +14,18 @@ import (
 )
 
 type ResolutionHandler struct {
-	service        *service.ResolutionService
-	companyService *service.CompanyService
+	service          *service.ResolutionService
+	syncService      *service.ResolutionSyncService
+	numberingService *service.NumberingService
+	companyService   *service.CompanyService
 }
 
-func NewResolutionHandler(service *service.ResolutionService, companyService *service.CompanyService) *ResolutionHandler {
+func NewResolutionHandler(service *service.ResolutionService, syncService *service.ResolutionSyncService, numberingService *service.NumberingService, companyService *service.CompanyService) *ResolutionHandler {
 	return &ResolutionHandler{
-		service:        service,
-		companyService: companyService,
+		service:          service,
+		syncService:      syncService,
+		numberingService: numberingService,
+		companyService:   companyService,
 	}
 }
 
@@ -51,7 +55,7 @@ func (h *ResolutionHandler) GetAll(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.BadRequest(c, err.Error())
 	}
@@ -96,7 +100,7 @@ func (h *ResolutionHandler) GetByID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrResolutionNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to resolution" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -104,6 +108,78 @@ func (h *ResolutionHandler) GetByID(c *fiber.Ctx) error {
 	return response.Success(c, "Resolution retrieved successfully", resolution)
 }
 
+// Numbering reports used, draft, deleted, missing and annulled consecutives of a resolution
+func (h *ResolutionHandler) Numbering(c *fiber.Ctx) error {
+	// Get resolution ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid resolution ID")
+	}
+
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	report, err := h.numberingService.Report(id, userID, c.QueryBool("include_used", false))
+	if err != nil {
+		if err.Error() == "resolution not found" {
+			return response.NotFound(c, errors.ErrResolutionNotFound.Message)
+		}
+		if err.Error() == "unauthorized access to resolution" {
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Success(c, "Numbering report generated successfully", report)
+}
+
+// Annul records the annulment of a skipped consecutive (missing or from a deleted draft)
+func (h *ResolutionHandler) Annul(c *fiber.Ctx) error {
+	// Get resolution ID
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid resolution ID")
+	}
+
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	var req domain.AnnulNumberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateAnnulNumber(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	record, err := h.numberingService.Annul(id, userID, &req)
+	if err != nil {
+		errMsg := err.Error()
+		if errMsg == "resolution not found" {
+			return response.NotFound(c, errors.ErrResolutionNotFound.Message)
+		}
+		if errMsg == "unauthorized access to resolution" {
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
+		}
+		if errMsg == "consecutive is outside the resolution range" || errMsg == "consecutive has not been assigned yet" {
+			return response.BadRequest(c, errMsg)
+		}
+		if errMsg == "consecutive is in use or already annulled" {
+			return response.Conflict(c, errMsg)
+		}
+		return response.InternalServerError(c, errors.ErrInternalServer.Message)
+	}
+
+	return response.Created(c, "Consecutive annulled successfully", record)
+}
+
 // Create creates a new resolution
 func (h *ResolutionHandler) Create(c *fiber.Ctx) error {
 	// Get user_id from context
@@ -132,11 +208,20 @@ func (h *ResolutionHandler) Create(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if errMsg == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		if errMsg == "resolution with this prefix already exists for this company" {
 			return response.Conflict(c, "A resolution with prefix "+req.Prefix+" already exists for this company")
 		}
+		if errMsg == "followed resolution not found" {
+			return response.NotFound(c, "Followed resolution not found")
+		}
+		if errMsg == "followed resolution does not belong to company" || errMsg == "follow-up resolution must have the same type_document_id" {
+			return response.BadRequest(c, errMsg)
+		}
+		if errMsg == "resolution already has a follow-up resolution" {
+			return response.Conflict(c, "The followed resolution already has a follow-up resolution")
+		}
 
 		// Foreign key errors
 		if strings.Contains(errMsg, "fk_resolutions_company") {
@@ -158,6 +243,9 @@ func (h *ResolutionHandler) Create(c *fiber.Ctx) error {
 		if strings.Contains(errMsg, "uq_resolutions_company_prefix") {
 			return response.Conflict(c, "A resolution with prefix "+req.Prefix+" already exists for this company")
 		}
+		if strings.Contains(errMsg, "uq_resolutions_follows") {
+			return response.Conflict(c, "The followed resolution already has a follow-up resolution")
+		}
 
 		return response.InternalServerError(c, "Error creating resolution: "+errMsg)
 	}
@@ -165,6 +253,43 @@ func (h *ResolutionHandler) Create(c *fiber.Ctx) error {
 	return response.Success(c, "Resolution created successfully", resolution)
 }
 
+// Sync synchronizes the company resolutions with DIAN (GetNumberingRange)
+func (h *ResolutionHandler) Sync(c *fiber.Ctx) error {
+	// Get user_id from context
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	// Get company_id from query params
+	companyID, err := strconv.ParseInt(c.Query("company_id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "company_id query parameter is required")
+	}
+	dryRun := c.QueryBool("dry_run", false)
+
+	result, err := h.syncService.Sync(companyID, userID, dryRun)
+	if err != nil {
+		errMsg := err.Error()
+		switch {
+		case errMsg == "company not found":
+			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
+		case errMsg == "unauthorized access to company":
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
+		case errMsg == "software not configured for company",
+			strings.HasPrefix(errMsg, "no certificate found"),
+			strings.HasPrefix(errMsg, "certificate expired"),
+			strings.HasPrefix(errMsg, "certificate not valid"):
+			return response.UnprocessableEntity(c, errMsg, nil)
+		case strings.HasPrefix(errMsg, "DIAN_ERROR:"):
+			return response.UnprocessableEntity(c, strings.TrimPrefix(errMsg, "DIAN_ERROR: "), nil)
+		}
+		return response.InternalServerError(c, "Error synchronizing resolutions: "+errMsg)
+	}
+
+	return response.Success(c, "Resolutions synchronized with DIAN", result)
+}
+
 // Delete deletes (soft delete) a resolution
 func (h *ResolutionHandler) Delete(c *fiber.Ctx) error {
 	// Get resolution ID
@@ -185,7 +310,7 @@ func (h *ResolutionHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrResolutionNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to resolution" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/middleware"
 	"apidian-go/internal/repository"
 	"apidian-go/internal/service"
+	"apidian-go/internal/service/keyring"
 	"apidian-go/pkg/response"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,35 +24,75 @@ func SetupSystemRoutes(app *fiber.App, cfg *config.Config) {
 	})
 }
 
-func SetupPublicRoutes(api fiber.Router, db *database.Database, cfg *config.Config) {
-	// Auth routes
+// SetupPublicRoutes registra las rutas sin autenticación; ipLimit (RateLimiter.ByIP) limita
+// cada una por IP, traiga o no credenciales
+func SetupPublicRoutes(api fiber.Router, db *database.Database, cfg *config.Config, ipLimit fiber.Handler) {
+	// Auth routes (/auth también tiene rutas protegidas, así que el límite va por ruta)
 	auth := api.Group("/auth")
 	authHandler := NewAuthHandler(db, cfg)
-	auth.Post("/register", authHandler.Register)
-	auth.Post("/login", authHandler.Login)
+	auth.Post("/register", ipLimit, authHandler.Register)
+	auth.Post("/login", ipLimit, authHandler.Login)
+	auth.Post("/login/2fa", ipLimit, authHandler.LoginTwoFactor)
+	auth.Post("/refresh", ipLimit, authHandler.Refresh)
+	auth.Post("/verify-email", ipLimit, authHandler.VerifyEmail)
+	auth.Post("/resend-verification", ipLimit, authHandler.ResendVerification)
+	auth.Post("/forgot-password", ipLimit, authHandler.ForgotPassword)
+	auth.Post("/reset-password", ipLimit, authHandler.ResetPassword)
+	auth.Post("/unlock-account", ipLimit, authHandler.UnlockAccount) // Enlace enviado al bloquear la cuenta por logins fallidos
 
 	// Public info
-	api.Get("/ping", func(c *fiber.Ctx) error {
+	api.Get("/ping", ipLimit, func(c *fiber.Ctx) error {
 		return response.Success(c, "pong", fiber.Map{
 			"timestamp": c.Context().Time(),
 		})
 	})
-	
-	// TEMPORAL: PDF preview sin autenticación para testing
-	pdfHandler := NewPDFHandler(db, cfg)
-	api.Get("/invoices/pdf/:number", pdfHandler.GenerateInvoicePDFByNumber) // Por número de factura (título visible en navegador)
+
+	// Portal del comprador: el enlace firmado (expires y signature) es la credencial
+	portal := api.Group("/portal/documents", ipLimit)
+	portalHandler := NewPortalHandler(db, cfg)
+	portal.Get("/:id", portalHandler.Page)                          // Página HTML con el documento y el registro de eventos
+	portal.Get("/:id/pdf", portalHandler.PDF)                       // PDF
+	portal.Get("/:id/xml", portalHandler.XML)                       // XML firmado
+	portal.Get("/:id/attached", portalHandler.AttachedDocument)     // ZIP con el AttachedDocument
+	portal.Post("/:id/events", portalHandler.RegisterEvent)         // Acuse (030), recibo (032), aceptación (033) o reclamo (031)
 }
 
-func SetupProtectedRoutes(api fiber.Router, db *database.Database, cfg *config.Config) {
+func SetupProtectedRoutes(api fiber.Router, db *database.Database, cfg *config.Config, limiter *middleware.RateLimiter) {
+	// API keys: cada ruta declara el permiso y de dónde sale la empresa; sin scope la key no tiene
+	// acceso. Con JWT esa misma empresa se cuenta contra su plan.
+	keys := middleware.NewAPIKeyScope(db, limiter)
+
 	// Companies CRUD
-	companies := api.Group("/companies")
+	companies := api.Group("/companies", middleware.RejectAPIKeys())
 	companyHandler := NewCompanyHandler(db, cfg)
 	companies.Get("/", companyHandler.GetAll)
 	companies.Get("/:id", companyHandler.GetByID)
 	companies.Post("/", companyHandler.Create)
 	companies.Put("/:id", companyHandler.Update)
 	companies.Delete("/:id", companyHandler.Delete)
+	companies.Put("/:id/two-factor-policy", companyHandler.UpdateTwoFactorPolicy) // Exigir 2FA para firmar y certificados
 	companies.Post("/:id/certificate", companyHandler.UploadCertificate) // Deprecated
+
+	// API keys de la empresa (integraciones ERP/POS)
+	apiKeyHandler := NewAPIKeyHandler(db)
+	companies.Get("/:id/api-keys", apiKeyHandler.GetAll)
+	companies.Post("/:id/api-keys", apiKeyHandler.Create)               // La key solo se muestra en esta respuesta
+	companies.Delete("/:id/api-keys/:key_id", apiKeyHandler.Revoke)
+
+	// Miembros e invitaciones de la empresa
+	memberHandler := NewMemberHandler(db)
+	companies.Get("/:id/members", memberHandler.GetAll)
+	companies.Put("/:id/members/:user_id", memberHandler.Update)              // Cambiar rol
+	companies.Delete("/:id/members/:user_id", memberHandler.Remove)           // Un miembro puede retirarse a sí mismo
+	companies.Get("/:id/invitations", memberHandler.GetInvitations)
+	companies.Post("/:id/invitations", memberHandler.Invite)
+	companies.Delete("/:id/invitations/:invitation_id", memberHandler.RevokeInvitation)
+
+	// Invitaciones recibidas por el usuario autenticado
+	invitations := api.Group("/invitations", middleware.RejectAPIKeys())
+	invitations.Get("/", memberHandler.GetMyInvitations)
+	invitations.Post("/:id/accept", memberHandler.AcceptInvitation)
+	invitations.Post("/:id/decline", memberHandler.DeclineInvitation)
 	
 	// TODO: Implementar certificación ante DIAN (SendTestSetAsync)
 	// companies.Post("/:id/certification/testset", companyHandler.SubmitTestSet)     // Enviar set de pruebas (30 FV + 10 NC + 10 ND)
@@ -57,44 +101,86 @@ func SetupProtectedRoutes(api fiber.Router, db *database.Database, cfg *config.C
 	// Customers (FLAT with company_id filter)
 	customers := api.Group("/customers")
 	customerHandler := NewCustomerHandler(db)
-	customers.Get("/", customerHandler.GetAll)           // ?company_id=1
-	customers.Get("/:id", customerHandler.GetByID)
-	customers.Post("/", customerHandler.Create)          // company_id in JSON body
-	customers.Put("/:id", customerHandler.Update)
-	customers.Delete("/:id", customerHandler.Delete)
+	customers.Get("/", keys.Query(domain.PermissionCustomersRead), customerHandler.GetAll)           // ?company_id=1
+	customers.Get("/export", keys.Query(domain.PermissionCustomersRead), customerHandler.Export)     // ?company_id=1&format=csv|xlsx
+	customers.Post("/import", keys.Query(domain.PermissionCustomersWrite), customerHandler.Import)   // CSV/XLSX (multipart "file"), upsert por identification_number
+	customers.Get("/:id", keys.Resource(domain.PermissionCustomersRead, repository.ScopeCustomer), customerHandler.GetByID)
+	customers.Post("/", keys.Body(domain.PermissionCustomersWrite), customerHandler.Create)          // company_id in JSON body
+	customers.Put("/:id", keys.Resource(domain.PermissionCustomersWrite, repository.ScopeCustomer), customerHandler.Update)
+	customers.Delete("/:id", keys.Resource(domain.PermissionCustomersWrite, repository.ScopeCustomer), customerHandler.Delete)
 
 	// Products (FLAT with company_id filter)
 	products := api.Group("/products")
 	productHandler := NewProductHandler(db)
-	products.Get("/", productHandler.GetAll)             // ?company_id=1
-	products.Get("/:id", productHandler.GetByID)
-	products.Post("/", productHandler.Create)            // company_id in JSON body
-	products.Put("/:id", productHandler.Update)
-	products.Delete("/:id", productHandler.Delete)
+	products.Get("/", keys.Query(domain.PermissionProductsRead), productHandler.GetAll)             // ?company_id=1
+	products.Get("/export", keys.Query(domain.PermissionProductsRead), productHandler.Export)       // ?company_id=1&format=csv|xlsx
+	products.Post("/import", keys.Query(domain.PermissionProductsWrite), productHandler.Import)     // CSV/XLSX (multipart "file"), upsert por code
+	products.Get("/:id", keys.Resource(domain.PermissionProductsRead, repository.ScopeProduct), productHandler.GetByID)
+	products.Post("/", keys.Body(domain.PermissionProductsWrite), productHandler.Create)            // company_id in JSON body
+	products.Put("/:id", keys.Resource(domain.PermissionProductsWrite, repository.ScopeProduct), productHandler.Update)
+	products.Delete("/:id", keys.Resource(domain.PermissionProductsWrite, repository.ScopeProduct), productHandler.Delete)
 
 	// Invoices (FLAT with company_id filter)
 	invoices := api.Group("/invoices")
 	invoiceHandler := NewInvoiceHandler(db, cfg)
 	pdfHandler := NewPDFHandler(db, cfg)
-	invoices.Get("/", invoiceHandler.GetAll)                              // ?company_id=1&status=draft
-	invoices.Get("/:id", invoiceHandler.GetByID)
-	invoices.Post("/", invoiceHandler.Create)                             // company_id in JSON body
-	invoices.Put("/:id", invoiceHandler.Update)
-	invoices.Delete("/:id", invoiceHandler.Delete)
-	invoices.Post("/:id/sign", invoiceHandler.Sign)                       // Firmar factura
-	invoices.Post("/:id/send", invoiceHandler.SendToDIAN)                 // Enviar a DIAN (SendBillSync - individual)
-	invoices.Post("/:id/status", invoiceHandler.GetInvoiceStatus)         // Consultar estado en DIAN
-	api.Get("/invoices/pdf/:number", pdfHandler.GenerateInvoicePDFByNumber) // Por número de factura (título visible)
-	invoices.Post("/:id/attached", invoiceHandler.GenerateAttachedDocument) // Generar AttachedDocument
-	invoices.Get("/:id/download", invoiceHandler.DownloadZIP)             // Descargar ZIP final
-	invoices.Get("/:id/xml", invoiceHandler.GetXML)                       // Obtener XML firmado
+	paymentHandler := NewPaymentHandler(db, cfg)
+	verificationHandler := NewVerificationHandler(db, cfg)
+	portalHandler := NewPortalHandler(db, cfg)
+	invoiceRead := keys.Resource(domain.PermissionInvoicesRead, repository.ScopeDocument)
+	invoiceWrite := keys.Resource(domain.PermissionInvoicesWrite, repository.ScopeDocument)
+	invoices.Get("/", keys.Query(domain.PermissionInvoicesRead), invoiceHandler.GetAll)   // ?company_id=1&status=draft
+	invoices.Post("/import", keys.Query(domain.PermissionInvoicesWrite), invoiceHandler.Import) // CSV/XLSX (multipart "file"), ?company_id=1&dry_run=false
+	invoices.Get("/import/:job_id", keys.ResourceParam(domain.PermissionInvoicesRead, repository.ScopeImportJob, "job_id"), invoiceHandler.GetImportJob) // Progreso de la importación
+	invoices.Get("/:id", invoiceRead, invoiceHandler.GetByID)
+	invoices.Post("/", keys.Body(domain.PermissionInvoicesWrite), invoiceHandler.Create)  // company_id in JSON body
+	invoices.Put("/:id", invoiceWrite, invoiceHandler.Update)
+	invoices.Delete("/:id", invoiceWrite, invoiceHandler.Delete)
+	invoices.Post("/:id/prevalidate", invoiceWrite, invoiceHandler.Prevalidate)         // Validar XML contra XSD y reglas DIAN
+	invoices.Post("/:id/sign", invoiceWrite, invoiceHandler.Sign)                       // Firmar factura
+	invoices.Post("/:id/send", invoiceWrite, invoiceHandler.SendToDIAN)                 // Enviar a DIAN (SendBillSync - individual)
+	invoices.Post("/:id/status", invoiceWrite, invoiceHandler.GetInvoiceStatus)         // Consultar estado en DIAN
+	invoices.Get("/:id/pdf", invoiceRead, pdfHandler.GenerateInvoicePDF)                 // PDF (inline)
+	invoices.Post("/:id/link", invoiceWrite, portalHandler.CreateLink)                  // Enlace firmado al portal del comprador (y envío por correo)
+	invoices.Get("/:id/events", invoiceRead, portalHandler.GetEvents)                   // Eventos RADIAN registrados por el comprador
+	invoices.Post("/:id/attached", invoiceWrite, invoiceHandler.GenerateAttachedDocument) // Generar AttachedDocument
+	invoices.Get("/:id/download", invoiceRead, invoiceHandler.DownloadZIP)             // Descargar ZIP final
+	invoices.Get("/:id/xml", invoiceRead, invoiceHandler.GetXML)                       // Obtener XML firmado
+	invoices.Get("/:id/verify", invoiceRead, verificationHandler.VerifyInvoice)        // Verificar firma y CUFE del XML almacenado
+	invoices.Get("/:id/payments", keys.Resource(domain.PermissionPaymentsRead, repository.ScopeDocument), paymentHandler.GetInvoicePayments) // Saldo, estado de pago y abonos
 	
 	// TODO: Implementar envío masivo de facturas (SendBillAsync)
 	// invoices.Post("/batch/send", invoiceHandler.SendBatchToDIAN)       // Enviar lote de facturas (retorna ZipKey)
 	// invoices.Get("/batch/:zipKey/status", invoiceHandler.GetBatchStatus) // Consultar estado de lote (GetStatusZip)
 
+	// Quotations & sales orders (FLAT with company_id filter) - no se envían a DIAN
+	quotations := api.Group("/quotations")
+	quotationHandler := NewQuotationHandler(db, cfg)
+	quotationRead := keys.Resource(domain.PermissionQuotationsRead, repository.ScopeQuotation)
+	quotationWrite := keys.Resource(domain.PermissionQuotationsWrite, repository.ScopeQuotation)
+	quotations.Get("/", keys.Query(domain.PermissionQuotationsRead), quotationHandler.GetAll) // ?company_id=1&kind=quotation&status=accepted
+	quotations.Get("/:id", quotationRead, quotationHandler.GetByID)
+	quotations.Post("/", keys.Body(domain.PermissionQuotationsWrite), quotationHandler.Create) // company_id in JSON body
+	quotations.Delete("/:id", quotationWrite, quotationHandler.Delete)
+	quotations.Put("/:id/status", quotationWrite, quotationHandler.UpdateStatus)      // sent | accepted | rejected
+	quotations.Post("/:id/convert", quotationWrite, quotationHandler.Convert)         // Genera factura borrador vía InvoiceService.Create
+	quotations.Get("/:id/pdf", quotationRead, quotationHandler.GeneratePDF)           // PDF de cotización/pedido
+
+	// Payments & accounts receivable (FLAT with company_id filter)
+	payments := api.Group("/payments")
+	payments.Get("/", keys.Query(domain.PermissionPaymentsRead), paymentHandler.GetAll) // ?company_id=1&customer_id=2
+	payments.Post("/", keys.BodyResource(domain.PermissionPaymentsWrite, repository.ScopeDocument, "document_id"), paymentHandler.Create) // document_id in JSON body
+	payments.Delete("/:id", keys.Resource(domain.PermissionPaymentsWrite, repository.ScopePayment), paymentHandler.Delete)
+
+	receivables := api.Group("/receivables")
+	receivables.Get("/aging", keys.Query(domain.PermissionReportsRead), paymentHandler.Aging)         // ?company_id=1&as_of=2025-01-31
+	receivables.Get("/statement", keys.Query(domain.PermissionReportsRead), paymentHandler.Statement) // ?company_id=1&customer_id=2&from=&to=&format=json|pdf
+
+	// Verification of signed XML (own or third-party; base64 in JSON body)
+	api.Post("/verify", middleware.RejectAPIKeys(), verificationHandler.VerifyXML)
+
 	// Certificates (FLAT with company_id filter)
-	certificates := api.Group("/certificates")
+	certificates := api.Group("/certificates", middleware.RejectAPIKeys())
 	certificateHandler := NewCertificateHandler(db, cfg)
 	certificates.Get("/", certificateHandler.GetByCompanyID)    // ?company_id=1 (active)
 	certificates.Get("/all", certificateHandler.GetAllByCompanyID) // ?company_id=1 (history)
@@ -107,15 +193,29 @@ func SetupProtectedRoutes(api fiber.Router, db *database.Database, cfg *config.C
 	resolutionRepo := repository.NewResolutionRepository(db)
 	companyRepoForResolution := repository.NewCompanyRepository(db)
 	resolutionService := service.NewResolutionService(resolutionRepo, companyRepoForResolution)
+	resolutionSyncService := service.NewResolutionSyncService(
+		resolutionRepo,
+		companyRepoForResolution,
+		repository.NewSoftwareRepository(db),
+		repository.NewCertificateRepository(db),
+		keyring.New(repository.NewDataKeyRepository(db), storage.New(&cfg.Storage)),
+	)
 	companyServiceForResolution := service.NewCompanyService(companyRepoForResolution)
-	resolutionHandler := NewResolutionHandler(resolutionService, companyServiceForResolution)
-	resolutions.Get("/", resolutionHandler.GetAll)       // ?company_id=1
-	resolutions.Get("/:id", resolutionHandler.GetByID)
-	resolutions.Post("/", resolutionHandler.Create)      // company_id in JSON body
-	resolutions.Delete("/:id", resolutionHandler.Delete)
+	numberingService := service.NewNumberingService(repository.NewNumberingRepository(db), resolutionRepo, companyRepoForResolution)
+	resolutionHandler := NewResolutionHandler(resolutionService, resolutionSyncService, numberingService, companyServiceForResolution)
+	// Las API keys solo pueden leer resoluciones; crear, sincronizar y anular es configuración
+	// de la empresa (resolutions:write no se puede otorgar a una key, igual que certificados y software)
+	resolutionWrite := middleware.RejectAPIKeys()
+	resolutions.Get("/", keys.Query(domain.PermissionResolutionsRead), resolutionHandler.GetAll) // ?company_id=1
+	resolutions.Post("/sync", resolutionWrite, resolutionHandler.Sync)                           // ?company_id=1&dry_run=false (DIAN GetNumberingRange)
+	resolutions.Get("/:id", keys.Resource(domain.PermissionResolutionsRead, repository.ScopeResolution), resolutionHandler.GetByID)
+	resolutions.Get("/:id/numbering", keys.Resource(domain.PermissionResolutionsRead, repository.ScopeResolution), resolutionHandler.Numbering) // ?include_used=true
+	resolutions.Post("/:id/annulments", resolutionWrite, resolutionHandler.Annul)
+	resolutions.Post("/", resolutionWrite, resolutionHandler.Create) // company_id in JSON body
+	resolutions.Delete("/:id", resolutionWrite, resolutionHandler.Delete)
 
 	// Software (FLAT with company_id filter)
-	software := api.Group("/software")
+	software := api.Group("/software", middleware.RejectAPIKeys())
 	softwareRepo := repository.NewSoftwareRepository(db)
 	companyRepoForSoftware := repository.NewCompanyRepository(db)
 	softwareService := service.NewSoftwareService(softwareRepo, companyRepoForSoftware)
@@ -127,17 +227,46 @@ func SetupProtectedRoutes(api fiber.Router, db *database.Database, cfg *config.C
 	software.Put("/:id", softwareHandler.Update)
 	software.Delete("/:id", softwareHandler.Delete)
 
-	// Users (CRUD de usuarios)
-	users := api.Group("/users")
-	userHandler := NewUserHandler(db)
-	users.Get("/", userHandler.GetAll)
-	users.Get("/:id", userHandler.GetByID)
-	users.Put("/:id", userHandler.Update)
-	users.Delete("/:id", userHandler.Delete)
+	// Historial de cambios de la empresa (triggers de auditoría, solo lectura)
+	auditHandler := NewAuditHandler(db)
+	api.Get("/audit", middleware.RejectAPIKeys(), auditHandler.GetAll) // ?company_id=1&table=documents&record_id=10&operation=&user_id=&from=&to=
+
+	// Administración de la plataforma (usuarios, empresas, suplantación y bitácora)
+	admin := api.Group("/admin", middleware.RejectAPIKeys(), middleware.RequirePlatformAdmin(db))
+	adminHandler := NewAdminHandler(db, cfg)
+	admin.Get("/users", adminHandler.GetUsers)
+	admin.Get("/users/:id", adminHandler.GetUser)
+	admin.Put("/users/:id", adminHandler.UpdateUser)
+	admin.Delete("/users/:id", adminHandler.DeleteUser)
+	admin.Post("/users/:id/impersonate", adminHandler.Impersonate) // Sesión de 30 min sin refresh token
+	admin.Get("/companies", adminHandler.GetCompanies)             // Uso por empresa; ?suspended=true
+	admin.Get("/companies/:id", adminHandler.GetCompany)
+	admin.Post("/companies/:id/suspend", adminHandler.SuspendCompany) // Bloquea firmar y enviar a DIAN
+	admin.Post("/companies/:id/unsuspend", adminHandler.UnsuspendCompany)
+	admin.Get("/companies/:id/usage", adminHandler.GetCompanyUsageHistory) // Documentos y llamadas API por mes; ?months=12
+	admin.Put("/companies/:id/plan", adminHandler.SetCompanyPlan)
+	admin.Get("/plans", adminHandler.GetPlans)
+	admin.Get("/actions", adminHandler.GetActions)
+	admin.Get("/audit", auditHandler.GetAllPlatform) // Todas las empresas y eventos de autenticación (table=auth)
 
 	// Auth protected routes
+	auth := api.Group("/auth", middleware.RejectAPIKeys())
 	authHandler := NewAuthHandler(db, cfg)
-	api.Post("/auth/logout", authHandler.Logout)
-	api.Get("/auth/me", authHandler.Me)
-	api.Post("/auth/change-password", userHandler.ChangePassword)
+	auth.Post("/logout", authHandler.Logout)
+	auth.Post("/logout-all", middleware.RejectImpersonation(), authHandler.LogoutAll)
+	auth.Get("/sessions", authHandler.Sessions)
+	auth.Delete("/sessions/:id", authHandler.RevokeSession)
+	userHandler := NewUserHandler(db)
+	auth.Get("/me", authHandler.Me)
+	auth.Put("/me", middleware.RejectImpersonation(), authHandler.UpdateProfile) // Nombre y email propios (email: current_password)
+	auth.Post("/change-password", middleware.RejectImpersonation(), userHandler.ChangePassword)
+
+	// Verificación en dos pasos (TOTP) del usuario autenticado
+	twoFactor := auth.Group("/2fa", middleware.RejectImpersonation())
+	twoFactorHandler := NewTwoFactorHandler(db, cfg)
+	twoFactor.Get("/", twoFactorHandler.Status)
+	twoFactor.Post("/setup", twoFactorHandler.Setup)     // Secreto pendiente y código QR
+	twoFactor.Post("/enable", twoFactorHandler.Enable)   // Confirma con un código; retorna los códigos de recuperación
+	twoFactor.Post("/disable", twoFactorHandler.Disable) // Contraseña y código
+	twoFactor.Post("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
 }
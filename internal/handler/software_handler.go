@@ -51,7 +51,7 @@ func (h *SoftwareHandler) GetByCompanyID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrCompanyNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+			return accessDenied(c, err, errors.ErrUnauthorized.Message)
 		}
 		return response.BadRequest(c, err.Error())
 	}
@@ -63,7 +63,7 @@ func (h *SoftwareHandler) GetByCompanyID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrSoftwareNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -92,7 +92,7 @@ func (h *SoftwareHandler) GetByID(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrSoftwareNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to software" {
-			return response.Unauthorized(c, "Unauthorized access to software")
+			return accessDenied(c, err, "Unauthorized access to software")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
@@ -128,7 +128,7 @@ func (h *SoftwareHandler) Create(c *fiber.Ctx) error {
 			return response.NotFound(c, "Company not found")
 		}
 		if errMsg == "unauthorized access to company" {
-			return response.Unauthorized(c, "Unauthorized access to company")
+			return accessDenied(c, err, "Unauthorized access to company")
 		}
 		if errMsg == "software already exists for this company" {
 			return response.Conflict(c, "A software configuration already exists for this company")
@@ -188,7 +188,7 @@ func (h *SoftwareHandler) Update(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrSoftwareNotFound.Message)
 		}
 		if errMsg == "unauthorized access to software" {
-			return response.Unauthorized(c, "Unauthorized access to software")
+			return accessDenied(c, err, "Unauthorized access to software")
 		}
 
 		// CHECK constraint errors
@@ -222,7 +222,7 @@ func (h *SoftwareHandler) Delete(c *fiber.Ctx) error {
 			return response.NotFound(c, errors.ErrSoftwareNotFound.Message)
 		}
 		if err.Error() == "unauthorized access to software" {
-			return response.Unauthorized(c, "Unauthorized access to software")
+			return accessDenied(c, err, "Unauthorized access to software")
 		}
 		return response.InternalServerError(c, errors.ErrInternalServer.Message)
 	}
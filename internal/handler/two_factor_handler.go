@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/mail"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/pkg/errors"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/validator"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TwoFactorHandler exposes the TOTP two-factor enrolment of the authenticated user
+type TwoFactorHandler struct {
+	service *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(db *database.Database, cfg *config.Config) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		service: service.NewTwoFactorService(
+			repository.NewTwoFactorRepository(db),
+			repository.NewUserRepository(db),
+			repository.NewSessionRepository(db),
+			mail.New(&cfg.Mail),
+		),
+	}
+}
+
+// Status returns whether 2FA is enabled and which companies require it
+func (h *TwoFactorHandler) Status(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	status, err := h.service.Status(userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor status retrieved successfully", status)
+}
+
+// Setup generates a pending TOTP secret and its QR code
+func (h *TwoFactorHandler) Setup(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	var req domain.TwoFactorSetupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateTwoFactorSetup(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	setup, err := h.service.Setup(userID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Scan the QR code and confirm with a code from the app", setup)
+}
+
+// Enable confirms the pending secret, enables 2FA and returns the recovery codes (shown once)
+func (h *TwoFactorHandler) Enable(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+	sessionID, _ := utils.GetSessionID(c)
+
+	var req domain.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateTwoFactorCode(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	codes, err := h.service.Enable(userID, sessionID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor authentication enabled, store the recovery codes in a safe place", codes)
+}
+
+// Disable turns 2FA off with the password and a TOTP or recovery code
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	var req domain.DisableTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateDisableTwoFactor(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	if err := h.service.Disable(userID, &req); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes (requires a TOTP code)
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	}
+
+	var req domain.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if err := validator.ValidateTwoFactorCode(&req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID, &req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, "Recovery codes regenerated, the previous ones no longer work", codes)
+}
+
+func (h *TwoFactorHandler) handleError(c *fiber.Ctx, err error) error {
+	msg := err.Error()
+	switch {
+	case msg == "user not found":
+		return response.Unauthorized(c, errors.ErrUnauthorized.Message)
+	case msg == "password is incorrect":
+		return response.BadRequest(c, "Password is incorrect")
+	case msg == "invalid two-factor code":
+		return response.BadRequest(c, "Invalid two-factor code")
+	case msg == "two-factor authentication not set up":
+		return response.BadRequest(c, "Two-factor authentication not set up, call /auth/2fa/setup first")
+	case msg == "two-factor authentication not enabled", msg == "two-factor authentication already enabled":
+		return response.Conflict(c, msg)
+	case strings.HasPrefix(msg, "cannot disable two-factor authentication"):
+		return response.Forbidden(c, msg)
+	}
+	return response.InternalServerError(c, errors.ErrInternalServer.Message)
+}
+
+// twoFactorRequiredMessage answers actions blocked by the company two-factor policy
+const twoFactorRequiredMessage = "The company requires two-factor authentication for this action, enable it at /auth/2fa/setup"
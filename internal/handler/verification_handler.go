@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/pkg/response"
+	"apidian-go/pkg/utils"
+	"apidian-go/pkg/xmldsig"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VerificationHandler struct {
+	service *service.VerificationService
+}
+
+func NewVerificationHandler(db *database.Database, cfg *config.Config) *VerificationHandler {
+	store := storage.New(&cfg.Storage)
+	keys := keyring.New(repository.NewDataKeyRepository(db), store)
+	verificationService := service.NewVerificationService(
+		repository.NewInvoiceRepository(db),
+		repository.NewCompanyRepository(db),
+		repository.NewResolutionRepository(db),
+		repository.NewSoftwareRepository(db),
+		repository.NewCertificateRepository(db),
+		keys,
+	)
+
+	return &VerificationHandler{
+		service: verificationService,
+	}
+}
+
+// VerifyXML verifies an uploaded signed XML (own or third-party)
+func (h *VerificationHandler) VerifyXML(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	var req domain.VerifyXMLRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if req.XML == "" {
+		return response.BadRequest(c, "The 'xml' field is required")
+	}
+
+	report, err := h.service.VerifyXML(&req, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, verificationMessage(report), report)
+}
+
+// VerifyInvoice verifies the stored signed XML of an invoice
+func (h *VerificationHandler) VerifyInvoice(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return response.Unauthorized(c, "User not authenticated")
+	}
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return response.BadRequest(c, "Invalid ID")
+	}
+
+	report, err := h.service.VerifyInvoice(id, userID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	return response.Success(c, verificationMessage(report), report)
+}
+
+func (h *VerificationHandler) handleError(c *fiber.Ctx, err error) error {
+	errMsg := err.Error()
+	switch {
+	case errMsg == "invoice not found":
+		return response.NotFound(c, "Invoice not found")
+	case errMsg == "unauthorized access to invoice":
+		return accessDenied(c, err, "Unauthorized access to invoice")
+	case errMsg == "invoice has not been signed":
+		return response.BadRequest(c, "Invoice has not been signed")
+	case errMsg == "signed XML not found in storage":
+		return response.NotFound(c, "Signed XML not found in storage")
+	case errMsg == "xml must be valid base64 encoded data":
+		return response.BadRequest(c, "XML must be valid base64 encoded data")
+	case strings.HasPrefix(errMsg, "invalid XML"):
+		return response.BadRequest(c, errMsg)
+	case errors.Is(err, xmldsig.ErrNoSignature):
+		return response.UnprocessableEntity(c, "The document has no XML signature", nil)
+	case strings.HasPrefix(errMsg, "unsupported") || strings.HasPrefix(errMsg, "signature has no"):
+		return response.UnprocessableEntity(c, errMsg, nil)
+	}
+	return response.InternalServerError(c, errMsg)
+}
+
+func verificationMessage(report *domain.VerificationReport) string {
+	if report.Valid {
+		return "Document is intact: signature and UUID are valid"
+	}
+	return "Document verification failed"
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// Los triggers de auditoría (audit_row_change) registran como autor de cada cambio la variable
+// de sesión app.user_id. Se fija con set_config(..., true), que solo dura la transacción actual,
+// así que una conexión del pool nunca arrastra el usuario de otra petición. Sin actor (procesos
+// en segundo plano) el cambio queda con user_id NULL.
+
+// SetActor fija app.user_id en una transacción abierta; userID 0 no fija nada
+func SetActor(tx *sql.Tx, userID int64) error {
+	if userID == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(`SELECT set_config('app.user_id', $1, true)`, strconv.FormatInt(userID, 10)); err != nil {
+		return fmt.Errorf("error setting audit actor: %w", err)
+	}
+	return nil
+}
+
+// ExecAs es DB.Exec a nombre del usuario: con actor, la sentencia corre en una transacción con
+// app.user_id
+func (d *Database) ExecAs(userID int64, query string, args ...any) (sql.Result, error) {
+	if userID == 0 {
+		return d.DB.Exec(query, args...)
+	}
+
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := SetActor(tx, userID); err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// QueryRowAs es DB.QueryRow a nombre del usuario, para INSERT/UPDATE ... RETURNING. La sentencia
+// se ejecuta al llamar Scan.
+func (d *Database) QueryRowAs(userID int64, query string, args ...any) *ActorRow {
+	return &ActorRow{db: d, userID: userID, query: query, args: args}
+}
+
+// ActorRow es el resultado diferido de QueryRowAs
+type ActorRow struct {
+	db     *Database
+	userID int64
+	query  string
+	args   []any
+}
+
+// Scan ejecuta la sentencia y copia la fila en dest; sin filas retorna sql.ErrNoRows
+func (r *ActorRow) Scan(dest ...any) error {
+	if r.userID == 0 {
+		return r.db.DB.QueryRow(r.query, r.args...).Scan(dest...)
+	}
+
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := SetActor(tx, r.userID); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(r.query, r.args...).Scan(dest...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
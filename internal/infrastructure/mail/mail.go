@@ -0,0 +1,146 @@
+package mail
+
+import (
+	"apidian-go/internal/config"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Message es un correo de texto plano para un destinatario
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer entrega correos transaccionales (verificación de email, restablecimiento de contraseña)
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New crea el mailer configurado en MAIL_DRIVER (log o smtp)
+func New(cfg *config.MailConfig) Mailer {
+	if cfg.Driver == config.MailDriverSMTP {
+		return NewSMTPMailer(cfg)
+	}
+	return LogMailer{}
+}
+
+// LogMailer escribe el correo completo en el log en lugar de enviarlo; solo para desarrollo,
+// porque el cuerpo incluye los enlaces con tokens
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("✉ [mail] to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPMailer envía por SMTP. Usa STARTTLS si el servidor lo anuncia y se autentica solo si hay
+// usuario, así funciona igual con un proveedor real que con MailHog o Mailpit en local.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	timeout  time.Duration
+}
+
+func NewSMTPMailer(cfg *config.MailConfig) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:     cfg.Host,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+		timeout:  15 * time.Second,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	data, err := m.build(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", m.addr, m.timeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(m.timeout))
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error starting SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("error starting TLS: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("error authenticating with SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("error setting sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("error setting recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error sending message: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("error sending message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending message: %w", err)
+	}
+	return client.Quit()
+}
+
+// build arma el mensaje RFC 5322 en UTF-8 (asunto codificado y cuerpo quoted-printable)
+func (m *SMTPMailer) build(from, to *mail.Address, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, fmt.Errorf("error encoding message: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
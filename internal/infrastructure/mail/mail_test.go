@@ -0,0 +1,274 @@
+package mail
+
+import (
+	"apidian-go/internal/config"
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpSession es lo que el stand-in recibió en una conexión
+type smtpSession struct {
+	auth string
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTP es un servidor SMTP mínimo en memoria, como MailHog o Mailpit: anuncia AUTH PLAIN,
+// registra la sesión y responde 550 a los destinatarios en rejectRcpt.
+type fakeSMTP struct {
+	listener   net.Listener
+	rejectRcpt string
+
+	mu       sync.Mutex
+	sessions []smtpSession
+	wg       sync.WaitGroup
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeSMTP{listener: ln}
+	f.wg.Add(1)
+	go f.serve()
+	t.Cleanup(func() {
+		ln.Close()
+		f.wg.Wait()
+	})
+	return f
+}
+
+func (f *fakeSMTP) config(username, password string) *config.MailConfig {
+	host, port, _ := net.SplitHostPort(f.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return &config.MailConfig{
+		Driver:   config.MailDriverSMTP,
+		Host:     host,
+		Port:     p,
+		Username: username,
+		Password: password,
+		From:     "Apidian <no-reply@apidian.test>",
+	}
+}
+
+func (f *fakeSMTP) serve() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			f.handle(conn)
+		}()
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var s smtpSession
+	reply("220 fake.smtp ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake.smtp")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN "):
+			s.auth = line[len("AUTH PLAIN "):]
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			rcpt := line[len("RCPT TO:"):]
+			if f.rejectRcpt != "" && strings.Contains(rcpt, f.rejectRcpt) {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			s.rcpt = append(s.rcpt, rcpt)
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			f.mu.Lock()
+			f.sessions = append(f.sessions, s)
+			f.mu.Unlock()
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (f *fakeSMTP) delivered() []smtpSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]smtpSession(nil), f.sessions...)
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantAuth string
+	}{
+		{name: "without credentials skips AUTH"},
+		{name: "with credentials uses AUTH PLAIN", username: "apidian", password: "s3cret", wantAuth: "\x00apidian\x00s3cret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTP(t)
+			mailer := NewSMTPMailer(server.config(tt.username, tt.password))
+
+			err := mailer.Send(Message{
+				To:      "Cliente <cliente@example.com>",
+				Subject: "Verificación de email",
+				Body:    "Hola, confirma tu correo en https://app.test/verify?token=abc=123\n.\nGracias",
+			})
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			sessions := server.delivered()
+			if len(sessions) != 1 {
+				t.Fatalf("delivered %d sessions, want 1", len(sessions))
+			}
+			s := sessions[0]
+
+			auth := ""
+			if s.auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(s.auth)
+				if err != nil {
+					t.Fatalf("AUTH PLAIN payload: %v", err)
+				}
+				auth = string(decoded)
+			}
+			if auth != tt.wantAuth {
+				t.Errorf("AUTH = %q, want %q", auth, tt.wantAuth)
+			}
+			if s.from != "<no-reply@apidian.test>" {
+				t.Errorf("MAIL FROM = %q", s.from)
+			}
+			if len(s.rcpt) != 1 || s.rcpt[0] != "<cliente@example.com>" {
+				t.Errorf("RCPT TO = %v", s.rcpt)
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(s.data))
+			if err != nil {
+				t.Fatalf("parse delivered message: %v", err)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != "Verificación de email" {
+				t.Errorf("Subject = %q, %v", subject, err)
+			}
+			if got := msg.Header.Get("To"); got != `"Cliente" <cliente@example.com>` {
+				t.Errorf("To = %q", got)
+			}
+			if got := msg.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+				t.Errorf("Content-Transfer-Encoding = %q", got)
+			}
+			body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+			if err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			// La línea con solo "." debe sobrevivir al dot-stuffing de DATA
+			got := strings.TrimRight(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+			want := "Hola, confirma tu correo en https://app.test/verify?token=abc=123\n.\nGracias"
+			if got != want {
+				t.Errorf("body = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerErrors(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rejectRcpt = "nadie@example.com"
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+	closedHost, closedPort, _ := net.SplitHostPort(closedAddr)
+	port, _ := strconv.Atoi(closedPort)
+
+	badFrom := server.config("", "")
+	badFrom.From = "no es un correo"
+
+	tests := []struct {
+		name    string
+		cfg     *config.MailConfig
+		to      string
+		wantErr string
+	}{
+		{"invalid sender", badFrom, "cliente@example.com", "invalid MAIL_FROM"},
+		{"invalid recipient", server.config("", ""), "cliente", "invalid recipient"},
+		{"recipient rejected by server", server.config("", ""), "nadie@example.com", "error setting recipient"},
+		{"server unreachable", &config.MailConfig{Host: closedHost, Port: port, From: "no-reply@apidian.test"}, "cliente@example.com", "error connecting to SMTP server"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewSMTPMailer(tt.cfg).Send(Message{To: tt.to, Subject: "x", Body: "x"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if n := len(server.delivered()); n != 0 {
+		t.Errorf("delivered %d messages, want none", n)
+	}
+}
+
+func TestNewSelectsDriver(t *testing.T) {
+	if _, ok := New(&config.MailConfig{Driver: config.MailDriverLog}).(LogMailer); !ok {
+		t.Error("log driver must return LogMailer")
+	}
+	if _, ok := New(&config.MailConfig{Driver: config.MailDriverSMTP, Host: "localhost", Port: 25}).(*SMTPMailer); !ok {
+		t.Error("smtp driver must return *SMTPMailer")
+	}
+}
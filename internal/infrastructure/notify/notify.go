@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"apidian-go/internal/config"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Tipos de evento
+const (
+	EventCertificateExpiring  = "certificate.expiring"
+	EventCertificateExpired   = "certificate.expired"
+	EventCertificateActivated = "certificate.activated"
+
+	EventResolutionLow       = "resolution.low"       // Pocos consecutivos restantes
+	EventResolutionExpiring  = "resolution.expiring"  // Próxima a vencer
+	EventResolutionExhausted = "resolution.exhausted" // Sin consecutivos
+	EventResolutionExpired   = "resolution.expired"   // Vencida
+	EventResolutionActivated = "resolution.activated" // Continuación en uso
+)
+
+// Event es una alerta operativa dirigida a una empresa
+type Event struct {
+	Type       string    `json:"event"`
+	CompanyID  int64     `json:"company_id"`
+	Message    string    `json:"message"`
+	Data       any       `json:"data,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Notifier entrega alertas (log, webhook)
+type Notifier interface {
+	Notify(event Event) error
+}
+
+// New crea el notificador configurado: siempre registra en el log y, si ALERT_WEBHOOK_URL
+// está definido, también envía el evento por webhook
+func New(cfg *config.AlertsConfig) Notifier {
+	notifiers := Multi{LogNotifier{}}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret))
+	}
+	return notifiers
+}
+
+// LogNotifier escribe la alerta en el log de la aplicación
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(event Event) error {
+	log.Printf("⚠ [%s] company %d: %s", event.Type, event.CompanyID, event.Message)
+	return nil
+}
+
+// WebhookNotifier envía la alerta como JSON por POST. Si hay secreto, el cuerpo se firma con
+// HMAC-SHA256 en la cabecera X-Apidian-Signature ("sha256=<hex>").
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Notify(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Apidian-Event", event.Type)
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set("X-Apidian-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Multi entrega la alerta a todos los notificadores y retorna los errores combinados
+type Multi []Notifier
+
+func (m Multi) Notify(event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
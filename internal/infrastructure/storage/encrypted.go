@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"apidian-go/pkg/crypto"
+	"fmt"
+	"log"
+)
+
+// EncryptedStorage cifra con AES-256-GCM (llave de datos de la empresa) todo lo que guarda
+// y descifra en memoria al leer. Los archivos sin cifrar anteriores a esta capa se cifran en la
+// primera lectura.
+type EncryptedStorage struct {
+	inner   Storage
+	dataKey []byte
+}
+
+func NewEncryptedStorage(inner Storage, dataKey []byte) *EncryptedStorage {
+	return &EncryptedStorage{inner: inner, dataKey: dataKey}
+}
+
+// Put cifra y guarda el contenido; la clave se autentica como dato adicional
+func (s *EncryptedStorage) Put(key string, data []byte) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := crypto.SealBlob(s.dataKey, data, []byte(cleaned))
+	if err != nil {
+		return fmt.Errorf("error encrypting %s: %w", cleaned, err)
+	}
+	return s.inner.Put(cleaned, sealed)
+}
+
+// Get lee y descifra el contenido
+func (s *EncryptedStorage) Get(key string) ([]byte, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.inner.Get(cleaned)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archivos legados guardados en claro: se re-escriben cifrados. Si falla se sirven igual y se
+	// reintenta en la próxima lectura. Las claves se escriben una vez (XML firmados, ZIP,
+	// certificados), así que no se pisa un contenido más nuevo.
+	if !crypto.IsSealedBlob(data) {
+		if err := s.Put(cleaned, data); err != nil {
+			log.Printf("Warning: could not encrypt legacy file %s: %v", cleaned, err)
+		}
+		return data, nil
+	}
+
+	plaintext, err := crypto.OpenBlob(s.dataKey, data, []byte(cleaned))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", cleaned, err)
+	}
+	return plaintext, nil
+}
+
+func (s *EncryptedStorage) Delete(key string) error {
+	return s.inner.Delete(key)
+}
+
+func (s *EncryptedStorage) Exists(key string) (bool, error) {
+	return s.inner.Exists(key)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import "path"
+
+// Las claves reproducen la estructura histórica de storage/app para que los
+// archivos existentes sigan siendo válidos con el backend local.
+
+// CompanyProfileKey retorna el prefijo del perfil de una empresa
+func CompanyProfileKey(nit string) string {
+	return path.Join("companies", nit, "profile")
+}
+
+// CompanyLogoKey retorna la clave del logo de una empresa (ext incluye el punto)
+func CompanyLogoKey(nit, ext string) string {
+	return path.Join(CompanyProfileKey(nit), "logo"+ext)
+}
+
+// CertificateKey retorna la clave de un certificado de la empresa
+func CertificateKey(nit, filename string) string {
+	return path.Join("companies", nit, "certificates", filename)
+}
+
+// InvoiceKey retorna el prefijo de los archivos de una factura
+func InvoiceKey(nit, numero string) string {
+	return path.Join("companies", nit, "documents", "invoices", numero)
+}
+
+// InvoiceXMLKey retorna la clave del XML sin firmar de una factura
+func InvoiceXMLKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), numero+".xml")
+}
+
+// InvoiceSignedXMLKey retorna la clave del XML firmado de una factura
+func InvoiceSignedXMLKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), numero+"_signed.xml")
+}
+
+// InvoiceZIPKey retorna la clave del ZIP enviado a DIAN
+func InvoiceZIPKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), numero+".zip")
+}
+
+// InvoiceApplicationResponseKey retorna la clave del ApplicationResponse de DIAN
+func InvoiceApplicationResponseKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), "ApplicationResponse-"+numero+".xml")
+}
+
+// AttachedDocumentXMLKey retorna la clave del AttachedDocument sin firmar
+func AttachedDocumentXMLKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), "AttachedDocument-"+numero+".xml")
+}
+
+// AttachedDocumentSignedXMLKey retorna la clave del AttachedDocument firmado
+func AttachedDocumentSignedXMLKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), "ad"+numero+".xml")
+}
+
+// AttachedDocumentZIPKey retorna la clave del ZIP final para el cliente
+func AttachedDocumentZIPKey(nit, numero string) string {
+	return path.Join(InvoiceKey(nit, numero), "ad"+numero+".zip")
+}
+
+// DefaultLogoKey retorna la clave del logo por defecto
+func DefaultLogoKey() string {
+	return "assets/logo_default.png"
+}
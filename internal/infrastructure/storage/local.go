@@ -2,14 +2,11 @@ package storage
 
 import (
 	"fmt"
-	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
-	"strings"
-	"time"
 )
 
+// LocalStorage guarda los blobs en el sistema de archivos bajo basePath (storage/app)
 type LocalStorage struct {
 	basePath string
 }
@@ -18,76 +15,94 @@ func NewLocalStorage(basePath string) *LocalStorage {
 	return &LocalStorage{basePath: basePath}
 }
 
-// SaveFile guarda un archivo en el sistema de archivos local
-func (s *LocalStorage) SaveFile(file *multipart.FileHeader, subdir string) (string, error) {
-	// Crear directorio si no existe
-	fullPath := filepath.Join(s.basePath, subdir)
-	if err := os.MkdirAll(fullPath, 0755); err != nil {
-		return "", fmt.Errorf("error creating directory: %w", err)
+// Put escribe el archivo de forma atómica (archivo temporal + rename)
+func (s *LocalStorage) Put(key string, data []byte) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
 	}
 
-	// Generar nombre único para el archivo
-	timestamp := time.Now().Unix()
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%d_%s%s", timestamp, sanitizeFilename(file.Filename), ext)
-	
-	// Ruta completa del archivo
-	filePath := filepath.Join(fullPath, filename)
+	// Crear directorio si no existe
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
 
-	// Abrir archivo subido
-	src, err := file.Open()
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
 	if err != nil {
-		return "", fmt.Errorf("error opening uploaded file: %w", err)
+		return fmt.Errorf("error creating file: %w", err)
 	}
-	defer src.Close()
+	defer os.Remove(tmp.Name())
 
-	// Crear archivo destino
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("error creating file: %w", err)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0640); err != nil {
+		return fmt.Errorf("error saving file: %w", err)
 	}
-	defer dst.Close()
 
-	// Copiar contenido
-	if _, err := io.Copy(dst, src); err != nil {
-		return "", fmt.Errorf("error saving file: %w", err)
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return fmt.Errorf("error saving file: %w", err)
+	}
+	return nil
+}
+
+// Get lee un archivo del sistema
+func (s *LocalStorage) Get(key string) ([]byte, error) {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return nil, err
 	}
 
-	// Retornar ruta relativa
-	return filepath.Join(subdir, filename), nil
+	data, err := os.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return data, nil
 }
 
-// DeleteFile elimina un archivo del sistema
-func (s *LocalStorage) DeleteFile(relativePath string) error {
-	fullPath := filepath.Join(s.basePath, relativePath)
+// Delete elimina un archivo del sistema
+func (s *LocalStorage) Delete(key string) error {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+
 	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error deleting file: %w", err)
 	}
 	return nil
 }
 
-// FileExists verifica si un archivo existe
-func (s *LocalStorage) FileExists(relativePath string) bool {
-	fullPath := filepath.Join(s.basePath, relativePath)
-	_, err := os.Stat(fullPath)
-	return err == nil
-}
+// Exists verifica si un archivo existe
+func (s *LocalStorage) Exists(key string) (bool, error) {
+	fullPath, err := s.fullPath(key)
+	if err != nil {
+		return false, err
+	}
 
-// GetFilePath obtiene la ruta completa de un archivo
-func (s *LocalStorage) GetFilePath(relativePath string) string {
-	return filepath.Join(s.basePath, relativePath)
+	_, err = os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking file: %w", err)
+	}
+	return true, nil
 }
 
-// sanitizeFilename limpia el nombre del archivo
-func sanitizeFilename(filename string) string {
-	// Remover extensión
-	name := strings.TrimSuffix(filename, filepath.Ext(filename))
-	// Reemplazar caracteres no permitidos
-	name = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
-			return r
-		}
-		return '_'
-	}, name)
-	return name
+// fullPath obtiene la ruta completa de una clave dentro de basePath
+func (s *LocalStorage) fullPath(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.basePath, filepath.FromSlash(cleaned)), nil
 }
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"apidian-go/internal/config"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage implementa Storage sobre la API REST de S3 (AWS, MinIO, Ceph, R2, etc.)
+// firmando las peticiones con AWS Signature V4.
+type S3Storage struct {
+	cfg      config.S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Storage(cfg config.S3Config) *S3Storage {
+	endpoint, _ := url.Parse(cfg.Endpoint) // validado en config.Load
+	return &S3Storage{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Put sube un objeto
+func (s *S3Storage) Put(key string, data []byte) error {
+	resp, err := s.do(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError("put", key, resp)
+	}
+	return nil
+}
+
+// Get descarga un objeto
+func (s *S3Storage) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError("get", key, resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading object %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete elimina un objeto; S3 responde 204 aunque no exista
+func (s *S3Storage) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError("delete", key, resp)
+	}
+	return nil
+}
+
+// Exists consulta el objeto con HEAD
+func (s *S3Storage) Exists(key string) (bool, error) {
+	resp, err := s.do(http.MethodHead, key, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, s.responseError("head", key, resp)
+	}
+}
+
+// do construye, firma y ejecuta la petición para una clave
+func (s *S3Storage) do(method, key string, body []byte) (*http.Response, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	objectKey := cleaned
+	if s.cfg.Prefix != "" {
+		objectKey = path.Join(strings.Trim(s.cfg.Prefix, "/"), cleaned)
+	}
+
+	// Path-style (MinIO y compatibles): {endpoint}/{bucket}/{key}
+	// Virtual-hosted (AWS):             {bucket}.{endpoint}/{key}
+	host := s.endpoint.Host
+	objectPath := "/" + s.cfg.Bucket + "/" + objectKey
+	if !s.cfg.ForcePathStyle {
+		host = s.cfg.Bucket + "." + s.endpoint.Host
+		objectPath = "/" + objectKey
+	}
+	objectPath = strings.TrimSuffix(s.endpoint.Path, "/") + objectPath
+
+	u := &url.URL{
+		Scheme:  s.endpoint.Scheme,
+		Host:    host,
+		Path:    objectPath,
+		RawPath: encodePath(objectPath),
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error building storage request: %w", err)
+	}
+	if body != nil {
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	s.sign(req, u.RawPath, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error contacting storage: %w", err)
+	}
+	return resp, nil
+}
+
+// sign agrega las cabeceras de AWS Signature V4. Se firman host y todas las cabeceras presentes
+// en la petición al momento de firmar (las que agrega http.Client después no entran en la firma).
+func (s *S3Storage) sign(req *http.Request, canonicalURI string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func (s *S3Storage) responseError(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("storage %s %s failed: status %d: %s", op, key, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// encodePath codifica cada segmento según RFC 3986 como exige SigV4 (sin codificar "/")
+func encodePath(p string) string {
+	return uriEncode(p, false)
+}
+
+// canonicalQuery ordena los parámetros por nombre y valor y los codifica según RFC 3986
+func canonicalQuery(values url.Values) string {
+	type param struct{ name, value string }
+	params := make([]param, 0, len(values))
+	for name, vals := range values {
+		for _, v := range vals {
+			params = append(params, param{uriEncode(name, true), uriEncode(v, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].name != params[j].name {
+			return params[i].name < params[j].name
+		}
+		return params[i].value < params[j].value
+	})
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.name + "=" + p.value
+	}
+	return strings.Join(pairs, "&")
+}
+
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
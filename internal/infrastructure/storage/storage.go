@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"apidian-go/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound se retorna cuando la clave solicitada no existe en el storage
+var ErrNotFound = errors.New("object not found")
+
+// ErrNoMemoryTempDir se retorna cuando no hay tmpfs para materializar material de firma descifrado
+var ErrNoMemoryTempDir = errors.New("no in-memory temp directory (/dev/shm) available; refusing to write decrypted key material to disk")
+
+// memoryTempPath es el tmpfs donde Materialize escribe los certificados descifrados
+var memoryTempPath = "/dev/shm"
+
+// Storage abstrae el almacenamiento de blobs (XML, ZIP, certificados, logos, ApplicationResponse).
+// Las claves son rutas relativas con "/" como separador, p. ej.
+// companies/{nit}/documents/invoices/{numero}/{numero}_signed.xml
+type Storage interface {
+	// Put guarda (o reemplaza) el contenido de una clave
+	Put(key string, data []byte) error
+	// Get retorna el contenido de una clave o ErrNotFound
+	Get(key string) ([]byte, error)
+	// Delete elimina una clave; no falla si no existe
+	Delete(key string) error
+	// Exists indica si la clave existe
+	Exists(key string) (bool, error)
+}
+
+// New crea el backend configurado en STORAGE_DRIVER (local o s3)
+func New(cfg *config.StorageConfig) Storage {
+	if cfg.Driver == config.StorageDriverS3 {
+		return NewS3Storage(cfg.S3)
+	}
+	return NewLocalStorage(cfg.AppRoot())
+}
+
+// Materialize copia una clave a un archivo temporal para librerías que solo aceptan rutas en disco
+// (firmador y cliente SOAP de ubl21-dian). cleanup elimina el directorio temporal completo,
+// incluidos los archivos derivados que la librería cree junto al original (.pem, .client.pem).
+// El archivo vive en /dev/shm (tmpfs) y nunca toca el disco; sin tmpfs retorna ErrNoMemoryTempDir
+// en vez de escribir el certificado descifrado en el directorio temporal del sistema.
+func Materialize(s Storage, key string) (filePath string, cleanup func(), err error) {
+	tmpDir, err := memoryTempDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	data, err := s.Get(key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp(tmpDir, "apidian-")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	filePath = filepath.Join(dir, path.Base(key))
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error writing temp file: %w", err)
+	}
+
+	return filePath, cleanup, nil
+}
+
+// memoryTempDir retorna el directorio respaldado en memoria (tmpfs)
+func memoryTempDir() (string, error) {
+	if info, err := os.Stat(memoryTempPath); err == nil && info.IsDir() {
+		return memoryTempPath, nil
+	}
+	return "", ErrNoMemoryTempDir
+}
+
+// cleanKey normaliza una clave y rechaza rutas absolutas o que escapen de la raíz
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(key, "\\", "/"))
+	if key == "" || path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return cleaned, nil
+}
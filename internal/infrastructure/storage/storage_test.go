@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"apidian-go/pkg/crypto"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"companies/900/logo.png", "companies/900/logo.png", false},
+		{"companies//900/./logo.png", "companies/900/logo.png", false},
+		{`companies\900\logo.png`, "companies/900/logo.png", false},
+		{"companies/900/../901/logo.png", "companies/901/logo.png", false},
+		{"", "", true},
+		{".", "", true},
+		{"..", "", true},
+		{"../etc/passwd", "", true},
+		{"companies/../../etc/passwd", "", true},
+		{"/etc/passwd", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := cleanKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cleanKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cleanKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+	const key = "companies/900123456/certificates/cert.p12"
+
+	if ok, err := s.Exists(key); err != nil || ok {
+		t.Fatalf("Exists before put = %v, %v; want false, nil", ok, err)
+	}
+	if _, err := s.Get(key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before put error = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Put(key, []byte("v1")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(key, []byte("v2")); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+	got, err := s.Get(key)
+	if err != nil || string(got) != "v2" {
+		t.Fatalf("Get = %q, %v; want v2, nil", got, err)
+	}
+
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete missing key must not fail: %v", err)
+	}
+	if ok, _ := s.Exists(key); ok {
+		t.Error("Exists after delete = true")
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+	const key = "companies/900123456/certificates/cert.p12"
+	if err := s.Put(key, []byte("p12")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	original := memoryTempPath
+	defer func() { memoryTempPath = original }()
+
+	t.Run("tmpfs available", func(t *testing.T) {
+		memoryTempPath = t.TempDir()
+
+		filePath, cleanup, err := Materialize(s, key)
+		if err != nil {
+			t.Fatalf("Materialize: %v", err)
+		}
+		if !strings.HasPrefix(filePath, memoryTempPath) || filepath.Base(filePath) != "cert.p12" {
+			t.Errorf("filePath = %q, want cert.p12 under %q", filePath, memoryTempPath)
+		}
+		if data, err := os.ReadFile(filePath); err != nil || string(data) != "p12" {
+			t.Errorf("materialized content = %q, %v", data, err)
+		}
+
+		cleanup()
+		if _, err := os.Stat(filepath.Dir(filePath)); !os.IsNotExist(err) {
+			t.Errorf("cleanup must remove the temp directory, stat err = %v", err)
+		}
+	})
+
+	t.Run("no tmpfs", func(t *testing.T) {
+		memoryTempPath = filepath.Join(t.TempDir(), "missing")
+
+		if _, _, err := Materialize(s, key); !errors.Is(err, ErrNoMemoryTempDir) {
+			t.Fatalf("Materialize error = %v, want ErrNoMemoryTempDir", err)
+		}
+	})
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	inner := NewLocalStorage(t.TempDir())
+	dek, err := crypto.GenerateDataKey()
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+	s := NewEncryptedStorage(inner, dek)
+	const key = "companies/900123456/documents/invoices/SETP1/SETP1_signed.xml"
+
+	if err := s.Put(key, []byte("<Invoice/>")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	raw, _ := inner.Get(key)
+	if !crypto.IsSealedBlob(raw) || strings.Contains(string(raw), "<Invoice/>") {
+		t.Fatalf("stored content is not encrypted: %q", raw)
+	}
+	if got, err := s.Get(key); err != nil || string(got) != "<Invoice/>" {
+		t.Fatalf("Get = %q, %v; want <Invoice/>", got, err)
+	}
+
+	// Otra llave de datos u otra ruta no descifran el archivo
+	otherDEK, _ := crypto.GenerateDataKey()
+	if _, err := NewEncryptedStorage(inner, otherDEK).Get(key); err == nil {
+		t.Error("Get with another data key must fail")
+	}
+	inner.Put("companies/900123456/moved.xml", raw)
+	if _, err := s.Get("companies/900123456/moved.xml"); err == nil {
+		t.Error("Get of a blob moved to another key must fail")
+	}
+}
+
+func TestEncryptedStorageEncryptsLegacyFiles(t *testing.T) {
+	inner := NewLocalStorage(t.TempDir())
+	dek, _ := crypto.GenerateDataKey()
+	s := NewEncryptedStorage(inner, dek)
+	const key = "companies/900123456/certificates/cert.p12"
+
+	if err := inner.Put(key, []byte("legacy plaintext")); err != nil {
+		t.Fatalf("Put legacy: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := s.Get(key)
+		if err != nil || string(got) != "legacy plaintext" {
+			t.Fatalf("Get #%d = %q, %v; want legacy plaintext", i+1, got, err)
+		}
+		raw, _ := inner.Get(key)
+		if !crypto.IsSealedBlob(raw) {
+			t.Fatalf("after read #%d the legacy file is still plaintext: %q", i+1, raw)
+		}
+	}
+}
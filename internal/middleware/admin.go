@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/response"
+	"encoding/json"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePlatformAdmin limita la ruta a los administradores de la plataforma. El rol se consulta
+// en cada petición (no va en el JWT) para que retirarlo tenga efecto inmediato. Las sesiones de
+// suplantación no tienen acceso aunque las abra un administrador.
+func RequirePlatformAdmin(db *database.Database) fiber.Handler {
+	userRepo := repository.NewUserRepository(db)
+
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("impersonator_id").(int64); ok {
+			return response.Forbidden(c, "Not available while impersonating a user")
+		}
+		userID, ok := c.Locals("user_id").(int64)
+		if !ok {
+			return response.Unauthorized(c, "User not authenticated")
+		}
+
+		admin, err := userRepo.IsPlatformAdmin(userID)
+		if err != nil {
+			return response.InternalServerError(c, "Error checking platform admin role")
+		}
+		if !admin {
+			return response.Forbidden(c, "Platform admin role required")
+		}
+		return c.Next()
+	}
+}
+
+// RejectImpersonation bloquea en sesiones de suplantación las rutas que cambian las credenciales
+// o el perfil del usuario
+func RejectImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("impersonator_id").(int64); ok {
+			return response.Forbidden(c, "Not available while impersonating a user")
+		}
+		return c.Next()
+	}
+}
+
+// logImpersonatedRequest deja en la bitácora de administración cada petición de escritura hecha
+// suplantando a un usuario
+func logImpersonatedRequest(c *fiber.Ctx, repo *repository.AdminRepository, adminID, userID int64) {
+	details, _ := json.Marshal(map[string]string{
+		"method": c.Method(),
+		"path":   c.Path(),
+	})
+	ip := c.IP()
+	entry := &domain.AdminAction{
+		AdminID:    adminID,
+		Action:     domain.AdminActionImpersonatedRequest,
+		TargetType: domain.AdminTargetUser,
+		TargetID:   userID,
+		Details:    details,
+		IPAddress:  &ip,
+	}
+	if err := repo.LogAction(entry); err != nil {
+		log.Printf("Warning: failed to log impersonated request %s %s: %v", c.Method(), c.Path(), err)
+	}
+}
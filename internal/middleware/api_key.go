@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
+	"apidian-go/pkg/response"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyFromRequest obtiene la API key del header X-API-Key o de Authorization: Bearer adk_...
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key := c.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(token, domain.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey valida la key y la deja en el contexto. No define user_id: solo las rutas
+// con APIKeyScope autorizan la key, así las demás la rechazan.
+func authenticateAPIKey(c *fiber.Ctx, repo *repository.APIKeyRepository, plain string) error {
+	key, err := repo.GetActiveByHash(crypto.HashToken(plain))
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return response.Unauthorized(c, "Invalid, expired or revoked API key")
+		}
+		return response.InternalServerError(c, "Error validating API key")
+	}
+
+	if err := repo.Touch(key.ID, c.IP()); err != nil {
+		log.Printf("Warning: failed to update last use of api key %d: %v", key.ID, err)
+	}
+
+	c.Locals("api_key", key)
+	return c.Next()
+}
+
+// RejectAPIKeys bloquea las API keys en rutas exclusivas de usuarios (empresas, certificados, usuarios...)
+func RejectAPIKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("api_key").(*domain.APIKey); ok {
+			return response.Forbidden(c, "This endpoint is not available for API keys")
+		}
+		return c.Next()
+	}
+}
+
+// APIKeyScope autoriza las peticiones con API key en cada ruta: exige el permiso y que la
+// empresa de la petición sea la de la key. Las peticiones con JWT no se autorizan aquí (lo hace
+// el servicio), pero con la misma empresa se cuentan contra su plan (RateLimiter.ByCompany).
+type APIKeyScope struct {
+	repo    *repository.APIKeyRepository
+	limiter *RateLimiter
+}
+
+func NewAPIKeyScope(db *database.Database, limiter *RateLimiter) *APIKeyScope {
+	return &APIKeyScope{repo: repository.NewAPIKeyRepository(db), limiter: limiter}
+}
+
+// companyResolver obtiene la empresa a la que va dirigida la petición; key es nil con JWT
+type companyResolver func(c *fiber.Ctx, key *domain.APIKey) (int64, error)
+
+// Query toma la empresa del query param company_id; si no viene usa la de la key
+func (s *APIKeyScope) Query(permission string) fiber.Handler {
+	return s.require(permission, func(c *fiber.Ctx, key *domain.APIKey) (int64, error) {
+		if c.Query("company_id") == "" {
+			if key == nil {
+				return 0, errors.New("company_id is required")
+			}
+			c.Request().URI().QueryArgs().Set("company_id", strconv.FormatInt(key.CompanyID, 10))
+			return key.CompanyID, nil
+		}
+		return strconv.ParseInt(c.Query("company_id"), 10, 64)
+	})
+}
+
+// Body toma la empresa del campo company_id del JSON
+func (s *APIKeyScope) Body(permission string) fiber.Handler {
+	return s.require(permission, func(c *fiber.Ctx, key *domain.APIKey) (int64, error) {
+		return bodyID(c, "company_id")
+	})
+}
+
+// BodyResource toma la empresa del recurso referenciado en un campo del JSON (p. ej. document_id)
+func (s *APIKeyScope) BodyResource(permission, resource, field string) fiber.Handler {
+	return s.require(permission, func(c *fiber.Ctx, key *domain.APIKey) (int64, error) {
+		id, err := bodyID(c, field)
+		if err != nil {
+			return 0, err
+		}
+		return s.repo.ResourceCompanyID(resource, id)
+	})
+}
+
+// Resource toma la empresa del recurso del parámetro :id
+func (s *APIKeyScope) Resource(permission, resource string) fiber.Handler {
+	return s.ResourceParam(permission, resource, "id")
+}
+
+// ResourceParam toma la empresa del recurso identificado por el parámetro param
+func (s *APIKeyScope) ResourceParam(permission, resource, param string) fiber.Handler {
+	return s.require(permission, func(c *fiber.Ctx, key *domain.APIKey) (int64, error) {
+		id, err := strconv.ParseInt(c.Params(param), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return s.repo.ResourceCompanyID(resource, id)
+	})
+}
+
+func (s *APIKeyScope) require(permission string, resolve companyResolver) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := c.Locals("api_key").(*domain.APIKey)
+		if !ok {
+			return s.limitUser(c, resolve)
+		}
+
+		if !key.HasPermission(permission) {
+			return response.Forbidden(c, "API key lacks the "+permission+" permission")
+		}
+
+		companyID, err := resolve(c, key)
+		if err != nil {
+			if err.Error() == "resource not found" {
+				return response.NotFound(c, "Resource not found")
+			}
+			return response.BadRequest(c, "Could not determine the company of the request")
+		}
+		if companyID != key.CompanyID {
+			return response.Forbidden(c, "API key is not allowed for this company")
+		}
+
+		// La key actúa en nombre del usuario que la creó
+		c.Locals("user_id", key.UserID)
+		return c.Next()
+	}
+}
+
+// limitUser cuenta la petición con JWT contra el plan de su empresa. Si la empresa no se puede
+// determinar la petición sigue sin contarla: el handler valida la petición y responde el error.
+func (s *APIKeyScope) limitUser(c *fiber.Ctx, resolve companyResolver) error {
+	userID, ok := c.Locals("user_id").(int64)
+	if !ok || s.limiter == nil {
+		return c.Next()
+	}
+	companyID, err := resolve(c, nil)
+	if err != nil {
+		return c.Next()
+	}
+	return s.limiter.ByCompany(c, companyID, userID)
+}
+
+// bodyID lee un ID numérico de un campo del cuerpo JSON
+func bodyID(c *fiber.Ctx, field string) (int64, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := json.Unmarshal(body[field], &id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -3,10 +3,10 @@ package middleware
 import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
 	"apidian-go/pkg/response"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -19,9 +19,18 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// AuthMiddleware valida JWT
+// AuthMiddleware valida JWT y rechaza los access tokens revocados (logout) por su jti. También
+// acepta API keys de empresa (X-API-Key o Bearer adk_...), que se autorizan en cada ruta.
 func AuthMiddleware(cfg *config.JWTConfig, db *database.Database) fiber.Handler {
+	sessionRepo := repository.NewSessionRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	adminRepo := repository.NewAdminRepository(db)
+
 	return func(c *fiber.Ctx) error {
+		if key := apiKeyFromRequest(c); key != "" {
+			return authenticateAPIKey(c, apiKeyRepo, key)
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return response.Unauthorized(c, "Missing authorization header")
@@ -62,25 +71,39 @@ func AuthMiddleware(cfg *config.JWTConfig, db *database.Database) fiber.Handler
 			return response.Unauthorized(c, "Invalid email in token")
 		}
 
+		// Los access tokens pertenecen a una sesión y se pueden revocar por jti
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			return response.Unauthorized(c, "Invalid jti in token")
+		}
+
+		sessionID, ok := claims["sid"].(float64)
+		if !ok {
+			return response.Unauthorized(c, "Invalid session in token")
+		}
+
+		revoked, err := sessionRepo.IsTokenRevoked(jti)
+		if err != nil {
+			return response.InternalServerError(c, "Error validating token")
+		}
+		if revoked {
+			return response.Unauthorized(c, "Token has been revoked")
+		}
+
 		// Guardar información del usuario en el contexto
 		c.Locals("user_id", int64(userID))
 		c.Locals("email", email)
+		c.Locals("session_id", int64(sessionID))
+		c.Locals("jti", jti)
 
-		return c.Next()
-	}
-}
+		// Sesión abierta por un administrador suplantando al usuario
+		if impersonator, ok := claims["imp"].(float64); ok {
+			c.Locals("impersonator_id", int64(impersonator))
+			if c.Method() != fiber.MethodGet {
+				logImpersonatedRequest(c, adminRepo, int64(impersonator), int64(userID))
+			}
+		}
 
-func GenerateToken(userID, companyID int64, email string, cfg *config.JWTConfig) (string, error) {
-	claims := Claims{
-		UserID:    userID,
-		CompanyID: companyID,
-		Email:     email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(cfg.Expiration))),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+		return c.Next()
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
 }
@@ -11,7 +11,7 @@ func CORS(allowOrigins string) fiber.Handler {
 	config := cors.Config{
 		AllowOrigins:     allowOrigins,
 		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-Key",
 		AllowCredentials: true,
 		ExposeHeaders:    "Content-Length",
 		MaxAge:           86400,
@@ -1,35 +1,248 @@
 package middleware
 
 import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/response"
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-// RateLimiter retorna middleware de rate limiting
-// - Development: 1000 requests/minuto
-// - Production: 100 requests/minuto
-func RateLimiter(env string) fiber.Handler {
-	max := 100
-	if env == "development" {
-		max = 1000 // Más permisivo en desarrollo
-	}
-
-	return limiter.New(limiter.Config{
-		Max:        max,
-		Expiration: 1 * time.Minute,
-		KeyGenerator: func(c *fiber.Ctx) string {
-			return c.IP() // Limitar por IP
-		},
-		LimitReached: func(c *fiber.Ctx) error {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"success": false,
-				"error":   "Too many requests, please try again later",
-			})
-		},
-		SkipFailedRequests:     false,
-		SkipSuccessfulRequests: false,
-		Storage:                nil, // Usa memoria por defecto
-	})
+// rateLimitWindow es la duración de cada ventana de conteo
+const rateLimitWindow = time.Minute
+
+// RateLimiter limita las peticiones por ventana de un minuto con contadores en Postgres, así el
+// límite es el mismo con varias réplicas detrás del balanceador:
+//   - ByIP: rutas públicas (login, registro, 2FA, enlaces del portal), con o sin credenciales
+//   - ByIPUntilAuthenticated: antes de AuthMiddleware, las peticiones a rutas protegidas cuya
+//     credencial falta o no es válida
+//   - ByTenant: después de AuthMiddleware, por empresa según su plan (API keys), por API key
+//     si tiene límite propio y por usuario (JWT)
+//   - ByCompany: desde APIKeyScope, las peticiones con JWT por la empresa a la que van dirigidas,
+//     con el mismo límite del plan que sus API keys
+//
+// Si la base de datos falla, RATE_LIMIT_FAIL_OPEN decide: por defecto la petición pasa sin límite
+// (el límite no debe tumbar la API); con false responde 503. Una empresa sin plan válido siempre
+// se rechaza, para que un error de datos no la deje sin límites.
+type RateLimiter struct {
+	repo      *repository.RateLimitRepository
+	members   *repository.CompanyRepository
+	ipLimit   int
+	userLimit int
+	failOpen  bool
+}
+
+func NewRateLimiter(db *database.Database, cfg *config.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		repo:      repository.NewRateLimitRepository(db),
+		members:   repository.NewCompanyRepository(db),
+		ipLimit:   cfg.IPPerMinute,
+		userLimit: cfg.UserPerMinute,
+		failOpen:  cfg.FailOpen,
+	}
+}
+
+// rateLimitState es el límite más restrictivo de los buckets que aplican a la petición
+type rateLimitState struct {
+	limit     int
+	remaining int
+	exceeded  bool
+}
+
+// add cuenta un bucket; se queda con el que deja menos peticiones restantes
+func (s *rateLimitState) add(hits, limit int) {
+	remaining := limit - hits
+	if remaining < 0 {
+		remaining = 0
+	}
+	if s.limit == 0 || remaining < s.remaining {
+		s.limit = limit
+		s.remaining = remaining
+	}
+	if hits > limit {
+		s.exceeded = true
+	}
+}
+
+// ByIP limita por IP las rutas públicas. Cuenta todas sus peticiones: ahí nadie valida las
+// cabeceras Authorization o X-API-Key, así que traerlas no exime del límite.
+func (l *RateLimiter) ByIP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if l.ipLimit <= 0 {
+			return c.Next()
+		}
+
+		window := time.Now().Truncate(rateLimitWindow)
+		hits, err := l.repo.Hit("ip:"+c.IP(), window)
+		if err != nil {
+			return l.unavailable(c, "ip "+c.IP(), err)
+		}
+
+		state := &rateLimitState{}
+		state.add(hits, l.ipLimit)
+		return l.respond(c, state, window)
+	}
+}
+
+// ByIPUntilAuthenticated limita por IP las peticiones a rutas protegidas que AuthMiddleware no
+// autentica (sin credencial o con una inválida); solo una credencial validada exime del límite.
+// Como la credencial se valida después, cuenta al terminar la petición y rechaza de entrada a la
+// IP que ya superó el límite en la ventana.
+func (l *RateLimiter) ByIPUntilAuthenticated() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if l.ipLimit <= 0 {
+			return c.Next()
+		}
+
+		window := time.Now().Truncate(rateLimitWindow)
+		bucket := "unauthenticated:" + c.IP()
+		hits, err := l.repo.Hits(bucket, window)
+		if err != nil {
+			return l.unavailable(c, "ip "+c.IP(), err)
+		}
+		if hits >= l.ipLimit {
+			state := &rateLimitState{}
+			state.add(hits+1, l.ipLimit)
+			return l.respond(c, state, window)
+		}
+
+		err = c.Next()
+		if !authenticated(c) {
+			if _, err := l.repo.Hit(bucket, window); err != nil {
+				log.Printf("Warning: rate limit check failed: %v", err)
+			}
+		}
+		return err
+	}
+}
+
+// authenticated indica si AuthMiddleware validó un JWT o una API key en la petición
+func authenticated(c *fiber.Ctx) bool {
+	if _, ok := c.Locals("api_key").(*domain.APIKey); ok {
+		return true
+	}
+	_, ok := c.Locals("user_id").(int64)
+	return ok
+}
+
+// ByTenant limita las peticiones autenticadas. Con API key cuenta la empresa (límite del plan y
+// uso del mes) y la key si tiene límite propio; con JWT cuenta el usuario, y la empresa se cuenta
+// después en ByCompany, cuando la ruta la identifica. El estado queda en el contexto para que
+// ByCompany responda con el límite más restrictivo de los dos.
+func (l *RateLimiter) ByTenant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		window := time.Now().Truncate(rateLimitWindow)
+		state := &rateLimitState{}
+		c.Locals("rate_limit", state)
+
+		if key, ok := c.Locals("api_key").(*domain.APIKey); ok {
+			hits, limit, err := l.repo.HitCompany(key.CompanyID, window)
+			if err != nil {
+				if err.Error() == "company not found" || err.Error() == "plan not found" {
+					log.Printf("Warning: api key %d rejected, company %d has no valid plan", key.ID, key.CompanyID)
+					return response.Forbidden(c, "The company of this API key has no valid plan")
+				}
+				return l.unavailable(c, fmt.Sprintf("company %d", key.CompanyID), err)
+			}
+			if limit != nil {
+				state.add(hits, *limit)
+			}
+			if key.RateLimit != nil {
+				hits, err := l.repo.Hit(fmt.Sprintf("api_key:%d", key.ID), window)
+				if err != nil {
+					return l.unavailable(c, fmt.Sprintf("api key %d", key.ID), err)
+				}
+				state.add(hits, *key.RateLimit)
+			}
+		} else if userID, ok := c.Locals("user_id").(int64); ok && l.userLimit > 0 {
+			hits, err := l.repo.Hit(fmt.Sprintf("user:%d", userID), window)
+			if err != nil {
+				return l.unavailable(c, fmt.Sprintf("user %d", userID), err)
+			}
+			state.add(hits, l.userLimit)
+		}
+
+		if state.limit == 0 {
+			return c.Next()
+		}
+		return l.respond(c, state, window)
+	}
+}
+
+// ByCompany cuenta una petición con JWT contra el plan de la empresa a la que va dirigida (límite
+// por minuto y uso del mes), el mismo que comparten sus API keys. Si el usuario no es miembro la
+// petición sigue sin contar: el handler la rechaza, y así nadie consume la cuota de otra empresa.
+func (l *RateLimiter) ByCompany(c *fiber.Ctx, companyID, userID int64) error {
+	role, err := l.members.GetMemberRole(companyID, userID)
+	if err != nil {
+		return l.unavailable(c, fmt.Sprintf("company %d", companyID), err)
+	}
+	if role == "" {
+		return c.Next()
+	}
+
+	window := time.Now().Truncate(rateLimitWindow)
+	hits, limit, err := l.repo.HitCompany(companyID, window)
+	if err != nil {
+		if err.Error() == "company not found" || err.Error() == "plan not found" {
+			log.Printf("Warning: user %d rejected, company %d has no valid plan", userID, companyID)
+			return response.Forbidden(c, "The company has no valid plan")
+		}
+		return l.unavailable(c, fmt.Sprintf("company %d", companyID), err)
+	}
+
+	state, ok := c.Locals("rate_limit").(*rateLimitState)
+	if !ok {
+		state = &rateLimitState{}
+	}
+	if limit != nil {
+		state.add(hits, *limit)
+	}
+	if state.limit == 0 {
+		return c.Next()
+	}
+	return l.respond(c, state, window)
+}
+
+// unavailable aplica RATE_LIMIT_FAIL_OPEN cuando los contadores fallan: con fail-open la
+// petición pasa sin límite, si no responde 503
+func (l *RateLimiter) unavailable(c *fiber.Ctx, subject string, err error) error {
+	log.Printf("Warning: rate limit check failed for %s: %v", subject, err)
+	if l.failOpen {
+		return c.Next()
+	}
+	return response.ServiceUnavailable(c, "Rate limiting is temporarily unavailable, please try again later")
+}
+
+// respond agrega los headers X-RateLimit-* y corta la petición si superó el límite
+func (l *RateLimiter) respond(c *fiber.Ctx, state *rateLimitState, window time.Time) error {
+	reset := int(time.Until(window.Add(rateLimitWindow)).Seconds()) + 1
+	c.Set("X-RateLimit-Limit", strconv.Itoa(state.limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(state.remaining))
+	c.Set("X-RateLimit-Reset", strconv.Itoa(reset))
+
+	if state.exceeded {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(reset))
+		return response.TooManyRequests(c, "Too many requests, please try again later")
+	}
+	return c.Next()
+}
+
+// RunCleanup borra periódicamente las ventanas vencidas de la tabla de contadores
+func (l *RateLimiter) RunCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		if _, err := l.repo.Purge(time.Now().Add(-5 * rateLimitWindow)); err != nil {
+			log.Printf("Warning: rate limit cleanup failed: %v", err)
+		}
+	}
 }
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"apidian-go/internal/domain"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitStateAdd(t *testing.T) {
+	type bucket struct{ hits, limit int }
+	tests := []struct {
+		name      string
+		buckets   []bucket
+		limit     int
+		remaining int
+		exceeded  bool
+	}{
+		{"first request", []bucket{{1, 60}}, 60, 59, false},
+		{"last allowed request", []bucket{{60, 60}}, 60, 0, false},
+		{"over the limit", []bucket{{61, 60}}, 60, 0, true},
+		{"key limit tighter than the plan", []bucket{{10, 600}, {10, 30}}, 30, 20, false},
+		{"plan closer to its limit than the key", []bucket{{590, 600}, {10, 30}}, 600, 10, false},
+		{"any exceeded bucket rejects", []bucket{{10, 600}, {31, 30}}, 30, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &rateLimitState{}
+			for _, b := range tt.buckets {
+				state.add(b.hits, b.limit)
+			}
+			if state.limit != tt.limit || state.remaining != tt.remaining || state.exceeded != tt.exceeded {
+				t.Errorf("state = %+v, want limit %d, remaining %d, exceeded %v", *state, tt.limit, tt.remaining, tt.exceeded)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRespond(t *testing.T) {
+	limiter := &RateLimiter{}
+	hits := 0
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		hits++
+		state := &rateLimitState{}
+		state.add(hits, 2)
+		return limiter.respond(c, state, time.Now().Truncate(rateLimitWindow))
+	}, func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	for i, want := range []struct {
+		status    int
+		remaining string
+	}{{fiber.StatusOK, "1"}, {fiber.StatusOK, "0"}, {fiber.StatusTooManyRequests, "0"}} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		if resp.StatusCode != want.status {
+			t.Errorf("request %d: status %d, want %d", i+1, resp.StatusCode, want.status)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "2" || resp.Header.Get("X-RateLimit-Remaining") != want.remaining {
+			t.Errorf("request %d: limit %q, remaining %q", i+1, resp.Header.Get("X-RateLimit-Limit"), resp.Header.Get("X-RateLimit-Remaining"))
+		}
+		reset, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Reset"))
+		if reset < 1 || reset > int(rateLimitWindow.Seconds())+1 {
+			t.Errorf("request %d: reset %d outside the window", i+1, reset)
+		}
+		if retry := resp.Header.Get(fiber.HeaderRetryAfter); (want.status == fiber.StatusTooManyRequests) != (retry != "") {
+			t.Errorf("request %d: Retry-After %q", i+1, retry)
+		}
+	}
+}
+
+func TestRateLimiterUnavailable(t *testing.T) {
+	for _, tt := range []struct {
+		failOpen bool
+		status   int
+	}{{true, fiber.StatusOK}, {false, fiber.StatusServiceUnavailable}} {
+		limiter := &RateLimiter{failOpen: tt.failOpen}
+		app := fiber.New()
+		app.Get("/", func(c *fiber.Ctx) error {
+			return limiter.unavailable(c, "user 1", errors.New("connection refused"))
+		}, func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("fail open %v: status %d, want %d", tt.failOpen, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+// Con JWT la empresa se cuenta solo si la ruta la identifica; si no, el handler valida la petición
+func TestAPIKeyScopeSkipsUnresolvedCompany(t *testing.T) {
+	keys := &APIKeyScope{limiter: &RateLimiter{}}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", int64(1))
+		return c.Next()
+	})
+	app.Get("/customers", keys.Query(domain.PermissionCustomersRead), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Post("/customers", keys.Body(domain.PermissionCustomersWrite), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/customers", nil),
+		httptest.NewRequest("POST", "/customers", strings.NewReader(`{"name": "Cliente"}`)),
+	} {
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", req.Method, err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s: status %d, want %d", req.Method, resp.StatusCode, fiber.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type AdminRepository struct {
+	db *database.Database
+}
+
+func NewAdminRepository(db *database.Database) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+// LogAction registra una acción en la bitácora de administración
+func (r *AdminRepository) LogAction(action *domain.AdminAction) error {
+	var details any
+	if len(action.Details) > 0 {
+		details = []byte(action.Details)
+	}
+
+	return r.db.DB.QueryRow(`
+		INSERT INTO admin_actions (admin_id, action, target_type, target_id, details, ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at
+	`,
+		action.AdminID,
+		action.Action,
+		action.TargetType,
+		action.TargetID,
+		details,
+		action.IPAddress,
+	).Scan(&action.ID, &action.CreatedAt)
+}
+
+// GetActions lista la bitácora de administración, la más reciente primero
+func (r *AdminRepository) GetActions(filter domain.AdminActionFilter, page, pageSize int) ([]domain.AdminAction, int, error) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.AdminID != 0 {
+		add("a.admin_id = $%d", filter.AdminID)
+	}
+	if filter.Action != "" {
+		add("a.action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("a.target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		add("a.target_id = $%d", filter.TargetID)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.DB.QueryRow(`SELECT COUNT(*) FROM admin_actions a WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting admin actions: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT a.id, a.admin_id, u.email, a.action, a.target_type, a.target_id, a.details,
+			a.ip_address, a.created_at
+		FROM admin_actions a
+		JOIN users u ON u.id = a.admin_id
+		WHERE %s
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying admin actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []domain.AdminAction{}
+	for rows.Next() {
+		var action domain.AdminAction
+		var details []byte
+		err := rows.Scan(
+			&action.ID,
+			&action.AdminID,
+			&action.AdminEmail,
+			&action.Action,
+			&action.TargetType,
+			&action.TargetID,
+			&details,
+			&action.IPAddress,
+			&action.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning admin action: %w", err)
+		}
+		if len(details) > 0 {
+			action.Details = json.RawMessage(details)
+		}
+		actions = append(actions, action)
+	}
+	return actions, total, rows.Err()
+}
+
+const companyUsageSelect = `
+	SELECT
+		c.id, c.nit, c.name, c.user_id, u.email, c.suspended_at, c.suspended_reason,
+		(SELECT COUNT(*) FROM company_members m WHERE m.company_id = c.id),
+		(SELECT COUNT(*) FROM api_keys k
+			WHERE k.company_id = c.id AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())),
+		COALESCE(d.total, 0), COALESCE(d.this_month, 0), COALESCE(d.accepted, 0), COALESCE(d.rejected, 0),
+		d.last_at, c.plan, p.documents_per_month, COALESCE(cu.documents, 0),
+		p.api_calls_per_minute, COALESCE(cu.api_calls, 0), c.created_at
+	FROM companies c
+	JOIN users u ON u.id = c.user_id
+	JOIN plans p ON p.code = c.plan
+	LEFT JOIN company_usage cu ON cu.company_id = c.id AND cu.period = DATE_TRUNC('month', NOW())::date
+	LEFT JOIN (
+		SELECT company_id,
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE created_at >= DATE_TRUNC('month', NOW())) AS this_month,
+			COUNT(*) FILTER (WHERE status = 'accepted') AS accepted,
+			COUNT(*) FILTER (WHERE status = 'rejected') AS rejected,
+			MAX(created_at) AS last_at
+		FROM documents
+		GROUP BY company_id
+	) d ON d.company_id = c.id`
+
+// scanCompanyUsage lee una fila de companyUsageSelect
+func scanCompanyUsage(scanner interface{ Scan(...any) error }) (*domain.CompanyUsage, error) {
+	usage := &domain.CompanyUsage{}
+	err := scanner.Scan(
+		&usage.CompanyID,
+		&usage.NIT,
+		&usage.Name,
+		&usage.OwnerID,
+		&usage.OwnerEmail,
+		&usage.SuspendedAt,
+		&usage.SuspendedReason,
+		&usage.Members,
+		&usage.ActiveAPIKeys,
+		&usage.Documents,
+		&usage.DocumentsThisMonth,
+		&usage.DocumentsAccepted,
+		&usage.DocumentsRejected,
+		&usage.LastDocumentAt,
+		&usage.Plan,
+		&usage.DocumentQuota,
+		&usage.DocumentQuotaUsed,
+		&usage.APICallsPerMinute,
+		&usage.APICallsThisMonth,
+		&usage.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetCompaniesUsage lista las empresas activas con su uso; suspended filtra solo las suspendidas
+func (r *AdminRepository) GetCompaniesUsage(suspended bool, page, pageSize int) ([]domain.CompanyUsage, int, error) {
+	where := `c.is_active = true`
+	if suspended {
+		where += ` AND c.suspended_at IS NOT NULL`
+	}
+
+	var total int
+	if err := r.db.DB.QueryRow(`SELECT COUNT(*) FROM companies c WHERE ` + where).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting companies: %w", err)
+	}
+
+	rows, err := r.db.DB.Query(companyUsageSelect+`
+		WHERE `+where+`
+		ORDER BY d.last_at DESC NULLS LAST, c.id
+		LIMIT $1 OFFSET $2`, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying company usage: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []domain.CompanyUsage{}
+	for rows.Next() {
+		usage, err := scanCompanyUsage(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning company usage: %w", err)
+		}
+		companies = append(companies, *usage)
+	}
+	return companies, total, rows.Err()
+}
+
+// GetCompanyUsage obtiene el uso de una empresa activa
+func (r *AdminRepository) GetCompanyUsage(companyID int64) (*domain.CompanyUsage, error) {
+	usage, err := scanCompanyUsage(r.db.DB.QueryRow(companyUsageSelect+`
+		WHERE c.id = $1 AND c.is_active = true`, companyID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("company not found")
+		}
+		return nil, fmt.Errorf("error getting company usage: %w", err)
+	}
+	return usage, nil
+}
+
+// SetCompanySuspension suspende (reason != nil) o reactiva una empresa activa
+func (r *AdminRepository) SetCompanySuspension(companyID, adminID int64, reason *string) error {
+	var query string
+	var args []any
+	if reason != nil {
+		query = `UPDATE companies SET suspended_at = NOW(), suspended_reason = $2, suspended_by = $3, updated_at = NOW()
+			WHERE id = $1 AND is_active = true AND suspended_at IS NULL`
+		args = []any{companyID, *reason, adminID}
+	} else {
+		query = `UPDATE companies SET suspended_at = NULL, suspended_reason = NULL, suspended_by = NULL, updated_at = NOW()
+			WHERE id = $1 AND is_active = true AND suspended_at IS NOT NULL`
+		args = []any{companyID}
+	}
+
+	result, err := r.db.ExecAs(adminID, query, args...)
+	if err != nil {
+		return fmt.Errorf("error updating company suspension: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if reason != nil {
+			return errors.New("company not found or already suspended")
+		}
+		return errors.New("company not found or not suspended")
+	}
+	return nil
+}
+
+// SetCompanyPlan cambia el plan de una empresa activa; el cambio queda en el historial de la
+// empresa a nombre del administrador
+func (r *AdminRepository) SetCompanyPlan(companyID, adminID int64, plan string) error {
+	result, err := r.db.ExecAs(adminID, `
+		UPDATE companies SET plan = $2, updated_at = NOW() WHERE id = $1 AND is_active = true
+	`, companyID, plan)
+	if err != nil {
+		return fmt.Errorf("error updating company plan: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("company not found")
+	}
+	return nil
+}
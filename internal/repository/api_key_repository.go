@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `
+	k.id, k.company_id, k.user_id, k.name, k.key_prefix, k.permissions, k.rate_limit_per_minute, k.expires_at,
+	k.last_used_at, k.last_used_ip, k.revoked_at, k.created_at`
+
+// scanAPIKey lee una fila con las columnas de apiKeyColumns
+func scanAPIKey(scanner interface{ Scan(...any) error }) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	err := scanner.Scan(
+		&key.ID,
+		&key.CompanyID,
+		&key.UserID,
+		&key.Name,
+		&key.KeyPrefix,
+		pq.Array(&key.Permissions),
+		&key.RateLimit,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Recursos cuya empresa se puede resolver por ID para limitar las API keys a su empresa
+const (
+	ScopeCustomer   = "customers"
+	ScopeProduct    = "products"
+	ScopeDocument   = "documents"
+	ScopeImportJob  = "invoice_import_jobs"
+	ScopeQuotation  = "quotations"
+	ScopePayment    = "payments"
+	ScopeResolution = "resolutions"
+)
+
+var scopedResources = map[string]bool{
+	ScopeCustomer:   true,
+	ScopeProduct:    true,
+	ScopeDocument:   true,
+	ScopeImportJob:  true,
+	ScopeQuotation:  true,
+	ScopePayment:    true,
+	ScopeResolution: true,
+}
+
+type APIKeyRepository struct {
+	db *database.Database
+}
+
+func NewAPIKeyRepository(db *database.Database) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create registra una API key (solo el hash de la key)
+func (r *APIKeyRepository) Create(key *domain.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (company_id, user_id, name, key_prefix, key_hash, permissions, rate_limit_per_minute, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	return r.db.DB.QueryRow(query,
+		key.CompanyID,
+		key.UserID,
+		key.Name,
+		key.KeyPrefix,
+		keyHash,
+		pq.Array(key.Permissions),
+		key.RateLimit,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetByCompanyID obtiene las API keys de una empresa, incluidas las revocadas y vencidas
+func (r *APIKeyRepository) GetByCompanyID(companyID int64) ([]domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys k WHERE k.company_id = $1 ORDER BY k.created_at DESC`
+
+	rows, err := r.db.DB.Query(query, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// GetActiveByHash obtiene la API key vigente con ese hash. La key deja de funcionar si se revoca,
+// vence, la empresa se desactiva o su creador ya no está activo o no es miembro de la empresa.
+func (r *APIKeyRepository) GetActiveByHash(keyHash string) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + `
+		FROM api_keys k
+		JOIN companies c ON c.id = k.company_id AND c.is_active = true
+		JOIN company_members m ON m.company_id = k.company_id AND m.user_id = k.user_id
+		JOIN users u ON u.id = k.user_id AND u.is_active = true
+		WHERE k.key_hash = $1
+			AND k.revoked_at IS NULL
+			AND (k.expires_at IS NULL OR k.expires_at > NOW())`
+
+	key, err := scanAPIKey(r.db.DB.QueryRow(query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// Touch registra el último uso de la key (como máximo una escritura por minuto)
+func (r *APIKeyRepository) Touch(id int64, ip string) error {
+	_, err := r.db.DB.Exec(`
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id, ip)
+	return err
+}
+
+// Revoke revoca una API key de la empresa
+func (r *APIKeyRepository) Revoke(id, companyID int64) error {
+	result, err := r.db.DB.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND company_id = $2 AND revoked_at IS NULL
+	`, id, companyID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// ResourceCompanyID obtiene la empresa dueña de un recurso (uno de los Scope*)
+func (r *APIKeyRepository) ResourceCompanyID(resource string, id int64) (int64, error) {
+	if !scopedResources[resource] {
+		return 0, fmt.Errorf("unknown resource %q", resource)
+	}
+
+	var companyID int64
+	err := r.db.DB.QueryRow(`SELECT company_id FROM `+resource+` WHERE id = $1`, id).Scan(&companyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New("resource not found")
+		}
+		return 0, err
+	}
+	return companyID, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type AuditRepository struct {
+	db *database.Database
+}
+
+func NewAuditRepository(db *database.Database) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create registra una entrada en audit_log
+func (r *AuditRepository) Create(entry *domain.AuditEntry) error {
+	var oldData, newData any
+	if len(entry.OldData) > 0 {
+		oldData = []byte(entry.OldData)
+	}
+	if len(entry.NewData) > 0 {
+		newData = []byte(entry.NewData)
+	}
+
+	return r.db.DB.QueryRow(`
+		INSERT INTO audit_log (table_name, operation, user_id, old_data, new_data, changed_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, changed_at
+	`,
+		entry.TableName,
+		entry.Operation,
+		entry.UserID,
+		oldData,
+		newData,
+	).Scan(&entry.ID, &entry.ChangedAt)
+}
+
+// List lista audit_log con el filtro, el cambio más reciente primero
+func (r *AuditRepository) List(filter domain.AuditFilter, page, pageSize int) ([]domain.AuditEntry, int, error) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.CompanyID != 0 {
+		add("a.company_id = $%d", filter.CompanyID)
+	}
+	if filter.TableName != "" {
+		add("a.table_name = $%d", filter.TableName)
+	}
+	if filter.RecordID != 0 {
+		add("a.record_id = $%d", filter.RecordID)
+	}
+	if filter.Operation != "" {
+		add("a.operation = $%d", filter.Operation)
+	}
+	if filter.UserID != 0 {
+		add("a.user_id = $%d", filter.UserID)
+	}
+	if filter.From != nil {
+		add("a.changed_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("a.changed_at < $%d", *filter.To)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.DB.QueryRow(`SELECT COUNT(*) FROM audit_log a WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting audit entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT a.id, a.table_name, a.operation, a.record_id, a.company_id, a.user_id, u.email,
+			a.old_data, a.new_data, a.changed_at
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE %s
+		ORDER BY a.changed_at DESC, a.id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var oldData, newData []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TableName,
+			&entry.Operation,
+			&entry.RecordID,
+			&entry.CompanyID,
+			&entry.UserID,
+			&entry.UserEmail,
+			&oldData,
+			&newData,
+			&entry.ChangedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning audit entry: %w", err)
+		}
+		if len(oldData) > 0 {
+			entry.OldData = json.RawMessage(oldData)
+		}
+		if len(newData) > 0 {
+			entry.NewData = json.RawMessage(newData)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"fmt"
+)
+
+// catalogTables lista las tablas de catálogo DIAN que se pueden consultar por código.
+// Los nombres de tabla no se pueden parametrizar en SQL, por eso se validan aquí.
+var catalogTables = map[string]bool{
+	"countries":          true,
+	"departments":        true,
+	"municipalities":     true,
+	"currency_codes":     true,
+	"document_types":     true,
+	"organization_types": true,
+	"payment_forms":      true,
+	"payment_methods":    true,
+	"regime_types":       true,
+	"tax_level_codes":    true,
+	"tax_types":          true,
+	"unit_codes":         true,
+}
+
+type CatalogRepository struct {
+	db *database.Database
+}
+
+func NewCatalogRepository(db *database.Database) *CatalogRepository {
+	return &CatalogRepository{db: db}
+}
+
+// GetIDByCode obtiene el id de un registro de catálogo activo a partir de su código DIAN
+func (r *CatalogRepository) GetIDByCode(table, code string) (int, error) {
+	if !catalogTables[table] {
+		return 0, fmt.Errorf("unknown catalog %s", table)
+	}
+
+	var id int
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE code = $1 AND is_active = true`, table)
+	err := r.db.DB.QueryRow(query, code).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%s code '%s' not found", table, code)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting %s by code: %w", table, err)
+	}
+
+	return id, nil
+}
+
+// ResolveID obtiene el id de un registro de catálogo a partir de su código DIAN o su nombre
+// (sin distinguir mayúsculas). Si el nombre coincide con varios registros se exige el código.
+func (r *CatalogRepository) ResolveID(table, value string) (int, error) {
+	if !catalogTables[table] {
+		return 0, fmt.Errorf("unknown catalog %s", table)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, code = $1 AS by_code FROM %s
+		WHERE is_active = true AND (code = $1 OR LOWER(name) = LOWER($1))
+		ORDER BY by_code DESC
+		LIMIT 2
+	`, table)
+
+	rows, err := r.db.DB.Query(query, value)
+	if err != nil {
+		return 0, fmt.Errorf("error resolving %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	var byCode []bool
+	for rows.Next() {
+		var id int
+		var matchedCode bool
+		if err := rows.Scan(&id, &matchedCode); err != nil {
+			return 0, fmt.Errorf("error resolving %s: %w", table, err)
+		}
+		ids = append(ids, id)
+		byCode = append(byCode, matchedCode)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error resolving %s: %w", table, err)
+	}
+
+	switch {
+	case len(ids) == 0:
+		return 0, fmt.Errorf("%s '%s' not found", table, value)
+	case len(ids) > 1 && !byCode[0]:
+		// El código es único; solo los nombres pueden repetirse (ej. municipios homónimos)
+		return 0, fmt.Errorf("%s '%s' is ambiguous, use the DIAN code", table, value)
+	}
+
+	return ids[0], nil
+}
+
+// ResolveMunicipality obtiene municipio, departamento y país a partir del código DANE
+// o del nombre del municipio
+func (r *CatalogRepository) ResolveMunicipality(value string) (municipalityID, departmentID, countryID int, err error) {
+	municipalityID, err = r.ResolveID("municipalities", value)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	query := `
+		SELECT m.department_id, d.country_id
+		FROM municipalities m
+		INNER JOIN departments d ON m.department_id = d.id
+		WHERE m.id = $1
+	`
+	if err = r.db.DB.QueryRow(query, municipalityID).Scan(&departmentID, &countryID); err != nil {
+		return 0, 0, 0, fmt.Errorf("error resolving municipality: %w", err)
+	}
+
+	return municipalityID, departmentID, countryID, nil
+}
+
+// GetCodes obtiene el mapa id -> código de un catálogo (usado en exportaciones)
+func (r *CatalogRepository) GetCodes(table string) (map[int]string, error) {
+	if !catalogTables[table] {
+		return nil, fmt.Errorf("unknown catalog %s", table)
+	}
+
+	rows, err := r.db.DB.Query(fmt.Sprintf(`SELECT id, code FROM %s`, table))
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s codes: %w", table, err)
+	}
+	defer rows.Close()
+
+	codes := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, fmt.Errorf("error scanning %s code: %w", table, err)
+		}
+		codes[id] = code
+	}
+
+	return codes, rows.Err()
+}
@@ -3,63 +3,130 @@ package repository
 import (
 	"apidian-go/internal/domain"
 	"apidian-go/internal/infrastructure/database"
-	"fmt"
+	"time"
 )
 
 type CertificateRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // User credited with the writes in audit_log; 0 for system processes
 }
 
 func NewCertificateRepository(db *database.Database) *CertificateRepository {
 	return &CertificateRepository{db: db}
 }
 
-// Create creates a new certificate
-func (r *CertificateRepository) Create(cert *domain.Certificate) (*domain.Certificate, error) {
-	query := `
-		INSERT INTO certificates (company_id, name, password, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
-		RETURNING id, company_id, name, password, is_active, created_at, updated_at
-	`
+// As returns a copy of the repository whose writes are recorded in audit_log under the user
+func (r *CertificateRepository) As(userID int64) *CertificateRepository {
+	return &CertificateRepository{db: r.db, actorID: userID}
+}
 
-	err := r.db.DB.QueryRow(
-		query,
-		cert.CompanyID,
-		cert.Name,
-		cert.Password,
-		cert.IsActive,
-	).Scan(
+const certificateColumns = `
+	id, company_id, name, password, is_active, status, activates_at, deactivated_at,
+	subject, issuer, serial_number, subject_nit, nit_matches, not_before, not_after, expiry_notified_days,
+	created_at, updated_at`
+
+func scanCertificate(scanner interface{ Scan(...any) error }, cert *domain.Certificate) error {
+	return scanner.Scan(
 		&cert.ID,
 		&cert.CompanyID,
 		&cert.Name,
 		&cert.Password,
 		&cert.IsActive,
+		&cert.Status,
+		&cert.ActivatesAt,
+		&cert.DeactivatedAt,
+		&cert.Subject,
+		&cert.Issuer,
+		&cert.SerialNumber,
+		&cert.SubjectNIT,
+		&cert.NITMatches,
+		&cert.NotBefore,
+		&cert.NotAfter,
+		&cert.ExpiryNotifiedDays,
 		&cert.CreatedAt,
 		&cert.UpdatedAt,
 	)
+}
 
-	return cert, err
+// Create creates a new certificate. An active certificate supersedes the current one and a
+// scheduled certificate replaces any other pending schedule, all in one transaction.
+// Previous certificates are never deleted.
+func (r *CertificateRepository) Create(cert *domain.Certificate) (*domain.Certificate, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := database.SetActor(tx, r.actorID); err != nil {
+		return nil, err
+	}
+
+	switch cert.Status {
+	case domain.CertificateStatusActive:
+		if _, err := tx.Exec(`
+			UPDATE certificates
+			SET is_active = false, status = $1, deactivated_at = NOW(), updated_at = NOW()
+			WHERE company_id = $2 AND status = $3
+		`, domain.CertificateStatusSuperseded, cert.CompanyID, domain.CertificateStatusActive); err != nil {
+			return nil, err
+		}
+	case domain.CertificateStatusScheduled:
+		if _, err := tx.Exec(`
+			UPDATE certificates
+			SET status = $1, deactivated_at = NOW(), updated_at = NOW()
+			WHERE company_id = $2 AND status = $3
+		`, domain.CertificateStatusDisabled, cert.CompanyID, domain.CertificateStatusScheduled); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		INSERT INTO certificates (
+			company_id, name, password, is_active, status, activates_at,
+			subject, issuer, serial_number, subject_nit, nit_matches, not_before, not_after,
+			created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		RETURNING ` + certificateColumns
+
+	err = scanCertificate(tx.QueryRow(
+		query,
+		cert.CompanyID,
+		cert.Name,
+		cert.Password,
+		cert.Status == domain.CertificateStatusActive,
+		cert.Status,
+		cert.ActivatesAt,
+		cert.Subject,
+		cert.Issuer,
+		cert.SerialNumber,
+		cert.SubjectNIT,
+		cert.NITMatches,
+		cert.NotBefore,
+		cert.NotAfter,
+	), cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return cert, tx.Commit()
 }
 
-// GetByCompanyID gets the active certificate for a company
+// GetByCompanyID gets the certificate in force for a company: the active one, or a scheduled
+// certificate whose activation date has passed even if ActivateScheduled has not run yet
 func (r *CertificateRepository) GetByCompanyID(companyID int64) (*domain.Certificate, error) {
 	query := `
-		SELECT id, company_id, name, password, is_active, created_at, updated_at
+		SELECT ` + certificateColumns + `
 		FROM certificates
-		WHERE company_id = $1 AND is_active = true
+		WHERE company_id = $1
+		  AND (status = $2 OR (status = $3 AND activates_at <= NOW()))
+		ORDER BY (status = $3) DESC, activates_at DESC
 		LIMIT 1
 	`
 
 	cert := &domain.Certificate{}
-	err := r.db.DB.QueryRow(query, companyID).Scan(
-		&cert.ID,
-		&cert.CompanyID,
-		&cert.Name,
-		&cert.Password,
-		&cert.IsActive,
-		&cert.CreatedAt,
-		&cert.UpdatedAt,
-	)
+	err := scanCertificate(r.db.DB.QueryRow(query, companyID, domain.CertificateStatusActive, domain.CertificateStatusScheduled), cert)
 
 	return cert, err
 }
@@ -67,29 +134,91 @@ func (r *CertificateRepository) GetByCompanyID(companyID int64) (*domain.Certifi
 // GetByID gets a certificate by ID
 func (r *CertificateRepository) GetByID(id int64) (*domain.Certificate, error) {
 	query := `
-		SELECT id, company_id, name, password, is_active, created_at, updated_at
+		SELECT ` + certificateColumns + `
 		FROM certificates
 		WHERE id = $1
 	`
 
 	cert := &domain.Certificate{}
-	err := r.db.DB.QueryRow(query, id).Scan(
-		&cert.ID,
-		&cert.CompanyID,
-		&cert.Name,
-		&cert.Password,
-		&cert.IsActive,
-		&cert.CreatedAt,
-		&cert.UpdatedAt,
-	)
+	err := scanCertificate(r.db.DB.QueryRow(query, id), cert)
 
 	return cert, err
 }
 
+// Delete soft deletes a certificate (status disabled); the record and its file are kept as history
+func (r *CertificateRepository) Delete(id int64) error {
+	query := `
+		UPDATE certificates
+		SET is_active = false, status = $1, deactivated_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND status IN ($3, $4)
+	`
+
+	_, err := r.db.ExecAs(r.actorID, query, domain.CertificateStatusDisabled, id, domain.CertificateStatusActive, domain.CertificateStatusScheduled)
+	return err
+}
+
+// ActivateScheduled activates the scheduled certificates whose activation date has passed,
+// superseding the previous active certificate of each company. Returns the activated ones.
+func (r *CertificateRepository) ActivateScheduled(now time.Time) ([]domain.Certificate, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, company_id
+		FROM certificates
+		WHERE status = $1 AND activates_at <= $2
+		ORDER BY activates_at
+		FOR UPDATE SKIP LOCKED
+	`, domain.CertificateStatusScheduled, now)
+	if err != nil {
+		return nil, err
+	}
+	var due []domain.Certificate
+	for rows.Next() {
+		var cert domain.Certificate
+		if err := rows.Scan(&cert.ID, &cert.CompanyID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, cert)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var activated []domain.Certificate
+	for _, cert := range due {
+		if _, err := tx.Exec(`
+			UPDATE certificates
+			SET is_active = false, status = $1, deactivated_at = NOW(), updated_at = NOW()
+			WHERE company_id = $2 AND status = $3
+		`, domain.CertificateStatusSuperseded, cert.CompanyID, domain.CertificateStatusActive); err != nil {
+			return nil, err
+		}
+
+		err := scanCertificate(tx.QueryRow(`
+			UPDATE certificates
+			SET is_active = true, status = $1, updated_at = NOW()
+			WHERE id = $2
+			RETURNING `+certificateColumns,
+			domain.CertificateStatusActive, cert.ID), &cert)
+		if err != nil {
+			return nil, err
+		}
+		activated = append(activated, cert)
+	}
+
+	return activated, tx.Commit()
+}
+
 // GetAllByCompanyID gets all certificates for a company (including inactive)
-func (r *CertificateRepository) GetAllByCompanyIDIncludingInactive(companyID int64) ([]*domain.Certificate, error) {
+func (r *CertificateRepository) GetAllByCompanyID(companyID int64) ([]domain.Certificate, error) {
 	query := `
-		SELECT id, company_id, name, password, is_active, created_at, updated_at
+		SELECT ` + certificateColumns + `
 		FROM certificates
 		WHERE company_id = $1
 		ORDER BY created_at DESC
@@ -101,19 +230,10 @@ func (r *CertificateRepository) GetAllByCompanyIDIncludingInactive(companyID int
 	}
 	defer rows.Close()
 
-	var certificates []*domain.Certificate
+	var certificates []domain.Certificate
 	for rows.Next() {
-		cert := &domain.Certificate{}
-		err := rows.Scan(
-			&cert.ID,
-			&cert.CompanyID,
-			&cert.Name,
-			&cert.Password,
-			&cert.IsActive,
-			&cert.CreatedAt,
-			&cert.UpdatedAt,
-		)
-		if err != nil {
+		var cert domain.Certificate
+		if err := scanCertificate(rows, &cert); err != nil {
 			return nil, err
 		}
 		certificates = append(certificates, cert)
@@ -122,47 +242,63 @@ func (r *CertificateRepository) GetAllByCompanyIDIncludingInactive(companyID int
 	return certificates, rows.Err()
 }
 
-// DeleteAllByCompanyID permanently deletes all certificates for a company
-// This is used before uploading a new certificate to keep only one active certificate
-func (r *CertificateRepository) DeleteAllByCompanyID(companyID int64) error {
-	query := `DELETE FROM certificates WHERE company_id = $1`
+// GetActiveExpiringBefore gets the active certificates (all companies) whose not_after is before the given time
+func (r *CertificateRepository) GetActiveExpiringBefore(before time.Time) ([]domain.Certificate, error) {
+	query := `
+		SELECT ` + certificateColumns + `
+		FROM certificates
+		WHERE is_active = true AND not_after IS NOT NULL AND not_after < $1
+		ORDER BY not_after
+	`
 
-	result, err := r.db.DB.Exec(query, companyID)
+	rows, err := r.db.DB.Query(query, before)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Log how many rows were deleted (for debugging)
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected > 0 {
-		fmt.Printf("Deleted %d certificate(s) for company %d\n", rowsAffected, companyID)
+	var certificates []domain.Certificate
+	for rows.Next() {
+		var cert domain.Certificate
+		if err := scanCertificate(rows, &cert); err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, cert)
 	}
 
-	return nil
+	return certificates, rows.Err()
 }
 
-// Delete soft deletes a certificate (sets is_active to false)
-func (r *CertificateRepository) Delete(id int64) error {
+// MarkExpiryNotified records that the alert for a threshold was sent. It returns false if
+// that threshold (or a lower one) was already recorded, so each alert goes out only once
+// even with several API instances running the check.
+func (r *CertificateRepository) MarkExpiryNotified(id int64, threshold int) (bool, error) {
 	query := `
 		UPDATE certificates
-		SET is_active = false, updated_at = NOW()
-		WHERE id = $1
+		SET expiry_notified_days = $1
+		WHERE id = $2 AND (expiry_notified_days IS NULL OR expiry_notified_days > $1)
 	`
 
-	_, err := r.db.DB.Exec(query, id)
-	return err
+	result, err := r.db.DB.Exec(query, threshold, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
 }
 
-// GetAllByCompanyID gets all certificates for a company (including inactive)
-func (r *CertificateRepository) GetAllByCompanyID(companyID int64) ([]domain.Certificate, error) {
+// GetAllPasswords gets id and encrypted password of every certificate (all companies, including inactive)
+func (r *CertificateRepository) GetAllPasswords() ([]domain.Certificate, error) {
 	query := `
-		SELECT id, company_id, name, password, is_active, created_at, updated_at
+		SELECT id, company_id, password
 		FROM certificates
-		WHERE company_id = $1
-		ORDER BY created_at DESC
+		ORDER BY id
 	`
 
-	rows, err := r.db.DB.Query(query, companyID)
+	rows, err := r.db.DB.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -171,16 +307,7 @@ func (r *CertificateRepository) GetAllByCompanyID(companyID int64) ([]domain.Cer
 	var certificates []domain.Certificate
 	for rows.Next() {
 		var cert domain.Certificate
-		err := rows.Scan(
-			&cert.ID,
-			&cert.CompanyID,
-			&cert.Name,
-			&cert.Password,
-			&cert.IsActive,
-			&cert.CreatedAt,
-			&cert.UpdatedAt,
-		)
-		if err != nil {
+		if err := rows.Scan(&cert.ID, &cert.CompanyID, &cert.Password); err != nil {
 			return nil, err
 		}
 		certificates = append(certificates, cert)
@@ -188,3 +315,15 @@ func (r *CertificateRepository) GetAllByCompanyID(companyID int64) ([]domain.Cer
 
 	return certificates, rows.Err()
 }
+
+// UpdatePassword replaces the encrypted password of a certificate
+func (r *CertificateRepository) UpdatePassword(id int64, encryptedPassword string) error {
+	query := `
+		UPDATE certificates
+		SET password = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	_, err := r.db.DB.Exec(query, encryptedPassword, id)
+	return err
+}
@@ -10,13 +10,19 @@ import (
 )
 
 type CompanyRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // Usuario al que se atribuyen las escrituras en audit_log; 0 para procesos del sistema
 }
 
 func NewCompanyRepository(db *database.Database) *CompanyRepository {
 	return &CompanyRepository{db: db}
 }
 
+// As retorna una copia del repositorio cuyas escrituras quedan en audit_log a nombre del usuario
+func (r *CompanyRepository) As(userID int64) *CompanyRepository {
+	return &CompanyRepository{db: r.db, actorID: userID}
+}
+
 // Create crea una nueva empresa
 func (r *CompanyRepository) Create(userID int64, req *domain.CreateCompanyRequest) (*domain.Company, error) {
 	query := `
@@ -55,7 +61,7 @@ func (r *CompanyRepository) Create(userID int64, req *domain.CreateCompanyReques
 		LogoPath:           req.LogoPath,
 	}
 
-	err := r.db.DB.QueryRow(
+	err := r.db.QueryRowAs(userID,
 		query,
 		userID,
 		req.DocumentTypeID,
@@ -109,7 +115,7 @@ func (r *CompanyRepository) GetByID(id int64) (*domain.Company, error) {
 			id, user_id, document_type_id, nit, dv, name, trade_name, registration_name,
 			tax_level_code_id, type_organization_id, type_regime_id, industry_codes,
 			country_id, department_id, municipality_id, address_line, postal_zone,
-			phone, email, website, logo_path, is_active,
+			phone, email, website, logo_path, is_active, suspended_at, suspended_reason, require_two_factor,
 			created_at, updated_at
 		FROM companies
 		WHERE id = $1 AND is_active = true
@@ -139,6 +145,9 @@ func (r *CompanyRepository) GetByID(id int64) (*domain.Company, error) {
 		&company.Website,
 		&company.LogoPath,
 		&company.IsActive,
+		&company.SuspendedAt,
+		&company.SuspendedReason,
+		&company.RequireTwoFactor,
 		&company.CreatedAt,
 		&company.UpdatedAt,
 	)
@@ -153,13 +162,71 @@ func (r *CompanyRepository) GetByID(id int64) (*domain.Company, error) {
 	return company, nil
 }
 
-// GetByUserID obtiene todas las empresas de un usuario
+// GetMemberRole obtiene el rol del usuario en la empresa; vacío si no es miembro
+func (r *CompanyRepository) GetMemberRole(companyID, userID int64) (string, error) {
+	var role string
+	err := r.db.DB.QueryRow(
+		`SELECT role FROM company_members WHERE company_id = $1 AND user_id = $2`,
+		companyID, userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error getting member role: %w", err)
+	}
+	return role, nil
+}
+
+// MemberTwoFactorEnabled indica si el miembro tiene activa la verificación en dos pasos
+func (r *CompanyRepository) MemberTwoFactorEnabled(companyID, userID int64) (bool, error) {
+	var enabled bool
+	err := r.db.DB.QueryRow(`
+		SELECT u.totp_enabled_at IS NOT NULL
+		FROM company_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.company_id = $1 AND m.user_id = $2
+	`, companyID, userID).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking two-factor authentication: %w", err)
+	}
+	return enabled, nil
+}
+
+// SetRequireTwoFactor activa o desactiva la política de 2FA de la empresa
+func (r *CompanyRepository) SetRequireTwoFactor(id int64, require bool) error {
+	result, err := r.db.ExecAs(r.actorID, `
+		UPDATE companies SET require_two_factor = $2, updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`, id, require)
+	if err != nil {
+		return fmt.Errorf("error updating two-factor policy: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("company not found")
+	}
+	return nil
+}
+
+// GetByUserID obtiene las empresas de las que el usuario es miembro, con su rol
 func (r *CompanyRepository) GetByUserID(userID int64, page, pageSize int) ([]domain.Company, int, error) {
 	offset := (page - 1) * pageSize
 
 	// Contar total
 	var total int
-	countQuery := `SELECT COUNT(*) FROM companies WHERE user_id = $1 AND is_active = true`
+	countQuery := `
+		SELECT COUNT(*)
+		FROM companies c
+		JOIN company_members m ON m.company_id = c.id AND m.user_id = $1
+		WHERE c.is_active = true
+	`
 	err := r.db.DB.QueryRow(countQuery, userID).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error counting companies: %w", err)
@@ -168,14 +235,15 @@ func (r *CompanyRepository) GetByUserID(userID int64, page, pageSize int) ([]dom
 	// Obtener empresas
 	query := `
 		SELECT 
-			id, user_id, document_type_id, nit, dv, name, trade_name, registration_name,
-			tax_level_code_id, type_organization_id, type_regime_id, industry_codes,
-			country_id, department_id, municipality_id, address_line, postal_zone,
-			phone, email, website, logo_path, is_active,
-			created_at, updated_at
-		FROM companies
-		WHERE user_id = $1 AND is_active = true
-		ORDER BY created_at DESC
+			c.id, c.user_id, c.document_type_id, c.nit, c.dv, c.name, c.trade_name, c.registration_name,
+			c.tax_level_code_id, c.type_organization_id, c.type_regime_id, c.industry_codes,
+			c.country_id, c.department_id, c.municipality_id, c.address_line, c.postal_zone,
+			c.phone, c.email, c.website, c.logo_path, c.is_active, c.suspended_at, c.suspended_reason, c.require_two_factor, m.role,
+			c.created_at, c.updated_at
+		FROM companies c
+		JOIN company_members m ON m.company_id = c.id AND m.user_id = $1
+		WHERE c.is_active = true
+		ORDER BY c.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
@@ -211,6 +279,10 @@ func (r *CompanyRepository) GetByUserID(userID int64, page, pageSize int) ([]dom
 			&company.Website,
 			&company.LogoPath,
 			&company.IsActive,
+			&company.SuspendedAt,
+			&company.SuspendedReason,
+			&company.RequireTwoFactor,
+			&company.Role,
 			&company.CreatedAt,
 			&company.UpdatedAt,
 		)
@@ -248,7 +320,7 @@ func (r *CompanyRepository) Update(id int64, req *domain.UpdateCompanyRequest) e
 		WHERE id = $18
 	`
 
-	result, err := r.db.DB.Exec(
+	result, err := r.db.ExecAs(r.actorID,
 		query,
 		req.Name,
 		req.TradeName,
@@ -290,7 +362,7 @@ func (r *CompanyRepository) Update(id int64, req *domain.UpdateCompanyRequest) e
 func (r *CompanyRepository) Delete(id int64) error {
 	query := `UPDATE companies SET is_active = false, updated_at = NOW() WHERE id = $1`
 
-	result, err := r.db.DB.Exec(query, id)
+	result, err := r.db.ExecAs(r.actorID, query, id)
 	if err != nil {
 		return fmt.Errorf("error deleting company: %w", err)
 	}
@@ -314,7 +386,7 @@ func (r *CompanyRepository) GetByNIT(nit, dv string) (*domain.Company, error) {
 			id, user_id, document_type_id, nit, dv, name, trade_name, registration_name,
 			tax_level_code_id, type_organization_id, type_regime_id, industry_codes,
 			country_id, department_id, municipality_id, address_line, postal_zone,
-			phone, email, website, logo_path, is_active,
+			phone, email, website, logo_path, is_active, suspended_at, suspended_reason, require_two_factor,
 			created_at, updated_at
 		FROM companies
 		WHERE nit = $1 AND dv = $2 AND is_active = true
@@ -344,6 +416,9 @@ func (r *CompanyRepository) GetByNIT(nit, dv string) (*domain.Company, error) {
 		&company.Website,
 		&company.LogoPath,
 		&company.IsActive,
+		&company.SuspendedAt,
+		&company.SuspendedReason,
+		&company.RequireTwoFactor,
 		&company.CreatedAt,
 		&company.UpdatedAt,
 	)
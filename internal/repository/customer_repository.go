@@ -10,13 +10,19 @@ import (
 )
 
 type CustomerRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // Usuario al que se atribuyen las escrituras en audit_log; 0 para procesos del sistema
 }
 
 func NewCustomerRepository(db *database.Database) *CustomerRepository {
 	return &CustomerRepository{db: db}
 }
 
+// As retorna una copia del repositorio cuyas escrituras quedan en audit_log a nombre del usuario
+func (r *CustomerRepository) As(userID int64) *CustomerRepository {
+	return &CustomerRepository{db: r.db, actorID: userID}
+}
+
 // Create crea un nuevo cliente
 func (r *CustomerRepository) Create(userID int64, req *domain.CreateCustomerRequest) (*domain.Customer, error) {
 	query := `
@@ -50,7 +56,7 @@ func (r *CustomerRepository) Create(userID int64, req *domain.CreateCustomerRequ
 		Email:                req.Email,
 	}
 
-	err := r.db.DB.QueryRow(
+	err := r.db.QueryRowAs(userID,
 		query,
 		req.CompanyID,
 		req.DocumentTypeID,
@@ -212,7 +218,8 @@ func (r *CustomerRepository) GetByUserID(userID int64, page, pageSize int) ([]do
 		SELECT COUNT(*) 
 		FROM customers c
 		INNER JOIN companies co ON c.company_id = co.id
-		WHERE co.user_id = $1 AND c.is_active = true AND co.is_active = true
+		INNER JOIN company_members m ON m.company_id = co.id
+		WHERE m.user_id = $1 AND c.is_active = true AND co.is_active = true
 	`
 	err := r.db.DB.QueryRow(countQuery, userID).Scan(&total)
 	if err != nil {
@@ -228,7 +235,8 @@ func (r *CustomerRepository) GetByUserID(userID int64, page, pageSize int) ([]do
 			c.phone, c.email, c.is_active, c.created_at, c.updated_at
 		FROM customers c
 		INNER JOIN companies co ON c.company_id = co.id
-		WHERE co.user_id = $1 AND c.is_active = true AND co.is_active = true
+		INNER JOIN company_members m ON m.company_id = co.id
+		WHERE m.user_id = $1 AND c.is_active = true AND co.is_active = true
 		ORDER BY c.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -294,7 +302,7 @@ func (r *CustomerRepository) Update(id int64, req *domain.UpdateCustomerRequest)
 		WHERE id = $14
 	`
 
-	result, err := r.db.DB.Exec(
+	result, err := r.db.ExecAs(r.actorID,
 		query,
 		req.Name,
 		req.TradeName,
@@ -332,7 +340,7 @@ func (r *CustomerRepository) Update(id int64, req *domain.UpdateCustomerRequest)
 func (r *CustomerRepository) Delete(id int64) error {
 	query := `UPDATE customers SET is_active = false, updated_at = NOW() WHERE id = $1`
 
-	result, err := r.db.DB.Exec(query, id)
+	result, err := r.db.ExecAs(r.actorID, query, id)
 	if err != nil {
 		return fmt.Errorf("error deleting customer: %w", err)
 	}
@@ -394,3 +402,57 @@ func (r *CustomerRepository) GetByIdentification(companyID int64, identification
 
 	return customer, nil
 }
+
+// GetAllByCompanyID obtiene todos los clientes activos de una empresa sin paginación (exportación)
+func (r *CustomerRepository) GetAllByCompanyID(companyID int64) ([]domain.Customer, error) {
+	query := `
+		SELECT
+			id, company_id, document_type_id, identification_number, dv, name, trade_name,
+			tax_level_code_id, tax_type_id, type_organization_id, type_regime_id,
+			country_id, department_id, municipality_id, address_line, postal_zone,
+			phone, email, is_active, created_at, updated_at
+		FROM customers
+		WHERE company_id = $1 AND is_active = true
+		ORDER BY identification_number
+	`
+
+	rows, err := r.db.DB.Query(query, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []domain.Customer{}
+	for rows.Next() {
+		var customer domain.Customer
+		err := rows.Scan(
+			&customer.ID,
+			&customer.CompanyID,
+			&customer.DocumentTypeID,
+			&customer.IdentificationNumber,
+			&customer.DV,
+			&customer.Name,
+			&customer.TradeName,
+			&customer.TaxLevelCodeID,
+			&customer.TaxTypeID,
+			&customer.TypeOrganizationID,
+			&customer.TypeRegimeID,
+			&customer.CountryID,
+			&customer.DepartmentID,
+			&customer.MunicipalityID,
+			&customer.AddressLine,
+			&customer.PostalZone,
+			&customer.Phone,
+			&customer.Email,
+			&customer.IsActive,
+			&customer.CreatedAt,
+			&customer.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning customer: %w", err)
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, rows.Err()
+}
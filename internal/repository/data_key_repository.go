@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"fmt"
+)
+
+type DataKeyRepository struct {
+	db *database.Database
+}
+
+func NewDataKeyRepository(db *database.Database) *DataKeyRepository {
+	return &DataKeyRepository{db: db}
+}
+
+// GetByCompanyID obtiene la llave de datos envuelta de una empresa
+func (r *DataKeyRepository) GetByCompanyID(companyID int64) (*domain.CompanyDataKey, error) {
+	query := `
+		SELECT company_id, wrapped_key, created_at
+		FROM company_data_keys
+		WHERE company_id = $1
+	`
+
+	key := &domain.CompanyDataKey{}
+	err := r.db.DB.QueryRow(query, companyID).Scan(&key.CompanyID, &key.WrappedKey, &key.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("data key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// CreateIfNotExists guarda la llave si la empresa aún no tiene una y retorna la vigente.
+// Si otra réplica la creó primero, se conserva la existente.
+func (r *DataKeyRepository) CreateIfNotExists(companyID int64, wrappedKey string) (*domain.CompanyDataKey, error) {
+	query := `
+		INSERT INTO company_data_keys (company_id, wrapped_key, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (company_id) DO NOTHING
+	`
+
+	if _, err := r.db.DB.Exec(query, companyID, wrappedKey); err != nil {
+		return nil, err
+	}
+
+	return r.GetByCompanyID(companyID)
+}
+
+// GetAll obtiene las llaves de datos de todas las empresas
+func (r *DataKeyRepository) GetAll() ([]domain.CompanyDataKey, error) {
+	query := `
+		SELECT company_id, wrapped_key, created_at
+		FROM company_data_keys
+		ORDER BY company_id
+	`
+
+	rows, err := r.db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.CompanyDataKey
+	for rows.Next() {
+		var key domain.CompanyDataKey
+		if err := rows.Scan(&key.CompanyID, &key.WrappedKey, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// UpdateWrappedKey reemplaza la llave envuelta (mismo DEK, otra llave maestra)
+func (r *DataKeyRepository) UpdateWrappedKey(companyID int64, wrappedKey string) error {
+	query := `UPDATE company_data_keys SET wrapped_key = $1 WHERE company_id = $2`
+
+	_, err := r.db.DB.Exec(query, wrappedKey, companyID)
+	return err
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DocumentEventRepository guarda los eventos RADIAN de los documentos (tabla application_responses)
+type DocumentEventRepository struct {
+	db *database.Database
+}
+
+func NewDocumentEventRepository(db *database.Database) *DocumentEventRepository {
+	return &DocumentEventRepository{db: db}
+}
+
+// GetByDocumentID lista los eventos de un documento en el orden en que se registraron
+func (r *DocumentEventRepository) GetByDocumentID(documentID int64) ([]domain.DocumentEvent, error) {
+	return queryDocumentEvents(r.db.DB, documentID)
+}
+
+// eventQuerier es *sql.DB o *sql.Tx
+type eventQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryDocumentEvents(q eventQuerier, documentID int64) ([]domain.DocumentEvent, error) {
+	rows, err := q.Query(`
+		SELECT
+			ar.id, ar.document_id, e.code, e.name, rt.code, rt.name, ar.notes,
+			ar.registered_by_name, ar.registered_by_identification, ar.ip_address, ar.response_date
+		FROM application_responses ar
+		JOIN events e ON e.id = ar.event_id
+		LEFT JOIN rejection_types rt ON rt.id = ar.rejection_type_id
+		WHERE ar.document_id = $1
+		ORDER BY ar.response_date, ar.id
+	`, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying document events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.DocumentEvent{}
+	for rows.Next() {
+		var event domain.DocumentEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.DocumentID,
+			&event.EventCode,
+			&event.EventName,
+			&event.RejectionCode,
+			&event.RejectionName,
+			&event.Notes,
+			&event.RegisteredByName,
+			&event.RegisteredByIdentification,
+			&event.IPAddress,
+			&event.ResponseDate,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning document event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Create registra un evento; los códigos del evento y del motivo de reclamo se resuelven contra
+// los catálogos events y rejection_types. Bloquea el documento mientras valida el evento contra
+// los ya registrados (domain.CheckBuyerEvent), así dos registros simultáneos, como la aceptación
+// y el reclamo, no pasan la validación a la vez.
+func (r *DocumentEventRepository) Create(event *domain.DocumentEvent) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var acceptedByDIANAt *time.Time
+	err = tx.QueryRow(
+		`SELECT accepted_by_dian_at FROM documents WHERE id = $1 FOR UPDATE`, event.DocumentID,
+	).Scan(&acceptedByDIANAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("invoice not found")
+	}
+	if err != nil {
+		return fmt.Errorf("error locking document: %w", err)
+	}
+
+	events, err := queryDocumentEvents(tx, event.DocumentID)
+	if err != nil {
+		return err
+	}
+	if err := domain.CheckBuyerEvent(acceptedByDIANAt, events, event.EventCode); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO application_responses (
+			document_id, event_id, rejection_type_id, response_date, notes,
+			registered_by_name, registered_by_identification, ip_address
+		)
+		SELECT $1, e.id, rt.id, NOW(), $4, $5, $6, $7
+		FROM events e
+		LEFT JOIN rejection_types rt ON rt.code = $3 AND rt.is_active = true
+		WHERE e.code = $2 AND e.is_active = true AND ($3::varchar IS NULL OR rt.id IS NOT NULL)
+		RETURNING id, response_date, (SELECT name FROM events WHERE code = $2), (SELECT name FROM rejection_types WHERE code = $3)
+	`,
+		event.DocumentID,
+		event.EventCode,
+		event.RejectionCode,
+		event.Notes,
+		event.RegisteredByName,
+		event.RegisteredByIdentification,
+		event.IPAddress,
+	).Scan(&event.ID, &event.ResponseDate, &event.EventName, &event.RejectionName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("invalid event or rejection code")
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.New("event already registered")
+		}
+		return fmt.Errorf("error registering document event: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetRejectionTypes lista los motivos de reclamo activos
+func (r *DocumentEventRepository) GetRejectionTypes() ([]domain.RejectionType, error) {
+	rows, err := r.db.DB.Query(`SELECT code, name FROM rejection_types WHERE is_active = true ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying rejection types: %w", err)
+	}
+	defer rows.Close()
+
+	types := []domain.RejectionType{}
+	for rows.Next() {
+		var rejectionType domain.RejectionType
+		if err := rows.Scan(&rejectionType.Code, &rejectionType.Name); err != nil {
+			return nil, fmt.Errorf("error scanning rejection type: %w", err)
+		}
+		types = append(types, rejectionType)
+	}
+	return types, rows.Err()
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type InvoiceImportRepository struct {
+	db *database.Database
+}
+
+func NewInvoiceImportRepository(db *database.Database) *InvoiceImportRepository {
+	return &InvoiceImportRepository{db: db}
+}
+
+// Create registra un trabajo de importación en estado pending
+func (r *InvoiceImportRepository) Create(job *domain.InvoiceImportJob) error {
+	query := `
+		INSERT INTO invoice_import_jobs (
+			company_id, user_id, filename, status, total_invoices, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	job.Status = domain.ImportJobStatusPending
+	err := r.db.DB.QueryRow(
+		query,
+		job.CompanyID,
+		job.UserID,
+		job.Filename,
+		job.Status,
+		job.TotalInvoices,
+	).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating import job: %w", err)
+	}
+
+	job.Results = []domain.InvoiceImportJobResult{}
+	return nil
+}
+
+// GetByID obtiene un trabajo de importación con sus resultados
+func (r *InvoiceImportRepository) GetByID(id int64) (*domain.InvoiceImportJob, error) {
+	query := `
+		SELECT
+			id, company_id, user_id, filename, status, total_invoices,
+			processed, succeeded, failed, results, error,
+			created_at, started_at, finished_at, updated_at
+		FROM invoice_import_jobs
+		WHERE id = $1
+	`
+
+	job := &domain.InvoiceImportJob{}
+	var results []byte
+	err := r.db.DB.QueryRow(query, id).Scan(
+		&job.ID,
+		&job.CompanyID,
+		&job.UserID,
+		&job.Filename,
+		&job.Status,
+		&job.TotalInvoices,
+		&job.Processed,
+		&job.Succeeded,
+		&job.Failed,
+		&results,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting import job: %w", err)
+	}
+
+	if err := json.Unmarshal(results, &job.Results); err != nil {
+		return nil, fmt.Errorf("error decoding import job results: %w", err)
+	}
+
+	return job, nil
+}
+
+// MarkRunning marca el inicio del procesamiento
+func (r *InvoiceImportRepository) MarkRunning(id int64) error {
+	_, err := r.db.DB.Exec(`
+		UPDATE invoice_import_jobs
+		SET status = $1, started_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`, domain.ImportJobStatusRunning, id)
+	if err != nil {
+		return fmt.Errorf("error updating import job: %w", err)
+	}
+	return nil
+}
+
+// UpdateProgress guarda el avance y los resultados acumulados
+func (r *InvoiceImportRepository) UpdateProgress(job *domain.InvoiceImportJob) error {
+	results, err := json.Marshal(job.Results)
+	if err != nil {
+		return fmt.Errorf("error encoding import job results: %w", err)
+	}
+
+	_, err = r.db.DB.Exec(`
+		UPDATE invoice_import_jobs
+		SET processed = $1, succeeded = $2, failed = $3, results = $4, updated_at = NOW()
+		WHERE id = $5
+	`, job.Processed, job.Succeeded, job.Failed, results, job.ID)
+	if err != nil {
+		return fmt.Errorf("error updating import job progress: %w", err)
+	}
+	return nil
+}
+
+// Finish cierra el trabajo como completed o failed
+func (r *InvoiceImportRepository) Finish(id int64, status string, errMsg *string) error {
+	_, err := r.db.DB.Exec(`
+		UPDATE invoice_import_jobs
+		SET status = $1, error = $2, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`, status, errMsg, id)
+	if err != nil {
+		return fmt.Errorf("error finishing import job: %w", err)
+	}
+	return nil
+}
+
+// Touch renueva updated_at mientras el trabajo sigue en ejecución (latido)
+func (r *InvoiceImportRepository) Touch(id int64) error {
+	_, err := r.db.DB.Exec(`
+		UPDATE invoice_import_jobs SET updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`, id, domain.ImportJobStatusRunning)
+	if err != nil {
+		return fmt.Errorf("error updating import job: %w", err)
+	}
+	return nil
+}
+
+// FailStale cierra como failed los trabajos pending o running sin latido desde before (el proceso
+// que los ejecutaba terminó); los resultados guardados indican qué facturas alcanzaron a crearse
+func (r *InvoiceImportRepository) FailStale(before time.Time, errMsg string) (int64, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE invoice_import_jobs
+		SET status = $1, error = $2, finished_at = NOW(), updated_at = NOW()
+		WHERE status IN ($3, $4) AND updated_at < $5
+	`, domain.ImportJobStatusFailed, errMsg, domain.ImportJobStatusPending, domain.ImportJobStatusRunning, before)
+	if err != nil {
+		return 0, fmt.Errorf("error failing stale import jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -9,30 +9,64 @@ import (
 )
 
 type InvoiceRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // Usuario al que se atribuyen las escrituras en audit_log; 0 para procesos del sistema
 }
 
 func NewInvoiceRepository(db *database.Database) *InvoiceRepository {
 	return &InvoiceRepository{db: db}
 }
 
-// Create crea una nueva factura con sus líneas
-func (r *InvoiceRepository) Create(invoice *domain.Invoice, lines []domain.InvoiceLine) error {
+// As retorna una copia del repositorio cuyas escrituras quedan en audit_log a nombre del usuario
+func (r *InvoiceRepository) As(userID int64) *InvoiceRepository {
+	return &InvoiceRepository{db: r.db, actorID: userID}
+}
+
+// Create crea una nueva factura con sus líneas. El consecutivo de invoice.ResolutionID (o de su
+// resolución de continuación) se asigna en la misma transacción y queda registrado con el
+// motivo de origin, así un INSERT fallido no consume el número. Retorna ErrDocumentQuotaExceeded
+// si la empresa agotó los documentos del mes de su plan.
+func (r *InvoiceRepository) Create(invoice *domain.Invoice, lines []domain.InvoiceLine, origin domain.NumberOrigin) error {
 	tx, err := r.db.DB.Begin()
 	if err != nil {
 		return fmt.Errorf("error starting transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := database.SetActor(tx, r.actorID); err != nil {
+		return err
+	}
+
+	// Conversión de cotización: se reclama antes de consumir cuota y consecutivo
+	if origin.QuotationID != nil {
+		if err := claimQuotation(tx, *origin.QuotationID); err != nil {
+			return err
+		}
+	}
+
+	// Cuota de documentos del plan; se descuenta solo si la factura se crea
+	if err := consumeDocumentQuota(tx, invoice.CompanyID, time.Now()); err != nil {
+		return err
+	}
+
+	// Asignar consecutivo (formato del número: PREFIX + consecutivo)
+	resolutionID, prefix, consecutive, err := allocateConsecutive(tx, invoice.ResolutionID, time.Now())
+	if err != nil {
+		return fmt.Errorf("error getting consecutive: %w", err)
+	}
+	invoice.ResolutionID = resolutionID
+	invoice.Consecutive = consecutive
+	invoice.Number = fmt.Sprintf("%s%d", prefix, consecutive)
+
 	// Insertar documento (factura) - UUID se generará al firmar (CUFE)
 	query := `
 		INSERT INTO documents (
 			company_id, customer_id, resolution_id, number, consecutive,
 			issue_date, issue_time, due_date, type_document_id, currency_code_id,
 			notes, payment_method_id, payment_form_id,
-			subtotal, tax_total, total, status,
+			subtotal, tax_total, total, prepaid_amount, status,
 			created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW(), NOW())
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
 
@@ -54,6 +88,7 @@ func (r *InvoiceRepository) Create(invoice *domain.Invoice, lines []domain.Invoi
 		invoice.Subtotal,
 		invoice.TaxTotal,
 		invoice.Total,
+		invoice.PrepaidAmount,
 		invoice.Status,
 	).Scan(&invoice.ID, &invoice.CreatedAt, &invoice.UpdatedAt)
 
@@ -97,6 +132,57 @@ func (r *InvoiceRepository) Create(invoice *domain.Invoice, lines []domain.Invoi
 		lines[i].LineNumber = int64(i + 1)
 	}
 
+	// Insertar cuotas
+	for i := range invoice.Installments {
+		installment := &invoice.Installments[i]
+		err = tx.QueryRow(`
+			INSERT INTO document_installments (
+				document_id, installment_number, due_date, amount, payment_method_id, created_at
+			) VALUES ($1, $2, $3, $4, $5, NOW())
+			RETURNING id, created_at
+		`,
+			invoice.ID,
+			i+1,
+			installment.DueDate,
+			installment.Amount,
+			installment.PaymentMethodID,
+		).Scan(&installment.ID, &installment.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error creating invoice installment %d: %w", i+1, err)
+		}
+		installment.DocumentID = invoice.ID
+		installment.InstallmentNumber = i + 1
+	}
+
+	// Insertar anticipos
+	for i := range invoice.Prepayments {
+		prepayment := &invoice.Prepayments[i]
+		err = tx.QueryRow(`
+			INSERT INTO document_prepayments (document_id, received_date, amount, reference, created_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			RETURNING id, created_at
+		`,
+			invoice.ID,
+			prepayment.ReceivedDate,
+			prepayment.Amount,
+			prepayment.Reference,
+		).Scan(&prepayment.ID, &prepayment.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error creating invoice prepayment %d: %w", i+1, err)
+		}
+		prepayment.DocumentID = invoice.ID
+	}
+
+	if err := recordNumber(tx, invoice, origin); err != nil {
+		return fmt.Errorf("error recording consecutive: %w", err)
+	}
+
+	if origin.QuotationID != nil {
+		if err := markQuotationConverted(tx, *origin.QuotationID, invoice.ID); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error committing transaction: %w", err)
 	}
@@ -115,8 +201,8 @@ func (r *InvoiceRepository) GetByID(id int64) (*domain.Invoice, error) {
 			d.id, d.company_id, d.customer_id, d.resolution_id, d.number, d.consecutive,
 			d.uuid, d.issue_date, d.issue_time, d.due_date, d.type_document_id, d.currency_code_id,
 			d.notes, d.payment_method_id, d.payment_form_id,
-			d.subtotal, d.tax_total, d.total,
-			d.xml_path, d.pdf_path, d.zip_path, d.qr_code_url, d.track_id,
+			d.subtotal, d.tax_total, d.total, d.prepaid_amount,
+			d.xml_path, d.pdf_path, d.zip_path, d.qr_code_url, d.track_id, d.certificate_id,
 			d.status, d.dian_status, d.dian_response, d.dian_status_code, d.dian_status_description,
 			d.sent_to_dian_at, d.accepted_by_dian_at,
 			d.created_at, d.updated_at,
@@ -265,11 +351,13 @@ func (r *InvoiceRepository) GetByID(id int64) (*domain.Invoice, error) {
 		&invoice.Subtotal,
 		&invoice.TaxTotal,
 		&invoice.Total,
+		&invoice.PrepaidAmount,
 		&invoice.XMLPath,
 		&invoice.PDFPath,
 		&invoice.ZipPath,
 		&invoice.QRCodeURL,
 		&invoice.TrackID,
+		&invoice.CertificateID,
 		&invoice.Status,
 		&invoice.DIANStatus,
 		&invoice.DIANResponse,
@@ -382,9 +470,98 @@ func (r *InvoiceRepository) GetByID(id int64) (*domain.Invoice, error) {
 	}
 	invoice.Lines = lines
 
+	// Obtener cuotas y anticipos
+	installments, err := r.GetInstallmentsByDocumentID(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Installments = installments
+
+	prepayments, err := r.GetPrepaymentsByDocumentID(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	invoice.Prepayments = prepayments
+
 	return invoice, nil
 }
 
+// GetInstallmentsByDocumentID obtiene las cuotas de una factura en orden de vencimiento
+func (r *InvoiceRepository) GetInstallmentsByDocumentID(documentID int64) ([]domain.InvoiceInstallment, error) {
+	query := `
+		SELECT
+			di.id, di.document_id, di.installment_number, di.due_date, di.amount,
+			di.payment_method_id, di.created_at, pm.code, pm.name
+		FROM document_installments di
+		LEFT JOIN payment_methods pm ON di.payment_method_id = pm.id
+		WHERE di.document_id = $1
+		ORDER BY di.installment_number ASC
+	`
+
+	rows, err := r.db.DB.Query(query, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying installments: %w", err)
+	}
+	defer rows.Close()
+
+	var installments []domain.InvoiceInstallment
+	for rows.Next() {
+		var installment domain.InvoiceInstallment
+		err := rows.Scan(
+			&installment.ID,
+			&installment.DocumentID,
+			&installment.InstallmentNumber,
+			&installment.DueDate,
+			&installment.Amount,
+			&installment.PaymentMethodID,
+			&installment.CreatedAt,
+			&installment.PaymentMethodCode,
+			&installment.PaymentMethodName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning installment: %w", err)
+		}
+		installments = append(installments, installment)
+	}
+
+	return installments, rows.Err()
+}
+
+// GetPrepaymentsByDocumentID obtiene los anticipos aplicados a una factura
+func (r *InvoiceRepository) GetPrepaymentsByDocumentID(documentID int64) ([]domain.InvoicePrepayment, error) {
+	query := `
+		SELECT id, document_id, received_date, amount, reference, created_at
+		FROM document_prepayments
+		WHERE document_id = $1
+		ORDER BY received_date ASC, id ASC
+	`
+
+	rows, err := r.db.DB.Query(query, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying prepayments: %w", err)
+	}
+	defer rows.Close()
+
+	var prepayments []domain.InvoicePrepayment
+	for rows.Next() {
+		var prepayment domain.InvoicePrepayment
+		err := rows.Scan(
+			&prepayment.ID,
+			&prepayment.DocumentID,
+			&prepayment.ReceivedDate,
+			&prepayment.Amount,
+			&prepayment.Reference,
+			&prepayment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning prepayment: %w", err)
+		}
+		prepayments = append(prepayments, prepayment)
+	}
+
+	return prepayments, rows.Err()
+}
+
 // GetLinesByDocumentID obtiene las líneas de un documento (sin JOINs, para compatibilidad)
 // DEPRECATED: Usar GetLinesDetailByDocumentID para datos completos
 func (r *InvoiceRepository) GetLinesByDocumentID(documentID int64) ([]domain.InvoiceLine, error) {
@@ -532,7 +709,7 @@ func (r *InvoiceRepository) GetByCompanyID(companyID int64, limit, offset int) (
 			id, company_id, customer_id, resolution_id, number, consecutive,
 			uuid, issue_date, issue_time, due_date, type_document_id, currency_code_id,
 			notes, payment_method_id, payment_form_id,
-			subtotal, tax_total, total,
+			subtotal, tax_total, total, prepaid_amount,
 			xml_path, pdf_path, zip_path, qr_code_url,
 			status, dian_status, dian_response, dian_status_code, dian_status_description,
 			sent_to_dian_at, accepted_by_dian_at,
@@ -571,6 +748,7 @@ func (r *InvoiceRepository) GetByCompanyID(companyID int64, limit, offset int) (
 			&invoice.Subtotal,
 			&invoice.TaxTotal,
 			&invoice.Total,
+			&invoice.PrepaidAmount,
 			&invoice.XMLPath,
 			&invoice.PDFPath,
 			&invoice.ZipPath,
@@ -608,7 +786,7 @@ func (r *InvoiceRepository) Update(invoice *domain.Invoice) error {
 		RETURNING updated_at
 	`
 
-	err := r.db.DB.QueryRow(
+	err := r.db.QueryRowAs(r.actorID,
 		query,
 		invoice.DueDate,
 		invoice.Notes,
@@ -631,7 +809,7 @@ func (r *InvoiceRepository) UpdateStatus(id int64, status string) error {
 		WHERE id = $2 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, status, id)
+	result, err := r.db.ExecAs(r.actorID, query, status, id)
 	if err != nil {
 		return err
 	}
@@ -649,12 +827,31 @@ func (r *InvoiceRepository) UpdateStatus(id int64, status string) error {
 
 // Delete elimina una factura (solo si está en draft)
 func (r *InvoiceRepository) Delete(id int64) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := database.SetActor(tx, r.actorID); err != nil {
+		return err
+	}
+
+	// El consecutivo queda registrado como borrado para el reporte de numeración
+	if _, err := tx.Exec(`
+		UPDATE resolution_numbers
+		SET status = $2, updated_at = NOW()
+		WHERE document_id = $1 AND status = $3
+	`, id, domain.NumberStatusDeleted, domain.NumberStatusIssued); err != nil {
+		return err
+	}
+
 	query := `
 		DELETE FROM documents
 		WHERE id = $1 AND type_document_id = 1 AND status = 'draft'
 	`
 
-	result, err := r.db.DB.Exec(query, id)
+	result, err := tx.Exec(query, id)
 	if err != nil {
 		return err
 	}
@@ -667,7 +864,7 @@ func (r *InvoiceRepository) Delete(id int64) error {
 		return fmt.Errorf("invoice not found or cannot be deleted (only draft invoices can be deleted)")
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // UpdateDIANStatus actualiza el estado DIAN de una factura
@@ -685,7 +882,7 @@ func (r *InvoiceRepository) UpdateDIANStatus(id int64, dianStatus, dianResponse,
 		WHERE id = $5 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, dianStatus, dianResponse, dianStatusCode, dianStatusDescription, id)
+	result, err := r.db.ExecAs(r.actorID, query, dianStatus, dianResponse, dianStatusCode, dianStatusDescription, id)
 	if err != nil {
 		return err
 	}
@@ -709,7 +906,7 @@ func (r *InvoiceRepository) UpdateIssueDateAndTime(id int64, issueDate time.Time
 		WHERE id = $3 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, issueDate, issueTime, id)
+	result, err := r.db.ExecAs(r.actorID, query, issueDate, issueTime, id)
 	if err != nil {
 		return err
 	}
@@ -733,7 +930,7 @@ func (r *InvoiceRepository) UpdateUUID(id int64, uuid string) error {
 		WHERE id = $2 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, uuid, id)
+	result, err := r.db.ExecAs(r.actorID, query, uuid, id)
 	if err != nil {
 		return err
 	}
@@ -757,7 +954,31 @@ func (r *InvoiceRepository) UpdateXMLPath(id int64, xmlPath string) error {
 		WHERE id = $2 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, xmlPath, id)
+	result, err := r.db.ExecAs(r.actorID, query, xmlPath, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("invoice not found")
+	}
+
+	return nil
+}
+
+// UpdateCertificateID registra el certificado con el que se firmó la factura
+func (r *InvoiceRepository) UpdateCertificateID(id int64, certificateID int64) error {
+	query := `
+		UPDATE documents
+		SET certificate_id = $1, updated_at = NOW()
+		WHERE id = $2 AND type_document_id = 1
+	`
+
+	result, err := r.db.ExecAs(r.actorID, query, certificateID, id)
 	if err != nil {
 		return err
 	}
@@ -781,7 +1002,7 @@ func (r *InvoiceRepository) UpdatePDFPath(id int64, pdfPath string) error {
 		WHERE id = $2 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, pdfPath, id)
+	result, err := r.db.ExecAs(r.actorID, query, pdfPath, id)
 	if err != nil {
 		return err
 	}
@@ -823,7 +1044,7 @@ func (r *InvoiceRepository) UpdateZIPPath(id int64, zipPath string) error {
 		WHERE id = $2 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, zipPath, id)
+	result, err := r.db.ExecAs(r.actorID, query, zipPath, id)
 	if err != nil {
 		return err
 	}
@@ -847,7 +1068,7 @@ func (r *InvoiceRepository) UpdateTrackId(id int64, trackId string) error {
 		WHERE id = $2 AND type_document_id = 1
 	`
 
-	result, err := r.db.DB.Exec(query, trackId, id)
+	result, err := r.db.ExecAs(r.actorID, query, trackId, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LoginAttemptRepository guarda los contadores de logins fallidos en Postgres para que todas las
+// réplicas de la API apliquen los mismos límites
+type LoginAttemptRepository struct {
+	db *database.Database
+}
+
+func NewLoginAttemptRepository(db *database.Database) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// Claim reserva un intento de login antes de verificar la contraseña: cuenta el intento y fija la
+// espera y el bloqueo que aplican al siguiente con la fila bloqueada (FOR UPDATE), así las
+// peticiones simultáneas se serializan y ninguna pasa con un contador desactualizado. Retorna
+// false, sin contar el intento, si el contador está bloqueado o en espera. El contador vuelve a
+// uno si el último intento es anterior a domain.LoginFailureWindowMinutes o si ya venció un
+// bloqueo. También depura los contadores viejos.
+func (r *LoginAttemptRepository) Claim(scope, subject string, limits domain.LoginLimits) (*domain.LoginFailure, bool, error) {
+	if _, err := r.db.DB.Exec(`
+		DELETE FROM login_failures
+		WHERE last_failed_at < NOW() - make_interval(mins => $1) AND (locked_until IS NULL OR locked_until < NOW())
+	`, domain.LoginFailureWindowMinutes); err != nil {
+		return nil, false, fmt.Errorf("error purging login failures: %w", err)
+	}
+
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO login_failures (scope, subject, failures) VALUES ($1, $2, 0)
+		ON CONFLICT (scope, subject) DO NOTHING
+	`, scope, subject); err != nil {
+		return nil, false, fmt.Errorf("error claiming login attempt: %w", err)
+	}
+
+	failure := &domain.LoginFailure{Scope: scope, Subject: subject}
+	err = tx.QueryRow(`
+		SELECT failures, first_failed_at, last_failed_at, next_attempt_at, locked_until
+		FROM login_failures
+		WHERE scope = $1 AND subject = $2
+		FOR UPDATE
+	`, scope, subject).Scan(
+		&failure.Failures,
+		&failure.FirstFailedAt,
+		&failure.LastFailedAt,
+		&failure.NextAttemptAt,
+		&failure.LockedUntil,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("error claiming login attempt: %w", err)
+	}
+
+	if !claimAttempt(failure, time.Now(), limits) {
+		return failure, false, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE login_failures
+		SET failures = $3, first_failed_at = $4, last_failed_at = $5, next_attempt_at = $6, locked_until = $7
+		WHERE scope = $1 AND subject = $2
+	`, scope, subject, failure.Failures, failure.FirstFailedAt, failure.LastFailedAt, failure.NextAttemptAt, failure.LockedUntil); err != nil {
+		return nil, false, fmt.Errorf("error claiming login attempt: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return failure, true, nil
+}
+
+// claimAttempt cuenta un intento en el contador a la hora now y fija la espera y el bloqueo que
+// aplican al siguiente; retorna false, sin cambiar el contador, si está bloqueado o en espera
+func claimAttempt(failure *domain.LoginFailure, now time.Time, limits domain.LoginLimits) bool {
+	if failure.LockedUntil != nil && failure.LockedUntil.After(now) {
+		return false
+	}
+	if failure.NextAttemptAt != nil && failure.NextAttemptAt.After(now) {
+		return false
+	}
+
+	window := domain.LoginFailureWindowMinutes * time.Minute
+	if failure.Failures == 0 || failure.LockedUntil != nil || failure.LastFailedAt.Before(now.Add(-window)) {
+		failure.Failures = 0
+		failure.FirstFailedAt = now
+	}
+	failure.Failures++
+	failure.LastFailedAt = now
+	failure.NextAttemptAt = nil
+	failure.LockedUntil = nil
+	if limits.Delay {
+		if delay := domain.LoginDelay(failure.Failures); delay > 0 {
+			next := now.Add(delay)
+			failure.NextAttemptAt = &next
+		}
+	}
+	if limits.MaxFailures > 0 && failure.Failures >= limits.MaxFailures {
+		until := now.Add(time.Duration(limits.LockMinutes) * time.Minute)
+		failure.LockedUntil = &until
+	}
+	return true
+}
+
+// Release devuelve un intento reservado con Claim que no resultó fallido: descuenta el intento,
+// quita la espera y levanta el bloqueo si el contador quedó por debajo de maxFailures
+func (r *LoginAttemptRepository) Release(scope, subject string, maxFailures int) error {
+	_, err := r.db.DB.Exec(`
+		UPDATE login_failures SET
+			failures = GREATEST(failures - 1, 0),
+			next_attempt_at = NULL,
+			locked_until = CASE WHEN $3 > 0 AND failures - 1 >= $3 THEN locked_until END
+		WHERE scope = $1 AND subject = $2
+	`, scope, subject, maxFailures)
+	if err != nil {
+		return fmt.Errorf("error releasing login attempt: %w", err)
+	}
+	return nil
+}
+
+// Reset borra el contador y retorna los intentos que tenía (incluido el que lo reinicia)
+func (r *LoginAttemptRepository) Reset(scope, subject string) (int, error) {
+	var failures int
+	err := r.db.DB.QueryRow(`
+		DELETE FROM login_failures WHERE scope = $1 AND subject = $2 RETURNING failures
+	`, scope, subject).Scan(&failures)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("error resetting login failures: %w", err)
+	}
+	return failures, nil
+}
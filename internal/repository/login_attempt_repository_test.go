@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:   0,
+		2:   0,
+		3:   time.Second,
+		4:   2 * time.Second,
+		5:   4 * time.Second,
+		8:   32 * time.Second,
+		9:   domain.LoginMaxDelaySeconds * time.Second,
+		100: domain.LoginMaxDelaySeconds * time.Second,
+	}
+	for failures, want := range tests {
+		if got := domain.LoginDelay(failures); got != want {
+			t.Errorf("LoginDelay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestClaimAttempt(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	account := domain.LoginLimits{Delay: true, MaxFailures: 5, LockMinutes: 15}
+	ip := domain.LoginLimits{MaxFailures: 20, LockMinutes: 15}
+
+	tests := []struct {
+		name         string
+		failure      domain.LoginFailure
+		limits       domain.LoginLimits
+		claimed      bool
+		failures     int
+		nextAttempt  *time.Time
+		lockedUntil  *time.Time
+		firstFailure time.Time
+	}{
+		{
+			name:         "first attempt",
+			limits:       account,
+			claimed:      true,
+			failures:     1,
+			firstFailure: now,
+		},
+		{
+			name:         "delay starts at the third failure",
+			failure:      domain.LoginFailure{Failures: 2, FirstFailedAt: now.Add(-time.Minute), LastFailedAt: now.Add(-time.Second)},
+			limits:       account,
+			claimed:      true,
+			failures:     3,
+			nextAttempt:  at(time.Second),
+			firstFailure: now.Add(-time.Minute),
+		},
+		{
+			name:         "delay elapsed",
+			failure:      domain.LoginFailure{Failures: 3, FirstFailedAt: now.Add(-time.Minute), LastFailedAt: now.Add(-2 * time.Second), NextAttemptAt: at(-time.Second)},
+			limits:       account,
+			claimed:      true,
+			failures:     4,
+			nextAttempt:  at(2 * time.Second),
+			firstFailure: now.Add(-time.Minute),
+		},
+		{
+			name:         "lock at max failures",
+			failure:      domain.LoginFailure{Failures: 4, FirstFailedAt: now.Add(-time.Minute), LastFailedAt: now.Add(-3 * time.Second)},
+			limits:       account,
+			claimed:      true,
+			failures:     5,
+			nextAttempt:  at(4 * time.Second),
+			lockedUntil:  at(15 * time.Minute),
+			firstFailure: now.Add(-time.Minute),
+		},
+		{
+			name:        "still waiting",
+			failure:     domain.LoginFailure{Failures: 3, LastFailedAt: now, NextAttemptAt: at(time.Second)},
+			limits:      account,
+			failures:    3,
+			nextAttempt: at(time.Second),
+		},
+		{
+			name:        "locked",
+			failure:     domain.LoginFailure{Failures: 5, LastFailedAt: now.Add(-time.Minute), LockedUntil: at(time.Minute)},
+			limits:      account,
+			failures:    5,
+			lockedUntil: at(time.Minute),
+		},
+		{
+			name:         "expired lock starts over",
+			failure:      domain.LoginFailure{Failures: 5, LastFailedAt: now.Add(-16 * time.Minute), LockedUntil: at(-time.Minute)},
+			limits:       account,
+			claimed:      true,
+			failures:     1,
+			firstFailure: now,
+		},
+		{
+			name:         "failures outside the window start over",
+			failure:      domain.LoginFailure{Failures: 4, LastFailedAt: now.Add(-(domain.LoginFailureWindowMinutes + 1) * time.Minute)},
+			limits:       account,
+			claimed:      true,
+			failures:     1,
+			firstFailure: now,
+		},
+		{
+			name:         "ip counters have no delay",
+			failure:      domain.LoginFailure{Failures: 10, FirstFailedAt: now.Add(-time.Minute), LastFailedAt: now},
+			limits:       ip,
+			claimed:      true,
+			failures:     11,
+			firstFailure: now.Add(-time.Minute),
+		},
+		{
+			name:         "max failures 0 never locks",
+			failure:      domain.LoginFailure{Failures: 50, FirstFailedAt: now.Add(-time.Minute), LastFailedAt: now},
+			limits:       domain.LoginLimits{LockMinutes: 15},
+			claimed:      true,
+			failures:     51,
+			firstFailure: now.Add(-time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := tt.failure
+			if claimed := claimAttempt(&failure, now, tt.limits); claimed != tt.claimed {
+				t.Fatalf("claimed = %v, want %v", claimed, tt.claimed)
+			}
+			if failure.Failures != tt.failures {
+				t.Errorf("failures = %d, want %d", failure.Failures, tt.failures)
+			}
+			if !equalTime(failure.NextAttemptAt, tt.nextAttempt) {
+				t.Errorf("next attempt = %v, want %v", failure.NextAttemptAt, tt.nextAttempt)
+			}
+			if !equalTime(failure.LockedUntil, tt.lockedUntil) {
+				t.Errorf("locked until = %v, want %v", failure.LockedUntil, tt.lockedUntil)
+			}
+			if tt.claimed && !failure.FirstFailedAt.Equal(tt.firstFailure) {
+				t.Errorf("first failure = %v, want %v", failure.FirstFailedAt, tt.firstFailure)
+			}
+		})
+	}
+}
+
+// Un atacante que respeta cada espera queda bloqueado en el intento LOGIN_MAX_FAILURES
+func TestClaimAttemptLocksAfterMaxFailures(t *testing.T) {
+	limits := domain.LoginLimits{Delay: true, MaxFailures: 5, LockMinutes: 15}
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	failure := &domain.LoginFailure{}
+
+	for i := 1; i <= limits.MaxFailures; i++ {
+		if !claimAttempt(failure, now, limits) {
+			t.Fatalf("attempt %d rejected", i)
+		}
+		if i < limits.MaxFailures && failure.LockedUntil != nil {
+			t.Fatalf("locked after %d failures", i)
+		}
+		if failure.NextAttemptAt != nil {
+			now = *failure.NextAttemptAt
+		}
+	}
+	if failure.LockedUntil == nil {
+		t.Fatal("not locked after max failures")
+	}
+
+	// Durante el bloqueo ningún intento cuenta, aunque ya pasó la espera
+	if claimAttempt(failure, now.Add(time.Minute), limits) || failure.Failures != limits.MaxFailures {
+		t.Fatalf("attempt during lock claimed, failures = %d", failure.Failures)
+	}
+	if !claimAttempt(failure, failure.LockedUntil.Add(time.Second), limits) || failure.Failures != 1 {
+		t.Fatalf("attempt after lock: failures = %d, want 1", failure.Failures)
+	}
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
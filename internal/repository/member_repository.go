@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const memberColumns = `
+	m.id, m.company_id, m.user_id, u.name, u.email, m.role, m.invited_by, m.created_at, m.updated_at`
+
+// scanMember lee una fila con las columnas de memberColumns
+func scanMember(scanner interface{ Scan(...any) error }) (*domain.Member, error) {
+	member := &domain.Member{}
+	err := scanner.Scan(
+		&member.ID,
+		&member.CompanyID,
+		&member.UserID,
+		&member.Name,
+		&member.Email,
+		&member.Role,
+		&member.InvitedBy,
+		&member.CreatedAt,
+		&member.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+const invitationColumns = `
+	i.id, i.company_id, c.name, i.email, i.role, i.invited_by, i.expires_at,
+	i.accepted_at, i.accepted_by, i.declined_at, i.revoked_at, i.created_at`
+
+// scanInvitation lee una fila con las columnas de invitationColumns
+func scanInvitation(scanner interface{ Scan(...any) error }) (*domain.Invitation, error) {
+	invitation := &domain.Invitation{}
+	err := scanner.Scan(
+		&invitation.ID,
+		&invitation.CompanyID,
+		&invitation.CompanyName,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.AcceptedAt,
+		&invitation.AcceptedBy,
+		&invitation.DeclinedAt,
+		&invitation.RevokedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// invitationPending filtra las invitaciones sin responder ni revocar
+const invitationPending = `i.accepted_at IS NULL AND i.declined_at IS NULL AND i.revoked_at IS NULL`
+
+type MemberRepository struct {
+	db *database.Database
+}
+
+func NewMemberRepository(db *database.Database) *MemberRepository {
+	return &MemberRepository{db: db}
+}
+
+// GetByCompanyID lista los miembros de la empresa, del rol con más privilegios al de menos
+func (r *MemberRepository) GetByCompanyID(companyID int64) ([]domain.Member, error) {
+	query := `SELECT ` + memberColumns + `
+		FROM company_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.company_id = $1
+		ORDER BY CASE m.role
+			WHEN 'owner' THEN 1 WHEN 'admin' THEN 2 WHEN 'accountant' THEN 3 WHEN 'cashier' THEN 4 ELSE 5
+		END, u.name`
+
+	rows, err := r.db.DB.Query(query, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []domain.Member{}
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning member: %w", err)
+		}
+		members = append(members, *member)
+	}
+	return members, rows.Err()
+}
+
+// Get obtiene un miembro de la empresa
+func (r *MemberRepository) Get(companyID, userID int64) (*domain.Member, error) {
+	query := `SELECT ` + memberColumns + `
+		FROM company_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.company_id = $1 AND m.user_id = $2`
+
+	member, err := scanMember(r.db.DB.QueryRow(query, companyID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("member not found")
+		}
+		return nil, fmt.Errorf("error getting member: %w", err)
+	}
+	return member, nil
+}
+
+// UpdateRole cambia el rol de un miembro; el rol owner no se modifica aquí
+func (r *MemberRepository) UpdateRole(companyID, userID int64, role string) error {
+	result, err := r.db.DB.Exec(`
+		UPDATE company_members SET role = $3, updated_at = NOW()
+		WHERE company_id = $1 AND user_id = $2 AND role <> 'owner'
+	`, companyID, userID, role)
+	if err != nil {
+		return fmt.Errorf("error updating member: %w", err)
+	}
+	return requireAffected(result, "member not found")
+}
+
+// Remove retira a un miembro de la empresa; el owner no se puede retirar
+func (r *MemberRepository) Remove(companyID, userID int64) error {
+	result, err := r.db.DB.Exec(`
+		DELETE FROM company_members
+		WHERE company_id = $1 AND user_id = $2 AND role <> 'owner'
+	`, companyID, userID)
+	if err != nil {
+		return fmt.Errorf("error removing member: %w", err)
+	}
+	return requireAffected(result, "member not found")
+}
+
+// CreateInvitation registra una invitación. Las invitaciones vencidas del mismo email se
+// revocan antes para que no bloqueen la nueva.
+func (r *MemberRepository) CreateInvitation(invitation *domain.Invitation) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE company_invitations i SET revoked_at = NOW()
+		WHERE i.company_id = $1 AND LOWER(i.email) = LOWER($2) AND `+invitationPending+`
+			AND i.expires_at <= NOW()
+	`, invitation.CompanyID, invitation.Email)
+	if err != nil {
+		return fmt.Errorf("error revoking expired invitations: %w", err)
+	}
+
+	var pending bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM company_invitations i
+			WHERE i.company_id = $1 AND LOWER(i.email) = LOWER($2) AND `+invitationPending+`
+		)
+	`, invitation.CompanyID, invitation.Email).Scan(&pending)
+	if err != nil {
+		return fmt.Errorf("error checking pending invitations: %w", err)
+	}
+	if pending {
+		return errors.New("invitation already pending")
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO company_invitations (company_id, email, role, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`,
+		invitation.CompanyID,
+		invitation.Email,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating invitation: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetPendingByCompanyID lista las invitaciones pendientes de la empresa (incluye vencidas)
+func (r *MemberRepository) GetPendingByCompanyID(companyID int64) ([]domain.Invitation, error) {
+	return r.queryInvitations(`
+		WHERE i.company_id = $1 AND `+invitationPending+`
+		ORDER BY i.created_at DESC`, companyID)
+}
+
+// GetPendingByEmail lista las invitaciones vigentes dirigidas al email
+func (r *MemberRepository) GetPendingByEmail(email string) ([]domain.Invitation, error) {
+	return r.queryInvitations(`
+		WHERE LOWER(i.email) = LOWER($1) AND `+invitationPending+` AND i.expires_at > NOW()
+			AND c.is_active = true
+		ORDER BY i.created_at DESC`, email)
+}
+
+func (r *MemberRepository) queryInvitations(where string, args ...any) ([]domain.Invitation, error) {
+	query := `SELECT ` + invitationColumns + `
+		FROM company_invitations i
+		JOIN companies c ON c.id = i.company_id
+		` + where
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []domain.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation: %w", err)
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// Accept acepta una invitación vigente dirigida al email y agrega al usuario como miembro
+// en la misma transacción
+func (r *MemberRepository) Accept(invitationID, userID int64, email string) (*domain.Invitation, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	invitation, err := scanInvitation(tx.QueryRow(`SELECT `+invitationColumns+`
+		FROM company_invitations i
+		JOIN companies c ON c.id = i.company_id
+		WHERE i.id = $1 AND LOWER(i.email) = LOWER($2) AND `+invitationPending+`
+			AND i.expires_at > NOW() AND c.is_active = true
+		FOR UPDATE OF i`, invitationID, email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, fmt.Errorf("error getting invitation: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO company_members (company_id, user_id, role, invited_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (company_id, user_id) DO NOTHING
+	`, invitation.CompanyID, userID, invitation.Role, invitation.InvitedBy)
+	if err != nil {
+		return nil, fmt.Errorf("error adding member: %w", err)
+	}
+	if err := requireAffected(result, "user is already a member"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		UPDATE company_invitations SET accepted_at = $2, accepted_by = $3 WHERE id = $1
+	`, invitation.ID, now, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+	invitation.AcceptedAt = &now
+	invitation.AcceptedBy = &userID
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return invitation, nil
+}
+
+// Decline rechaza una invitación vigente dirigida al email
+func (r *MemberRepository) Decline(invitationID int64, email string) error {
+	result, err := r.db.DB.Exec(`
+		UPDATE company_invitations i SET declined_at = NOW()
+		WHERE i.id = $1 AND LOWER(i.email) = LOWER($2) AND `+invitationPending+`
+			AND i.expires_at > NOW()
+	`, invitationID, email)
+	if err != nil {
+		return fmt.Errorf("error declining invitation: %w", err)
+	}
+	return requireAffected(result, "invitation not found")
+}
+
+// RevokeInvitation revoca una invitación pendiente de la empresa
+func (r *MemberRepository) RevokeInvitation(invitationID, companyID int64) error {
+	result, err := r.db.DB.Exec(`
+		UPDATE company_invitations i SET revoked_at = NOW()
+		WHERE i.id = $1 AND i.company_id = $2 AND `+invitationPending+`
+	`, invitationID, companyID)
+	if err != nil {
+		return fmt.Errorf("error revoking invitation: %w", err)
+	}
+	return requireAffected(result, "invitation not found")
+}
+
+// requireAffected retorna notFound si la sentencia no modificó filas
+func requireAffected(result sql.Result, notFound string) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New(notFound)
+	}
+	return nil
+}
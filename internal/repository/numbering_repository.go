@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+)
+
+const numberRecordColumns = `
+	id, resolution_id, consecutive, number, status, reason, notes, document_id, user_id,
+	annulment_reason, annulled_by, annulled_at, created_at, updated_at`
+
+// scanNumberRecord lee una fila con las columnas de numberRecordColumns
+func scanNumberRecord(scanner interface{ Scan(...any) error }) (*domain.NumberRecord, error) {
+	record := &domain.NumberRecord{}
+	err := scanner.Scan(
+		&record.ID,
+		&record.ResolutionID,
+		&record.Consecutive,
+		&record.Number,
+		&record.Status,
+		&record.Reason,
+		&record.Notes,
+		&record.DocumentID,
+		&record.UserID,
+		&record.AnnulmentReason,
+		&record.AnnulledBy,
+		&record.AnnulledAt,
+		&record.CreatedAt,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// recordNumber registra el consecutivo asignado a un documento dentro de la transacción que lo crea
+func recordNumber(tx *sql.Tx, invoice *domain.Invoice, origin domain.NumberOrigin) error {
+	reason := origin.Reason
+	if reason == "" {
+		reason = domain.NumberReasonInvoice
+	}
+	var userID *int64
+	if origin.UserID != 0 {
+		userID = &origin.UserID
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO resolution_numbers (
+			resolution_id, consecutive, number, status, reason, notes, document_id, user_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		invoice.ResolutionID,
+		invoice.Consecutive,
+		invoice.Number,
+		domain.NumberStatusIssued,
+		reason,
+		origin.Notes,
+		invoice.ID,
+		userID,
+	)
+	return err
+}
+
+// NumberedDocument es el estado de un documento que ocupa un consecutivo
+type NumberedDocument struct {
+	ID          int64
+	Consecutive int64
+	Status      string
+}
+
+type NumberingRepository struct {
+	db *database.Database
+}
+
+func NewNumberingRepository(db *database.Database) *NumberingRepository {
+	return &NumberingRepository{db: db}
+}
+
+// ListRecords obtiene los consecutivos registrados de una resolución ordenados por consecutivo
+func (r *NumberingRepository) ListRecords(resolutionID int64) ([]domain.NumberRecord, error) {
+	query := `SELECT ` + numberRecordColumns + ` FROM resolution_numbers WHERE resolution_id = $1 ORDER BY consecutive`
+
+	rows, err := r.db.DB.Query(query, resolutionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []domain.NumberRecord
+	for rows.Next() {
+		record, err := scanNumberRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// ListDocuments obtiene los documentos numerados con la resolución (id, consecutivo y estado)
+func (r *NumberingRepository) ListDocuments(resolutionID int64) ([]NumberedDocument, error) {
+	query := `
+		SELECT id, consecutive, status
+		FROM documents
+		WHERE resolution_id = $1 AND consecutive IS NOT NULL
+		ORDER BY consecutive
+	`
+
+	rows, err := r.db.DB.Query(query, resolutionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []NumberedDocument
+	for rows.Next() {
+		var doc NumberedDocument
+		if err := rows.Scan(&doc.ID, &doc.Consecutive, &doc.Status); err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+	return documents, rows.Err()
+}
+
+// Annul registra la anulación de un consecutivo saltado: crea el registro si el número no
+// tenía ninguno o anula el de un documento eliminado. Falla si el número tiene un documento.
+func (r *NumberingRepository) Annul(resolutionID, consecutive int64, number, reason string, userID int64) (*domain.NumberRecord, error) {
+	query := `
+		INSERT INTO resolution_numbers (
+			resolution_id, consecutive, number, status, reason,
+			annulment_reason, annulled_by, annulled_at
+		)
+		SELECT $1, $2, $3, $4, $5, $6, $7, NOW()
+		WHERE NOT EXISTS (
+			SELECT 1 FROM documents WHERE resolution_id = $1 AND consecutive = $2
+		)
+		ON CONFLICT (resolution_id, consecutive) DO UPDATE SET
+			status = EXCLUDED.status,
+			annulment_reason = EXCLUDED.annulment_reason,
+			annulled_by = EXCLUDED.annulled_by,
+			annulled_at = EXCLUDED.annulled_at,
+			updated_at = NOW()
+		WHERE resolution_numbers.status <> $4
+			AND (resolution_numbers.status = $8 OR resolution_numbers.document_id IS NULL)
+		RETURNING ` + numberRecordColumns
+
+	record, err := scanNumberRecord(r.db.DB.QueryRow(query,
+		resolutionID,
+		consecutive,
+		number,
+		domain.NumberStatusAnnulled,
+		domain.NumberReasonUnknown,
+		reason,
+		userID,
+		domain.NumberStatusDeleted,
+	))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("consecutive is in use or already annulled")
+	}
+	return record, err
+}
@@ -0,0 +1,392 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PaymentRepository struct {
+	db *database.Database
+}
+
+func NewPaymentRepository(db *database.Database) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// Create registra un pago verificando en la misma transacción que no supere el saldo de la factura
+func (r *PaymentRepository) Create(payment *domain.Payment) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Bloquear la factura para serializar pagos concurrentes (el saldo descuenta anticipos)
+	var total float64
+	err = tx.QueryRow(
+		`SELECT total - prepaid_amount FROM documents WHERE id = $1 AND type_document_id = 1 FOR UPDATE`,
+		payment.DocumentID,
+	).Scan(&total)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("invoice not found")
+	}
+	if err != nil {
+		return fmt.Errorf("error locking invoice: %w", err)
+	}
+
+	var paid float64
+	err = tx.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM payments WHERE document_id = $1`, payment.DocumentID).Scan(&paid)
+	if err != nil {
+		return fmt.Errorf("error getting paid amount: %w", err)
+	}
+	if err := checkPaymentBalance(total, paid, payment.Amount); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO payments (
+			company_id, document_id, customer_id, payment_date, amount,
+			payment_method_id, reference, notes, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(
+		query,
+		payment.CompanyID,
+		payment.DocumentID,
+		payment.CustomerID,
+		payment.PaymentDate,
+		payment.Amount,
+		payment.PaymentMethodID,
+		payment.Reference,
+		payment.Notes,
+		payment.CreatedBy,
+	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating payment: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// checkPaymentBalance rechaza el pago que, sumado a lo ya pagado, supera el saldo a pagar
+// (total menos anticipos); tolera medio centavo de redondeo
+func checkPaymentBalance(payable, paid, amount float64) error {
+	if paid+amount > payable+0.005 {
+		return fmt.Errorf("payment exceeds invoice balance (%.2f)", payable-paid)
+	}
+	return nil
+}
+
+const paymentSelect = `
+	SELECT
+		p.id, p.company_id, p.document_id, p.customer_id, p.payment_date, p.amount,
+		p.payment_method_id, p.reference, p.notes, p.created_by, p.created_at, p.updated_at,
+		d.number, pm.name
+	FROM payments p
+	INNER JOIN documents d ON p.document_id = d.id
+	LEFT JOIN payment_methods pm ON p.payment_method_id = pm.id
+`
+
+func scanPayment(scanner interface{ Scan(...any) error }, p *domain.Payment) error {
+	return scanner.Scan(
+		&p.ID,
+		&p.CompanyID,
+		&p.DocumentID,
+		&p.CustomerID,
+		&p.PaymentDate,
+		&p.Amount,
+		&p.PaymentMethodID,
+		&p.Reference,
+		&p.Notes,
+		&p.CreatedBy,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.DocumentNumber,
+		&p.PaymentMethodName,
+	)
+}
+
+// GetByID obtiene un pago por ID
+func (r *PaymentRepository) GetByID(id int64) (*domain.Payment, error) {
+	payment := &domain.Payment{}
+	err := scanPayment(r.db.DB.QueryRow(paymentSelect+` WHERE p.id = $1`, id), payment)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("payment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting payment: %w", err)
+	}
+	return payment, nil
+}
+
+// GetByDocumentID obtiene los pagos de una factura en orden cronológico
+func (r *PaymentRepository) GetByDocumentID(documentID int64) ([]domain.Payment, error) {
+	rows, err := r.db.DB.Query(paymentSelect+` WHERE p.document_id = $1 ORDER BY p.payment_date, p.id`, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []domain.Payment{}
+	for rows.Next() {
+		var payment domain.Payment
+		if err := scanPayment(rows, &payment); err != nil {
+			return nil, fmt.Errorf("error scanning payment: %w", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+// GetByCompanyID obtiene los pagos de una empresa, opcionalmente filtrados por cliente
+func (r *PaymentRepository) GetByCompanyID(companyID, customerID int64, page, pageSize int) ([]domain.Payment, int, error) {
+	offset := (page - 1) * pageSize
+
+	where := ` WHERE p.company_id = $1 AND ($2 = 0 OR p.customer_id = $2)`
+
+	var total int
+	err := r.db.DB.QueryRow(`SELECT COUNT(*) FROM payments p`+where, companyID, customerID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error counting payments: %w", err)
+	}
+
+	rows, err := r.db.DB.Query(
+		paymentSelect+where+` ORDER BY p.payment_date DESC, p.id DESC LIMIT $3 OFFSET $4`,
+		companyID, customerID, pageSize, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := []domain.Payment{}
+	for rows.Next() {
+		var payment domain.Payment
+		if err := scanPayment(rows, &payment); err != nil {
+			return nil, 0, fmt.Errorf("error scanning payment: %w", err)
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, total, rows.Err()
+}
+
+// Delete elimina un pago (reversa de un abono registrado por error)
+func (r *PaymentRepository) Delete(id int64) error {
+	result, err := r.db.DB.Exec(`DELETE FROM payments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting payment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("payment not found")
+	}
+
+	return nil
+}
+
+const receivableSelect = `
+	SELECT
+		d.id, d.company_id, d.number, d.customer_id, c.name, c.identification_number,
+		d.status, d.issue_date, d.due_date, d.total, d.prepaid_amount,
+		COALESCE((
+			SELECT SUM(p.amount) FROM payments p
+			WHERE p.document_id = d.id AND p.payment_date <= $%d
+		), 0) AS amount_paid
+	FROM documents d
+	INNER JOIN customers c ON d.customer_id = c.id
+`
+
+func scanReceivable(scanner interface{ Scan(...any) error }, rec *domain.Receivable) error {
+	return scanner.Scan(
+		&rec.DocumentID,
+		&rec.CompanyID,
+		&rec.Number,
+		&rec.CustomerID,
+		&rec.CustomerName,
+		&rec.IdentificationNumber,
+		&rec.Status,
+		&rec.IssueDate,
+		&rec.DueDate,
+		&rec.Total,
+		&rec.PrepaidAmount,
+		&rec.AmountPaid,
+	)
+}
+
+// GetReceivable obtiene total y pagos de una factura a la fecha indicada
+func (r *PaymentRepository) GetReceivable(documentID int64, asOf time.Time) (*domain.Receivable, error) {
+	query := fmt.Sprintf(receivableSelect, 2) + ` WHERE d.id = $1 AND d.type_document_id = 1`
+
+	rec := &domain.Receivable{}
+	err := scanReceivable(r.db.DB.QueryRow(query, documentID, asOf), rec)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invoice not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting invoice balance: %w", err)
+	}
+	return rec, nil
+}
+
+// GetReceivables obtiene las facturas emitidas hasta asOf con saldo pendiente a esa fecha.
+// customerID = 0 incluye todos los clientes de la empresa.
+func (r *PaymentRepository) GetReceivables(companyID, customerID int64, asOf time.Time) ([]domain.Receivable, error) {
+	query := `SELECT * FROM (` + fmt.Sprintf(receivableSelect, 3) + `
+		WHERE d.company_id = $1 AND d.type_document_id = 1
+			AND ($2 = 0 OR d.customer_id = $2)
+			AND d.status = ANY($4)
+			AND d.issue_date <= $3
+	) r
+	WHERE r.total - r.prepaid_amount - r.amount_paid > 0.005
+	ORDER BY r.issue_date, r.id`
+
+	rows, err := r.db.DB.Query(query, companyID, customerID, asOf, pq.Array(domain.ReceivableStatuses))
+	if err != nil {
+		return nil, fmt.Errorf("error querying receivables: %w", err)
+	}
+	defer rows.Close()
+
+	receivables := []domain.Receivable{}
+	for rows.Next() {
+		var rec domain.Receivable
+		if err := scanReceivable(rows, &rec); err != nil {
+			return nil, fmt.Errorf("error scanning receivable: %w", err)
+		}
+		receivables = append(receivables, rec)
+	}
+
+	return receivables, rows.Err()
+}
+
+// GetOpeningBalance calcula el saldo del cliente antes de la fecha indicada
+func (r *PaymentRepository) GetOpeningBalance(companyID, customerID int64, before time.Time) (float64, error) {
+	query := `
+		SELECT
+			COALESCE((
+				SELECT SUM(d.total - d.prepaid_amount) FROM documents d
+				WHERE d.company_id = $1 AND d.customer_id = $2 AND d.type_document_id = 1
+					AND d.status = ANY($4) AND d.issue_date < $3
+			), 0)
+			-
+			COALESCE((
+				SELECT SUM(p.amount) FROM payments p
+				INNER JOIN documents d ON p.document_id = d.id
+				WHERE p.company_id = $1 AND p.customer_id = $2
+					AND d.status = ANY($4) AND p.payment_date < $3
+			), 0)
+	`
+
+	var balance float64
+	err := r.db.DB.QueryRow(query, companyID, customerID, before, pq.Array(domain.ReceivableStatuses)).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("error getting opening balance: %w", err)
+	}
+	return balance, nil
+}
+
+// GetStatementEntries obtiene facturas (débitos), anticipos y pagos (créditos) del cliente en el periodo
+func (r *PaymentRepository) GetStatementEntries(companyID, customerID int64, from, to time.Time) ([]domain.StatementEntry, error) {
+	query := `
+		SELECT date, type, number, reference, due_date, debit, credit FROM (
+			SELECT d.issue_date AS date, 'invoice' AS type, d.number, NULL::VARCHAR AS reference,
+				d.due_date, d.total AS debit, 0::NUMERIC AS credit, d.id AS sort_id
+			FROM documents d
+			WHERE d.company_id = $1 AND d.customer_id = $2 AND d.type_document_id = 1
+				AND d.status = ANY($5) AND d.issue_date BETWEEN $3 AND $4
+			UNION ALL
+			SELECT d.issue_date, 'prepayment', d.number, pp.reference,
+				NULL::DATE, 0::NUMERIC, pp.amount, pp.id
+			FROM document_prepayments pp
+			INNER JOIN documents d ON pp.document_id = d.id
+			WHERE d.company_id = $1 AND d.customer_id = $2 AND d.type_document_id = 1
+				AND d.status = ANY($5) AND d.issue_date BETWEEN $3 AND $4
+			UNION ALL
+			SELECT p.payment_date, 'payment', d.number, p.reference,
+				NULL::DATE, 0::NUMERIC, p.amount, p.id
+			FROM payments p
+			INNER JOIN documents d ON p.document_id = d.id
+			WHERE p.company_id = $1 AND p.customer_id = $2
+				AND d.status = ANY($5) AND p.payment_date BETWEEN $3 AND $4
+		) e
+		ORDER BY date, CASE type WHEN 'invoice' THEN 0 WHEN 'prepayment' THEN 1 ELSE 2 END, sort_id
+	`
+
+	rows, err := r.db.DB.Query(query, companyID, customerID, from, to, pq.Array(domain.ReceivableStatuses))
+	if err != nil {
+		return nil, fmt.Errorf("error querying statement entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.StatementEntry{}
+	for rows.Next() {
+		var entry domain.StatementEntry
+		err := rows.Scan(
+			&entry.Date,
+			&entry.Type,
+			&entry.Number,
+			&entry.Reference,
+			&entry.DueDate,
+			&entry.Debit,
+			&entry.Credit,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning statement entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetInstallments obtiene las cuotas de varias facturas agrupadas por documento
+func (r *PaymentRepository) GetInstallments(documentIDs []int64) (map[int64][]domain.InvoiceInstallment, error) {
+	installments := map[int64][]domain.InvoiceInstallment{}
+	if len(documentIDs) == 0 {
+		return installments, nil
+	}
+
+	query := `
+		SELECT id, document_id, installment_number, due_date, amount, payment_method_id, created_at
+		FROM document_installments
+		WHERE document_id = ANY($1)
+		ORDER BY document_id, installment_number
+	`
+
+	rows, err := r.db.DB.Query(query, pq.Array(documentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying installments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var installment domain.InvoiceInstallment
+		err := rows.Scan(
+			&installment.ID,
+			&installment.DocumentID,
+			&installment.InstallmentNumber,
+			&installment.DueDate,
+			&installment.Amount,
+			&installment.PaymentMethodID,
+			&installment.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning installment: %w", err)
+		}
+		installments[installment.DocumentID] = append(installments[installment.DocumentID], installment)
+	}
+
+	return installments, rows.Err()
+}
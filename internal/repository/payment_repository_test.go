@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPaymentBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		payable float64 // total menos anticipos
+		paid    float64
+		amount  float64
+		wantErr string
+	}{
+		{name: "partial payment", payable: 1190000, amount: 500000},
+		{name: "pays the balance exactly", payable: 1190000, paid: 690000, amount: 500000},
+		{name: "rounding within half a cent", payable: 100.10, paid: 33.37, amount: 66.735},
+		{name: "overpayment", payable: 1190000, paid: 690000, amount: 500000.01, wantErr: "payment exceeds invoice balance (500000.00)"},
+		{name: "already paid", payable: 1190000, paid: 1190000, amount: 1, wantErr: "payment exceeds invoice balance (0.00)"},
+		{name: "prepayment covers the invoice", payable: 0, amount: 10, wantErr: "payment exceeds invoice balance (0.00)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPaymentBalance(tt.payable, tt.paid, tt.amount)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
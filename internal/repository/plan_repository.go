@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDocumentQuotaExceeded indica que la empresa ya creó los documentos del mes que permite su plan
+var ErrDocumentQuotaExceeded = errors.New("monthly document quota exceeded")
+
+type PlanRepository struct {
+	db *database.Database
+}
+
+func NewPlanRepository(db *database.Database) *PlanRepository {
+	return &PlanRepository{db: db}
+}
+
+// GetAll lista los planes, del más limitado al ilimitado
+func (r *PlanRepository) GetAll() ([]domain.Plan, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT code, name, documents_per_month, api_calls_per_minute
+		FROM plans
+		ORDER BY documents_per_month NULLS LAST, code
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []domain.Plan{}
+	for rows.Next() {
+		var plan domain.Plan
+		if err := rows.Scan(&plan.Code, &plan.Name, &plan.DocumentsPerMonth, &plan.APICallsPerMinute); err != nil {
+			return nil, fmt.Errorf("error scanning plan: %w", err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// GetByCode obtiene un plan por su código
+func (r *PlanRepository) GetByCode(code string) (*domain.Plan, error) {
+	plan := &domain.Plan{}
+	err := r.db.DB.QueryRow(`
+		SELECT code, name, documents_per_month, api_calls_per_minute FROM plans WHERE code = $1
+	`, code).Scan(&plan.Code, &plan.Name, &plan.DocumentsPerMonth, &plan.APICallsPerMinute)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("plan not found")
+		}
+		return nil, fmt.Errorf("error getting plan: %w", err)
+	}
+	return plan, nil
+}
+
+// GetUsageHistory retorna el plan de la empresa y su uso de los últimos months meses
+func (r *PlanRepository) GetUsageHistory(companyID int64, months int) (*domain.CompanyUsageHistory, error) {
+	history := &domain.CompanyUsageHistory{CompanyID: companyID, Periods: []domain.UsagePeriod{}}
+	err := r.db.DB.QueryRow(`
+		SELECT p.code, p.name, p.documents_per_month, p.api_calls_per_minute
+		FROM companies c
+		JOIN plans p ON p.code = c.plan
+		WHERE c.id = $1 AND c.is_active = true
+	`, companyID).Scan(
+		&history.Plan.Code,
+		&history.Plan.Name,
+		&history.Plan.DocumentsPerMonth,
+		&history.Plan.APICallsPerMinute,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("company not found")
+		}
+		return nil, fmt.Errorf("error getting company plan: %w", err)
+	}
+
+	since := domain.UsagePeriodStart(time.Now()).AddDate(0, -(months - 1), 0)
+	rows, err := r.db.DB.Query(`
+		SELECT period, documents, api_calls, updated_at
+		FROM company_usage
+		WHERE company_id = $1 AND period >= $2
+		ORDER BY period DESC
+	`, companyID, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("error querying company usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var period time.Time
+		var usage domain.UsagePeriod
+		if err := rows.Scan(&period, &usage.Documents, &usage.APICalls, &usage.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning company usage: %w", err)
+		}
+		usage.Period = period.Format("2006-01")
+		history.Periods = append(history.Periods, usage)
+	}
+	return history, rows.Err()
+}
+
+// consumeDocumentQuota cuenta un documento nuevo en el uso del mes, dentro de la transacción que
+// lo crea (si el INSERT falla el documento no se cuenta). Retorna ErrDocumentQuotaExceeded si la
+// empresa ya llegó a la cuota de su plan; la fila de uso queda bloqueada hasta el commit, así
+// dos creaciones simultáneas no pasan la cuota.
+func consumeDocumentQuota(tx *sql.Tx, companyID int64, now time.Time) error {
+	var documents int
+	err := tx.QueryRow(`
+		INSERT INTO company_usage (company_id, period, documents)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (company_id, period) DO UPDATE
+			SET documents = company_usage.documents + 1, updated_at = NOW()
+			WHERE company_usage.documents < COALESCE(
+				(SELECT p.documents_per_month FROM companies c JOIN plans p ON p.code = c.plan WHERE c.id = $1),
+				2147483647
+			)
+		RETURNING documents
+	`, companyID, domain.UsagePeriodStart(now).Format("2006-01-02")).Scan(&documents)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrDocumentQuotaExceeded
+		}
+		return fmt.Errorf("error updating document usage: %w", err)
+	}
+	return nil
+}
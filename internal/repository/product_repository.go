@@ -10,13 +10,19 @@ import (
 )
 
 type ProductRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // Usuario al que se atribuyen las escrituras en audit_log; 0 para procesos del sistema
 }
 
 func NewProductRepository(db *database.Database) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
+// As retorna una copia del repositorio cuyas escrituras quedan en audit_log a nombre del usuario
+func (r *ProductRepository) As(userID int64) *ProductRepository {
+	return &ProductRepository{db: r.db, actorID: userID}
+}
+
 // Create crea un nuevo producto
 func (r *ProductRepository) Create(userID int64, req *domain.CreateProductRequest) (*domain.Product, error) {
 	query := `
@@ -46,7 +52,7 @@ func (r *ProductRepository) Create(userID int64, req *domain.CreateProductReques
 		ModelName:                req.ModelName,
 	}
 
-	err := r.db.DB.QueryRow(
+	err := r.db.QueryRowAs(userID,
 		query,
 		req.CompanyID,
 		req.Code,
@@ -197,7 +203,8 @@ func (r *ProductRepository) GetByUserID(userID int64, page, pageSize int) ([]dom
 		SELECT COUNT(*) 
 		FROM products p
 		INNER JOIN companies c ON p.company_id = c.id
-		WHERE c.user_id = $1 AND p.is_active = true AND c.is_active = true
+		INNER JOIN company_members m ON m.company_id = c.id
+		WHERE m.user_id = $1 AND p.is_active = true AND c.is_active = true
 	`
 	err := r.db.DB.QueryRow(countQuery, userID).Scan(&total)
 	if err != nil {
@@ -212,7 +219,8 @@ func (r *ProductRepository) GetByUserID(userID int64, page, pageSize int) ([]dom
 			p.tax_rate, p.brand_name, p.model_name, p.is_active, p.created_at, p.updated_at
 		FROM products p
 		INNER JOIN companies c ON p.company_id = c.id
-		WHERE c.user_id = $1 AND p.is_active = true AND c.is_active = true
+		INNER JOIN company_members m ON m.company_id = c.id
+		WHERE m.user_id = $1 AND p.is_active = true AND c.is_active = true
 		ORDER BY p.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
@@ -274,7 +282,7 @@ func (r *ProductRepository) Update(id int64, req *domain.UpdateProductRequest) e
 		WHERE id = $13
 	`
 
-	result, err := r.db.DB.Exec(
+	result, err := r.db.ExecAs(r.actorID,
 		query,
 		req.Name,
 		req.Description,
@@ -311,7 +319,7 @@ func (r *ProductRepository) Update(id int64, req *domain.UpdateProductRequest) e
 func (r *ProductRepository) Delete(id int64) error {
 	query := `UPDATE products SET is_active = false, updated_at = NOW() WHERE id = $1`
 
-	result, err := r.db.DB.Exec(query, id)
+	result, err := r.db.ExecAs(r.actorID, query, id)
 	if err != nil {
 		return fmt.Errorf("error deleting product: %w", err)
 	}
@@ -369,3 +377,52 @@ func (r *ProductRepository) GetByCode(companyID int64, code string) (*domain.Pro
 
 	return product, nil
 }
+
+// GetAllByCompanyID obtiene todos los productos activos de una empresa sin paginación (exportación)
+func (r *ProductRepository) GetAllByCompanyID(companyID int64) ([]domain.Product, error) {
+	query := `
+		SELECT
+			id, company_id, code, name, description, type_item_identification_id,
+			standard_item_code, unspsc_code, unit_code_id, price, tax_type_id,
+			tax_rate, brand_name, model_name, is_active, created_at, updated_at
+		FROM products
+		WHERE company_id = $1 AND is_active = true
+		ORDER BY code
+	`
+
+	rows, err := r.db.DB.Query(query, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		var product domain.Product
+		err := rows.Scan(
+			&product.ID,
+			&product.CompanyID,
+			&product.Code,
+			&product.Name,
+			&product.Description,
+			&product.TypeItemIdentificationID,
+			&product.StandardItemCode,
+			&product.UNSPSCCode,
+			&product.UnitCodeID,
+			&product.Price,
+			&product.TaxTypeID,
+			&product.TaxRate,
+			&product.BrandName,
+			&product.ModelName,
+			&product.IsActive,
+			&product.CreatedAt,
+			&product.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning product: %w", err)
+		}
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
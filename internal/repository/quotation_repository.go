@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrQuotationConverted indica que la cotización ya se convirtió en factura
+var ErrQuotationConverted = errors.New("quotation already converted")
+
+type QuotationRepository struct {
+	db *database.Database
+}
+
+func NewQuotationRepository(db *database.Database) *QuotationRepository {
+	return &QuotationRepository{db: db}
+}
+
+// Create crea una cotización con sus líneas asignando el siguiente consecutivo de la empresa
+func (r *QuotationRepository) Create(quotation *domain.Quotation, lines []domain.QuotationLine) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Numeración propia por empresa y tipo (no consume consecutivos DIAN). El contador se
+	// incrementa con bloqueo de fila, así los números no se repiten aunque se elimine el último borrador
+	err = tx.QueryRow(`
+		INSERT INTO quotation_counters (company_id, kind, last_consecutive)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (company_id, kind)
+		DO UPDATE SET last_consecutive = quotation_counters.last_consecutive + 1
+		RETURNING last_consecutive
+	`, quotation.CompanyID, quotation.Kind).Scan(&quotation.Consecutive)
+	if err != nil {
+		return fmt.Errorf("error getting quotation consecutive: %w", err)
+	}
+	quotation.Number = fmt.Sprintf("%s%d", quotation.Prefix, quotation.Consecutive)
+
+	query := `
+		INSERT INTO quotations (
+			company_id, customer_id, kind, prefix, number, consecutive,
+			issue_date, expires_at, currency_code_id, notes,
+			payment_method_id, payment_form_id,
+			subtotal, tax_total, total, status,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(
+		query,
+		quotation.CompanyID,
+		quotation.CustomerID,
+		quotation.Kind,
+		quotation.Prefix,
+		quotation.Number,
+		quotation.Consecutive,
+		quotation.IssueDate,
+		quotation.ExpiresAt,
+		quotation.CurrencyCodeID,
+		quotation.Notes,
+		quotation.PaymentMethodID,
+		quotation.PaymentFormID,
+		quotation.Subtotal,
+		quotation.TaxTotal,
+		quotation.Total,
+		quotation.Status,
+	).Scan(&quotation.ID, &quotation.CreatedAt, &quotation.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating quotation: %w", err)
+	}
+
+	// Insertar líneas
+	for i, line := range lines {
+		lineQuery := `
+			INSERT INTO quotation_lines (
+				quotation_id, product_id, line_number, description,
+				quantity, unit_price, line_total, tax_rate, tax_amount,
+				brand_name, model_name, standard_item_code, classification_code,
+				created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
+			RETURNING id, created_at
+		`
+
+		err = tx.QueryRow(
+			lineQuery,
+			quotation.ID,
+			line.ProductID,
+			i+1,
+			line.Description,
+			line.Quantity,
+			line.UnitPrice,
+			line.LineTotal,
+			line.TaxRate,
+			line.TaxAmount,
+			line.BrandName,
+			line.ModelName,
+			line.StandardItemCode,
+			line.ClassificationCode,
+		).Scan(&lines[i].ID, &lines[i].CreatedAt)
+		if err != nil {
+			return fmt.Errorf("error creating quotation line %d: %w", i+1, err)
+		}
+		lines[i].QuotationID = quotation.ID
+		lines[i].LineNumber = int64(i + 1)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	quotation.Lines = lines
+	return nil
+}
+
+// GetByID obtiene una cotización por ID con emisor, cliente y líneas (JOINs para PDF)
+func (r *QuotationRepository) GetByID(id int64) (*domain.Quotation, error) {
+	query := `
+		SELECT
+			-- Cotización base
+			q.id, q.company_id, q.customer_id, q.kind, q.prefix, q.number, q.consecutive,
+			q.issue_date, q.expires_at, q.currency_code_id, q.notes,
+			q.payment_method_id, q.payment_form_id,
+			q.subtotal, q.tax_total, q.total, q.status,
+			q.responded_at, q.converted_invoice_id, q.converted_at,
+			q.created_at, q.updated_at,
+			pm.name AS payment_method_name,
+			pf.name AS payment_form_name,
+
+			-- Company (Emisor)
+			c.id, c.nit, c.dv, c.name, c.trade_name, c.registration_name,
+			tlc_c.name AS company_tax_level_name,
+			tr_c.name AS company_type_regime_name,
+			c.address_line, c.phone, c.email, c.logo_path,
+			mun_c.name AS company_municipality,
+			dep_c.name AS company_department,
+			country_c.name AS company_country_name,
+
+			-- Customer (Adquiriente)
+			cust.id, cust.identification_number, cust.dv, cust.name, cust.trade_name,
+			tlc_cust.name AS customer_tax_level_name,
+			tr_cust.name AS customer_type_regime_name,
+			cust.address_line, cust.phone, cust.email,
+			mun_cust.name AS customer_municipality,
+			dep_cust.name AS customer_department,
+			country_cust.name AS customer_country_name
+
+		FROM quotations q
+
+		-- JOINs EMISOR
+		INNER JOIN companies c ON q.company_id = c.id
+		INNER JOIN tax_level_codes tlc_c ON c.tax_level_code_id = tlc_c.id
+		INNER JOIN regime_types tr_c ON c.type_regime_id = tr_c.id
+		INNER JOIN municipalities mun_c ON c.municipality_id = mun_c.id
+		INNER JOIN departments dep_c ON c.department_id = dep_c.id
+		INNER JOIN countries country_c ON c.country_id = country_c.id
+
+		-- JOINs ADQUIRIENTE
+		INNER JOIN customers cust ON q.customer_id = cust.id
+		INNER JOIN tax_level_codes tlc_cust ON cust.tax_level_code_id = tlc_cust.id
+		INNER JOIN regime_types tr_cust ON cust.type_regime_id = tr_cust.id
+		INNER JOIN municipalities mun_cust ON cust.municipality_id = mun_cust.id
+		INNER JOIN departments dep_cust ON cust.department_id = dep_cust.id
+		INNER JOIN countries country_cust ON cust.country_id = country_cust.id
+
+		-- JOINs CÓDIGOS DIAN
+		LEFT JOIN payment_methods pm ON q.payment_method_id = pm.id
+		LEFT JOIN payment_forms pf ON q.payment_form_id = pf.id
+
+		WHERE q.id = $1
+	`
+
+	quotation := &domain.Quotation{}
+	company := &domain.CompanyDetail{}
+	customer := &domain.CustomerDetail{}
+
+	err := r.db.DB.QueryRow(query, id).Scan(
+		&quotation.ID,
+		&quotation.CompanyID,
+		&quotation.CustomerID,
+		&quotation.Kind,
+		&quotation.Prefix,
+		&quotation.Number,
+		&quotation.Consecutive,
+		&quotation.IssueDate,
+		&quotation.ExpiresAt,
+		&quotation.CurrencyCodeID,
+		&quotation.Notes,
+		&quotation.PaymentMethodID,
+		&quotation.PaymentFormID,
+		&quotation.Subtotal,
+		&quotation.TaxTotal,
+		&quotation.Total,
+		&quotation.Status,
+		&quotation.RespondedAt,
+		&quotation.ConvertedInvoiceID,
+		&quotation.ConvertedAt,
+		&quotation.CreatedAt,
+		&quotation.UpdatedAt,
+		&quotation.PaymentMethodName,
+		&quotation.PaymentFormName,
+
+		// Company
+		&company.ID,
+		&company.NIT,
+		&company.DV,
+		&company.Name,
+		&company.TradeName,
+		&company.RegistrationName,
+		&company.TaxLevelName,
+		&company.TypeRegimeName,
+		&company.AddressLine,
+		&company.Phone,
+		&company.Email,
+		&company.LogoPath,
+		&company.Municipality,
+		&company.Department,
+		&company.CountryName,
+
+		// Customer
+		&customer.ID,
+		&customer.IdentificationNumber,
+		&customer.DV,
+		&customer.Name,
+		&customer.TradeName,
+		&customer.TaxLevelName,
+		&customer.TypeRegimeName,
+		&customer.AddressLine,
+		&customer.Phone,
+		&customer.Email,
+		&customer.Municipality,
+		&customer.Department,
+		&customer.CountryName,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("quotation not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting quotation: %w", err)
+	}
+
+	quotation.Company = company
+	quotation.Customer = customer
+
+	lines, err := r.GetLinesByQuotationID(quotation.ID)
+	if err != nil {
+		return nil, err
+	}
+	quotation.Lines = lines
+
+	return quotation, nil
+}
+
+// GetLinesByQuotationID obtiene las líneas de una cotización con datos del producto
+func (r *QuotationRepository) GetLinesByQuotationID(quotationID int64) ([]domain.QuotationLine, error) {
+	query := `
+		SELECT
+			ql.id, ql.quotation_id, ql.product_id, ql.line_number, ql.description,
+			ql.quantity, ql.unit_price, ql.line_total, ql.tax_rate, ql.tax_amount,
+			ql.brand_name, ql.model_name, ql.standard_item_code, ql.classification_code,
+			ql.created_at,
+			p.code AS product_code,
+			p.name AS product_name,
+			uc.code AS unit_code,
+			uc.name AS unit_name
+		FROM quotation_lines ql
+		INNER JOIN products p ON ql.product_id = p.id
+		INNER JOIN unit_codes uc ON p.unit_code_id = uc.id
+		WHERE ql.quotation_id = $1
+		ORDER BY ql.line_number ASC
+	`
+
+	rows, err := r.db.DB.Query(query, quotationID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying quotation lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []domain.QuotationLine
+	for rows.Next() {
+		var line domain.QuotationLine
+		err := rows.Scan(
+			&line.ID,
+			&line.QuotationID,
+			&line.ProductID,
+			&line.LineNumber,
+			&line.Description,
+			&line.Quantity,
+			&line.UnitPrice,
+			&line.LineTotal,
+			&line.TaxRate,
+			&line.TaxAmount,
+			&line.BrandName,
+			&line.ModelName,
+			&line.StandardItemCode,
+			&line.ClassificationCode,
+			&line.CreatedAt,
+			&line.ProductCode,
+			&line.ProductName,
+			&line.UnitCode,
+			&line.UnitName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning quotation line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// GetByCompanyID obtiene las cotizaciones de una empresa, opcionalmente filtradas por tipo y estado
+func (r *QuotationRepository) GetByCompanyID(companyID int64, kind, status string, page, pageSize int) ([]domain.Quotation, int, error) {
+	offset := (page - 1) * pageSize
+
+	// Contar total
+	var total int
+	countQuery := `
+		SELECT COUNT(*) FROM quotations
+		WHERE company_id = $1 AND ($2 = '' OR kind = $2) AND ($3 = '' OR status = $3)
+	`
+	if err := r.db.DB.QueryRow(countQuery, companyID, kind, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting quotations: %w", err)
+	}
+
+	query := `
+		SELECT
+			id, company_id, customer_id, kind, prefix, number, consecutive,
+			issue_date, expires_at, currency_code_id, notes,
+			payment_method_id, payment_form_id,
+			subtotal, tax_total, total, status,
+			responded_at, converted_invoice_id, converted_at,
+			created_at, updated_at
+		FROM quotations
+		WHERE company_id = $1 AND ($2 = '' OR kind = $2) AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.DB.Query(query, companyID, kind, status, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying quotations: %w", err)
+	}
+	defer rows.Close()
+
+	quotations := []domain.Quotation{}
+	for rows.Next() {
+		var q domain.Quotation
+		err := rows.Scan(
+			&q.ID,
+			&q.CompanyID,
+			&q.CustomerID,
+			&q.Kind,
+			&q.Prefix,
+			&q.Number,
+			&q.Consecutive,
+			&q.IssueDate,
+			&q.ExpiresAt,
+			&q.CurrencyCodeID,
+			&q.Notes,
+			&q.PaymentMethodID,
+			&q.PaymentFormID,
+			&q.Subtotal,
+			&q.TaxTotal,
+			&q.Total,
+			&q.Status,
+			&q.RespondedAt,
+			&q.ConvertedInvoiceID,
+			&q.ConvertedAt,
+			&q.CreatedAt,
+			&q.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning quotation: %w", err)
+		}
+		quotations = append(quotations, q)
+	}
+
+	return quotations, total, nil
+}
+
+// UpdateStatus actualiza el estado de una cotización (sent, accepted, rejected)
+func (r *QuotationRepository) UpdateStatus(id int64, status string) error {
+	query := `
+		UPDATE quotations
+		SET
+			status = $1,
+			responded_at = CASE WHEN $1 IN ('accepted', 'rejected') THEN NOW() ELSE responded_at END,
+			updated_at = NOW()
+		WHERE id = $2 AND status NOT IN ('converted', 'expired')
+	`
+
+	result, err := r.db.DB.Exec(query, status, id)
+	if err != nil {
+		return fmt.Errorf("error updating quotation status: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("quotation not found")
+	}
+
+	return nil
+}
+
+// claimQuotation bloquea la cotización que se convierte dentro de la transacción que crea la
+// factura, así dos conversiones simultáneas no crean dos facturas ni consumen dos consecutivos
+func claimQuotation(tx *sql.Tx, id int64) error {
+	var convertedInvoiceID sql.NullInt64
+	err := tx.QueryRow(`SELECT converted_invoice_id FROM quotations WHERE id = $1 FOR UPDATE`, id).Scan(&convertedInvoiceID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("quotation not found")
+	}
+	if err != nil {
+		return fmt.Errorf("error locking quotation: %w", err)
+	}
+	if convertedInvoiceID.Valid {
+		return ErrQuotationConverted
+	}
+	return nil
+}
+
+// markQuotationConverted enlaza la cotización reclamada con la factura generada en la misma transacción
+func markQuotationConverted(tx *sql.Tx, id int64, invoiceID int64) error {
+	query := `
+		UPDATE quotations
+		SET status = 'converted', converted_invoice_id = $1, converted_at = NOW(), updated_at = NOW()
+		WHERE id = $2 AND converted_invoice_id IS NULL
+	`
+
+	result, err := tx.Exec(query, invoiceID, id)
+	if err != nil {
+		return fmt.Errorf("error marking quotation as converted: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrQuotationConverted
+	}
+
+	return nil
+}
+
+// ExpireOverdue marca como vencidas las cotizaciones abiertas cuya fecha de expiración ya pasó
+func (r *QuotationRepository) ExpireOverdue(companyID int64) error {
+	query := `
+		UPDATE quotations
+		SET status = 'expired', updated_at = NOW()
+		WHERE company_id = $1
+		  AND status IN ('draft', 'sent')
+		  AND expires_at IS NOT NULL
+		  AND expires_at < date_trunc('day', NOW())
+	`
+
+	if _, err := r.db.DB.Exec(query, companyID); err != nil {
+		return fmt.Errorf("error expiring quotations: %w", err)
+	}
+	return nil
+}
+
+// Delete elimina una cotización (solo si está en draft)
+func (r *QuotationRepository) Delete(id int64) error {
+	query := `DELETE FROM quotations WHERE id = $1 AND status = 'draft'`
+
+	result, err := r.db.DB.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("error deleting quotation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("quotation not found or cannot be deleted (only draft quotations can be deleted)")
+	}
+
+	return nil
+}
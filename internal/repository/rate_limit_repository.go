@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RateLimitRepository lleva los contadores de peticiones por ventana de un minuto en Postgres,
+// compartidos entre réplicas. Cada petición es un solo UPSERT atómico.
+type RateLimitRepository struct {
+	db *database.Database
+}
+
+func NewRateLimitRepository(db *database.Database) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Hit cuenta una petición en la ventana del bucket (ip:..., user:..., api_key:...) y retorna
+// las peticiones acumuladas en ella
+func (r *RateLimitRepository) Hit(bucket string, window time.Time) (int, error) {
+	var hits int
+	err := r.db.DB.QueryRow(`
+		INSERT INTO rate_limit_counters (bucket, window_start, hits)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (bucket, window_start) DO UPDATE SET hits = rate_limit_counters.hits + 1
+		RETURNING hits
+	`, bucket, window).Scan(&hits)
+	if err != nil {
+		return 0, fmt.Errorf("error counting request: %w", err)
+	}
+	return hits, nil
+}
+
+// Hits retorna las peticiones contadas en la ventana del bucket sin contar una nueva
+func (r *RateLimitRepository) Hits(bucket string, window time.Time) (int, error) {
+	var hits int
+	err := r.db.DB.QueryRow(`
+		SELECT hits FROM rate_limit_counters WHERE bucket = $1 AND window_start = $2
+	`, bucket, window).Scan(&hits)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("error getting request count: %w", err)
+	}
+	return hits, nil
+}
+
+// HitCompany cuenta una petición de la empresa (API key o miembro con JWT) en su ventana y en el
+// uso del mes. Retorna las peticiones de la ventana y el límite por minuto del plan (nil = sin
+// límite). Si el plan de la empresa no existe aplica domain.DefaultPlan; si tampoco existe
+// retorna un error.
+func (r *RateLimitRepository) HitCompany(companyID int64, window time.Time) (int, *int, error) {
+	var hits int
+	var plan sql.NullString
+	var limit *int
+	err := r.db.DB.QueryRow(`
+		WITH usage AS (
+			INSERT INTO company_usage (company_id, period, api_calls)
+			VALUES ($1, $2, 1)
+			ON CONFLICT (company_id, period) DO UPDATE
+				SET api_calls = company_usage.api_calls + 1, updated_at = NOW()
+		), hit AS (
+			INSERT INTO rate_limit_counters (bucket, window_start, hits)
+			VALUES ($3, $4, 1)
+			ON CONFLICT (bucket, window_start) DO UPDATE SET hits = rate_limit_counters.hits + 1
+			RETURNING hits
+		)
+		SELECT hit.hits,
+			COALESCE(p.code, d.code),
+			CASE WHEN p.code IS NOT NULL THEN p.api_calls_per_minute ELSE d.api_calls_per_minute END
+		FROM hit, companies c
+		LEFT JOIN plans p ON p.code = c.plan
+		LEFT JOIN plans d ON d.code = $5
+		WHERE c.id = $1
+	`,
+		companyID,
+		domain.UsagePeriodStart(window).Format("2006-01-02"),
+		fmt.Sprintf("company:%d", companyID),
+		window,
+		domain.DefaultPlan,
+	).Scan(&hits, &plan, &limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, errors.New("company not found")
+		}
+		return 0, nil, fmt.Errorf("error counting request: %w", err)
+	}
+	if !plan.Valid {
+		return 0, nil, errors.New("plan not found")
+	}
+	return hits, limit, nil
+}
+
+// Purge borra las ventanas que empezaron antes de before
+func (r *RateLimitRepository) Purge(before time.Time) (int64, error) {
+	result, err := r.db.DB.Exec(`DELETE FROM rate_limit_counters WHERE window_start < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("error purging rate limit counters: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -5,25 +5,80 @@ import (
 	"apidian-go/internal/infrastructure/database"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
+// ErrResolutionUnavailable envuelve los errores de asignación de consecutivos por el estado
+// de la resolución (agotada o vencida sin continuación, o pendiente de activación)
+var ErrResolutionUnavailable = errors.New("resolution unavailable")
+
+// maxResolutionChain limita cuántas resoluciones encadenadas se recorren al asignar un consecutivo
+const maxResolutionChain = 10
+
+const resolutionColumns = `
+	id, company_id, type_document_id, prefix, resolution, technical_key,
+	from_number, to_number, current_number, date_from, date_to,
+	is_active, created_at, updated_at, sync_status, synced_at, sync_notes,
+	status, follows_resolution_id, activated_at, alert_remaining, alert_days`
+
+// scanResolution lee una fila con las columnas de resolutionColumns
+func scanResolution(scanner interface{ Scan(...any) error }) (*domain.Resolution, error) {
+	resolution := &domain.Resolution{}
+	err := scanner.Scan(
+		&resolution.ID,
+		&resolution.CompanyID,
+		&resolution.TypeDocumentID,
+		&resolution.Prefix,
+		&resolution.Resolution,
+		&resolution.TechnicalKey,
+		&resolution.FromNumber,
+		&resolution.ToNumber,
+		&resolution.CurrentNumber,
+		&resolution.DateFrom,
+		&resolution.DateTo,
+		&resolution.IsActive,
+		&resolution.CreatedAt,
+		&resolution.UpdatedAt,
+		&resolution.SyncStatus,
+		&resolution.SyncedAt,
+		&resolution.SyncNotes,
+		&resolution.Status,
+		&resolution.FollowsResolutionID,
+		&resolution.ActivatedAt,
+		&resolution.AlertRemaining,
+		&resolution.AlertDays,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resolution, nil
+}
+
 type ResolutionRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // Usuario al que se atribuyen las escrituras en audit_log; 0 para procesos del sistema
 }
 
 func NewResolutionRepository(db *database.Database) *ResolutionRepository {
 	return &ResolutionRepository{db: db}
 }
 
-// Create crea una nueva resolución
+// As retorna una copia del repositorio cuyas escrituras quedan en audit_log a nombre del usuario
+func (r *ResolutionRepository) As(userID int64) *ResolutionRepository {
+	return &ResolutionRepository{db: r.db, actorID: userID}
+}
+
+// Create crea una nueva resolución. Si continúa a otra resolución queda pendiente hasta que
+// aquella se agote o venza.
 func (r *ResolutionRepository) Create(req *domain.CreateResolutionRequest) (*domain.Resolution, error) {
 	query := `
 		INSERT INTO resolutions (
 			company_id, type_document_id, prefix, resolution, technical_key,
-			from_number, to_number, current_number, date_from, date_to
+			from_number, to_number, current_number, date_from, date_to,
+			status, follows_resolution_id, alert_remaining, alert_days
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		) RETURNING id, is_active, created_at, updated_at
 	`
 
@@ -31,20 +86,29 @@ func (r *ResolutionRepository) Create(req *domain.CreateResolutionRequest) (*dom
 	dateFrom, _ := time.Parse("2006-01-02", req.DateFrom)
 	dateTo, _ := time.Parse("2006-01-02", req.DateTo)
 
+	status := domain.ResolutionStatusActive
+	if req.FollowsResolutionID != nil {
+		status = domain.ResolutionStatusPending
+	}
+
 	resolution := &domain.Resolution{
-		CompanyID:      req.CompanyID,
-		TypeDocumentID: req.TypeDocumentID,
-		Prefix:         req.Prefix,
-		Resolution:     req.Resolution,
-		TechnicalKey:   req.TechnicalKey,
-		FromNumber:     req.FromNumber,
-		ToNumber:       req.ToNumber,
-		CurrentNumber:  req.FromNumber, // Inicia en from_number
-		DateFrom:       dateFrom,
-		DateTo:         dateTo,
+		CompanyID:           req.CompanyID,
+		TypeDocumentID:      req.TypeDocumentID,
+		Prefix:              req.Prefix,
+		Resolution:          req.Resolution,
+		TechnicalKey:        req.TechnicalKey,
+		FromNumber:          req.FromNumber,
+		ToNumber:            req.ToNumber,
+		CurrentNumber:       req.FromNumber, // Inicia en from_number
+		DateFrom:            dateFrom,
+		DateTo:              dateTo,
+		Status:              status,
+		FollowsResolutionID: req.FollowsResolutionID,
+		AlertRemaining:      req.AlertRemaining,
+		AlertDays:           req.AlertDays,
 	}
 
-	err := r.db.DB.QueryRow(
+	err := r.db.QueryRowAs(r.actorID,
 		query,
 		req.CompanyID,
 		req.TypeDocumentID,
@@ -56,6 +120,10 @@ func (r *ResolutionRepository) Create(req *domain.CreateResolutionRequest) (*dom
 		req.FromNumber, // current_number inicia en from_number
 		dateFrom,
 		dateTo,
+		status,
+		req.FollowsResolutionID,
+		req.AlertRemaining,
+		req.AlertDays,
 	).Scan(
 		&resolution.ID,
 		&resolution.IsActive,
@@ -72,33 +140,9 @@ func (r *ResolutionRepository) Create(req *domain.CreateResolutionRequest) (*dom
 
 // GetByID obtiene una resolución por ID
 func (r *ResolutionRepository) GetByID(id int64) (*domain.Resolution, error) {
-	query := `
-		SELECT 
-			id, company_id, type_document_id, prefix, resolution, technical_key,
-			from_number, to_number, current_number, date_from, date_to,
-			is_active, created_at, updated_at
-		FROM resolutions
-		WHERE id = $1 AND is_active = true
-	`
-
-	resolution := &domain.Resolution{}
-	err := r.db.DB.QueryRow(query, id).Scan(
-		&resolution.ID,
-		&resolution.CompanyID,
-		&resolution.TypeDocumentID,
-		&resolution.Prefix,
-		&resolution.Resolution,
-		&resolution.TechnicalKey,
-		&resolution.FromNumber,
-		&resolution.ToNumber,
-		&resolution.CurrentNumber,
-		&resolution.DateFrom,
-		&resolution.DateTo,
-		&resolution.IsActive,
-		&resolution.CreatedAt,
-		&resolution.UpdatedAt,
-	)
+	query := `SELECT ` + resolutionColumns + ` FROM resolutions WHERE id = $1 AND is_active = true`
 
+	resolution, err := scanResolution(r.db.DB.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("resolution not found")
@@ -122,11 +166,7 @@ func (r *ResolutionRepository) GetByCompanyID(companyID int64, page, pageSize in
 	}
 
 	// Obtener resoluciones paginadas
-	query := `
-		SELECT 
-			id, company_id, type_document_id, prefix, resolution, technical_key,
-			from_number, to_number, current_number, date_from, date_to,
-			is_active, created_at, updated_at
+	query := `SELECT ` + resolutionColumns + `
 		FROM resolutions
 		WHERE company_id = $1 AND is_active = true
 		ORDER BY created_at DESC
@@ -141,27 +181,11 @@ func (r *ResolutionRepository) GetByCompanyID(companyID int64, page, pageSize in
 
 	var resolutions []domain.Resolution
 	for rows.Next() {
-		var resolution domain.Resolution
-		err := rows.Scan(
-			&resolution.ID,
-			&resolution.CompanyID,
-			&resolution.TypeDocumentID,
-			&resolution.Prefix,
-			&resolution.Resolution,
-			&resolution.TechnicalKey,
-			&resolution.FromNumber,
-			&resolution.ToNumber,
-			&resolution.CurrentNumber,
-			&resolution.DateFrom,
-			&resolution.DateTo,
-			&resolution.IsActive,
-			&resolution.CreatedAt,
-			&resolution.UpdatedAt,
-		)
+		resolution, err := scanResolution(rows)
 		if err != nil {
 			return nil, err
 		}
-		resolutions = append(resolutions, resolution)
+		resolutions = append(resolutions, *resolution)
 	}
 
 	return &domain.ResolutionListResponse{
@@ -182,7 +206,8 @@ func (r *ResolutionRepository) GetByUserID(userID int64, page, pageSize int) (*d
 		SELECT COUNT(*) 
 		FROM resolutions res
 		INNER JOIN companies c ON res.company_id = c.id
-		WHERE c.user_id = $1 AND res.is_active = true AND c.is_active = true
+		INNER JOIN company_members m ON m.company_id = c.id
+		WHERE m.user_id = $1 AND res.is_active = true AND c.is_active = true
 	`
 	err := r.db.DB.QueryRow(countQuery, userID).Scan(&total)
 	if err != nil {
@@ -190,15 +215,15 @@ func (r *ResolutionRepository) GetByUserID(userID int64, page, pageSize int) (*d
 	}
 
 	// Obtener resoluciones paginadas
-	query := `
-		SELECT 
-			res.id, res.company_id, res.type_document_id, res.prefix, res.resolution, 
-			res.technical_key, res.from_number, res.to_number, res.current_number, 
-			res.date_from, res.date_to, res.is_active, res.created_at, res.updated_at
-		FROM resolutions res
-		INNER JOIN companies c ON res.company_id = c.id
-		WHERE c.user_id = $1 AND res.is_active = true AND c.is_active = true
-		ORDER BY res.created_at DESC
+	query := `SELECT ` + resolutionColumns + `
+		FROM resolutions
+		WHERE company_id IN (
+			SELECT c.id FROM companies c
+			JOIN company_members m ON m.company_id = c.id
+			WHERE m.user_id = $1 AND c.is_active = true
+		)
+			AND is_active = true
+		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
@@ -210,27 +235,11 @@ func (r *ResolutionRepository) GetByUserID(userID int64, page, pageSize int) (*d
 
 	var resolutions []domain.Resolution
 	for rows.Next() {
-		var resolution domain.Resolution
-		err := rows.Scan(
-			&resolution.ID,
-			&resolution.CompanyID,
-			&resolution.TypeDocumentID,
-			&resolution.Prefix,
-			&resolution.Resolution,
-			&resolution.TechnicalKey,
-			&resolution.FromNumber,
-			&resolution.ToNumber,
-			&resolution.CurrentNumber,
-			&resolution.DateFrom,
-			&resolution.DateTo,
-			&resolution.IsActive,
-			&resolution.CreatedAt,
-			&resolution.UpdatedAt,
-		)
+		resolution, err := scanResolution(rows)
 		if err != nil {
 			return nil, err
 		}
-		resolutions = append(resolutions, resolution)
+		resolutions = append(resolutions, *resolution)
 	}
 
 	return &domain.ResolutionListResponse{
@@ -250,7 +259,7 @@ func (r *ResolutionRepository) Delete(id int64) error {
 		WHERE id = $1 AND is_active = true
 	`
 
-	result, err := r.db.DB.Exec(query, id)
+	result, err := r.db.ExecAs(r.actorID, query, id)
 	if err != nil {
 		return err
 	}
@@ -269,33 +278,9 @@ func (r *ResolutionRepository) Delete(id int64) error {
 
 // GetByCompanyAndPrefix obtiene una resolución por empresa y prefijo
 func (r *ResolutionRepository) GetByCompanyAndPrefix(companyID int64, prefix string) (*domain.Resolution, error) {
-	query := `
-		SELECT 
-			id, company_id, type_document_id, prefix, resolution, technical_key,
-			from_number, to_number, current_number, date_from, date_to,
-			is_active, created_at, updated_at
-		FROM resolutions
-		WHERE company_id = $1 AND prefix = $2 AND is_active = true
-	`
-
-	resolution := &domain.Resolution{}
-	err := r.db.DB.QueryRow(query, companyID, prefix).Scan(
-		&resolution.ID,
-		&resolution.CompanyID,
-		&resolution.TypeDocumentID,
-		&resolution.Prefix,
-		&resolution.Resolution,
-		&resolution.TechnicalKey,
-		&resolution.FromNumber,
-		&resolution.ToNumber,
-		&resolution.CurrentNumber,
-		&resolution.DateFrom,
-		&resolution.DateTo,
-		&resolution.IsActive,
-		&resolution.CreatedAt,
-		&resolution.UpdatedAt,
-	)
+	query := `SELECT ` + resolutionColumns + ` FROM resolutions WHERE company_id = $1 AND prefix = $2 AND is_active = true`
 
+	resolution, err := scanResolution(r.db.DB.QueryRow(query, companyID, prefix))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No existe, pero no es error
@@ -306,62 +291,358 @@ func (r *ResolutionRepository) GetByCompanyAndPrefix(companyID int64, prefix str
 	return resolution, nil
 }
 
-// GetAndIncrementConsecutive obtiene el consecutivo actual y lo incrementa de forma atómica
-// Este método es thread-safe y previene race conditions usando transacciones con FOR UPDATE
-func (r *ResolutionRepository) GetAndIncrementConsecutive(resolutionID int64) (int64, error) {
-	// Iniciar transacción
+// allocateConsecutive asigna el siguiente consecutivo dentro de tx (la transacción que inserta
+// el documento, para que un INSERT fallido no deje huecos). Si la resolución está agotada o
+// vencida (date_to anterior a today) la marca como terminada y asigna el consecutivo de su
+// resolución de continuación, activándola si estaba pendiente. Retorna la resolución usada,
+// su prefijo y el consecutivo. FOR UPDATE serializa las asignaciones concurrentes.
+func allocateConsecutive(tx *sql.Tx, resolutionID int64, today time.Time) (int64, string, int64, error) {
+	day := today.Format("2006-01-02")
+	id := resolutionID
+
+	for hop := 0; hop < maxResolutionChain; hop++ {
+		// Obtener resolución y bloquear la fila (FOR UPDATE previene lecturas concurrentes)
+		query := `
+			SELECT prefix, current_number, is_active, status, date_to,
+				current_number >= to_number, date_to < $2::date
+			FROM resolutions
+			WHERE id = $1
+			FOR UPDATE
+		`
+
+		var prefix, status string
+		var currentNumber int64
+		var isActive, exhausted, expired bool
+		var dateTo time.Time
+		err := tx.QueryRow(query, id, day).Scan(&prefix, &currentNumber, &isActive, &status, &dateTo, &exhausted, &expired)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return 0, "", 0, errors.New("resolution not found")
+			}
+			return 0, "", 0, err
+		}
+
+		// Validar que la resolución esté activa
+		if !isActive {
+			return 0, "", 0, errors.New("resolution is not active")
+		}
+		if status == domain.ResolutionStatusPending {
+			return 0, "", 0, fmt.Errorf("%w: resolution is pending activation, it is used once the resolution it follows is exhausted or expires", ErrResolutionUnavailable)
+		}
+
+		if status == domain.ResolutionStatusActive {
+			if !exhausted && !expired {
+				// Incrementar current_number en la base de datos
+				updateQuery := `
+					UPDATE resolutions 
+					SET current_number = current_number + 1, updated_at = NOW()
+					WHERE id = $1
+				`
+				if _, err = tx.Exec(updateQuery, id); err != nil {
+					return 0, "", 0, errors.New("error incrementing consecutive: " + err.Error())
+				}
+
+				// Retornar el consecutivo que se usó (antes del incremento)
+				return id, prefix, currentNumber, nil
+			}
+
+			// Sin consecutivos o vencida: la resolución termina aquí. Si la asignación falla
+			// el cambio se revierte con la transacción y lo hace la revisión periódica.
+			status = domain.ResolutionStatusExpired
+			if exhausted {
+				status = domain.ResolutionStatusExhausted
+			}
+			if err := markResolutionEnded(tx, id, status); err != nil {
+				return 0, "", 0, err
+			}
+		}
+
+		// Continuar con la resolución de continuación
+		var nextID int64
+		var nextStatus string
+		var nextDateFrom time.Time
+		var started bool
+		err = tx.QueryRow(`
+			SELECT id, status, date_from, date_from <= $2::date
+			FROM resolutions
+			WHERE follows_resolution_id = $1 AND is_active = true
+			FOR UPDATE
+		`, id, day).Scan(&nextID, &nextStatus, &nextDateFrom, &started)
+		if err == sql.ErrNoRows {
+			if status == domain.ResolutionStatusExhausted {
+				return 0, "", 0, fmt.Errorf("%w: no hay consecutivos disponibles en esta resolución", ErrResolutionUnavailable)
+			}
+			return 0, "", 0, fmt.Errorf("%w: resolution expired on %s and has no follow-up resolution", ErrResolutionUnavailable, dateTo.Format("2006-01-02"))
+		}
+		if err != nil {
+			return 0, "", 0, err
+		}
+
+		if nextStatus == domain.ResolutionStatusPending {
+			if !started {
+				return 0, "", 0, fmt.Errorf("%w: follow-up resolution %d is not valid until %s", ErrResolutionUnavailable, nextID, nextDateFrom.Format("2006-01-02"))
+			}
+			if err := activateResolution(tx, nextID); err != nil {
+				return 0, "", 0, err
+			}
+		}
+		id = nextID
+	}
+
+	return 0, "", 0, errors.New("too many chained follow-up resolutions")
+}
+
+// markResolutionEnded marca una resolución activa como agotada o vencida
+func markResolutionEnded(tx *sql.Tx, id int64, status string) error {
+	_, err := tx.Exec(`
+		UPDATE resolutions
+		SET status = $2, status_notified_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, status)
+	return err
+}
+
+// activateResolution pone en uso una resolución de continuación pendiente
+func activateResolution(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(`
+		UPDATE resolutions
+		SET status = $2, activated_at = NOW(), status_notified_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, domain.ResolutionStatusActive)
+	return err
+}
+
+// GetFollowUp obtiene la resolución de continuación registrada para una resolución
+func (r *ResolutionRepository) GetFollowUp(id int64) (*domain.Resolution, error) {
+	query := `SELECT ` + resolutionColumns + ` FROM resolutions WHERE follows_resolution_id = $1 AND is_active = true`
+
+	resolution, err := scanResolution(r.db.DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("resolution not found")
+		}
+		return nil, err
+	}
+	return resolution, nil
+}
+
+// SwitchOverDue termina las resoluciones activas agotadas o vencidas a la fecha today y activa
+// las continuaciones pendientes cuya resolución anterior terminó (o fue eliminada) y cuya
+// vigencia ya empezó. Complementa el cambio que hace allocateConsecutive al facturar.
+func (r *ResolutionRepository) SwitchOverDue(today time.Time) error {
 	tx, err := r.db.DB.Begin()
 	if err != nil {
-		return 0, errors.New("error starting transaction: " + err.Error())
+		return err
 	}
 	defer tx.Rollback()
 
-	// Obtener resolución y bloquear la fila (FOR UPDATE previene lecturas concurrentes)
+	day := today.Format("2006-01-02")
+
+	if _, err := tx.Exec(`
+		UPDATE resolutions
+		SET status = CASE WHEN current_number >= to_number THEN $2 ELSE $3 END,
+			status_notified_at = NULL, updated_at = NOW()
+		WHERE is_active = true AND status = $1
+			AND (current_number >= to_number OR date_to < $4::date)
+	`, domain.ResolutionStatusActive, domain.ResolutionStatusExhausted, domain.ResolutionStatusExpired, day); err != nil {
+		return err
+	}
+
+	// Una pasada por eslabón: una continuación activada puede terminar a su vez en la siguiente revisión
+	if _, err := tx.Exec(`
+		UPDATE resolutions r
+		SET status = $1, activated_at = NOW(), status_notified_at = NULL, updated_at = NOW()
+		FROM resolutions p
+		WHERE r.follows_resolution_id = p.id
+			AND r.is_active = true AND r.status = $2 AND r.date_from <= $5::date
+			AND (p.status IN ($3, $4) OR p.is_active = false)
+	`, domain.ResolutionStatusActive, domain.ResolutionStatusPending,
+		domain.ResolutionStatusExhausted, domain.ResolutionStatusExpired, day); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClaimStatusNotifications reserva los cambios de estado aún no notificados (resoluciones que
+// terminaron y continuaciones que entraron en uso). Con varias instancias solo una los obtiene.
+func (r *ResolutionRepository) ClaimStatusNotifications() ([]domain.Resolution, error) {
 	query := `
-		SELECT current_number, to_number, is_active
-		FROM resolutions
-		WHERE id = $1
-		FOR UPDATE
-	`
-	
-	var currentNumber, toNumber int64
-	var isActive bool
-	err = tx.QueryRow(query, resolutionID).Scan(&currentNumber, &toNumber, &isActive)
+		UPDATE resolutions SET status_notified_at = NOW()
+		WHERE is_active = true AND status_notified_at IS NULL
+			AND (status IN ($1, $2) OR (status = $3 AND activated_at IS NOT NULL))
+		RETURNING ` + resolutionColumns
+
+	return r.claim(query, domain.ResolutionStatusExhausted, domain.ResolutionStatusExpired, domain.ResolutionStatusActive)
+}
+
+// ClaimLowAlerts reserva las resoluciones activas cuyos consecutivos restantes cruzaron el
+// umbral (alert_remaining o defaultRemaining) y que aún no se notificaron
+func (r *ResolutionRepository) ClaimLowAlerts(defaultRemaining int) ([]domain.Resolution, error) {
+	query := `
+		UPDATE resolutions SET low_notified_at = NOW()
+		WHERE is_active = true AND status = $1 AND low_notified_at IS NULL
+			AND COALESCE(alert_remaining, $2) > 0
+			AND to_number - current_number <= COALESCE(alert_remaining, $2)
+		RETURNING ` + resolutionColumns
+
+	return r.claim(query, domain.ResolutionStatusActive, defaultRemaining)
+}
+
+// ClaimExpiryAlerts reserva las resoluciones activas que vencen dentro del umbral de días
+// (alert_days o defaultDays) y que aún no se notificaron
+func (r *ResolutionRepository) ClaimExpiryAlerts(today time.Time, defaultDays int) ([]domain.Resolution, error) {
+	query := `
+		UPDATE resolutions SET expiry_notified_at = NOW()
+		WHERE is_active = true AND status = $1 AND expiry_notified_at IS NULL
+			AND COALESCE(alert_days, $2) > 0
+			AND date_to - $3::date <= COALESCE(alert_days, $2)
+		RETURNING ` + resolutionColumns
+
+	return r.claim(query, domain.ResolutionStatusActive, defaultDays, today.Format("2006-01-02"))
+}
+
+// claim ejecuta un UPDATE ... RETURNING resolutionColumns y lee las filas reservadas
+func (r *ResolutionRepository) claim(query string, args ...any) ([]domain.Resolution, error) {
+	rows, err := r.db.DB.Query(query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, errors.New("resolution not found")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resolutions []domain.Resolution
+	for rows.Next() {
+		resolution, err := scanResolution(rows)
+		if err != nil {
+			return nil, err
 		}
-		return 0, err
+		resolutions = append(resolutions, *resolution)
 	}
+	return resolutions, rows.Err()
+}
 
-	// Validar que la resolución esté activa
-	if !isActive {
-		return 0, errors.New("resolution is not active")
+// ListActiveByCompanyID obtiene todas las resoluciones activas de una empresa (sin paginación)
+func (r *ResolutionRepository) ListActiveByCompanyID(companyID int64) ([]domain.Resolution, error) {
+	query := `SELECT ` + resolutionColumns + ` FROM resolutions WHERE company_id = $1 AND is_active = true ORDER BY prefix`
+
+	rows, err := r.db.DB.Query(query, companyID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Validar que hay consecutivos disponibles
-	if currentNumber >= toNumber {
-		return 0, errors.New("no hay consecutivos disponibles en esta resolución")
+	var resolutions []domain.Resolution
+	for rows.Next() {
+		resolution, err := scanResolution(rows)
+		if err != nil {
+			return nil, err
+		}
+		resolutions = append(resolutions, *resolution)
 	}
+	return resolutions, rows.Err()
+}
 
-	// Incrementar current_number en la base de datos
-	updateQuery := `
-		UPDATE resolutions 
-		SET current_number = current_number + 1, updated_at = NOW()
-		WHERE id = $1
+// CreateFromDIAN registra un rango autorizado en DIAN que no existe localmente. Si el prefijo
+// pertenece a una resolución eliminada se reactiva: conserva el consecutivo si es la misma
+// resolución y reinicia en from_number si es una nueva.
+func (r *ResolutionRepository) CreateFromDIAN(res *domain.Resolution, notes string) (*domain.Resolution, error) {
+	query := `
+		INSERT INTO resolutions (
+			company_id, type_document_id, prefix, resolution, technical_key,
+			from_number, to_number, current_number, date_from, date_to,
+			sync_status, synced_at, sync_notes
+		) VALUES (
+			$1, (SELECT id FROM invoice_type_codes WHERE code = '01'), $2, $3, $4,
+			$5, $6, $5, $7, $8, $9, NOW(), NULLIF($10, '')
+		)
+		ON CONFLICT (company_id, prefix) DO UPDATE SET
+			resolution = EXCLUDED.resolution,
+			technical_key = EXCLUDED.technical_key,
+			from_number = EXCLUDED.from_number,
+			to_number = EXCLUDED.to_number,
+			current_number = CASE WHEN resolutions.resolution = EXCLUDED.resolution
+				THEN GREATEST(resolutions.current_number, EXCLUDED.from_number)
+				ELSE EXCLUDED.from_number END,
+			date_from = EXCLUDED.date_from,
+			date_to = EXCLUDED.date_to,
+			is_active = true,
+			status = 'active',
+			follows_resolution_id = NULL,
+			activated_at = NULL,
+			low_notified_at = NULL,
+			expiry_notified_at = NULL,
+			status_notified_at = NULL,
+			sync_status = EXCLUDED.sync_status,
+			synced_at = EXCLUDED.synced_at,
+			sync_notes = EXCLUDED.sync_notes,
+			updated_at = NOW()
+		WHERE resolutions.is_active = false
+		RETURNING ` + resolutionColumns
+
+	created, err := scanResolution(r.db.QueryRowAs(r.actorID, query,
+		res.CompanyID,
+		res.Prefix,
+		res.Resolution,
+		res.TechnicalKey,
+		res.FromNumber,
+		res.ToNumber,
+		res.DateFrom,
+		res.DateTo,
+		domain.ResolutionSyncCreated,
+		notes,
+	))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("resolution with this prefix already exists for this company")
+	}
+	return created, err
+}
+
+// UpdateFromDIAN corrige una resolución con los datos autorizados en DIAN. Solo actualiza si el
+// consecutivo actual queda dentro del nuevo rango (el CHECK de la tabla lo garantiza también).
+func (r *ResolutionRepository) UpdateFromDIAN(id int64, res *domain.Resolution, notes string) error {
+	query := `
+		UPDATE resolutions SET
+			resolution = $2,
+			technical_key = $3,
+			from_number = $4,
+			to_number = $5,
+			date_from = $6,
+			date_to = $7,
+			sync_status = $8,
+			synced_at = NOW(),
+			sync_notes = NULLIF($9, ''),
+			low_notified_at = CASE WHEN to_number = $5 THEN low_notified_at END,
+			expiry_notified_at = CASE WHEN date_to = $7 THEN expiry_notified_at END,
+			updated_at = NOW()
+		WHERE id = $1 AND is_active = true AND current_number BETWEEN $4 AND $5
 	`
-	_, err = tx.Exec(updateQuery, resolutionID)
+
+	result, err := r.db.ExecAs(r.actorID, query, id, res.Resolution, res.TechnicalKey, res.FromNumber, res.ToNumber,
+		res.DateFrom, res.DateTo, domain.ResolutionSyncUpdated, notes)
 	if err != nil {
-		return 0, errors.New("error incrementing consecutive: " + err.Error())
+		return err
 	}
-
-	// Commit de la transacción
-	if err = tx.Commit(); err != nil {
-		return 0, errors.New("error committing transaction: " + err.Error())
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
+	if rowsAffected == 0 {
+		return errors.New("current consecutive is outside the DIAN range")
+	}
+	return nil
+}
 
-	// Retornar el consecutivo que se usó (antes del incremento)
-	return currentNumber, nil
+// MarkSynced registra el resultado de la sincronización sin modificar los datos de la resolución
+func (r *ResolutionRepository) MarkSynced(id int64, status, notes string) error {
+	query := `
+		UPDATE resolutions SET
+			sync_status = $2,
+			synced_at = NOW(),
+			sync_notes = NULLIF($3, '')
+		WHERE id = $1
+	`
+	_, err := r.db.ExecAs(r.actorID, query, id, status, notes)
+	return err
 }
 
 // Placeholder para evitar error de compilación
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+)
+
+const sessionColumns = `
+	id, user_id, refresh_token_hash, previous_refresh_token_hash, access_jti, access_expires_at,
+	user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason,
+	impersonator_id`
+
+// scanSession lee una fila con las columnas de sessionColumns
+func scanSession(scanner interface{ Scan(...any) error }) (*domain.Session, error) {
+	session := &domain.Session{}
+	err := scanner.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.PreviousRefreshTokenHash,
+		&session.AccessJTI,
+		&session.AccessExpiresAt,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.RevokedReason,
+		&session.ImpersonatorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+type SessionRepository struct {
+	db *database.Database
+}
+
+func NewSessionRepository(db *database.Database) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create registra una sesión nueva
+func (r *SessionRepository) Create(session *domain.Session) error {
+	query := `
+		INSERT INTO user_sessions (
+			user_id, refresh_token_hash, access_jti, access_expires_at,
+			user_agent, ip_address, expires_at, impersonator_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, last_used_at
+	`
+
+	return r.db.DB.QueryRow(query,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.AccessJTI,
+		session.AccessExpiresAt,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+		session.ImpersonatorID,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+}
+
+// GetActiveByRefreshHash obtiene la sesión vigente dueña del refresh token
+func (r *SessionRepository) GetActiveByRefreshHash(hash string) (*domain.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+		WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()`
+
+	session, err := scanSession(r.db.DB.QueryRow(query, hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// Rotate reemplaza el refresh token de la sesión y revoca el access token anterior. Solo una
+// rotación concurrente con el mismo refresh token tiene éxito; retorna false si perdió.
+func (r *SessionRepository) Rotate(session *domain.Session, oldHash string) (bool, error) {
+	query := `
+		WITH old AS (
+			SELECT id, user_id, access_jti, access_expires_at
+			FROM user_sessions
+			WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL
+			FOR UPDATE
+		), rotated AS (
+			UPDATE user_sessions s SET
+				previous_refresh_token_hash = s.refresh_token_hash,
+				refresh_token_hash = $3,
+				access_jti = $4,
+				access_expires_at = $5,
+				expires_at = $6,
+				user_agent = COALESCE($7, s.user_agent),
+				ip_address = COALESCE($8, s.ip_address),
+				last_used_at = NOW()
+			FROM old
+			WHERE s.id = old.id
+			RETURNING s.id
+		), revoked AS (
+			INSERT INTO revoked_tokens (jti, user_id, expires_at)
+			SELECT access_jti, user_id, access_expires_at FROM old WHERE access_expires_at > NOW()
+			ON CONFLICT (jti) DO NOTHING
+		)
+		SELECT COUNT(*) FROM rotated
+	`
+
+	var rotated int
+	err := r.db.DB.QueryRow(query,
+		session.ID,
+		oldHash,
+		session.RefreshTokenHash,
+		session.AccessJTI,
+		session.AccessExpiresAt,
+		session.ExpiresAt,
+		session.UserAgent,
+		session.IPAddress,
+	).Scan(&rotated)
+	if err != nil {
+		return false, err
+	}
+	return rotated > 0, nil
+}
+
+// ListActive obtiene las sesiones vigentes de un usuario, la más reciente primero
+func (r *SessionRepository) ListActive(userID int64) ([]domain.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC`
+
+	rows, err := r.db.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []domain.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke cierra una sesión del usuario y revoca su access token
+func (r *SessionRepository) Revoke(id, userID int64, reason string) error {
+	revoked, err := r.revokeWhere(`id = $2 AND user_id = $3`, reason, id, userID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeAll cierra todas las sesiones del usuario y retorna cuántas se cerraron
+func (r *SessionRepository) RevokeAll(userID int64, reason string) (int, error) {
+	return r.revokeWhere(`user_id = $2`, reason, userID)
+}
+
+// RevokeOthers cierra las sesiones del usuario menos la actual y retorna cuántas se cerraron
+func (r *SessionRepository) RevokeOthers(userID, currentID int64, reason string) (int, error) {
+	return r.revokeWhere(`user_id = $2 AND id <> $3`, reason, userID, currentID)
+}
+
+// RevokeByPreviousHash cierra la sesión cuyo refresh token anterior se volvió a presentar
+// (posible robo del token) y retorna cuántas se cerraron
+func (r *SessionRepository) RevokeByPreviousHash(hash string) (int, error) {
+	return r.revokeWhere(`previous_refresh_token_hash = $2`, domain.SessionRevokedReuse, hash)
+}
+
+// revokeWhere revoca las sesiones activas que cumplen condition ($1 es el motivo) junto con
+// sus access tokens, y depura los tokens revocados que ya expiraron
+func (r *SessionRepository) revokeWhere(condition, reason string, args ...any) (int, error) {
+	query := `
+		WITH closed AS (
+			UPDATE user_sessions SET revoked_at = NOW(), revoked_reason = $1
+			WHERE revoked_at IS NULL AND ` + condition + `
+			RETURNING user_id, access_jti, access_expires_at
+		), revoked AS (
+			INSERT INTO revoked_tokens (jti, user_id, expires_at)
+			SELECT access_jti, user_id, access_expires_at FROM closed WHERE access_expires_at > NOW()
+			ON CONFLICT (jti) DO NOTHING
+		)
+		SELECT COUNT(*) FROM closed
+	`
+
+	var closed int
+	if err := r.db.DB.QueryRow(query, append([]any{reason}, args...)...).Scan(&closed); err != nil {
+		return 0, err
+	}
+
+	if _, err := r.db.DB.Exec(`DELETE FROM revoked_tokens WHERE expires_at < NOW()`); err != nil {
+		return closed, err
+	}
+	return closed, nil
+}
+
+// IsTokenRevoked indica si el access token (jti) fue revocado
+func (r *SessionRepository) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
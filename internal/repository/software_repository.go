@@ -8,13 +8,19 @@ import (
 )
 
 type SoftwareRepository struct {
-	db *database.Database
+	db      *database.Database
+	actorID int64 // Usuario al que se atribuyen las escrituras en audit_log; 0 para procesos del sistema
 }
 
 func NewSoftwareRepository(db *database.Database) *SoftwareRepository {
 	return &SoftwareRepository{db: db}
 }
 
+// As retorna una copia del repositorio cuyas escrituras quedan en audit_log a nombre del usuario
+func (r *SoftwareRepository) As(userID int64) *SoftwareRepository {
+	return &SoftwareRepository{db: r.db, actorID: userID}
+}
+
 // Create crea una nueva configuración de software
 func (r *SoftwareRepository) Create(req *domain.CreateSoftwareRequest) (*domain.Software, error) {
 	query := `
@@ -33,7 +39,7 @@ func (r *SoftwareRepository) Create(req *domain.CreateSoftwareRequest) (*domain.
 		TestSetID:   req.TestSetID,
 	}
 
-	err := r.db.DB.QueryRow(
+	err := r.db.QueryRowAs(r.actorID,
 		query,
 		req.CompanyID,
 		req.Identifier,
@@ -133,7 +139,7 @@ func (r *SoftwareRepository) Update(id int64, req *domain.UpdateSoftwareRequest)
 		WHERE id = $6 AND is_active = true
 	`
 
-	result, err := r.db.DB.Exec(
+	result, err := r.db.ExecAs(r.actorID,
 		query,
 		req.Identifier,
 		req.Pin,
@@ -168,7 +174,7 @@ func (r *SoftwareRepository) Delete(id int64) error {
 		WHERE id = $1 AND is_active = true
 	`
 
-	result, err := r.db.DB.Exec(query, id)
+	result, err := r.db.ExecAs(r.actorID, query, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type TwoFactorRepository struct {
+	db *database.Database
+}
+
+func NewTwoFactorRepository(db *database.Database) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// TwoFactorSecret es el secreto TOTP cifrado de un usuario
+type TwoFactorSecret struct {
+	UserID    int64
+	Secret    string // Cifrado con la llave maestra
+	EnabledAt *time.Time
+	LastStep  int64 // Último paso TOTP aceptado; 0 si no se ha usado ninguno
+}
+
+// GetSecret obtiene el secreto TOTP del usuario activo (pendiente o confirmado)
+func (r *TwoFactorRepository) GetSecret(userID int64) (*TwoFactorSecret, error) {
+	secret := &TwoFactorSecret{UserID: userID}
+	var encrypted sql.NullString
+	var lastStep sql.NullInt64
+	err := r.db.DB.QueryRow(`
+		SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1 AND is_active = true
+	`, userID).Scan(&encrypted, &secret.EnabledAt, &lastStep)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("error getting two-factor secret: %w", err)
+	}
+	if !encrypted.Valid {
+		return nil, errors.New("two-factor authentication not set up")
+	}
+	secret.Secret = encrypted.String
+	secret.LastStep = lastStep.Int64
+	return secret, nil
+}
+
+// SetPendingSecret guarda un secreto nuevo sin activar 2FA; falla si ya está activo
+func (r *TwoFactorRepository) SetPendingSecret(userID int64, encrypted string) error {
+	result, err := r.db.DB.Exec(`
+		UPDATE users SET totp_secret = $2, totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1 AND is_active = true AND totp_enabled_at IS NULL
+	`, userID, encrypted)
+	if err != nil {
+		return fmt.Errorf("error saving two-factor secret: %w", err)
+	}
+	return requireAffected(result, "two-factor authentication already enabled")
+}
+
+// Enable activa 2FA con el paso TOTP que lo confirmó y reemplaza los códigos de recuperación
+func (r *TwoFactorRepository) Enable(userID, step int64, codeHashes []string) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+		WHERE id = $1 AND is_active = true AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("error enabling two-factor authentication: %w", err)
+	}
+	if err := requireAffected(result, "two-factor authentication already enabled"); err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Disable borra el secreto y los códigos de recuperación del usuario
+func (r *TwoFactorRepository) Disable(userID int64) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+		WHERE id = $1 AND is_active = true AND totp_enabled_at IS NOT NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("error disabling two-factor authentication: %w", err)
+	}
+	if err := requireAffected(result, "two-factor authentication not enabled"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UseStep registra el paso TOTP de un código aceptado; false si ese paso (o uno posterior) ya se
+// usó, así un código interceptado no sirve dos veces aunque siga vigente
+func (r *TwoFactorRepository) UseStep(userID, step int64) (bool, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("error saving TOTP step: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// UseRecoveryCode consume un código de recuperación sin usar; false si no existe o ya se usó
+func (r *TwoFactorRepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReplaceRecoveryCodes invalida los códigos de recuperación anteriores y guarda los nuevos
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountRecoveryCodes cuenta los códigos de recuperación sin usar
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int64) (int, error) {
+	var count int
+	err := r.db.DB.QueryRow(`
+		SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("error saving recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompanyRole es la membresía de un usuario en una empresa
+type CompanyRole struct {
+	CompanyID   int64
+	CompanyName string
+	Role        string
+}
+
+// GetRequiringCompanies lista las empresas activas del usuario que exigen 2FA, con su rol
+func (r *TwoFactorRepository) GetRequiringCompanies(userID int64) ([]CompanyRole, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT c.id, c.name, m.role
+		FROM company_members m
+		JOIN companies c ON c.id = m.company_id
+		WHERE m.user_id = $1 AND c.is_active = true AND c.require_two_factor = true
+		ORDER BY c.name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying companies: %w", err)
+	}
+	defer rows.Close()
+
+	companies := []CompanyRole{}
+	for rows.Next() {
+		var company CompanyRole
+		if err := rows.Scan(&company.CompanyID, &company.CompanyName, &company.Role); err != nil {
+			return nil, fmt.Errorf("error scanning company: %w", err)
+		}
+		companies = append(companies, company)
+	}
+	return companies, rows.Err()
+}
+
+// CreateChallenge registra el segundo paso pendiente de un login y depura los vencidos
+func (r *TwoFactorRepository) CreateChallenge(challenge *domain.LoginChallenge) error {
+	if _, err := r.db.DB.Exec(`DELETE FROM login_challenges WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("error purging login challenges: %w", err)
+	}
+
+	return r.db.DB.QueryRow(`
+		INSERT INTO login_challenges (user_id, token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`,
+		challenge.UserID,
+		challenge.TokenHash,
+		challenge.UserAgent,
+		challenge.IPAddress,
+		challenge.ExpiresAt,
+	).Scan(&challenge.ID, &challenge.CreatedAt)
+}
+
+// AttemptChallenge cuenta un intento sobre un reto vigente y lo retorna. Después de
+// domain.TwoFactorMaxAttempts intentos el reto deja de servir y hay que repetir el login.
+func (r *TwoFactorRepository) AttemptChallenge(hash string) (*domain.LoginChallenge, error) {
+	challenge := &domain.LoginChallenge{TokenHash: hash}
+	err := r.db.DB.QueryRow(`
+		UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+		RETURNING id, user_id, attempts, user_agent, ip_address, expires_at, created_at
+	`, hash, domain.TwoFactorMaxAttempts).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.Attempts,
+		&challenge.UserAgent,
+		&challenge.IPAddress,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("invalid or expired challenge")
+		}
+		return nil, fmt.Errorf("error getting login challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// CompleteChallenge marca el reto como usado; falla si otra petición lo completó primero
+func (r *TwoFactorRepository) CompleteChallenge(id int64) error {
+	result, err := r.db.DB.Exec(`UPDATE login_challenges SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("error completing login challenge: %w", err)
+	}
+	return requireAffected(result, "invalid or expired challenge")
+}
+
+// GetAllSecrets lista los secretos TOTP guardados (para re-cifrarlos al rotar la llave maestra)
+func (r *TwoFactorRepository) GetAllSecrets() ([]TwoFactorSecret, error) {
+	rows, err := r.db.DB.Query(`SELECT id, totp_secret, totp_enabled_at FROM users WHERE totp_secret IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying two-factor secrets: %w", err)
+	}
+	defer rows.Close()
+
+	secrets := []TwoFactorSecret{}
+	for rows.Next() {
+		var secret TwoFactorSecret
+		if err := rows.Scan(&secret.UserID, &secret.Secret, &secret.EnabledAt); err != nil {
+			return nil, fmt.Errorf("error scanning two-factor secret: %w", err)
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+// UpdateSecret reemplaza el secreto cifrado sin cambiar el estado de 2FA
+func (r *TwoFactorRepository) UpdateSecret(userID int64, encrypted string) error {
+	_, err := r.db.DB.Exec(`UPDATE users SET totp_secret = $2 WHERE id = $1 AND totp_secret IS NOT NULL`, userID, encrypted)
+	return err
+}
@@ -36,7 +36,7 @@ func (r *UserRepository) GetByID(id int64) (*domain.User, error) {
 	query := `
 		SELECT 
 			id, name, email, email_verified_at, password, 
-			is_active, created_at, updated_at
+			is_active, is_platform_admin, totp_enabled_at IS NOT NULL, created_at, updated_at
 		FROM users
 		WHERE id = $1 AND is_active = true
 	`
@@ -48,6 +48,8 @@ func (r *UserRepository) GetByID(id int64) (*domain.User, error) {
 		&user.EmailVerifiedAt,
 		&user.Password,
 		&user.IsActive,
+		&user.IsPlatformAdmin,
+		&user.TwoFactorEnabled,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -62,7 +64,7 @@ func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
 	query := `
 		SELECT 
 			id, name, email, email_verified_at, password, 
-			is_active, created_at, updated_at
+			is_active, is_platform_admin, totp_enabled_at IS NOT NULL, created_at, updated_at
 		FROM users
 		WHERE email = $1 AND is_active = true
 	`
@@ -74,6 +76,8 @@ func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
 		&user.EmailVerifiedAt,
 		&user.Password,
 		&user.IsActive,
+		&user.IsPlatformAdmin,
+		&user.TwoFactorEnabled,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -98,7 +102,7 @@ func (r *UserRepository) GetAll(page, pageSize int) ([]*domain.User, int, error)
 	query := `
 		SELECT 
 			id, name, email, email_verified_at, 
-			is_active, created_at, updated_at
+			is_active, is_platform_admin, totp_enabled_at IS NOT NULL, created_at, updated_at
 		FROM users
 		WHERE is_active = true
 		ORDER BY created_at DESC
@@ -119,6 +123,8 @@ func (r *UserRepository) GetAll(page, pageSize int) ([]*domain.User, int, error)
 			&user.Email,
 			&user.EmailVerifiedAt,
 			&user.IsActive,
+			&user.IsPlatformAdmin,
+			&user.TwoFactorEnabled,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -135,13 +141,14 @@ func (r *UserRepository) GetAll(page, pageSize int) ([]*domain.User, int, error)
 	return users, total, nil
 }
 
-// Update actualiza un usuario
+// Update actualiza un usuario; cambiar el email lo deja sin verificar
 func (r *UserRepository) Update(user *domain.User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, password = $3, is_active = $4, updated_at = NOW()
+		SET name = $1, email = $2, password = $3, is_active = $4, updated_at = NOW(),
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
 		WHERE id = $5 AND is_active = true
-		RETURNING updated_at
+		RETURNING updated_at, email_verified_at
 	`
 	return r.db.DB.QueryRow(
 		query,
@@ -150,7 +157,7 @@ func (r *UserRepository) Update(user *domain.User) error {
 		user.Password,
 		user.IsActive,
 		user.ID,
-	).Scan(&user.UpdatedAt)
+	).Scan(&user.UpdatedAt, &user.EmailVerifiedAt)
 }
 
 // Delete realiza soft delete de un usuario
@@ -181,3 +188,32 @@ func (r *UserRepository) EmailExists(email string) (bool, error) {
 	err := r.db.DB.QueryRow(query, email).Scan(&exists)
 	return exists, err
 }
+
+// IsPlatformAdmin indica si el usuario activo es administrador de la plataforma
+func (r *UserRepository) IsPlatformAdmin(id int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND is_active = true AND is_platform_admin = true)`
+	var admin bool
+	err := r.db.DB.QueryRow(query, id).Scan(&admin)
+	return admin, err
+}
+
+// SetPlatformAdmin otorga o retira el rol de administrador de la plataforma
+func (r *UserRepository) SetPlatformAdmin(id int64, admin bool) error {
+	query := `
+		UPDATE users
+		SET is_platform_admin = $2, updated_at = NOW()
+		WHERE id = $1 AND is_active = true
+	`
+	result, err := r.db.DB.Exec(query, id, admin)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
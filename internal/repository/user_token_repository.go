@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/database"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type UserTokenRepository struct {
+	db *database.Database
+}
+
+func NewUserTokenRepository(db *database.Database) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Create registra un token nuevo e invalida los anteriores del mismo propósito, de modo que solo
+// el último enlace enviado sirve. Si ya se emitió uno hace menos de domain.UserTokenResendSeconds
+// retorna "token recently issued" sin crear otro.
+func (r *UserTokenRepository) Create(token *domain.UserToken) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serializa las solicitudes simultáneas del mismo usuario
+	var userID int64
+	if err := tx.QueryRow(`SELECT id FROM users WHERE id = $1 AND is_active = true FOR UPDATE`, token.UserID).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("error locking user: %w", err)
+	}
+
+	var recent bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM user_tokens
+			WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+				AND created_at > NOW() - make_interval(secs => $3)
+		)`, token.UserID, token.Purpose, domain.UserTokenResendSeconds).Scan(&recent)
+	if err != nil {
+		return fmt.Errorf("error checking user tokens: %w", err)
+	}
+	if recent {
+		return errors.New("token recently issued")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		token.UserID, token.Purpose); err != nil {
+		return fmt.Errorf("error invalidating user tokens: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.Email,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating user token: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// VerifyEmail consume un token de verificación y marca el email como verificado. El token solo
+// sirve si el usuario conserva el email al que se envió.
+func (r *UserTokenRepository) VerifyEmail(hash string) (int64, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userID, email, err := consumeToken(tx, hash, domain.UserTokenVerifyEmail)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2 AND is_active = true
+	`, userID, email)
+	if err != nil {
+		return 0, fmt.Errorf("error verifying email: %w", err)
+	}
+	if err := requireAffected(result, "invalid or expired token"); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return userID, nil
+}
+
+// ResetPassword consume un token de restablecimiento, guarda el hash de la nueva contraseña e
+// invalida los demás enlaces pendientes. Recibir el correo demuestra que el usuario controla la
+// dirección, así que también la marca como verificada.
+func (r *UserTokenRepository) ResetPassword(hash, passwordHash string) (int64, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userID, email, err := consumeToken(tx, hash, domain.UserTokenResetPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET password = $3, email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2 AND is_active = true
+	`, userID, email, passwordHash)
+	if err != nil {
+		return 0, fmt.Errorf("error resetting password: %w", err)
+	}
+	if err := requireAffected(result, "invalid or expired token"); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, domain.UserTokenResetPassword); err != nil {
+		return 0, fmt.Errorf("error invalidating user tokens: %w", err)
+	}
+
+	// Con la contraseña nueva, el bloqueo por logins fallidos ya no protege nada
+	if err := clearAccountLock(tx, email); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return userID, nil
+}
+
+// UnlockAccount consume un token de desbloqueo y borra el contador de logins fallidos del email
+// al que se envió
+func (r *UserTokenRepository) UnlockAccount(hash string) (int64, error) {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userID, email, err := consumeToken(tx, hash, domain.UserTokenUnlockAccount)
+	if err != nil {
+		return 0, err
+	}
+	if err := clearAccountLock(tx, email); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return userID, nil
+}
+
+func clearAccountLock(tx *sql.Tx, email string) error {
+	if _, err := tx.Exec(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2`,
+		domain.LoginScopeAccount, domain.LoginSubject(email)); err != nil {
+		return fmt.Errorf("error clearing login failures: %w", err)
+	}
+	return nil
+}
+
+// consumeToken marca como usado un token vigente y retorna su usuario y el email al que se envió
+func consumeToken(tx *sql.Tx, hash, purpose string) (int64, string, error) {
+	var userID int64
+	var email string
+	err := tx.QueryRow(`
+		UPDATE user_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, email
+	`, hash, purpose).Scan(&userID, &email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", errors.New("invalid or expired token")
+		}
+		return 0, "", fmt.Errorf("error consuming user token: %w", err)
+	}
+	return userID, email, nil
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/utils"
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+// AdminService agrupa las operaciones de los administradores de la plataforma. Cada acción que
+// modifica usuarios o empresas queda en la bitácora admin_actions.
+type AdminService struct {
+	repo        *repository.AdminRepository
+	planRepo    *repository.PlanRepository
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	users       *UserService
+	auth        *AuthService
+}
+
+func NewAdminService(
+	repo *repository.AdminRepository,
+	planRepo *repository.PlanRepository,
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	users *UserService,
+	auth *AuthService,
+) *AdminService {
+	return &AdminService{
+		repo:        repo,
+		planRepo:    planRepo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		users:       users,
+		auth:        auth,
+	}
+}
+
+// GetUsers lista todos los usuarios activos
+func (s *AdminService) GetUsers(page, pageSize int) (*domain.UserListResponse, error) {
+	return s.users.GetAll(page, pageSize)
+}
+
+// GetUser obtiene cualquier usuario activo
+func (s *AdminService) GetUser(id int64) (*domain.User, error) {
+	return s.users.GetByID(id)
+}
+
+// UpdateUser actualiza cualquier usuario, incluido el rol de administrador. Un administrador no
+// puede desactivarse ni quitarse el rol a sí mismo. Desactivar al usuario o cambiar su contraseña
+// cierra sus sesiones.
+func (s *AdminService) UpdateUser(adminID, id int64, req *domain.UpdateUserRequest, ip string) (*domain.User, error) {
+	if id == adminID {
+		if req.IsActive != nil && !*req.IsActive {
+			return nil, errors.New("cannot deactivate your own user")
+		}
+		if req.IsPlatformAdmin != nil && !*req.IsPlatformAdmin {
+			return nil, errors.New("cannot remove your own platform admin role")
+		}
+	}
+
+	user, err := s.users.Update(id, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.IsPlatformAdmin != nil {
+		if err := s.userRepo.SetPlatformAdmin(id, *req.IsPlatformAdmin); err != nil {
+			return nil, err
+		}
+		user.IsPlatformAdmin = *req.IsPlatformAdmin
+	}
+	if !user.IsActive || req.Password != nil {
+		if _, err := s.sessionRepo.RevokeAll(id, domain.SessionRevokedByAdmin); err != nil {
+			return nil, err
+		}
+	}
+
+	s.log(adminID, domain.AdminActionUserUpdate, domain.AdminTargetUser, id, ip, map[string]any{
+		"fields": updatedUserFields(req),
+	})
+	return user, nil
+}
+
+// DeleteUser desactiva un usuario (soft delete) y cierra sus sesiones
+func (s *AdminService) DeleteUser(adminID, id int64, ip string) error {
+	if id == adminID {
+		return errors.New("cannot delete your own user")
+	}
+	if err := s.users.Delete(id); err != nil {
+		return err
+	}
+	if _, err := s.sessionRepo.RevokeAll(id, domain.SessionRevokedByAdmin); err != nil {
+		return err
+	}
+
+	s.log(adminID, domain.AdminActionUserDelete, domain.AdminTargetUser, id, ip, nil)
+	return nil
+}
+
+// Impersonate abre una sesión corta del usuario para soporte. No se puede suplantar a otro
+// administrador; la sesión aparece en la lista de sesiones del usuario y sus peticiones de
+// escritura quedan en la bitácora.
+func (s *AdminService) Impersonate(adminID, userID int64, req *domain.ImpersonateRequest, client domain.SessionClient) (*domain.LoginResponse, error) {
+	if userID == adminID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsPlatformAdmin {
+		return nil, errors.New("cannot impersonate a platform admin")
+	}
+
+	resp, err := s.auth.Impersonate(user, adminID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(adminID, domain.AdminActionUserImpersonate, domain.AdminTargetUser, userID, client.IPAddress, map[string]any{
+		"reason":     req.Reason,
+		"session_id": resp.SessionID,
+		"expires_at": resp.ExpiresAt,
+	})
+	return resp, nil
+}
+
+// GetCompaniesUsage lista el uso por empresa (miembros, API keys y documentos)
+func (s *AdminService) GetCompaniesUsage(suspended bool, page, pageSize int) (*domain.CompanyUsageListResponse, error) {
+	page, pageSize = utils.NormalizePagination(page, pageSize)
+	companies, total, err := s.repo.GetCompaniesUsage(suspended, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CompanyUsageListResponse{
+		Companies: companies,
+		Total:     total,
+		Page:      page,
+		PageSize:  pageSize,
+	}, nil
+}
+
+// GetCompanyUsage obtiene el uso de una empresa
+func (s *AdminService) GetCompanyUsage(companyID int64) (*domain.CompanyUsage, error) {
+	return s.repo.GetCompanyUsage(companyID)
+}
+
+// SuspendCompany suspende una empresa: sus miembros pueden consultar, pero no firmar ni enviar
+// documentos a DIAN
+func (s *AdminService) SuspendCompany(adminID, companyID int64, req *domain.SuspendCompanyRequest, ip string) (*domain.CompanyUsage, error) {
+	if err := s.repo.SetCompanySuspension(companyID, adminID, &req.Reason); err != nil {
+		return nil, err
+	}
+	s.log(adminID, domain.AdminActionCompanySuspend, domain.AdminTargetCompany, companyID, ip, map[string]any{
+		"reason": req.Reason,
+	})
+	return s.repo.GetCompanyUsage(companyID)
+}
+
+// UnsuspendCompany reactiva una empresa suspendida
+func (s *AdminService) UnsuspendCompany(adminID, companyID int64, ip string) (*domain.CompanyUsage, error) {
+	if err := s.repo.SetCompanySuspension(companyID, adminID, nil); err != nil {
+		return nil, err
+	}
+	s.log(adminID, domain.AdminActionCompanyUnsuspend, domain.AdminTargetCompany, companyID, ip, nil)
+	return s.repo.GetCompanyUsage(companyID)
+}
+
+// GetPlans lista los planes disponibles
+func (s *AdminService) GetPlans() ([]domain.Plan, error) {
+	return s.planRepo.GetAll()
+}
+
+// SetCompanyPlan cambia el plan de una empresa; las nuevas cuotas aplican desde la siguiente
+// petición, y el uso del mes en curso se conserva
+func (s *AdminService) SetCompanyPlan(adminID, companyID int64, req *domain.SetCompanyPlanRequest, ip string) (*domain.CompanyUsage, error) {
+	if _, err := s.planRepo.GetByCode(req.Plan); err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetCompanyUsage(companyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetCompanyPlan(companyID, adminID, req.Plan); err != nil {
+		return nil, err
+	}
+	s.log(adminID, domain.AdminActionCompanyPlan, domain.AdminTargetCompany, companyID, ip, map[string]any{
+		"from": previous.Plan,
+		"to":   req.Plan,
+	})
+	return s.repo.GetCompanyUsage(companyID)
+}
+
+// GetCompanyUsageHistory retorna el plan de una empresa y su uso de los últimos meses
+func (s *AdminService) GetCompanyUsageHistory(companyID int64, months int) (*domain.CompanyUsageHistory, error) {
+	if months <= 0 || months > 24 {
+		months = 12
+	}
+	return s.planRepo.GetUsageHistory(companyID, months)
+}
+
+// GetActions lista la bitácora de administración
+func (s *AdminService) GetActions(filter domain.AdminActionFilter, page, pageSize int) (*domain.AdminActionListResponse, error) {
+	page, pageSize = utils.NormalizePagination(page, pageSize)
+	actions, total, err := s.repo.GetActions(filter, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AdminActionListResponse{
+		Actions:  actions,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// log registra la acción en la bitácora; un fallo no revierte la acción ya hecha
+func (s *AdminService) log(adminID int64, action, targetType string, targetID int64, ip string, details map[string]any) {
+	entry := &domain.AdminAction{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if ip != "" {
+		entry.IPAddress = &ip
+	}
+	if details != nil {
+		if raw, err := json.Marshal(details); err == nil {
+			entry.Details = raw
+		}
+	}
+	if err := s.repo.LogAction(entry); err != nil {
+		log.Printf("Warning: failed to log admin action %s on %s %d: %v", action, targetType, targetID, err)
+	}
+}
+
+// updatedUserFields lista los campos que cambia la solicitud (sin sus valores)
+func updatedUserFields(req *domain.UpdateUserRequest) []string {
+	fields := []string{}
+	if req.Name != nil {
+		fields = append(fields, "name")
+	}
+	if req.Email != nil {
+		fields = append(fields, "email")
+	}
+	if req.Password != nil {
+		fields = append(fields, "password")
+	}
+	if req.IsActive != nil {
+		fields = append(fields, "is_active")
+	}
+	if req.IsPlatformAdmin != nil {
+		fields = append(fields, "is_platform_admin")
+	}
+	return fields
+}
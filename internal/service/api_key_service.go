@@ -0,0 +1,83 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
+	"fmt"
+)
+
+// apiKeyDisplayLength es la cantidad de caracteres de la key que se guardan para identificarla
+const apiKeyDisplayLength = 12
+
+type APIKeyService struct {
+	repo        *repository.APIKeyRepository
+	companyRepo *repository.CompanyRepository
+	authz       *Authorizer
+}
+
+func NewAPIKeyService(repo *repository.APIKeyRepository, companyRepo *repository.CompanyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo:        repo,
+		companyRepo: companyRepo,
+		authz:       NewAuthorizer(companyRepo),
+	}
+}
+
+// Create genera una API key para la empresa; la key en claro solo se retorna aquí.
+// Al usarla también se exige que el rol de su creador tenga el permiso.
+func (s *APIKeyService) Create(companyID, userID int64, req *domain.CreateAPIKeyRequest) (*domain.CreateAPIKeyResponse, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionAPIKeysManage); err != nil {
+		return nil, err
+	}
+
+	token, err := crypto.RandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating api key: %w", err)
+	}
+	plain := domain.APIKeyPrefix + token
+
+	key := &domain.APIKey{
+		CompanyID:   companyID,
+		UserID:      userID,
+		Name:        req.Name,
+		KeyPrefix:   plain[:apiKeyDisplayLength],
+		Permissions: uniquePermissions(req.Permissions),
+		RateLimit:   req.RateLimit,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err := s.repo.Create(key, crypto.HashToken(plain)); err != nil {
+		return nil, fmt.Errorf("error creating api key: %w", err)
+	}
+
+	return &domain.CreateAPIKeyResponse{APIKey: key, Key: plain}, nil
+}
+
+// GetByCompanyID lista las API keys de la empresa (sin la key)
+func (s *APIKeyService) GetByCompanyID(companyID, userID int64) ([]domain.APIKey, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionAPIKeysManage); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByCompanyID(companyID)
+}
+
+// Revoke revoca una API key de la empresa; deja de funcionar de inmediato
+func (s *APIKeyService) Revoke(companyID, keyID, userID int64) error {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionAPIKeysManage); err != nil {
+		return err
+	}
+	return s.repo.Revoke(keyID, companyID)
+}
+
+// uniquePermissions elimina permisos repetidos conservando el orden
+func uniquePermissions(permissions []string) []string {
+	seen := make(map[string]bool, len(permissions))
+	unique := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
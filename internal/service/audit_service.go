@@ -0,0 +1,53 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/utils"
+	"errors"
+)
+
+// AuditService consulta el historial de cambios que los triggers de la base de datos registran
+// en audit_log. El historial no se puede modificar desde la aplicación.
+type AuditService struct {
+	repo  *repository.AuditRepository
+	authz *Authorizer
+}
+
+func NewAuditService(repo *repository.AuditRepository, companyRepo *repository.CompanyRepository) *AuditService {
+	return &AuditService{
+		repo:  repo,
+		authz: NewAuthorizer(companyRepo),
+	}
+}
+
+// GetByCompanyID lista los cambios de las tablas de negocio de una empresa
+func (s *AuditService) GetByCompanyID(filter domain.AuditFilter, userID int64, page, pageSize int) (*domain.AuditListResponse, error) {
+	if _, err := s.authz.Authorize(filter.CompanyID, userID, domain.PermissionAuditRead); err != nil {
+		return nil, err
+	}
+	if filter.TableName != "" && !domain.IsAuditedTable(filter.TableName) {
+		return nil, errors.New("invalid table")
+	}
+	return s.list(filter, page, pageSize)
+}
+
+// GetAll lista todo audit_log, incluidos los eventos de autenticación (administradores de la
+// plataforma)
+func (s *AuditService) GetAll(filter domain.AuditFilter, page, pageSize int) (*domain.AuditListResponse, error) {
+	return s.list(filter, page, pageSize)
+}
+
+func (s *AuditService) list(filter domain.AuditFilter, page, pageSize int) (*domain.AuditListResponse, error) {
+	page, pageSize = utils.NormalizePagination(page, pageSize)
+	entries, total, err := s.repo.List(filter, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AuditListResponse{
+		Entries:  entries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
@@ -3,8 +3,11 @@ package service
 import (
 	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/mail"
 	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -13,32 +16,58 @@ import (
 )
 
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtConfig *config.JWTConfig
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	tokenRepo   *repository.UserTokenRepository
+	twoFactor   *repository.TwoFactorRepository
+	attemptRepo *repository.LoginAttemptRepository
+	auditRepo   *repository.AuditRepository
+	mailer      mail.Mailer
+	jwtConfig   *config.JWTConfig
+	authConfig  *config.AuthConfig
+	appURL      string
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtConfig *config.JWTConfig) *AuthService {
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	tokenRepo *repository.UserTokenRepository,
+	twoFactor *repository.TwoFactorRepository,
+	attemptRepo *repository.LoginAttemptRepository,
+	auditRepo *repository.AuditRepository,
+	mailer mail.Mailer,
+	cfg *config.Config,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtConfig: jwtConfig,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		twoFactor:   twoFactor,
+		attemptRepo: attemptRepo,
+		auditRepo:   auditRepo,
+		mailer:      mailer,
+		jwtConfig:   &cfg.JWT,
+		authConfig:  &cfg.Auth,
+		appURL:      cfg.Mail.AppURL,
 	}
 }
 
-// Register registra un nuevo usuario
-func (s *AuthService) Register(req *domain.RegisterRequest) (*domain.LoginResponse, error) {
+// Register registra un nuevo usuario y le envía el enlace de verificación de email. Con
+// UNVERIFIED_LOGIN=block no abre sesión: retorna solo el usuario.
+func (s *AuthService) Register(req *domain.RegisterRequest, client domain.SessionClient) (*domain.User, *domain.LoginResponse, error) {
 	// Verificar si el email ya existe
 	exists, err := s.userRepo.EmailExists(req.Email)
 	if err != nil {
-		return nil, fmt.Errorf("error checking email: %w", err)
+		return nil, nil, fmt.Errorf("error checking email: %w", err)
 	}
 	if exists {
-		return nil, fmt.Errorf("email already exists")
+		return nil, nil, fmt.Errorf("email already exists")
 	}
 
 	// Hash de la contraseña
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, fmt.Errorf("error hashing password: %w", err)
+		return nil, nil, fmt.Errorf("error hashing password: %w", err)
 	}
 
 	// Crear usuario
@@ -52,56 +81,513 @@ func (s *AuthService) Register(req *domain.RegisterRequest) (*domain.LoginRespon
 	if err := s.userRepo.Create(user); err != nil {
 		// Si es error de duplicate key, retornar mensaje amigable
 		if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique constraint") {
-			return nil, fmt.Errorf("email already exists")
+			return nil, nil, fmt.Errorf("email already exists")
 		}
-		return nil, fmt.Errorf("error creating user: %w", err)
+		return nil, nil, fmt.Errorf("error creating user: %w", err)
 	}
 
-	// Generar JWT token
-	token, err := s.generateJWT(user.ID, user.Email)
-	if err != nil {
-		return nil, fmt.Errorf("error generating token: %w", err)
+	// Un fallo al emitir el enlace no revierte el registro; se puede pedir de nuevo
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("Warning: failed to issue verification email for user %d: %v", user.ID, err)
 	}
 
-	return &domain.LoginResponse{
-		User:  user,
-		Token: token,
-	}, nil
+	if s.authConfig.UnverifiedLogin == config.UnverifiedLoginBlock {
+		return user, nil, nil
+	}
+
+	// Abrir sesión con access token y refresh token
+	resp, err := s.startSession(user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, resp, nil
 }
 
-// Login autentica un usuario
-func (s *AuthService) Login(req *domain.LoginRequest) (*domain.LoginResponse, error) {
+// Login autentica un usuario. Si tiene 2FA activo no abre sesión: retorna un reto que se
+// completa con LoginTwoFactor. Con demasiados fallos de la cuenta o la IP retorna un
+// *domain.LoginThrottleError sin verificar la contraseña.
+func (s *AuthService) Login(req *domain.LoginRequest, client domain.SessionClient) (*domain.LoginResponse, *domain.TwoFactorChallengeResponse, error) {
+	attempt, err := s.claimLoginAttempt(domain.LoginSubject(req.Email), client.IPAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Buscar usuario por email
 	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		s.recordLoginFailure(attempt, nil, client)
+		return nil, nil, fmt.Errorf("invalid credentials")
 	}
 
 	// Verificar contraseña
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		s.recordLoginFailure(attempt, user, client)
+		return nil, nil, fmt.Errorf("invalid credentials")
+	}
+
+	if err := s.checkVerified(user); err != nil {
+		s.releaseLoginAttempt(attempt)
+		return nil, nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		// El contador de la cuenta se reinicia al completar el segundo paso
+		s.releaseLoginAttempt(attempt)
+		challenge, err := s.startChallenge(user, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+	s.recordLoginSuccess(attempt, user, client)
+
+	// Abrir sesión con access token y refresh token
+	resp, err := s.startSession(user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, nil, nil
+}
+
+// LoginTwoFactor completa el login con el código TOTP o un código de recuperación. Cada reto
+// admite domain.TwoFactorMaxAttempts códigos y vence en domain.TwoFactorChallengeMinutes.
+func (s *AuthService) LoginTwoFactor(req *domain.TwoFactorLoginRequest, client domain.SessionClient) (*domain.LoginResponse, error) {
+	challenge, err := s.twoFactor.AttemptChallenge(crypto.HashToken(req.ChallengeToken))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired challenge")
+	}
+
+	// Un código erróneo indica que la contraseña ya se conoce: cuenta como login fallido
+	attempt, err := s.claimLoginAttempt(domain.LoginSubject(user.Email), client.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	usedRecovery, err := verifyTwoFactorCode(s.twoFactor, user.ID, req.Code, true)
+	if err != nil {
+		if err.Error() == "invalid two-factor code" {
+			s.recordLoginFailure(attempt, user, client)
+			return nil, err
+		}
+		s.releaseLoginAttempt(attempt)
+		if err.Error() == "two-factor authentication not enabled" {
+			// 2FA se desactivó entre el primer y el segundo paso
+			return nil, fmt.Errorf("invalid or expired challenge")
+		}
+		return nil, err
+	}
+	if err := s.twoFactor.CompleteChallenge(challenge.ID); err != nil {
+		s.releaseLoginAttempt(attempt)
+		return nil, err
+	}
+	s.recordLoginSuccess(attempt, user, client)
+
+	if usedRecovery {
+		log.Printf("User %d logged in with a recovery code", user.ID)
+	}
+	return s.startSession(user, client)
+}
+
+// Refresh rota el refresh token y emite un access token nuevo para la misma sesión. Un refresh
+// token ya rotado que se vuelve a presentar cierra la sesión (posible robo del token).
+func (s *AuthService) Refresh(refreshToken string, client domain.SessionClient) (*domain.LoginResponse, error) {
+	oldHash := crypto.HashToken(refreshToken)
+
+	session, err := s.sessionRepo.GetActiveByRefreshHash(oldHash)
+	if err != nil {
+		if err.Error() != "session not found" {
+			return nil, fmt.Errorf("error getting session: %w", err)
+		}
+		if closed, err := s.sessionRepo.RevokeByPreviousHash(oldHash); err != nil {
+			log.Printf("Warning: failed to revoke session after refresh token reuse: %v", err)
+		} else if closed > 0 {
+			log.Printf("Warning: rotated refresh token reused, %d session(s) revoked", closed)
+		}
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	// Las sesiones de suplantación no se renuevan
+	if session.ImpersonatorID != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	user, err := s.userRepo.GetByID(session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	// Con UNVERIFIED_LOGIN=limited la sesión deja de renovarse al vencer el plazo
+	if err := s.checkVerified(user); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err = s.fillTokens(session, client)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessionRepo.Rotate(session, oldHash)
+	if err != nil {
+		return nil, fmt.Errorf("error rotating session: %w", err)
+	}
+	if !rotated {
+		// Otra petición rotó el mismo refresh token primero
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	return s.loginResponse(user, session, refreshToken)
+}
+
+// Logout cierra la sesión del access token actual
+func (s *AuthService) Logout(userID, sessionID int64) error {
+	return s.sessionRepo.Revoke(sessionID, userID, domain.SessionRevokedLogout)
+}
+
+// LogoutAll cierra todas las sesiones del usuario (todos los dispositivos)
+func (s *AuthService) LogoutAll(userID int64) (int, error) {
+	return s.sessionRepo.RevokeAll(userID, domain.SessionRevokedLogoutAll)
+}
+
+// ListSessions lista las sesiones vigentes del usuario marcando la actual
+func (s *AuthService) ListSessions(userID, currentSessionID int64) ([]domain.Session, error) {
+	sessions, err := s.sessionRepo.ListActive(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession cierra una sesión del usuario (p. ej. un dispositivo perdido)
+func (s *AuthService) RevokeSession(userID, sessionID int64) error {
+	return s.sessionRepo.Revoke(sessionID, userID, domain.SessionRevokedByUser)
+}
+
+// VerifyEmail marca el email como verificado con el token enviado por correo
+func (s *AuthService) VerifyEmail(token string) error {
+	_, err := s.tokenRepo.VerifyEmail(crypto.HashToken(token))
+	return err
+}
+
+// ResendVerification envía un enlace de verificación nuevo si el email pertenece a una cuenta
+// activa sin verificar. Se procesa en segundo plano y no retorna error, para que ni la respuesta
+// ni su tiempo revelen si la cuenta existe.
+func (s *AuthService) ResendVerification(email string) {
+	go func() {
+		user, err := s.userRepo.GetByEmail(email)
+		if err != nil || user.EmailVerifiedAt != nil {
+			return
+		}
+		if err := s.sendVerification(user); err != nil && err.Error() != "token recently issued" {
+			log.Printf("Warning: failed to issue verification email for user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+// ForgotPassword envía un enlace para restablecer la contraseña si el email pertenece a una
+// cuenta activa. Igual que ResendVerification, no revela si la cuenta existe.
+func (s *AuthService) ForgotPassword(email string) {
+	go func() {
+		user, err := s.userRepo.GetByEmail(email)
+		if err != nil {
+			return
+		}
+		token, err := s.issueToken(user, domain.UserTokenResetPassword,
+			time.Duration(s.authConfig.ResetTokenMinutes)*time.Minute)
+		if err != nil {
+			if err.Error() != "token recently issued" {
+				log.Printf("Warning: failed to issue password reset for user %d: %v", user.ID, err)
+			}
+			return
+		}
+		deliverMail(s.mailer, mail.Message{
+			To:      user.Email,
+			Subject: "Restablece tu contraseña",
+			Body: fmt.Sprintf("Hola %s,\n\n"+
+				"Recibimos una solicitud para restablecer la contraseña de tu cuenta. Para elegir una nueva abre el siguiente enlace:\n\n"+
+				"%s/reset-password?token=%s\n\n"+
+				"O envía este token con la nueva contraseña a POST /api/v1/auth/reset-password:\n\n%s\n\n"+
+				"El enlace vence en %d minutos y solo se puede usar una vez. Al restablecer la contraseña se cerrarán todas tus sesiones.\n"+
+				"Si no solicitaste el cambio, ignora este mensaje: tu contraseña actual sigue vigente.\n",
+				user.Name, s.appURL, token, token, s.authConfig.ResetTokenMinutes),
+		})
+	}()
+}
+
+// ResetPassword cambia la contraseña con el token enviado por correo y cierra todas las sesiones
+// del usuario
+func (s *AuthService) ResetPassword(req *domain.ResetPasswordRequest) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
 	}
 
-	// Generar JWT token
-	token, err := s.generateJWT(user.ID, user.Email)
+	userID, err := s.tokenRepo.ResetPassword(crypto.HashToken(req.Token), string(hashedPassword))
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.sessionRepo.RevokeAll(userID, domain.SessionRevokedReset); err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+	return nil
+}
+
+// UpdateProfile actualiza el nombre o el email del propio usuario. Cambiar el email exige la
+// contraseña actual (una sesión robada no basta para desviar los correos de restablecimiento),
+// deja la dirección sin verificar y envía el enlace de verificación a la nueva; la anterior
+// recibe un aviso del cambio.
+func (s *AuthService) UpdateProfile(userID int64, req *domain.UpdateProfileRequest) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+
+	previousEmail := user.Email
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged {
+		if req.CurrentPassword == nil || *req.CurrentPassword == "" {
+			return nil, fmt.Errorf("current password is required to change the email")
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(*req.CurrentPassword)); err != nil {
+			return nil, fmt.Errorf("current password is incorrect")
+		}
+		exists, err := s.userRepo.EmailExists(*req.Email)
+		if err != nil {
+			return nil, fmt.Errorf("error checking email: %w", err)
+		}
+		if exists {
+			return nil, fmt.Errorf("email already exists")
+		}
+		user.Email = *req.Email
+	}
+
+	// Update limpia email_verified_at cuando cambia el email
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := s.sendVerification(user); err != nil {
+			log.Printf("Warning: failed to issue verification email for user %d: %v", user.ID, err)
+		}
+		deliverMail(s.mailer, mail.Message{
+			To:      previousEmail,
+			Subject: "Tu correo electrónico fue cambiado",
+			Body: fmt.Sprintf("Hola %s,\n\n"+
+				"El correo electrónico de tu cuenta se cambió a %s. A partir de ahora los avisos y los enlaces "+
+				"para restablecer la contraseña se enviarán a esa dirección.\n\n"+
+				"Si no hiciste este cambio, contacta al administrador de la plataforma de inmediato.\n",
+				user.Name, user.Email),
+		})
+	}
+
+	return user, nil
+}
+
+// Impersonate abre una sesión del usuario a nombre de un administrador de la plataforma. Dura
+// domain.ImpersonationMinutes, no se renueva y su access token lleva el claim imp.
+func (s *AuthService) Impersonate(user *domain.User, adminID int64, client domain.SessionClient) (*domain.LoginResponse, error) {
+	session := &domain.Session{UserID: user.ID, ImpersonatorID: &adminID}
+	if _, err := s.fillTokens(session, client); err != nil {
+		return nil, err
+	}
+	session.AccessExpiresAt = time.Now().Add(domain.ImpersonationMinutes * time.Minute)
+	session.ExpiresAt = session.AccessExpiresAt
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+
+	resp, err := s.loginResponse(user, session, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.ImpersonatorID = &adminID
+	return resp, nil
+}
+
+// checkVerified aplica UNVERIFIED_LOGIN a una cuenta con el email sin verificar
+func (s *AuthService) checkVerified(user *domain.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	switch s.authConfig.UnverifiedLogin {
+	case config.UnverifiedLoginBlock:
+		return fmt.Errorf("email not verified")
+	case config.UnverifiedLoginLimited:
+		if time.Now().After(*s.verificationDeadline(user)) {
+			return fmt.Errorf("email not verified")
+		}
+	}
+	return nil
+}
+
+// verificationDeadline retorna hasta cuándo puede iniciar sesión una cuenta sin verificar con
+// UNVERIFIED_LOGIN=limited; nil si no aplica
+func (s *AuthService) verificationDeadline(user *domain.User) *time.Time {
+	if user.EmailVerifiedAt != nil || s.authConfig.UnverifiedLogin != config.UnverifiedLoginLimited {
+		return nil
+	}
+	deadline := user.CreatedAt.Add(time.Duration(s.authConfig.UnverifiedGraceHours) * time.Hour)
+	return &deadline
+}
+
+// sendVerification emite un token de verificación y envía el enlace al email del usuario
+func (s *AuthService) sendVerification(user *domain.User) error {
+	token, err := s.issueToken(user, domain.UserTokenVerifyEmail,
+		time.Duration(s.authConfig.VerifyTokenHours)*time.Hour)
+	if err != nil {
+		return err
+	}
+	deliverMail(s.mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Verifica tu correo electrónico",
+		Body: fmt.Sprintf("Hola %s,\n\n"+
+			"Para verificar tu correo electrónico abre el siguiente enlace:\n\n"+
+			"%s/verify-email?token=%s\n\n"+
+			"O envía este token a POST /api/v1/auth/verify-email:\n\n%s\n\n"+
+			"El enlace vence en %d horas. Si no creaste una cuenta, ignora este mensaje.\n",
+			user.Name, s.appURL, token, token, s.authConfig.VerifyTokenHours),
+	})
+	return nil
+}
+
+// issueToken genera un token de un solo uso para el usuario; retorna el token en claro (solo se
+// guarda su hash)
+func (s *AuthService) issueToken(user *domain.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := crypto.RandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	if err := s.tokenRepo.Create(&domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: crypto.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// deliverMail envía el correo en segundo plano; un fallo del servidor SMTP solo queda en el log
+func deliverMail(mailer mail.Mailer, msg mail.Message) {
+	go func() {
+		if err := mailer.Send(msg); err != nil {
+			log.Printf("Warning: failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// startChallenge registra el segundo paso pendiente de un login con 2FA
+func (s *AuthService) startChallenge(user *domain.User, client domain.SessionClient) (*domain.TwoFactorChallengeResponse, error) {
+	token, err := crypto.RandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	challenge := &domain.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: crypto.HashToken(token),
+		ExpiresAt: time.Now().Add(domain.TwoFactorChallengeMinutes * time.Minute),
+	}
+	if client.UserAgent != "" {
+		challenge.UserAgent = &client.UserAgent
+	}
+	if client.IPAddress != "" {
+		challenge.IPAddress = &client.IPAddress
+	}
+	if err := s.twoFactor.CreateChallenge(challenge); err != nil {
+		return nil, fmt.Errorf("error creating login challenge: %w", err)
+	}
+
+	return &domain.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         challenge.ExpiresAt,
+	}, nil
+}
+
+// startSession registra una sesión nueva para el usuario
+func (s *AuthService) startSession(user *domain.User, client domain.SessionClient) (*domain.LoginResponse, error) {
+	session := &domain.Session{UserID: user.ID}
+	refreshToken, err := s.fillTokens(session, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("error creating session: %w", err)
+	}
+	return s.loginResponse(user, session, refreshToken)
+}
+
+// fillTokens genera un refresh token nuevo y el jti del próximo access token de la sesión;
+// retorna el refresh token en claro (solo se guarda su hash)
+func (s *AuthService) fillTokens(session *domain.Session, client domain.SessionClient) (string, error) {
+	refreshToken, err := crypto.RandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	jti, err := crypto.RandomID()
+	if err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+
+	now := time.Now()
+	session.RefreshTokenHash = crypto.HashToken(refreshToken)
+	session.AccessJTI = jti
+	session.AccessExpiresAt = now.Add(time.Minute * time.Duration(s.jwtConfig.AccessTokenMinutes))
+	session.ExpiresAt = now.AddDate(0, 0, s.jwtConfig.RefreshTokenDays)
+	if client.UserAgent != "" {
+		session.UserAgent = &client.UserAgent
+	}
+	if client.IPAddress != "" {
+		session.IPAddress = &client.IPAddress
+	}
+	return refreshToken, nil
+}
+
+// loginResponse firma el access token de la sesión y arma la respuesta
+func (s *AuthService) loginResponse(user *domain.User, session *domain.Session, refreshToken string) (*domain.LoginResponse, error) {
+	token, err := s.generateJWT(user.ID, user.Email, session)
 	if err != nil {
 		return nil, fmt.Errorf("error generating token: %w", err)
 	}
 
 	return &domain.LoginResponse{
-		User:  user,
-		Token: token,
+		User:                 user,
+		Token:                token,
+		ExpiresAt:            session.AccessExpiresAt,
+		RefreshToken:         refreshToken,
+		RefreshExpiresAt:     session.ExpiresAt,
+		SessionID:            session.ID,
+		VerificationDeadline: s.verificationDeadline(user),
 	}, nil
 }
 
-// generateJWT genera un token JWT
-func (s *AuthService) generateJWT(userID int64, email string) (string, error) {
+// generateJWT genera el access token (JWT) de una sesión; jti permite revocarlo antes de exp
+func (s *AuthService) generateJWT(userID int64, email string, session *domain.Session) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
-		"exp":     time.Now().Add(time.Hour * time.Duration(s.jwtConfig.Expiration)).Unix(),
+		"sid":     session.ID,
+		"jti":     session.AccessJTI,
+		"exp":     session.AccessExpiresAt.Unix(),
 		"iat":     time.Now().Unix(),
 	}
+	if session.ImpersonatorID != nil {
+		claims["imp"] = *session.ImpersonatorID
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.jwtConfig.Secret))
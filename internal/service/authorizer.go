@@ -0,0 +1,81 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"errors"
+)
+
+// Authorizer es la única verificación de acceso a las empresas: el usuario debe ser miembro y
+// su rol debe tener el permiso de la acción (ver domain.RoleHasPermission)
+type Authorizer struct {
+	companyRepo CompanyMembers
+}
+
+// CompanyMembers son las consultas de empresa y membresía que usa el Authorizer
+// (*repository.CompanyRepository)
+type CompanyMembers interface {
+	GetByID(id int64) (*domain.Company, error)
+	GetMemberRole(companyID, userID int64) (string, error)
+	MemberTwoFactorEnabled(companyID, userID int64) (bool, error)
+}
+
+func NewAuthorizer(companyRepo CompanyMembers) *Authorizer {
+	return &Authorizer{companyRepo: companyRepo}
+}
+
+// Authorize obtiene la empresa si el usuario puede realizar la acción. Retorna "company not
+// found" si no existe y *domain.AccessError si no es miembro o su rol no tiene el permiso.
+func (a *Authorizer) Authorize(companyID, userID int64, permission string) (*domain.Company, error) {
+	company, err := a.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := a.companyRepo.GetMemberRole(companyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, &domain.AccessError{Resource: "company", Permission: permission}
+	}
+	if !domain.RoleHasPermission(role, permission) {
+		return nil, &domain.AccessError{Resource: "company", Role: role, Permission: permission}
+	}
+
+	company.Role = role
+	return company, nil
+}
+
+// RequireTwoFactor aplica la política de 2FA de la empresa a firmar, enviar a DIAN y
+// administrar certificados: si la empresa la exige, el usuario debe tener 2FA activo. Las API
+// keys se evalúan con el usuario que las creó.
+func (a *Authorizer) RequireTwoFactor(company *domain.Company, userID int64) error {
+	if !company.RequireTwoFactor {
+		return nil
+	}
+	enabled, err := a.companyRepo.MemberTwoFactorEnabled(company.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("two-factor authentication required")
+	}
+	return nil
+}
+
+// AuthorizeResource es Authorize para un recurso de la empresa: la empresa inexistente o la
+// falta de acceso se reportan como "unauthorized access to <resource>"
+func (a *Authorizer) AuthorizeResource(companyID, userID int64, permission, resource string) (*domain.Company, error) {
+	company, err := a.Authorize(companyID, userID, permission)
+	if err != nil {
+		var denied *domain.AccessError
+		if errors.As(err, &denied) {
+			return nil, &domain.AccessError{Resource: resource, Role: denied.Role, Permission: permission}
+		}
+		if err.Error() == "company not found" {
+			return nil, &domain.AccessError{Resource: resource, Permission: permission}
+		}
+		return nil, err
+	}
+	return company, nil
+}
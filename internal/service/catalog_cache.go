@@ -0,0 +1,60 @@
+package service
+
+import (
+	"apidian-go/internal/repository"
+	"strconv"
+)
+
+// catalogCache evita consultar el mismo código/nombre de catálogo en cada fila de una importación
+type catalogCache struct {
+	repo           *repository.CatalogRepository
+	ids            map[string]int
+	municipalities map[string][3]int
+}
+
+func newCatalogCache(repo *repository.CatalogRepository) *catalogCache {
+	return &catalogCache{
+		repo:           repo,
+		ids:            map[string]int{},
+		municipalities: map[string][3]int{},
+	}
+}
+
+// resolve obtiene el id de un registro de catálogo por código DIAN o nombre
+func (c *catalogCache) resolve(table, value string) (int, error) {
+	key := table + ":" + value
+	if id, ok := c.ids[key]; ok {
+		return id, nil
+	}
+	id, err := c.repo.ResolveID(table, value)
+	if err != nil {
+		return 0, err
+	}
+	c.ids[key] = id
+	return id, nil
+}
+
+// resolveMunicipality retorna municipio, departamento y país a partir del municipio
+func (c *catalogCache) resolveMunicipality(value string) (int, int, int, error) {
+	if ids, ok := c.municipalities[value]; ok {
+		return ids[0], ids[1], ids[2], nil
+	}
+	municipalityID, departmentID, countryID, err := c.repo.ResolveMunicipality(value)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	c.municipalities[value] = [3]int{municipalityID, departmentID, countryID}
+	return municipalityID, departmentID, countryID, nil
+}
+
+// stringValue retorna "" para punteros nil (exportación)
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func formatDecimal(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
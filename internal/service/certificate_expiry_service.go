@@ -0,0 +1,149 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/notify"
+	"apidian-go/internal/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+// certificateExpiryThresholds son los días antes del vencimiento en que se avisa; 0 = vencido
+var certificateExpiryThresholds = []int{30, 15, 7, 0}
+
+// CertificateExpiryService revisa periódicamente los certificados: activa los programados
+// cuya fecha llegó y avisa cuando los activos cruzan un umbral de vencimiento. Cada umbral
+// se notifica una sola vez por certificado.
+type CertificateExpiryService struct {
+	certRepo    *repository.CertificateRepository
+	companyRepo *repository.CompanyRepository
+	notifier    notify.Notifier
+}
+
+func NewCertificateExpiryService(certRepo *repository.CertificateRepository, companyRepo *repository.CompanyRepository, notifier notify.Notifier) *CertificateExpiryService {
+	return &CertificateExpiryService{
+		certRepo:    certRepo,
+		companyRepo: companyRepo,
+		notifier:    notifier,
+	}
+}
+
+// Run ejecuta la revisión de inmediato y luego cada interval (bloqueante, usar con go)
+func (s *CertificateExpiryService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.ActivateScheduled(time.Now())
+		if sent, err := s.CheckExpirations(time.Now()); err != nil {
+			log.Printf("Warning: certificate expiry check failed: %v", err)
+		} else if sent > 0 {
+			log.Printf("Certificate expiry check: %d alert(s) sent", sent)
+		}
+		<-ticker.C
+	}
+}
+
+// ActivateScheduled hace el cambio de certificado programado y avisa a cada empresa
+func (s *CertificateExpiryService) ActivateScheduled(now time.Time) {
+	activated, err := s.certRepo.ActivateScheduled(now)
+	if err != nil {
+		log.Printf("Warning: scheduled certificate activation failed: %v", err)
+		return
+	}
+
+	for _, cert := range activated {
+		event := notify.Event{
+			Type:       notify.EventCertificateActivated,
+			CompanyID:  cert.CompanyID,
+			Message:    fmt.Sprintf("Scheduled certificate %s is now active", cert.Name),
+			Data:       cert,
+			OccurredAt: now,
+		}
+		if err := s.notifier.Notify(event); err != nil {
+			log.Printf("Warning: failed to send activation alert for certificate %d: %v", cert.ID, err)
+		}
+	}
+}
+
+// CheckExpirations envía las alertas pendientes y retorna cuántas se enviaron
+func (s *CertificateExpiryService) CheckExpirations(now time.Time) (int, error) {
+	horizon := now.AddDate(0, 0, certificateExpiryThresholds[0]+1)
+	certs, err := s.certRepo.GetActiveExpiringBefore(horizon)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expiring certificates: %w", err)
+	}
+
+	sent := 0
+	for i := range certs {
+		cert := &certs[i]
+		days := *cert.DaysToExpire(now)
+
+		threshold, ok := expiryThreshold(days)
+		if !ok {
+			continue
+		}
+		if cert.ExpiryNotifiedDays != nil && *cert.ExpiryNotifiedDays <= threshold {
+			continue
+		}
+
+		// Reservar el umbral antes de avisar: con varias instancias solo una lo consigue
+		claimed, err := s.certRepo.MarkExpiryNotified(cert.ID, threshold)
+		if err != nil {
+			log.Printf("Warning: failed to mark certificate %d as notified: %v", cert.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.notify(cert, days, threshold); err != nil {
+			log.Printf("Warning: failed to send expiry alert for certificate %d: %v", cert.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (s *CertificateExpiryService) notify(cert *domain.Certificate, days, threshold int) error {
+	alert := domain.CertificateExpiryAlert{
+		CertificateID: cert.ID,
+		CompanyID:     cert.CompanyID,
+		Name:          cert.Name,
+		NotAfter:      *cert.NotAfter,
+		DaysToExpire:  days,
+		Threshold:     threshold,
+	}
+	if company, err := s.companyRepo.GetByID(cert.CompanyID); err == nil {
+		alert.CompanyName = company.Name
+		alert.CompanyNIT = company.NIT
+	}
+
+	event := notify.Event{
+		Type:       notify.EventCertificateExpiring,
+		CompanyID:  cert.CompanyID,
+		Message:    fmt.Sprintf("Certificate %s of %s (NIT %s) expires in %d day(s), on %s", cert.Name, alert.CompanyName, alert.CompanyNIT, days, cert.NotAfter.Format("2006-01-02")),
+		Data:       alert,
+		OccurredAt: time.Now(),
+	}
+	if threshold == 0 {
+		event.Type = notify.EventCertificateExpired
+		event.Message = fmt.Sprintf("Certificate %s of %s (NIT %s) expired on %s; documents cannot be signed until a new certificate is uploaded", cert.Name, alert.CompanyName, alert.CompanyNIT, cert.NotAfter.Format("2006-01-02"))
+	}
+
+	return s.notifier.Notify(event)
+}
+
+// expiryThreshold retorna el menor umbral alcanzado por los días restantes
+func expiryThreshold(days int) (int, bool) {
+	reached, ok := 0, false
+	for _, threshold := range certificateExpiryThresholds {
+		if days <= threshold {
+			reached, ok = threshold, true
+		}
+	}
+	return reached, ok
+}
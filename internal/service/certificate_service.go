@@ -1,45 +1,49 @@
 package service
 
 import (
-	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/internal/repository"
+	"apidian-go/internal/service/keyring"
 	"apidian-go/pkg/crypto"
+	"crypto/x509"
 	"database/sql"
+	"encoding/asn1"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"log"
+	"regexp"
+	"strings"
 	"time"
 )
 
 type CertificateService struct {
 	certRepo    *repository.CertificateRepository
 	companyRepo *repository.CompanyRepository
-	storage     *config.StorageConfig
+	authz       *Authorizer
+	storage     storage.Storage
+	keyring     *keyring.Keyring
 }
 
-func NewCertificateService(certRepo *repository.CertificateRepository, companyRepo *repository.CompanyRepository, storage *config.StorageConfig) *CertificateService {
+func NewCertificateService(certRepo *repository.CertificateRepository, companyRepo *repository.CompanyRepository, store storage.Storage, keys *keyring.Keyring) *CertificateService {
 	return &CertificateService{
 		certRepo:    certRepo,
 		companyRepo: companyRepo,
-		storage:     storage,
+		authz:       NewAuthorizer(companyRepo),
+		storage:     store,
+		keyring:     keys,
 	}
 }
 
 // Create uploads and stores a new certificate
 func (s *CertificateService) Create(req *domain.CreateCertificateRequest, userID int64) (*domain.CertificateResponse, error) {
-	// Validate company exists and belongs to user
-	company, err := s.companyRepo.GetByID(req.CompanyID)
+	// Validate the user can manage certificates of the company
+	company, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionCertificatesManage)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("company not found")
-		}
 		return nil, err
 	}
-
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
+	if err := s.authz.RequireTwoFactor(company, userID); err != nil {
+		return nil, err
 	}
 
 	// Decode base64 certificate
@@ -53,22 +57,55 @@ func (s *CertificateService) Create(req *domain.CreateCertificateRequest, userID
 		return nil, errors.New("certificate size must not exceed 5MB")
 	}
 
-	// Note: We don't validate PKCS12 format here to support both BER and DER encodings
-	// Validation will occur when the certificate is actually used for signing
-	// This allows compatibility with certificates from various Certificate Authorities
+	// Parse the signer certificate (DER via x/crypto, BER via openssl fallback).
+	// This also validates the password before anything is stored
+	x509Cert, err := crypto.ParsePKCS12Certificate(certificateData, req.Password)
+	if err != nil {
+		return nil, errors.New("invalid certificate or password")
+	}
+	now := time.Now()
+	if now.After(x509Cert.NotAfter) {
+		return nil, fmt.Errorf("certificate expired on %s", x509Cert.NotAfter.Format("2006-01-02"))
+	}
+
+	// Activate now, or schedule the rollover for activates_at. A certificate that is not
+	// valid yet is scheduled for its not_before date
+	status := domain.CertificateStatusActive
+	var activatesAt *time.Time
+	if req.ActivatesAt != nil && req.ActivatesAt.After(now) {
+		activatesAt = req.ActivatesAt
+	} else if x509Cert.NotBefore.After(now) {
+		notBefore := x509Cert.NotBefore
+		activatesAt = &notBefore
+	}
+	if activatesAt != nil {
+		if activatesAt.Before(x509Cert.NotBefore) {
+			return nil, fmt.Errorf("activation date must not be before the certificate is valid (%s)", x509Cert.NotBefore.Format(time.RFC3339))
+		}
+		if !activatesAt.Before(x509Cert.NotAfter) {
+			return nil, fmt.Errorf("activation date must be before the certificate expires (%s)", x509Cert.NotAfter.Format(time.RFC3339))
+		}
+		status = domain.CertificateStatusScheduled
+	}
+
+	// The certificate must belong to the company; a mismatch is recorded and logged
+	// (some CAs put the NIT in non-standard fields) but the upload is not rejected
+	subjectNIT, nitMatches := matchCertificateNIT(x509Cert, company.NIT, company.DV)
+	if !nitMatches {
+		log.Printf("Warning: certificate subject %q does not contain company NIT %s", x509Cert.Subject.String(), company.NIT)
+	}
 
 	// Generate filename based on company NIT with timestamp for historical tracking
 	timestamp := time.Now().Unix()
 	filename := fmt.Sprintf("%s_%d.p12", company.NIT, timestamp)
 
-	certificatesDir := s.storage.CertificatesPath(company.NIT)
-	if err := os.MkdirAll(certificatesDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	// Save certificate to storage, encrypted with the company data key
+	store, err := s.keyring.CompanyStorage(req.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load company data key: %w", err)
 	}
-
-	// Save certificate to filesystem
-	certPath := filepath.Join(certificatesDir, filename)
-	if err := os.WriteFile(certPath, certificateData, 0600); err != nil {
+	certPath := s.GetCertificatePath(filename, company.NIT)
+	if err := store.Put(certPath, certificateData); err != nil {
 		return nil, fmt.Errorf("failed to save certificate: %w", err)
 	}
 
@@ -76,59 +113,40 @@ func (s *CertificateService) Create(req *domain.CreateCertificateRequest, userID
 	encryptedPassword, err := crypto.EncryptPassword(req.Password)
 	if err != nil {
 		// Clean up file if password encryption fails
-		os.Remove(certPath)
+		s.storage.Delete(certPath)
 		return nil, fmt.Errorf("failed to encrypt password: %w", err)
 	}
 
-	// Delete previous certificates (hard delete from DB and filesystem)
-	// This ensures only one certificate exists per company at any time
-	if err := s.deletePreviousCertificates(req.CompanyID, company.NIT); err != nil {
-		// Clean up newly uploaded file if deletion of previous certs fails
-		os.Remove(certPath)
-		return nil, fmt.Errorf("failed to delete previous certificates: %w", err)
-	}
-
-	// Create certificate record (now safe because previous ones are deleted)
+	// Create certificate record. Previous certificates are kept as history (superseded)
+	// so documents signed with them can still be verified
 	cert := &domain.Certificate{
-		CompanyID: req.CompanyID,
-		Name:      filename,
-		Password:  encryptedPassword,
-		IsActive:  true,
+		CompanyID:   req.CompanyID,
+		Name:        filename,
+		Password:    encryptedPassword,
+		Status:      status,
+		ActivatesAt: activatesAt,
 	}
+	setCertificateMetadata(cert, x509Cert, subjectNIT, nitMatches)
 
-	cert, err = s.certRepo.Create(cert)
+	cert, err = s.certRepo.As(userID).Create(cert)
 	if err != nil {
 		// Clean up file if database insert fails
-		os.Remove(certPath)
+		s.storage.Delete(certPath)
 		return nil, fmt.Errorf("failed to create certificate record: %w", err)
 	}
 
-	return &domain.CertificateResponse{
-		ID:        cert.ID,
-		CompanyID: cert.CompanyID,
-		Name:      cert.Name,
-		Path:      certPath,
-		IsActive:  cert.IsActive,
-		CreatedAt: cert.CreatedAt,
-		UpdatedAt: cert.UpdatedAt,
-	}, nil
+	resp := s.toResponse(cert, company.NIT)
+	return &resp, nil
 }
 
 // GetByCompanyID gets the active certificate for a company
 func (s *CertificateService) GetByCompanyID(companyID int64, userID int64) (*domain.CertificateResponse, error) {
-	// Validate company belongs to user
-	company, err := s.companyRepo.GetByID(companyID)
+	// Validate the user can read certificates of the company
+	company, err := s.authz.Authorize(companyID, userID, domain.PermissionCertificatesRead)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("company not found")
-		}
 		return nil, err
 	}
 
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
-	}
-
 	cert, err := s.certRepo.GetByCompanyID(companyID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -137,57 +155,32 @@ func (s *CertificateService) GetByCompanyID(companyID int64, userID int64) (*dom
 		return nil, err
 	}
 
-	certPath := s.GetCertificatePath(cert.Name, company.NIT)
-
-	return &domain.CertificateResponse{
-		ID:        cert.ID,
-		CompanyID: cert.CompanyID,
-		Name:      cert.Name,
-		Path:      certPath,
-		IsActive:  cert.IsActive,
-		CreatedAt: cert.CreatedAt,
-		UpdatedAt: cert.UpdatedAt,
-	}, nil
+	resp := s.toResponse(cert, company.NIT)
+	return &resp, nil
 }
 
 // GetAllByCompanyID gets all certificates for a company
 func (s *CertificateService) GetAllByCompanyID(companyID int64, userID int64) ([]domain.CertificateResponse, error) {
-	// Validate company belongs to user
-	company, err := s.companyRepo.GetByID(companyID)
+	// Validate the user can read certificates of the company
+	company, err := s.authz.Authorize(companyID, userID, domain.PermissionCertificatesRead)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("company not found")
-		}
 		return nil, err
 	}
 
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
-	}
-
 	certs, err := s.certRepo.GetAllByCompanyID(companyID)
 	if err != nil {
 		return nil, err
 	}
 
 	var responses []domain.CertificateResponse
-	for _, cert := range certs {
-		certPath := s.GetCertificatePath(cert.Name, company.NIT)
-		responses = append(responses, domain.CertificateResponse{
-			ID:        cert.ID,
-			CompanyID: cert.CompanyID,
-			Name:      cert.Name,
-			Path:      certPath,
-			IsActive:  cert.IsActive,
-			CreatedAt: cert.CreatedAt,
-			UpdatedAt: cert.UpdatedAt,
-		})
+	for i := range certs {
+		responses = append(responses, s.toResponse(&certs[i], company.NIT))
 	}
 
 	return responses, nil
 }
 
-// Delete deletes a certificate
+// Delete disables a certificate (active or scheduled)
 func (s *CertificateService) Delete(id int64, userID int64) error {
 	cert, err := s.certRepo.GetByID(id)
 	if err != nil {
@@ -197,105 +190,144 @@ func (s *CertificateService) Delete(id int64, userID int64) error {
 		return err
 	}
 
-	// Validate company belongs to user
-	company, err := s.companyRepo.GetByID(cert.CompanyID)
+	// Validate the user can manage certificates of the company
+	company, err := s.authz.AuthorizeResource(cert.CompanyID, userID, domain.PermissionCertificatesManage, "certificate")
 	if err != nil {
-		return errors.New("company not found")
-	}
-
-	if company.UserID != userID {
-		return errors.New("unauthorized access to certificate")
-	}
-
-	// Soft delete in database
-	if err := s.certRepo.Delete(id); err != nil {
 		return err
 	}
-
-	// Optionally delete file from filesystem (company already retrieved above)
-	certPath := s.GetCertificatePath(cert.Name, company.NIT)
-	if err := os.Remove(certPath); err != nil && !os.IsNotExist(err) {
-		// Log error but don't fail the operation
-		fmt.Printf("Warning: failed to delete certificate file: %v\n", err)
+	if err := s.authz.RequireTwoFactor(company, userID); err != nil {
+		return err
 	}
 
-	return nil
+	// Soft delete only: the record and the file are kept to verify documents signed with it
+	return s.certRepo.As(userID).Delete(id)
 }
 
-// deletePreviousCertificates deletes all previous certificates for a company
-// This includes both database records and physical files
-func (s *CertificateService) deletePreviousCertificates(companyID int64, companyNIT string) error {
-	// Get all existing certificates for this company
-	certs, err := s.certRepo.GetAllByCompanyIDIncludingInactive(companyID)
-	if err != nil {
-		return fmt.Errorf("failed to get existing certificates: %w", err)
+// toResponse builds the public view of a certificate, including days left until expiry
+func (s *CertificateService) toResponse(cert *domain.Certificate, companyNIT string) domain.CertificateResponse {
+	now := time.Now()
+	return domain.CertificateResponse{
+		ID:            cert.ID,
+		CompanyID:     cert.CompanyID,
+		Name:          cert.Name,
+		Path:          s.GetCertificatePath(cert.Name, companyNIT),
+		IsActive:      cert.IsActive,
+		Status:        cert.Status,
+		ActivatesAt:   cert.ActivatesAt,
+		DeactivatedAt: cert.DeactivatedAt,
+		Subject:       cert.Subject,
+		Issuer:        cert.Issuer,
+		SerialNumber:  cert.SerialNumber,
+		SubjectNIT:    cert.SubjectNIT,
+		NITMatches:    cert.NITMatches,
+		NotBefore:     cert.NotBefore,
+		NotAfter:      cert.NotAfter,
+		DaysToExpire:  cert.DaysToExpire(now),
+		Expired:       cert.NotAfter != nil && now.After(*cert.NotAfter),
+		CreatedAt:     cert.CreatedAt,
+		UpdatedAt:     cert.UpdatedAt,
 	}
+}
 
-	// Delete physical files first
-	for _, cert := range certs {
-		certPath := s.GetCertificatePath(cert.Name, companyNIT)
-		if err := os.Remove(certPath); err != nil && !os.IsNotExist(err) {
-			// Log warning but continue - file might already be deleted
-			fmt.Printf("Warning: failed to delete certificate file %s: %v\n", certPath, err)
-		} else if err == nil {
-			fmt.Printf("Deleted certificate file: %s\n", certPath)
-		}
+// setCertificateMetadata copies the X.509 data extracted at upload into the record
+func setCertificateMetadata(cert *domain.Certificate, x509Cert *x509.Certificate, subjectNIT string, nitMatches bool) {
+	subject := x509Cert.Subject.String()
+	issuer := x509Cert.Issuer.String()
+	serial := strings.ToUpper(x509Cert.SerialNumber.Text(16))
+	notBefore := x509Cert.NotBefore
+	notAfter := x509Cert.NotAfter
+
+	cert.Subject = &subject
+	cert.Issuer = &issuer
+	cert.SerialNumber = &serial
+	cert.NotBefore = &notBefore
+	cert.NotAfter = &notAfter
+	cert.NITMatches = &nitMatches
+	if subjectNIT != "" {
+		cert.SubjectNIT = &subjectNIT
 	}
+}
 
-	// Delete all database records for this company
-	if err := s.certRepo.DeleteAllByCompanyID(companyID); err != nil {
-		return fmt.Errorf("failed to delete certificate records: %w", err)
-	}
+var (
+	oidSerialNumber = asn1.ObjectIdentifier{2, 5, 4, 5}
+	nitPattern      = regexp.MustCompile(`\d{6,15}`)
+)
 
-	return nil
+// matchCertificateNIT busca el NIT de la empresa en los atributos del sujeto del certificado.
+// Las CAs colombianas lo ubican en serialNumber, CN u OU, con o sin puntos y dígito de
+// verificación (900.123.456-7, 9001234567, NIT 900123456). Retorna el NIT encontrado
+// (el del serialNumber si no hay coincidencia) y si coincide con el de la empresa.
+func matchCertificateNIT(cert *x509.Certificate, nit string, dv *string) (string, bool) {
+	nitWithDV := nit
+	if dv != nil {
+		nitWithDV += *dv
+	}
+
+	var first string
+	for _, attr := range cert.Subject.Names {
+		value, ok := attr.Value.(string)
+		if !ok {
+			continue
+		}
+		value = strings.NewReplacer(".", "", " ", "", "-", "").Replace(value)
+		for _, candidate := range nitPattern.FindAllString(value, -1) {
+			if candidate == nit || candidate == nitWithDV {
+				return nit, true
+			}
+			if first == "" || attr.Type.Equal(oidSerialNumber) {
+				first = candidate
+			}
+		}
+	}
+	return first, false
 }
 
-// GetCertificatePath returns the full path to a certificate file
-// Path structure: /storage/{NIT}/certificates/{filename}
+// GetCertificatePath returns the storage key of a certificate file
+// Key structure: companies/{NIT}/certificates/{filename}
 func (s *CertificateService) GetCertificatePath(filename string, companyNIT string) string {
-	return s.storage.CertificatePath(companyNIT, filename)
+	return storage.CertificateKey(companyNIT, filename)
 }
 
 // GetCertificateForSigning gets certificate data and password for signing operations
-func (s *CertificateService) GetCertificateForSigning(companyID int64) (path string, password string, err error) {
+func (s *CertificateService) GetCertificateForSigning(companyID int64) (data []byte, password string, err error) {
 	cert, err := s.certRepo.GetByCompanyID(companyID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", "", errors.New("no active certificate found for company")
+			return nil, "", errors.New("no active certificate found for company")
 		}
-		return "", "", err
+		return nil, "", err
 	}
 
 	// Decrypt password
 	decryptedPassword, err := crypto.DecryptPassword(cert.Password)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to decrypt certificate password: %w", err)
+		return nil, "", fmt.Errorf("failed to decrypt certificate password: %w", err)
 	}
 
 	// Get company to retrieve NIT for path
 	company, err := s.companyRepo.GetByID(companyID)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get company: %w", err)
+		return nil, "", fmt.Errorf("failed to get company: %w", err)
 	}
 
-	certPath := s.GetCertificatePath(cert.Name, company.NIT)
-
-	// Verify file exists
-	if _, err := os.Stat(certPath); os.IsNotExist(err) {
-		return "", "", errors.New("certificate file not found on filesystem")
+	// Read and decrypt certificate file in memory
+	store, err := s.keyring.CompanyStorage(companyID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load company data key: %w", err)
+	}
+	certData, err := store.Get(s.GetCertificatePath(cert.Name, company.NIT))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", errors.New("certificate file not found in storage")
 	}
-
-	// Read certificate file
-	certData, err := os.ReadFile(certPath)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to read certificate file: %w", err)
+		return nil, "", fmt.Errorf("failed to read certificate file: %w", err)
 	}
 
 	// Validate PKCS12 format and password when actually using the certificate
 	// This validation happens here (not at upload) to support both BER and DER encodings
 	if err := crypto.ValidatePKCS12(certData, decryptedPassword); err != nil {
-		return "", "", fmt.Errorf("certificate validation failed: %w. The certificate may be corrupted or the password is incorrect. Please upload a new certificate", err)
+		return nil, "", fmt.Errorf("certificate validation failed: %w. The certificate may be corrupted or the password is incorrect. Please upload a new certificate", err)
 	}
 
-	return certPath, decryptedPassword, nil
+	return certData, decryptedPassword, nil
 }
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+	"time"
+)
+
+func TestMatchCertificateNIT(t *testing.T) {
+	dv := "7"
+	subject := func(names ...pkix.AttributeTypeAndValue) *x509.Certificate {
+		return &x509.Certificate{Subject: pkix.Name{Names: names}}
+	}
+	cn := asn1.ObjectIdentifier{2, 5, 4, 3}
+	ou := asn1.ObjectIdentifier{2, 5, 4, 11}
+
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		found   string
+		matches bool
+	}{
+		{"serial number with DV", subject(pkix.AttributeTypeAndValue{Type: oidSerialNumber, Value: "9001234567"}), "900123456", true},
+		{"dotted NIT in CN", subject(pkix.AttributeTypeAndValue{Type: cn, Value: "EMPRESA SAS 900.123.456-7"}), "900123456", true},
+		{"NIT prefix in OU", subject(pkix.AttributeTypeAndValue{Type: ou, Value: "NIT 900123456"}), "900123456", true},
+		{
+			name: "another company reports the serial number",
+			cert: subject(
+				pkix.AttributeTypeAndValue{Type: cn, Value: "OTRA SAS 800199436"},
+				pkix.AttributeTypeAndValue{Type: oidSerialNumber, Value: "8001994365"},
+			),
+			found: "8001994365",
+		},
+		{"no NIT in the subject", subject(pkix.AttributeTypeAndValue{Type: cn, Value: "Persona Natural"}), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, matches := matchCertificateNIT(tt.cert, "900123456", &dv)
+			if found != tt.found || matches != tt.matches {
+				t.Errorf("matchCertificateNIT = %q, %v; want %q, %v", found, matches, tt.found, tt.matches)
+			}
+		})
+	}
+}
+
+func TestExpiryThreshold(t *testing.T) {
+	tests := []struct {
+		days      int
+		threshold int
+		reached   bool
+	}{
+		{45, 0, false},
+		{31, 0, false},
+		{30, 30, true},
+		{16, 30, true},
+		{15, 15, true},
+		{7, 7, true},
+		{1, 7, true},
+		{0, 0, true},
+		{-3, 0, true},
+	}
+	for _, tt := range tests {
+		if threshold, reached := expiryThreshold(tt.days); threshold != tt.threshold || reached != tt.reached {
+			t.Errorf("expiryThreshold(%d) = %d, %v; want %d, %v", tt.days, threshold, reached, tt.threshold, tt.reached)
+		}
+	}
+}
+
+func TestCertificateValidity(t *testing.T) {
+	notBefore := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+	cert := &domain.Certificate{NotBefore: &notBefore, NotAfter: &notAfter}
+
+	if err := cert.CheckValidity(notBefore.AddDate(0, 3, 0)); err != nil {
+		t.Errorf("valid certificate: %v", err)
+	}
+	if err := cert.CheckValidity(notBefore.Add(-time.Hour)); err == nil {
+		t.Error("certificate used before not_before must fail")
+	}
+	if err := cert.CheckValidity(notAfter.Add(time.Hour)); err == nil {
+		t.Error("expired certificate must fail")
+	}
+	if err := (&domain.Certificate{}).CheckValidity(notAfter.AddDate(5, 0, 0)); err != nil {
+		t.Errorf("certificate without tracked dates must be accepted: %v", err)
+	}
+
+	if days := cert.DaysToExpire(notAfter.Add(-36 * time.Hour)); days == nil || *days != 2 {
+		t.Errorf("DaysToExpire 36h before = %v, want 2", days)
+	}
+	if days := cert.DaysToExpire(notAfter.Add(24 * time.Hour)); days == nil || *days != -1 {
+		t.Errorf("DaysToExpire a day after = %v, want -1", days)
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"apidian-go/internal/repository"
 	"apidian-go/pkg/utils"
 	"apidian-go/pkg/validator"
-	"fmt"
+	"errors"
 )
 
 type CompanyService struct {
-	repo *repository.CompanyRepository
+	repo  *repository.CompanyRepository
+	authz *Authorizer
 }
 
 func NewCompanyService(repo *repository.CompanyRepository) *CompanyService {
-	return &CompanyService{repo: repo}
+	return &CompanyService{
+		repo:  repo,
+		authz: NewAuthorizer(repo),
+	}
 }
 
 // Create crea una nueva empresa
@@ -32,22 +36,17 @@ func (s *CompanyService) Create(userID int64, req *domain.CreateCompanyRequest)
 	return company, nil
 }
 
-// GetByID obtiene una empresa por ID
+// GetByID obtiene una empresa por ID si el usuario es miembro (con su rol)
 func (s *CompanyService) GetByID(id int64, userID int64) (*domain.Company, error) {
-	company, err := s.repo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Verificar que la empresa pertenece al usuario
-	if company.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to company")
-	}
+	return s.authz.Authorize(id, userID, domain.PermissionCompanyRead)
+}
 
-	return company, nil
+// Authorize obtiene la empresa si el rol del usuario tiene el permiso
+func (s *CompanyService) Authorize(id, userID int64, permission string) (*domain.Company, error) {
+	return s.authz.Authorize(id, userID, permission)
 }
 
-// GetByUserID obtiene todas las empresas de un usuario con paginación
+// GetByUserID obtiene las empresas de las que el usuario es miembro con paginación
 func (s *CompanyService) GetByUserID(userID int64, page, pageSize int) (*domain.CompanyListResponse, error) {
 	// Normalizar paginación
 	page, pageSize = utils.NormalizePagination(page, pageSize)
@@ -67,38 +66,53 @@ func (s *CompanyService) GetByUserID(userID int64, page, pageSize int) (*domain.
 
 // Update actualiza una empresa
 func (s *CompanyService) Update(id int64, userID int64, req *domain.UpdateCompanyRequest) error {
-	// Verificar que la empresa existe y pertenece al usuario
-	company, err := s.repo.GetByID(id)
-	if err != nil {
+	// Verificar que el usuario puede modificar la empresa
+	if _, err := s.authz.Authorize(id, userID, domain.PermissionCompanyWrite); err != nil {
 		return err
 	}
 
-	if company.UserID != userID {
-		return fmt.Errorf("unauthorized access to company")
-	}
-
 	// Actualizar empresa (PostgreSQL maneja validaciones)
-	if err := s.repo.Update(id, req); err != nil {
+	if err := s.repo.As(userID).Update(id, req); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// Delete elimina (soft delete) una empresa
-func (s *CompanyService) Delete(id int64, userID int64) error {
-	// Verificar que la empresa existe y pertenece al usuario
-	company, err := s.repo.GetByID(id)
+// SetTwoFactorPolicy activa o desactiva la exigencia de 2FA para firmar, enviar a DIAN y
+// administrar certificados. Quien la activa debe tener 2FA activo, para no bloquearse a sí mismo.
+func (s *CompanyService) SetTwoFactorPolicy(id int64, userID int64, require bool) (*domain.Company, error) {
+	company, err := s.authz.Authorize(id, userID, domain.PermissionMembersManage)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if require {
+		enabled, err := s.repo.MemberTwoFactorEnabled(id, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, errors.New("cannot require two-factor authentication without enabling it on your own account")
+		}
 	}
 
-	if company.UserID != userID {
-		return fmt.Errorf("unauthorized access to company")
+	if err := s.repo.As(userID).SetRequireTwoFactor(id, require); err != nil {
+		return nil, err
+	}
+	company.RequireTwoFactor = require
+	return company, nil
+}
+
+// Delete elimina (soft delete) una empresa
+func (s *CompanyService) Delete(id int64, userID int64) error {
+	// Solo el dueño puede eliminar la empresa
+	if _, err := s.authz.Authorize(id, userID, domain.PermissionCompanyDelete); err != nil {
+		return err
 	}
 
 	// Eliminar empresa
-	if err := s.repo.Delete(id); err != nil {
+	if err := s.repo.As(userID).Delete(id); err != nil {
 		return err
 	}
 
@@ -119,9 +133,5 @@ func (s *CompanyService) GetByNIT(nit, dv string, userID int64) (*domain.Company
 	}
 	
 	// Verificar que el usuario tenga acceso a esta empresa
-	if company.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to company")
-	}
-	
-	return company, nil
+	return s.authz.Authorize(company.ID, userID, domain.PermissionCompanyRead)
 }
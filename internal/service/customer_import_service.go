@@ -0,0 +1,293 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/spreadsheet"
+	"apidian-go/pkg/validator"
+	"fmt"
+)
+
+// customerColumns define el formato de importación/exportación de clientes.
+// Los catálogos se exportan con su código DIAN; al importar se acepta código o nombre.
+var customerColumns = []string{
+	"identification_number",
+	"dv",
+	"document_type",
+	"name",
+	"trade_name",
+	"tax_level_code",
+	"tax_type",
+	"organization_type",
+	"regime_type",
+	"municipality",
+	"address_line",
+	"postal_zone",
+	"phone",
+	"email",
+}
+
+type CustomerImportService struct {
+	customerRepo *repository.CustomerRepository
+	companyRepo  *repository.CompanyRepository
+	authz        *Authorizer
+	catalogRepo  *repository.CatalogRepository
+}
+
+func NewCustomerImportService(
+	customerRepo *repository.CustomerRepository,
+	companyRepo *repository.CompanyRepository,
+	catalogRepo *repository.CatalogRepository,
+) *CustomerImportService {
+	return &CustomerImportService{
+		customerRepo: customerRepo,
+		companyRepo:  companyRepo,
+		authz:        NewAuthorizer(companyRepo),
+		catalogRepo:  catalogRepo,
+	}
+}
+
+// Import crea o actualiza clientes (upsert por identification_number) desde un CSV/XLSX.
+// Las filas inválidas se reportan y se omiten; con dryRun solo se valida.
+func (s *CustomerImportService) Import(companyID int64, filename string, data []byte, dryRun bool, userID int64) (*domain.ImportReport, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionCustomersWrite); err != nil {
+		return nil, err
+	}
+
+	table, err := spreadsheet.Read(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file: %w", err)
+	}
+	for _, column := range []string{"identification_number", "name"} {
+		if !table.HasColumn(column) {
+			return nil, fmt.Errorf("invalid file: missing required column '%s'", column)
+		}
+	}
+
+	report := &domain.ImportReport{Filename: filename, DryRun: dryRun, Rows: []domain.ImportRowResult{}}
+	catalogs := newCatalogCache(s.catalogRepo)
+	seen := map[string]int{}
+
+	for _, row := range table.Rows {
+		result := domain.ImportRowResult{Row: row.Number, Key: row.Get("identification_number")}
+
+		if result.Key == "" {
+			result.Errors = append(result.Errors, "identification_number es requerido")
+			report.Add(result)
+			continue
+		}
+		if previous, ok := seen[result.Key]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("identification_number duplicado (fila %d)", previous))
+			report.Add(result)
+			continue
+		}
+		seen[result.Key] = row.Number
+
+		existing, err := s.customerRepo.GetByIdentification(companyID, result.Key)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			report.Add(result)
+			continue
+		}
+
+		req := domain.CreateCustomerRequest{CompanyID: companyID, IdentificationNumber: result.Key}
+		result.Action = domain.ImportActionCreate
+		if existing != nil {
+			result.Action = domain.ImportActionUpdate
+			req = customerToRequest(existing)
+		}
+
+		result.Errors = append(result.Errors, s.applyRow(&req, existing, row, catalogs)...)
+
+		if len(result.Errors) == 0 {
+			if err := validator.ValidateCreateCustomer(&req); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+
+		if len(result.Errors) == 0 && !dryRun {
+			if existing == nil {
+				_, err = s.customerRepo.Create(userID, &req)
+			} else {
+				err = s.customerRepo.As(userID).Update(existing.ID, customerUpdateRequest(&req))
+			}
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+
+		report.Add(result)
+	}
+
+	return report, nil
+}
+
+// applyRow copia al request las columnas no vacías de la fila, resolviendo catálogos a IDs
+func (s *CustomerImportService) applyRow(req *domain.CreateCustomerRequest, existing *domain.Customer, row spreadsheet.Row, catalogs *catalogCache) []string {
+	var errs []string
+
+	if value := row.Get("document_type"); value != "" {
+		id, err := catalogs.resolve("document_types", value)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if existing != nil && id != existing.DocumentTypeID {
+			errs = append(errs, "document_type no se puede modificar en un cliente existente")
+		}
+		req.DocumentTypeID = id
+	}
+
+	if value := row.Get("dv"); value != "" {
+		if existing != nil && (existing.DV == nil || *existing.DV != value) {
+			errs = append(errs, "dv no se puede modificar en un cliente existente")
+		}
+		req.DV = &value
+	}
+
+	if value := row.Get("name"); value != "" {
+		req.Name = value
+	}
+	if value := row.Get("trade_name"); value != "" {
+		req.TradeName = &value
+	}
+
+	for _, field := range []struct {
+		column string
+		table  string
+		target *int
+	}{
+		{"tax_level_code", "tax_level_codes", &req.TaxLevelCodeID},
+		{"organization_type", "organization_types", &req.TypeOrganizationID},
+		{"regime_type", "regime_types", &req.TypeRegimeID},
+	} {
+		value := row.Get(field.column)
+		if value == "" {
+			continue
+		}
+		id, err := catalogs.resolve(field.table, value)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		*field.target = id
+	}
+
+	if value := row.Get("tax_type"); value != "" {
+		id, err := catalogs.resolve("tax_types", value)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			req.TaxTypeID = &id
+		}
+	}
+
+	if value := row.Get("municipality"); value != "" {
+		municipalityID, departmentID, countryID, err := catalogs.resolveMunicipality(value)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			req.MunicipalityID = municipalityID
+			req.DepartmentID = departmentID
+			req.CountryID = countryID
+		}
+	}
+
+	if value := row.Get("address_line"); value != "" {
+		req.AddressLine = value
+	}
+	if value := row.Get("postal_zone"); value != "" {
+		req.PostalZone = &value
+	}
+	if value := row.Get("phone"); value != "" {
+		req.Phone = &value
+	}
+	if value := row.Get("email"); value != "" {
+		req.Email = &value
+	}
+
+	return errs
+}
+
+// Export genera el archivo de clientes en el mismo formato que acepta Import
+func (s *CustomerImportService) Export(companyID int64, format string, userID int64) ([]byte, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionCustomersRead); err != nil {
+		return nil, err
+	}
+
+	customers, err := s.customerRepo.GetAllByCompanyID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := map[string]map[int]string{}
+	for _, table := range []string{"document_types", "tax_level_codes", "tax_types", "organization_types", "regime_types", "municipalities"} {
+		if codes[table], err = s.catalogRepo.GetCodes(table); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([][]string, 0, len(customers))
+	for _, c := range customers {
+		taxType := ""
+		if c.TaxTypeID != nil {
+			taxType = codes["tax_types"][*c.TaxTypeID]
+		}
+		rows = append(rows, []string{
+			c.IdentificationNumber,
+			stringValue(c.DV),
+			codes["document_types"][c.DocumentTypeID],
+			c.Name,
+			stringValue(c.TradeName),
+			codes["tax_level_codes"][c.TaxLevelCodeID],
+			taxType,
+			codes["organization_types"][c.TypeOrganizationID],
+			codes["regime_types"][c.TypeRegimeID],
+			codes["municipalities"][c.MunicipalityID],
+			c.AddressLine,
+			stringValue(c.PostalZone),
+			stringValue(c.Phone),
+			stringValue(c.Email),
+		})
+	}
+
+	return spreadsheet.Write(format, "Clientes", customerColumns, rows)
+}
+
+// customerToRequest parte de los datos actuales del cliente para aplicar la fila encima
+func customerToRequest(c *domain.Customer) domain.CreateCustomerRequest {
+	return domain.CreateCustomerRequest{
+		CompanyID:            c.CompanyID,
+		DocumentTypeID:       c.DocumentTypeID,
+		IdentificationNumber: c.IdentificationNumber,
+		DV:                   c.DV,
+		Name:                 c.Name,
+		TradeName:            c.TradeName,
+		TaxLevelCodeID:       c.TaxLevelCodeID,
+		TaxTypeID:            c.TaxTypeID,
+		TypeOrganizationID:   c.TypeOrganizationID,
+		TypeRegimeID:         c.TypeRegimeID,
+		CountryID:            c.CountryID,
+		DepartmentID:         c.DepartmentID,
+		MunicipalityID:       c.MunicipalityID,
+		AddressLine:          c.AddressLine,
+		PostalZone:           c.PostalZone,
+		Phone:                c.Phone,
+		Email:                c.Email,
+	}
+}
+
+func customerUpdateRequest(req *domain.CreateCustomerRequest) *domain.UpdateCustomerRequest {
+	return &domain.UpdateCustomerRequest{
+		Name:               &req.Name,
+		TradeName:          req.TradeName,
+		TaxLevelCodeID:     &req.TaxLevelCodeID,
+		TaxTypeID:          req.TaxTypeID,
+		TypeOrganizationID: &req.TypeOrganizationID,
+		TypeRegimeID:       &req.TypeRegimeID,
+		DepartmentID:       &req.DepartmentID,
+		MunicipalityID:     &req.MunicipalityID,
+		AddressLine:        &req.AddressLine,
+		PostalZone:         req.PostalZone,
+		Phone:              req.Phone,
+		Email:              req.Email,
+	}
+}
@@ -8,15 +8,24 @@ import (
 )
 
 type CustomerService struct {
-	repo *repository.CustomerRepository
+	repo  *repository.CustomerRepository
+	authz *Authorizer
 }
 
-func NewCustomerService(repo *repository.CustomerRepository) *CustomerService {
-	return &CustomerService{repo: repo}
+func NewCustomerService(repo *repository.CustomerRepository, companyRepo *repository.CompanyRepository) *CustomerService {
+	return &CustomerService{
+		repo:  repo,
+		authz: NewAuthorizer(companyRepo),
+	}
 }
 
 // Create crea un nuevo cliente
 func (s *CustomerService) Create(userID int64, req *domain.CreateCustomerRequest) (*domain.Customer, error) {
+	// Verificar que el usuario puede crear clientes en la empresa
+	if _, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionCustomersWrite); err != nil {
+		return nil, err
+	}
+
 	// Validar que no exista cliente con la misma identificación en la empresa
 	existing, err := s.repo.GetByIdentification(req.CompanyID, req.IdentificationNumber)
 	if err != nil {
@@ -36,15 +45,15 @@ func (s *CustomerService) Create(userID int64, req *domain.CreateCustomerRequest
 }
 
 // GetByID obtiene un cliente por ID
-func (s *CustomerService) GetByID(id int64, companyID int64) (*domain.Customer, error) {
+func (s *CustomerService) GetByID(id int64, userID int64) (*domain.Customer, error) {
 	customer, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Si se proporciona companyID, verificar pertenencia
-	if companyID > 0 && customer.CompanyID != companyID {
-		return nil, fmt.Errorf("unauthorized access to customer")
+	// Verificar que el usuario tiene acceso a la empresa del cliente
+	if _, err := s.authz.AuthorizeResource(customer.CompanyID, userID, domain.PermissionCustomersRead, "customer"); err != nil {
+		return nil, err
 	}
 
 	return customer, nil
@@ -69,20 +78,20 @@ func (s *CustomerService) GetByCompanyID(companyID int64, page, pageSize int) (*
 }
 
 // Update actualiza un cliente
-func (s *CustomerService) Update(id int64, companyID int64, req *domain.UpdateCustomerRequest) error {
+func (s *CustomerService) Update(id int64, userID int64, req *domain.UpdateCustomerRequest) error {
 	// Verificar que el cliente existe
 	customer, err := s.repo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	// Si se proporciona companyID, verificar pertenencia
-	if companyID > 0 && customer.CompanyID != companyID {
-		return fmt.Errorf("unauthorized access to customer")
+	// Verificar que el usuario tiene acceso a la empresa del cliente
+	if _, err := s.authz.AuthorizeResource(customer.CompanyID, userID, domain.PermissionCustomersWrite, "customer"); err != nil {
+		return err
 	}
 
 	// Actualizar cliente (PostgreSQL maneja validaciones)
-	if err := s.repo.Update(id, req); err != nil {
+	if err := s.repo.As(userID).Update(id, req); err != nil {
 		return err
 	}
 
@@ -90,20 +99,20 @@ func (s *CustomerService) Update(id int64, companyID int64, req *domain.UpdateCu
 }
 
 // Delete elimina (soft delete) un cliente
-func (s *CustomerService) Delete(id int64, companyID int64) error {
+func (s *CustomerService) Delete(id int64, userID int64) error {
 	// Verificar que el cliente existe
 	customer, err := s.repo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	// Si se proporciona companyID, verificar pertenencia
-	if companyID > 0 && customer.CompanyID != companyID {
-		return fmt.Errorf("unauthorized access to customer")
+	// Verificar que el usuario tiene acceso a la empresa del cliente
+	if _, err := s.authz.AuthorizeResource(customer.CompanyID, userID, domain.PermissionCustomersWrite, "customer"); err != nil {
+		return err
 	}
 
 	// Eliminar cliente
-	if err := s.repo.Delete(id); err != nil {
+	if err := s.repo.As(userID).Delete(id); err != nil {
 		return err
 	}
 
@@ -2,64 +2,63 @@ package invoice
 
 import (
 	"archive/zip"
+	"bytes"
+	"errors"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 
 	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/pkg/crypto"
 	attachedpkg "github.com/diegofxm/ubl21-dian/documents/attached"
 	"github.com/diegofxm/ubl21-dian/signature"
 )
 
-// updateInvoiceMetadata actualiza UUID y XML path en la BD
-func (s *InvoiceService) updateInvoiceMetadata(id int64, uuid, xmlPath string) error {
+// updateInvoiceMetadata actualiza UUID, la clave del XML y el certificado firmante en la BD
+func (s *InvoiceService) updateInvoiceMetadata(id int64, uuid, xmlKey string, certificateID int64) error {
 	// Actualizar UUID (CUFE)
 	if err := s.invoiceRepo.UpdateUUID(id, uuid); err != nil {
 		return err
 	}
 	
 	// Actualizar XML path
-	if err := s.invoiceRepo.UpdateXMLPath(id, xmlPath); err != nil {
+	if err := s.invoiceRepo.UpdateXMLPath(id, xmlKey); err != nil {
+		return err
+	}
+
+	// Registrar con qué certificado se firmó (histórico para re-verificar firmas)
+	if err := s.invoiceRepo.UpdateCertificateID(id, certificateID); err != nil {
 		return err
 	}
 	
 	return nil
 }
 
-// createZipFile crea un archivo ZIP con un solo archivo XML
-func (s *InvoiceService) createZipFile(zipPath, xmlFileName string, xmlContent []byte) error {
-	// Crear archivo ZIP
-	zipFile, err := os.Create(zipPath)
-	if err != nil {
-		return fmt.Errorf("error creating zip file: %w", err)
-	}
-	defer zipFile.Close()
-
-	// Crear writer ZIP
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+// createZip crea en memoria un ZIP con un solo archivo XML
+func createZip(xmlFileName string, xmlContent []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
 
 	// Agregar XML al ZIP
-	xmlWriter, err := zipWriter.Create(xmlFileName)
-	if err != nil {
-		return fmt.Errorf("error creating entry in zip: %w", err)
+	if err := addFileToZip(zipWriter, xmlFileName, xmlContent); err != nil {
+		return nil, err
 	}
 
-	if _, err := xmlWriter.Write(xmlContent); err != nil {
-		return fmt.Errorf("error writing to zip: %w", err)
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("error closing zip: %w", err)
 	}
 
-	return nil
+	return buf.Bytes(), nil
 }
 
 // GeneratePDF genera el PDF de una factura firmada
 func (s *InvoiceService) GeneratePDF(id int64, userID int64) error {
-	// 1. Obtener factura completa
-	invoice, err := s.GetByID(id, userID)
+	s = s.as(userID)
+
+	// 1. Obtener factura completa; firmar exige una empresa activa y la política de 2FA
+	invoice, err := s.getIssuable(id, userID)
 	if err != nil {
 		return err
 	}
@@ -74,8 +73,13 @@ func (s *InvoiceService) GeneratePDF(id int64, userID int64) error {
 		return fmt.Errorf("invoice does not have signed XML")
 	}
 
+	store, err := s.companyStorage(invoice)
+	if err != nil {
+		return err
+	}
+
 	// 4. Leer XML firmado
-	xmlSigned, err := os.ReadFile(*invoice.XMLPath)
+	xmlSigned, err := store.Get(*invoice.XMLPath)
 	if err != nil {
 		return fmt.Errorf("error reading signed XML: %w", err)
 	}
@@ -108,8 +112,10 @@ func (s *InvoiceService) GeneratePDF(id int64, userID int64) error {
 
 // GenerateAttachedDocument genera el AttachedDocument para el cliente
 func (s *InvoiceService) GenerateAttachedDocument(id int64, userID int64) error {
-	// 1. Obtener factura completa
-	invoice, err := s.GetByID(id, userID)
+	s = s.as(userID)
+
+	// 1. Obtener factura completa; firmar exige una empresa activa y la política de 2FA
+	invoice, err := s.getIssuable(id, userID)
 	if err != nil {
 		return err
 	}
@@ -124,21 +130,21 @@ func (s *InvoiceService) GenerateAttachedDocument(id int64, userID int64) error
 		return fmt.Errorf("invoice does not have signed XML")
 	}
 
-	// Crear directorio de factura si no existe
-	invoiceDir := s.storage.InvoicePath(invoice.Company.NIT, invoice.Number)
-	if err := os.MkdirAll(invoiceDir, 0755); err != nil {
-		return fmt.Errorf("error creating invoice directory: %w", err)
+	store, err := s.companyStorage(invoice)
+	if err != nil {
+		return err
 	}
-	
+
+	nit := invoice.Company.NIT
+
 	// 4. Leer Invoice firmado
-	invoiceXML, err := os.ReadFile(*invoice.XMLPath)
+	invoiceXML, err := store.Get(*invoice.XMLPath)
 	if err != nil {
 		return fmt.Errorf("error reading signed invoice XML: %w", err)
 	}
 
 	// 5. Leer ApplicationResponse
-	appResponsePath := s.storage.InvoiceApplicationResponsePath(invoice.Company.NIT, invoice.Number)
-	appResponseXML, err := os.ReadFile(appResponsePath)
+	appResponseXML, err := store.Get(storage.InvoiceApplicationResponseKey(nit, invoice.Number))
 	if err != nil {
 		return fmt.Errorf("error reading ApplicationResponse XML: %w", err)
 	}
@@ -194,10 +200,11 @@ func (s *InvoiceService) GenerateAttachedDocument(id int64, userID int64) error
 		return fmt.Errorf("error generating AttachedDocument XML: %w", err)
 	}
 
-	// 8. Guardar AttachedDocument sin firma (temporal, se eliminará después de firmar)
-	attachedPath := filepath.Join(invoiceDir, fmt.Sprintf("AttachedDocument-%s.xml", invoice.Number))
-	if err := os.WriteFile(attachedPath, attachedXMLBytes, 0644); err != nil {
-		return fmt.Errorf("error saving AttachedDocument XML: %w", err)
+	// 8. Guardar AttachedDocument sin firma (solo si keepUnsignedXML está activado)
+	if s.keepUnsignedXML {
+		if err := store.Put(storage.AttachedDocumentXMLKey(nit, invoice.Number), attachedXMLBytes); err != nil {
+			return fmt.Errorf("error saving AttachedDocument XML: %w", err)
+		}
 	}
 
 	// 9. Obtener certificado y firmar AttachedDocument
@@ -205,6 +212,9 @@ func (s *InvoiceService) GenerateAttachedDocument(id int64, userID int64) error
 	if err != nil {
 		return fmt.Errorf("no certificate found for company: %w", err)
 	}
+	if err := cert.CheckValidity(time.Now()); err != nil {
+		return err
+	}
 	
 	// 9.1. Desencriptar contraseña del certificado
 	decryptedPassword, err := crypto.DecryptPassword(cert.Password)
@@ -212,7 +222,11 @@ func (s *InvoiceService) GenerateAttachedDocument(id int64, userID int64) error
 		return fmt.Errorf("failed to decrypt certificate password: %w", err)
 	}
 	
-	certPath := s.storage.CertificatePath(invoice.Company.NIT, cert.Name)
+	certPath, cleanup, err := storage.Materialize(store, storage.CertificateKey(nit, cert.Name))
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %w", err)
+	}
+	defer cleanup()
 	
 	// Usar fallback automático a PEM si P12 falla
 	signer, err := signature.NewSignerFromP12WithFallback(certPath, decryptedPassword)
@@ -230,54 +244,53 @@ func (s *InvoiceService) GenerateAttachedDocument(id int64, userID int64) error
 	}
 
 	// 10. Guardar AttachedDocument firmado
-	attachedSignedPath := filepath.Join(invoiceDir, fmt.Sprintf("ad%s.xml", invoice.Number))
-	if err := os.WriteFile(attachedSignedPath, attachedXMLSigned, 0644); err != nil {
+	if err := store.Put(storage.AttachedDocumentSignedXMLKey(nit, invoice.Number), attachedXMLSigned); err != nil {
 		return fmt.Errorf("error saving signed AttachedDocument: %w", err)
 	}
 
 	// 11. Crear ZIP final con todos los documentos
-	zipPath := filepath.Join(invoiceDir, fmt.Sprintf("ad%s.zip", invoice.Number))
-	if err := s.createAttachedDocumentZip(zipPath, invoice.Number, invoiceXML, appResponseXML, attachedXMLSigned); err != nil {
+	zipData, err := createAttachedDocumentZip(invoice.Number, invoiceXML, appResponseXML, attachedXMLSigned)
+	if err != nil {
 		return fmt.Errorf("error creating final ZIP: %w", err)
 	}
+	zipKey := storage.AttachedDocumentZIPKey(nit, invoice.Number)
+	if err := store.Put(zipKey, zipData); err != nil {
+		return fmt.Errorf("error saving final ZIP: %w", err)
+	}
 
-	// 12. Actualizar BD con zip_path
-	if err := s.invoiceRepo.UpdateZIPPath(id, zipPath); err != nil {
+	// 12. Actualizar BD con zip_path (clave en storage)
+	if err := s.invoiceRepo.UpdateZIPPath(id, zipKey); err != nil {
 		return fmt.Errorf("error updating ZIP path: %w", err)
 	}
 
 	return nil
 }
 
-// createAttachedDocumentZip crea el ZIP final para el cliente con todos los documentos
-func (s *InvoiceService) createAttachedDocumentZip(zipPath, invoiceNumber string, invoiceXML, appResponseXML, attachedXML []byte) error {
-	// Crear archivo ZIP
-	zipFile, err := os.Create(zipPath)
-	if err != nil {
-		return fmt.Errorf("error creating zip file: %w", err)
-	}
-	defer zipFile.Close()
-
-	// Crear writer ZIP
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+// createAttachedDocumentZip crea en memoria el ZIP final para el cliente con todos los documentos
+func createAttachedDocumentZip(invoiceNumber string, invoiceXML, appResponseXML, attachedXML []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
 
 	// Agregar Invoice firmado
 	if err := addFileToZip(zipWriter, fmt.Sprintf("FES-%s.xml", invoiceNumber), invoiceXML); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Agregar ApplicationResponse
 	if err := addFileToZip(zipWriter, fmt.Sprintf("ApplicationResponse-%s.xml", invoiceNumber), appResponseXML); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Agregar AttachedDocument firmado
 	if err := addFileToZip(zipWriter, fmt.Sprintf("ad%s.xml", invoiceNumber), attachedXML); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("error closing zip: %w", err)
+	}
+
+	return buf.Bytes(), nil
 }
 
 // addFileToZip agrega un archivo al ZIP
@@ -294,79 +307,63 @@ func addFileToZip(zipWriter *zip.Writer, fileName string, content []byte) error
 	return nil
 }
 
-// DownloadInvoiceZip retorna el path del ZIP final para descarga
-func (s *InvoiceService) DownloadInvoiceZip(id int64, userID int64) (string, error) {
+// DownloadInvoiceZip retorna el ZIP final y su nombre de archivo para descarga
+func (s *InvoiceService) DownloadInvoiceZip(id int64, userID int64) ([]byte, string, error) {
 	invoice, err := s.GetByID(id, userID)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
+	return s.readAttachedZIP(invoice)
+}
 
+// readAttachedZIP lee el ZIP con el AttachedDocument de una factura
+func (s *InvoiceService) readAttachedZIP(invoice *domain.Invoice) ([]byte, string, error) {
 	if invoice.ZipPath == nil || *invoice.ZipPath == "" {
-		return "", fmt.Errorf("invoice does not have ZIP file, generate AttachedDocument first")
-	}
-
-	// Verificar que el archivo existe
-	if _, err := os.Stat(*invoice.ZipPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("ZIP file not found on disk")
+		return nil, "", fmt.Errorf("invoice does not have ZIP file, generate AttachedDocument first")
 	}
 
-	return *invoice.ZipPath, nil
-}
-
-// GetInvoiceXML retorna el XML firmado de una factura
-func (s *InvoiceService) GetInvoiceXML(id int64, userID int64) ([]byte, error) {
-	invoice, err := s.GetByID(id, userID)
+	store, err := s.companyStorage(invoice)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	if invoice.XMLPath == nil || *invoice.XMLPath == "" {
-		return nil, fmt.Errorf("invoice does not have signed XML")
+	zipData, err := store.Get(*invoice.ZipPath)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", fmt.Errorf("ZIP file not found in storage")
 	}
-
-	xmlContent, err := os.ReadFile(*invoice.XMLPath)
 	if err != nil {
-		return nil, fmt.Errorf("error reading XML file: %w", err)
+		return nil, "", fmt.Errorf("error reading ZIP file: %w", err)
 	}
 
-	return xmlContent, nil
+	return zipData, path.Base(*invoice.ZipPath), nil
 }
 
-// GetInvoicePDF retorna el PDF de una factura
-func (s *InvoiceService) GetInvoicePDF(id int64, userID int64) (string, error) {
+// GetInvoiceXML retorna el XML firmado de una factura
+func (s *InvoiceService) GetInvoiceXML(id int64, userID int64) ([]byte, error) {
 	invoice, err := s.GetByID(id, userID)
 	if err != nil {
-		return "", err
-	}
-
-	if invoice.PDFPath == nil || *invoice.PDFPath == "" {
-		return "", fmt.Errorf("invoice does not have PDF, generate it first")
+		return nil, err
 	}
+	return s.readSignedXML(invoice)
+}
 
-	// Verificar que el archivo existe
-	if _, err := os.Stat(*invoice.PDFPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("PDF file not found on disk")
+// readSignedXML lee el XML firmado de una factura
+func (s *InvoiceService) readSignedXML(invoice *domain.Invoice) ([]byte, error) {
+	if invoice.XMLPath == nil || *invoice.XMLPath == "" {
+		return nil, fmt.Errorf("invoice does not have signed XML")
 	}
 
-	return *invoice.PDFPath, nil
-}
-
-// CopyFile copia un archivo de src a dst
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
+	store, err := s.companyStorage(invoice)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer sourceFile.Close()
 
-	destFile, err := os.Create(dst)
+	xmlContent, err := store.Get(*invoice.XMLPath)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("error reading XML file: %w", err)
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	return xmlContent, nil
 }
 
 // getStringValue retorna el valor de un puntero a string o vacío
@@ -0,0 +1,88 @@
+package invoice
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/service"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeInvoices struct {
+	invoiceStore
+	invoice *domain.Invoice
+}
+
+func (f fakeInvoices) GetByID(id int64) (*domain.Invoice, error) {
+	if id != f.invoice.ID {
+		return nil, errors.New("invoice not found")
+	}
+	return f.invoice, nil
+}
+
+type fakeMembers struct {
+	company   *domain.Company
+	role      string
+	twoFactor bool
+}
+
+func (f fakeMembers) GetByID(id int64) (*domain.Company, error) {
+	company := *f.company
+	return &company, nil
+}
+
+func (f fakeMembers) GetMemberRole(companyID, userID int64) (string, error) {
+	return f.role, nil
+}
+
+func (f fakeMembers) MemberTwoFactorEnabled(companyID, userID int64) (bool, error) {
+	return f.twoFactor, nil
+}
+
+// Generar el AttachedDocument lo firma: aplica las mismas reglas que Sign y SendToDIAN
+func TestGenerateAttachedDocumentRequiresIssuableCompany(t *testing.T) {
+	suspendedAt := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		company domain.Company
+		members fakeMembers
+		wantErr string
+	}{
+		{
+			name:    "company requires 2FA and the user has none",
+			company: domain.Company{ID: 7, RequireTwoFactor: true},
+			members: fakeMembers{role: domain.MemberRoleAccountant},
+			wantErr: "two-factor authentication required",
+		},
+		{
+			name:    "suspended company",
+			company: domain.Company{ID: 7, SuspendedAt: &suspendedAt},
+			members: fakeMembers{role: domain.MemberRoleOwner, twoFactor: true},
+			wantErr: "company is suspended",
+		},
+		{
+			// Pasa la autorización y se detiene en la primera validación de la factura
+			name:    "company requires 2FA and the user has it",
+			company: domain.Company{ID: 7, RequireTwoFactor: true},
+			members: fakeMembers{role: domain.MemberRoleAccountant, twoFactor: true},
+			wantErr: "invoice must be sent to DIAN to generate AttachedDocument",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := tt.members
+			members.company = &tt.company
+			s := &InvoiceService{
+				invoiceRepo: fakeInvoices{invoice: &domain.Invoice{ID: 10, CompanyID: 7, Status: "draft"}},
+				authz:       service.NewAuthorizer(members),
+			}
+
+			err := s.GenerateAttachedDocument(10, 3)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
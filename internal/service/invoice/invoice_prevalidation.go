@@ -0,0 +1,60 @@
+package invoice
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/service/prevalidation"
+	"fmt"
+	"time"
+)
+
+// Prevalidate valida localmente el XML de la factura contra el XSD y las reglas DIAN sin enviarlo.
+// Las facturas firmadas se validan sobre el XML firmado almacenado; los borradores sobre el XML
+// que generaría Sign en este momento, sin persistir cambios.
+func (s *InvoiceService) Prevalidate(id int64, userID int64) (*domain.PrevalidationReport, error) {
+	invoice, err := s.getAuthorized(id, userID, domain.PermissionInvoicesWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if invoice.Status == "draft" {
+		if err := ValidateInvoiceForDIAN(invoice); err != nil {
+			return nil, fmt.Errorf("invoice validation failed: %w", err)
+		}
+
+		// Misma fecha y hora de emisión que asignaría Sign (regla FAD09e)
+		now := time.Now()
+		invoice.IssueDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		invoice.IssueTime = now
+
+		xmlUnsigned, _, err := s.BuildInvoiceWithTemplates(invoice)
+		if err != nil {
+			return nil, fmt.Errorf("error generating XML with templates: %w", err)
+		}
+		return s.prevalidator.Validate(xmlUnsigned, prevalidationContext(invoice)), nil
+	}
+
+	if invoice.XMLPath == nil || *invoice.XMLPath == "" {
+		return nil, fmt.Errorf("invoice does not have signed XML")
+	}
+	store, err := s.companyStorage(invoice)
+	if err != nil {
+		return nil, err
+	}
+	xmlSigned, err := store.Get(*invoice.XMLPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading signed XML: %w", err)
+	}
+	return s.prevalidator.Validate(xmlSigned, prevalidationContext(invoice)), nil
+}
+
+// prevalidationContext toma la clave técnica y el PIN para recalcular CUFE y SoftwareSecurityCode
+func prevalidationContext(invoice *domain.Invoice) prevalidation.Context {
+	var ctx prevalidation.Context
+	if invoice.Resolution != nil && invoice.Resolution.TechnicalKey != nil {
+		ctx.TechnicalKey = *invoice.Resolution.TechnicalKey
+	}
+	if invoice.Software != nil {
+		ctx.SoftwarePIN = invoice.Software.PIN
+	}
+	return ctx
+}
@@ -1,13 +1,16 @@
 package invoice
 
 import (
-	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/internal/repository"
+	"apidian-go/internal/service"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/internal/service/prevalidation"
 	"apidian-go/pkg/crypto"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/diegofxm/ubl21-dian/signature"
@@ -15,14 +18,34 @@ import (
 	"github.com/diegofxm/ubl21-dian/soap/types"
 )
 
+// invoiceStore son las operaciones de facturas del servicio (*repository.InvoiceRepository)
+type invoiceStore interface {
+	Create(invoice *domain.Invoice, lines []domain.InvoiceLine, origin domain.NumberOrigin) error
+	GetByID(id int64) (*domain.Invoice, error)
+	GetByCompanyID(companyID int64, limit, offset int) ([]domain.Invoice, int64, error)
+	Update(invoice *domain.Invoice) error
+	Delete(id int64) error
+	UpdateStatus(id int64, status string) error
+	UpdateUUID(id int64, uuid string) error
+	UpdateIssueDateAndTime(id int64, issueDate time.Time, issueTime time.Time) error
+	UpdateCertificateID(id int64, certificateID int64) error
+	UpdateXMLPath(id int64, xmlPath string) error
+	UpdatePDFPath(id int64, pdfPath string) error
+	UpdateZIPPath(id int64, zipPath string) error
+	UpdateTrackId(id int64, trackId string) error
+	UpdateDIANStatus(id int64, dianStatus, dianResponse, dianStatusCode, dianStatusDescription string) error
+}
+
 type InvoiceService struct {
-	invoiceRepo     *repository.InvoiceRepository
+	invoiceRepo     invoiceStore
 	companyRepo     *repository.CompanyRepository
 	customerRepo    *repository.CustomerRepository
 	resolutionRepo  *repository.ResolutionRepository
 	productRepo     *repository.ProductRepository
 	certificateRepo *repository.CertificateRepository
-	storage         *config.StorageConfig
+	authz           *service.Authorizer
+	keyring         *keyring.Keyring
+	prevalidator    *prevalidation.Validator
 	keepUnsignedXML bool
 }
 
@@ -33,7 +56,8 @@ func NewInvoiceService(
 	resolutionRepo *repository.ResolutionRepository,
 	productRepo *repository.ProductRepository,
 	certificateRepo *repository.CertificateRepository,
-	storage *config.StorageConfig,
+	keys *keyring.Keyring,
+	prevalidator *prevalidation.Validator,
 	keepUnsignedXML bool,
 ) *InvoiceService {
 	return &InvoiceService{
@@ -43,20 +67,39 @@ func NewInvoiceService(
 		resolutionRepo:  resolutionRepo,
 		productRepo:     productRepo,
 		certificateRepo: certificateRepo,
-		storage:         storage,
+		authz:           service.NewAuthorizer(companyRepo),
+		keyring:         keys,
+		prevalidator:    prevalidator,
 		keepUnsignedXML: keepUnsignedXML,
 	}
 }
 
-// Create crea una nueva factura con validaciones de negocio
-func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64) (*domain.Invoice, error) {
-	// Validar que la empresa pertenezca al usuario
-	company, err := s.companyRepo.GetByID(req.CompanyID)
+// as retorna una copia del servicio cuyas escrituras de facturas quedan en audit_log a nombre
+// del usuario
+func (s *InvoiceService) as(userID int64) *InvoiceService {
+	scoped := *s
+	if repo, ok := s.invoiceRepo.(*repository.InvoiceRepository); ok {
+		scoped.invoiceRepo = repo.As(userID)
+	}
+	return &scoped
+}
+
+// companyStorage retorna el storage cifrado con la llave de datos de la empresa de la factura
+func (s *InvoiceService) companyStorage(invoice *domain.Invoice) (storage.Storage, error) {
+	store, err := s.keyring.CompanyStorage(invoice.CompanyID)
 	if err != nil {
-		return nil, fmt.Errorf("company not found")
+		return nil, fmt.Errorf("error loading company data key: %w", err)
 	}
-	if company.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to company")
+	return store, nil
+}
+
+// Create crea una nueva factura con validaciones de negocio
+func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64) (*domain.Invoice, error) {
+	s = s.as(userID)
+
+	// Validar que el usuario puede facturar en la empresa
+	if _, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionInvoicesWrite); err != nil {
+		return nil, err
 	}
 
 	// Validar que el cliente pertenezca a la empresa
@@ -82,12 +125,6 @@ func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64)
 		return nil, fmt.Errorf("resolution is not active")
 	}
 
-	// Obtener y actualizar consecutivo de forma atómica desde resolutions.current_number
-	nextConsecutive, err := s.resolutionRepo.GetAndIncrementConsecutive(req.ResolutionID)
-	if err != nil {
-		return nil, fmt.Errorf("error getting consecutive: %w", err)
-	}
-
 	// Parsear fechas en la zona horaria local (Colombia)
 	issueDate, err := time.ParseInLocation("2006-01-02", req.IssueDate, time.Local)
 	if err != nil {
@@ -103,8 +140,32 @@ func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64)
 		dueDate = &parsed
 	}
 
+	// Cuotas y anticipos
+	installments, err := parseInstallments(req.Installments, issueDate, req.PaymentMethodID)
+	if err != nil {
+		return nil, err
+	}
+	prepayments, prepaidAmount, err := parsePrepayments(req.Prepayments, issueDate)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(installments); n > 0 {
+		lastDueDate := installments[n-1].DueDate
+		if dueDate != nil && !dueDate.Equal(lastDueDate) {
+			return nil, fmt.Errorf("invalid due_date: must match the last installment due_date")
+		}
+		dueDate = &lastDueDate
+		if req.PaymentFormID != nil && *req.PaymentFormID != 2 {
+			return nil, fmt.Errorf("invalid payment_form_id: installments require credit (2)")
+		}
+	}
+
 	// Calcular payment_form_id automáticamente si no viene en el request
 	paymentFormID := req.PaymentFormID
+	if paymentFormID == nil && len(installments) > 0 {
+		credito := 2
+		paymentFormID = &credito
+	}
 	if paymentFormID == nil {
 		// Determinar automáticamente basándose en las fechas
 		if dueDate == nil || dueDate.Equal(issueDate) {
@@ -177,16 +238,16 @@ func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64)
 
 	total := subtotal + taxTotal
 
-	// Generar número de factura (formato: PREFIX + consecutivo)
-	number := fmt.Sprintf("%s%d", resolution.Prefix, nextConsecutive)
+	if err := validatePaymentTerms(total, prepaidAmount, installments); err != nil {
+		return nil, err
+	}
 
-	// Crear factura
+	// Crear factura; el número (PREFIX + consecutivo) se asigna al guardar. Si la resolución se
+	// agotó o venció se usa su continuación y ResolutionID cambia.
 	invoice := &domain.Invoice{
 		CompanyID:       req.CompanyID,
 		CustomerID:      req.CustomerID,
-		ResolutionID:    req.ResolutionID,
-		Number:          number,
-		Consecutive:     nextConsecutive,
+		ResolutionID:    resolution.ID,
 		IssueDate:       issueDate,
 		IssueTime:       time.Now(),
 		DueDate:         dueDate,
@@ -198,11 +259,19 @@ func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64)
 		Subtotal:        subtotal,
 		TaxTotal:        taxTotal,
 		Total:           total,
+		PrepaidAmount:   prepaidAmount,
 		Status:          "draft",
+		Installments:    installments,
+		Prepayments:     prepayments,
 	}
 
 	// Guardar en base de datos
-	if err := s.invoiceRepo.Create(invoice, lines); err != nil {
+	origin := domain.NumberOrigin{Reason: req.Origin, Notes: req.OriginNotes, UserID: userID, QuotationID: req.QuotationID}
+	if err := s.invoiceRepo.Create(invoice, lines, origin); err != nil {
+		if errors.Is(err, repository.ErrResolutionUnavailable) || errors.Is(err, repository.ErrDocumentQuotaExceeded) ||
+			errors.Is(err, repository.ErrQuotationConverted) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("error creating invoice: %w", err)
 	}
 
@@ -211,18 +280,40 @@ func (s *InvoiceService) Create(req *domain.CreateInvoiceRequest, userID int64)
 
 // GetByID obtiene una factura por ID validando permisos
 func (s *InvoiceService) GetByID(id int64, userID int64) (*domain.Invoice, error) {
+	return s.getAuthorized(id, userID, domain.PermissionInvoicesRead)
+}
+
+// getAuthorized obtiene la factura si el rol del usuario tiene el permiso en su empresa
+func (s *InvoiceService) getAuthorized(id, userID int64, permission string) (*domain.Invoice, error) {
 	invoice, err := s.invoiceRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Validar que la empresa de la factura pertenezca al usuario
-	company, err := s.companyRepo.GetByID(invoice.CompanyID)
+	if _, err := s.authz.AuthorizeResource(invoice.CompanyID, userID, permission, "invoice"); err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// getIssuable es getAuthorized para firmar o enviar a DIAN: la empresa no puede estar suspendida
+// y, si exige 2FA, el usuario debe tenerlo activo
+func (s *InvoiceService) getIssuable(id, userID int64) (*domain.Invoice, error) {
+	invoice, err := s.invoiceRepo.GetByID(id)
 	if err != nil {
-		return nil, fmt.Errorf("company not found")
+		return nil, err
+	}
+
+	company, err := s.authz.AuthorizeResource(invoice.CompanyID, userID, domain.PermissionInvoicesWrite, "invoice")
+	if err != nil {
+		return nil, err
 	}
-	if company.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to invoice")
+	if company.SuspendedAt != nil {
+		return nil, fmt.Errorf("company is suspended")
+	}
+	if err := s.authz.RequireTwoFactor(company, userID); err != nil {
+		return nil, err
 	}
 
 	return invoice, nil
@@ -230,13 +321,9 @@ func (s *InvoiceService) GetByID(id int64, userID int64) (*domain.Invoice, error
 
 // GetByCompanyID gets all invoices for a company
 func (s *InvoiceService) GetByCompanyID(companyID int64, userID int64, limit, offset int) (*domain.InvoiceListResponse, error) {
-	// Validate that the company belongs to the user
-	company, err := s.companyRepo.GetByID(companyID)
-	if err != nil {
-		return nil, fmt.Errorf("company not found")
-	}
-	if company.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to company")
+	// Validate that the user can read invoices of the company
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionInvoicesRead); err != nil {
+		return nil, err
 	}
 
 	invoices, total, err := s.invoiceRepo.GetByCompanyID(companyID, limit, offset)
@@ -260,8 +347,10 @@ func (s *InvoiceService) GetByCompanyID(companyID int64, userID int64, limit, of
 
 // Update actualiza una factura (solo campos editables)
 func (s *InvoiceService) Update(id int64, req *domain.UpdateInvoiceRequest, userID int64) error {
+	s = s.as(userID)
+
 	// Obtener factura
-	invoice, err := s.GetByID(id, userID)
+	invoice, err := s.getAuthorized(id, userID, domain.PermissionInvoicesWrite)
 	if err != nil {
 		return err
 	}
@@ -293,8 +382,10 @@ func (s *InvoiceService) Update(id int64, req *domain.UpdateInvoiceRequest, user
 
 // Delete elimina una factura (solo si está en draft)
 func (s *InvoiceService) Delete(id int64, userID int64) error {
+	s = s.as(userID)
+
 	// Validar permisos
-	invoice, err := s.GetByID(id, userID)
+	invoice, err := s.getAuthorized(id, userID, domain.PermissionInvoicesWrite)
 	if err != nil {
 		return err
 	}
@@ -309,8 +400,10 @@ func (s *InvoiceService) Delete(id int64, userID int64) error {
 
 // Sign firma una factura electrónicamente con certificado digital
 func (s *InvoiceService) Sign(id int64, userID int64) error {
+	s = s.as(userID)
+
 	// 1. Obtener factura completa con JOINs
-	invoice, err := s.GetByID(id, userID)
+	invoice, err := s.getIssuable(id, userID)
 	if err != nil {
 		return err
 	}
@@ -342,16 +435,17 @@ func (s *InvoiceService) Sign(id int64, userID int64) error {
 		return fmt.Errorf("error generating XML with templates: %w", err)
 	}
 
-	// 6. Crear directorio de storage para la factura
-	invoiceDir := s.storage.InvoicePath(invoice.Company.NIT, invoice.Number)
-	if err := os.MkdirAll(invoiceDir, 0755); err != nil {
-		return fmt.Errorf("error creating invoice directory: %w", err)
+	store, err := s.companyStorage(invoice)
+	if err != nil {
+		return err
 	}
 
 	// 7. Guardar XML sin firma (opcional, solo si keepUnsignedXML está activado)
-	unsignedPath := s.storage.InvoiceXMLPath(invoice.Company.NIT, invoice.Number)
-	if err := os.WriteFile(unsignedPath, xmlUnsignedBytes, 0644); err != nil {
-		return fmt.Errorf("error saving unsigned XML: %w", err)
+	if s.keepUnsignedXML {
+		unsignedKey := storage.InvoiceXMLKey(invoice.Company.NIT, invoice.Number)
+		if err := store.Put(unsignedKey, xmlUnsignedBytes); err != nil {
+			return fmt.Errorf("error saving unsigned XML: %w", err)
+		}
 	}
 
 	// 8. Obtener certificado activo de la empresa
@@ -359,6 +453,9 @@ func (s *InvoiceService) Sign(id int64, userID int64) error {
 	if err != nil {
 		return fmt.Errorf("no certificate found for company: %w", err)
 	}
+	if err := cert.CheckValidity(time.Now()); err != nil {
+		return err
+	}
 	
 	// 8.1. Desencriptar contraseña del certificado
 	decryptedPassword, err := crypto.DecryptPassword(cert.Password)
@@ -366,8 +463,12 @@ func (s *InvoiceService) Sign(id int64, userID int64) error {
 		return fmt.Errorf("failed to decrypt certificate password: %w", err)
 	}
 	
-	// 8.2. Construir ruta al certificado P12
-	certPath := s.storage.CertificatePath(invoice.Company.NIT, cert.Name)
+	// 8.2. Descifrar el certificado P12 a un archivo temporal en memoria (el firmador requiere una ruta)
+	certPath, cleanup, err := storage.Materialize(store, storage.CertificateKey(invoice.Company.NIT, cert.Name))
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %w", err)
+	}
+	defer cleanup()
 	
 	// 9. Crear signer desde certificado P12 con fallback automático a PEM
 	// Si el P12 está en formato BER (no DER), automáticamente lo convierte a PEM usando OpenSSL
@@ -388,26 +489,18 @@ func (s *InvoiceService) Sign(id int64, userID int64) error {
 	}
 
 	// 12. Guardar XML firmado
-	signedPath := s.storage.InvoiceSignedXMLPath(invoice.Company.NIT, invoice.Number)
-	if err := os.WriteFile(signedPath, xmlSignedBytes, 0644); err != nil {
+	signedKey := storage.InvoiceSignedXMLKey(invoice.Company.NIT, invoice.Number)
+	if err := store.Put(signedKey, xmlSignedBytes); err != nil {
 		return fmt.Errorf("error saving signed XML: %w", err)
 	}
 
-	// 13. Eliminar XML sin firmar si keepUnsignedXML es false
-	if !s.keepUnsignedXML {
-		if err := os.Remove(unsignedPath); err != nil {
-			// Log el error pero no fallar el proceso de firma
-			fmt.Printf("Warning: could not delete unsigned XML: %v\n", err)
-		}
-	}
-
-	// 14. Actualizar BD con UUID (CUFE), xml_path y status
+	// 13. Actualizar BD con UUID (CUFE), xml_path (clave en storage) y status
 	if err := s.invoiceRepo.UpdateStatus(id, "signed"); err != nil {
 		return err
 	}
 
-	// Actualizar UUID y XML path
-	if err := s.updateInvoiceMetadata(id, cufe, signedPath); err != nil {
+	// Actualizar UUID, XML path y certificado firmante
+	if err := s.updateInvoiceMetadata(id, cufe, signedKey, cert.ID); err != nil {
 		return fmt.Errorf("error updating invoice metadata: %w", err)
 	}
 
@@ -416,8 +509,10 @@ func (s *InvoiceService) Sign(id int64, userID int64) error {
 
 // SendToDIAN envía una factura firmada a la DIAN vía SOAP
 func (s *InvoiceService) SendToDIAN(id int64, userID int64) error {
+	s = s.as(userID)
+
 	// 1. Obtener factura completa
-	invoice, err := s.GetByID(id, userID)
+	invoice, err := s.getIssuable(id, userID)
 	if err != nil {
 		return err
 	}
@@ -432,23 +527,33 @@ func (s *InvoiceService) SendToDIAN(id int64, userID int64) error {
 		return fmt.Errorf("invoice does not have signed XML")
 	}
 
+	store, err := s.companyStorage(invoice)
+	if err != nil {
+		return err
+	}
+
 	// 4. Leer XML firmado
-	xmlSigned, err := os.ReadFile(*invoice.XMLPath)
+	xmlSigned, err := store.Get(*invoice.XMLPath)
 	if err != nil {
 		return fmt.Errorf("error reading signed XML: %w", err)
 	}
 
-	// 5. Crear ZIP con el XML firmado
-	zipPath := s.storage.InvoiceZIPPath(invoice.Company.NIT, invoice.Number)
-	if err := s.createZipFile(zipPath, fmt.Sprintf("FES-%s.xml", invoice.Number), xmlSigned); err != nil {
-		return fmt.Errorf("error creating ZIP: %w", err)
+	// 4.1. Prevalidar localmente (XSD + reglas DIAN): lo que DIAN rechazaría no se envía
+	if report := s.prevalidator.Validate(xmlSigned, prevalidationContext(invoice)); !report.Valid {
+		return &prevalidation.Error{Report: report}
 	}
 
-	// 6. Leer ZIP y convertir a Base64
-	zipData, err := os.ReadFile(zipPath)
+	// 5. Crear ZIP con el XML firmado
+	zipData, err := createZip(fmt.Sprintf("FES-%s.xml", invoice.Number), xmlSigned)
 	if err != nil {
-		return fmt.Errorf("error reading ZIP: %w", err)
+		return fmt.Errorf("error creating ZIP: %w", err)
 	}
+	zipKey := storage.InvoiceZIPKey(invoice.Company.NIT, invoice.Number)
+	if err := store.Put(zipKey, zipData); err != nil {
+		return fmt.Errorf("error saving ZIP: %w", err)
+	}
+
+	// 6. Convertir ZIP a Base64
 	zipBase64 := base64.StdEncoding.EncodeToString(zipData)
 
 	// 7. Determinar ambiente DIAN
@@ -464,6 +569,9 @@ func (s *InvoiceService) SendToDIAN(id int64, userID int64) error {
 	if err != nil {
 		return fmt.Errorf("no certificate found for company: %w", err)
 	}
+	if err := cert.CheckValidity(time.Now()); err != nil {
+		return err
+	}
 	
 	// 8.1. Desencriptar contraseña del certificado
 	decryptedPassword, err := crypto.DecryptPassword(cert.Password)
@@ -471,8 +579,13 @@ func (s *InvoiceService) SendToDIAN(id int64, userID int64) error {
 		return fmt.Errorf("failed to decrypt certificate password: %w", err)
 	}
 	
-	// 8.2. Construir ruta al certificado P12
-	certPath := s.storage.CertificatePath(invoice.Company.NIT, cert.Name)
+	// 8.2. Descifrar el certificado P12 a un archivo temporal en memoria; el PEM de cliente
+	// que genera ConvertP12ToClientPEM queda en el mismo directorio y se elimina con cleanup
+	certPath, cleanup, err := storage.Materialize(store, storage.CertificateKey(invoice.Company.NIT, cert.Name))
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %w", err)
+	}
+	defer cleanup()
 	
 	// 8.3. Convertir P12 a PEM de cliente (solo cert de usuario, sin CA certs)
 	clientPemPath, err := signature.ConvertP12ToClientPEM(certPath, decryptedPassword)
@@ -517,8 +630,8 @@ func (s *InvoiceService) SendToDIAN(id int64, userID int64) error {
 	if response.XmlBase64Bytes != "" {
 		appResponseXML, err := base64.StdEncoding.DecodeString(response.XmlBase64Bytes)
 		if err == nil {
-			appResponsePath := s.storage.InvoiceApplicationResponsePath(invoice.Company.NIT, invoice.Number)
-			if err := os.WriteFile(appResponsePath, appResponseXML, 0644); err != nil {
+			appResponseKey := storage.InvoiceApplicationResponseKey(invoice.Company.NIT, invoice.Number)
+			if err := store.Put(appResponseKey, appResponseXML); err != nil {
 				fmt.Printf("Warning: Failed to save ApplicationResponse: %v\n", err)
 			}
 		}
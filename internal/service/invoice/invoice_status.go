@@ -1,10 +1,11 @@
 package invoice
 
 import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
 	"apidian-go/pkg/crypto"
 	"encoding/base64"
 	"fmt"
-	"os"
 
 	"github.com/diegofxm/ubl21-dian/signature"
 	"github.com/diegofxm/ubl21-dian/soap"
@@ -13,8 +14,10 @@ import (
 
 // GetInvoiceStatus consulta el estado de una factura en DIAN usando TrackId
 func (s *InvoiceService) GetInvoiceStatus(id int64, trackID string, userID int64) error {
+	s = s.as(userID)
+
 	// 1. Obtener factura
-	invoice, err := s.GetByID(id, userID)
+	invoice, err := s.getAuthorized(id, userID, domain.PermissionInvoicesWrite)
 	if err != nil {
 		return err
 	}
@@ -35,7 +38,17 @@ func (s *InvoiceService) GetInvoiceStatus(id int64, trackID string, userID int64
 		return fmt.Errorf("failed to decrypt certificate password: %w", err)
 	}
 
-	certPath := s.storage.CertificatePath(invoice.Company.NIT, cert.Name)
+	store, err := s.companyStorage(invoice)
+	if err != nil {
+		return err
+	}
+
+	certPath, cleanup, err := storage.Materialize(store, storage.CertificateKey(invoice.Company.NIT, cert.Name))
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %w", err)
+	}
+	defer cleanup()
+
 	clientPemPath, err := signature.ConvertP12ToClientPEM(certPath, decryptedPassword)
 	if err != nil {
 		return fmt.Errorf("failed to convert certificate: %w", err)
@@ -72,8 +85,8 @@ func (s *InvoiceService) GetInvoiceStatus(id int64, trackID string, userID int64
 	if statusResp.XmlBase64Bytes != "" {
 		appResponseXML, err := base64.StdEncoding.DecodeString(statusResp.XmlBase64Bytes)
 		if err == nil {
-			appResponsePath := s.storage.InvoiceApplicationResponsePath(invoice.Company.NIT, invoice.Number)
-			if err := os.WriteFile(appResponsePath, appResponseXML, 0644); err != nil {
+			appResponseKey := storage.InvoiceApplicationResponseKey(invoice.Company.NIT, invoice.Number)
+			if err := store.Put(appResponseKey, appResponseXML); err != nil {
 				fmt.Printf("Warning: Failed to save ApplicationResponse: %v\n", err)
 			}
 		}
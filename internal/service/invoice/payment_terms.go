@@ -0,0 +1,181 @@
+package invoice
+
+import (
+	"apidian-go/internal/domain"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Tolerancia para comparar sumas de cuotas/anticipos con los totales de la factura
+const amountTolerance = 0.01
+
+// parseInstallments convierte las cuotas del request validando fechas y orden de vencimiento
+func parseInstallments(reqs []domain.CreateInstallmentRequest, issueDate time.Time, defaultMethodID *int) ([]domain.InvoiceInstallment, error) {
+	installments := make([]domain.InvoiceInstallment, 0, len(reqs))
+
+	for i, req := range reqs {
+		dueDate, err := time.ParseInLocation("2006-01-02", req.DueDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid due_date format in installment %d, use YYYY-MM-DD", i+1)
+		}
+		if dueDate.Before(issueDate) {
+			return nil, fmt.Errorf("invalid installment %d: due_date must be on or after issue_date", i+1)
+		}
+		if i > 0 && !dueDate.After(installments[i-1].DueDate) {
+			return nil, fmt.Errorf("invalid installment %d: due dates must be in ascending order", i+1)
+		}
+		if req.Amount <= 0 {
+			return nil, fmt.Errorf("invalid installment %d: amount must be greater than 0", i+1)
+		}
+
+		methodID := req.PaymentMethodID
+		if methodID == nil {
+			methodID = defaultMethodID
+		}
+
+		installments = append(installments, domain.InvoiceInstallment{
+			InstallmentNumber: i + 1,
+			DueDate:           dueDate,
+			Amount:            roundAmount(req.Amount),
+			PaymentMethodID:   methodID,
+		})
+	}
+
+	return installments, nil
+}
+
+// parsePrepayments convierte los anticipos del request; deben haberse recibido a más tardar en issue_date
+func parsePrepayments(reqs []domain.CreatePrepaymentRequest, issueDate time.Time) ([]domain.InvoicePrepayment, float64, error) {
+	prepayments := make([]domain.InvoicePrepayment, 0, len(reqs))
+	var total float64
+
+	for i, req := range reqs {
+		receivedDate, err := time.ParseInLocation("2006-01-02", req.ReceivedDate, time.Local)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid received_date format in prepayment %d, use YYYY-MM-DD", i+1)
+		}
+		if receivedDate.After(issueDate) {
+			return nil, 0, fmt.Errorf("invalid prepayment %d: received_date must be on or before issue_date", i+1)
+		}
+		if req.Amount <= 0 {
+			return nil, 0, fmt.Errorf("invalid prepayment %d: amount must be greater than 0", i+1)
+		}
+
+		amount := roundAmount(req.Amount)
+		total += amount
+		prepayments = append(prepayments, domain.InvoicePrepayment{
+			ReceivedDate: receivedDate,
+			Amount:       amount,
+			Reference:    req.Reference,
+		})
+	}
+
+	return prepayments, roundAmount(total), nil
+}
+
+// validatePaymentTerms verifica que anticipos y cuotas cuadren con el total de la factura
+func validatePaymentTerms(total, prepaid float64, installments []domain.InvoiceInstallment) error {
+	if prepaid > total+amountTolerance {
+		return fmt.Errorf("invalid prepayments: total prepaid (%.2f) exceeds invoice total (%.2f)", prepaid, total)
+	}
+
+	if len(installments) == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, installment := range installments {
+		sum += installment.Amount
+	}
+	payable := total - prepaid
+	if math.Abs(sum-payable) > amountTolerance {
+		return fmt.Errorf("invalid installments: sum (%.2f) must equal payable amount (%.2f)", sum, payable)
+	}
+
+	return nil
+}
+
+func roundAmount(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+var (
+	paymentMeansPattern  = regexp.MustCompile(`(?s)([ \t]*)<cac:PaymentMeans>.*?</cac:PaymentMeans>`)
+	prepaidAmountPattern = regexp.MustCompile(`<cbc:PrepaidAmount([^>]*)>[^<]*</cbc:PrepaidAmount>`)
+	payableAmountPattern = regexp.MustCompile(`([ \t]*)<cbc:PayableAmount([^>]*)>[^<]*</cbc:PayableAmount>`)
+)
+
+// applyPaymentTerms completa el XML generado por el builder (que solo admite un PaymentMeans)
+// con un PaymentMeans/PaymentDueDate por cuota, los PrepaidPayment y el PrepaidAmount.
+// Se aplica antes de firmar para que el digest cubra el documento final.
+func applyPaymentTerms(xmlBytes []byte, inv *domain.Invoice) ([]byte, error) {
+	if len(inv.Installments) == 0 && len(inv.Prepayments) == 0 {
+		return xmlBytes, nil
+	}
+
+	xmlStr := string(xmlBytes)
+
+	loc := paymentMeansPattern.FindStringSubmatchIndex(xmlStr)
+	if loc == nil {
+		return nil, fmt.Errorf("PaymentMeans not found in generated XML")
+	}
+	indent := xmlStr[loc[2]:loc[3]]
+
+	var b strings.Builder
+	if len(inv.Installments) == 0 {
+		b.WriteString(xmlStr[loc[0]:loc[1]])
+	}
+	for i, installment := range inv.Installments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		var methodID *int64
+		if installment.PaymentMethodID != nil {
+			id := int64(*installment.PaymentMethodID)
+			methodID = &id
+		}
+		fmt.Fprintf(&b, "%s<cac:PaymentMeans>\n", indent)
+		fmt.Fprintf(&b, "%s\t<cbc:ID>2</cbc:ID>\n", indent) // 2 = Crédito
+		fmt.Fprintf(&b, "%s\t<cbc:PaymentMeansCode>%s</cbc:PaymentMeansCode>\n", indent, getPaymentMethodCode(methodID))
+		fmt.Fprintf(&b, "%s\t<cbc:PaymentDueDate>%s</cbc:PaymentDueDate>\n", indent, installment.DueDate.Format("2006-01-02"))
+		fmt.Fprintf(&b, "%s\t<cbc:PaymentID>%d</cbc:PaymentID>\n", indent, installment.InstallmentNumber)
+		fmt.Fprintf(&b, "%s</cac:PaymentMeans>", indent)
+	}
+
+	// PrepaidPayment va después de PaymentMeans en el orden del esquema UBL
+	for i, prepayment := range inv.Prepayments {
+		receivedDate := prepayment.ReceivedDate.Format("2006-01-02")
+		fmt.Fprintf(&b, "\n%s<cac:PrepaidPayment>\n", indent)
+		fmt.Fprintf(&b, "%s\t<cbc:ID>%d</cbc:ID>\n", indent, i+1)
+		fmt.Fprintf(&b, "%s\t<cbc:PaidAmount currencyID=\"COP\">%.2f</cbc:PaidAmount>\n", indent, prepayment.Amount)
+		fmt.Fprintf(&b, "%s\t<cbc:ReceivedDate>%s</cbc:ReceivedDate>\n", indent, receivedDate)
+		fmt.Fprintf(&b, "%s\t<cbc:PaidDate>%s</cbc:PaidDate>\n", indent, receivedDate)
+		if prepayment.Reference != nil && *prepayment.Reference != "" {
+			fmt.Fprintf(&b, "%s\t<cbc:InstructionID>%s</cbc:InstructionID>\n", indent, html.EscapeString(*prepayment.Reference))
+		}
+		fmt.Fprintf(&b, "%s</cac:PrepaidPayment>", indent)
+	}
+
+	xmlStr = xmlStr[:loc[0]] + b.String() + xmlStr[loc[1]:]
+
+	// LegalMonetaryTotal: PrepaidAmount va inmediatamente antes de PayableAmount
+	if !payableAmountPattern.MatchString(xmlStr) {
+		return nil, fmt.Errorf("PayableAmount not found in generated XML")
+	}
+	prepaid := fmt.Sprintf("%.2f", inv.PrepaidAmount)
+	payable := fmt.Sprintf("%.2f", inv.PayableAmount())
+
+	if prepaidAmountPattern.MatchString(xmlStr) {
+		xmlStr = prepaidAmountPattern.ReplaceAllString(xmlStr, "<cbc:PrepaidAmount$1>"+prepaid+"</cbc:PrepaidAmount>")
+		xmlStr = payableAmountPattern.ReplaceAllString(xmlStr, "$1<cbc:PayableAmount$2>"+payable+"</cbc:PayableAmount>")
+	} else {
+		xmlStr = payableAmountPattern.ReplaceAllString(xmlStr,
+			"$1<cbc:PrepaidAmount$2>"+prepaid+"</cbc:PrepaidAmount>\n$1<cbc:PayableAmount$2>"+payable+"</cbc:PayableAmount>")
+	}
+
+	return []byte(xmlStr), nil
+}
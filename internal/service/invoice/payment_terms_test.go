@@ -0,0 +1,186 @@
+package invoice
+
+import (
+	"apidian-go/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInstallments(t *testing.T) {
+	issue := time.Date(2026, time.March, 15, 0, 0, 0, 0, time.Local)
+	transfer := 4
+	cash := 1
+
+	tests := []struct {
+		name     string
+		reqs     []domain.CreateInstallmentRequest
+		wantErr  string
+		dueDates []string
+		methods  []int
+	}{
+		{
+			name: "ascending schedule with default method",
+			reqs: []domain.CreateInstallmentRequest{
+				{DueDate: "2026-03-15", Amount: 100000},
+				{DueDate: "2026-04-15", Amount: 100000.004, PaymentMethodID: &transfer},
+				{DueDate: "2026-05-15", Amount: 100000},
+			},
+			dueDates: []string{"2026-03-15", "2026-04-15", "2026-05-15"},
+			methods:  []int{cash, transfer, cash},
+		},
+		{name: "invalid date", reqs: []domain.CreateInstallmentRequest{{DueDate: "15/04/2026", Amount: 1}}, wantErr: "invalid due_date format in installment 1"},
+		{name: "due before issue", reqs: []domain.CreateInstallmentRequest{{DueDate: "2026-03-14", Amount: 1}}, wantErr: "installment 1: due_date must be on or after issue_date"},
+		{
+			name:    "same due date twice",
+			reqs:    []domain.CreateInstallmentRequest{{DueDate: "2026-04-15", Amount: 1}, {DueDate: "2026-04-15", Amount: 1}},
+			wantErr: "installment 2: due dates must be in ascending order",
+		},
+		{
+			name:    "descending due dates",
+			reqs:    []domain.CreateInstallmentRequest{{DueDate: "2026-05-15", Amount: 1}, {DueDate: "2026-04-15", Amount: 1}},
+			wantErr: "installment 2: due dates must be in ascending order",
+		},
+		{name: "zero amount", reqs: []domain.CreateInstallmentRequest{{DueDate: "2026-04-15", Amount: 0}}, wantErr: "installment 1: amount must be greater than 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installments, err := parseInstallments(tt.reqs, issue, &cash)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, installment := range installments {
+				if installment.InstallmentNumber != i+1 || installment.DueDate.Format("2006-01-02") != tt.dueDates[i] || *installment.PaymentMethodID != tt.methods[i] {
+					t.Errorf("installment %d = %+v", i+1, installment)
+				}
+			}
+			if installments[1].Amount != 100000 {
+				t.Errorf("amount = %v, want rounded to cents", installments[1].Amount)
+			}
+		})
+	}
+}
+
+func TestParsePrepayments(t *testing.T) {
+	issue := time.Date(2026, time.March, 15, 0, 0, 0, 0, time.Local)
+
+	prepayments, total, err := parsePrepayments([]domain.CreatePrepaymentRequest{
+		{ReceivedDate: "2026-03-01", Amount: 50000.123},
+		{ReceivedDate: "2026-03-15", Amount: 25000},
+	}, issue)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prepayments) != 2 || total != 75000.12 {
+		t.Errorf("prepayments = %+v, total %.2f", prepayments, total)
+	}
+
+	_, _, err = parsePrepayments([]domain.CreatePrepaymentRequest{{ReceivedDate: "2026-03-16", Amount: 1}}, issue)
+	if err == nil || !strings.Contains(err.Error(), "received_date must be on or before issue_date") {
+		t.Errorf("prepayment after issue: error = %v", err)
+	}
+	_, _, err = parsePrepayments([]domain.CreatePrepaymentRequest{{ReceivedDate: "2026-03-01", Amount: -5}}, issue)
+	if err == nil || !strings.Contains(err.Error(), "amount must be greater than 0") {
+		t.Errorf("negative prepayment: error = %v", err)
+	}
+}
+
+func TestValidatePaymentTerms(t *testing.T) {
+	schedule := func(amounts ...float64) []domain.InvoiceInstallment {
+		result := make([]domain.InvoiceInstallment, len(amounts))
+		for i, amount := range amounts {
+			result[i] = domain.InvoiceInstallment{InstallmentNumber: i + 1, Amount: amount}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name         string
+		total        float64
+		prepaid      float64
+		installments []domain.InvoiceInstallment
+		wantErr      string
+	}{
+		{name: "no terms", total: 1190000},
+		{name: "installments equal the total", total: 1190000, installments: schedule(595000, 595000)},
+		{name: "installments equal the payable after prepayment", total: 1190000, prepaid: 190000, installments: schedule(500000, 500000)},
+		{name: "prepayment covers the total", total: 1190000, prepaid: 1190000},
+		{name: "prepayment exceeds the total", total: 1190000, prepaid: 1190000.02, wantErr: "total prepaid (1190000.02) exceeds invoice total (1190000.00)"},
+		{name: "installments short of the payable", total: 1190000, prepaid: 190000, installments: schedule(500000, 499999.98), wantErr: "sum (999999.98) must equal payable amount (1000000.00)"},
+		{name: "installments ignore the prepayment", total: 1190000, prepaid: 190000, installments: schedule(595000, 595000), wantErr: "sum (1190000.00) must equal payable amount (1000000.00)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePaymentTerms(tt.total, tt.prepaid, tt.installments)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyPaymentTerms(t *testing.T) {
+	const generated = "<Invoice>\n" +
+		"\t<cac:PaymentMeans>\n\t\t<cbc:ID>1</cbc:ID>\n\t\t<cbc:PaymentMeansCode>10</cbc:PaymentMeansCode>\n\t</cac:PaymentMeans>\n" +
+		"\t<cac:LegalMonetaryTotal>\n" +
+		"\t\t<cbc:TaxInclusiveAmount currencyID=\"COP\">1190000.00</cbc:TaxInclusiveAmount>\n" +
+		"\t\t<cbc:PayableAmount currencyID=\"COP\">1190000.00</cbc:PayableAmount>\n" +
+		"\t</cac:LegalMonetaryTotal>\n</Invoice>"
+
+	transfer := 4
+	reference := "RC-<001>"
+	inv := &domain.Invoice{
+		Total:         1190000,
+		PrepaidAmount: 190000,
+		Installments: []domain.InvoiceInstallment{
+			{InstallmentNumber: 1, DueDate: time.Date(2026, time.April, 15, 0, 0, 0, 0, time.Local), Amount: 500000},
+			{InstallmentNumber: 2, DueDate: time.Date(2026, time.May, 15, 0, 0, 0, 0, time.Local), Amount: 500000, PaymentMethodID: &transfer},
+		},
+		Prepayments: []domain.InvoicePrepayment{
+			{ReceivedDate: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.Local), Amount: 190000, Reference: &reference},
+		},
+	}
+
+	out, err := applyPaymentTerms([]byte(generated), inv)
+	if err != nil {
+		t.Fatalf("applyPaymentTerms: %v", err)
+	}
+	xml := string(out)
+
+	for _, want := range []string{
+		"<cbc:PaymentDueDate>2026-04-15</cbc:PaymentDueDate>\n\t\t<cbc:PaymentID>1</cbc:PaymentID>",
+		"<cbc:PaymentMeansCode>30</cbc:PaymentMeansCode>\n\t\t<cbc:PaymentDueDate>2026-05-15</cbc:PaymentDueDate>\n\t\t<cbc:PaymentID>2</cbc:PaymentID>",
+		"<cbc:PaidAmount currencyID=\"COP\">190000.00</cbc:PaidAmount>",
+		"<cbc:InstructionID>RC-&lt;001&gt;</cbc:InstructionID>",
+		"\t\t<cbc:PrepaidAmount currencyID=\"COP\">190000.00</cbc:PrepaidAmount>\n\t\t<cbc:PayableAmount currencyID=\"COP\">1000000.00</cbc:PayableAmount>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("XML does not contain %q:\n%s", want, xml)
+		}
+	}
+	if strings.Count(xml, "<cac:PaymentMeans>") != 2 || strings.Contains(xml, "<cbc:ID>1</cbc:ID>\n\t\t<cbc:PaymentMeansCode>10") {
+		t.Errorf("the builder PaymentMeans must be replaced by one per installment:\n%s", xml)
+	}
+	if strings.Index(xml, "<cac:PrepaidPayment>") < strings.LastIndex(xml, "</cac:PaymentMeans>") {
+		t.Error("PrepaidPayment must follow PaymentMeans")
+	}
+
+	// Sin cuotas ni anticipos el XML no cambia
+	if out, err := applyPaymentTerms([]byte(generated), &domain.Invoice{Total: 1190000}); err != nil || string(out) != generated {
+		t.Errorf("invoice without terms changed: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,243 @@
+package invoice
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/mail"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service/pdf"
+	"apidian-go/pkg/crypto"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PortalService atiende el portal del comprador: enlaces firmados (HMAC) y con vencimiento para
+// ver y descargar una factura, y registro de los eventos RADIAN sobre ella. El enlace es la única
+// credencial; quien lo tenga puede ver el documento hasta que venza.
+type PortalService struct {
+	invoices  *InvoiceService
+	eventRepo *repository.DocumentEventRepository
+	pdf       *pdf.PDFInvoiceService
+	mailer    mail.Mailer
+	cfg       *config.PortalConfig
+}
+
+func NewPortalService(
+	invoices *InvoiceService,
+	eventRepo *repository.DocumentEventRepository,
+	pdfService *pdf.PDFInvoiceService,
+	mailer mail.Mailer,
+	cfg *config.PortalConfig,
+) *PortalService {
+	return &PortalService{
+		invoices:  invoices,
+		eventRepo: eventRepo,
+		pdf:       pdfService,
+		mailer:    mailer,
+		cfg:       cfg,
+	}
+}
+
+// PortalPath retorna la ruta del portal de un documento
+func PortalPath(id int64) string {
+	return fmt.Sprintf("/api/v1/portal/documents/%d", id)
+}
+
+// linkMessage es lo que firma el enlace: el documento y el vencimiento
+func linkMessage(id, expires int64) string {
+	return fmt.Sprintf("document:%d:%d", id, expires)
+}
+
+// LinkQuery retorna el query string firmado (expires y signature) de un documento
+func (s *PortalService) LinkQuery(id int64, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", crypto.SignHMAC(s.cfg.LinkSecret, linkMessage(id, expires)))
+	return query.Encode()
+}
+
+// CreateLink emite el enlace firmado de una factura firmada con PORTAL_BASE_URL como base; con
+// SendEmail lo envía al email del cliente. Compartir la factura exige el permiso de escritura.
+func (s *PortalService) CreateLink(id, userID int64, req *domain.CreateDocumentLinkRequest) (*domain.DocumentLink, error) {
+	if s.cfg.BaseURL == "" {
+		return nil, fmt.Errorf("portal base URL is not configured")
+	}
+	invoice, err := s.invoices.getAuthorized(id, userID, domain.PermissionInvoicesWrite)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.XMLPath == nil || *invoice.XMLPath == "" {
+		return nil, fmt.Errorf("invoice must be signed to share it")
+	}
+	if req.SendEmail && (invoice.Customer == nil || invoice.Customer.Email == nil || *invoice.Customer.Email == "") {
+		return nil, fmt.Errorf("customer has no email")
+	}
+
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = s.cfg.LinkHours
+	}
+
+	expiresAt := time.Now().Add(time.Duration(hours) * time.Hour).Truncate(time.Second)
+	base := s.cfg.BaseURL + PortalPath(id)
+	query := "?" + s.LinkQuery(id, expiresAt)
+	link := &domain.DocumentLink{
+		URL:       base + query,
+		PDFURL:    base + "/pdf" + query,
+		XMLURL:    base + "/xml" + query,
+		ZIPURL:    base + "/attached" + query,
+		ExpiresAt: expiresAt,
+	}
+
+	if req.SendEmail {
+		link.EmailedTo = invoice.Customer.Email
+		s.sendLink(invoice, link)
+	}
+	return link, nil
+}
+
+// sendLink envía el enlace al cliente en segundo plano; un fallo del servidor SMTP solo queda en el log
+func (s *PortalService) sendLink(invoice *domain.Invoice, link *domain.DocumentLink) {
+	msg := mail.Message{
+		To:      *invoice.Customer.Email,
+		Subject: fmt.Sprintf("Factura electrónica %s de %s", invoice.Number, invoice.Company.Name),
+		Body: fmt.Sprintf("Hola %s,\n\n"+
+			"%s te envió la factura electrónica %s por %.2f %s.\n\n"+
+			"En el siguiente enlace puedes verla, descargar el PDF, el XML y el AttachedDocument, y registrar el acuse de recibo, el recibo de la mercancía, la aceptación o un reclamo:\n\n"+
+			"%s\n\n"+
+			"El enlace vence el %s.\n",
+			invoice.Customer.Name, invoice.Company.Name, invoice.Number, invoice.Total, invoice.CurrencyCode,
+			link.URL, link.ExpiresAt.Format("2006-01-02 15:04")),
+	}
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Warning: failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// Verify valida la firma y el vencimiento de un enlace
+func (s *PortalService) Verify(id, expires int64, signature string) error {
+	if expires < time.Now().Unix() || !crypto.VerifyHMAC(s.cfg.LinkSecret, linkMessage(id, expires), signature) {
+		return errors.New("invalid or expired link")
+	}
+	return nil
+}
+
+// getInvoice obtiene la factura de un enlace ya verificado; solo las firmadas tienen portal
+func (s *PortalService) getInvoice(id int64) (*domain.Invoice, error) {
+	invoice, err := s.invoices.invoiceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.XMLPath == nil || *invoice.XMLPath == "" {
+		return nil, fmt.Errorf("invoice not found")
+	}
+	return invoice, nil
+}
+
+// GetDocument retorna la factura con sus eventos y los que el comprador puede registrar
+func (s *PortalService) GetDocument(id int64) (*domain.PortalDocument, error) {
+	invoice, err := s.getInvoice(id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.eventRepo.GetByDocumentID(id)
+	if err != nil {
+		return nil, err
+	}
+	rejectionTypes, err := s.eventRepo.GetRejectionTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	document := &domain.PortalDocument{
+		Invoice:         invoice,
+		Events:          events,
+		AvailableEvents: []string{},
+		RejectionTypes:  rejectionTypes,
+		HasXML:          true,
+		HasZIP:          invoice.ZipPath != nil && *invoice.ZipPath != "",
+	}
+	for _, code := range domain.BuyerEvents {
+		if domain.CheckBuyerEvent(invoice.AcceptedByDIANAt, events, code) == nil {
+			document.AvailableEvents = append(document.AvailableEvents, code)
+		}
+	}
+	return document, nil
+}
+
+// GetPDF genera el PDF de la factura
+func (s *PortalService) GetPDF(id int64) ([]byte, string, error) {
+	invoice, err := s.getInvoice(id)
+	if err != nil {
+		return nil, "", err
+	}
+	pdfBytes, err := s.pdf.GenerateInvoicePDF(invoice)
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating PDF: %w", err)
+	}
+	return pdfBytes, invoice.Number + ".pdf", nil
+}
+
+// GetXML retorna el XML firmado de la factura
+func (s *PortalService) GetXML(id int64) ([]byte, string, error) {
+	invoice, err := s.getInvoice(id)
+	if err != nil {
+		return nil, "", err
+	}
+	xmlContent, err := s.invoices.readSignedXML(invoice)
+	if err != nil {
+		return nil, "", err
+	}
+	return xmlContent, invoice.Number + ".xml", nil
+}
+
+// GetAttachedZIP retorna el ZIP con el AttachedDocument de la factura
+func (s *PortalService) GetAttachedZIP(id int64) ([]byte, string, error) {
+	invoice, err := s.getInvoice(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.invoices.readAttachedZIP(invoice)
+}
+
+// RegisterEvent registra un evento RADIAN del comprador. Los eventos siguen el orden de RADIAN:
+// acuse (030), recibo del bien (032) y luego aceptación expresa (033) o reclamo (031), no ambos;
+// el repositorio lo valida con el documento bloqueado (domain.CheckBuyerEvent).
+func (s *PortalService) RegisterEvent(id int64, req *domain.RegisterDocumentEventRequest, ip string) (*domain.DocumentEvent, error) {
+	if _, err := s.getInvoice(id); err != nil {
+		return nil, err
+	}
+
+	event := &domain.DocumentEvent{
+		DocumentID:                 id,
+		EventCode:                  req.Event,
+		RegisteredByName:           &req.Name,
+		RegisteredByIdentification: &req.IdentificationNumber,
+		IPAddress:                  &ip,
+	}
+	if req.Event == domain.EventClaim {
+		event.RejectionCode = &req.RejectionCode
+	}
+	if req.Notes != "" {
+		event.Notes = &req.Notes
+	}
+	if err := s.eventRepo.Create(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetEvents lista los eventos del comprador sobre una factura de la empresa del usuario
+func (s *PortalService) GetEvents(id, userID int64) ([]domain.DocumentEvent, error) {
+	if _, err := s.invoices.GetByID(id, userID); err != nil {
+		return nil, err
+	}
+	return s.eventRepo.GetByDocumentID(id)
+}
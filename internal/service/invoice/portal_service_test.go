@@ -0,0 +1,113 @@
+package invoice
+
+import (
+	"apidian-go/internal/config"
+	"apidian-go/internal/domain"
+	"apidian-go/internal/service"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPortalLinkQuery(t *testing.T) {
+	s := &PortalService{cfg: &config.PortalConfig{LinkSecret: "portal-secret"}}
+	expiresAt := time.Unix(1893456000, 0) // 2030-01-01
+
+	query, err := url.ParseQuery(s.LinkQuery(42, expiresAt))
+	if err != nil {
+		t.Fatalf("ParseQuery: %v", err)
+	}
+	if got := query.Get("expires"); got != "1893456000" {
+		t.Errorf("expires = %q, want 1893456000", got)
+	}
+	// HMAC-SHA256("portal-secret", "document:42:1893456000")
+	const want = "56a82fdb69892f3df1e3f64f77e005f710c918dcabd42e35200b49744ee149e1"
+	if got := query.Get("signature"); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if err := s.Verify(42, 1893456000, query.Get("signature")); err != nil {
+		t.Errorf("Verify of the issued link: %v", err)
+	}
+}
+
+func TestPortalVerify(t *testing.T) {
+	s := &PortalService{cfg: &config.PortalConfig{LinkSecret: "portal-secret"}}
+	other := &PortalService{cfg: &config.PortalConfig{LinkSecret: "another-secret"}}
+
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	past := time.Now().Add(-time.Second).Truncate(time.Second)
+	signature := func(s *PortalService, id int64, expiresAt time.Time) string {
+		query, _ := url.ParseQuery(s.LinkQuery(id, expiresAt))
+		return query.Get("signature")
+	}
+	tampered := []byte(signature(s, 10, future))
+	if tampered[0] == '0' {
+		tampered[0] = '1'
+	} else {
+		tampered[0] = '0'
+	}
+
+	tests := []struct {
+		name      string
+		id        int64
+		expires   int64
+		signature string
+		wantErr   bool
+	}{
+		{"valid link", 10, future.Unix(), signature(s, 10, future), false},
+		{"expired link", 10, past.Unix(), signature(s, 10, past), true},
+		{"other document", 11, future.Unix(), signature(s, 10, future), true},
+		{"extended expiry", 10, future.Add(24 * time.Hour).Unix(), signature(s, 10, future), true},
+		{"signed with another secret", 10, future.Unix(), signature(other, 10, future), true},
+		{"tampered signature", 10, future.Unix(), string(tampered), true},
+		{"empty signature", 10, future.Unix(), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Verify(tt.id, tt.expires, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify(%d, %d) error = %v, wantErr %v", tt.id, tt.expires, err, tt.wantErr)
+			}
+			if err != nil && err.Error() != "invalid or expired link" {
+				t.Errorf("error = %q, want invalid or expired link", err)
+			}
+		})
+	}
+}
+
+func TestCreateLink(t *testing.T) {
+	xmlPath := "900123456/invoices/SETP990000001.xml"
+	newService := func(baseURL, role string) *PortalService {
+		invoices := &InvoiceService{
+			invoiceRepo: fakeInvoices{invoice: &domain.Invoice{ID: 10, CompanyID: 7, XMLPath: &xmlPath}},
+			authz:       service.NewAuthorizer(fakeMembers{company: &domain.Company{ID: 7}, role: role}),
+		}
+		return &PortalService{
+			invoices: invoices,
+			cfg:      &config.PortalConfig{LinkSecret: "portal-secret", LinkHours: 24, BaseURL: baseURL},
+		}
+	}
+
+	link, err := newService("https://api.example.com", domain.MemberRoleCashier).CreateLink(10, 3, &domain.CreateDocumentLinkRequest{})
+	if err != nil {
+		t.Fatalf("CreateLink: %v", err)
+	}
+	if !strings.HasPrefix(link.URL, "https://api.example.com/api/v1/portal/documents/10?") {
+		t.Errorf("url = %q, want it under PORTAL_BASE_URL", link.URL)
+	}
+
+	// Solo lectura no puede compartir la factura
+	_, err = newService("https://api.example.com", domain.MemberRoleReadOnly).CreateLink(10, 3, &domain.CreateDocumentLinkRequest{SendEmail: true})
+	var denied *domain.AccessError
+	if !errors.As(err, &denied) || denied.Permission != domain.PermissionInvoicesWrite {
+		t.Errorf("read-only member: error = %v, want access denied for %s", err, domain.PermissionInvoicesWrite)
+	}
+
+	// Sin PORTAL_BASE_URL no se emiten enlaces con el Host de la petición
+	if _, err := newService("", domain.MemberRoleCashier).CreateLink(10, 3, &domain.CreateDocumentLinkRequest{}); err == nil || err.Error() != "portal base URL is not configured" {
+		t.Errorf("without base URL: error = %v", err)
+	}
+}
@@ -24,8 +24,13 @@ func (s *InvoiceService) BuildInvoiceWithTemplates(inv *domain.Invoice) ([]byte,
 	if inv.DueDate != nil {
 		dueDate = inv.DueDate.Format("2006-01-02")
 	}
+	// Con cuotas, el vencimiento de la factura es el de la última cuota
+	if n := len(inv.Installments); n > 0 {
+		dueDate = inv.Installments[n-1].DueDate.Format("2006-01-02")
+	}
 
-	// 3. Calcular CUFE
+	// 3. Calcular CUFE (ValTot = PayableAmount, descontando anticipos)
+	payableAmount := inv.PayableAmount()
 	var ivaAmount, incAmount, icaAmount float64
 	for _, line := range inv.Lines {
 		if line.TaxTypeCode == "01" {
@@ -50,7 +55,7 @@ func (s *InvoiceService) BuildInvoiceWithTemplates(inv *domain.Invoice) ([]byte,
 		ivaAmount,
 		incAmount,
 		icaAmount,
-		inv.Total,
+		payableAmount,
 		inv.Company.NIT,
 		inv.Customer.IdentificationNumber,
 		technicalKey,
@@ -72,7 +77,7 @@ func (s *InvoiceService) BuildInvoiceWithTemplates(inv *domain.Invoice) ([]byte,
 		inv.Customer.IdentificationNumber,
 		inv.Subtotal,
 		ivaAmount,
-		inv.Total,
+		payableAmount,
 		cufe,
 		getEnvironmentStr(inv.Software),
 	)
@@ -193,13 +198,15 @@ func (s *InvoiceService) BuildInvoiceWithTemplates(inv *domain.Invoice) ([]byte,
 	}
 	builder.SetPaymentMeans("1", getPaymentMethodCode(&paymentMethodID), dueDate)
 
+	// Cuotas adicionales y anticipos se agregan al XML después del Build (ver applyPaymentTerms)
+
 	// 10. Configurar totales
 	builder.SetMonetaryTotals(
 		fmt.Sprintf("%.2f", inv.Subtotal),
 		fmt.Sprintf("%.2f", inv.Subtotal),
 		fmt.Sprintf("%.2f", inv.Total),
 		"0.00",
-		fmt.Sprintf("%.2f", inv.Total),
+		fmt.Sprintf("%.2f", payableAmount),
 	)
 
 	// 10.5. Calcular y agregar TaxTotals
@@ -304,6 +311,12 @@ func (s *InvoiceService) BuildInvoiceWithTemplates(inv *domain.Invoice) ([]byte,
 		return nil, "", fmt.Errorf("error building invoice XML: %w", err)
 	}
 
+	// 12.1. Agregar cuotas (PaymentMeans) y anticipos (PrepaidPayment)
+	xmlBytes, err = applyPaymentTerms(xmlBytes, inv)
+	if err != nil {
+		return nil, "", fmt.Errorf("error adding payment terms to XML: %w", err)
+	}
+
 	return xmlBytes, cufe, nil
 }
 
@@ -0,0 +1,568 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/spreadsheet"
+	"apidian-go/pkg/validator"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// Columnas reconocidas en el archivo de importación (encabezados normalizados).
+// Las filas con el mismo invoice_ref forman una sola factura; las columnas de
+// encabezado solo son obligatorias en la primera fila de cada factura.
+var invoiceImportRequiredColumns = []string{
+	"customer_identification",
+	"resolution_prefix",
+	"issue_date",
+	"product_code",
+	"quantity",
+}
+
+var invoiceImportHeaderColumns = []string{
+	"customer_identification",
+	"resolution_prefix",
+	"issue_date",
+	"due_date",
+	"currency",
+	"payment_method",
+	"payment_form",
+	"notes",
+}
+
+// defaultImportCurrency se usa cuando el archivo no trae la columna currency
+const defaultImportCurrency = "COP"
+
+// Un trabajo en ejecución renueva updated_at cada importJobHeartbeat; si pasa importJobStaleAfter
+// sin latido, el proceso que lo ejecutaba terminó (reinicio o caída) y el trabajo se marca failed
+const (
+	importJobHeartbeat  = time.Minute
+	importJobStaleAfter = 5 * time.Minute
+)
+
+type InvoiceImportService struct {
+	importRepo     *repository.InvoiceImportRepository
+	companyRepo    *repository.CompanyRepository
+	authz          *Authorizer
+	customerRepo   *repository.CustomerRepository
+	productRepo    *repository.ProductRepository
+	resolutionRepo *repository.ResolutionRepository
+	catalogRepo    *repository.CatalogRepository
+	invoiceCreator InvoiceCreator
+}
+
+func NewInvoiceImportService(
+	importRepo *repository.InvoiceImportRepository,
+	companyRepo *repository.CompanyRepository,
+	customerRepo *repository.CustomerRepository,
+	productRepo *repository.ProductRepository,
+	resolutionRepo *repository.ResolutionRepository,
+	catalogRepo *repository.CatalogRepository,
+	invoiceCreator InvoiceCreator,
+) *InvoiceImportService {
+	return &InvoiceImportService{
+		importRepo:     importRepo,
+		companyRepo:    companyRepo,
+		authz:          NewAuthorizer(companyRepo),
+		customerRepo:   customerRepo,
+		productRepo:    productRepo,
+		resolutionRepo: resolutionRepo,
+		catalogRepo:    catalogRepo,
+		invoiceCreator: invoiceCreator,
+	}
+}
+
+// importGroup agrupa las filas de una misma factura (invoice_ref)
+type importGroup struct {
+	ref     string
+	rows    []*domain.InvoiceImportRowResult
+	headers map[string]string // valores crudos de encabezado para detectar conflictos
+	req     domain.CreateInvoiceRequest
+}
+
+func (g *importGroup) valid() bool {
+	for _, row := range g.rows {
+		if !row.Valid {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *importGroup) hasRowErrors() bool {
+	for _, row := range g.rows {
+		if len(row.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *importGroup) rowNumbers() []int {
+	numbers := make([]int, len(g.rows))
+	for i, row := range g.rows {
+		numbers[i] = row.Row
+	}
+	return numbers
+}
+
+// importLookup cachea las búsquedas por código durante una importación
+type importLookup struct {
+	customers   map[string]int64
+	resolutions map[string]int64
+	products    map[string]int64
+	catalogs    map[string]int
+}
+
+// Validate genera el reporte de validación fila por fila sin crear facturas (dry-run)
+func (s *InvoiceImportService) Validate(companyID int64, filename string, data []byte, userID int64) (*domain.InvoiceImportReport, error) {
+	report, _, err := s.parse(companyID, filename, data, userID)
+	return report, err
+}
+
+// Import valida el archivo y lanza un trabajo asíncrono que crea las facturas borrador.
+// Si hay filas inválidas y skipInvalid es false, no se crea el trabajo y se retorna el reporte.
+func (s *InvoiceImportService) Import(companyID int64, filename string, data []byte, skipInvalid bool, userID int64) (*domain.InvoiceImportReport, *domain.InvoiceImportJob, error) {
+	report, groups, err := s.parse(companyID, filename, data, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if report.InvalidRows > 0 && !skipInvalid {
+		return report, nil, fmt.Errorf("file has invalid rows")
+	}
+
+	var pending []*importGroup
+	for _, g := range groups {
+		if g.valid() {
+			pending = append(pending, g)
+		}
+	}
+	if len(pending) == 0 {
+		return report, nil, fmt.Errorf("no valid invoices to import")
+	}
+
+	job := &domain.InvoiceImportJob{
+		CompanyID:     companyID,
+		UserID:        userID,
+		Filename:      filename,
+		TotalInvoices: len(pending),
+	}
+	if err := s.importRepo.Create(job); err != nil {
+		return nil, nil, err
+	}
+
+	go s.run(job.ID, pending, userID)
+
+	return report, job, nil
+}
+
+// GetJob obtiene el progreso de un trabajo de importación
+func (s *InvoiceImportService) GetJob(id int64, userID int64) (*domain.InvoiceImportJob, error) {
+	job, err := s.importRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authz.AuthorizeResource(job.CompanyID, userID, domain.PermissionInvoicesRead, "import job"); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// run crea las facturas una por una y guarda el progreso después de cada una
+func (s *InvoiceImportService) run(jobID int64, groups []*importGroup, userID int64) {
+	job := &domain.InvoiceImportJob{ID: jobID, Results: []domain.InvoiceImportJobResult{}}
+
+	if err := s.importRepo.MarkRunning(jobID); err != nil {
+		log.Printf("invoice import job %d: %v", jobID, err)
+	}
+
+	// Latido independiente del avance: una sola factura puede tardar (bloqueos de la resolución)
+	heartbeat := time.NewTicker(importJobHeartbeat)
+	done := make(chan struct{})
+	defer func() {
+		heartbeat.Stop()
+		close(done)
+	}()
+	go func() {
+		for {
+			select {
+			case <-heartbeat.C:
+				if err := s.importRepo.Touch(jobID); err != nil {
+					log.Printf("invoice import job %d: %v", jobID, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			msg := fmt.Sprintf("import aborted: %v", r)
+			if err := s.importRepo.Finish(jobID, domain.ImportJobStatusFailed, &msg); err != nil {
+				log.Printf("invoice import job %d: %v", jobID, err)
+			}
+		}
+	}()
+
+	for _, g := range groups {
+		result := domain.InvoiceImportJobResult{
+			InvoiceRef: g.ref,
+			Rows:       g.rowNumbers(),
+		}
+
+		originNotes := fmt.Sprintf("import job %d, invoice_ref %s", jobID, g.ref)
+		g.req.Origin = domain.NumberReasonImport
+		g.req.OriginNotes = &originNotes
+
+		invoice, err := s.invoiceCreator.Create(&g.req, userID)
+		if err != nil {
+			msg := err.Error()
+			result.Error = &msg
+			job.Failed++
+		} else {
+			result.InvoiceID = &invoice.ID
+			result.Number = &invoice.Number
+			job.Succeeded++
+		}
+
+		job.Processed++
+		job.Results = append(job.Results, result)
+		if err := s.importRepo.UpdateProgress(job); err != nil {
+			log.Printf("invoice import job %d: %v", jobID, err)
+		}
+	}
+
+	if err := s.importRepo.Finish(jobID, domain.ImportJobStatusCompleted, nil); err != nil {
+		log.Printf("invoice import job %d: %v", jobID, err)
+	}
+}
+
+// ImportJobMonitor cierra los trabajos de importación que quedaron pending o running al
+// terminar el proceso que los ejecutaba
+type ImportJobMonitor struct {
+	importRepo *repository.InvoiceImportRepository
+}
+
+func NewImportJobMonitor(importRepo *repository.InvoiceImportRepository) *ImportJobMonitor {
+	return &ImportJobMonitor{importRepo: importRepo}
+}
+
+// Run revisa al iniciar (trabajos huérfanos de la ejecución anterior) y luego cada interval
+func (m *ImportJobMonitor) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if failed, err := m.FailStale(time.Now()); err != nil {
+			log.Printf("Warning: import job check failed: %v", err)
+		} else if failed > 0 {
+			log.Printf("Import job check: %d interrupted job(s) marked as failed", failed)
+		}
+		<-ticker.C
+	}
+}
+
+// FailStale marca como failed los trabajos sin latido desde hace importJobStaleAfter
+func (m *ImportJobMonitor) FailStale(now time.Time) (int64, error) {
+	msg := "import interrupted: the server stopped while the job was running; results list the invoices already created, import the remaining ones again"
+	return m.importRepo.FailStale(now.Add(-importJobStaleAfter), msg)
+}
+
+// parse lee el archivo, agrupa las filas por factura y valida cada fila
+func (s *InvoiceImportService) parse(companyID int64, filename string, data []byte, userID int64) (*domain.InvoiceImportReport, []*importGroup, error) {
+	// Validar que el usuario pueda emitir facturas en la empresa
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionInvoicesWrite); err != nil {
+		return nil, nil, err
+	}
+
+	table, err := spreadsheet.Read(filename, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid file: %w", err)
+	}
+	for _, column := range invoiceImportRequiredColumns {
+		if !table.HasColumn(column) {
+			return nil, nil, fmt.Errorf("invalid file: missing required column '%s'", column)
+		}
+	}
+	if len(table.Rows) == 0 {
+		return nil, nil, fmt.Errorf("invalid file: no data rows")
+	}
+
+	lookup := &importLookup{
+		customers:   map[string]int64{},
+		resolutions: map[string]int64{},
+		products:    map[string]int64{},
+		catalogs:    map[string]int{},
+	}
+
+	groupsByRef := map[string]*importGroup{}
+	var groups []*importGroup
+	var results []*domain.InvoiceImportRowResult
+
+	for _, row := range table.Rows {
+		ref := row.Get("invoice_ref")
+		if ref == "" {
+			ref = fmt.Sprintf("row-%d", row.Number)
+		}
+
+		result := &domain.InvoiceImportRowResult{Row: row.Number, InvoiceRef: ref}
+		results = append(results, result)
+
+		g, ok := groupsByRef[ref]
+		if !ok {
+			g = &importGroup{
+				ref:     ref,
+				headers: map[string]string{},
+				req:     domain.CreateInvoiceRequest{CompanyID: companyID},
+			}
+			groupsByRef[ref] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, result)
+
+		result.Errors = append(result.Errors, s.applyHeader(g, row, companyID, lookup)...)
+		result.Errors = append(result.Errors, s.applyLine(g, row, companyID, lookup)...)
+	}
+
+	// Validación a nivel de factura (mismas reglas que POST /invoices)
+	for _, g := range groups {
+		first := g.rows[0]
+		if _, ok := g.headers["currency"]; !ok {
+			id, err := s.catalogID(lookup, "currency_codes", defaultImportCurrency)
+			if err != nil {
+				first.Errors = append(first.Errors, err.Error())
+			}
+			g.req.CurrencyCodeID = id
+		}
+
+		if g.hasRowErrors() {
+			continue
+		}
+		if err := validator.ValidateCreateInvoice(&g.req); err != nil {
+			first.Errors = append(first.Errors, err.Error())
+		}
+	}
+
+	report := &domain.InvoiceImportReport{
+		Filename:      filename,
+		TotalRows:     len(results),
+		TotalInvoices: len(groups),
+	}
+	for _, result := range results {
+		result.Valid = len(result.Errors) == 0
+		if result.Valid {
+			report.ValidRows++
+		} else {
+			report.InvalidRows++
+		}
+		report.Rows = append(report.Rows, *result)
+	}
+	for _, g := range groups {
+		if g.valid() {
+			report.ValidInvoices++
+		} else {
+			report.InvalidInvoices++
+		}
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Row < report.Rows[j].Row })
+
+	return report, groups, nil
+}
+
+// applyHeader toma los datos de encabezado de la fila y verifica que no contradigan
+// los de filas anteriores de la misma factura
+func (s *InvoiceImportService) applyHeader(g *importGroup, row spreadsheet.Row, companyID int64, lookup *importLookup) []string {
+	var errs []string
+	isFirst := len(g.rows) == 1
+
+	for _, column := range invoiceImportHeaderColumns {
+		value := row.Get(column)
+		if value == "" {
+			continue
+		}
+
+		if previous, ok := g.headers[column]; ok {
+			if previous != value {
+				errs = append(errs, fmt.Sprintf("%s '%s' no coincide con '%s' de la fila %d (invoice_ref %s)", column, value, previous, g.rows[0].Row, g.ref))
+			}
+			continue
+		}
+		if !isFirst {
+			errs = append(errs, fmt.Sprintf("%s debe ir en la primera fila de la factura %s", column, g.ref))
+			continue
+		}
+		g.headers[column] = value
+
+		if err := s.setHeaderValue(&g.req, column, value, companyID, lookup); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if isFirst {
+		for _, column := range []string{"customer_identification", "resolution_prefix", "issue_date"} {
+			if row.Get(column) == "" {
+				errs = append(errs, fmt.Sprintf("%s es requerido", column))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s *InvoiceImportService) setHeaderValue(req *domain.CreateInvoiceRequest, column, value string, companyID int64, lookup *importLookup) error {
+	switch column {
+	case "customer_identification":
+		id, ok := lookup.customers[value]
+		if !ok {
+			customer, err := s.customerRepo.GetByIdentification(companyID, value)
+			if err != nil {
+				return err
+			}
+			if customer == nil {
+				return fmt.Errorf("customer '%s' not found", value)
+			}
+			id = customer.ID
+			lookup.customers[value] = id
+		}
+		req.CustomerID = id
+
+	case "resolution_prefix":
+		id, ok := lookup.resolutions[value]
+		if !ok {
+			resolution, err := s.resolutionRepo.GetByCompanyAndPrefix(companyID, value)
+			if err != nil {
+				return fmt.Errorf("error getting resolution: %w", err)
+			}
+			if resolution == nil {
+				return fmt.Errorf("active resolution with prefix '%s' not found", value)
+			}
+			id = resolution.ID
+			lookup.resolutions[value] = id
+		}
+		req.ResolutionID = id
+
+	case "issue_date":
+		date, err := spreadsheet.NormalizeDate(value)
+		if err != nil {
+			return fmt.Errorf("issue_date: %w", err)
+		}
+		req.IssueDate = date
+
+	case "due_date":
+		date, err := spreadsheet.NormalizeDate(value)
+		if err != nil {
+			return fmt.Errorf("due_date: %w", err)
+		}
+		req.DueDate = &date
+
+	case "currency":
+		id, err := s.catalogID(lookup, "currency_codes", value)
+		if err != nil {
+			return err
+		}
+		req.CurrencyCodeID = id
+
+	case "payment_method":
+		id, err := s.catalogID(lookup, "payment_methods", value)
+		if err != nil {
+			return err
+		}
+		req.PaymentMethodID = &id
+
+	case "payment_form":
+		id, err := s.catalogID(lookup, "payment_forms", value)
+		if err != nil {
+			return err
+		}
+		req.PaymentFormID = &id
+
+	case "notes":
+		notes := value
+		req.Notes = &notes
+	}
+
+	return nil
+}
+
+// applyLine convierte la fila en una línea de factura y la valida con pkg/validator
+func (s *InvoiceImportService) applyLine(g *importGroup, row spreadsheet.Row, companyID int64, lookup *importLookup) []string {
+	var errs []string
+	line := domain.CreateInvoiceLineRequest{}
+
+	code := row.Get("product_code")
+	if code != "" {
+		id, ok := lookup.products[code]
+		if !ok {
+			product, err := s.productRepo.GetByCode(companyID, code)
+			if err != nil {
+				errs = append(errs, err.Error())
+			} else if product == nil {
+				errs = append(errs, fmt.Sprintf("product '%s' not found", code))
+			} else {
+				id = product.ID
+				lookup.products[code] = id
+			}
+		}
+		line.ProductID = id
+	}
+
+	if description := row.Get("description"); description != "" {
+		line.Description = &description
+	}
+
+	if value := row.Get("quantity"); value != "" {
+		quantity, err := spreadsheet.ParseNumber(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("quantity '%s' no es un número válido", value))
+		}
+		line.Quantity = quantity
+	}
+
+	if value := row.Get("unit_price"); value != "" {
+		price, err := spreadsheet.ParseNumber(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unit_price '%s' no es un número válido", value))
+		}
+		line.UnitPrice = &price
+	}
+
+	if value := row.Get("tax_rate"); value != "" {
+		rate, err := spreadsheet.ParseNumber(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("tax_rate '%s' no es un número válido", value))
+		}
+		line.TaxRate = &rate
+	}
+
+	lineNumber := len(g.req.Lines) + 1
+	if code == "" {
+		errs = append(errs, "product_code es requerido")
+	} else if len(errs) == 0 {
+		if err := validator.ValidateCreateInvoiceLine(&line, lineNumber); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	g.req.Lines = append(g.req.Lines, line)
+	return errs
+}
+
+func (s *InvoiceImportService) catalogID(lookup *importLookup, table, code string) (int, error) {
+	key := table + ":" + code
+	if id, ok := lookup.catalogs[key]; ok {
+		return id, nil
+	}
+	id, err := s.catalogRepo.GetIDByCode(table, code)
+	if err != nil {
+		return 0, err
+	}
+	lookup.catalogs[key] = id
+	return id, nil
+}
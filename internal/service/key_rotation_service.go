@@ -0,0 +1,129 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
+	"fmt"
+)
+
+// Operaciones de los repositorios que usa la rotación (los implementan los repositorios de
+// certificados, llaves de datos y 2FA)
+type passwordStore interface {
+	GetAllPasswords() ([]domain.Certificate, error)
+	UpdatePassword(id int64, encryptedPassword string) error
+}
+
+type dataKeyStore interface {
+	GetAll() ([]domain.CompanyDataKey, error)
+	UpdateWrappedKey(companyID int64, wrappedKey string) error
+}
+
+type totpSecretStore interface {
+	GetAllSecrets() ([]repository.TwoFactorSecret, error)
+	UpdateSecret(userID int64, encrypted string) error
+}
+
+// KeyRotationService re-cifra los secretos guardados con la llave maestra activa.
+// Es idempotente: los valores que ya usan la llave activa no se tocan, así que puede
+// relanzarse tras un fallo parcial.
+type KeyRotationService struct {
+	certRepo      passwordStore
+	dataKeyRepo   dataKeyStore
+	twoFactorRepo totpSecretStore
+}
+
+func NewKeyRotationService(
+	certRepo *repository.CertificateRepository,
+	dataKeyRepo *repository.DataKeyRepository,
+	twoFactorRepo *repository.TwoFactorRepository,
+) *KeyRotationService {
+	return &KeyRotationService{
+		certRepo:      certRepo,
+		dataKeyRepo:   dataKeyRepo,
+		twoFactorRepo: twoFactorRepo,
+	}
+}
+
+// Reencrypt re-cifra certificates.password, company_data_keys.wrapped_key y users.totp_secret
+func (s *KeyRotationService) Reencrypt(dryRun bool) (*domain.KeyRotationReport, error) {
+	activeID, err := crypto.ActiveKeyID()
+	if err != nil {
+		return nil, err
+	}
+	report := &domain.KeyRotationReport{ActiveKeyID: activeID, DryRun: dryRun}
+
+	certs, err := s.certRepo.GetAllPasswords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certificates: %w", err)
+	}
+	report.CertificatesTotal = len(certs)
+
+	for _, cert := range certs {
+		reencrypted, changed, err := crypto.Reencrypt(cert.Password)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("certificate %d: %v", cert.ID, err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if !dryRun {
+			if err := s.certRepo.UpdatePassword(cert.ID, reencrypted); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("certificate %d: %v", cert.ID, err))
+				continue
+			}
+		}
+		report.CertificatesReencrypted++
+	}
+
+	dataKeys, err := s.dataKeyRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data keys: %w", err)
+	}
+	report.DataKeysTotal = len(dataKeys)
+
+	for _, key := range dataKeys {
+		rewrapped, changed, err := crypto.Reencrypt(key.WrappedKey)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("data key of company %d: %v", key.CompanyID, err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if !dryRun {
+			if err := s.dataKeyRepo.UpdateWrappedKey(key.CompanyID, rewrapped); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("data key of company %d: %v", key.CompanyID, err))
+				continue
+			}
+		}
+		report.DataKeysRewrapped++
+	}
+
+	secrets, err := s.twoFactorRepo.GetAllSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list two-factor secrets: %w", err)
+	}
+	report.TwoFactorSecretsTotal = len(secrets)
+
+	for _, secret := range secrets {
+		reencrypted, changed, err := crypto.Reencrypt(secret.Secret)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("two-factor secret of user %d: %v", secret.UserID, err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if !dryRun {
+			if err := s.twoFactorRepo.UpdateSecret(secret.UserID, reencrypted); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("two-factor secret of user %d: %v", secret.UserID, err))
+				continue
+			}
+		}
+		report.TwoFactorSecretsReencrypted++
+	}
+
+	return report, nil
+}
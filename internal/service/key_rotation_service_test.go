@@ -0,0 +1,203 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	rotationKeyOld = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	rotationKeyNew = "202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f"
+)
+
+// fakeSecretStore guarda en memoria los valores cifrados de las tres tablas que re-cifra la rotación
+type fakeSecretStore struct {
+	passwords map[int64]string
+	dataKeys  map[int64]string
+	secrets   map[int64]string
+	failIDs   map[int64]bool // los Update de estos IDs fallan
+}
+
+func (f *fakeSecretStore) GetAllPasswords() ([]domain.Certificate, error) {
+	var certs []domain.Certificate
+	for id, password := range f.passwords {
+		certs = append(certs, domain.Certificate{ID: id, Password: password})
+	}
+	return certs, nil
+}
+
+func (f *fakeSecretStore) UpdatePassword(id int64, encryptedPassword string) error {
+	if f.failIDs[id] {
+		return errors.New("connection reset")
+	}
+	f.passwords[id] = encryptedPassword
+	return nil
+}
+
+func (f *fakeSecretStore) GetAll() ([]domain.CompanyDataKey, error) {
+	var keys []domain.CompanyDataKey
+	for companyID, wrapped := range f.dataKeys {
+		keys = append(keys, domain.CompanyDataKey{CompanyID: companyID, WrappedKey: wrapped})
+	}
+	return keys, nil
+}
+
+func (f *fakeSecretStore) UpdateWrappedKey(companyID int64, wrappedKey string) error {
+	if f.failIDs[companyID] {
+		return errors.New("connection reset")
+	}
+	f.dataKeys[companyID] = wrappedKey
+	return nil
+}
+
+func (f *fakeSecretStore) GetAllSecrets() ([]repository.TwoFactorSecret, error) {
+	var secrets []repository.TwoFactorSecret
+	for userID, secret := range f.secrets {
+		secrets = append(secrets, repository.TwoFactorSecret{UserID: userID, Secret: secret})
+	}
+	return secrets, nil
+}
+
+func (f *fakeSecretStore) UpdateSecret(userID int64, encrypted string) error {
+	if f.failIDs[userID] {
+		return errors.New("connection reset")
+	}
+	f.secrets[userID] = encrypted
+	return nil
+}
+
+func (f *fakeSecretStore) snapshot() map[string]string {
+	values := map[string]string{}
+	for id, v := range f.passwords {
+		values[fmt.Sprintf("password:%d", id)] = v
+	}
+	for id, v := range f.dataKeys {
+		values[fmt.Sprintf("data_key:%d", id)] = v
+	}
+	for id, v := range f.secrets {
+		values[fmt.Sprintf("secret:%d", id)] = v
+	}
+	return values
+}
+
+func mustEncrypt(t *testing.T, value string) string {
+	t.Helper()
+	encrypted, err := crypto.EncryptPassword(value)
+	if err != nil {
+		t.Fatalf("EncryptPassword: %v", err)
+	}
+	return encrypted
+}
+
+func TestKeyRotationServiceReencrypt(t *testing.T) {
+	crypto.SetKeyProvider(crypto.EnvKeyProvider{})
+	t.Setenv("ENCRYPTION_KEYS", "")
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	t.Setenv("ENCRYPTION_KEY", rotationKeyOld)
+
+	dek, _ := crypto.GenerateDataKey()
+	wrapped, err := crypto.WrapDataKey(dek)
+	if err != nil {
+		t.Fatalf("WrapDataKey: %v", err)
+	}
+	store := &fakeSecretStore{
+		passwords: map[int64]string{1: mustEncrypt(t, "cert-password"), 2: "lost:AAAA"},
+		dataKeys:  map[int64]string{10: wrapped},
+		secrets:   map[int64]string{100: mustEncrypt(t, "JBSWY3DPEHPK3PXP")},
+		failIDs:   map[int64]bool{},
+	}
+	service := &KeyRotationService{certRepo: store, dataKeyRepo: store, twoFactorRepo: store}
+
+	// Rotar: llave nueva activa, la anterior conservada para descifrar
+	t.Setenv("ENCRYPTION_KEYS", "2026a:"+rotationKeyNew+",default:"+rotationKeyOld)
+	t.Setenv("ENCRYPTION_KEY_ID", "2026a")
+	t.Setenv("ENCRYPTION_KEY", "")
+
+	// Dry run: reporta sin escribir
+	before := store.snapshot()
+	report, err := service.Reencrypt(true)
+	if err != nil {
+		t.Fatalf("Reencrypt dry run: %v", err)
+	}
+	if report.ActiveKeyID != "2026a" || !report.DryRun {
+		t.Errorf("report = %+v", report)
+	}
+	if report.CertificatesReencrypted != 1 || report.DataKeysRewrapped != 1 || report.TwoFactorSecretsReencrypted != 1 {
+		t.Errorf("dry run counts = %d/%d/%d, want 1/1/1", report.CertificatesReencrypted, report.DataKeysRewrapped, report.TwoFactorSecretsReencrypted)
+	}
+	for k, v := range store.snapshot() {
+		if before[k] != v {
+			t.Fatalf("dry run modified %s", k)
+		}
+	}
+
+	// Aplicar
+	report, err = service.Reencrypt(false)
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	if report.CertificatesTotal != 2 || report.CertificatesReencrypted != 1 || report.DataKeysRewrapped != 1 || report.TwoFactorSecretsReencrypted != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "certificate 2") {
+		t.Errorf("errors = %v, want only certificate 2 (unknown key)", report.Errors)
+	}
+
+	// Retirar la llave anterior: todo lo migrado se descifra con la nueva
+	t.Setenv("ENCRYPTION_KEYS", "2026a:"+rotationKeyNew)
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	if got, err := crypto.DecryptPassword(store.passwords[1]); err != nil || got != "cert-password" {
+		t.Errorf("certificate password = %q, %v", got, err)
+	}
+	if got, err := crypto.UnwrapDataKey(store.dataKeys[10]); err != nil || string(got) != string(dek) {
+		t.Errorf("data key = %x, %v", got, err)
+	}
+	if got, err := crypto.DecryptPassword(store.secrets[100]); err != nil || got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("two-factor secret = %q, %v", got, err)
+	}
+
+	// Idempotente: una segunda ejecución no re-cifra nada
+	delete(store.passwords, 2)
+	report, err = service.Reencrypt(false)
+	if err != nil {
+		t.Fatalf("second Reencrypt: %v", err)
+	}
+	if report.CertificatesReencrypted+report.DataKeysRewrapped+report.TwoFactorSecretsReencrypted != 0 || len(report.Errors) != 0 {
+		t.Errorf("second run report = %+v, want nothing to do", report)
+	}
+}
+
+// Un fallo al guardar se reporta y el valor queda con la llave anterior para el siguiente intento
+func TestKeyRotationServiceReportsUpdateErrors(t *testing.T) {
+	crypto.SetKeyProvider(crypto.EnvKeyProvider{})
+	t.Setenv("ENCRYPTION_KEYS", "")
+	t.Setenv("ENCRYPTION_KEY_ID", "")
+	t.Setenv("ENCRYPTION_KEY", rotationKeyOld)
+
+	store := &fakeSecretStore{
+		passwords: map[int64]string{1: mustEncrypt(t, "a"), 2: mustEncrypt(t, "b")},
+		dataKeys:  map[int64]string{},
+		secrets:   map[int64]string{},
+		failIDs:   map[int64]bool{2: true},
+	}
+	service := &KeyRotationService{certRepo: store, dataKeyRepo: store, twoFactorRepo: store}
+
+	t.Setenv("ENCRYPTION_KEYS", "2026a:"+rotationKeyNew+",default:"+rotationKeyOld)
+	t.Setenv("ENCRYPTION_KEY", "")
+
+	report, err := service.Reencrypt(false)
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	if report.CertificatesReencrypted != 1 || len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "certificate 2: connection reset") {
+		t.Errorf("report = %+v", report)
+	}
+	if crypto.CiphertextKeyID(store.passwords[2]) != "default" {
+		t.Errorf("failed value key id = %q, want default", crypto.CiphertextKeyID(store.passwords[2]))
+	}
+}
@@ -0,0 +1,55 @@
+package keyring
+
+import (
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
+	"fmt"
+)
+
+// Keyring administra las llaves de datos por empresa y entrega un storage que cifra
+// con ellas. Se usa para certificados, XML firmados, ApplicationResponse y ZIPs.
+type Keyring struct {
+	dataKeyRepo *repository.DataKeyRepository
+	storage     storage.Storage
+}
+
+func New(dataKeyRepo *repository.DataKeyRepository, store storage.Storage) *Keyring {
+	return &Keyring{
+		dataKeyRepo: dataKeyRepo,
+		storage:     store,
+	}
+}
+
+// CompanyStorage retorna el storage cifrado con la llave de datos de la empresa
+func (k *Keyring) CompanyStorage(companyID int64) (storage.Storage, error) {
+	dek, err := k.dataKey(companyID)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewEncryptedStorage(k.storage, dek), nil
+}
+
+// dataKey obtiene (o genera la primera vez) la llave de datos de la empresa
+func (k *Keyring) dataKey(companyID int64) ([]byte, error) {
+	key, err := k.dataKeyRepo.GetByCompanyID(companyID)
+	if err != nil {
+		if err.Error() != "data key not found" {
+			return nil, fmt.Errorf("failed to get data key: %w", err)
+		}
+
+		dek, err := crypto.GenerateDataKey()
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := crypto.WrapDataKey(dek)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = k.dataKeyRepo.CreateIfNotExists(companyID, wrapped); err != nil {
+			return nil, fmt.Errorf("failed to save data key: %w", err)
+		}
+	}
+
+	return crypto.UnwrapDataKey(key.WrappedKey)
+}
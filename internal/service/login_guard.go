@@ -0,0 +1,206 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/mail"
+	"apidian-go/pkg/crypto"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Protección del login contra fuerza bruta. Los intentos se cuentan por email (exista o no la
+// cuenta) y por IP en Postgres, así que todas las réplicas ven los mismos contadores:
+//   - desde domain.LoginDelayAfterFailures fallos seguidos la cuenta exige una espera creciente
+//     entre intentos;
+//   - con LOGIN_MAX_FAILURES la cuenta queda bloqueada LOGIN_LOCK_MINUTES y el usuario recibe un
+//     enlace para desbloquearla;
+//   - con LOGIN_IP_MAX_FAILURES la IP queda bloqueada el mismo tiempo.
+// Cada intento se reserva en los contadores antes de verificar la contraseña, así las peticiones
+// simultáneas no pasan todas con el mismo contador; el intento se devuelve si no resulta fallido.
+// Mientras hay espera o bloqueo la contraseña no se verifica. Los bloqueos, los desbloqueos y
+// los logins correctos después de varios fallos quedan en audit_log.
+
+// loginAttempt es un intento reservado en el contador de la cuenta y en el de la IP (nil sin IP)
+type loginAttempt struct {
+	account *domain.LoginFailure
+	address *domain.LoginFailure
+}
+
+// claimLoginAttempt reserva el intento antes de verificar la contraseña o lo rechaza con un
+// *domain.LoginThrottleError si la cuenta o la IP están bloqueadas o en espera
+func (s *AuthService) claimLoginAttempt(subject, ip string) (*loginAttempt, error) {
+	account, ok, err := s.attemptRepo.Claim(domain.LoginScopeAccount, subject, s.accountLimits())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, throttleError(account)
+	}
+	attempt := &loginAttempt{account: account}
+
+	if ip != "" {
+		address, ok, err := s.attemptRepo.Claim(domain.LoginScopeIP, ip, s.ipLimits())
+		if err != nil || !ok {
+			s.releaseLoginAttempt(attempt)
+			if err != nil {
+				return nil, err
+			}
+			return nil, throttleError(address)
+		}
+		attempt.address = address
+	}
+	return attempt, nil
+}
+
+// throttleError describe por qué un contador rechazó el intento
+func throttleError(failure *domain.LoginFailure) *domain.LoginThrottleError {
+	now := time.Now()
+	if failure.LockedUntil != nil && failure.LockedUntil.After(now) {
+		return &domain.LoginThrottleError{Scope: failure.Scope, Locked: true, RetryAfter: failure.LockedUntil.Sub(now)}
+	}
+	retryAfter := time.Second
+	if failure.NextAttemptAt != nil && failure.NextAttemptAt.After(now) {
+		retryAfter = failure.NextAttemptAt.Sub(now)
+	}
+	return &domain.LoginThrottleError{Scope: failure.Scope, RetryAfter: retryAfter}
+}
+
+func (s *AuthService) accountLimits() domain.LoginLimits {
+	return domain.LoginLimits{Delay: true, MaxFailures: s.authConfig.LoginMaxFailures, LockMinutes: s.authConfig.LoginLockMinutes}
+}
+
+func (s *AuthService) ipLimits() domain.LoginLimits {
+	return domain.LoginLimits{MaxFailures: s.authConfig.LoginIPMaxFailures, LockMinutes: s.authConfig.LoginLockMinutes}
+}
+
+// recordLoginFailure confirma como fallido (contraseña o código 2FA) un intento reservado y, si
+// el intento bloqueó la cuenta o la IP, lo registra y avisa. user es nil si el email no pertenece
+// a una cuenta.
+func (s *AuthService) recordLoginFailure(attempt *loginAttempt, user *domain.User, client domain.SessionClient) {
+	if account := attempt.account; account.LockedUntil != nil {
+		var userID *int64
+		if user != nil {
+			userID = &user.ID
+		}
+		s.audit(domain.AuditOpAccountLocked, userID, map[string]any{
+			"email":        account.Subject,
+			"ip_address":   client.IPAddress,
+			"user_agent":   client.UserAgent,
+			"failures":     account.Failures,
+			"since":        account.FirstFailedAt,
+			"locked_until": *account.LockedUntil,
+		})
+		if user != nil {
+			s.sendUnlock(user, account.Failures, client)
+		}
+	}
+
+	if address := attempt.address; address != nil && address.LockedUntil != nil {
+		log.Printf("Warning: IP %s blocked until %s after %d failed logins", address.Subject, address.LockedUntil.Format(time.RFC3339), address.Failures)
+		s.audit(domain.AuditOpIPBlocked, nil, map[string]any{
+			"ip_address":   address.Subject,
+			"user_agent":   client.UserAgent,
+			"failures":     address.Failures,
+			"since":        address.FirstFailedAt,
+			"locked_until": *address.LockedUntil,
+		})
+	}
+}
+
+// releaseLoginAttempt devuelve un intento reservado que no fue fallido pero tampoco completó el
+// login (falta el código 2FA, email sin verificar o error interno). Un error solo queda en el log.
+func (s *AuthService) releaseLoginAttempt(attempt *loginAttempt) {
+	if err := s.attemptRepo.Release(domain.LoginScopeAccount, attempt.account.Subject, s.authConfig.LoginMaxFailures); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	s.releaseAddress(attempt)
+}
+
+func (s *AuthService) releaseAddress(attempt *loginAttempt) {
+	if attempt.address == nil {
+		return
+	}
+	if err := s.attemptRepo.Release(domain.LoginScopeIP, attempt.address.Subject, s.authConfig.LoginIPMaxFailures); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// recordLoginSuccess reinicia el contador de la cuenta. Un login correcto después de varios
+// fallos seguidos puede ser un ataque que acertó, así que queda en audit_log. En la IP solo se
+// devuelve el intento: si se reiniciara, un atacante la limpiaría entrando a su propia cuenta.
+func (s *AuthService) recordLoginSuccess(attempt *loginAttempt, user *domain.User, client domain.SessionClient) {
+	s.releaseAddress(attempt)
+
+	attempts, err := s.attemptRepo.Reset(domain.LoginScopeAccount, attempt.account.Subject)
+	if err != nil {
+		log.Printf("Warning: failed to reset login failures of user %d: %v", user.ID, err)
+		return
+	}
+	if failures := attempts - 1; failures >= domain.LoginDelayAfterFailures {
+		s.audit(domain.AuditOpLoginAfterFails, &user.ID, map[string]any{
+			"email":      attempt.account.Subject,
+			"ip_address": client.IPAddress,
+			"user_agent": client.UserAgent,
+			"failures":   failures,
+		})
+	}
+}
+
+// UnlockAccount desbloquea la cuenta con el token enviado por correo al bloquearla
+func (s *AuthService) UnlockAccount(token string) error {
+	userID, err := s.tokenRepo.UnlockAccount(crypto.HashToken(token))
+	if err != nil {
+		return err
+	}
+	s.audit(domain.AuditOpAccountUnlocked, &userID, nil)
+	return nil
+}
+
+// sendUnlock avisa al usuario del bloqueo con un enlace para desbloquear la cuenta
+func (s *AuthService) sendUnlock(user *domain.User, failures int, client domain.SessionClient) {
+	token, err := s.issueToken(user, domain.UserTokenUnlockAccount, domain.UnlockTokenHours*time.Hour)
+	if err != nil {
+		if err.Error() != "token recently issued" {
+			log.Printf("Warning: failed to issue unlock token for user %d: %v", user.ID, err)
+		}
+		return
+	}
+	origin := ""
+	if client.IPAddress != "" {
+		origin = " desde la IP " + client.IPAddress
+	}
+	deliverMail(s.mailer, mail.Message{
+		To:      user.Email,
+		Subject: "Tu cuenta fue bloqueada temporalmente",
+		Body: fmt.Sprintf("Hola %s,\n\n"+
+			"Bloqueamos el inicio de sesión en tu cuenta durante %d minutos después de %d intentos fallidos%s.\n\n"+
+			"Si fuiste tú, puedes desbloquearla ahora con el siguiente enlace:\n\n"+
+			"%s/unlock-account?token=%s\n\n"+
+			"O envía este token a POST /api/v1/auth/unlock-account:\n\n%s\n\n"+
+			"Si no fuiste tú, alguien está intentando adivinar tu contraseña: te recomendamos cambiarla "+
+			"y activar la verificación en dos pasos.\n",
+			user.Name, s.authConfig.LoginLockMinutes, failures, origin, s.appURL, token, token),
+	})
+}
+
+// audit registra un evento de autenticación en audit_log; un fallo solo queda en el log
+func (s *AuthService) audit(operation string, userID *int64, data map[string]any) {
+	entry := &domain.AuditEntry{
+		TableName: domain.AuditTableAuth,
+		Operation: operation,
+		UserID:    userID,
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Warning: failed to encode audit entry %s: %v", operation, err)
+			return
+		}
+		entry.NewData = raw
+	}
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Warning: failed to write audit entry %s: %v", operation, err)
+	}
+}
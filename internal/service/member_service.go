@@ -0,0 +1,162 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"errors"
+	"strings"
+	"time"
+)
+
+type MemberService struct {
+	repo     *repository.MemberRepository
+	userRepo *repository.UserRepository
+	authz    *Authorizer
+}
+
+func NewMemberService(repo *repository.MemberRepository, companyRepo *repository.CompanyRepository, userRepo *repository.UserRepository) *MemberService {
+	return &MemberService{
+		repo:     repo,
+		userRepo: userRepo,
+		authz:    NewAuthorizer(companyRepo),
+	}
+}
+
+// GetByCompanyID lista los miembros de la empresa; cualquier miembro puede consultarlos
+func (s *MemberService) GetByCompanyID(companyID, userID int64) ([]domain.Member, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionCompanyRead); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByCompanyID(companyID)
+}
+
+// UpdateRole cambia el rol de un miembro. El owner no cambia de rol ni se puede otorgar.
+func (s *MemberService) UpdateRole(companyID, memberUserID, userID int64, req *domain.UpdateMemberRequest) (*domain.Member, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionMembersManage); err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.Get(companyID, memberUserID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == domain.MemberRoleOwner {
+		return nil, errors.New("cannot change the owner role")
+	}
+
+	if err := s.repo.UpdateRole(companyID, memberUserID, req.Role); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(companyID, memberUserID)
+}
+
+// Remove retira a un miembro de la empresa. Un miembro puede retirarse a sí mismo;
+// el owner no se puede retirar.
+func (s *MemberService) Remove(companyID, memberUserID, userID int64) error {
+	permission := domain.PermissionMembersManage
+	if memberUserID == userID {
+		permission = domain.PermissionCompanyRead
+	}
+	if _, err := s.authz.Authorize(companyID, userID, permission); err != nil {
+		return err
+	}
+
+	member, err := s.repo.Get(companyID, memberUserID)
+	if err != nil {
+		return err
+	}
+	if member.Role == domain.MemberRoleOwner {
+		return errors.New("cannot remove the company owner")
+	}
+
+	return s.repo.Remove(companyID, memberUserID)
+}
+
+// Invite invita a un email a la empresa con un rol; el invitado la acepta al iniciar sesión
+func (s *MemberService) Invite(companyID, userID int64, req *domain.InviteMemberRequest) (*domain.Invitation, error) {
+	company, err := s.authz.Authorize(companyID, userID, domain.PermissionMembersManage)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if user, err := s.userRepo.GetByEmail(email); err == nil {
+		if _, err := s.repo.Get(companyID, user.ID); err == nil {
+			return nil, errors.New("user is already a member")
+		}
+	}
+
+	invitation := &domain.Invitation{
+		CompanyID:   companyID,
+		CompanyName: company.Name,
+		Email:       email,
+		Role:        req.Role,
+		InvitedBy:   &userID,
+		ExpiresAt:   time.Now().Add(domain.InvitationTTL),
+	}
+	if err := s.repo.CreateInvitation(invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetInvitations lista las invitaciones pendientes de la empresa
+func (s *MemberService) GetInvitations(companyID, userID int64) ([]domain.Invitation, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionMembersManage); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPendingByCompanyID(companyID)
+}
+
+// RevokeInvitation revoca una invitación pendiente de la empresa
+func (s *MemberService) RevokeInvitation(companyID, invitationID, userID int64) error {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionMembersManage); err != nil {
+		return err
+	}
+	return s.repo.RevokeInvitation(invitationID, companyID)
+}
+
+// GetMyInvitations lista las invitaciones vigentes dirigidas al email del usuario
+func (s *MemberService) GetMyInvitations(userID int64) ([]domain.Invitation, error) {
+	user, err := s.verifiedUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetPendingByEmail(user.Email)
+}
+
+// AcceptInvitation agrega al usuario a la empresa con el rol de la invitación
+func (s *MemberService) AcceptInvitation(invitationID, userID int64) (*domain.Member, error) {
+	user, err := s.verifiedUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.repo.Accept(invitationID, userID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Get(invitation.CompanyID, userID)
+}
+
+// DeclineInvitation rechaza una invitación dirigida al usuario
+func (s *MemberService) DeclineInvitation(invitationID, userID int64) error {
+	user, err := s.verifiedUser(userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Decline(invitationID, user.Email)
+}
+
+// verifiedUser obtiene el usuario y exige que haya verificado su email: las invitaciones se
+// asocian por email, y con UNVERIFIED_LOGIN=allow cualquiera puede registrar una dirección ajena
+func (s *MemberService) verifiedUser(userID int64) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, errors.New("email not verified")
+	}
+	return user, nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"errors"
+	"fmt"
+)
+
+// NumberingService audita la numeración de las resoluciones: qué consecutivos se usaron,
+// cuáles quedaron en borrador, se eliminaron o faltan, y registra las anulaciones
+type NumberingService struct {
+	numberingRepo  *repository.NumberingRepository
+	resolutionRepo *repository.ResolutionRepository
+	companyRepo    *repository.CompanyRepository
+	authz          *Authorizer
+}
+
+func NewNumberingService(numberingRepo *repository.NumberingRepository, resolutionRepo *repository.ResolutionRepository, companyRepo *repository.CompanyRepository) *NumberingService {
+	return &NumberingService{
+		numberingRepo:  numberingRepo,
+		resolutionRepo: resolutionRepo,
+		companyRepo:    companyRepo,
+		authz:          NewAuthorizer(companyRepo),
+	}
+}
+
+// Report clasifica cada consecutivo asignado de la resolución (de from_number al último
+// asignado). Entries lista los que no están emitidos; con includeUsed lista todos.
+func (s *NumberingService) Report(resolutionID, userID int64, includeUsed bool) (*domain.NumberingReport, error) {
+	resolution, err := s.getAuthorizedResolution(resolutionID, userID, domain.PermissionResolutionsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.numberingRepo.ListDocuments(resolution.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting documents: %w", err)
+	}
+	records, err := s.numberingRepo.ListRecords(resolution.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting numbering records: %w", err)
+	}
+
+	documentsByNumber := make(map[int64]repository.NumberedDocument, len(documents))
+	for _, doc := range documents {
+		documentsByNumber[doc.Consecutive] = doc
+	}
+	recordsByNumber := make(map[int64]*domain.NumberRecord, len(records))
+	for i := range records {
+		recordsByNumber[records[i].Consecutive] = &records[i]
+	}
+
+	report := &domain.NumberingReport{
+		ResolutionID: resolution.ID,
+		Prefix:       resolution.Prefix,
+		Resolution:   resolution.Resolution,
+		FromNumber:   resolution.FromNumber,
+		ToNumber:     resolution.ToNumber,
+		NextNumber:   resolution.CurrentNumber,
+		Assigned:     resolution.CurrentNumber - resolution.FromNumber,
+		UsedRanges:   []domain.ConsecutiveRange{},
+		Entries:      []domain.NumberingEntry{},
+	}
+
+	// current_number es el siguiente a asignar: los asignados son [from_number, current_number)
+	for n := resolution.FromNumber; n < resolution.CurrentNumber; n++ {
+		entry := domain.NumberingEntry{
+			Consecutive: n,
+			Number:      fmt.Sprintf("%s%d", resolution.Prefix, n),
+		}
+
+		record := recordsByNumber[n]
+		if record != nil {
+			entry.Number = record.Number
+			entry.Reason = &record.Reason
+			entry.Notes = record.Notes
+			entry.AnnulmentReason = record.AnnulmentReason
+			entry.RecordedAt = &record.CreatedAt
+		}
+
+		if doc, ok := documentsByNumber[n]; ok {
+			entry.DocumentID = &doc.ID
+			entry.DocumentStatus = &doc.Status
+			entry.State = domain.NumberingStateUsed
+			if doc.Status == "draft" {
+				entry.State = domain.NumberingStateDraft
+			}
+		} else if record == nil {
+			entry.State = domain.NumberingStateMissing
+		} else if record.Status == domain.NumberStatusAnnulled {
+			entry.State = domain.NumberingStateAnnulled
+		} else {
+			// Borrado explícito o documento eliminado por cascada (document_id quedó en NULL)
+			entry.State = domain.NumberingStateDeleted
+		}
+
+		switch entry.State {
+		case domain.NumberingStateUsed:
+			report.Used++
+			report.UsedRanges = appendToRange(report.UsedRanges, n)
+		case domain.NumberingStateDraft:
+			report.Draft++
+		case domain.NumberingStateDeleted:
+			report.Deleted++
+		case domain.NumberingStateMissing:
+			report.Missing++
+		case domain.NumberingStateAnnulled:
+			report.Annulled++
+		}
+
+		if entry.State != domain.NumberingStateUsed || includeUsed {
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+
+	return report, nil
+}
+
+// Annul registra la anulación de un consecutivo asignado que no tiene documento (faltante o
+// de un borrador eliminado), para justificar el hueco en la numeración
+func (s *NumberingService) Annul(resolutionID, userID int64, req *domain.AnnulNumberRequest) (*domain.NumberRecord, error) {
+	resolution, err := s.getAuthorizedResolution(resolutionID, userID, domain.PermissionResolutionsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Consecutive < resolution.FromNumber || req.Consecutive > resolution.ToNumber {
+		return nil, errors.New("consecutive is outside the resolution range")
+	}
+	if req.Consecutive >= resolution.CurrentNumber {
+		return nil, errors.New("consecutive has not been assigned yet")
+	}
+
+	number := fmt.Sprintf("%s%d", resolution.Prefix, req.Consecutive)
+	return s.numberingRepo.Annul(resolution.ID, req.Consecutive, number, req.Reason, userID)
+}
+
+// getAuthorizedResolution obtiene la resolución si el rol del usuario tiene el permiso en su empresa
+func (s *NumberingService) getAuthorizedResolution(resolutionID, userID int64, permission string) (*domain.Resolution, error) {
+	resolution, err := s.resolutionRepo.GetByID(resolutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authz.AuthorizeResource(resolution.CompanyID, userID, permission, "resolution"); err != nil {
+		return nil, err
+	}
+
+	return resolution, nil
+}
+
+// appendToRange agrega n al último rango si es contiguo o abre un rango nuevo
+func appendToRange(ranges []domain.ConsecutiveRange, n int64) []domain.ConsecutiveRange {
+	if last := len(ranges) - 1; last >= 0 && ranges[last].To == n-1 {
+		ranges[last].To = n
+		return ranges
+	}
+	return append(ranges, domain.ConsecutiveRange{From: n, To: n})
+}
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Estados de factura que no admiten pagos (no generan cartera)
+var nonPayableStatuses = map[string]bool{
+	"draft":     true,
+	"rejected":  true,
+	"cancelled": true,
+}
+
+type PaymentService struct {
+	paymentRepo  *repository.PaymentRepository
+	companyRepo  *repository.CompanyRepository
+	authz        *Authorizer
+	customerRepo *repository.CustomerRepository
+}
+
+func NewPaymentService(
+	paymentRepo *repository.PaymentRepository,
+	companyRepo *repository.CompanyRepository,
+	customerRepo *repository.CustomerRepository,
+) *PaymentService {
+	return &PaymentService{
+		paymentRepo:  paymentRepo,
+		companyRepo:  companyRepo,
+		authz:        NewAuthorizer(companyRepo),
+		customerRepo: customerRepo,
+	}
+}
+
+// Create registra un pago (parcial o total) contra una factura emitida
+func (s *PaymentService) Create(req *domain.CreatePaymentRequest, userID int64) (*domain.Payment, error) {
+	paymentDate, err := time.ParseInLocation("2006-01-02", req.PaymentDate, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment_date format, use YYYY-MM-DD")
+	}
+
+	invoice, err := s.paymentRepo.GetReceivable(req.DocumentID, paymentDate)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.Authorize(invoice.CompanyID, userID, domain.PermissionPaymentsWrite); err != nil {
+		return nil, err
+	}
+
+	if nonPayableStatuses[invoice.Status] {
+		return nil, fmt.Errorf("invoice in status '%s' does not accept payments", invoice.Status)
+	}
+	if paymentDate.Before(invoice.IssueDate) {
+		return nil, fmt.Errorf("payment_date must be on or after invoice issue date")
+	}
+
+	payment := &domain.Payment{
+		CompanyID:       invoice.CompanyID,
+		DocumentID:      invoice.DocumentID,
+		CustomerID:      invoice.CustomerID,
+		PaymentDate:     paymentDate,
+		Amount:          math.Round(req.Amount*100) / 100,
+		PaymentMethodID: req.PaymentMethodID,
+		Reference:       req.Reference,
+		Notes:           req.Notes,
+		CreatedBy:       userID,
+	}
+
+	// El repositorio valida el saldo con la factura bloqueada
+	if err := s.paymentRepo.Create(payment); err != nil {
+		return nil, err
+	}
+
+	payment.DocumentNumber = invoice.Number
+	return payment, nil
+}
+
+// GetByCompanyID obtiene los pagos de una empresa con paginación
+func (s *PaymentService) GetByCompanyID(companyID, customerID, userID int64, page, pageSize int) (*domain.PaymentListResponse, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionPaymentsRead); err != nil {
+		return nil, err
+	}
+
+	payments, total, err := s.paymentRepo.GetByCompanyID(companyID, customerID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PaymentListResponse{
+		Payments: payments,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// Delete elimina un pago registrado por error
+func (s *PaymentService) Delete(id, userID int64) error {
+	payment, err := s.paymentRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authz.Authorize(payment.CompanyID, userID, domain.PermissionPaymentsWrite); err != nil {
+		return err
+	}
+	return s.paymentRepo.Delete(id)
+}
+
+// GetInvoicePayments obtiene saldo, estado de pago y pagos de una factura
+func (s *PaymentService) GetInvoicePayments(invoiceID, userID int64) (*domain.InvoicePayments, error) {
+	today := startOfDay(time.Now())
+
+	invoice, err := s.paymentRepo.GetReceivable(invoiceID, today)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.Authorize(invoice.CompanyID, userID, domain.PermissionPaymentsRead); err != nil {
+		return nil, err
+	}
+
+	payments, err := s.paymentRepo.GetByDocumentID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Incluir todos los pagos registrados, también los de fecha futura
+	invoice.AmountPaid = 0
+	for _, p := range payments {
+		invoice.AmountPaid += p.Amount
+	}
+
+	receivables := []domain.Receivable{*invoice}
+	if err := s.attachInstallments(receivables); err != nil {
+		return nil, err
+	}
+	invoice = &receivables[0]
+	applyPaymentStatus(invoice, today)
+
+	return &domain.InvoicePayments{Receivable: *invoice, Payments: payments}, nil
+}
+
+// Aging genera el reporte de antigüedad de cartera por cliente a la fecha indicada
+func (s *PaymentService) Aging(companyID int64, asOf time.Time, userID int64) (*domain.AgingReport, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionReportsRead); err != nil {
+		return nil, err
+	}
+
+	asOf = startOfDay(asOf)
+	receivables, err := s.paymentRepo.GetReceivables(companyID, 0, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachInstallments(receivables); err != nil {
+		return nil, err
+	}
+
+	report := &domain.AgingReport{
+		CompanyID: companyID,
+		AsOf:      asOf.Format("2006-01-02"),
+		Customers: []domain.AgingCustomer{},
+	}
+
+	index := map[int64]int{}
+	for i := range receivables {
+		rec := &receivables[i]
+		applyPaymentStatus(rec, asOf)
+
+		pos, ok := index[rec.CustomerID]
+		if !ok {
+			pos = len(report.Customers)
+			index[rec.CustomerID] = pos
+			report.Customers = append(report.Customers, domain.AgingCustomer{
+				CustomerID:           rec.CustomerID,
+				IdentificationNumber: rec.IdentificationNumber,
+				Name:                 rec.CustomerName,
+			})
+		}
+
+		customer := &report.Customers[pos]
+		customer.Invoices++
+		addToBucket(&customer.AgingBuckets, rec, asOf)
+		addToBucket(&report.Totals, rec, asOf)
+	}
+
+	return report, nil
+}
+
+// Statement genera el estado de cuenta de un cliente entre from y to (inclusive)
+func (s *PaymentService) Statement(companyID, customerID int64, from, to time.Time, userID int64) (*domain.AccountStatement, error) {
+	company, err := s.authz.Authorize(companyID, userID, domain.PermissionReportsRead)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := s.customerRepo.GetByID(customerID)
+	if err != nil {
+		return nil, fmt.Errorf("customer not found")
+	}
+	if customer.CompanyID != companyID {
+		return nil, fmt.Errorf("customer does not belong to company")
+	}
+
+	from, to = startOfDay(from), startOfDay(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("invalid range, 'to' must be on or after 'from'")
+	}
+
+	opening, err := s.paymentRepo.GetOpeningBalance(companyID, customerID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.paymentRepo.GetStatementEntries(companyID, customerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &domain.AccountStatement{
+		Company:        companyAsDetail(company),
+		Customer:       customer,
+		From:           from.Format("2006-01-02"),
+		To:             to.Format("2006-01-02"),
+		OpeningBalance: opening,
+		Entries:        entries,
+	}
+
+	balance := opening
+	for i := range statement.Entries {
+		entry := &statement.Entries[i]
+		balance += entry.Debit - entry.Credit
+		entry.Balance = balance
+		statement.TotalDebit += entry.Debit
+		statement.TotalCredit += entry.Credit
+	}
+	statement.ClosingBalance = balance
+
+	// Facturas con saldo al cierre del periodo
+	openInvoices, err := s.paymentRepo.GetReceivables(companyID, customerID, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachInstallments(openInvoices); err != nil {
+		return nil, err
+	}
+	for i := range openInvoices {
+		applyPaymentStatus(&openInvoices[i], to)
+		addToBucket(&statement.Aging, &openInvoices[i], to)
+	}
+	statement.OpenInvoices = openInvoices
+
+	return statement, nil
+}
+
+// applyPaymentStatus calcula saldo, estado de pago y días de mora a la fecha asOf.
+// El saldo descuenta anticipos; sin due_date (contado) la factura vence el mismo día de emisión.
+func applyPaymentStatus(rec *domain.Receivable, asOf time.Time) {
+	rec.Balance = math.Round((rec.Total-rec.PrepaidAmount-rec.AmountPaid)*100) / 100
+
+	rec.DaysOverdue = 0
+	for _, due := range dueAmounts(rec, asOf) {
+		if due.balance > 0.005 && due.daysOverdue > rec.DaysOverdue {
+			rec.DaysOverdue = due.daysOverdue
+		}
+	}
+
+	switch {
+	case rec.Balance <= 0.005:
+		rec.PaymentStatus = domain.PaymentStatusPaid
+		rec.DaysOverdue = 0
+	case rec.DaysOverdue > 0:
+		rec.PaymentStatus = domain.PaymentStatusOverdue
+	case rec.AmountPaid > 0 || rec.PrepaidAmount > 0:
+		rec.PaymentStatus = domain.PaymentStatusPartiallyPaid
+	default:
+		rec.PaymentStatus = domain.PaymentStatusUnpaid
+	}
+}
+
+type dueAmount struct {
+	daysOverdue int
+	balance     float64
+}
+
+// dueAmounts retorna el saldo pendiente por vencimiento. Con cuotas, los pagos se aplican
+// en orden de vencimiento y se actualiza el saldo de cada cuota.
+func dueAmounts(rec *domain.Receivable, asOf time.Time) []dueAmount {
+	if len(rec.Installments) == 0 {
+		due := rec.IssueDate
+		if rec.DueDate != nil {
+			due = *rec.DueDate
+		}
+		return []dueAmount{{daysOverdue: daysPastDue(due, asOf), balance: rec.Balance}}
+	}
+
+	amounts := make([]dueAmount, 0, len(rec.Installments))
+	remaining := rec.AmountPaid
+	for i := range rec.Installments {
+		installment := &rec.Installments[i]
+
+		paid := math.Min(remaining, installment.Amount)
+		remaining -= paid
+		balance := math.Round((installment.Amount-paid)*100) / 100
+		days := 0
+		if balance > 0.005 {
+			days = daysPastDue(installment.DueDate, asOf)
+		}
+
+		installment.AmountPaid = &paid
+		installment.Balance = &balance
+		installment.DaysOverdue = &days
+		amounts = append(amounts, dueAmount{daysOverdue: days, balance: balance})
+	}
+	return amounts
+}
+
+// daysPastDue retorna los días transcurridos desde el vencimiento (0 si aún no vence)
+func daysPastDue(due, asOf time.Time) int {
+	due = startOfDay(due)
+	if !asOf.After(due) {
+		return 0
+	}
+	return int(asOf.Sub(due).Hours() / 24)
+}
+
+// addToBucket suma el saldo de cada vencimiento al rango de días vencidos correspondiente
+func addToBucket(buckets *domain.AgingBuckets, rec *domain.Receivable, asOf time.Time) {
+	for _, due := range dueAmounts(rec, asOf) {
+		switch {
+		case due.daysOverdue == 0:
+			buckets.NotDue += due.balance
+		case due.daysOverdue <= 30:
+			buckets.Days0To30 += due.balance
+		case due.daysOverdue <= 60:
+			buckets.Days31To60 += due.balance
+		case due.daysOverdue <= 90:
+			buckets.Days61To90 += due.balance
+		default:
+			buckets.Over90 += due.balance
+		}
+		buckets.Total += due.balance
+	}
+}
+
+// attachInstallments carga las cuotas de las facturas para calcular vencimientos por cuota
+func (s *PaymentService) attachInstallments(receivables []domain.Receivable) error {
+	ids := make([]int64, len(receivables))
+	for i := range receivables {
+		ids[i] = receivables[i].DocumentID
+	}
+
+	installments, err := s.paymentRepo.GetInstallments(ids)
+	if err != nil {
+		return err
+	}
+	for i := range receivables {
+		receivables[i].Installments = installments[receivables[i].DocumentID]
+	}
+	return nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// companyAsDetail adapta la empresa al formato que consumen los templates PDF
+func companyAsDetail(company *domain.Company) *domain.CompanyDetail {
+	return &domain.CompanyDetail{
+		ID:               company.ID,
+		NIT:              company.NIT,
+		DV:               company.DV,
+		Name:             company.Name,
+		TradeName:        company.TradeName,
+		RegistrationName: company.RegistrationName,
+		AddressLine:      company.AddressLine,
+		PostalZone:       company.PostalZone,
+		Phone:            company.Phone,
+		Email:            company.Email,
+		Website:          company.Website,
+		LogoPath:         company.LogoPath,
+	}
+}
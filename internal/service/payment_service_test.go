@@ -0,0 +1,198 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+}
+
+// installments arma cuotas de igual valor que vencen cada 30 días desde la emisión
+func installments(issue time.Time, amounts ...float64) []domain.InvoiceInstallment {
+	result := make([]domain.InvoiceInstallment, len(amounts))
+	for i, amount := range amounts {
+		result[i] = domain.InvoiceInstallment{InstallmentNumber: i + 1, DueDate: issue.AddDate(0, 0, 30*(i+1)), Amount: amount}
+	}
+	return result
+}
+
+func TestApplyPaymentStatus(t *testing.T) {
+	issue := day(2026, time.January, 10)
+	dueDate := issue.AddDate(0, 0, 30)
+
+	tests := []struct {
+		name        string
+		rec         domain.Receivable
+		asOf        time.Time
+		balance     float64
+		status      string
+		daysOverdue int
+	}{
+		{
+			name:    "cash invoice on issue date",
+			rec:     domain.Receivable{IssueDate: issue, Total: 1190000},
+			asOf:    issue,
+			balance: 1190000, status: domain.PaymentStatusUnpaid,
+		},
+		{
+			name:    "cash invoice is due the day it is issued",
+			rec:     domain.Receivable{IssueDate: issue, Total: 1190000},
+			asOf:    issue.AddDate(0, 0, 10),
+			balance: 1190000, status: domain.PaymentStatusOverdue, daysOverdue: 10,
+		},
+		{
+			name:    "credit invoice before its due date",
+			rec:     domain.Receivable{IssueDate: issue, DueDate: &dueDate, Total: 1190000, AmountPaid: 190000},
+			asOf:    dueDate,
+			balance: 1000000, status: domain.PaymentStatusPartiallyPaid,
+		},
+		{
+			name:    "credit invoice after its due date",
+			rec:     domain.Receivable{IssueDate: issue, DueDate: &dueDate, Total: 1190000, AmountPaid: 190000},
+			asOf:    dueDate.AddDate(0, 0, 45),
+			balance: 1000000, status: domain.PaymentStatusOverdue, daysOverdue: 45,
+		},
+		{
+			name:    "prepayment lowers the balance",
+			rec:     domain.Receivable{IssueDate: issue, DueDate: &dueDate, Total: 1190000, PrepaidAmount: 190000},
+			asOf:    issue,
+			balance: 1000000, status: domain.PaymentStatusPartiallyPaid,
+		},
+		{
+			name:    "prepayment and payments cover the total",
+			rec:     domain.Receivable{IssueDate: issue, Total: 1190000, PrepaidAmount: 190000, AmountPaid: 1000000},
+			asOf:    issue.AddDate(0, 3, 0),
+			balance: 0, status: domain.PaymentStatusPaid,
+		},
+		{
+			name:    "rounding residue counts as paid",
+			rec:     domain.Receivable{IssueDate: issue, Total: 100.10, AmountPaid: 100.096},
+			asOf:    issue.AddDate(0, 0, 5),
+			balance: 0, status: domain.PaymentStatusPaid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.rec
+			applyPaymentStatus(&rec, tt.asOf)
+			if rec.Balance != tt.balance || rec.PaymentStatus != tt.status || rec.DaysOverdue != tt.daysOverdue {
+				t.Errorf("balance %.2f, status %s, days overdue %d; want %.2f, %s, %d",
+					rec.Balance, rec.PaymentStatus, rec.DaysOverdue, tt.balance, tt.status, tt.daysOverdue)
+			}
+		})
+	}
+}
+
+// Los pagos se aplican a las cuotas en orden de vencimiento; la mora es la de la cuota vencida más antigua
+func TestApplyPaymentStatusInstallments(t *testing.T) {
+	issue := day(2026, time.January, 10)
+
+	tests := []struct {
+		name        string
+		rec         domain.Receivable
+		asOf        time.Time
+		status      string
+		daysOverdue int
+		paid        []float64
+		balances    []float64
+		overdue     []int
+		aging       domain.AgingBuckets
+	}{
+		{
+			name:     "first installment not due yet",
+			rec:      domain.Receivable{IssueDate: issue, Total: 300000, Installments: installments(issue, 100000, 100000, 100000)},
+			asOf:     issue.AddDate(0, 0, 20),
+			status:   domain.PaymentStatusUnpaid,
+			paid:     []float64{0, 0, 0},
+			balances: []float64{100000, 100000, 100000},
+			overdue:  []int{0, 0, 0},
+			aging:    domain.AgingBuckets{NotDue: 300000, Total: 300000},
+		},
+		{
+			name:        "payment covers the first installment and part of the second",
+			rec:         domain.Receivable{IssueDate: issue, Total: 300000, AmountPaid: 150000, Installments: installments(issue, 100000, 100000, 100000)},
+			asOf:        issue.AddDate(0, 0, 65),
+			status:      domain.PaymentStatusOverdue,
+			daysOverdue: 5,
+			paid:        []float64{100000, 50000, 0},
+			balances:    []float64{0, 50000, 100000},
+			overdue:     []int{0, 5, 0},
+			aging:       domain.AgingBuckets{NotDue: 100000, Days0To30: 50000, Total: 150000},
+		},
+		{
+			name:        "unpaid installments in several aging buckets",
+			rec:         domain.Receivable{IssueDate: issue, Total: 300000, Installments: installments(issue, 100000, 100000, 100000)},
+			asOf:        issue.AddDate(0, 0, 125),
+			status:      domain.PaymentStatusOverdue,
+			daysOverdue: 95,
+			paid:        []float64{0, 0, 0},
+			balances:    []float64{100000, 100000, 100000},
+			overdue:     []int{95, 65, 35},
+			aging:       domain.AgingBuckets{Days31To60: 100000, Days61To90: 100000, Over90: 100000, Total: 300000},
+		},
+		{
+			name:     "installments split the balance after the prepayment",
+			rec:      domain.Receivable{IssueDate: issue, Total: 300000, PrepaidAmount: 100000, AmountPaid: 100000, Installments: installments(issue, 100000, 100000)},
+			asOf:     issue.AddDate(0, 0, 40),
+			status:   domain.PaymentStatusPartiallyPaid,
+			paid:     []float64{100000, 0},
+			balances: []float64{0, 100000},
+			overdue:  []int{0, 0},
+			aging:    domain.AgingBuckets{NotDue: 100000, Total: 100000},
+		},
+		{
+			name:     "fully paid",
+			rec:      domain.Receivable{IssueDate: issue, Total: 300000, AmountPaid: 300000, Installments: installments(issue, 150000, 150000)},
+			asOf:     issue.AddDate(0, 0, 200),
+			status:   domain.PaymentStatusPaid,
+			paid:     []float64{150000, 150000},
+			balances: []float64{0, 0},
+			overdue:  []int{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.rec
+			applyPaymentStatus(&rec, tt.asOf)
+			if rec.PaymentStatus != tt.status || rec.DaysOverdue != tt.daysOverdue {
+				t.Errorf("status %s, days overdue %d; want %s, %d", rec.PaymentStatus, rec.DaysOverdue, tt.status, tt.daysOverdue)
+			}
+			for i, installment := range rec.Installments {
+				if *installment.AmountPaid != tt.paid[i] || *installment.Balance != tt.balances[i] || *installment.DaysOverdue != tt.overdue[i] {
+					t.Errorf("installment %d: paid %.2f, balance %.2f, days overdue %d; want %.2f, %.2f, %d", i+1,
+						*installment.AmountPaid, *installment.Balance, *installment.DaysOverdue, tt.paid[i], tt.balances[i], tt.overdue[i])
+				}
+			}
+
+			var aging domain.AgingBuckets
+			addToBucket(&aging, &rec, tt.asOf)
+			if aging != tt.aging {
+				t.Errorf("aging = %+v, want %+v", aging, tt.aging)
+			}
+		})
+	}
+}
+
+func TestDaysPastDue(t *testing.T) {
+	due := day(2026, time.March, 1)
+	tests := []struct {
+		asOf time.Time
+		want int
+	}{
+		{due.AddDate(0, 0, -1), 0},
+		{due, 0},
+		{due.AddDate(0, 0, 1), 1},
+		{due.AddDate(0, 1, 0), 31},
+	}
+	for _, tt := range tests {
+		// El vencimiento con hora cuenta desde el inicio del día
+		if got := daysPastDue(due.Add(15*time.Hour), tt.asOf); got != tt.want {
+			t.Errorf("daysPastDue(%s) = %d, want %d", tt.asOf.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/johnfercher/maroto/v2"
 	"github.com/johnfercher/maroto/v2/pkg/components/code"
 	"github.com/johnfercher/maroto/v2/pkg/components/col"
-	"github.com/johnfercher/maroto/v2/pkg/components/image"
 	"github.com/johnfercher/maroto/v2/pkg/components/row"
 	"github.com/johnfercher/maroto/v2/pkg/components/text"
 	marotocfg "github.com/johnfercher/maroto/v2/pkg/config"
@@ -20,15 +19,17 @@ import (
 )
 
 // DefaultTemplate implementa el diseño por defecto de facturas
-type DefaultTemplate struct{}
+type DefaultTemplate struct {
+	totalLabel string
+}
 
 // NewDefaultTemplate crea una nueva instancia del template por defecto
 func NewDefaultTemplate() *DefaultTemplate {
-	return &DefaultTemplate{}
+	return &DefaultTemplate{totalLabel: "Total Factura:"}
 }
 
 // BuildPDF construye el documento PDF completo para una factura
-func (t *DefaultTemplate) BuildPDF(invoice *domain.Invoice, logoPath string) core.Maroto {
+func (t *DefaultTemplate) BuildPDF(invoice *domain.Invoice, logo *Logo) core.Maroto {
 	cfg := marotocfg.NewBuilder().
 		WithLeftMargin(10).
 		WithTopMargin(10).
@@ -45,11 +46,12 @@ func (t *DefaultTemplate) BuildPDF(invoice *domain.Invoice, logoPath string) cor
 	mrt := maroto.New(cfg)
 	m := maroto.NewMetricsDecorator(mrt)
 
-	t.addHeader(m, invoice, logoPath)
+	t.addHeader(m, invoice, logo)
 	m.AddRows(text.NewRow(10, "", props.Text{}))
 	t.addCustomerInfo(m, invoice)
 	m.AddRows(text.NewRow(5, "", props.Text{}))
 	t.addItemsTable(m, invoice)
+	t.addPaymentTermsSection(m, invoice)
 	m.AddRows(text.NewRow(3, "", props.Text{}))
 	m.AddRows(text.NewRow(5, "", props.Text{}))
 	t.addNotesSection(m, invoice)
@@ -58,7 +60,7 @@ func (t *DefaultTemplate) BuildPDF(invoice *domain.Invoice, logoPath string) cor
 	return m
 }
 
-func (t *DefaultTemplate) addHeader(m core.Maroto, invoice *domain.Invoice, logoPath string) {
+func (t *DefaultTemplate) addHeader(m core.Maroto, invoice *domain.Invoice, logo *Logo) {
 	company := invoice.Company
 	resolution := invoice.Resolution
 
@@ -102,12 +104,7 @@ func (t *DefaultTemplate) addHeader(m core.Maroto, invoice *domain.Invoice, logo
 	}
 
 	m.AddRow(25,
-		col.New(2).Add(
-			image.NewFromFile(logoPath, props.Rect{
-				Center:  false,
-				Percent: 90,
-			}),
-		),
+		logoCol(logo),
 		col.New(7).Add(
 			text.New(companyName, props.Text{
 				Top:   0,
@@ -475,7 +472,7 @@ func (t *DefaultTemplate) addTotalsSection(m core.Maroto, invoice *domain.Invoic
 		text.NewCol(1, "", props.Text{Size: 7}),
 		text.NewCol(1, "", props.Text{Size: 7}),
 		col.New(1),
-		text.NewCol(2, "Total Factura:", props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left, Top: 2, Left: 1}),
+		text.NewCol(2, t.totalLabel, props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Left, Top: 2, Left: 1}),
 		text.NewCol(2, fmt.Sprintf("%.2f", invoice.Total), props.Text{Size: 9, Style: fontstyle.Bold, Align: align.Right, Top: 2, Right: 1, Color: &props.Color{Red: 0, Green: 100, Blue: 0}}),
 	)
 	filaTotal.WithStyle(&props.Cell{
@@ -485,6 +482,113 @@ func (t *DefaultTemplate) addTotalsSection(m core.Maroto, invoice *domain.Invoic
 		BorderThickness: 0.1,
 	})
 	m.AddRows(filaTotal)
+
+	// Anticipos: el valor a pagar es el total menos lo ya recibido
+	if invoice.PrepaidAmount > 0 {
+		for _, r := range []struct {
+			concept string
+			value   float64
+			bold    bool
+		}{
+			{"Anticipos:", invoice.PrepaidAmount, false},
+			{"Total a Pagar:", invoice.PayableAmount(), true},
+		} {
+			style := fontstyle.Normal
+			if r.bold {
+				style = fontstyle.Bold
+			}
+			fila := row.New(7)
+			fila.Add(
+				col.New(8),
+				text.NewCol(2, r.concept, props.Text{Size: 8, Style: style, Align: align.Left, Top: 1.5, Left: 1}),
+				text.NewCol(2, fmt.Sprintf("%.2f", r.value), props.Text{Size: 8, Style: style, Align: align.Right, Top: 1.5, Right: 1}),
+			)
+			fila.WithStyle(&props.Cell{
+				BorderColor:     &props.Color{Red: 220, Green: 220, Blue: 220},
+				BorderType:      border.Full,
+				BorderThickness: 0.1,
+			})
+			m.AddRows(fila)
+		}
+	}
+}
+
+// addPaymentTermsSection muestra las cuotas (vencimientos) y anticipos de la factura
+func (t *DefaultTemplate) addPaymentTermsSection(m core.Maroto, invoice *domain.Invoice) {
+	if len(invoice.Installments) == 0 && len(invoice.Prepayments) == 0 {
+		return
+	}
+
+	m.AddRows(text.NewRow(5, "", props.Text{}))
+
+	headerStyle := &props.Cell{
+		BackgroundColor: &props.Color{Red: 200, Green: 200, Blue: 200},
+		BorderColor:     &props.Color{Red: 180, Green: 180, Blue: 180},
+		BorderType:      border.Full,
+		BorderThickness: 0.1,
+	}
+	rowStyle := &props.Cell{
+		BorderColor:     &props.Color{Red: 220, Green: 220, Blue: 220},
+		BorderType:      border.Full,
+		BorderThickness: 0.1,
+	}
+	headerText := props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Center, Top: 1.5}
+	cellText := props.Text{Size: 7, Align: align.Center, Top: 1.5}
+
+	if len(invoice.Installments) > 0 {
+		m.AddRows(text.NewRow(5, "Plan de Pagos", props.Text{Size: 8, Style: fontstyle.Bold}))
+
+		header := row.New(6).Add(
+			text.NewCol(2, "Cuota", headerText),
+			text.NewCol(3, "Vencimiento", headerText),
+			text.NewCol(4, "Medio de Pago", headerText),
+			text.NewCol(3, "Valor", headerText),
+		)
+		header.WithStyle(headerStyle)
+		m.AddRows(header)
+
+		for _, installment := range invoice.Installments {
+			method := ""
+			if installment.PaymentMethodName != nil {
+				method = *installment.PaymentMethodName
+			}
+			fila := row.New(6).Add(
+				text.NewCol(2, fmt.Sprintf("%d", installment.InstallmentNumber), cellText),
+				text.NewCol(3, installment.DueDate.Format("2006-01-02"), cellText),
+				text.NewCol(4, method, cellText),
+				text.NewCol(3, fmt.Sprintf("%.2f", installment.Amount), cellText),
+			)
+			fila.WithStyle(rowStyle)
+			m.AddRows(fila)
+		}
+	}
+
+	if len(invoice.Prepayments) > 0 {
+		m.AddRows(text.NewRow(3, "", props.Text{}))
+		m.AddRows(text.NewRow(5, "Anticipos Recibidos", props.Text{Size: 8, Style: fontstyle.Bold}))
+
+		header := row.New(6).Add(
+			text.NewCol(3, "Fecha", headerText),
+			text.NewCol(6, "Referencia", headerText),
+			text.NewCol(3, "Valor", headerText),
+		)
+		header.WithStyle(headerStyle)
+		m.AddRows(header)
+
+		for _, prepayment := range invoice.Prepayments {
+			reference := ""
+			if prepayment.Reference != nil {
+				reference = *prepayment.Reference
+			}
+			fila := row.New(6).Add(
+				text.NewCol(3, prepayment.ReceivedDate.Format("2006-01-02"), cellText),
+				text.NewCol(6, reference, cellText),
+				text.NewCol(3, fmt.Sprintf("%.2f", prepayment.Amount), cellText),
+			)
+			fila.WithStyle(rowStyle)
+			m.AddRows(fila)
+		}
+	}
 }
 
 func (t *DefaultTemplate) addNotesSection(m core.Maroto, invoice *domain.Invoice) {
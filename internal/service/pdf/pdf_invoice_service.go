@@ -1,31 +1,34 @@
 package pdf
 
 import (
-	"apidian-go/internal/config"
 	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
 	"fmt"
-	"os"
+	"path"
+	"strings"
+
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
 )
 
 // PDFInvoiceService maneja la generación de PDFs de facturas
 type PDFInvoiceService struct {
-	storage  *config.StorageConfig
+	storage  storage.Storage
 	template InvoiceTemplate
 }
 
 // NewPDFInvoiceService crea una nueva instancia del servicio de PDFs
-func NewPDFInvoiceService(storage *config.StorageConfig) *PDFInvoiceService {
+func NewPDFInvoiceService(store storage.Storage) *PDFInvoiceService {
 	return &PDFInvoiceService{
-		storage:  storage,
+		storage:  store,
 		template: NewDefaultTemplate(),
 	}
 }
 
 // GenerateInvoicePDF genera el PDF de una factura y retorna los bytes
 func (s *PDFInvoiceService) GenerateInvoicePDF(invoice *domain.Invoice) ([]byte, error) {
-	logoPath := s.getLogoPath(invoice.Company)
+	logo := s.getLogo(invoice.Company)
 	
-	m := s.template.BuildPDF(invoice, logoPath)
+	m := s.template.BuildPDF(invoice, logo)
 
 	document, err := m.Generate()
 	if err != nil {
@@ -36,21 +39,113 @@ func (s *PDFInvoiceService) GenerateInvoicePDF(invoice *domain.Invoice) ([]byte,
 	return pdfBytes, nil
 }
 
-// getLogoPath obtiene la ruta del logo de la empresa o el logo por defecto
-func (s *PDFInvoiceService) getLogoPath(company *domain.CompanyDetail) string {
+// GenerateQuotationPDF genera el PDF de una cotización o pedido y retorna los bytes
+func (s *PDFInvoiceService) GenerateQuotationPDF(quotation *domain.Quotation) ([]byte, error) {
+	title := "COTIZACIÓN"
+	if quotation.Kind == domain.QuotationKindOrder {
+		title = "PEDIDO DE VENTA"
+	}
+
+	view := quotationAsInvoice(quotation)
+	logo := s.getLogo(view.Company)
+
+	m := NewQuotationTemplate(title).BuildPDF(view, logo)
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("error al generar documento: %w", err)
+	}
+
+	return document.GetBytes(), nil
+}
+
+// GenerateStatementPDF genera el PDF del estado de cuenta de un cliente
+func (s *PDFInvoiceService) GenerateStatementPDF(statement *domain.AccountStatement) ([]byte, error) {
+	logo := s.getLogo(statement.Company)
+
+	m := NewStatementTemplate().BuildPDF(statement, logo)
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("error al generar documento: %w", err)
+	}
+
+	return document.GetBytes(), nil
+}
+
+// quotationAsInvoice adapta una cotización a la estructura que consumen los templates
+func quotationAsInvoice(q *domain.Quotation) *domain.Invoice {
+	invoice := &domain.Invoice{
+		ID:                q.ID,
+		CompanyID:         q.CompanyID,
+		CustomerID:        q.CustomerID,
+		Number:            q.Number,
+		Consecutive:       q.Consecutive,
+		IssueDate:         q.IssueDate,
+		IssueTime:         q.CreatedAt,
+		DueDate:           q.ExpiresAt,
+		CurrencyCodeID:    q.CurrencyCodeID,
+		Notes:             q.Notes,
+		PaymentMethodID:   q.PaymentMethodID,
+		PaymentFormID:     q.PaymentFormID,
+		PaymentMethodName: q.PaymentMethodName,
+		PaymentFormName:   q.PaymentFormName,
+		Subtotal:          q.Subtotal,
+		TaxTotal:          q.TaxTotal,
+		Total:             q.Total,
+		Status:            q.Status,
+		Company:           q.Company,
+		Customer:          q.Customer,
+	}
+
+	for _, line := range q.Lines {
+		invoice.Lines = append(invoice.Lines, domain.InvoiceLineDetail{
+			ID:                 line.ID,
+			ProductID:          line.ProductID,
+			LineNumber:         line.LineNumber,
+			Description:        line.Description,
+			Quantity:           line.Quantity,
+			UnitPrice:          line.UnitPrice,
+			LineTotal:          line.LineTotal,
+			TaxRate:            line.TaxRate,
+			TaxAmount:          line.TaxAmount,
+			BrandName:          line.BrandName,
+			ModelName:          line.ModelName,
+			StandardItemCode:   line.StandardItemCode,
+			ClassificationCode: line.ClassificationCode,
+			CreatedAt:          line.CreatedAt,
+			ProductCode:        line.ProductCode,
+			ProductName:        line.ProductName,
+			UnitCode:           line.UnitCode,
+			UnitName:           line.UnitName,
+		})
+	}
+
+	return invoice
+}
+
+// getLogo obtiene el logo de la empresa o el logo por defecto; nil si ninguno existe
+func (s *PDFInvoiceService) getLogo(company *domain.CompanyDetail) *Logo {
 	if company != nil && company.LogoPath != nil && *company.LogoPath != "" {
-		profilePath := s.storage.CompanyProfilePath(company.NIT)
-		
-		// Buscar logo con diferentes extensiones
-		extensions := []string{".png", ".jpg", ".jpeg"}
-		for _, ext := range extensions {
-			logoPath := fmt.Sprintf("%s/logo%s", profilePath, ext)
-			if _, err := os.Stat(logoPath); err == nil {
-				return logoPath
-			}
+		if logo := s.loadLogo(*company.LogoPath); logo != nil {
+			return logo
 		}
 	}
-	
+
 	// Si no existe logo personalizado, usar el logo por defecto
-	return s.storage.DefaultLogoPath()
+	return s.loadLogo(storage.DefaultLogoKey())
+}
+
+func (s *PDFInvoiceService) loadLogo(key string) *Logo {
+	ext := extension.Type(strings.TrimPrefix(strings.ToLower(path.Ext(key)), "."))
+	if !ext.IsValid() {
+		return nil
+	}
+
+	data, err := s.storage.Get(key)
+	if err != nil {
+		return nil
+	}
+
+	return &Logo{Data: data, Extension: ext}
 }
@@ -0,0 +1,264 @@
+package pdf
+
+import (
+	"apidian-go/internal/domain"
+	"fmt"
+	"time"
+
+	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/col"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	marotocfg "github.com/johnfercher/maroto/v2/pkg/config"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/border"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+)
+
+// QuotationTemplate implementa el diseño de cotizaciones y pedidos.
+// Reutiliza la tabla de ítems, totales y notas del template por defecto,
+// pero omite resolución, QR y CUFE porque el documento no va a la DIAN.
+type QuotationTemplate struct {
+	*DefaultTemplate
+	title string
+}
+
+// NewQuotationTemplate crea un template para cotizaciones/pedidos con el título indicado
+func NewQuotationTemplate(title string) *QuotationTemplate {
+	return &QuotationTemplate{
+		DefaultTemplate: &DefaultTemplate{totalLabel: "Total:"},
+		title:           title,
+	}
+}
+
+// BuildPDF construye el documento PDF de la cotización.
+// invoice.DueDate se interpreta como la fecha de validez de la oferta.
+func (t *QuotationTemplate) BuildPDF(invoice *domain.Invoice, logo *Logo) core.Maroto {
+	cfg := marotocfg.NewBuilder().
+		WithLeftMargin(10).
+		WithTopMargin(10).
+		WithRightMargin(10).
+		WithPageNumber(props.PageNumber{
+			Pattern: "Página {current} de {total}",
+			Place:   props.Bottom,
+			Family:  "",
+			Style:   fontstyle.Normal,
+			Size:    7,
+		}).
+		Build()
+
+	mrt := maroto.New(cfg)
+	m := maroto.NewMetricsDecorator(mrt)
+
+	t.addQuotationHeader(m, invoice, logo)
+	m.AddRows(text.NewRow(10, "", props.Text{}))
+	t.addQuotationCustomerInfo(m, invoice)
+	m.AddRows(text.NewRow(5, "", props.Text{}))
+	t.addItemsTable(m, invoice)
+	m.AddRows(text.NewRow(8, "", props.Text{}))
+	t.addNotesSection(m, invoice)
+	t.addQuotationFooter(m, invoice)
+
+	return m
+}
+
+func (t *QuotationTemplate) addQuotationHeader(m core.Maroto, invoice *domain.Invoice, logo *Logo) {
+	company := invoice.Company
+
+	companyName := "EMPRESA SAS"
+	nit := "000000000-0"
+	address := "Dirección"
+	phone := "Teléfono"
+	email := "email@empresa.com"
+
+	if company != nil {
+		companyName = company.Name
+		nit = company.NIT
+		if company.DV != nil {
+			nit += "-" + *company.DV
+		}
+		address = company.AddressLine + " - " + company.Municipality + " - " + company.Department + " - " + company.CountryName
+		if company.Phone != nil {
+			phone = "Telefono - " + *company.Phone
+		}
+		if company.Email != nil {
+			email = "E-mail: " + *company.Email
+		}
+	}
+
+	validity := "Validez: no definida"
+	if invoice.DueDate != nil {
+		validity = fmt.Sprintf("Válida hasta: %s", invoice.DueDate.In(time.Local).Format("2006-01-02"))
+	}
+
+	m.AddRow(25,
+		logoCol(logo),
+		col.New(7).Add(
+			text.New(companyName, props.Text{
+				Top:   0,
+				Size:  14,
+				Style: fontstyle.Bold,
+				Align: align.Center,
+				Color: &props.Color{Red: 0, Green: 102, Blue: 204},
+			}),
+			text.New("NIT: "+nit, props.Text{
+				Top:   6.5,
+				Size:  6,
+				Align: align.Center,
+			}),
+			text.New(address, props.Text{
+				Top:   9.1,
+				Size:  6,
+				Align: align.Center,
+			}),
+			text.New(phone, props.Text{
+				Top:   11.7,
+				Size:  6,
+				Align: align.Center,
+			}),
+			text.New(email, props.Text{
+				Top:   14.3,
+				Size:  6,
+				Align: align.Center,
+			}),
+		),
+		col.New(3).Add(
+			text.New(t.title, props.Text{
+				Top:   1,
+				Size:  8,
+				Style: fontstyle.Bold,
+				Align: align.Right,
+			}),
+			text.New(invoice.Number, props.Text{
+				Top:   4,
+				Size:  12,
+				Style: fontstyle.Bold,
+				Align: align.Right,
+				Color: &props.Color{Red: 0, Green: 102, Blue: 204},
+			}),
+			text.New(fmt.Sprintf("Fecha: %s", invoice.IssueDate.In(time.Local).Format("2006-01-02")), props.Text{
+				Top:   9,
+				Size:  8,
+				Align: align.Right,
+			}),
+			text.New(validity, props.Text{
+				Top:   12.5,
+				Size:  7,
+				Style: fontstyle.Bold,
+				Align: align.Right,
+			}),
+		),
+	)
+}
+
+func (t *QuotationTemplate) addQuotationCustomerInfo(m core.Maroto, invoice *domain.Invoice) {
+	customer := invoice.Customer
+
+	identification := "000000000"
+	customerName := "CLIENTE"
+	customerAddress := "Dirección"
+	city := "Ciudad"
+	customerPhone := "Teléfono"
+	customerEmail := "email@cliente.com"
+
+	if customer != nil {
+		identification = customer.IdentificationNumber
+		if customer.DV != nil {
+			identification += "-" + *customer.DV
+		}
+		customerName = customer.Name
+		customerAddress = customer.AddressLine
+		city = customer.Municipality + " - " + customer.CountryName
+		if customer.Phone != nil {
+			customerPhone = *customer.Phone
+		}
+		if customer.Email != nil {
+			customerEmail = *customer.Email
+		}
+	}
+
+	paymentForm := "Contado"
+	if invoice.PaymentFormName != nil {
+		paymentForm = *invoice.PaymentFormName
+	}
+
+	paymentMeans := "Efectivo"
+	if invoice.PaymentMethodName != nil {
+		paymentMeans = *invoice.PaymentMethodName
+	}
+
+	m.AddRow(20,
+		col.New(1).Add(
+			text.New("CC o NIT:", props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Cliente:", props.Text{Top: 3, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Dirección:", props.Text{Top: 6, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Ciudad:", props.Text{Top: 9, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Telefono:", props.Text{Top: 12, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Email:", props.Text{Top: 15, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+		),
+		col.New(5).Add(
+			text.New(identification, props.Text{Size: 7, Align: align.Left}),
+			text.New(customerName, props.Text{Top: 3, Size: 7, Align: align.Left}),
+			text.New(customerAddress, props.Text{Top: 6, Size: 7, Align: align.Left}),
+			text.New(city, props.Text{Top: 9, Size: 7, Align: align.Left}),
+			text.New(customerPhone, props.Text{Top: 12, Size: 7, Align: align.Left}),
+			text.New(customerEmail, props.Text{Top: 15, Size: 7, Align: align.Left}),
+		),
+		col.New(2).Add(
+			text.New("Forma de Pago:", props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Medio de Pago:", props.Text{Top: 3, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+			text.New("Estado:", props.Text{Top: 6, Size: 7, Style: fontstyle.Bold, Align: align.Left}),
+		),
+		col.New(4).Add(
+			text.New(paymentForm, props.Text{Size: 7, Align: align.Left}),
+			text.New(paymentMeans, props.Text{Top: 3, Size: 7, Align: align.Left}),
+			text.New(quotationStatusLabel(invoice.Status), props.Text{Top: 6, Size: 7, Align: align.Left}),
+		),
+	)
+}
+
+func (t *QuotationTemplate) addQuotationFooter(m core.Maroto, invoice *domain.Invoice) {
+	m.AddRows(text.NewRow(10, "", props.Text{}))
+
+	lineaFooter := row.New(3)
+	lineaFooter.Add(col.New(12))
+	lineaFooter.WithStyle(&props.Cell{
+		BorderColor:     &props.Color{Red: 180, Green: 180, Blue: 180},
+		BorderType:      border.Top,
+		BorderThickness: 0.1,
+	})
+	m.AddRows(lineaFooter)
+
+	m.AddRows(
+		text.NewRow(3, fmt.Sprintf("%s No: %s - Fecha de Generación: %s", t.title, invoice.Number, time.Now().Format("2006-01-02 15:04:05")), props.Text{
+			Size:  6,
+			Align: align.Center,
+		}),
+		text.NewRow(3, "DOCUMENTO COMERCIAL - NO ES UNA FACTURA ELECTRÓNICA DE VENTA", props.Text{
+			Size:  7,
+			Align: align.Center,
+			Style: fontstyle.Bold,
+			Color: &props.Color{Red: 120, Green: 120, Blue: 120},
+		}),
+	)
+}
+
+// quotationStatusLabel traduce el estado de la cotización para el PDF
+func quotationStatusLabel(status string) string {
+	switch status {
+	case domain.QuotationStatusSent:
+		return "Enviada"
+	case domain.QuotationStatusAccepted:
+		return "Aceptada"
+	case domain.QuotationStatusRejected:
+		return "Rechazada"
+	case domain.QuotationStatusExpired:
+		return "Vencida"
+	case domain.QuotationStatusConverted:
+		return "Facturada"
+	default:
+		return "Borrador"
+	}
+}
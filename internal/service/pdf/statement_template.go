@@ -0,0 +1,322 @@
+package pdf
+
+import (
+	"apidian-go/internal/domain"
+	"fmt"
+	"time"
+
+	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/col"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	marotocfg "github.com/johnfercher/maroto/v2/pkg/config"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/border"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+)
+
+// StatementTemplate implementa el diseño del estado de cuenta de un cliente
+type StatementTemplate struct{}
+
+// NewStatementTemplate crea el template de estado de cuenta
+func NewStatementTemplate() *StatementTemplate {
+	return &StatementTemplate{}
+}
+
+// BuildPDF construye el estado de cuenta: movimientos del periodo, facturas abiertas y antigüedad
+func (t *StatementTemplate) BuildPDF(statement *domain.AccountStatement, logo *Logo) core.Maroto {
+	cfg := marotocfg.NewBuilder().
+		WithLeftMargin(10).
+		WithTopMargin(10).
+		WithRightMargin(10).
+		WithPageNumber(props.PageNumber{
+			Pattern: "Página {current} de {total}",
+			Place:   props.Bottom,
+			Family:  "",
+			Style:   fontstyle.Normal,
+			Size:    7,
+		}).
+		Build()
+
+	mrt := maroto.New(cfg)
+	m := maroto.NewMetricsDecorator(mrt)
+
+	t.addHeader(m, statement, logo)
+	m.AddRows(text.NewRow(8, "", props.Text{}))
+	t.addCustomerInfo(m, statement)
+	m.AddRows(text.NewRow(5, "", props.Text{}))
+	t.addEntriesTable(m, statement)
+	m.AddRows(text.NewRow(8, "", props.Text{}))
+	t.addOpenInvoices(m, statement)
+	m.AddRows(text.NewRow(5, "", props.Text{}))
+	t.addAging(m, statement)
+	t.addFooter(m)
+
+	return m
+}
+
+func (t *StatementTemplate) addHeader(m core.Maroto, statement *domain.AccountStatement, logo *Logo) {
+	companyName := "EMPRESA SAS"
+	nit := "000000000-0"
+	address := ""
+	contact := ""
+
+	if company := statement.Company; company != nil {
+		companyName = company.Name
+		nit = company.NIT
+		if company.DV != nil {
+			nit += "-" + *company.DV
+		}
+		address = company.AddressLine
+		if company.Phone != nil {
+			contact = "Telefono - " + *company.Phone
+		}
+		if company.Email != nil {
+			if contact != "" {
+				contact += " - "
+			}
+			contact += "E-mail: " + *company.Email
+		}
+	}
+
+	m.AddRow(22,
+		logoCol(logo),
+		col.New(7).Add(
+			text.New(companyName, props.Text{
+				Size:  14,
+				Style: fontstyle.Bold,
+				Align: align.Center,
+				Color: &props.Color{Red: 0, Green: 102, Blue: 204},
+			}),
+			text.New("NIT: "+nit, props.Text{Top: 6.5, Size: 6, Align: align.Center}),
+			text.New(address, props.Text{Top: 9.1, Size: 6, Align: align.Center}),
+			text.New(contact, props.Text{Top: 11.7, Size: 6, Align: align.Center}),
+		),
+		col.New(3).Add(
+			text.New("ESTADO DE CUENTA", props.Text{
+				Top:   1,
+				Size:  10,
+				Style: fontstyle.Bold,
+				Align: align.Right,
+				Color: &props.Color{Red: 0, Green: 102, Blue: 204},
+			}),
+			text.New(fmt.Sprintf("Desde: %s", statement.From), props.Text{Top: 7, Size: 8, Align: align.Right}),
+			text.New(fmt.Sprintf("Hasta: %s", statement.To), props.Text{Top: 10.5, Size: 8, Align: align.Right}),
+		),
+	)
+}
+
+func (t *StatementTemplate) addCustomerInfo(m core.Maroto, statement *domain.AccountStatement) {
+	customer := statement.Customer
+
+	identification := ""
+	customerName := ""
+	customerAddress := ""
+	if customer != nil {
+		identification = customer.IdentificationNumber
+		if customer.DV != nil {
+			identification += "-" + *customer.DV
+		}
+		customerName = customer.Name
+		customerAddress = customer.AddressLine
+	}
+
+	m.AddRow(12,
+		col.New(2).Add(
+			text.New("CC o NIT:", props.Text{Size: 7, Style: fontstyle.Bold}),
+			text.New("Cliente:", props.Text{Top: 3.5, Size: 7, Style: fontstyle.Bold}),
+			text.New("Dirección:", props.Text{Top: 7, Size: 7, Style: fontstyle.Bold}),
+		),
+		col.New(5).Add(
+			text.New(identification, props.Text{Size: 7}),
+			text.New(customerName, props.Text{Top: 3.5, Size: 7}),
+			text.New(customerAddress, props.Text{Top: 7, Size: 7}),
+		),
+		col.New(3).Add(
+			text.New("Saldo inicial:", props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Right}),
+			text.New("Saldo final:", props.Text{Top: 3.5, Size: 7, Style: fontstyle.Bold, Align: align.Right}),
+		),
+		col.New(2).Add(
+			text.New(formatMoney(statement.OpeningBalance), props.Text{Size: 7, Align: align.Right}),
+			text.New(formatMoney(statement.ClosingBalance), props.Text{Top: 3.5, Size: 7, Style: fontstyle.Bold, Align: align.Right}),
+		),
+	)
+}
+
+func (t *StatementTemplate) addEntriesTable(m core.Maroto, statement *domain.AccountStatement) {
+	addTableHeader(m,
+		[]string{"Fecha", "Tipo", "Documento", "Referencia", "Vence", "Débito", "Crédito", "Saldo"},
+		[]int{1, 1, 2, 2, 1, 2, 1, 2},
+	)
+
+	addTableRow(m, 0, []int{7, 5}, []string{"Saldo inicial", formatMoney(statement.OpeningBalance)})
+
+	for i, entry := range statement.Entries {
+		kind := "Factura"
+		switch entry.Type {
+		case "prepayment":
+			kind = "Anticipo"
+		case "payment":
+			kind = "Pago"
+		}
+		reference := ""
+		if entry.Reference != nil {
+			reference = *entry.Reference
+		}
+		dueDate := ""
+		if entry.DueDate != nil {
+			dueDate = formatDate(*entry.DueDate)
+		}
+		debit, credit := "", ""
+		if entry.Debit != 0 {
+			debit = formatMoney(entry.Debit)
+		}
+		if entry.Credit != 0 {
+			credit = formatMoney(entry.Credit)
+		}
+
+		addTableRow(m, i+1, []int{1, 1, 2, 2, 1, 2, 1, 2}, []string{
+			formatDate(entry.Date), kind, entry.Number, reference, dueDate, debit, credit, formatMoney(entry.Balance),
+		})
+	}
+
+	totals := row.New(6).Add(
+		text.NewCol(7, "Totales del periodo", props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Right, Top: 1.5}),
+		text.NewCol(2, formatMoney(statement.TotalDebit), props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Center, Top: 1.5}),
+		text.NewCol(1, formatMoney(statement.TotalCredit), props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Center, Top: 1.5}),
+		text.NewCol(2, formatMoney(statement.ClosingBalance), props.Text{Size: 7, Style: fontstyle.Bold, Align: align.Center, Top: 1.5}),
+	)
+	totals.WithStyle(&props.Cell{
+		BackgroundColor: &props.Color{Red: 230, Green: 230, Blue: 230},
+		BorderColor:     &props.Color{Red: 180, Green: 180, Blue: 180},
+		BorderType:      border.Full,
+		BorderThickness: 0.1,
+	})
+	m.AddRows(totals)
+}
+
+func (t *StatementTemplate) addOpenInvoices(m core.Maroto, statement *domain.AccountStatement) {
+	m.AddRows(text.NewRow(6, "FACTURAS PENDIENTES AL "+statement.To, props.Text{Size: 8, Style: fontstyle.Bold}))
+
+	if len(statement.OpenInvoices) == 0 {
+		m.AddRows(text.NewRow(6, "El cliente no tiene facturas pendientes.", props.Text{Size: 7}))
+		return
+	}
+
+	widths := []int{2, 2, 2, 2, 2, 1, 1}
+	addTableHeader(m, []string{"Factura", "Emisión", "Vence", "Total", "Saldo", "Días", "Estado"}, widths)
+
+	for i, rec := range statement.OpenInvoices {
+		dueDate := ""
+		if rec.DueDate != nil {
+			dueDate = formatDate(*rec.DueDate)
+		}
+		addTableRow(m, i, widths, []string{
+			rec.Number,
+			formatDate(rec.IssueDate),
+			dueDate,
+			formatMoney(rec.Total),
+			formatMoney(rec.Balance),
+			fmt.Sprintf("%d", rec.DaysOverdue),
+			paymentStatusLabel(rec.PaymentStatus),
+		})
+	}
+}
+
+func (t *StatementTemplate) addAging(m core.Maroto, statement *domain.AccountStatement) {
+	aging := statement.Aging
+	widths := []int{2, 2, 2, 2, 2, 2}
+
+	addTableHeader(m, []string{"Sin vencer", "0-30 días", "31-60 días", "61-90 días", "Más de 90", "Total"}, widths)
+	addTableRow(m, 0, widths, []string{
+		formatMoney(aging.NotDue),
+		formatMoney(aging.Days0To30),
+		formatMoney(aging.Days31To60),
+		formatMoney(aging.Days61To90),
+		formatMoney(aging.Over90),
+		formatMoney(aging.Total),
+	})
+}
+
+func (t *StatementTemplate) addFooter(m core.Maroto) {
+	m.AddRows(text.NewRow(10, "", props.Text{}))
+
+	lineaFooter := row.New(3)
+	lineaFooter.Add(col.New(12))
+	lineaFooter.WithStyle(&props.Cell{
+		BorderColor:     &props.Color{Red: 180, Green: 180, Blue: 180},
+		BorderType:      border.Top,
+		BorderThickness: 0.1,
+	})
+	m.AddRows(lineaFooter)
+
+	m.AddRows(
+		text.NewRow(3, fmt.Sprintf("Fecha de Generación: %s", time.Now().Format("2006-01-02 15:04:05")), props.Text{
+			Size:  6,
+			Align: align.Center,
+		}),
+	)
+}
+
+// addTableHeader agrega una fila de encabezado con el estilo de la tabla de ítems de factura
+func addTableHeader(m core.Maroto, header []string, widths []int) {
+	headerRow := row.New(6)
+	for i, h := range header {
+		headerRow.Add(text.NewCol(widths[i], h, props.Text{
+			Size:  7,
+			Style: fontstyle.Bold,
+			Align: align.Center,
+			Top:   1.5,
+		}))
+	}
+	headerRow.WithStyle(&props.Cell{
+		BackgroundColor: &props.Color{Red: 200, Green: 200, Blue: 200},
+		BorderColor:     &props.Color{Red: 180, Green: 180, Blue: 180},
+		BorderType:      border.Full,
+		BorderThickness: 0.1,
+	})
+	m.AddRows(headerRow)
+}
+
+// addTableRow agrega una fila de datos alternando el color de fondo
+func addTableRow(m core.Maroto, index int, widths []int, values []string) {
+	dataRow := row.New(6)
+	for i, value := range values {
+		dataRow.Add(text.NewCol(widths[i], value, props.Text{Size: 7, Align: align.Center, Top: 1.5}))
+	}
+
+	style := &props.Cell{
+		BorderColor:     &props.Color{Red: 220, Green: 220, Blue: 220},
+		BorderType:      border.Full,
+		BorderThickness: 0.1,
+	}
+	if index%2 == 1 {
+		style.BackgroundColor = &props.Color{Red: 245, Green: 245, Blue: 245}
+	}
+	dataRow.WithStyle(style)
+	m.AddRows(dataRow)
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func formatMoney(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+// paymentStatusLabel traduce el estado de pago de la factura para el PDF
+func paymentStatusLabel(status string) string {
+	switch status {
+	case domain.PaymentStatusPaid:
+		return "Pagada"
+	case domain.PaymentStatusPartiallyPaid:
+		return "Abonada"
+	case domain.PaymentStatusOverdue:
+		return "Vencida"
+	default:
+		return "Pendiente"
+	}
+}
@@ -3,11 +3,33 @@ package pdf
 import (
 	"apidian-go/internal/domain"
 
+	"github.com/johnfercher/maroto/v2/pkg/components/col"
+	"github.com/johnfercher/maroto/v2/pkg/components/image"
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
 	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
 )
 
 // InvoiceTemplate define la interfaz para templates de facturas
 type InvoiceTemplate interface {
 	// BuildPDF construye el documento PDF completo para una factura
-	BuildPDF(invoice *domain.Invoice, logoPath string) core.Maroto
+	BuildPDF(invoice *domain.Invoice, logo *Logo) core.Maroto
+}
+
+// Logo es la imagen del logo leída desde el storage
+type Logo struct {
+	Data      []byte
+	Extension extension.Type
+}
+
+// logoCol retorna la columna del encabezado con el logo (vacía si no hay logo)
+func logoCol(logo *Logo) core.Col {
+	c := col.New(2)
+	if logo != nil {
+		c.Add(image.NewFromBytes(logo.Data, logo.Extension, props.Rect{
+			Center:  false,
+			Percent: 90,
+		}))
+	}
+	return c
 }
@@ -0,0 +1,106 @@
+package prevalidation
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/pkg/xmldsig"
+	"errors"
+	"fmt"
+)
+
+// Context aporta los datos que no viajan en el XML y que DIAN usa para validarlo
+type Context struct {
+	TechnicalKey string // Clave técnica de la resolución (CUFE)
+	SoftwarePIN  string // PIN del software (SoftwareSecurityCode)
+}
+
+// Validator valida localmente el XML UBL 2.1 contra el XSD y las reglas de la tabla de
+// validaciones DIAN, para detectar rechazos antes de enviar el documento.
+type Validator struct {
+	schemaDir string
+}
+
+// New crea el validador; schemaDir es la raíz de los XSD de UBL 2.1 (vacío = sin validación XSD)
+func New(schemaDir string) *Validator {
+	return &Validator{schemaDir: schemaDir}
+}
+
+// Error se retorna cuando la prevalidación encuentra errores que DIAN rechazaría
+type Error struct {
+	Report *domain.PrevalidationReport
+}
+
+func (e *Error) Error() string {
+	for _, issue := range e.Report.Issues {
+		if issue.Severity == domain.SeverityError {
+			return fmt.Sprintf("prevalidation failed with %d error(s): %s %s", e.Report.Errors, issue.Rule, issue.Message)
+		}
+	}
+	return "prevalidation failed"
+}
+
+// ReportFrom extrae el reporte si err (o un error envuelto) es un *Error de prevalidación
+func ReportFrom(err error) (*domain.PrevalidationReport, bool) {
+	var prevalidationErr *Error
+	if errors.As(err, &prevalidationErr) {
+		return prevalidationErr.Report, true
+	}
+	return nil, false
+}
+
+// Validate ejecuta la validación XSD y las reglas DIAN del tipo de documento
+func (v *Validator) Validate(data []byte, ctx Context) *domain.PrevalidationReport {
+	report := &domain.PrevalidationReport{Issues: []domain.ValidationIssue{}}
+
+	root, err := xmldsig.Parse(data)
+	if err != nil {
+		addIssue(report, domain.ValidationIssue{Rule: RuleXML, Severity: domain.SeverityError, Message: err.Error()})
+		return finish(report)
+	}
+	report.DocumentType = root.Local
+	report.Number = elementText(root, "ID")
+	report.Signed = root.Find(xmldsig.NamespaceDSig, "Signature") != nil
+
+	schemaIssues, checked := v.validateSchema(data, root.Local)
+	report.SchemaChecked = checked
+	for _, issue := range schemaIssues {
+		addIssue(report, issue)
+	}
+
+	doc := &document{root: root, ctx: ctx}
+	rules := rulesFor(root.Local)
+	if rules == nil {
+		addIssue(report, domain.ValidationIssue{
+			Rule:     RuleXML,
+			Severity: domain.SeverityWarning,
+			XPath:    root.XPath(),
+			Message:  fmt.Sprintf("no DIAN rule set for document type %s", root.Local),
+		})
+	}
+	for _, r := range rules {
+		report.RulesChecked++
+		for _, f := range r.check(doc) {
+			severity := r.severity
+			if f.severity != "" {
+				severity = f.severity
+			}
+			addIssue(report, domain.ValidationIssue{Rule: r.code, Severity: severity, XPath: f.xpath, Message: f.message})
+		}
+	}
+
+	return finish(report)
+}
+
+// addIssue agrega un hallazgo y actualiza los contadores
+func addIssue(report *domain.PrevalidationReport, issue domain.ValidationIssue) {
+	report.Issues = append(report.Issues, issue)
+	if issue.Severity == domain.SeverityError {
+		report.Errors++
+	} else {
+		report.Warnings++
+	}
+}
+
+func finish(report *domain.PrevalidationReport) *domain.PrevalidationReport {
+	report.Valid = report.Errors == 0
+	return report
+}
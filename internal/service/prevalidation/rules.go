@@ -0,0 +1,613 @@
+package prevalidation
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/pkg/validator"
+	"apidian-go/pkg/xmldsig"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// document es el XML bajo validación con el contexto de la empresa emisora
+type document struct {
+	root *xmldsig.Element
+	ctx  Context
+}
+
+// finding es un incumplimiento de una regla; severity vacío usa la severidad de la regla
+type finding struct {
+	xpath    string
+	message  string
+	severity string
+}
+
+// rule es una validación de la tabla DIAN identificada por su código (FAD, FAB, FAJ, FAK, FAS, FAU, FAV)
+type rule struct {
+	code     string
+	severity string
+	check    func(d *document) []finding
+}
+
+// amountTolerance absorbe las diferencias de redondeo entre líneas y totales
+const amountTolerance = 1.0
+
+var (
+	issueTimePattern = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?-05:00$`)
+	currencyPattern  = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// invoiceRules replica, por grupos, la tabla de validaciones de la factura electrónica de venta
+// del anexo técnico DIAN. Nuevas reglas se agregan aquí con su código oficial.
+var invoiceRules = []rule{
+	// Encabezado del documento
+	{"FAD01", domain.SeverityError, expectValue([]string{"UBL 2.1"}, "cbc:UBLVersionID")},
+	{"FAD02", domain.SeverityError, checkCustomizationID},
+	{"FAD03", domain.SeverityError, expectValue([]string{"DIAN 2.1: Factura Electrónica de Venta"}, "cbc:ProfileID")},
+	{"FAD04", domain.SeverityError, expectValue([]string{"1", "2"}, "cbc:ProfileExecutionID")},
+	{"FAD05", domain.SeverityError, required("cbc:ID")},
+	{"FAD06", domain.SeverityError, checkCUFE},
+	{"FAD07", domain.SeverityError, checkUUIDSchemeID},
+	{"FAD08", domain.SeverityError, expectAttr("schemeName", "CUFE-SHA384", "cbc:UUID")},
+	{"FAD09", domain.SeverityError, checkIssueDate},
+	{"FAD09e", domain.SeverityError, checkSigningTime},
+	{"FAD10", domain.SeverityError, checkIssueTime},
+	{"FAD11", domain.SeverityError, expectValue([]string{"01", "02", "03", "04"}, "cbc:InvoiceTypeCode")},
+	{"FAD13", domain.SeverityError, checkCurrency},
+	{"FAD15", domain.SeverityError, checkLineCount},
+
+	// Extensiones DIAN (sts:DianExtensions)
+	{"FAB02", domain.SeverityError, checkAuthorization},
+	{"FAB05", domain.SeverityError, checkAuthorizationPeriod("StartDate")},
+	{"FAB06", domain.SeverityError, checkAuthorizationPeriod("EndDate")},
+	{"FAB07", domain.SeverityError, checkPrefix},
+	{"FAB08", domain.SeverityError, checkAuthorizedRange},
+	{"FAB10", domain.SeverityError, extension("sts:SoftwareProvider", "sts:SoftwareID")},
+	{"FAB19", domain.SeverityError, checkSoftwareSecurityCode},
+	{"FAB27", domain.SeverityWarning, checkQRCode},
+
+	// Emisor (AccountingSupplierParty)
+	{"FAJ02", domain.SeverityError, expectValue([]string{"1", "2"}, "cac:AccountingSupplierParty", "cbc:AdditionalAccountID")},
+	{"FAJ21", domain.SeverityError, required("cac:AccountingSupplierParty", "cac:Party", "cac:PartyTaxScheme", "cbc:CompanyID")},
+	{"FAJ24", domain.SeverityError, checkDV("cac:AccountingSupplierParty")},
+	{"FAJ43", domain.SeverityError, required("cac:AccountingSupplierParty", "cac:Party", "cac:PartyTaxScheme", "cbc:RegistrationName")},
+
+	// Adquiriente (AccountingCustomerParty)
+	{"FAK02", domain.SeverityError, expectValue([]string{"1", "2"}, "cac:AccountingCustomerParty", "cbc:AdditionalAccountID")},
+	{"FAK21", domain.SeverityError, required("cac:AccountingCustomerParty", "cac:Party", "cac:PartyTaxScheme", "cbc:CompanyID")},
+	{"FAK24", domain.SeverityError, checkDV("cac:AccountingCustomerParty")},
+	{"FAK43", domain.SeverityError, required("cac:AccountingCustomerParty", "cac:Party", "cac:PartyTaxScheme", "cbc:RegistrationName")},
+
+	// Impuestos
+	{"FAS01", domain.SeverityError, checkTaxTotals},
+	{"FAS05", domain.SeverityError, checkTaxSubtotals},
+
+	// Totales (LegalMonetaryTotal)
+	{"FAU02", domain.SeverityError, checkLineExtensionTotal},
+	{"FAU06", domain.SeverityError, checkTaxInclusiveAmount},
+	{"FAU14", domain.SeverityError, checkPayableAmount},
+
+	// Líneas (InvoiceLine)
+	{"FAV02", domain.SeverityError, checkLineIDs},
+	{"FAV04", domain.SeverityError, checkLineQuantities},
+	{"FAV05", domain.SeverityError, checkLineAmounts},
+	{"FAV08", domain.SeverityError, checkLineDescriptions},
+}
+
+// rulesFor retorna las reglas del tipo de documento (nil si no hay tabla implementada)
+func rulesFor(documentType string) []rule {
+	if documentType == "Invoice" {
+		return invoiceRules
+	}
+	return nil
+}
+
+// locate recorre hijos directos por nombre calificado ("cac:Party"). Si falta un paso retorna
+// nil y la ruta esperada, para reportar el XPath del elemento ausente.
+func locate(el *xmldsig.Element, steps ...string) (*xmldsig.Element, string) {
+	for i, step := range steps {
+		child := el.Child(localName(step))
+		if child == nil {
+			return nil, el.XPath() + "/" + strings.Join(steps[i:], "/")
+		}
+		el = child
+	}
+	return el, el.XPath()
+}
+
+func localName(step string) string {
+	if i := strings.IndexByte(step, ':'); i >= 0 {
+		return step[i+1:]
+	}
+	return step
+}
+
+func fail(xpath, format string, args ...any) []finding {
+	return []finding{{xpath: xpath, message: fmt.Sprintf(format, args...)}}
+}
+
+func amountsDiffer(a, b float64) bool {
+	return math.Abs(a-b) > amountTolerance
+}
+
+// required exige que el elemento exista y tenga contenido
+func required(steps ...string) func(d *document) []finding {
+	return func(d *document) []finding {
+		el, xpath := locate(d.root, steps...)
+		if el == nil || el.Text() == "" {
+			return fail(xpath, "%s is required", steps[len(steps)-1])
+		}
+		return nil
+	}
+}
+
+// expectValue exige que el elemento exista y tenga uno de los valores permitidos
+func expectValue(allowed []string, steps ...string) func(d *document) []finding {
+	return func(d *document) []finding {
+		el, xpath := locate(d.root, steps...)
+		if el == nil {
+			return fail(xpath, "%s is required", steps[len(steps)-1])
+		}
+		for _, value := range allowed {
+			if el.Text() == value {
+				return nil
+			}
+		}
+		return fail(xpath, "invalid value %q, expected %s", el.Text(), strings.Join(allowed, " or "))
+	}
+}
+
+// expectAttr exige un valor fijo en un atributo del elemento
+func expectAttr(attr, expected string, steps ...string) func(d *document) []finding {
+	return func(d *document) []finding {
+		el, xpath := locate(d.root, steps...)
+		if el == nil {
+			return fail(xpath, "%s is required", steps[len(steps)-1])
+		}
+		if el.Attr(attr) != expected {
+			return fail(xpath+"/@"+attr, "invalid value %q, expected %q", el.Attr(attr), expected)
+		}
+		return nil
+	}
+}
+
+func checkCustomizationID(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:CustomizationID")
+	if el == nil || el.Text() == "" {
+		return fail(xpath, "operation type (CustomizationID) is required")
+	}
+	switch el.Text() {
+	case "09", "10", "11", "12", "13", "15", "16":
+		return nil
+	}
+	return []finding{{
+		xpath:    xpath,
+		message:  fmt.Sprintf("unknown operation type %q for an invoice", el.Text()),
+		severity: domain.SeverityWarning,
+	}}
+}
+
+func checkCUFE(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:UUID")
+	if el == nil || el.Text() == "" {
+		return fail(xpath, "CUFE (UUID) is required")
+	}
+	if d.ctx.TechnicalKey == "" {
+		return []finding{{xpath: xpath, message: "CUFE not checked: technical key not available", severity: domain.SeverityWarning}}
+	}
+	if computed := DocumentUUID(d.root, d.ctx.TechnicalKey); !strings.EqualFold(computed, el.Text()) {
+		return fail(xpath, "CUFE does not match the document content (computed %s)", computed)
+	}
+	return nil
+}
+
+func checkUUIDSchemeID(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:UUID")
+	if el == nil {
+		return nil // FAD06
+	}
+	if environment := elementText(d.root, "ProfileExecutionID"); el.Attr("schemeID") != environment {
+		return fail(xpath+"/@schemeID", "schemeID %q must match ProfileExecutionID %q", el.Attr("schemeID"), environment)
+	}
+	return nil
+}
+
+func checkIssueDate(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:IssueDate")
+	if el == nil {
+		return fail(xpath, "IssueDate is required")
+	}
+	if _, err := time.Parse("2006-01-02", el.Text()); err != nil {
+		return fail(xpath, "invalid IssueDate %q, expected YYYY-MM-DD", el.Text())
+	}
+	return nil
+}
+
+// checkSigningTime exige que la fecha de firma (xades:SigningTime) coincida con IssueDate
+func checkSigningTime(d *document) []finding {
+	signingTime := d.root.Find(xmldsig.NamespaceXAdES, "SigningTime")
+	if signingTime == nil {
+		return nil // Documento sin firmar
+	}
+	issueDate := elementText(d.root, "IssueDate")
+	if !strings.HasPrefix(signingTime.Text(), issueDate) {
+		return fail(signingTime.XPath(), "signing date %s differs from IssueDate %s", signingTime.Text(), issueDate)
+	}
+	return nil
+}
+
+func checkIssueTime(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:IssueTime")
+	if el == nil {
+		return fail(xpath, "IssueTime is required")
+	}
+	if !issueTimePattern.MatchString(el.Text()) {
+		return fail(xpath, "invalid IssueTime %q, expected hh:mm:ss-05:00", el.Text())
+	}
+	return nil
+}
+
+// checkCurrency exige DocumentCurrencyCode y que todos los montos usen esa moneda
+func checkCurrency(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:DocumentCurrencyCode")
+	if el == nil || !currencyPattern.MatchString(el.Text()) {
+		return fail(xpath, "DocumentCurrencyCode must be an ISO 4217 code")
+	}
+
+	var findings []finding
+	var walk func(*xmldsig.Element)
+	walk = func(node *xmldsig.Element) {
+		if currency := node.Attr("currencyID"); currency != "" && currency != el.Text() {
+			findings = append(findings, finding{
+				xpath:   node.XPath(),
+				message: fmt.Sprintf("currencyID %s differs from DocumentCurrencyCode %s", currency, el.Text()),
+			})
+		}
+		for _, child := range node.ChildElements() {
+			walk(child)
+		}
+	}
+	walk(d.root)
+	return findings
+}
+
+func checkLineCount(d *document) []finding {
+	el, xpath := locate(d.root, "cbc:LineCountNumeric")
+	if el == nil {
+		return fail(xpath, "LineCountNumeric is required")
+	}
+	lines := len(invoiceLines(d.root))
+	if count, err := strconv.Atoi(el.Text()); err != nil || count != lines {
+		return fail(xpath, "LineCountNumeric %q does not match the %d invoice lines", el.Text(), lines)
+	}
+	return nil
+}
+
+// extensions retorna sts:DianExtensions o nil
+func (d *document) extensions() *xmldsig.Element {
+	return d.root.Find("", "DianExtensions")
+}
+
+// extension exige un elemento dentro de sts:DianExtensions (si las extensiones existen; FAB02)
+func extension(steps ...string) func(d *document) []finding {
+	return func(d *document) []finding {
+		ext := d.extensions()
+		if ext == nil {
+			return nil
+		}
+		el, xpath := locate(ext, steps...)
+		if el == nil || el.Text() == "" {
+			return fail(xpath, "%s is required", steps[len(steps)-1])
+		}
+		return nil
+	}
+}
+
+func checkAuthorization(d *document) []finding {
+	ext := d.extensions()
+	if ext == nil {
+		return fail(d.root.XPath()+"/ext:UBLExtensions/ext:UBLExtension/ext:ExtensionContent/sts:DianExtensions", "DIAN extensions are required")
+	}
+	el, xpath := locate(ext, "sts:InvoiceControl", "sts:InvoiceAuthorization")
+	if el == nil || el.Text() == "" {
+		return fail(xpath, "numbering resolution (InvoiceAuthorization) is required")
+	}
+	return nil
+}
+
+// checkAuthorizationPeriod valida IssueDate contra el inicio (StartDate) o fin (EndDate) de la resolución
+func checkAuthorizationPeriod(bound string) func(d *document) []finding {
+	return func(d *document) []finding {
+		ext := d.extensions()
+		if ext == nil {
+			return nil
+		}
+		el, xpath := locate(ext, "sts:InvoiceControl", "sts:AuthorizationPeriod", "cbc:"+bound)
+		if el == nil {
+			return fail(xpath, "resolution %s is required", bound)
+		}
+		limit, err := time.Parse("2006-01-02", el.Text())
+		if err != nil {
+			return fail(xpath, "invalid resolution %s %q", bound, el.Text())
+		}
+		issueDate, err := time.Parse("2006-01-02", elementText(d.root, "IssueDate"))
+		if err != nil {
+			return nil // FAD09
+		}
+		if bound == "StartDate" && issueDate.Before(limit) {
+			return fail(xpath, "IssueDate %s is before the resolution start date %s", issueDate.Format("2006-01-02"), el.Text())
+		}
+		if bound == "EndDate" && issueDate.After(limit) {
+			return fail(xpath, "IssueDate %s is after the resolution end date %s", issueDate.Format("2006-01-02"), el.Text())
+		}
+		return nil
+	}
+}
+
+func checkPrefix(d *document) []finding {
+	ext := d.extensions()
+	if ext == nil {
+		return nil
+	}
+	el, xpath := locate(ext, "sts:InvoiceControl", "sts:AuthorizedInvoices", "sts:Prefix")
+	if el == nil {
+		return nil // Resoluciones sin prefijo
+	}
+	if number := elementText(d.root, "ID"); !strings.HasPrefix(number, el.Text()) {
+		return fail(xpath, "invoice number %s does not start with the resolution prefix %s", number, el.Text())
+	}
+	return nil
+}
+
+func checkAuthorizedRange(d *document) []finding {
+	ext := d.extensions()
+	if ext == nil {
+		return nil
+	}
+	authorized, xpath := locate(ext, "sts:InvoiceControl", "sts:AuthorizedInvoices")
+	if authorized == nil {
+		return fail(xpath, "authorized range is required")
+	}
+	from, errFrom := strconv.ParseInt(elementText(authorized, "From"), 10, 64)
+	to, errTo := strconv.ParseInt(elementText(authorized, "To"), 10, 64)
+	if errFrom != nil || errTo != nil {
+		return fail(xpath, "authorized range From/To must be numeric")
+	}
+
+	number := strings.TrimPrefix(elementText(d.root, "ID"), elementText(authorized, "Prefix"))
+	consecutive, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return fail(d.root.XPath()+"/cbc:ID", "invoice consecutive %q is not numeric", number)
+	}
+	if consecutive < from || consecutive > to {
+		return fail(d.root.XPath()+"/cbc:ID", "consecutive %d is outside the authorized range %d-%d", consecutive, from, to)
+	}
+	return nil
+}
+
+func checkSoftwareSecurityCode(d *document) []finding {
+	ext := d.extensions()
+	if ext == nil {
+		return nil
+	}
+	el, xpath := locate(ext, "sts:SoftwareSecurityCode")
+	if el == nil || el.Text() == "" {
+		return fail(xpath, "SoftwareSecurityCode is required")
+	}
+	if d.ctx.SoftwarePIN == "" {
+		return []finding{{xpath: xpath, message: "SoftwareSecurityCode not checked: software PIN not available", severity: domain.SeverityWarning}}
+	}
+	expected := SoftwareSecurityCode(elementText(ext, "SoftwareProvider", "SoftwareID"), d.ctx.SoftwarePIN, elementText(d.root, "ID"))
+	if !strings.EqualFold(expected, el.Text()) {
+		return fail(xpath, "SoftwareSecurityCode does not match the software ID, PIN and number")
+	}
+	return nil
+}
+
+func checkQRCode(d *document) []finding {
+	ext := d.extensions()
+	if ext == nil {
+		return nil
+	}
+	el, xpath := locate(ext, "sts:QRCode")
+	if el == nil || el.Text() == "" {
+		return fail(xpath, "QRCode is required")
+	}
+	if uuid := elementText(d.root, "UUID"); uuid != "" && !strings.Contains(el.Text(), uuid) {
+		return fail(xpath, "QRCode does not contain the CUFE")
+	}
+	return nil
+}
+
+// checkDV valida el dígito de verificación (schemeID) de un NIT (schemeName 31)
+func checkDV(party string) func(d *document) []finding {
+	return func(d *document) []finding {
+		el, _ := locate(d.root, party, "cac:Party", "cac:PartyTaxScheme", "cbc:CompanyID")
+		if el == nil || el.Attr("schemeName") != "31" {
+			return nil // FAJ21/FAK21 o identificación distinta de NIT
+		}
+		if expected := validator.CalculateDV(el.Text()); el.Attr("schemeID") != expected {
+			return fail(el.XPath()+"/@schemeID", "invalid check digit %q for NIT %s, expected %s", el.Attr("schemeID"), el.Text(), expected)
+		}
+		return nil
+	}
+}
+
+// checkTaxTotals exige que cada TaxTotal sea la suma de sus TaxSubtotal (cabecera y líneas)
+func checkTaxTotals(d *document) []finding {
+	var findings []finding
+	for _, taxTotal := range d.root.FindAll("", "TaxTotal") {
+		var sum float64
+		for _, subtotal := range taxTotal.ChildElements() {
+			if subtotal.Local == "TaxSubtotal" {
+				sum += parseAmount(elementText(subtotal, "TaxAmount"))
+			}
+		}
+		total, xpath := locate(taxTotal, "cbc:TaxAmount")
+		if total == nil {
+			findings = append(findings, finding{xpath: xpath, message: "TaxAmount is required"})
+			continue
+		}
+		if amountsDiffer(parseAmount(total.Text()), sum) {
+			findings = append(findings, finding{
+				xpath:   xpath,
+				message: fmt.Sprintf("TaxAmount %s differs from the sum of its subtotals %s", total.Text(), formatAmount(sum)),
+			})
+		}
+	}
+	return findings
+}
+
+// checkTaxSubtotals exige TaxAmount = TaxableAmount * Percent / 100 en impuestos porcentuales
+func checkTaxSubtotals(d *document) []finding {
+	var findings []finding
+	for _, subtotal := range d.root.FindAll("", "TaxSubtotal") {
+		percent := subtotal.Path("TaxCategory", "Percent")
+		if percent == nil {
+			continue // Impuestos por unidad
+		}
+		expected := parseAmount(elementText(subtotal, "TaxableAmount")) * parseAmount(percent.Text()) / 100
+		amount, xpath := locate(subtotal, "cbc:TaxAmount")
+		if amount == nil {
+			findings = append(findings, finding{xpath: xpath, message: "TaxAmount is required"})
+			continue
+		}
+		if amountsDiffer(parseAmount(amount.Text()), expected) {
+			findings = append(findings, finding{
+				xpath:   xpath,
+				message: fmt.Sprintf("TaxAmount %s differs from TaxableAmount x Percent = %s", amount.Text(), formatAmount(expected)),
+			})
+		}
+	}
+	return findings
+}
+
+func checkLineExtensionTotal(d *document) []finding {
+	total, xpath := locate(d.root, "cac:LegalMonetaryTotal", "cbc:LineExtensionAmount")
+	if total == nil {
+		return fail(xpath, "LineExtensionAmount is required")
+	}
+	var sum float64
+	for _, line := range invoiceLines(d.root) {
+		sum += parseAmount(elementText(line, "LineExtensionAmount"))
+	}
+	if amountsDiffer(parseAmount(total.Text()), sum) {
+		return fail(xpath, "LineExtensionAmount %s differs from the sum of the lines %s", total.Text(), formatAmount(sum))
+	}
+	return nil
+}
+
+func checkTaxInclusiveAmount(d *document) []finding {
+	total, xpath := locate(d.root, "cac:LegalMonetaryTotal", "cbc:TaxInclusiveAmount")
+	if total == nil {
+		return fail(xpath, "TaxInclusiveAmount is required")
+	}
+	expected := parseAmount(elementText(d.root, "LegalMonetaryTotal", "LineExtensionAmount"))
+	for _, amount := range documentTaxes(d.root) {
+		expected += amount
+	}
+	if amountsDiffer(parseAmount(total.Text()), expected) {
+		return fail(xpath, "TaxInclusiveAmount %s differs from LineExtensionAmount plus taxes %s", total.Text(), formatAmount(expected))
+	}
+	return nil
+}
+
+func checkPayableAmount(d *document) []finding {
+	monetaryTotal, xpath := locate(d.root, "cac:LegalMonetaryTotal")
+	if monetaryTotal == nil {
+		return fail(xpath, "LegalMonetaryTotal is required")
+	}
+	payable, xpath := locate(monetaryTotal, "cbc:PayableAmount")
+	if payable == nil {
+		return fail(xpath, "PayableAmount is required")
+	}
+	amount := func(name string) float64 { return parseAmount(elementText(monetaryTotal, name)) }
+	expected := amount("TaxInclusiveAmount") - amount("AllowanceTotalAmount") + amount("ChargeTotalAmount") -
+		amount("PrepaidAmount") + amount("PayableRoundingAmount")
+	if amountsDiffer(parseAmount(payable.Text()), expected) {
+		return fail(xpath, "PayableAmount %s differs from the computed total %s", payable.Text(), formatAmount(expected))
+	}
+	return nil
+}
+
+func invoiceLines(root *xmldsig.Element) []*xmldsig.Element {
+	var lines []*xmldsig.Element
+	for _, el := range root.ChildElements() {
+		if el.Local == "InvoiceLine" {
+			lines = append(lines, el)
+		}
+	}
+	return lines
+}
+
+func checkLineIDs(d *document) []finding {
+	var findings []finding
+	for i, line := range invoiceLines(d.root) {
+		id, xpath := locate(line, "cbc:ID")
+		if id == nil || id.Text() != strconv.Itoa(i+1) {
+			findings = append(findings, finding{xpath: xpath, message: fmt.Sprintf("line IDs must be consecutive starting at 1, expected %d", i+1)})
+		}
+	}
+	return findings
+}
+
+func checkLineQuantities(d *document) []finding {
+	var findings []finding
+	for _, line := range invoiceLines(d.root) {
+		quantity, xpath := locate(line, "cbc:InvoicedQuantity")
+		if quantity == nil || parseAmount(quantity.Text()) <= 0 {
+			findings = append(findings, finding{xpath: xpath, message: "InvoicedQuantity must be greater than 0"})
+		}
+	}
+	return findings
+}
+
+// checkLineAmounts exige LineExtensionAmount = cantidad x precio / cantidad base - descuentos + cargos
+func checkLineAmounts(d *document) []finding {
+	var findings []finding
+	for _, line := range invoiceLines(d.root) {
+		amount, xpath := locate(line, "cbc:LineExtensionAmount")
+		if amount == nil {
+			findings = append(findings, finding{xpath: xpath, message: "LineExtensionAmount is required"})
+			continue
+		}
+
+		baseQuantity := parseAmount(elementText(line, "Price", "BaseQuantity"))
+		if baseQuantity == 0 {
+			baseQuantity = 1
+		}
+		expected := parseAmount(elementText(line, "InvoicedQuantity")) * parseAmount(elementText(line, "Price", "PriceAmount")) / baseQuantity
+		for _, adjustment := range line.ChildElements() {
+			if adjustment.Local != "AllowanceCharge" {
+				continue
+			}
+			if elementText(adjustment, "ChargeIndicator") == "true" {
+				expected += parseAmount(elementText(adjustment, "Amount"))
+			} else {
+				expected -= parseAmount(elementText(adjustment, "Amount"))
+			}
+		}
+
+		if amountsDiffer(parseAmount(amount.Text()), expected) {
+			findings = append(findings, finding{
+				xpath:   xpath,
+				message: fmt.Sprintf("LineExtensionAmount %s differs from quantity x price %s", amount.Text(), formatAmount(expected)),
+			})
+		}
+	}
+	return findings
+}
+
+func checkLineDescriptions(d *document) []finding {
+	var findings []finding
+	for _, line := range invoiceLines(d.root) {
+		description, xpath := locate(line, "cac:Item", "cbc:Description")
+		if description == nil || description.Text() == "" {
+			findings = append(findings, finding{xpath: xpath, message: "item description is required"})
+		}
+	}
+	return findings
+}
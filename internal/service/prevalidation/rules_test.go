@@ -0,0 +1,249 @@
+package prevalidation
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/pkg/validator"
+	"apidian-go/pkg/xmldsig"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	testTechnicalKey = "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c"
+	testSoftwarePIN  = "12345"
+	testSupplierNIT  = "900123456"
+	testCustomerNIT  = "800199436"
+)
+
+var testContext = Context{TechnicalKey: testTechnicalKey, SoftwarePIN: testSoftwarePIN}
+
+// mutation reemplaza la primera aparición de old después de marker (vacío = desde el inicio)
+type mutation struct {
+	marker, old, new string
+}
+
+// buildInvoice aplica las mutaciones a testdata/invoice.xml y completa los dígitos de
+// verificación, el CUFE y el SoftwareSecurityCode con el contenido resultante, para que cada
+// caso rompa solo la regla que prueba.
+func buildInvoice(t *testing.T, mutations ...mutation) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "invoice.xml"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	xml := string(data)
+	for _, m := range mutations {
+		start := strings.Index(xml, m.marker)
+		if start < 0 {
+			t.Fatalf("marker %q not found in fixture", m.marker)
+		}
+		i := strings.Index(xml[start:], m.old)
+		if i < 0 {
+			t.Fatalf("%q not found after %q in fixture", m.old, m.marker)
+		}
+		xml = xml[:start+i] + m.new + xml[start+i+len(m.old):]
+	}
+
+	xml = strings.NewReplacer(
+		"{{SUPPLIER_DV}}", validator.CalculateDV(testSupplierNIT),
+		"{{CUSTOMER_DV}}", validator.CalculateDV(testCustomerNIT),
+	).Replace(xml)
+
+	root, err := xmldsig.Parse([]byte(xml))
+	if err != nil {
+		t.Fatalf("parse fixture: %v", err)
+	}
+	softwareID := ""
+	if ext := root.Find("", "DianExtensions"); ext != nil {
+		softwareID = elementText(ext, "SoftwareProvider", "SoftwareID")
+	}
+	xml = strings.NewReplacer(
+		"{{CUFE}}", DocumentUUID(root, testTechnicalKey),
+		"{{SOFTWARE_SECURITY_CODE}}", SoftwareSecurityCode(softwareID, testSoftwarePIN, elementText(root, "ID")),
+	).Replace(xml)
+	return []byte(xml)
+}
+
+// ruleIssues agrupa los códigos de regla del reporte por severidad, sin el aviso de XSD omitido
+func ruleIssues(report *domain.PrevalidationReport) (errs, warnings []string) {
+	seen := map[string]bool{}
+	for _, issue := range report.Issues {
+		if issue.Rule == RuleXSD || seen[issue.Severity+issue.Rule] {
+			continue
+		}
+		seen[issue.Severity+issue.Rule] = true
+		if issue.Severity == domain.SeverityError {
+			errs = append(errs, issue.Rule)
+		} else {
+			warnings = append(warnings, issue.Rule)
+		}
+	}
+	sort.Strings(errs)
+	sort.Strings(warnings)
+	return errs, warnings
+}
+
+func TestValidateValidInvoice(t *testing.T) {
+	report := New("").Validate(buildInvoice(t), testContext)
+
+	errs, warnings := ruleIssues(report)
+	if len(errs) != 0 || len(warnings) != 0 || !report.Valid {
+		t.Fatalf("valid invoice: errors %v, warnings %v, issues %+v", errs, warnings, report.Issues)
+	}
+	if report.RulesChecked != len(invoiceRules) {
+		t.Errorf("RulesChecked = %d, want %d", report.RulesChecked, len(invoiceRules))
+	}
+	if report.DocumentType != "Invoice" || report.Number != "SETP990000001" || report.Signed {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// Cada regla con un documento que la incumple; el documento válido es el caso que la cumple
+func TestInvoiceRules(t *testing.T) {
+	const (
+		supplier = "<cac:AccountingSupplierParty>"
+		customer = "<cac:AccountingCustomerParty>"
+		line1    = "<cbc:ID>1</cbc:ID>"
+		line2    = "<cbc:ID>2</cbc:ID>"
+		totals   = "<cac:LegalMonetaryTotal>"
+	)
+	signingTime := func(value string) mutation {
+		return mutation{"", "</sts:DianExtensions>", `</sts:DianExtensions><xades:SigningTime xmlns:xades="` +
+			xmldsig.NamespaceXAdES + `">` + value + `</xades:SigningTime>`}
+	}
+
+	tests := []struct {
+		name      string
+		mutations []mutation
+		ctx       *Context
+		errors    []string
+		warnings  []string
+	}{
+		{name: "FAD01 UBL version", mutations: []mutation{{"", "UBL 2.1", "UBL 2.0"}}, errors: []string{"FAD01"}},
+		{name: "FAD02 missing operation type", mutations: []mutation{{"", "<cbc:CustomizationID>10</cbc:CustomizationID>", ""}}, errors: []string{"FAD02"}},
+		{name: "FAD02 unknown operation type", mutations: []mutation{{"", "<cbc:CustomizationID>10<", "<cbc:CustomizationID>99<"}}, warnings: []string{"FAD02"}},
+		{name: "FAD03 profile", mutations: []mutation{{"", "<cbc:ProfileID>DIAN 2.1", "<cbc:ProfileID>DIAN 2.0"}}, errors: []string{"FAD03"}},
+		{
+			name:      "FAD04 environment",
+			mutations: []mutation{{"", "<cbc:ProfileExecutionID>2<", "<cbc:ProfileExecutionID>3<"}, {"", `schemeID="2"`, `schemeID="3"`}},
+			errors:    []string{"FAD04"},
+		},
+		{
+			// Sin número tampoco se pueden validar prefijo ni rango
+			name:      "FAD05 missing number",
+			mutations: []mutation{{"", "<cbc:ID>SETP990000001</cbc:ID>", "<cbc:ID></cbc:ID>"}},
+			errors:    []string{"FAB07", "FAB08", "FAD05"},
+		},
+		{name: "FAD06 CUFE with another technical key", ctx: &Context{TechnicalKey: "other", SoftwarePIN: testSoftwarePIN}, errors: []string{"FAD06"}},
+		{name: "FAD06 without technical key", ctx: &Context{SoftwarePIN: testSoftwarePIN}, warnings: []string{"FAD06"}},
+		{name: "FAD07 UUID scheme", mutations: []mutation{{"", `schemeID="2"`, `schemeID="1"`}}, errors: []string{"FAD07"}},
+		{name: "FAD08 UUID algorithm", mutations: []mutation{{"", "CUFE-SHA384", "CUFE-SHA1"}}, errors: []string{"FAD08"}},
+		{name: "FAD09 issue date format", mutations: []mutation{{"", "<cbc:IssueDate>2024-03-15<", "<cbc:IssueDate>15/03/2024<"}}, errors: []string{"FAD09"}},
+		{name: "FAD09e signing date matches", mutations: []mutation{signingTime("2024-03-15T10:30:05-05:00")}},
+		{name: "FAD09e signing date differs", mutations: []mutation{signingTime("2024-03-16T08:00:00-05:00")}, errors: []string{"FAD09e"}},
+		{name: "FAD10 issue time zone", mutations: []mutation{{"", "10:30:00-05:00", "15:30:00Z"}}, errors: []string{"FAD10"}},
+		{name: "FAD11 invoice type", mutations: []mutation{{"", "<cbc:InvoiceTypeCode>01<", "<cbc:InvoiceTypeCode>05<"}}, errors: []string{"FAD11"}},
+		{name: "FAD13 currency code", mutations: []mutation{{"", "<cbc:DocumentCurrencyCode>COP<", "<cbc:DocumentCurrencyCode>pesos<"}}, errors: []string{"FAD13"}},
+		{name: "FAD13 amount in another currency", mutations: []mutation{{line1, `currencyID="COP">50000.00`, `currencyID="USD">50000.00`}}, errors: []string{"FAD13"}},
+		{name: "FAD15 line count", mutations: []mutation{{"", "<cbc:LineCountNumeric>2<", "<cbc:LineCountNumeric>3<"}}, errors: []string{"FAD15"}},
+		{
+			name:      "FAB02 without DIAN extensions",
+			mutations: []mutation{{"", "<sts:DianExtensions>", "<sts:Removed>"}, {"", "</sts:DianExtensions>", "</sts:Removed>"}},
+			errors:    []string{"FAB02"},
+		},
+		{name: "FAB02 missing resolution", mutations: []mutation{{"", "<sts:InvoiceAuthorization>18760000001<", "<sts:InvoiceAuthorization><"}}, errors: []string{"FAB02"}},
+		{name: "FAB05 issued before the resolution", mutations: []mutation{{"", "<cbc:StartDate>2019-01-19<", "<cbc:StartDate>2024-04-01<"}}, errors: []string{"FAB05"}},
+		{name: "FAB06 issued after the resolution", mutations: []mutation{{"", "<cbc:EndDate>2030-01-19<", "<cbc:EndDate>2024-03-14<"}}, errors: []string{"FAB06"}},
+		{
+			// Con otro prefijo el consecutivo tampoco es numérico
+			name:      "FAB07 prefix",
+			mutations: []mutation{{"", "<sts:Prefix>SETP<", "<sts:Prefix>FE<"}},
+			errors:    []string{"FAB07", "FAB08"},
+		},
+		{name: "FAB08 outside the authorized range", mutations: []mutation{{"", "<sts:From>990000000<", "<sts:From>990000002<"}}, errors: []string{"FAB08"}},
+		{name: "FAB10 software ID", mutations: []mutation{{"", "56f2ae4e-9812-4fad-9255-08fcfcd5ccb0", ""}}, errors: []string{"FAB10"}},
+		{name: "FAB19 another software PIN", ctx: &Context{TechnicalKey: testTechnicalKey, SoftwarePIN: "54321"}, errors: []string{"FAB19"}},
+		{name: "FAB19 without software PIN", ctx: &Context{TechnicalKey: testTechnicalKey}, warnings: []string{"FAB19"}},
+		{name: "FAB27 QR without CUFE", mutations: []mutation{{"", "documentkey={{CUFE}}", "documentkey="}}, warnings: []string{"FAB27"}},
+		{name: "FAJ02 supplier person type", mutations: []mutation{{supplier, "<cbc:AdditionalAccountID>1<", "<cbc:AdditionalAccountID>3<"}}, errors: []string{"FAJ02"}},
+		{
+			name:      "FAJ21 supplier NIT",
+			mutations: []mutation{{supplier, `<cbc:CompanyID schemeAgencyID="195" schemeID="{{SUPPLIER_DV}}" schemeName="31">900123456</cbc:CompanyID>`, ""}},
+			errors:    []string{"FAJ21"},
+		},
+		{name: "FAJ24 supplier check digit", mutations: []mutation{{supplier, ">900123456<", ">900123457<"}}, errors: []string{"FAJ24"}},
+		{name: "FAJ43 supplier name", mutations: []mutation{{supplier, "Emisor de Pruebas SAS", ""}}, errors: []string{"FAJ43"}},
+		{name: "FAK02 customer person type", mutations: []mutation{{customer, "<cbc:AdditionalAccountID>1<", "<cbc:AdditionalAccountID>3<"}}, errors: []string{"FAK02"}},
+		{
+			name:      "FAK21 customer NIT",
+			mutations: []mutation{{customer, `<cbc:CompanyID schemeAgencyID="195" schemeID="{{CUSTOMER_DV}}" schemeName="31">800199436</cbc:CompanyID>`, ""}},
+			errors:    []string{"FAK21"},
+		},
+		{name: "FAK24 customer check digit", mutations: []mutation{{customer, ">800199436<", ">800199437<"}}, errors: []string{"FAK24"}},
+		{name: "FAK24 non NIT identification is not checked", mutations: []mutation{{customer, `schemeName="31">800199436<`, `schemeName="13">800199437<`}}},
+		{name: "FAK43 customer name", mutations: []mutation{{customer, "Cliente de Pruebas SAS", ""}}, errors: []string{"FAK43"}},
+		{name: "FAS01 line tax total", mutations: []mutation{{line1, ">19000.00<", ">21000.00<"}}, errors: []string{"FAS01"}},
+		{name: "FAS05 tax subtotal percent", mutations: []mutation{{line1, "<cbc:Percent>19.00<", "<cbc:Percent>16.00<"}}, errors: []string{"FAS05"}},
+		{name: "FAS05 rounding within tolerance", mutations: []mutation{{line1, "<cbc:TaxAmount currencyID=\"COP\">19000.00</cbc:TaxAmount>\n        <cac:TaxCategory>", "<cbc:TaxAmount currencyID=\"COP\">19000.60</cbc:TaxAmount>\n        <cac:TaxCategory>"}}},
+		{
+			// Total de líneas inconsistente aunque los demás totales cuadren entre sí
+			name: "FAU02 line extension total",
+			mutations: []mutation{
+				{totals, "<cbc:LineExtensionAmount currencyID=\"COP\">200000.00<", "<cbc:LineExtensionAmount currencyID=\"COP\">210000.00<"},
+				{totals, ">238000.00<", ">248000.00<"},
+				{totals, "<cbc:PayableAmount currencyID=\"COP\">200000.00<", "<cbc:PayableAmount currencyID=\"COP\">210000.00<"},
+			},
+			errors: []string{"FAU02"},
+		},
+		{
+			name:      "FAU06 tax inclusive amount",
+			mutations: []mutation{{totals, ">238000.00<", ">240000.00<"}, {totals, "<cbc:PrepaidAmount currencyID=\"COP\">38000.00<", "<cbc:PrepaidAmount currencyID=\"COP\">40000.00<"}},
+			errors:    []string{"FAU06"},
+		},
+		{name: "FAU14 payable amount", mutations: []mutation{{totals, "<cbc:PayableAmount currencyID=\"COP\">200000.00<", "<cbc:PayableAmount currencyID=\"COP\">238000.00<"}}, errors: []string{"FAU14"}},
+		{name: "FAV02 line IDs", mutations: []mutation{{"", line2, "<cbc:ID>3</cbc:ID>"}}, errors: []string{"FAV02"}},
+		{
+			// Sin cantidad el valor de la línea tampoco cuadra
+			name:      "FAV04 zero quantity",
+			mutations: []mutation{{line2, `<cbc:InvoicedQuantity unitCode="94">1<`, `<cbc:InvoicedQuantity unitCode="94">0<`}},
+			errors:    []string{"FAV04", "FAV05"},
+		},
+		{name: "FAV05 line amount", mutations: []mutation{{line1, ">50000.00<", ">55000.00<"}}, errors: []string{"FAV05"}},
+		{name: "FAV05 charge instead of discount", mutations: []mutation{{line2, "<cbc:ChargeIndicator>false<", "<cbc:ChargeIndicator>true<"}}, errors: []string{"FAV05"}},
+		{name: "FAV08 item description", mutations: []mutation{{line1, "Producto A", ""}}, errors: []string{"FAV08"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext
+			if tt.ctx != nil {
+				ctx = *tt.ctx
+			}
+			report := New("").Validate(buildInvoice(t, tt.mutations...), ctx)
+
+			errs, warnings := ruleIssues(report)
+			if strings.Join(errs, ",") != strings.Join(tt.errors, ",") || strings.Join(warnings, ",") != strings.Join(tt.warnings, ",") {
+				t.Fatalf("errors %v, warnings %v; want errors %v, warnings %v\nissues: %+v", errs, warnings, tt.errors, tt.warnings, report.Issues)
+			}
+			if report.Valid != (len(tt.errors) == 0) {
+				t.Errorf("Valid = %v with %d errors", report.Valid, report.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateMalformedAndUnknownDocuments(t *testing.T) {
+	report := New("").Validate([]byte("<Invoice><cbc:ID>"), testContext)
+	if report.Valid || len(report.Issues) != 1 || report.Issues[0].Rule != RuleXML {
+		t.Errorf("malformed XML report = %+v", report)
+	}
+
+	report = New("").Validate([]byte(`<ApplicationResponse xmlns="urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2"/>`), testContext)
+	if !report.Valid || report.RulesChecked != 0 || report.Warnings != 2 {
+		t.Errorf("unknown document type report = %+v", report)
+	}
+}
@@ -0,0 +1,106 @@
+package prevalidation
+
+import (
+	"apidian-go/internal/domain"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Códigos de los hallazgos que no provienen de la tabla de validaciones DIAN
+const (
+	RuleXML = "XML" // Documento mal formado o sin reglas
+	RuleXSD = "XSD" // Validación contra el esquema UBL 2.1
+)
+
+const schemaTimeout = 60 * time.Second
+
+// Línea de error de xmllint, p. ej.
+// -:12: element PayableAmount: Schemas validity error : Element '{urn:...}PayableAmount': This element is not expected.
+var xmllintIssue = regexp.MustCompile(`^-:(\d+): (?:element \S+: )?(?:Schemas validity error|Schemas parser error|parser error) : (.*)$`)
+
+// schemaFile retorna el XSD principal del tipo de documento con la estructura de la
+// distribución UBL 2.1 (xsd/maindoc/UBL-Invoice-2.1.xsd), aceptando también maindoc/ en la raíz
+func (v *Validator) schemaFile(documentType string) (string, error) {
+	name := "UBL-" + documentType + "-2.1.xsd"
+	for _, candidate := range []string{
+		filepath.Join(v.schemaDir, "xsd", "maindoc", name),
+		filepath.Join(v.schemaDir, "maindoc", name),
+	} {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s", name, v.schemaDir)
+}
+
+// validateSchema valida el documento con xmllint (libxml2). Si los XSD o xmllint no están
+// disponibles la validación se omite con un warning y checked es false.
+func (v *Validator) validateSchema(data []byte, documentType string) (issues []domain.ValidationIssue, checked bool) {
+	skipped := func(reason string) ([]domain.ValidationIssue, bool) {
+		return []domain.ValidationIssue{{
+			Rule:     RuleXSD,
+			Severity: domain.SeverityWarning,
+			Message:  "schema validation skipped: " + reason,
+		}}, false
+	}
+
+	if v.schemaDir == "" {
+		return skipped("UBL_XSD_PATH not configured")
+	}
+	schema, err := v.schemaFile(documentType)
+	if err != nil {
+		return skipped(err.Error())
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		return skipped("xmllint not installed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaTimeout)
+	defer cancel()
+
+	// --nonet evita descargar imports remotos: los XSD deben estar completos en disco
+	cmd := exec.CommandContext(ctx, xmllint, "--noout", "--nonet", "--schema", schema, "-")
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		m := xmllintIssue.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(m[1])
+		issues = append(issues, domain.ValidationIssue{
+			Rule:     RuleXSD,
+			Severity: domain.SeverityError,
+			Line:     lineNumber,
+			Message:  m[2],
+		})
+	}
+
+	if runErr != nil && len(issues) == 0 {
+		// xmllint falló sin reportar errores del documento: el esquema no compiló o expiró
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) && strings.TrimSpace(stderr.String()) != "" {
+			return skipped(firstLine(stderr.String()))
+		}
+		return skipped(runErr.Error())
+	}
+	return issues, true
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,76 @@
+package prevalidation
+
+import (
+	"apidian-go/pkg/xmldsig"
+	"crypto/sha512"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// DocumentUUID recalcula el CUFE (facturas, con la clave técnica) o el CUDE (notas, con el
+// PIN del software) desde el contenido del XML según el anexo técnico de facturación electrónica:
+// SHA-384(Num + Fec + Hor + ValBase + "01" + IVA + "04" + INC + "03" + ICA + ValTot + NitOFE + NumAdq + Clave + TipoAmbiente)
+func DocumentUUID(root *xmldsig.Element, key string) string {
+	monetaryTotal := "LegalMonetaryTotal"
+	if root.Local == "DebitNote" {
+		monetaryTotal = "RequestedMonetaryTotal"
+	}
+
+	var b strings.Builder
+	b.WriteString(elementText(root, "ID"))
+	b.WriteString(elementText(root, "IssueDate"))
+	b.WriteString(elementText(root, "IssueTime"))
+	b.WriteString(formatAmount(parseAmount(elementText(root, monetaryTotal, "LineExtensionAmount"))))
+	taxes := documentTaxes(root)
+	for _, code := range []string{"01", "04", "03"} {
+		b.WriteString(code)
+		b.WriteString(formatAmount(taxes[code]))
+	}
+	b.WriteString(formatAmount(parseAmount(elementText(root, monetaryTotal, "PayableAmount"))))
+	b.WriteString(elementText(root, "AccountingSupplierParty", "Party", "PartyTaxScheme", "CompanyID"))
+	b.WriteString(elementText(root, "AccountingCustomerParty", "Party", "PartyTaxScheme", "CompanyID"))
+	b.WriteString(key)
+	b.WriteString(elementText(root, "ProfileExecutionID"))
+
+	return sha384Hex(b.String())
+}
+
+// SoftwareSecurityCode calcula el código de seguridad del software: SHA-384(SoftwareID + PIN + Número)
+func SoftwareSecurityCode(softwareID, pin, number string) string {
+	return sha384Hex(softwareID + pin + number)
+}
+
+// documentTaxes suma los impuestos de cabecera (TaxTotal del documento, no de las líneas) por código
+func documentTaxes(root *xmldsig.Element) map[string]float64 {
+	taxes := map[string]float64{}
+	for _, taxTotal := range root.ChildElements() {
+		if taxTotal.Local != "TaxTotal" {
+			continue
+		}
+		scheme := elementText(taxTotal, "TaxSubtotal", "TaxCategory", "TaxScheme", "ID")
+		taxes[scheme] += parseAmount(elementText(taxTotal, "TaxAmount"))
+	}
+	return taxes
+}
+
+func sha384Hex(s string) string {
+	sum := sha512.Sum384([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func elementText(el *xmldsig.Element, path ...string) string {
+	if found := el.Path(path...); found != nil {
+		return found.Text()
+	}
+	return ""
+}
+
+func parseAmount(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
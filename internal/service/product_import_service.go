@@ -0,0 +1,251 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/spreadsheet"
+	"apidian-go/pkg/validator"
+	"fmt"
+)
+
+// productColumns define el formato de importación/exportación de productos.
+// unit_code y tax_type se exportan con su código DIAN; al importar se acepta código o nombre.
+var productColumns = []string{
+	"code",
+	"name",
+	"description",
+	"unit_code",
+	"price",
+	"tax_type",
+	"tax_rate",
+	"standard_item_code",
+	"unspsc_code",
+	"brand_name",
+	"model_name",
+}
+
+type ProductImportService struct {
+	productRepo *repository.ProductRepository
+	companyRepo *repository.CompanyRepository
+	authz       *Authorizer
+	catalogRepo *repository.CatalogRepository
+}
+
+func NewProductImportService(
+	productRepo *repository.ProductRepository,
+	companyRepo *repository.CompanyRepository,
+	catalogRepo *repository.CatalogRepository,
+) *ProductImportService {
+	return &ProductImportService{
+		productRepo: productRepo,
+		companyRepo: companyRepo,
+		authz:       NewAuthorizer(companyRepo),
+		catalogRepo: catalogRepo,
+	}
+}
+
+// Import crea o actualiza productos (upsert por code) desde un CSV/XLSX.
+// Las filas inválidas se reportan y se omiten; con dryRun solo se valida.
+func (s *ProductImportService) Import(companyID int64, filename string, data []byte, dryRun bool, userID int64) (*domain.ImportReport, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionProductsWrite); err != nil {
+		return nil, err
+	}
+
+	table, err := spreadsheet.Read(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file: %w", err)
+	}
+	for _, column := range []string{"code", "name"} {
+		if !table.HasColumn(column) {
+			return nil, fmt.Errorf("invalid file: missing required column '%s'", column)
+		}
+	}
+
+	report := &domain.ImportReport{Filename: filename, DryRun: dryRun, Rows: []domain.ImportRowResult{}}
+	catalogs := newCatalogCache(s.catalogRepo)
+	seen := map[string]int{}
+
+	for _, row := range table.Rows {
+		result := domain.ImportRowResult{Row: row.Number, Key: row.Get("code")}
+
+		if result.Key == "" {
+			result.Errors = append(result.Errors, "code es requerido")
+			report.Add(result)
+			continue
+		}
+		if previous, ok := seen[result.Key]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("code duplicado (fila %d)", previous))
+			report.Add(result)
+			continue
+		}
+		seen[result.Key] = row.Number
+
+		existing, err := s.productRepo.GetByCode(companyID, result.Key)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			report.Add(result)
+			continue
+		}
+
+		req := domain.CreateProductRequest{CompanyID: companyID, Code: result.Key}
+		result.Action = domain.ImportActionCreate
+		if existing != nil {
+			result.Action = domain.ImportActionUpdate
+			req = productToRequest(existing)
+		}
+
+		result.Errors = append(result.Errors, applyProductRow(&req, row, catalogs)...)
+
+		if len(result.Errors) == 0 {
+			if err := validator.ValidateCreateProduct(&req); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+
+		if len(result.Errors) == 0 && !dryRun {
+			if existing == nil {
+				_, err = s.productRepo.Create(userID, &req)
+			} else {
+				err = s.productRepo.As(userID).Update(existing.ID, productUpdateRequest(&req))
+			}
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+
+		report.Add(result)
+	}
+
+	return report, nil
+}
+
+// applyProductRow copia al request las columnas no vacías de la fila
+func applyProductRow(req *domain.CreateProductRequest, row spreadsheet.Row, catalogs *catalogCache) []string {
+	var errs []string
+
+	if value := row.Get("name"); value != "" {
+		req.Name = value
+	}
+	if value := row.Get("description"); value != "" {
+		req.Description = &value
+	}
+
+	if value := row.Get("unit_code"); value != "" {
+		id, err := catalogs.resolve("unit_codes", value)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.UnitCodeID = id
+	}
+	if value := row.Get("tax_type"); value != "" {
+		id, err := catalogs.resolve("tax_types", value)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		req.TaxTypeID = id
+	}
+
+	if value := row.Get("price"); value != "" {
+		price, err := spreadsheet.ParseNumber(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("price '%s' no es un número válido", value))
+		}
+		req.Price = price
+	}
+	if value := row.Get("tax_rate"); value != "" {
+		rate, err := spreadsheet.ParseNumber(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("tax_rate '%s' no es un número válido", value))
+		}
+		req.TaxRate = rate
+	}
+
+	if value := row.Get("standard_item_code"); value != "" {
+		req.StandardItemCode = &value
+	}
+	if value := row.Get("unspsc_code"); value != "" {
+		req.UNSPSCCode = &value
+	}
+	if value := row.Get("brand_name"); value != "" {
+		req.BrandName = &value
+	}
+	if value := row.Get("model_name"); value != "" {
+		req.ModelName = &value
+	}
+
+	return errs
+}
+
+// Export genera el archivo de productos en el mismo formato que acepta Import
+func (s *ProductImportService) Export(companyID int64, format string, userID int64) ([]byte, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionProductsRead); err != nil {
+		return nil, err
+	}
+
+	products, err := s.productRepo.GetAllByCompanyID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	unitCodes, err := s.catalogRepo.GetCodes("unit_codes")
+	if err != nil {
+		return nil, err
+	}
+	taxTypes, err := s.catalogRepo.GetCodes("tax_types")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(products))
+	for _, p := range products {
+		rows = append(rows, []string{
+			p.Code,
+			p.Name,
+			stringValue(p.Description),
+			unitCodes[p.UnitCodeID],
+			formatDecimal(p.Price),
+			taxTypes[p.TaxTypeID],
+			formatDecimal(p.TaxRate),
+			stringValue(p.StandardItemCode),
+			stringValue(p.UNSPSCCode),
+			stringValue(p.BrandName),
+			stringValue(p.ModelName),
+		})
+	}
+
+	return spreadsheet.Write(format, "Productos", productColumns, rows)
+}
+
+// productToRequest parte de los datos actuales del producto para aplicar la fila encima
+func productToRequest(p *domain.Product) domain.CreateProductRequest {
+	return domain.CreateProductRequest{
+		CompanyID:                p.CompanyID,
+		Code:                     p.Code,
+		Name:                     p.Name,
+		Description:              p.Description,
+		TypeItemIdentificationID: p.TypeItemIdentificationID,
+		StandardItemCode:         p.StandardItemCode,
+		UNSPSCCode:               p.UNSPSCCode,
+		UnitCodeID:               p.UnitCodeID,
+		Price:                    p.Price,
+		TaxTypeID:                p.TaxTypeID,
+		TaxRate:                  p.TaxRate,
+		BrandName:                p.BrandName,
+		ModelName:                p.ModelName,
+	}
+}
+
+func productUpdateRequest(req *domain.CreateProductRequest) *domain.UpdateProductRequest {
+	return &domain.UpdateProductRequest{
+		Name:             &req.Name,
+		Description:      req.Description,
+		StandardItemCode: req.StandardItemCode,
+		UNSPSCCode:       req.UNSPSCCode,
+		UnitCodeID:       &req.UnitCodeID,
+		Price:            &req.Price,
+		TaxTypeID:        &req.TaxTypeID,
+		TaxRate:          &req.TaxRate,
+		BrandName:        req.BrandName,
+		ModelName:        req.ModelName,
+	}
+}
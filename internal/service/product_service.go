@@ -8,15 +8,24 @@ import (
 )
 
 type ProductService struct {
-	repo *repository.ProductRepository
+	repo  *repository.ProductRepository
+	authz *Authorizer
 }
 
-func NewProductService(repo *repository.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+func NewProductService(repo *repository.ProductRepository, companyRepo *repository.CompanyRepository) *ProductService {
+	return &ProductService{
+		repo:  repo,
+		authz: NewAuthorizer(companyRepo),
+	}
 }
 
 // Create crea un nuevo producto
 func (s *ProductService) Create(userID int64, req *domain.CreateProductRequest) (*domain.Product, error) {
+	// Verificar que el usuario puede crear productos en la empresa
+	if _, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionProductsWrite); err != nil {
+		return nil, err
+	}
+
 	// Validar que no exista producto con el mismo código en la empresa
 	existing, err := s.repo.GetByCode(req.CompanyID, req.Code)
 	if err != nil {
@@ -36,15 +45,15 @@ func (s *ProductService) Create(userID int64, req *domain.CreateProductRequest)
 }
 
 // GetByID obtiene un producto por ID
-func (s *ProductService) GetByID(id int64, companyID int64) (*domain.Product, error) {
+func (s *ProductService) GetByID(id int64, userID int64) (*domain.Product, error) {
 	product, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Si se proporciona companyID, verificar pertenencia
-	if companyID > 0 && product.CompanyID != companyID {
-		return nil, fmt.Errorf("unauthorized access to product")
+	// Verificar que el usuario tiene acceso a la empresa del producto
+	if _, err := s.authz.AuthorizeResource(product.CompanyID, userID, domain.PermissionProductsRead, "product"); err != nil {
+		return nil, err
 	}
 
 	return product, nil
@@ -69,20 +78,20 @@ func (s *ProductService) GetByCompanyID(companyID int64, page, pageSize int) (*d
 }
 
 // Update actualiza un producto
-func (s *ProductService) Update(id int64, companyID int64, req *domain.UpdateProductRequest) error {
+func (s *ProductService) Update(id int64, userID int64, req *domain.UpdateProductRequest) error {
 	// Verificar que el producto existe
 	product, err := s.repo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	// Si se proporciona companyID, verificar pertenencia
-	if companyID > 0 && product.CompanyID != companyID {
-		return fmt.Errorf("unauthorized access to product")
+	// Verificar que el usuario tiene acceso a la empresa del producto
+	if _, err := s.authz.AuthorizeResource(product.CompanyID, userID, domain.PermissionProductsWrite, "product"); err != nil {
+		return err
 	}
 
 	// Actualizar producto
-	if err := s.repo.Update(id, req); err != nil {
+	if err := s.repo.As(userID).Update(id, req); err != nil {
 		return err
 	}
 
@@ -90,20 +99,20 @@ func (s *ProductService) Update(id int64, companyID int64, req *domain.UpdatePro
 }
 
 // Delete elimina (soft delete) un producto
-func (s *ProductService) Delete(id int64, companyID int64) error {
+func (s *ProductService) Delete(id int64, userID int64) error {
 	// Verificar que el producto existe
 	product, err := s.repo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
-	// Si se proporciona companyID, verificar pertenencia
-	if companyID > 0 && product.CompanyID != companyID {
-		return fmt.Errorf("unauthorized access to product")
+	// Verificar que el usuario tiene acceso a la empresa del producto
+	if _, err := s.authz.AuthorizeResource(product.CompanyID, userID, domain.PermissionProductsWrite, "product"); err != nil {
+		return err
 	}
 
 	// Eliminar producto
-	if err := s.repo.Delete(id); err != nil {
+	if err := s.repo.As(userID).Delete(id); err != nil {
 		return err
 	}
 
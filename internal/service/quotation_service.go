@@ -0,0 +1,336 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/utils"
+	"fmt"
+	"time"
+)
+
+// InvoiceCreator crea facturas borrador (implementado por invoice.InvoiceService)
+type InvoiceCreator interface {
+	Create(req *domain.CreateInvoiceRequest, userID int64) (*domain.Invoice, error)
+}
+
+// Prefijos de numeración propia (independiente de las resoluciones DIAN)
+var quotationPrefixes = map[string]string{
+	domain.QuotationKindQuotation: "COT",
+	domain.QuotationKindOrder:     "PED",
+}
+
+type QuotationService struct {
+	quotationRepo  *repository.QuotationRepository
+	companyRepo    *repository.CompanyRepository
+	customerRepo   *repository.CustomerRepository
+	authz          *Authorizer
+	productRepo    *repository.ProductRepository
+	invoiceCreator InvoiceCreator
+}
+
+func NewQuotationService(
+	quotationRepo *repository.QuotationRepository,
+	companyRepo *repository.CompanyRepository,
+	customerRepo *repository.CustomerRepository,
+	productRepo *repository.ProductRepository,
+	invoiceCreator InvoiceCreator,
+) *QuotationService {
+	return &QuotationService{
+		quotationRepo:  quotationRepo,
+		companyRepo:    companyRepo,
+		customerRepo:   customerRepo,
+		authz:          NewAuthorizer(companyRepo),
+		productRepo:    productRepo,
+		invoiceCreator: invoiceCreator,
+	}
+}
+
+// Create crea una cotización o pedido con numeración propia
+func (s *QuotationService) Create(req *domain.CreateQuotationRequest, userID int64) (*domain.Quotation, error) {
+	// Validar que el usuario puede cotizar en la empresa
+	if _, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionQuotationsWrite); err != nil {
+		return nil, err
+	}
+
+	// Validar que el cliente pertenezca a la empresa
+	customer, err := s.customerRepo.GetByID(req.CustomerID)
+	if err != nil {
+		return nil, fmt.Errorf("customer not found")
+	}
+	if customer.CompanyID != req.CompanyID {
+		return nil, fmt.Errorf("customer does not belong to company")
+	}
+
+	kind := req.Kind
+	if kind == "" {
+		kind = domain.QuotationKindQuotation
+	}
+	prefix, ok := quotationPrefixes[kind]
+	if !ok {
+		return nil, fmt.Errorf("invalid kind, use 'quotation' or 'order'")
+	}
+
+	issueDate, err := time.ParseInLocation("2006-01-02", req.IssueDate, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue_date format, use YYYY-MM-DD")
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		parsed, err := time.ParseInLocation("2006-01-02", *req.ExpiresAt, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at format, use YYYY-MM-DD")
+		}
+		if parsed.Before(issueDate) {
+			return nil, fmt.Errorf("expires_at must be on or after issue_date")
+		}
+		expiresAt = &parsed
+	}
+
+	// Calcular totales de las líneas (mismas reglas que la factura)
+	var subtotal, taxTotal float64
+	var lines []domain.QuotationLine
+
+	for i, lineReq := range req.Lines {
+		product, err := s.productRepo.GetByID(lineReq.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("product not found in line %d", i+1)
+		}
+		if product.CompanyID != req.CompanyID {
+			return nil, fmt.Errorf("product in line %d does not belong to company", i+1)
+		}
+
+		description := ""
+		if lineReq.Description != nil && *lineReq.Description != "" {
+			description = *lineReq.Description
+		} else if product.Description != nil {
+			description = *product.Description
+		}
+
+		unitPrice := product.Price
+		if lineReq.UnitPrice != nil {
+			unitPrice = *lineReq.UnitPrice
+		}
+
+		taxRate := product.TaxRate
+		if lineReq.TaxRate != nil {
+			taxRate = *lineReq.TaxRate
+		}
+
+		lineTotal := lineReq.Quantity * unitPrice
+		taxAmount := lineTotal * (taxRate / 100)
+
+		subtotal += lineTotal
+		taxTotal += taxAmount
+
+		lines = append(lines, domain.QuotationLine{
+			ProductID:          lineReq.ProductID,
+			Description:        description,
+			Quantity:           lineReq.Quantity,
+			UnitPrice:          unitPrice,
+			LineTotal:          lineTotal,
+			TaxRate:            taxRate,
+			TaxAmount:          taxAmount,
+			BrandName:          lineReq.BrandName,
+			ModelName:          lineReq.ModelName,
+			StandardItemCode:   lineReq.StandardItemCode,
+			ClassificationCode: lineReq.ClassificationCode,
+		})
+	}
+
+	quotation := &domain.Quotation{
+		CompanyID:       req.CompanyID,
+		CustomerID:      req.CustomerID,
+		Kind:            kind,
+		Prefix:          prefix,
+		IssueDate:       issueDate,
+		ExpiresAt:       expiresAt,
+		CurrencyCodeID:  req.CurrencyCodeID,
+		Notes:           req.Notes,
+		PaymentMethodID: req.PaymentMethodID,
+		PaymentFormID:   req.PaymentFormID,
+		Subtotal:        subtotal,
+		TaxTotal:        taxTotal,
+		Total:           subtotal + taxTotal,
+		Status:          domain.QuotationStatusDraft,
+	}
+
+	// El repositorio asigna consecutivo y número dentro de la transacción
+	if err := s.quotationRepo.Create(quotation, lines); err != nil {
+		return nil, err
+	}
+
+	return quotation, nil
+}
+
+// GetByID obtiene una cotización validando permisos
+func (s *QuotationService) GetByID(id int64, userID int64) (*domain.Quotation, error) {
+	return s.getAuthorized(id, userID, domain.PermissionQuotationsRead)
+}
+
+// getAuthorized obtiene la cotización si el rol del usuario tiene el permiso en su empresa
+func (s *QuotationService) getAuthorized(id, userID int64, permission string) (*domain.Quotation, error) {
+	quotation, err := s.quotationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authz.AuthorizeResource(quotation.CompanyID, userID, permission, "quotation"); err != nil {
+		return nil, err
+	}
+
+	// Reflejar vencimiento sin esperar a un proceso externo
+	if isQuotationOpen(quotation.Status) && isQuotationExpired(quotation) {
+		if err := s.quotationRepo.ExpireOverdue(quotation.CompanyID); err != nil {
+			return nil, err
+		}
+		quotation.Status = domain.QuotationStatusExpired
+	}
+
+	return quotation, nil
+}
+
+// GetByCompanyID obtiene las cotizaciones de una empresa con paginación
+func (s *QuotationService) GetByCompanyID(companyID int64, userID int64, kind, status string, page, pageSize int) (*domain.QuotationListResponse, error) {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionQuotationsRead); err != nil {
+		return nil, err
+	}
+
+	if err := s.quotationRepo.ExpireOverdue(companyID); err != nil {
+		return nil, err
+	}
+
+	page, pageSize = utils.NormalizePagination(page, pageSize)
+
+	quotations, total, err := s.quotationRepo.GetByCompanyID(companyID, kind, status, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.QuotationListResponse{
+		Quotations: quotations,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+	}, nil
+}
+
+// UpdateStatus registra el envío, la aceptación o el rechazo de una cotización
+func (s *QuotationService) UpdateStatus(id int64, status string, userID int64) error {
+	quotation, err := s.getAuthorized(id, userID, domain.PermissionQuotationsWrite)
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case domain.QuotationStatusSent:
+		if quotation.Status != domain.QuotationStatusDraft {
+			return fmt.Errorf("only draft quotations can be marked as sent (current status: '%s')", quotation.Status)
+		}
+	case domain.QuotationStatusAccepted, domain.QuotationStatusRejected:
+		if !isQuotationOpen(quotation.Status) {
+			return fmt.Errorf("quotation cannot be %s (current status: '%s')", status, quotation.Status)
+		}
+	default:
+		return fmt.Errorf("invalid status, use 'sent', 'accepted' or 'rejected'")
+	}
+
+	return s.quotationRepo.UpdateStatus(id, status)
+}
+
+// Convert genera una factura borrador a partir de la cotización y guarda el enlace
+func (s *QuotationService) Convert(id int64, req *domain.ConvertQuotationRequest, userID int64) (*domain.Invoice, error) {
+	// 1. Obtener cotización validando permisos y vencimiento
+	quotation, err := s.getAuthorized(id, userID, domain.PermissionQuotationsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Validar estado
+	switch quotation.Status {
+	case domain.QuotationStatusConverted:
+		return nil, fmt.Errorf("quotation already converted")
+	case domain.QuotationStatusRejected, domain.QuotationStatusExpired:
+		return nil, fmt.Errorf("quotation cannot be converted (current status: '%s')", quotation.Status)
+	}
+	// Las cotizaciones requieren aceptación del cliente; los pedidos ya son confirmaciones
+	if quotation.Kind == domain.QuotationKindQuotation && quotation.Status != domain.QuotationStatusAccepted {
+		return nil, fmt.Errorf("only accepted quotations can be converted (current status: '%s')", quotation.Status)
+	}
+
+	// 3. Construir la solicitud de factura con las líneas de la cotización
+	issueDate := time.Now().Format("2006-01-02")
+	if req.IssueDate != nil && *req.IssueDate != "" {
+		issueDate = *req.IssueDate
+	}
+
+	paymentFormID := req.PaymentFormID
+	if paymentFormID == nil {
+		paymentFormID = quotation.PaymentFormID
+	}
+
+	originNotes := fmt.Sprintf("%s %s", quotation.Kind, quotation.Number)
+	invoiceReq := &domain.CreateInvoiceRequest{
+		Origin:          domain.NumberReasonQuotation,
+		OriginNotes:     &originNotes,
+		QuotationID:     &quotation.ID,
+		CompanyID:       quotation.CompanyID,
+		CustomerID:      quotation.CustomerID,
+		ResolutionID:    req.ResolutionID,
+		IssueDate:       issueDate,
+		DueDate:         req.DueDate,
+		CurrencyCodeID:  quotation.CurrencyCodeID,
+		Notes:           quotation.Notes,
+		PaymentMethodID: quotation.PaymentMethodID,
+		PaymentFormID:   paymentFormID,
+	}
+	for _, line := range quotation.Lines {
+		description := line.Description
+		unitPrice := line.UnitPrice
+		taxRate := line.TaxRate
+		invoiceReq.Lines = append(invoiceReq.Lines, domain.CreateInvoiceLineRequest{
+			ProductID:          line.ProductID,
+			Description:        &description,
+			Quantity:           line.Quantity,
+			UnitPrice:          &unitPrice,
+			TaxRate:            &taxRate,
+			BrandName:          line.BrandName,
+			ModelName:          line.ModelName,
+			StandardItemCode:   line.StandardItemCode,
+			ClassificationCode: line.ClassificationCode,
+		})
+	}
+
+	// 4. Crear factura borrador (consume consecutivo de la resolución DIAN) y enlazar
+	// cotización -> factura en la misma transacción
+	return s.invoiceCreator.Create(invoiceReq, userID)
+}
+
+// Delete elimina una cotización (solo si está en draft)
+func (s *QuotationService) Delete(id int64, userID int64) error {
+	quotation, err := s.getAuthorized(id, userID, domain.PermissionQuotationsWrite)
+	if err != nil {
+		return err
+	}
+
+	if quotation.Status != domain.QuotationStatusDraft {
+		return fmt.Errorf("only draft quotations can be deleted")
+	}
+
+	return s.quotationRepo.Delete(id)
+}
+
+// isQuotationOpen indica si la cotización aún espera respuesta del cliente
+func isQuotationOpen(status string) bool {
+	return status == domain.QuotationStatusDraft || status == domain.QuotationStatusSent
+}
+
+// isQuotationExpired indica si la fecha de expiración ya pasó (se evalúa por día)
+func isQuotationExpired(q *domain.Quotation) bool {
+	if q.ExpiresAt == nil {
+		return false
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return q.ExpiresAt.Before(today)
+}
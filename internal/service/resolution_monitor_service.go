@@ -0,0 +1,173 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/notify"
+	"apidian-go/internal/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ResolutionMonitorService revisa periódicamente las resoluciones de numeración: termina las
+// agotadas o vencidas, activa sus continuaciones y avisa cuando quedan pocos consecutivos o
+// se acerca el vencimiento. Cada alerta se envía una sola vez por resolución.
+type ResolutionMonitorService struct {
+	resolutionRepo   *repository.ResolutionRepository
+	companyRepo      *repository.CompanyRepository
+	notifier         notify.Notifier
+	defaultRemaining int
+	defaultDays      int
+}
+
+// NewResolutionMonitorService crea el servicio; los umbrales aplican a las resoluciones que no
+// definen alert_remaining o alert_days
+func NewResolutionMonitorService(resolutionRepo *repository.ResolutionRepository, companyRepo *repository.CompanyRepository, notifier notify.Notifier, defaultRemaining, defaultDays int) *ResolutionMonitorService {
+	return &ResolutionMonitorService{
+		resolutionRepo:   resolutionRepo,
+		companyRepo:      companyRepo,
+		notifier:         notifier,
+		defaultRemaining: defaultRemaining,
+		defaultDays:      defaultDays,
+	}
+}
+
+// Run ejecuta la revisión de inmediato y luego cada interval (bloqueante, usar con go)
+func (s *ResolutionMonitorService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := s.Check(time.Now()); err != nil {
+			log.Printf("Warning: resolution check failed: %v", err)
+		} else if sent > 0 {
+			log.Printf("Resolution check: %d alert(s) sent", sent)
+		}
+		<-ticker.C
+	}
+}
+
+// Check hace los cambios de resolución pendientes y envía las alertas; retorna cuántas se enviaron
+func (s *ResolutionMonitorService) Check(now time.Time) (int, error) {
+	if err := s.resolutionRepo.SwitchOverDue(now); err != nil {
+		return 0, fmt.Errorf("failed to switch over resolutions: %w", err)
+	}
+
+	sent := 0
+
+	// Cambios de estado, incluidos los que hizo la facturación al agotar una resolución
+	changed, err := s.resolutionRepo.ClaimStatusNotifications()
+	if err != nil {
+		return sent, fmt.Errorf("failed to get resolution status changes: %w", err)
+	}
+	for i := range changed {
+		sent += s.send(s.statusEvent(&changed[i], now))
+	}
+
+	low, err := s.resolutionRepo.ClaimLowAlerts(s.defaultRemaining)
+	if err != nil {
+		return sent, fmt.Errorf("failed to get resolutions running low: %w", err)
+	}
+	for i := range low {
+		res := &low[i]
+		alert := s.alert(res, now)
+		alert.Threshold = thresholdOrDefault(res.AlertRemaining, s.defaultRemaining)
+		sent += s.send(notify.Event{
+			Type:       notify.EventResolutionLow,
+			CompanyID:  res.CompanyID,
+			Message:    fmt.Sprintf("Resolution %s (prefix %s) of %s has %d number(s) left%s", res.Resolution, res.Prefix, alert.CompanyName, alert.Remaining, followUpHint(alert)),
+			Data:       alert,
+			OccurredAt: now,
+		})
+	}
+
+	expiring, err := s.resolutionRepo.ClaimExpiryAlerts(now, s.defaultDays)
+	if err != nil {
+		return sent, fmt.Errorf("failed to get expiring resolutions: %w", err)
+	}
+	for i := range expiring {
+		res := &expiring[i]
+		alert := s.alert(res, now)
+		alert.Threshold = thresholdOrDefault(res.AlertDays, s.defaultDays)
+		sent += s.send(notify.Event{
+			Type:       notify.EventResolutionExpiring,
+			CompanyID:  res.CompanyID,
+			Message:    fmt.Sprintf("Resolution %s (prefix %s) of %s expires in %d day(s), on %s%s", res.Resolution, res.Prefix, alert.CompanyName, alert.DaysToExpire, res.DateTo.Format("2006-01-02"), followUpHint(alert)),
+			Data:       alert,
+			OccurredAt: now,
+		})
+	}
+
+	return sent, nil
+}
+
+// statusEvent arma la alerta de una resolución que terminó o de una continuación que entró en uso
+func (s *ResolutionMonitorService) statusEvent(res *domain.Resolution, now time.Time) notify.Event {
+	alert := s.alert(res, now)
+	event := notify.Event{CompanyID: res.CompanyID, Data: alert, OccurredAt: now}
+
+	switch res.Status {
+	case domain.ResolutionStatusExhausted:
+		event.Type = notify.EventResolutionExhausted
+		event.Message = fmt.Sprintf("Resolution %s (prefix %s) of %s has no numbers left%s", res.Resolution, res.Prefix, alert.CompanyName, endedHint(alert))
+	case domain.ResolutionStatusExpired:
+		event.Type = notify.EventResolutionExpired
+		event.Message = fmt.Sprintf("Resolution %s (prefix %s) of %s expired on %s%s", res.Resolution, res.Prefix, alert.CompanyName, res.DateTo.Format("2006-01-02"), endedHint(alert))
+	default:
+		event.Type = notify.EventResolutionActivated
+		event.Message = fmt.Sprintf("Follow-up resolution %s (prefix %s) of %s is now in use", res.Resolution, res.Prefix, alert.CompanyName)
+	}
+	return event
+}
+
+func (s *ResolutionMonitorService) alert(res *domain.Resolution, now time.Time) domain.ResolutionAlert {
+	alert := domain.ResolutionAlert{
+		ResolutionID:        res.ID,
+		CompanyID:           res.CompanyID,
+		Prefix:              res.Prefix,
+		Resolution:          res.Resolution,
+		Status:              res.Status,
+		Remaining:           res.Remaining(),
+		DateTo:              res.DateTo,
+		DaysToExpire:        res.DaysToExpire(now),
+		FollowsResolutionID: res.FollowsResolutionID,
+	}
+	if company, err := s.companyRepo.GetByID(res.CompanyID); err == nil {
+		alert.CompanyName = company.Name
+		alert.CompanyNIT = company.NIT
+	}
+	if followUp, err := s.resolutionRepo.GetFollowUp(res.ID); err == nil {
+		alert.FollowUpID = &followUp.ID
+	}
+	return alert
+}
+
+// send entrega el evento y retorna 1 si se envió
+func (s *ResolutionMonitorService) send(event notify.Event) int {
+	if err := s.notifier.Notify(event); err != nil {
+		log.Printf("Warning: failed to send %s alert for company %d: %v", event.Type, event.CompanyID, err)
+		return 0
+	}
+	return 1
+}
+
+func thresholdOrDefault(value *int, defaultValue int) *int {
+	if value != nil {
+		return value
+	}
+	return &defaultValue
+}
+
+func followUpHint(alert domain.ResolutionAlert) string {
+	if alert.FollowUpID != nil {
+		return fmt.Sprintf("; follow-up resolution %d is registered", *alert.FollowUpID)
+	}
+	return "; register a follow-up resolution to keep invoicing"
+}
+
+func endedHint(alert domain.ResolutionAlert) string {
+	if alert.FollowUpID != nil {
+		return fmt.Sprintf("; invoicing continues with follow-up resolution %d once it is valid", *alert.FollowUpID)
+	}
+	return "; invoices cannot be numbered until a follow-up resolution is registered"
+}
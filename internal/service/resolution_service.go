@@ -10,51 +10,64 @@ import (
 type ResolutionService struct {
 	repo        *repository.ResolutionRepository
 	companyRepo *repository.CompanyRepository
+	authz       *Authorizer
 }
 
 func NewResolutionService(repo *repository.ResolutionRepository, companyRepo *repository.CompanyRepository) *ResolutionService {
 	return &ResolutionService{
 		repo:        repo,
 		companyRepo: companyRepo,
+		authz:       NewAuthorizer(companyRepo),
 	}
 }
 
 // Create crea una nueva resolución
 func (s *ResolutionService) Create(userID int64, req *domain.CreateResolutionRequest) (*domain.Resolution, error) {
-	// Verificar que la empresa existe y pertenece al usuario
-	company, err := s.companyRepo.GetByID(req.CompanyID)
-	if err != nil {
-		if err.Error() == "company not found" {
-			return nil, errors.New("company not found")
-		}
+	// Verificar que el usuario puede gestionar resoluciones de la empresa
+	if _, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionResolutionsWrite); err != nil {
 		return nil, err
 	}
 
-	// Verificar que la empresa pertenece al usuario
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
-	}
-
 	// Verificar que no exista ya una resolución con el mismo prefijo para esta empresa
 	existing, _ := s.repo.GetByCompanyAndPrefix(req.CompanyID, req.Prefix)
 	if existing != nil {
 		return nil, errors.New("resolution with this prefix already exists for this company")
 	}
 
-	return s.repo.Create(req)
+	// La continuación debe ser de la misma empresa y tipo de documento, y solo puede haber una
+	if req.FollowsResolutionID != nil {
+		previous, err := s.repo.GetByID(*req.FollowsResolutionID)
+		if err != nil {
+			return nil, errors.New("followed resolution not found")
+		}
+		if previous.CompanyID != req.CompanyID {
+			return nil, errors.New("followed resolution does not belong to company")
+		}
+		if previous.TypeDocumentID != req.TypeDocumentID {
+			return nil, errors.New("follow-up resolution must have the same type_document_id")
+		}
+		if followUp, _ := s.repo.GetFollowUp(previous.ID); followUp != nil {
+			return nil, errors.New("resolution already has a follow-up resolution")
+		}
+	}
+
+	return s.repo.As(userID).Create(req)
 }
 
 // GetByID obtiene una resolución por ID
 func (s *ResolutionService) GetByID(id int64, userID int64) (*domain.Resolution, error) {
+	return s.getAuthorized(id, userID, domain.PermissionResolutionsRead)
+}
+
+// getAuthorized obtiene la resolución si el rol del usuario tiene el permiso en su empresa
+func (s *ResolutionService) getAuthorized(id, userID int64, permission string) (*domain.Resolution, error) {
 	resolution, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verificar que la empresa pertenece al usuario
-	company, err := s.companyRepo.GetByID(resolution.CompanyID)
-	if err != nil || company.UserID != userID {
-		return nil, errors.New("unauthorized access to resolution")
+	if _, err := s.authz.AuthorizeResource(resolution.CompanyID, userID, permission, "resolution"); err != nil {
+		return nil, err
 	}
 
 	return resolution, nil
@@ -64,15 +77,10 @@ func (s *ResolutionService) GetByID(id int64, userID int64) (*domain.Resolution,
 func (s *ResolutionService) GetByCompanyID(companyID, userID int64, page, pageSize int) (*domain.ResolutionListResponse, error) {
 	// Normalizar paginación
 	page, pageSize = utils.NormalizePagination(page, pageSize)
-	company, err := s.companyRepo.GetByID(companyID)
-	if err != nil {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionResolutionsRead); err != nil {
 		return nil, err
 	}
 
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
-	}
-
 	return s.repo.GetByCompanyID(companyID, page, pageSize)
 }
 
@@ -85,11 +93,10 @@ func (s *ResolutionService) GetByUserID(userID int64, page, pageSize int) (*doma
 
 // Delete elimina (soft delete) una resolución
 func (s *ResolutionService) Delete(id int64, userID int64) error {
-	// Verificar que la resolución existe y pertenece al usuario
-	_, err := s.GetByID(id, userID)
-	if err != nil {
+	// Verificar que la resolución existe y el usuario puede gestionarla
+	if _, err := s.getAuthorized(id, userID, domain.PermissionResolutionsWrite); err != nil {
 		return err
 	}
 
-	return s.repo.Delete(id)
+	return s.repo.As(userID).Delete(id)
 }
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/pkg/crypto"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diegofxm/ubl21-dian/signature"
+	"github.com/diegofxm/ubl21-dian/soap"
+	"github.com/diegofxm/ubl21-dian/soap/types"
+)
+
+// invoiceTypeDocumentID es el tipo de documento (invoice_type_codes) de las facturas de venta,
+// el único que DIAN autoriza por rangos de numeración
+const invoiceTypeDocumentID = 1
+
+// dianOperationSuccess es el OperationCode de GetNumberingRange cuando la consulta fue exitosa
+const dianOperationSuccess = "100"
+
+// ResolutionSyncService sincroniza las resoluciones de numeración de una empresa con los rangos
+// autorizados en DIAN (GetNumberingRange): crea las que faltan, corrige las que difieren y marca
+// las locales que DIAN no reconoce.
+type ResolutionSyncService struct {
+	resolutionRepo *repository.ResolutionRepository
+	companyRepo    *repository.CompanyRepository
+	authz          *Authorizer
+	softwareRepo   *repository.SoftwareRepository
+	certRepo       *repository.CertificateRepository
+	keyring        *keyring.Keyring
+}
+
+func NewResolutionSyncService(
+	resolutionRepo *repository.ResolutionRepository,
+	companyRepo *repository.CompanyRepository,
+	softwareRepo *repository.SoftwareRepository,
+	certRepo *repository.CertificateRepository,
+	keys *keyring.Keyring,
+) *ResolutionSyncService {
+	return &ResolutionSyncService{
+		resolutionRepo: resolutionRepo,
+		companyRepo:    companyRepo,
+		authz:          NewAuthorizer(companyRepo),
+		softwareRepo:   softwareRepo,
+		certRepo:       certRepo,
+		keyring:        keys,
+	}
+}
+
+// Sync consulta DIAN y concilia las resoluciones de la empresa. Con dryRun solo reporta.
+func (s *ResolutionSyncService) Sync(companyID, userID int64, dryRun bool) (*domain.ResolutionSyncResult, error) {
+	company, err := s.authz.Authorize(companyID, userID, domain.PermissionResolutionsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	software, err := s.softwareRepo.GetByCompanyID(companyID)
+	if err != nil {
+		return nil, errors.New("software not configured for company")
+	}
+
+	ranges, err := s.fetchNumberingRanges(company, software)
+	if err != nil {
+		return nil, err
+	}
+
+	locals, err := s.resolutionRepo.ListActiveByCompanyID(companyID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading resolutions: %w", err)
+	}
+	localByPrefix := make(map[string]*domain.Resolution, len(locals))
+	for i := range locals {
+		localByPrefix[locals[i].Prefix] = &locals[i]
+	}
+
+	repo := s.resolutionRepo.As(userID)
+	result := &domain.ResolutionSyncResult{
+		CompanyID: companyID,
+		DryRun:    dryRun,
+		SyncedAt:  time.Now(),
+		Items:     []domain.ResolutionSyncItem{},
+	}
+
+	prefixes := make([]string, 0, len(ranges))
+	for prefix := range ranges {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		authorized := ranges[prefix]
+		item := s.syncPrefix(repo, localByPrefix[prefix], authorized, dryRun)
+		result.Items = append(result.Items, item)
+		delete(localByPrefix, prefix)
+	}
+
+	// Resoluciones de factura locales que DIAN no reporta como autorizadas
+	for _, local := range locals {
+		if _, pending := localByPrefix[local.Prefix]; !pending || local.TypeDocumentID != invoiceTypeDocumentID {
+			continue
+		}
+		item := domain.ResolutionSyncItem{
+			ResolutionID: &local.ID,
+			Prefix:       local.Prefix,
+			Resolution:   local.Resolution,
+			Status:       domain.ResolutionSyncNotFound,
+			Message:      "prefix not authorized in DIAN for this software",
+		}
+		if !dryRun {
+			if err := repo.MarkSynced(local.ID, item.Status, item.Message); err != nil {
+				item.Message = "error saving sync status: " + err.Error()
+			}
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	for _, item := range result.Items {
+		switch item.Status {
+		case domain.ResolutionSyncMatched:
+			result.Matched++
+		case domain.ResolutionSyncCreated:
+			result.Created++
+		case domain.ResolutionSyncUpdated:
+			result.Updated++
+		case domain.ResolutionSyncMismatch:
+			result.Mismatch++
+		case domain.ResolutionSyncNotFound:
+			result.NotFound++
+		}
+	}
+
+	return result, nil
+}
+
+// syncPrefix concilia la resolución local de un prefijo (nil si no existe) con el rango de DIAN;
+// repo es el repositorio a nombre del usuario que sincroniza
+func (s *ResolutionSyncService) syncPrefix(repo *repository.ResolutionRepository, local, authorized *domain.Resolution, dryRun bool) domain.ResolutionSyncItem {
+	item := domain.ResolutionSyncItem{Prefix: authorized.Prefix, Resolution: authorized.Resolution}
+
+	if local == nil {
+		item.Status = domain.ResolutionSyncCreated
+		if dryRun {
+			return item
+		}
+		created, err := repo.CreateFromDIAN(authorized, "")
+		if err != nil {
+			item.Status = domain.ResolutionSyncMismatch
+			item.Message = "error creating resolution: " + err.Error()
+			return item
+		}
+		item.ResolutionID = &created.ID
+		return item
+	}
+
+	item.ResolutionID = &local.ID
+	differences, keyMatches := compareResolution(local, authorized)
+	item.Differences = differences
+	item.TechnicalKeyMatches = &keyMatches
+	if len(differences) == 0 && keyMatches {
+		item.Status = domain.ResolutionSyncMatched
+		if !dryRun {
+			if err := repo.MarkSynced(local.ID, item.Status, ""); err != nil {
+				item.Message = "error saving sync status: " + err.Error()
+			}
+		}
+		return item
+	}
+
+	notes := differencesNote(differences, keyMatches)
+	if local.CurrentNumber < authorized.FromNumber || local.CurrentNumber > authorized.ToNumber {
+		item.Status = domain.ResolutionSyncMismatch
+		item.Message = fmt.Sprintf("current consecutive %d is outside the DIAN range %d-%d; fix the resolution manually",
+			local.CurrentNumber, authorized.FromNumber, authorized.ToNumber)
+		if !dryRun {
+			if err := repo.MarkSynced(local.ID, item.Status, notes); err != nil {
+				item.Message = "error saving sync status: " + err.Error()
+			}
+		}
+		return item
+	}
+
+	item.Status = domain.ResolutionSyncUpdated
+	if dryRun {
+		return item
+	}
+	if err := repo.UpdateFromDIAN(local.ID, authorized, notes); err != nil {
+		item.Status = domain.ResolutionSyncMismatch
+		item.Message = "error updating resolution: " + err.Error()
+		if markErr := repo.MarkSynced(local.ID, item.Status, notes); markErr != nil {
+			item.Message += "; error saving sync status: " + markErr.Error()
+		}
+	}
+	return item
+}
+
+// fetchNumberingRanges consulta GetNumberingRange y retorna un rango por prefijo. Si DIAN
+// reporta varias resoluciones con el mismo prefijo se conserva la de vigencia más reciente.
+func (s *ResolutionSyncService) fetchNumberingRanges(company *domain.Company, software *domain.Software) (map[string]*domain.Resolution, error) {
+	client, cleanup, err := s.soapClient(company, software)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	resp, err := client.GetNumberingRange(&types.NumberingRangeRequest{
+		AccountCode:  company.NIT,
+		AccountCodeT: company.NIT,
+		SoftwareCode: software.Identifier,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error calling GetNumberingRange: %w", err)
+	}
+	if resp.OperationCode != dianOperationSuccess {
+		return nil, fmt.Errorf("DIAN_ERROR: OperationCode=%s, Message=%s", resp.OperationCode, resp.OperationDescription)
+	}
+
+	ranges := map[string]*domain.Resolution{}
+	for _, r := range resp.ResponseList {
+		dateFrom, errFrom := parseDIANDate(r.ValidDateFrom)
+		dateTo, errTo := parseDIANDate(r.ValidDateTo)
+		if errFrom != nil || errTo != nil {
+			return nil, fmt.Errorf("DIAN_ERROR: invalid validity dates for resolution %s", r.ResolutionNumber)
+		}
+
+		resolution := &domain.Resolution{
+			CompanyID:      company.ID,
+			TypeDocumentID: invoiceTypeDocumentID,
+			Prefix:         strings.TrimSpace(r.Prefix),
+			Resolution:     strings.TrimSpace(r.ResolutionNumber),
+			FromNumber:     r.FromNumber,
+			ToNumber:       r.ToNumber,
+			DateFrom:       dateFrom,
+			DateTo:         dateTo,
+		}
+		if key := strings.TrimSpace(r.TechnicalKey); key != "" {
+			resolution.TechnicalKey = &key
+		}
+
+		if current, ok := ranges[resolution.Prefix]; !ok || resolution.DateTo.After(current.DateTo) {
+			ranges[resolution.Prefix] = resolution
+		}
+	}
+	return ranges, nil
+}
+
+// soapClient crea el cliente SOAP con el certificado vigente de la empresa. cleanup elimina
+// el certificado descifrado en disco.
+func (s *ResolutionSyncService) soapClient(company *domain.Company, software *domain.Software) (*soap.Client, func(), error) {
+	cert, err := s.certRepo.GetByCompanyID(company.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no certificate found for company: %w", err)
+	}
+	if err := cert.CheckValidity(time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	decryptedPassword, err := crypto.DecryptPassword(cert.Password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt certificate password: %w", err)
+	}
+
+	store, err := s.keyring.CompanyStorage(company.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading company data key: %w", err)
+	}
+	certPath, cleanup, err := storage.Materialize(store, storage.CertificateKey(company.NIT, cert.Name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading certificate: %w", err)
+	}
+
+	clientPemPath, err := signature.ConvertP12ToClientPEM(certPath, decryptedPassword)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to convert certificate to client PEM: %w", err)
+	}
+
+	environment := types.Habilitacion
+	if software.Environment == "1" {
+		environment = types.Produccion
+	}
+	client, err := soap.NewClient(&types.Config{
+		Environment: environment,
+		Certificate: clientPemPath,
+		PrivateKey:  clientPemPath,
+	})
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error creating SOAP client: %w", err)
+	}
+	return client, cleanup, nil
+}
+
+// compareResolution lista los campos en que la resolución local difiere de la autorizada. La
+// clave técnica es un secreto: solo se indica si coincide, sin retornar ninguno de los dos valores.
+func compareResolution(local, authorized *domain.Resolution) ([]domain.ResolutionDifference, bool) {
+	var diffs []domain.ResolutionDifference
+	add := func(field, localValue, dianValue string) {
+		if localValue != dianValue {
+			diffs = append(diffs, domain.ResolutionDifference{Field: field, Local: localValue, DIAN: dianValue})
+		}
+	}
+
+	add("resolution", local.Resolution, authorized.Resolution)
+	add("from_number", strconv.FormatInt(local.FromNumber, 10), strconv.FormatInt(authorized.FromNumber, 10))
+	add("to_number", strconv.FormatInt(local.ToNumber, 10), strconv.FormatInt(authorized.ToNumber, 10))
+	add("date_from", local.DateFrom.Format("2006-01-02"), authorized.DateFrom.Format("2006-01-02"))
+	add("date_to", local.DateTo.Format("2006-01-02"), authorized.DateTo.Format("2006-01-02"))
+	return diffs, stringValue(local.TechnicalKey) == stringValue(authorized.TechnicalKey)
+}
+
+// differencesNote resume las diferencias para sync_notes (la clave técnica no se guarda en claro)
+func differencesNote(diffs []domain.ResolutionDifference, technicalKeyMatches bool) string {
+	parts := make([]string, 0, len(diffs)+1)
+	for _, d := range diffs {
+		parts = append(parts, fmt.Sprintf("%s: %s -> %s", d.Field, d.Local, d.DIAN))
+	}
+	if !technicalKeyMatches {
+		parts = append(parts, "technical_key differs")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseDIANDate acepta fechas yyyy-MM-dd con o sin hora
+func parseDIANDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > 10 {
+		value = value[:10]
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareResolution(t *testing.T) {
+	localKey := "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c"
+	dianKey := "693ff6f2a553c3646a063436fd4dd9ded0311471"
+	base := func() *domain.Resolution {
+		key := localKey
+		return &domain.Resolution{
+			Resolution:   "18760000001",
+			TechnicalKey: &key,
+			FromNumber:   990000000,
+			ToNumber:     995000000,
+			DateFrom:     time.Date(2019, time.January, 19, 0, 0, 0, 0, time.UTC),
+			DateTo:       time.Date(2030, time.January, 19, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		mutate     func(r *domain.Resolution)
+		fields     []string
+		keyMatches bool
+		note       string
+	}{
+		{name: "same resolution", mutate: func(r *domain.Resolution) {}, keyMatches: true},
+		{
+			name:       "range and validity",
+			mutate:     func(r *domain.Resolution) { r.ToNumber = 996000000; r.DateTo = r.DateTo.AddDate(1, 0, 0) },
+			fields:     []string{"to_number", "date_to"},
+			keyMatches: true,
+			note:       "to_number: 995000000 -> 996000000; date_to: 2030-01-19 -> 2031-01-19",
+		},
+		{
+			name:   "technical key",
+			mutate: func(r *domain.Resolution) { r.TechnicalKey = &dianKey },
+			note:   "technical_key differs",
+		},
+		{
+			name:   "resolution number and technical key",
+			mutate: func(r *domain.Resolution) { r.Resolution = "18760000002"; r.TechnicalKey = &dianKey },
+			fields: []string{"resolution"},
+			note:   "resolution: 18760000001 -> 18760000002; technical_key differs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, authorized := base(), base()
+			tt.mutate(authorized)
+
+			diffs, keyMatches := compareResolution(local, authorized)
+			var fields []string
+			for _, d := range diffs {
+				fields = append(fields, d.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") || keyMatches != tt.keyMatches {
+				t.Fatalf("differences %v, key matches %v; want %v, %v", fields, keyMatches, tt.fields, tt.keyMatches)
+			}
+			if note := differencesNote(diffs, keyMatches); note != tt.note {
+				t.Errorf("note = %q, want %q", note, tt.note)
+			}
+
+			// Ni el reporte ni las notas llevan la clave técnica
+			item := domain.ResolutionSyncItem{Differences: diffs, TechnicalKeyMatches: &keyMatches}
+			raw, _ := json.Marshal(item)
+			for _, key := range []string{localKey, dianKey} {
+				if strings.Contains(string(raw), key) || strings.Contains(tt.note, key) {
+					t.Errorf("technical key leaked: %s", raw)
+				}
+			}
+		})
+	}
+
+	// Sin clave técnica en ninguno de los dos lados no hay diferencia
+	local, authorized := base(), base()
+	local.TechnicalKey, authorized.TechnicalKey = nil, nil
+	if _, keyMatches := compareResolution(local, authorized); !keyMatches {
+		t.Error("resolutions without technical key must match")
+	}
+}
+
+func TestParseDIANDate(t *testing.T) {
+	for _, value := range []string{"2030-01-19", " 2030-01-19 ", "2030-01-19T00:00:00", "2030-01-19 00:00:00.000"} {
+		got, err := parseDIANDate(value)
+		if err != nil || got.Format("2006-01-02") != "2030-01-19" {
+			t.Errorf("parseDIANDate(%q) = %v, %v", value, got, err)
+		}
+	}
+	if _, err := parseDIANDate("19/01/2030"); err == nil {
+		t.Error("parseDIANDate must reject dd/mm/yyyy")
+	}
+}
@@ -9,51 +9,47 @@ import (
 type SoftwareService struct {
 	repo        *repository.SoftwareRepository
 	companyRepo *repository.CompanyRepository
+	authz       *Authorizer
 }
 
 func NewSoftwareService(repo *repository.SoftwareRepository, companyRepo *repository.CompanyRepository) *SoftwareService {
 	return &SoftwareService{
 		repo:        repo,
 		companyRepo: companyRepo,
+		authz:       NewAuthorizer(companyRepo),
 	}
 }
 
 // Create crea una nueva configuración de software
 func (s *SoftwareService) Create(userID int64, req *domain.CreateSoftwareRequest) (*domain.Software, error) {
-	// Verificar que la empresa existe y pertenece al usuario
-	company, err := s.companyRepo.GetByID(req.CompanyID)
-	if err != nil {
-		if err.Error() == "company not found" {
-			return nil, errors.New("company not found")
-		}
+	// Verificar que el usuario puede gestionar el software de la empresa
+	if _, err := s.authz.Authorize(req.CompanyID, userID, domain.PermissionSoftwareManage); err != nil {
 		return nil, err
 	}
 
-	// Verificar que la empresa pertenece al usuario
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
-	}
-
 	// Verificar que no exista ya un software para esta empresa
 	existing, _ := s.repo.GetByCompanyID(req.CompanyID)
 	if existing != nil {
 		return nil, errors.New("software already exists for this company")
 	}
 
-	return s.repo.Create(req)
+	return s.repo.As(userID).Create(req)
 }
 
 // GetByID obtiene un software por ID
 func (s *SoftwareService) GetByID(id int64, userID int64) (*domain.Software, error) {
+	return s.getAuthorized(id, userID, domain.PermissionSoftwareRead)
+}
+
+// getAuthorized obtiene el software si el rol del usuario tiene el permiso en su empresa
+func (s *SoftwareService) getAuthorized(id, userID int64, permission string) (*domain.Software, error) {
 	software, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verificar que la empresa pertenece al usuario
-	company, err := s.companyRepo.GetByID(software.CompanyID)
-	if err != nil || company.UserID != userID {
-		return nil, errors.New("unauthorized access to software")
+	if _, err := s.authz.AuthorizeResource(software.CompanyID, userID, permission, "software"); err != nil {
+		return nil, err
 	}
 
 	return software, nil
@@ -61,43 +57,29 @@ func (s *SoftwareService) GetByID(id int64, userID int64) (*domain.Software, err
 
 // GetByCompanyID obtiene el software de una empresa
 func (s *SoftwareService) GetByCompanyID(companyID int64, userID int64) (*domain.Software, error) {
-	// Verificar que la empresa pertenece al usuario
-	company, err := s.companyRepo.GetByID(companyID)
-	if err != nil {
+	if _, err := s.authz.Authorize(companyID, userID, domain.PermissionSoftwareRead); err != nil {
 		return nil, err
 	}
 
-	if company.UserID != userID {
-		return nil, errors.New("unauthorized access to company")
-	}
-
 	return s.repo.GetByCompanyID(companyID)
 }
 
 // Update actualiza un software
 func (s *SoftwareService) Update(id int64, userID int64, req *domain.UpdateSoftwareRequest) error {
-	// Verificar que el software existe y pertenece al usuario
-	software, err := s.GetByID(id, userID)
-	if err != nil {
+	// Verificar que el software existe y el usuario puede gestionarlo
+	if _, err := s.getAuthorized(id, userID, domain.PermissionSoftwareManage); err != nil {
 		return err
 	}
 
-	// Verificar que la empresa pertenece al usuario
-	company, err := s.companyRepo.GetByID(software.CompanyID)
-	if err != nil || company.UserID != userID {
-		return errors.New("unauthorized access to software")
-	}
-
-	return s.repo.Update(id, req)
+	return s.repo.As(userID).Update(id, req)
 }
 
 // Delete elimina (soft delete) un software
 func (s *SoftwareService) Delete(id int64, userID int64) error {
-	// Verificar que el software existe y pertenece al usuario
-	_, err := s.GetByID(id, userID)
-	if err != nil {
+	// Verificar que el software existe y el usuario puede gestionarlo
+	if _, err := s.getAuthorized(id, userID, domain.PermissionSoftwareManage); err != nil {
 		return err
 	}
 
-	return s.repo.Delete(id)
+	return s.repo.As(userID).Delete(id)
 }
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/mail"
+	"apidian-go/internal/repository"
+	"apidian-go/pkg/crypto"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"log"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TwoFactorService administra la verificación en dos pasos (TOTP) del usuario autenticado. El
+// secreto se guarda cifrado con la llave maestra y los códigos de recuperación como SHA-256.
+type TwoFactorService struct {
+	repo        *repository.TwoFactorRepository
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	mailer      mail.Mailer
+}
+
+func NewTwoFactorService(
+	repo *repository.TwoFactorRepository,
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	mailer mail.Mailer,
+) *TwoFactorService {
+	return &TwoFactorService{
+		repo:        repo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		mailer:      mailer,
+	}
+}
+
+// Status retorna si 2FA está activo, los códigos de recuperación restantes y las empresas que
+// lo exigen al usuario
+func (s *TwoFactorService) Status(userID int64) (*domain.TwoFactorStatus, error) {
+	status := &domain.TwoFactorStatus{}
+	secret, err := s.repo.GetSecret(userID)
+	if err != nil && err.Error() != "two-factor authentication not set up" {
+		return nil, err
+	}
+	if secret != nil && secret.EnabledAt != nil {
+		status.Enabled = true
+		status.EnabledAt = secret.EnabledAt
+		if status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if status.RequiredBy, err = s.requiredBy(userID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Setup genera un secreto TOTP pendiente y su código QR. 2FA no queda activo hasta confirmar
+// un código con Enable; repetir Setup reemplaza el secreto pendiente.
+func (s *TwoFactorService) Setup(userID int64, req *domain.TwoFactorSetupRequest) (*domain.TwoFactorSetupResponse, error) {
+	user, err := s.checkPassword(userID, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication already enabled")
+	}
+
+	secret, err := crypto.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := crypto.EncryptPassword(secret)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting secret: %w", err)
+	}
+	if err := s.repo.SetPendingSecret(userID, encrypted); err != nil {
+		return nil, err
+	}
+
+	otpURL := crypto.TOTPURL(domain.TwoFactorIssuer, user.Email, secret)
+	qrCode, err := qrDataURI(otpURL)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: otpURL,
+		QRCode:     qrCode,
+	}, nil
+}
+
+// Enable confirma el secreto pendiente con un código de la app, activa 2FA, entrega los códigos
+// de recuperación y cierra las demás sesiones del usuario
+func (s *TwoFactorService) Enable(userID, sessionID int64, req *domain.TwoFactorCodeRequest) (*domain.RecoveryCodesResponse, error) {
+	secret, err := s.repo.GetSecret(userID)
+	if err != nil {
+		return nil, err
+	}
+	if secret.EnabledAt != nil {
+		return nil, errors.New("two-factor authentication already enabled")
+	}
+
+	plain, err := crypto.DecryptPassword(secret.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret: %w", err)
+	}
+	step, ok := crypto.ValidateTOTP(plain, crypto.NormalizeRecoveryCode(req.Code), time.Now(), secret.LastStep)
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessionRepo.RevokeOthers(userID, sessionID, domain.SessionRevokedTwoFactor); err != nil {
+		log.Printf("Warning: failed to revoke sessions of user %d after enabling 2FA: %v", userID, err)
+	}
+	s.notify(userID, "Verificación en dos pasos activada",
+		"se activó la verificación en dos pasos en tu cuenta y se cerraron las demás sesiones.")
+
+	return &domain.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable desactiva 2FA con la contraseña y un código (TOTP o de recuperación). No se permite si
+// alguna empresa lo exige para el rol del usuario.
+func (s *TwoFactorService) Disable(userID int64, req *domain.DisableTwoFactorRequest) error {
+	if _, err := s.checkPassword(userID, req.Password); err != nil {
+		return err
+	}
+
+	required, err := s.requiredBy(userID)
+	if err != nil {
+		return err
+	}
+	if len(required) > 0 {
+		return fmt.Errorf("cannot disable two-factor authentication, required by %s", required[0])
+	}
+
+	if _, err := verifyTwoFactorCode(s.repo, userID, req.Code, true); err != nil {
+		return err
+	}
+	if err := s.repo.Disable(userID); err != nil {
+		return err
+	}
+
+	s.notify(userID, "Verificación en dos pasos desactivada",
+		"se desactivó la verificación en dos pasos en tu cuenta.")
+	return nil
+}
+
+// RegenerateRecoveryCodes reemplaza los códigos de recuperación; exige un código TOTP de la app
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int64, req *domain.TwoFactorCodeRequest) (*domain.RecoveryCodesResponse, error) {
+	if _, err := verifyTwoFactorCode(s.repo, userID, req.Code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return &domain.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// requiredBy lista las empresas cuya política exige 2FA para el rol del usuario
+func (s *TwoFactorService) requiredBy(userID int64) ([]string, error) {
+	companies, err := s.repo.GetRequiringCompanies(userID)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, company := range companies {
+		if domain.RoleRequiresTwoFactor(company.Role) {
+			names = append(names, company.CompanyName)
+		}
+	}
+	return names, nil
+}
+
+func (s *TwoFactorService) checkPassword(userID int64, password string) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, errors.New("password is incorrect")
+	}
+	return user, nil
+}
+
+// notify avisa al usuario por correo de un cambio en su 2FA
+func (s *TwoFactorService) notify(userID int64, subject, what string) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return
+	}
+	deliverMail(s.mailer, mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body: fmt.Sprintf("Hola %s,\n\n"+
+			"Te informamos que %s\n\n"+
+			"Si no fuiste tú, restablece tu contraseña de inmediato y contacta al administrador de tu empresa.\n",
+			user.Name, what),
+	})
+}
+
+// verifyTwoFactorCode acepta un código TOTP de 6 dígitos o, si allowRecovery, un código de
+// recuperación (que queda consumido). Retorna si se usó un código de recuperación.
+func verifyTwoFactorCode(repo *repository.TwoFactorRepository, userID int64, code string, allowRecovery bool) (bool, error) {
+	code = crypto.NormalizeRecoveryCode(code) // "123 456" o "ABCDE-FGHIJ"
+	secret, err := repo.GetSecret(userID)
+	if err != nil {
+		if err.Error() == "two-factor authentication not set up" {
+			return false, errors.New("two-factor authentication not enabled")
+		}
+		return false, err
+	}
+	if secret.EnabledAt == nil {
+		return false, errors.New("two-factor authentication not enabled")
+	}
+
+	if len(code) == crypto.TOTPDigits {
+		plain, err := crypto.DecryptPassword(secret.Secret)
+		if err != nil {
+			return false, fmt.Errorf("error decrypting secret: %w", err)
+		}
+		step, ok := crypto.ValidateTOTP(plain, code, time.Now(), secret.LastStep)
+		if !ok {
+			return false, errors.New("invalid two-factor code")
+		}
+		// UseStep repite la comprobación de forma atómica por si otra petición usó el mismo código
+		fresh, err := repo.UseStep(userID, step)
+		if err != nil {
+			return false, err
+		}
+		if !fresh {
+			return false, errors.New("invalid two-factor code")
+		}
+		return false, nil
+	}
+
+	if !allowRecovery {
+		return false, errors.New("invalid two-factor code")
+	}
+	used, err := repo.UseRecoveryCode(userID, crypto.HashToken(code))
+	if err != nil {
+		return false, err
+	}
+	if !used {
+		return false, errors.New("invalid two-factor code")
+	}
+	return true, nil
+}
+
+// generateRecoveryCodes genera domain.RecoveryCodeCount códigos y sus hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, domain.RecoveryCodeCount)
+	hashes := make([]string, 0, domain.RecoveryCodeCount)
+	for i := 0; i < domain.RecoveryCodeCount; i++ {
+		code, err := crypto.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, crypto.HashToken(crypto.NormalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// qrDataURI codifica el contenido como un PNG QR de 256x256 en un data URI
+func qrDataURI(content string) (string, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return "", fmt.Errorf("error generating QR code: %w", err)
+	}
+	code, err = barcode.Scale(code, 256, 256)
+	if err != nil {
+		return "", fmt.Errorf("error generating QR code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return "", fmt.Errorf("error encoding QR code: %w", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"apidian-go/internal/domain"
+	"apidian-go/internal/infrastructure/storage"
+	"apidian-go/internal/repository"
+	"apidian-go/internal/service/keyring"
+	"apidian-go/internal/service/prevalidation"
+	"apidian-go/pkg/xmldsig"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VerificationService verifica la integridad de documentos firmados: firma XAdES-EPES,
+// digests, vigencia del certificado al firmar y CUFE/CUDE recalculado desde el XML.
+type VerificationService struct {
+	invoiceRepo    *repository.InvoiceRepository
+	companyRepo    *repository.CompanyRepository
+	authz          *Authorizer
+	resolutionRepo *repository.ResolutionRepository
+	softwareRepo   *repository.SoftwareRepository
+	certRepo       *repository.CertificateRepository
+	keyring        *keyring.Keyring
+}
+
+func NewVerificationService(
+	invoiceRepo *repository.InvoiceRepository,
+	companyRepo *repository.CompanyRepository,
+	resolutionRepo *repository.ResolutionRepository,
+	softwareRepo *repository.SoftwareRepository,
+	certRepo *repository.CertificateRepository,
+	keys *keyring.Keyring,
+) *VerificationService {
+	return &VerificationService{
+		invoiceRepo:    invoiceRepo,
+		companyRepo:    companyRepo,
+		authz:          NewAuthorizer(companyRepo),
+		resolutionRepo: resolutionRepo,
+		softwareRepo:   softwareRepo,
+		certRepo:       certRepo,
+		keyring:        keys,
+	}
+}
+
+// uuidKeys son las claves para recalcular el CUFE (clave técnica) o el CUDE (PIN del software)
+type uuidKeys struct {
+	technicalKey string
+	softwarePIN  string
+}
+
+// VerifyInvoice verifica el XML firmado almacenado de una factura propia
+func (s *VerificationService) VerifyInvoice(id, userID int64) (*domain.VerificationReport, error) {
+	invoice, err := s.invoiceRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeResource(invoice.CompanyID, userID, domain.PermissionInvoicesRead, "invoice"); err != nil {
+		return nil, err
+	}
+	if invoice.XMLPath == nil || *invoice.XMLPath == "" {
+		return nil, errors.New("invoice has not been signed")
+	}
+
+	store, err := s.keyring.CompanyStorage(invoice.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load company data key: %w", err)
+	}
+	data, err := store.Get(*invoice.XMLPath)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, errors.New("signed XML not found in storage")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signed XML: %w", err)
+	}
+
+	root, err := xmldsig.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys uuidKeys
+	if invoice.Resolution != nil && invoice.Resolution.TechnicalKey != nil {
+		keys.technicalKey = *invoice.Resolution.TechnicalKey
+	}
+	if invoice.Software != nil {
+		keys.softwarePIN = invoice.Software.PIN
+	}
+
+	report, err := verifyDocument(root, keys)
+	if err != nil {
+		return nil, err
+	}
+	report.Source = "stored"
+
+	// El CUFE del XML debe ser el registrado en la BD
+	if invoice.UUID != nil {
+		matches := *invoice.UUID == report.UUID
+		report.StoredUUIDMatches = &matches
+		if !matches {
+			report.Errors = append(report.Errors, "the CUFE in the XML differs from the one recorded for the invoice")
+		}
+	}
+
+	// El certificado de la firma debe ser el registrado al firmar
+	if invoice.CertificateID != nil && report.Certificate != nil {
+		if cert, err := s.certRepo.GetByID(*invoice.CertificateID); err == nil && cert.SerialNumber != nil {
+			matches := strings.EqualFold(*cert.SerialNumber, report.Certificate.SerialNumber)
+			report.SignedWithRecordedCertificate = &matches
+			if !matches {
+				report.Errors = append(report.Errors, "the XML was not signed with the certificate recorded for the invoice")
+			}
+		}
+	}
+
+	finalizeReport(report)
+	return report, nil
+}
+
+// VerifyXML verifica un XML firmado subido por el usuario (propio o de un tercero).
+// Si es un AttachedDocument se verifica su firma y la del documento que contiene.
+func (s *VerificationService) VerifyXML(req *domain.VerifyXMLRequest, userID int64) (*domain.VerificationReport, error) {
+	data, err := base64.StdEncoding.DecodeString(req.XML)
+	if err != nil {
+		return nil, errors.New("xml must be valid base64 encoded data")
+	}
+
+	root, err := xmldsig.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	source := "uploaded"
+	var container *domain.SignatureVerification
+	if root.Local == "AttachedDocument" {
+		// Un contenedor sin firma (o con la firma fuera de su lugar) no es válido
+		if result, err := xmldsig.Verify(root); err != nil {
+			container = &domain.SignatureVerification{Errors: []string{err.Error()}}
+		} else {
+			summary := signatureSummary(result)
+			container = &summary
+		}
+
+		embedded := root.Path("Attachment", "ExternalReference", "Description")
+		if embedded == nil {
+			return nil, errors.New("invalid XML: AttachedDocument has no embedded document")
+		}
+		root, err = xmldsig.Parse([]byte(embedded.Text()))
+		if err != nil {
+			return nil, err
+		}
+		source = "attached_document"
+	}
+
+	// Claves enviadas en la petición; si no, las de la empresa del usuario emisora del documento
+	keys := s.ownKeys(root, userID)
+	if req.TechnicalKey != nil && *req.TechnicalKey != "" {
+		keys.technicalKey = *req.TechnicalKey
+	}
+	if req.SoftwarePIN != nil && *req.SoftwarePIN != "" {
+		keys.softwarePIN = *req.SoftwarePIN
+	}
+
+	report, err := verifyDocument(root, keys)
+	if err != nil {
+		return nil, err
+	}
+	report.Source = source
+	report.ContainerSignature = container
+	if container != nil && !container.Valid {
+		report.Errors = append(report.Errors, "the AttachedDocument signature is not valid")
+	}
+
+	finalizeReport(report)
+	return report, nil
+}
+
+// ownKeys busca la clave técnica y el PIN cuando el emisor es una empresa del usuario
+func (s *VerificationService) ownKeys(root *xmldsig.Element, userID int64) uuidKeys {
+	var keys uuidKeys
+
+	issuerNIT := elementText(root, "AccountingSupplierParty", "Party", "PartyTaxScheme", "CompanyID")
+	companies, _, err := s.companyRepo.GetByUserID(userID, 1, 1000)
+	if err != nil || issuerNIT == "" {
+		return keys
+	}
+
+	for _, company := range companies {
+		if company.NIT != issuerNIT {
+			continue
+		}
+		if prefix := documentPrefix(root); prefix != "" {
+			if resolution, err := s.resolutionRepo.GetByCompanyAndPrefix(company.ID, prefix); err == nil && resolution != nil && resolution.TechnicalKey != nil {
+				keys.technicalKey = *resolution.TechnicalKey
+			}
+		}
+		if software, err := s.softwareRepo.GetByCompanyID(company.ID); err == nil {
+			keys.softwarePIN = software.Pin
+		}
+		break
+	}
+	return keys
+}
+
+// verifyDocument verifica la firma y recalcula el CUFE/CUDE de un Invoice, CreditNote o DebitNote
+func verifyDocument(root *xmldsig.Element, keys uuidKeys) (*domain.VerificationReport, error) {
+	switch root.Local {
+	case "Invoice", "CreditNote", "DebitNote":
+	default:
+		return nil, fmt.Errorf("invalid XML: unsupported document type %s", root.Local)
+	}
+
+	report := &domain.VerificationReport{
+		DocumentType: root.Local,
+		Number:       elementText(root, "ID"),
+		IssuerNIT:    elementText(root, "AccountingSupplierParty", "Party", "PartyTaxScheme", "CompanyID"),
+	}
+	if uuid := root.Child("UUID"); uuid != nil {
+		report.UUID = uuid.Text()
+		report.UUIDScheme = uuid.Attr("schemeName")
+	}
+
+	result, err := xmldsig.Verify(root)
+	if err != nil {
+		return nil, err
+	}
+	report.Signature = signatureSummary(result)
+	if cert := result.Certificate; cert != nil {
+		report.Certificate = &domain.CertificateSummary{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: strings.ToUpper(cert.SerialNumber.Text(16)),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		}
+	}
+
+	report.UUIDCheck = checkDocumentUUID(root, report.UUID, keys)
+	return report, nil
+}
+
+// checkDocumentUUID recalcula el CUFE (facturas, con la clave técnica) o el CUDE (notas, con
+// el PIN del software) y lo compara con el UUID del documento
+func checkDocumentUUID(root *xmldsig.Element, uuid string, keys uuidKeys) domain.UUIDCheck {
+	key, keyName := keys.technicalKey, "technical key"
+	if root.Local == "CreditNote" || root.Local == "DebitNote" {
+		key, keyName = keys.softwarePIN, "software PIN"
+	}
+	if key == "" {
+		return domain.UUIDCheck{Status: domain.UUIDCheckSkipped, Reason: keyName + " not available"}
+	}
+	if uuid == "" {
+		return domain.UUIDCheck{Status: domain.UUIDCheckMismatch, Reason: "the document has no UUID"}
+	}
+
+	computed := prevalidation.DocumentUUID(root, key)
+	if !strings.EqualFold(computed, uuid) {
+		return domain.UUIDCheck{Status: domain.UUIDCheckMismatch, Computed: computed, Reason: "the UUID does not match the document content"}
+	}
+	return domain.UUIDCheck{Status: domain.UUIDCheckValid, Computed: computed}
+}
+
+func signatureSummary(result *xmldsig.Result) domain.SignatureVerification {
+	summary := domain.SignatureVerification{
+		Valid:                     result.Valid(),
+		SignatureValid:            result.SignatureValid,
+		DigestsValid:              result.DigestsValid,
+		DocumentCovered:           result.DocumentCovered,
+		SignedPropertiesCovered:   result.SignedPropertiesCovered,
+		SigningCertificateValid:   result.SigningCertificateValid,
+		CertificateValidAtSigning: result.CertificateValidAtSigning,
+		SignatureMethod:           result.SignatureMethod,
+		SigningTime:               result.SigningTime,
+		PolicyIdentifier:          result.PolicyIdentifier,
+		Errors:                    result.Errors,
+	}
+	for _, ref := range result.References {
+		summary.References = append(summary.References, domain.ReferenceCheck{
+			URI:            ref.URI,
+			Valid:          ref.Valid,
+			ExpectedDigest: ref.Expected,
+			ComputedDigest: ref.Computed,
+			Error:          ref.Error,
+		})
+	}
+	return summary
+}
+
+// finalizeReport calcula el veredicto: firma válida y UUID no discrepante
+func finalizeReport(report *domain.VerificationReport) {
+	report.Errors = append(append([]string{}, report.Signature.Errors...), report.Errors...)
+	if report.UUIDCheck.Status == domain.UUIDCheckMismatch {
+		report.Errors = append(report.Errors, "UUID check failed: "+report.UUIDCheck.Reason)
+	}
+
+	report.Valid = report.Signature.Valid &&
+		report.UUIDCheck.Status != domain.UUIDCheckMismatch &&
+		(report.StoredUUIDMatches == nil || *report.StoredUUIDMatches) &&
+		(report.SignedWithRecordedCertificate == nil || *report.SignedWithRecordedCertificate) &&
+		(report.ContainerSignature == nil || report.ContainerSignature.Valid)
+}
+
+// documentPrefix obtiene el prefijo de la resolución (sts:Prefix o las letras iniciales del número)
+func documentPrefix(root *xmldsig.Element) string {
+	if prefix := root.Find("", "Prefix"); prefix != nil {
+		return prefix.Text()
+	}
+	number := elementText(root, "ID")
+	return strings.TrimRight(number, "0123456789")
+}
+
+func elementText(el *xmldsig.Element, path ...string) string {
+	if found := el.Path(path...); found != nil {
+		return found.Text()
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// ParsePKCS12Certificate extrae el certificado del firmante (hoja) de un PKCS12.
+// Primero usa x/crypto/pkcs12 (DER); si falla (certificados BER de algunas CAs o
+// varios certificados en la cadena) recurre a openssl. Retorna error si el archivo
+// o la contraseña no son válidos.
+func ParsePKCS12Certificate(data []byte, password string) (*x509.Certificate, error) {
+	certs, err := certificatesFromPKCS12(data, password)
+	if err != nil {
+		var opensslErr error
+		certs, opensslErr = certificatesWithOpenSSL(data, password)
+		if opensslErr != nil {
+			return nil, fmt.Errorf("invalid certificate or password: %w", err)
+		}
+	}
+
+	leaf := leafCertificate(certs)
+	if leaf == nil {
+		return nil, errors.New("invalid certificate or password: no certificate found in PKCS12")
+	}
+	return leaf, nil
+}
+
+func certificatesFromPKCS12(data []byte, password string) ([]*x509.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for _, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// certificatesWithOpenSSL usa el binario openssl; la contraseña viaja por variable de
+// entorno y el archivo por stdin para no exponerlos en la línea de comandos ni en disco.
+func certificatesWithOpenSSL(data []byte, password string) ([]*x509.Certificate, error) {
+	var lastErr error
+	// OpenSSL 3 requiere -legacy para los algoritmos RC2/3DES de muchos .p12 de CAs colombianas
+	for _, extra := range [][]string{nil, {"-legacy"}} {
+		args := append([]string{"pkcs12", "-nokeys", "-passin", "env:APIDIAN_P12_PASSWORD"}, extra...)
+		cmd := exec.Command("openssl", args...)
+		cmd.Env = append(os.Environ(), "APIDIAN_P12_PASSWORD="+password)
+		cmd.Stdin = bytes.NewReader(data)
+
+		out, err := cmd.Output()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var certs []*x509.Certificate
+		for rest := out; ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, cert)
+		}
+		if len(certs) > 0 {
+			return certs, nil
+		}
+		lastErr = errors.New("openssl returned no certificates")
+	}
+	return nil, lastErr
+}
+
+// leafCertificate elige el certificado del firmante: el primero que no es CA
+func leafCertificate(certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return cert
+		}
+	}
+	if len(certs) > 0 {
+		return certs[0]
+	}
+	return nil
+}
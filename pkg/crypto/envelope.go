@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Envelope encryption: cada empresa tiene una llave de datos (DEK) aleatoria que cifra
+// sus archivos; la DEK se guarda envuelta (cifrada) con la llave maestra ENCRYPTION_KEY.
+
+// blobMagic identifica los archivos cifrados con SealBlob
+var blobMagic = []byte("APIDENC1")
+
+// GenerateDataKey genera una llave de datos AES-256 aleatoria
+func GenerateDataKey() ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return dek, nil
+}
+
+// WrapDataKey cifra una llave de datos con la llave maestra
+func WrapDataKey(dek []byte) (string, error) {
+	return encryptWithMasterKey(dek)
+}
+
+// UnwrapDataKey descifra una llave de datos envuelta con WrapDataKey
+func UnwrapDataKey(wrapped string) ([]byte, error) {
+	dek, err := decryptWithMasterKey(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	if len(dek) != 32 {
+		return nil, errors.New("invalid data key length")
+	}
+	return dek, nil
+}
+
+// SealBlob cifra un archivo con la llave de datos. additionalData (p. ej. la clave del
+// storage) se autentica para impedir intercambiar archivos cifrados entre rutas.
+func SealBlob(dek, plaintext, additionalData []byte) ([]byte, error) {
+	ciphertext, err := seal(dek, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, blobMagic...), ciphertext...), nil
+}
+
+// OpenBlob descifra un archivo producido por SealBlob
+func OpenBlob(dek, data, additionalData []byte) ([]byte, error) {
+	if !IsSealedBlob(data) {
+		return nil, errors.New("data is not an encrypted blob")
+	}
+	return open(dek, data[len(blobMagic):], additionalData)
+}
+
+// IsSealedBlob indica si el contenido fue cifrado con SealBlob
+func IsSealedBlob(data []byte) bool {
+	return bytes.HasPrefix(data, blobMagic)
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDataKeyWrapRoundTrip(t *testing.T) {
+	useKeyProvider(t, EnvKeyProvider{})
+	setEnvKeys(t, "", "", testKeyOld)
+
+	dek, err := GenerateDataKey()
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+	if other, _ := GenerateDataKey(); bytes.Equal(dek, other) {
+		t.Fatal("GenerateDataKey returned the same key twice")
+	}
+
+	wrapped, err := WrapDataKey(dek)
+	if err != nil {
+		t.Fatalf("WrapDataKey: %v", err)
+	}
+	unwrapped, err := UnwrapDataKey(wrapped)
+	if err != nil || !bytes.Equal(unwrapped, dek) {
+		t.Fatalf("UnwrapDataKey = %x, %v; want %x", unwrapped, err, dek)
+	}
+
+	// Con otra llave maestra la llave de datos no se recupera
+	setEnvKeys(t, "", "", testKeyNew)
+	if _, err := UnwrapDataKey(wrapped); err == nil {
+		t.Fatal("UnwrapDataKey with another master key must fail")
+	}
+
+	// Un valor cifrado que no es una llave de 32 bytes se rechaza
+	notAKey, _ := EncryptPassword("short")
+	if _, err := UnwrapDataKey(notAKey); err == nil {
+		t.Error("UnwrapDataKey of a non 32-byte value must fail")
+	}
+}
+
+func TestSealBlob(t *testing.T) {
+	dek, _ := GenerateDataKey()
+	otherDEK, _ := GenerateDataKey()
+	plaintext := []byte("<Invoice>...</Invoice>")
+	ad := []byte("companies/900123456/documents/invoices/SETP1/SETP1_signed.xml")
+
+	sealed, err := SealBlob(dek, plaintext, ad)
+	if err != nil {
+		t.Fatalf("SealBlob: %v", err)
+	}
+	if !IsSealedBlob(sealed) || bytes.Contains(sealed, plaintext) {
+		t.Fatal("sealed blob must carry the marker and hide the content")
+	}
+	if got, err := OpenBlob(dek, sealed, ad); err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("OpenBlob = %q, %v", got, err)
+	}
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name string
+		dek  []byte
+		data []byte
+		ad   []byte
+	}{
+		{"other data key", otherDEK, sealed, ad},
+		{"other storage key", dek, sealed, []byte("companies/900123456/other.xml")},
+		{"tampered content", dek, tampered, ad},
+		{"plaintext", dek, plaintext, ad},
+		{"truncated", dek, sealed[:len(blobMagic)+4], ad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenBlob(tt.dek, tt.data, tt.ad); err == nil {
+				t.Fatal("OpenBlob must fail")
+			}
+		})
+	}
+}
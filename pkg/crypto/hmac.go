@@ -0,0 +1,19 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignHMAC retorna el HMAC-SHA256 en hexadecimal de message con secret (enlaces firmados)
+func SignHMAC(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyHMAC compara en tiempo constante la firma recibida con la de message
+func VerifyHMAC(secret, message, signature string) bool {
+	return hmac.Equal([]byte(SignHMAC(secret, message)), []byte(signature))
+}
@@ -0,0 +1,399 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MasterKeys es el conjunto de llaves maestras disponibles. ActiveID cifra; todas descifran.
+type MasterKeys struct {
+	ActiveID string
+	Keys     map[string][]byte
+}
+
+// KeyProvider cifra y descifra valores pequeños (passwords, llaves de datos, secretos TOTP) con
+// las llaves maestras. Los proveedores env y file tienen las llaves en memoria; el proveedor kms
+// delega cada operación al servicio y la llave maestra nunca llega al proceso.
+type KeyProvider interface {
+	// ActiveKeyID retorna el ID de la llave con la que se cifra actualmente
+	ActiveKeyID() (string, error)
+	// Encrypt cifra con la llave activa y retorna su ID
+	Encrypt(plaintext []byte) (keyID string, ciphertext []byte, err error)
+	// Decrypt descifra con la llave keyID ("" = valor anterior al versionado de llaves)
+	Decrypt(keyID string, ciphertext []byte) ([]byte, error)
+}
+
+const (
+	KeyProviderEnv  = "env"
+	KeyProviderFile = "file"
+	KeyProviderKMS  = "kms"
+)
+
+// legacyKeyID es el ID asignado a ENCRYPTION_KEY cuando se usa sin ENCRYPTION_KEYS
+const legacyKeyID = "default"
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+var (
+	providerMu sync.RWMutex
+	provider   KeyProvider = EnvKeyProvider{}
+)
+
+// SetKeyProvider reemplaza el proveedor de llaves usado por EncryptPassword, DecryptPassword y WrapDataKey
+func SetKeyProvider(p KeyProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	provider = p
+}
+
+func currentProvider() KeyProvider {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return provider
+}
+
+// ActiveKeyID retorna el ID de la llave maestra con la que se cifra actualmente
+func ActiveKeyID() (string, error) {
+	return currentProvider().ActiveKeyID()
+}
+
+// NewKeyProvider crea el proveedor indicado en ENCRYPTION_KEY_PROVIDER
+func NewKeyProvider(kind, keysFile, kmsURL, kmsToken string) (KeyProvider, error) {
+	switch kind {
+	case "", KeyProviderEnv:
+		return EnvKeyProvider{}, nil
+	case KeyProviderFile:
+		if keysFile == "" {
+			return nil, errors.New("ENCRYPTION_KEYS_FILE is required for the file key provider")
+		}
+		return FileKeyProvider{Path: keysFile}, nil
+	case KeyProviderKMS:
+		if kmsURL == "" {
+			return nil, errors.New("KMS_URL is required for the kms key provider")
+		}
+		return NewKMSKeyProvider(kmsURL, kmsToken), nil
+	default:
+		return nil, fmt.Errorf("invalid ENCRYPTION_KEY_PROVIDER: %q (use env, file or kms)", kind)
+	}
+}
+
+// EnvKeyProvider lee las llaves del entorno en cada llamada:
+//
+//	ENCRYPTION_KEYS=2026a:<hex>,2025b:<hex>   (varias llaves con ID)
+//	ENCRYPTION_KEY_ID=2026a                   (llave activa; por defecto la primera)
+//	ENCRYPTION_KEY=<hex>                      (llave única heredada, ID "default")
+type EnvKeyProvider struct{}
+
+func (p EnvKeyProvider) ActiveKeyID() (string, error) {
+	keys, err := p.MasterKeys()
+	if err != nil {
+		return "", err
+	}
+	return keys.ActiveID, nil
+}
+
+func (p EnvKeyProvider) Encrypt(plaintext []byte) (string, []byte, error) {
+	keys, err := p.MasterKeys()
+	if err != nil {
+		return "", nil, err
+	}
+	return keys.encrypt(plaintext)
+}
+
+func (p EnvKeyProvider) Decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	keys, err := p.MasterKeys()
+	if err != nil {
+		return nil, err
+	}
+	return keys.decrypt(keyID, ciphertext)
+}
+
+// MasterKeys lee y valida las llaves del entorno
+func (EnvKeyProvider) MasterKeys() (*MasterKeys, error) {
+	keys := &MasterKeys{Keys: map[string][]byte{}}
+
+	if list := os.Getenv("ENCRYPTION_KEYS"); list != "" {
+		for _, entry := range strings.Split(list, ",") {
+			id, keyHex, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid ENCRYPTION_KEYS entry %q, use id:hexkey", entry)
+			}
+			if err := keys.add(id, keyHex); err != nil {
+				return nil, err
+			}
+			if keys.ActiveID == "" {
+				keys.ActiveID = id
+			}
+		}
+	}
+
+	if keyHex := os.Getenv("ENCRYPTION_KEY"); keyHex != "" {
+		if _, exists := keys.Keys[legacyKeyID]; !exists {
+			if err := keys.add(legacyKeyID, keyHex); err != nil {
+				return nil, err
+			}
+		}
+		if keys.ActiveID == "" {
+			keys.ActiveID = legacyKeyID
+		}
+	}
+
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("ENCRYPTION_KEY not set in environment")
+	}
+	if active := os.Getenv("ENCRYPTION_KEY_ID"); active != "" {
+		keys.ActiveID = active
+	}
+
+	return keys, keys.validate()
+}
+
+// keysDocument es el formato JSON del archivo de llaves
+//
+//	{"active": "2026a", "keys": {"2026a": "<hex>", "2025b": "<hex>"}}
+type keysDocument struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+func (d *keysDocument) toMasterKeys() (*MasterKeys, error) {
+	keys := &MasterKeys{ActiveID: d.Active, Keys: map[string][]byte{}}
+	for id, keyHex := range d.Keys {
+		if err := keys.add(id, keyHex); err != nil {
+			return nil, err
+		}
+	}
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("no encryption keys configured")
+	}
+	return keys, keys.validate()
+}
+
+// FileKeyProvider lee las llaves de un archivo JSON (p. ej. un secret montado en el contenedor)
+type FileKeyProvider struct {
+	Path string
+}
+
+func (p FileKeyProvider) ActiveKeyID() (string, error) {
+	keys, err := p.MasterKeys()
+	if err != nil {
+		return "", err
+	}
+	return keys.ActiveID, nil
+}
+
+func (p FileKeyProvider) Encrypt(plaintext []byte) (string, []byte, error) {
+	keys, err := p.MasterKeys()
+	if err != nil {
+		return "", nil, err
+	}
+	return keys.encrypt(plaintext)
+}
+
+func (p FileKeyProvider) Decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	keys, err := p.MasterKeys()
+	if err != nil {
+		return nil, err
+	}
+	return keys.decrypt(keyID, ciphertext)
+}
+
+// MasterKeys lee y valida el archivo de llaves
+func (p FileKeyProvider) MasterKeys() (*MasterKeys, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption keys file: %w", err)
+	}
+
+	var doc keysDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid encryption keys file: %w", err)
+	}
+	return doc.toMasterKeys()
+}
+
+// KMSKeyProvider cifra y descifra con un servicio HTTP tipo KMS; las llaves maestras se quedan en
+// el servicio. Sirve tanto para un KMS interno como para un stand-in local en desarrollo:
+//
+//	GET  {url}/active   -> {"key_id": "2026a"}
+//	POST {url}/encrypt  {"plaintext": "<base64>"} -> {"key_id": "2026a", "ciphertext": "<base64>"}
+//	POST {url}/decrypt  {"key_id": "2026a", "ciphertext": "<base64>"} -> {"plaintext": "<base64>"}
+//
+// Todas las peticiones llevan el token como Bearer. La llave activa se cachea durante cacheTTL.
+// Los valores sin ID (anteriores al versionado) se descifran con la llave "default".
+type KMSKeyProvider struct {
+	url      string
+	token    string
+	client   *http.Client
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	activeID  string
+	fetchedAt time.Time
+}
+
+func NewKMSKeyProvider(url, token string) *KMSKeyProvider {
+	return &KMSKeyProvider{
+		url:      strings.TrimSuffix(url, "/"),
+		token:    token,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		cacheTTL: 5 * time.Minute,
+	}
+}
+
+// kmsMessage es el cuerpo de las peticiones y respuestas del KMS
+type kmsMessage struct {
+	KeyID      string `json:"key_id,omitempty"`
+	Plaintext  []byte `json:"plaintext,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+func (p *KMSKeyProvider) ActiveKeyID() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.activeID != "" && time.Since(p.fetchedAt) < p.cacheTTL {
+		return p.activeID, nil
+	}
+
+	var resp kmsMessage
+	if err := p.call(http.MethodGet, "/active", nil, &resp); err != nil {
+		return "", err
+	}
+	if !keyIDPattern.MatchString(resp.KeyID) {
+		return "", fmt.Errorf("invalid KMS response: invalid key id %q", resp.KeyID)
+	}
+
+	p.activeID = resp.KeyID
+	p.fetchedAt = time.Now()
+	return p.activeID, nil
+}
+
+func (p *KMSKeyProvider) Encrypt(plaintext []byte) (string, []byte, error) {
+	var resp kmsMessage
+	if err := p.call(http.MethodPost, "/encrypt", &kmsMessage{Plaintext: plaintext}, &resp); err != nil {
+		return "", nil, err
+	}
+	if !keyIDPattern.MatchString(resp.KeyID) || len(resp.Ciphertext) == 0 {
+		return "", nil, errors.New("invalid KMS response: missing key id or ciphertext")
+	}
+	return resp.KeyID, resp.Ciphertext, nil
+}
+
+func (p *KMSKeyProvider) Decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	if keyID == "" {
+		keyID = legacyKeyID
+	}
+
+	var resp kmsMessage
+	if err := p.call(http.MethodPost, "/decrypt", &kmsMessage{KeyID: keyID, Ciphertext: ciphertext}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+func (p *KMSKeyProvider) call(method, path string, in, out *kmsMessage) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, p.url+path, body)
+	if err != nil {
+		return fmt.Errorf("invalid KMS_URL: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact KMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("KMS %s responded with status %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid KMS response: %w", err)
+	}
+	return nil
+}
+
+// validate comprueba que la llave activa esté entre las configuradas
+func (k *MasterKeys) validate() error {
+	if _, ok := k.Keys[k.ActiveID]; !ok {
+		return fmt.Errorf("active encryption key %q not found", k.ActiveID)
+	}
+	return nil
+}
+
+// encrypt cifra con la llave activa
+func (k *MasterKeys) encrypt(plaintext []byte) (string, []byte, error) {
+	ciphertext, err := seal(k.Keys[k.ActiveID], plaintext, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return k.ActiveID, ciphertext, nil
+}
+
+// decrypt descifra con la llave keyID. Los valores sin ID se prueban con la llave activa y
+// luego con las demás (GCM autentica, una llave errónea falla).
+func (k *MasterKeys) decrypt(keyID string, ciphertext []byte) ([]byte, error) {
+	if keyID != "" {
+		key, ok := k.Keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown encryption key id %q", keyID)
+		}
+		return open(key, ciphertext, nil)
+	}
+
+	if plaintext, err := open(k.Keys[k.ActiveID], ciphertext, nil); err == nil {
+		return plaintext, nil
+	}
+	for id, key := range k.Keys {
+		if id == k.ActiveID {
+			continue
+		}
+		if plaintext, err := open(key, ciphertext, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("failed to decrypt: no configured key matches")
+}
+
+func (k *MasterKeys) add(id, keyHex string) error {
+	if !keyIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid encryption key id %q (use letters, digits, '.', '_' or '-')", id)
+	}
+
+	// Decode hex key to bytes (must be 32 bytes for AES-256)
+	key, err := hex.DecodeString(strings.TrimSpace(keyHex))
+	if err != nil {
+		return fmt.Errorf("invalid encryption key %q format: %w", id, err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("encryption key %q must be 32 bytes (64 hex chars), got %d bytes", id, len(key))
+	}
+
+	k.Keys[id] = key
+	return nil
+}
@@ -0,0 +1,335 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testKeyOld = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	testKeyNew = "202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f"
+)
+
+// useKeyProvider configura p como proveedor global durante el test
+func useKeyProvider(t *testing.T, p KeyProvider) {
+	t.Helper()
+	previous := currentProvider()
+	SetKeyProvider(p)
+	t.Cleanup(func() { SetKeyProvider(previous) })
+}
+
+func setEnvKeys(t *testing.T, keys, active, legacy string) {
+	t.Helper()
+	t.Setenv("ENCRYPTION_KEYS", keys)
+	t.Setenv("ENCRYPTION_KEY_ID", active)
+	t.Setenv("ENCRYPTION_KEY", legacy)
+}
+
+func TestEnvKeyProviderRoundTrip(t *testing.T) {
+	useKeyProvider(t, EnvKeyProvider{})
+	setEnvKeys(t, "", "", testKeyOld)
+
+	encrypted, err := EncryptPassword("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptPassword: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, "default:") {
+		t.Errorf("ciphertext %q must carry the key id of ENCRYPTION_KEY", encrypted)
+	}
+	if got, err := DecryptPassword(encrypted); err != nil || got != "s3cret" {
+		t.Fatalf("DecryptPassword = %q, %v; want s3cret", got, err)
+	}
+
+	// Con otra llave el mismo valor no se descifra (GCM autentica)
+	setEnvKeys(t, "", "", testKeyNew)
+	if _, err := DecryptPassword(encrypted); err == nil {
+		t.Fatal("DecryptPassword with a different ENCRYPTION_KEY must fail")
+	}
+}
+
+func TestEnvKeyProviderConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		keys       string
+		active     string
+		legacy     string
+		wantActive string
+		wantErr    string
+	}{
+		{"single legacy key", "", "", testKeyOld, "default", ""},
+		{"first listed key is active", "2026a:" + testKeyNew + ",default:" + testKeyOld, "", "", "2026a", ""},
+		{"explicit active key", "2026a:" + testKeyNew + ",default:" + testKeyOld, "default", "", "default", ""},
+		{"legacy key kept with a list", "2026a:" + testKeyNew, "", testKeyOld, "2026a", ""},
+		{"no keys", "", "", "", "", "ENCRYPTION_KEY not set"},
+		{"unknown active key", "2026a:" + testKeyNew, "2025z", "", "", `active encryption key "2025z" not found`},
+		{"entry without id", testKeyNew, "", "", "", "use id:hexkey"},
+		{"short key", "2026a:abcd", "", "", "", "must be 32 bytes"},
+		{"invalid id", "bad id:" + testKeyNew, "", "", "", "invalid encryption key id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnvKeys(t, tt.keys, tt.active, tt.legacy)
+			active, err := EnvKeyProvider{}.ActiveKeyID()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ActiveKeyID error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || active != tt.wantActive {
+				t.Fatalf("ActiveKeyID = %q, %v; want %q", active, err, tt.wantActive)
+			}
+		})
+	}
+}
+
+// Rotación: la llave nueva cifra, la anterior sigue descifrando hasta que Reencrypt migra el valor
+func TestKeyRotation(t *testing.T) {
+	useKeyProvider(t, EnvKeyProvider{})
+	setEnvKeys(t, "", "", testKeyOld)
+
+	oldValue, err := EncryptPassword("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptPassword: %v", err)
+	}
+	oldWrapped, err := WrapDataKey(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("WrapDataKey: %v", err)
+	}
+
+	// 1. Llave nueva activa, la anterior conservada
+	setEnvKeys(t, "2026a:"+testKeyNew+",default:"+testKeyOld, "2026a", "")
+	if got, err := DecryptPassword(oldValue); err != nil || got != "s3cret" {
+		t.Fatalf("old value after rotation = %q, %v", got, err)
+	}
+
+	rotated, changed, err := Reencrypt(oldValue)
+	if err != nil || !changed {
+		t.Fatalf("Reencrypt = %v, %v; want changed", changed, err)
+	}
+	if CiphertextKeyID(rotated) != "2026a" {
+		t.Errorf("rotated value key id = %q, want 2026a", CiphertextKeyID(rotated))
+	}
+	if again, changed, err := Reencrypt(rotated); err != nil || changed || again != rotated {
+		t.Errorf("Reencrypt of a value on the active key must be a no-op, got %v, %v", changed, err)
+	}
+	rewrapped, _, err := Reencrypt(oldWrapped)
+	if err != nil {
+		t.Fatalf("Reencrypt data key: %v", err)
+	}
+
+	// 2. Retirar la llave anterior: lo migrado sigue funcionando, lo no migrado no
+	setEnvKeys(t, "2026a:"+testKeyNew, "", "")
+	if got, err := DecryptPassword(rotated); err != nil || got != "s3cret" {
+		t.Fatalf("rotated value = %q, %v", got, err)
+	}
+	if dek, err := UnwrapDataKey(rewrapped); err != nil || len(dek) != 32 {
+		t.Fatalf("rewrapped data key = %d bytes, %v", len(dek), err)
+	}
+	if _, err := DecryptPassword(oldValue); err == nil || !strings.Contains(err.Error(), `unknown encryption key id "default"`) {
+		t.Errorf("old value without its key: error = %v", err)
+	}
+}
+
+// Los valores sin ID (anteriores al versionado) se prueban con todas las llaves
+func TestDecryptLegacyValueWithoutKeyID(t *testing.T) {
+	useKeyProvider(t, EnvKeyProvider{})
+	oldKey, _ := hex.DecodeString(testKeyOld)
+	ciphertext, err := seal(oldKey, []byte("s3cret"), nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	legacy := base64.StdEncoding.EncodeToString(ciphertext)
+
+	setEnvKeys(t, "2026a:"+testKeyNew+",old:"+testKeyOld, "", "")
+	if got, err := DecryptPassword(legacy); err != nil || got != "s3cret" {
+		t.Fatalf("DecryptPassword(legacy) = %q, %v", got, err)
+	}
+
+	setEnvKeys(t, "2026a:"+testKeyNew, "", "")
+	if _, err := DecryptPassword(legacy); err == nil {
+		t.Fatal("legacy value without a matching key must fail")
+	}
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	doc := `{"active": "2026a", "keys": {"2026a": "` + testKeyNew + `", "default": "` + testKeyOld + `"}}`
+	if err := os.WriteFile(path, []byte(doc), 0600); err != nil {
+		t.Fatalf("write keys file: %v", err)
+	}
+	p := FileKeyProvider{Path: path}
+
+	keyID, ciphertext, err := p.Encrypt([]byte("s3cret"))
+	if err != nil || keyID != "2026a" {
+		t.Fatalf("Encrypt = %q, %v; want 2026a", keyID, err)
+	}
+	if got, err := p.Decrypt(keyID, ciphertext); err != nil || string(got) != "s3cret" {
+		t.Fatalf("Decrypt = %q, %v", got, err)
+	}
+	if _, err := p.Decrypt("default", ciphertext); err == nil {
+		t.Error("Decrypt with another key id must fail")
+	}
+
+	os.WriteFile(path, []byte(`{"active": "2025z", "keys": {"2026a": "`+testKeyNew+`"}}`), 0600)
+	if _, err := p.ActiveKeyID(); err == nil {
+		t.Error("file whose active key is not listed must fail")
+	}
+	if _, err := (FileKeyProvider{Path: filepath.Join(t.TempDir(), "missing.json")}).ActiveKeyID(); err == nil {
+		t.Error("missing keys file must fail")
+	}
+}
+
+// fakeKMS cifra con sus propias llaves; el cliente solo ve IDs y textos cifrados
+type fakeKMS struct {
+	keys   *MasterKeys
+	token  string
+	mu     sync.Mutex
+	bodies []string // todo lo que viajó por la red
+}
+
+func newFakeKMS(t *testing.T, token string) (*fakeKMS, *httptest.Server) {
+	t.Helper()
+	oldKey, _ := hex.DecodeString(testKeyOld)
+	newKey, _ := hex.DecodeString(testKeyNew)
+	f := &fakeKMS{
+		keys:  &MasterKeys{ActiveID: "2026a", Keys: map[string][]byte{"2026a": newKey, "default": oldKey}},
+		token: token,
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeKMS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var in kmsMessage
+	if r.Method == http.MethodPost {
+		body, _ := io.ReadAll(r.Body)
+		f.record(string(body))
+		if err := json.Unmarshal(body, &in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	var out kmsMessage
+	var err error
+	switch r.URL.Path {
+	case "/keys/active":
+		out.KeyID = f.keys.ActiveID
+	case "/keys/encrypt":
+		out.KeyID, out.Ciphertext, err = f.keys.encrypt(in.Plaintext)
+	case "/keys/decrypt":
+		out.Plaintext, err = f.keys.decrypt(in.KeyID, in.Ciphertext)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	response, _ := json.Marshal(out)
+	f.record(string(response))
+	w.Write(response)
+}
+
+func (f *fakeKMS) record(body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bodies = append(f.bodies, body)
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	kms, server := newFakeKMS(t, "kms-token")
+	p := NewKMSKeyProvider(server.URL+"/keys/", "kms-token")
+	useKeyProvider(t, p)
+
+	if active, err := ActiveKeyID(); err != nil || active != "2026a" {
+		t.Fatalf("ActiveKeyID = %q, %v; want 2026a", active, err)
+	}
+
+	encrypted, err := EncryptPassword("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptPassword: %v", err)
+	}
+	if CiphertextKeyID(encrypted) != "2026a" {
+		t.Errorf("ciphertext key id = %q, want 2026a", CiphertextKeyID(encrypted))
+	}
+	if got, err := DecryptPassword(encrypted); err != nil || got != "s3cret" {
+		t.Fatalf("DecryptPassword = %q, %v", got, err)
+	}
+
+	// Valores sin ID se descifran con la llave "default" del KMS
+	oldKey, _ := hex.DecodeString(testKeyOld)
+	ciphertext, _ := seal(oldKey, []byte("legacy"), nil)
+	if got, err := DecryptPassword(base64.StdEncoding.EncodeToString(ciphertext)); err != nil || got != "legacy" {
+		t.Fatalf("DecryptPassword(legacy) = %q, %v", got, err)
+	}
+
+	// Un valor alterado lo rechaza el KMS
+	tampered := encrypted[:len(encrypted)-4] + "AAA="
+	if _, err := DecryptPassword(tampered); err == nil {
+		t.Error("tampered ciphertext must fail")
+	}
+
+	// Las llaves maestras nunca viajan al proceso
+	for _, body := range kms.bodies {
+		for _, key := range kms.keys.Keys {
+			if strings.Contains(body, hex.EncodeToString(key)) || strings.Contains(body, base64.StdEncoding.EncodeToString(key)) {
+				t.Fatalf("master key sent over the wire: %s", body)
+			}
+		}
+	}
+}
+
+func TestKMSKeyProviderErrors(t *testing.T) {
+	_, server := newFakeKMS(t, "kms-token")
+
+	if _, _, err := NewKMSKeyProvider(server.URL+"/keys", "wrong-token").Encrypt([]byte("x")); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("wrong token: error = %v, want status 401", err)
+	}
+	if _, err := NewKMSKeyProvider(server.URL+"/other", "kms-token").ActiveKeyID(); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("wrong path: error = %v, want status 404", err)
+	}
+	server.Close()
+	if _, err := NewKMSKeyProvider(server.URL+"/keys", "kms-token").ActiveKeyID(); err == nil || !strings.Contains(err.Error(), "failed to contact KMS") {
+		t.Errorf("unreachable KMS: error = %v", err)
+	}
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	tests := []struct {
+		kind, file, url string
+		wantErr         bool
+	}{
+		{"", "", "", false},
+		{KeyProviderEnv, "", "", false},
+		{KeyProviderFile, "/run/secrets/keys.json", "", false},
+		{KeyProviderFile, "", "", true},
+		{KeyProviderKMS, "", "http://kms.local/keys", false},
+		{KeyProviderKMS, "", "", true},
+		{"vault", "", "", true},
+	}
+	for _, tt := range tests {
+		if _, err := NewKeyProvider(tt.kind, tt.file, tt.url, ""); (err != nil) != tt.wantErr {
+			t.Errorf("NewKeyProvider(%q) error = %v, wantErr %v", tt.kind, err, tt.wantErr)
+		}
+	}
+}
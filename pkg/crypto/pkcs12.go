@@ -5,10 +5,9 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
+	"strings"
 
 	"golang.org/x/crypto/pkcs12"
 )
@@ -35,99 +34,132 @@ func DecodePKCS12(base64Cert string) ([]byte, error) {
 	return certificateData, nil
 }
 
-// EncryptPassword encrypts a password using AES-256-GCM with the ENCRYPTION_KEY from environment
-// This provides strong encryption with authentication (AEAD)
+// EncryptPassword encrypts a password using AES-256-GCM with the active master key
+// of the configured KeyProvider. This provides strong encryption with authentication (AEAD)
 func EncryptPassword(password string) (string, error) {
-	// Get encryption key from environment
-	keyHex := os.Getenv("ENCRYPTION_KEY")
-	if keyHex == "" {
-		return "", errors.New("ENCRYPTION_KEY not set in environment")
+	return encryptWithMasterKey([]byte(password))
+}
+
+// DecryptPassword decrypts a password encrypted with EncryptPassword using AES-256-GCM
+func DecryptPassword(encryptedPassword string) (string, error) {
+	plaintext, err := decryptWithMasterKey(encryptedPassword)
+	if err != nil {
+		return "", err
 	}
+	return string(plaintext), nil
+}
 
-	// Decode hex key to bytes (must be 32 bytes for AES-256)
-	key, err := hex.DecodeString(keyHex)
+// encryptWithMasterKey encrypts with the active master key and returns "keyID:base64(ciphertext)"
+func encryptWithMasterKey(plaintext []byte) (string, error) {
+	keyID, ciphertext, err := currentProvider().Encrypt(plaintext)
 	if err != nil {
-		return "", fmt.Errorf("invalid ENCRYPTION_KEY format: %w", err)
+		return "", err
 	}
 
-	if len(key) != 32 {
-		return "", fmt.Errorf("ENCRYPTION_KEY must be 32 bytes (64 hex chars), got %d bytes", len(key))
+	// Encode to base64 for storage, prefixed with the key ID used
+	return keyID + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptWithMasterKey reverses encryptWithMasterKey. Ciphertexts without key ID (created
+// before key versioning) are passed to the provider with an empty key ID.
+func decryptWithMasterKey(encoded string) ([]byte, error) {
+	keyID, payload := CiphertextKeyID(encoded), encoded
+	if keyID != "" {
+		payload = encoded[len(keyID)+1:]
 	}
 
-	// Create AES cipher
-	block, err := aes.NewCipher(key)
+	// Decode base64 ciphertext
+	ciphertext, err := base64.StdEncoding.DecodeString(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return nil, fmt.Errorf("invalid encrypted password format: %w", err)
 	}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
+	return currentProvider().Decrypt(keyID, ciphertext)
+}
+
+// CiphertextKeyID returns the master key ID embedded in a ciphertext ("" for legacy values)
+func CiphertextKeyID(encoded string) string {
+	keyID, _, ok := strings.Cut(encoded, ":")
+	if !ok {
+		return ""
+	}
+	return keyID
+}
+
+// seal encrypts with AES-256-GCM and returns nonce || ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, err
 	}
 
 	// Generate random nonce
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
-		return "", fmt.Errorf("failed to generate nonce: %w", err)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	// Encrypt password
-	ciphertext := gcm.Seal(nonce, nonce, []byte(password), nil)
-
-	// Encode to base64 for storage
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
 }
 
-// DecryptPassword decrypts a password encrypted with EncryptPassword using AES-256-GCM
-func DecryptPassword(encryptedPassword string) (string, error) {
-	// Get encryption key from environment
-	keyHex := os.Getenv("ENCRYPTION_KEY")
-	if keyHex == "" {
-		return "", errors.New("ENCRYPTION_KEY not set in environment")
-	}
-
-	// Decode hex key to bytes
-	key, err := hex.DecodeString(keyHex)
+// open decrypts nonce || ciphertext produced by seal
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("invalid ENCRYPTION_KEY format: %w", err)
+		return nil, err
 	}
 
-	if len(key) != 32 {
-		return "", fmt.Errorf("ENCRYPTION_KEY must be 32 bytes (64 hex chars), got %d bytes", len(key))
+	// Extract nonce
+	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("ciphertext too short")
 	}
 
-	// Decode base64 ciphertext
-	ciphertext, err := base64.StdEncoding.DecodeString(encryptedPassword)
+	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
-		return "", fmt.Errorf("invalid encrypted password format: %w", err)
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
 	}
 
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	// Create AES cipher
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
 	// Create GCM mode
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
 
-	// Extract nonce
-	nonceSize := gcm.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return "", errors.New("ciphertext too short")
-	}
+	return gcm, nil
+}
 
-	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+// Reencrypt re-encrypts a value produced by EncryptPassword or WrapDataKey under the active
+// master key. It returns the value unchanged (and false) if it already uses the active key.
+func Reencrypt(encoded string) (string, bool, error) {
+	activeID, err := ActiveKeyID()
+	if err != nil {
+		return "", false, err
+	}
+	if CiphertextKeyID(encoded) == activeID {
+		return encoded, false, nil
+	}
 
-	// Decrypt
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := decryptWithMasterKey(encoded)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt: %w", err)
+		return "", false, err
 	}
 
-	return string(plaintext), nil
+	reencrypted, err := encryptWithMasterKey(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return reencrypted, true, nil
 }
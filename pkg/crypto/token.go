@@ -0,0 +1,34 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// RandomToken genera un token opaco aleatorio de size bytes codificado en base64 URL-safe
+func RandomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// RandomID genera un identificador aleatorio de 128 bits en hexadecimal (p. ej. jti de JWT)
+func RandomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken retorna el SHA-256 en hexadecimal de un token; es lo único que se guarda en la
+// base de datos, así una filtración no expone tokens utilizables
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parámetros TOTP (RFC 6238) compatibles con Google Authenticator, Authy, 1Password, etc.
+const (
+	TOTPPeriod = 30 // Segundos por paso
+	TOTPDigits = 6
+	totpSkew   = 1 // Pasos de tolerancia antes y después del actual (desfase de reloj)
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret genera un secreto TOTP de 160 bits en base32 sin relleno
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPStep retorna el paso TOTP que contiene el instante t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// TOTPCode calcula el código de un paso (HOTP con HMAC-SHA1, RFC 4226)
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000), nil
+}
+
+// ValidateTOTP verifica un código en el paso de t y sus vecinos posteriores a lastStep (el último
+// paso aceptado, 0 si ninguno), así un código ya usado o uno anterior no se acepta de nuevo dentro
+// de la ventana. Retorna el paso que coincidió para que el llamador lo registre.
+func ValidateTOTP(secret, code string, t time.Time, lastStep int64) (int64, bool) {
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := TOTPStep(t)
+	for step := max(current-totpSkew, lastStep+1); step <= current+totpSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURL arma la URI otpauth:// que las apps autenticadoras leen del código QR
+func TOTPURL(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(TOTPPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// GenerateRecoveryCode genera un código de recuperación de 10 caracteres en base32
+// con el formato xxxxx-xxxxx
+func GenerateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	code := strings.ToLower(totpEncoding.EncodeToString(buf))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// NormalizeRecoveryCode quita espacios, guiones y mayúsculas del código ingresado (también sirve
+// para códigos TOTP escritos como "123 456")
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,143 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret es la semilla SHA-1 del apéndice B de RFC 6238 ("12345678901234567890") en base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Vectores del apéndice B de RFC 6238 (SHA-1). El RFC publica 8 dígitos; con 6 dígitos el
+// código son los últimos 6, porque ambos se truncan del mismo valor.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		rfc  string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rfc, func(t *testing.T) {
+			step := TOTPStep(time.Unix(tt.unix, 0))
+			got, err := TOTPCode(rfc6238Secret, step)
+			if err != nil {
+				t.Fatalf("TOTPCode: %v", err)
+			}
+			if want := tt.rfc[len(tt.rfc)-TOTPDigits:]; got != want {
+				t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, got, want)
+			}
+			// El secreto se acepta en minúsculas, como lo escriben algunas apps
+			if lower, _ := TOTPCode(strings.ToLower(rfc6238Secret), step); lower != got {
+				t.Errorf("lowercase secret = %s, want %s", lower, got)
+			}
+		})
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Fatal("expected error for invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(current), 0, current, true},
+		{"previous step within skew", codeAt(current - 1), 0, current - 1, true},
+		{"next step within skew", codeAt(current + 1), 0, current + 1, true},
+		{"two steps behind", codeAt(current - 2), 0, 0, false},
+		{"two steps ahead", codeAt(current + 2), 0, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+		{"wrong length", codeAt(current)[:5], 0, 0, false},
+		{"replay of the last used step", codeAt(current), current, 0, false},
+		{"older step after a newer one was used", codeAt(current - 1), current, 0, false},
+		{"newer step after an older one was used", codeAt(current + 1), current, current + 1, true},
+		{"current step after the previous one was used", codeAt(current), current - 1, current, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// Un código aceptado no vuelve a servir en ninguna de las peticiones siguientes de su ventana
+func TestValidateTOTPRejectsReplayWithinWindow(t *testing.T) {
+	issued := time.Unix(1234567890, 0)
+	code, err := TOTPCode(rfc6238Secret, TOTPStep(issued))
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+
+	lastStep, ok := ValidateTOTP(rfc6238Secret, code, issued, 0)
+	if !ok {
+		t.Fatal("first use must be accepted")
+	}
+	for offset := time.Duration(0); offset <= 2*TOTPPeriod*time.Second; offset += 5 * time.Second {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, issued.Add(offset), lastStep); ok {
+			t.Fatalf("code replayed %s later was accepted", offset)
+		}
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret length = %d, want 32 (160 bits)", len(secret))
+	}
+	if _, err := TOTPCode(secret, 1); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	got := TOTPURL("Apidian", "ana@example.com", rfc6238Secret)
+	want := "otpauth://totp/Apidian:ana@example.com?algorithm=SHA1&digits=6&issuer=Apidian&period=30&secret=" + rfc6238Secret
+	if got != want {
+		t.Errorf("TOTPURL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := map[string]string{
+		" ABCDE-FGHIJ ": "abcdefghij",
+		"123 456":       "123456",
+		"abcde-fghij":   "abcdefghij",
+	}
+	for in, want := range tests {
+		if got := NormalizeRecoveryCode(in); got != want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
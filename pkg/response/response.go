@@ -59,3 +59,32 @@ func Conflict(c *fiber.Ctx, message string) error {
 		Error:   message,
 	})
 }
+
+func UnprocessableEntity(c *fiber.Ctx, message string, data interface{}) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(Response{
+		Success: false,
+		Error:   message,
+		Data:    data,
+	})
+}
+
+func Forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(Response{
+		Success: false,
+		Error:   message,
+	})
+}
+
+func TooManyRequests(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(Response{
+		Success: false,
+		Error:   message,
+	})
+}
+
+func ServiceUnavailable(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(Response{
+		Success: false,
+		Error:   message,
+	})
+}
@@ -0,0 +1,34 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+)
+
+// ReadCSV lee un CSV separado por coma o punto y coma (Excel en español usa ';')
+func ReadCSV(data []byte) (*Table, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+
+	return newTable(records)
+}
+
+// detectDelimiter elige ';' si la primera línea tiene más punto y coma que comas
+func detectDelimiter(data []byte) rune {
+	firstLine := data
+	if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+		firstLine = data[:idx]
+	}
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
@@ -0,0 +1,136 @@
+package spreadsheet
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRows limita el tamaño de los archivos importados
+const MaxRows = 10000
+
+// MaxColumns limita las columnas por fila de los archivos XLSX (las plantillas usan unas 30)
+const MaxColumns = 256
+
+// Table representa una hoja con encabezados normalizados
+type Table struct {
+	Headers []string
+	Rows    []Row
+}
+
+// Row representa una fila de datos con su número original en el archivo
+type Row struct {
+	Number int // Número de fila en el archivo (1 = encabezado)
+	Values map[string]string
+}
+
+// Get obtiene el valor de una columna (vacío si no existe)
+func (r Row) Get(column string) string {
+	return strings.TrimSpace(r.Values[column])
+}
+
+// Read lee un archivo CSV o XLSX según su extensión
+func Read(filename string, data []byte) (*Table, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return ReadCSV(data)
+	case ".xlsx":
+		return ReadXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported file type, use .csv or .xlsx")
+	}
+}
+
+// HasColumn indica si la tabla contiene la columna indicada
+func (t *Table) HasColumn(column string) bool {
+	for _, h := range t.Headers {
+		if h == column {
+			return true
+		}
+	}
+	return false
+}
+
+// newTable construye la tabla a partir de registros crudos (primera fila = encabezados)
+func newTable(records [][]string) (*Table, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	headers := make([]string, len(records[0]))
+	for i, h := range records[0] {
+		headers[i] = NormalizeHeader(h)
+	}
+
+	table := &Table{Headers: headers}
+	for i, record := range records[1:] {
+		if isEmptyRecord(record) {
+			continue
+		}
+		if len(table.Rows) >= MaxRows {
+			return nil, fmt.Errorf("file exceeds the maximum of %d rows", MaxRows)
+		}
+
+		values := make(map[string]string, len(headers))
+		for j, header := range headers {
+			if header == "" || j >= len(record) {
+				continue
+			}
+			values[header] = record[j]
+		}
+		table.Rows = append(table.Rows, Row{Number: i + 2, Values: values})
+	}
+
+	return table, nil
+}
+
+// NormalizeHeader convierte "Customer Identification" en "customer_identification"
+func NormalizeHeader(header string) string {
+	header = strings.TrimPrefix(header, "\ufeff") // BOM de Excel
+	header = strings.ToLower(strings.TrimSpace(header))
+	header = strings.ReplaceAll(header, " ", "_")
+	header = strings.ReplaceAll(header, "-", "_")
+	return header
+}
+
+// NormalizeDate acepta YYYY-MM-DD, DD/MM/YYYY o número serial de Excel y retorna YYYY-MM-DD
+func NormalizeDate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "2006/01/02", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+
+	// Excel guarda fechas como días desde 1899-12-30
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local)
+		return base.AddDate(0, 0, int(serial)).Format("2006-01-02"), nil
+	}
+
+	return "", fmt.Errorf("invalid date '%s', use YYYY-MM-DD", value)
+}
+
+// ParseNumber acepta punto o coma decimal ("1234.5", "1234,5")
+func ParseNumber(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, ",") && !strings.Contains(value, ".") {
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
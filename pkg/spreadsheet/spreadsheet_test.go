@@ -0,0 +1,309 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		headers []string
+		rows    []map[string]string
+		rowNums []int
+		wantErr bool
+	}{
+		{
+			name:    "comma with BOM and spaced headers",
+			data:    "\ufeffCustomer Identification,Total-Amount\n900123456,1500.50\n",
+			headers: []string{"customer_identification", "total_amount"},
+			rows:    []map[string]string{{"customer_identification": "900123456", "total_amount": "1500.50"}},
+			rowNums: []int{2},
+		},
+		{
+			name:    "semicolon from spanish excel",
+			data:    "code;price\nP001;1234,5\nP002;10\n",
+			headers: []string{"code", "price"},
+			rows: []map[string]string{
+				{"code": "P001", "price": "1234,5"},
+				{"code": "P002", "price": "10"},
+			},
+			rowNums: []int{2, 3},
+		},
+		{
+			name:    "blank lines keep original row numbers",
+			data:    "code,name\nA,Uno\n,\nB,Dos\n",
+			headers: []string{"code", "name"},
+			rows: []map[string]string{
+				{"code": "A", "name": "Uno"},
+				{"code": "B", "name": "Dos"},
+			},
+			rowNums: []int{2, 4},
+		},
+		{
+			name:    "short records leave missing columns empty",
+			data:    "code,name,price\nA\n",
+			headers: []string{"code", "name", "price"},
+			rows:    []map[string]string{{"code": "A"}},
+			rowNums: []int{2},
+		},
+		{
+			name:    "empty file",
+			data:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ReadCSV([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got table %+v", table)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertTable(t, table, tt.headers, tt.rows, tt.rowNums)
+		})
+	}
+}
+
+func TestReadCSVMaxRows(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("code\n")
+	for i := 0; i <= MaxRows; i++ {
+		buf.WriteString("X\n")
+	}
+
+	if _, err := ReadCSV(buf.Bytes()); err == nil {
+		t.Fatalf("expected error for more than %d rows", MaxRows)
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	sheet := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Active</t></is></c></row>` +
+		`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>45292</v></c><c r="C2" t="b"><v>1</v></c></row>` +
+		`<row r="4"><c r="B4"><v>45293</v></c><c r="C4" t="b"><v>0</v></c></row>` +
+		`</sheetData></worksheet>`
+	shared := `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<si><t>Code</t></si><si><t>Issue Date</t></si><si><r><t>00</t></r><r><t>7</t></r></si></sst>`
+
+	data := buildXLSX(t, map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Datos" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId7" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/worksheets/data.xml":     sheet,
+		"xl/sharedStrings.xml":       shared,
+	})
+
+	table, err := ReadXLSX(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertTable(t, table,
+		[]string{"code", "issue_date", "active"},
+		[]map[string]string{
+			{"code": "007", "issue_date": "45292", "active": "true"},
+			{"code": "", "issue_date": "45293", "active": "false"},
+		},
+		[]int{2, 4},
+	)
+}
+
+func TestReadXLSXInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a zip", []byte("code,name\n")},
+		{"missing workbook", buildXLSX(t, map[string]string{"xl/worksheets/sheet1.xml": "<worksheet/>"})},
+		{"workbook without sheets", buildXLSX(t, map[string]string{"xl/workbook.xml": "<workbook><sheets/></workbook>"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadXLSX(tt.data); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+// Archivos pequeños que declaran filas, columnas o contenido enormes se rechazan sin reservar memoria
+func TestReadXLSXLimits(t *testing.T) {
+	workbook := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Hoja1" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+	}
+	withSheet := func(rows string) []byte {
+		files := map[string]string{"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` + rows + `</sheetData></worksheet>`}
+		for name, content := range workbook {
+			files[name] = content
+		}
+		return buildXLSX(t, files)
+	}
+	header := `<row r="1"><c r="A1" t="inlineStr"><is><t>code</t></is></c></row>`
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"sparse row far beyond the limit", withSheet(header + `<row r="50000000"><c r="A50000000"><v>1</v></c></row>`), "maximum of 10000 rows"},
+		{"first row after the limit", withSheet(header + `<row r="10002"><c r="A10002"><v>1</v></c></row>`), "maximum of 10000 rows"},
+		{"huge column reference", withSheet(`<row r="1"><c r="ZZZZZ1"><v>1</v></c></row>`), "maximum of 256 columns"},
+		{"column reference that would overflow", withSheet(`<row r="1"><c r="` + strings.Repeat("Z", 40) + `1"><v>1</v></c></row>`), "maximum of 256 columns"},
+		{"last excel column", withSheet(`<row r="1"><c r="XFD1"><v>1</v></c></row>`), "maximum of 256 columns"},
+		{"zip bomb", withSheet(header + `<row r="2"><c r="A2"><v>` + strings.Repeat(" ", maxXMLSize) + `</v></c></row>`), "exceeds 64 MB uncompressed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadXLSX(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReadXLSX error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Filas solo con formato hasta el final de la hoja (Excel las escribe) no cuentan como datos
+	table, err := ReadXLSX(withSheet(header + `<row r="2"><c r="A2"><v>7</v></c></row><row r="1048576"/>`))
+	if err != nil {
+		t.Fatalf("unexpected error for trailing formatted rows: %v", err)
+	}
+	assertTable(t, table, []string{"code"}, []map[string]string{{"code": "7"}}, []int{2})
+
+	// Referencia sin columna: se usa la posición de la celda
+	table, err = ReadXLSX(withSheet(header + `<row r="2"><c r="2"><v>8</v></c></row>`))
+	if err != nil {
+		t.Fatalf("unexpected error for cell without column: %v", err)
+	}
+	assertTable(t, table, []string{"code"}, []map[string]string{{"code": "8"}}, []int{2})
+}
+
+func TestRead(t *testing.T) {
+	if _, err := Read("facturas.CSV", []byte("code\nA\n")); err != nil {
+		t.Fatalf("csv: unexpected error: %v", err)
+	}
+	if _, err := Read("facturas.xls", []byte("code\nA\n")); err == nil {
+		t.Fatal("xls: expected unsupported file type error")
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"2024-01-15", "2024-01-15", false},
+		{"15/01/2024", "2024-01-15", false},
+		{"2024/01/15", "2024-01-15", false},
+		{"2024-01-15 10:30:00", "2024-01-15", false},
+		{"45306", "2024-01-15", false},
+		{"  ", "", false},
+		{"15-01-2024", "", true},
+		{"-3", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := NormalizeDate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeDate(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeDate(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"1234.5", 1234.5, false},
+		{"1234,5", 1234.5, false},
+		{" 10 ", 10, false},
+		{"1,234.5", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseNumber(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNumber(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseNumber(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB12": 27, "BA1": 52, "IV1": 255, "12": -1}
+	for ref, want := range tests {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
+
+func assertTable(t *testing.T, table *Table, headers []string, rows []map[string]string, rowNums []int) {
+	t.Helper()
+
+	if len(table.Headers) != len(headers) {
+		t.Fatalf("headers = %v, want %v", table.Headers, headers)
+	}
+	for i, h := range headers {
+		if table.Headers[i] != h {
+			t.Errorf("header[%d] = %q, want %q", i, table.Headers[i], h)
+		}
+	}
+
+	if len(table.Rows) != len(rows) {
+		t.Fatalf("got %d rows, want %d", len(table.Rows), len(rows))
+	}
+	for i, want := range rows {
+		row := table.Rows[i]
+		if row.Number != rowNums[i] {
+			t.Errorf("row %d number = %d, want %d", i, row.Number, rowNums[i])
+		}
+		for col, value := range want {
+			if got := row.Get(col); got != value {
+				t.Errorf("row %d %s = %q, want %q", i, col, got, value)
+			}
+		}
+	}
+}
+
+func buildXLSX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
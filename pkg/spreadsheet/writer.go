@@ -0,0 +1,139 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Formatos de exportación soportados
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ContentType retorna el MIME type del formato de exportación
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write genera un archivo CSV o XLSX con los encabezados y filas indicados
+func Write(format string, sheetName string, headers []string, rows [][]string) ([]byte, error) {
+	switch format {
+	case FormatCSV, "":
+		return WriteCSV(headers, rows)
+	case FormatXLSX:
+		return WriteXLSX(sheetName, headers, rows)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s', use csv or xlsx", format)
+	}
+}
+
+// WriteCSV genera un CSV separado por comas con BOM para que Excel detecte UTF-8
+func WriteCSV(headers []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+
+	w := csv.NewWriter(&buf)
+	if err := w.Write(headers); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("error writing CSV: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteXLSX genera un libro XLSX de una hoja. Todas las celdas se escriben como
+// texto (inlineStr) para conservar ceros a la izquierda en códigos e identificaciones.
+func WriteXLSX(sheetName string, headers []string, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbookTemplate, xmlEscape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", buildSheetXML(headers, rows)},
+	}
+
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, fmt.Errorf("error writing XLSX: %w", err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			return nil, fmt.Errorf("error writing XLSX: %w", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing XLSX: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func buildSheetXML(headers []string, rows [][]string) string {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow := func(index int, values []string) {
+		fmt.Fprintf(&sb, `<row r="%d">`, index)
+		for i, v := range values {
+			if v == "" {
+				continue
+			}
+			fmt.Fprintf(&sb, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, columnName(i), index, xmlEscape(v))
+		}
+		sb.WriteString(`</row>`)
+	}
+
+	writeRow(1, headers)
+	for i, row := range rows {
+		writeRow(i+2, row)
+	}
+
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String()
+}
+
+// columnName convierte el índice 27 (base 0) en "AB"
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func xmlEscape(value string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbookTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
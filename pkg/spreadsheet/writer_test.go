@@ -0,0 +1,74 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	headers := []string{"Code", "Name", "Tax Rate"}
+	rows := [][]string{
+		{"007", "Café & Té <especial>", "19.00"},
+		{"P-002", "", "0"},
+	}
+
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			data, err := Write(format, "Productos", headers, rows)
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			table, err := Read("export."+format, data)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+
+			assertTable(t, table,
+				[]string{"code", "name", "tax_rate"},
+				[]map[string]string{
+					{"code": "007", "name": "Café & Té <especial>", "tax_rate": "19.00"},
+					{"code": "P-002", "name": "", "tax_rate": "0"},
+				},
+				[]int{2, 3},
+			)
+		})
+	}
+}
+
+func TestWriteCSVHasBOM(t *testing.T) {
+	data, err := WriteCSV([]string{"code"}, [][]string{{"A"}})
+	if err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("\ufeff")) {
+		t.Errorf("CSV export must start with a UTF-8 BOM, got %q", data[:4])
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	if _, err := Write("ods", "Hoja", []string{"code"}, nil); err == nil {
+		t.Fatal("expected unsupported format error")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", index, got, want)
+		}
+		if back := columnIndex(want + "1"); back != index {
+			t.Errorf("columnIndex(%q) = %d, want %d", want+"1", back, index)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	if got := ContentType(FormatXLSX); got != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Errorf("ContentType(xlsx) = %q", got)
+	}
+	if got := ContentType(FormatCSV); got != "text/csv; charset=utf-8" {
+		t.Errorf("ContentType(csv) = %q", got)
+	}
+}
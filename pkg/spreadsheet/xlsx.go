@@ -0,0 +1,225 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxXMLSize limita el tamaño descomprimido de cada parte del libro, para que un ZIP pequeño
+// no se expanda a gigabytes en memoria
+const maxXMLSize = 64 << 20
+
+// Estructuras mínimas de SpreadsheetML (solo lo necesario para leer la primera hoja)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX lee la primera hoja de un libro XLSX usando solo la librería estándar
+func ReadXLSX(data []byte) (*Table, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cadenas compartidas (opcional: hojas solo numéricas no lo incluyen)
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, fmt.Errorf("error reading shared strings: %w", err)
+		}
+	}
+
+	var sheet xlsxWorksheet
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("worksheet %s not found in XLSX", sheetPath)
+	}
+	if err := decodeZipXML(f, &sheet); err != nil {
+		return nil, fmt.Errorf("error reading worksheet: %w", err)
+	}
+
+	var records [][]string
+	lastRow := 0
+	for _, row := range sheet.Rows {
+		rowIndex := row.Index
+		if rowIndex == 0 {
+			rowIndex = lastRow + 1
+		}
+		// Excel escribe filas sin celdas (solo formato) hasta el final de la hoja; no cuentan
+		if len(row.Cells) == 0 {
+			continue
+		}
+		// Validar antes de rellenar, una referencia como r="50000000" no debe reservar memoria
+		if rowIndex > MaxRows+1 {
+			return nil, fmt.Errorf("file exceeds the maximum of %d rows", MaxRows)
+		}
+		// Conservar filas vacías intermedias para que los números de fila coincidan con Excel
+		for lastRow+1 < rowIndex {
+			records = append(records, nil)
+			lastRow++
+		}
+		lastRow = rowIndex
+
+		var record []string
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				if c := columnIndex(cell.Ref); c >= 0 {
+					col = c
+				}
+			}
+			if col >= MaxColumns {
+				return nil, fmt.Errorf("file exceeds the maximum of %d columns (cell %s)", MaxColumns, cell.Ref)
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err == nil && idx >= 0 && idx < len(shared.Items) {
+					record[col] = shared.Items[idx].String()
+				}
+			case "inlineStr":
+				record[col] = cell.Inline.String()
+			case "b":
+				record[col] = map[string]string{"1": "true", "0": "false"}[cell.Value]
+			default:
+				record[col] = cell.Value
+			}
+		}
+		records = append(records, record)
+	}
+
+	return newTable(records)
+}
+
+// firstSheetPath resuelve la ruta de la primera hoja declarada en el libro
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	wbFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("invalid XLSX file: workbook.xml not found")
+	}
+	var wb xlsxWorkbook
+	if err := decodeZipXML(wbFile, &wb); err != nil {
+		return "", fmt.Errorf("error reading workbook: %w", err)
+	}
+	if len(wb.Sheets) == 0 {
+		return "", fmt.Errorf("XLSX file has no sheets")
+	}
+
+	relsFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return fallback, nil
+	}
+	var rels xlsxRelationships
+	if err := decodeZipXML(relsFile, &rels); err != nil {
+		return "", fmt.Errorf("error reading workbook relationships: %w", err)
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return fallback, nil
+}
+
+// columnIndex convierte "AB12" en el índice de columna 27 (base 0); -1 si la referencia no tiene
+// columna. Deja de acumular al pasar MaxColumns, así una referencia larga no desborda el entero.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' || col > MaxColumns {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+func decodeZipXML(f *zip.File, out any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxXMLSize+1))
+	if err != nil {
+		return err
+	}
+	if len(content) > maxXMLSize {
+		return fmt.Errorf("%s exceeds %d MB uncompressed", f.Name, maxXMLSize>>20)
+	}
+	return xml.Unmarshal(content, out)
+}
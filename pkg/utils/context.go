@@ -15,3 +15,12 @@ func GetUserID(c *fiber.Ctx) (int64, error) {
 	}
 	return userID, nil
 }
+
+// GetSessionID extracts the session ID of the access token from the Fiber context
+func GetSessionID(c *fiber.Ctx) (int64, error) {
+	sessionID, ok := c.Locals("session_id").(int64)
+	if !ok {
+		return 0, errors.New("session not found in token")
+	}
+	return sessionID, nil
+}
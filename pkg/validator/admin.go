@@ -0,0 +1,35 @@
+package validator
+
+import (
+	"apidian-go/internal/domain"
+	"strings"
+)
+
+// ValidateSuspendCompany valida la solicitud de suspensión de una empresa
+func ValidateSuspendCompany(req *domain.SuspendCompanyRequest) error {
+	return validateReason(req.Reason)
+}
+
+// ValidateSetCompanyPlan valida la solicitud de cambio de plan de una empresa
+func ValidateSetCompanyPlan(req *domain.SetCompanyPlanRequest) error {
+	if strings.TrimSpace(req.Plan) == "" {
+		return NewError("plan", "es requerido")
+	}
+	return nil
+}
+
+// ValidateImpersonate valida la solicitud de suplantación de un usuario
+func ValidateImpersonate(req *domain.ImpersonateRequest) error {
+	return validateReason(req.Reason)
+}
+
+// validateReason exige el motivo que queda en la bitácora de administración
+func validateReason(reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return NewError("reason", "es requerido")
+	}
+	if len(reason) > 500 {
+		return NewError("reason", "debe tener máximo 500 caracteres")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package validator
+
+import (
+	"apidian-go/internal/domain"
+	"strings"
+	"time"
+)
+
+// ValidateCreateAPIKey valida la solicitud de creación de API key
+func ValidateCreateAPIKey(req *domain.CreateAPIKeyRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return NewError("name", "es requerido")
+	}
+	if len(req.Name) > 100 {
+		return NewError("name", "debe tener máximo 100 caracteres")
+	}
+
+	if len(req.Permissions) == 0 {
+		return NewError("permissions", "debe incluir al menos un permiso")
+	}
+	for _, permission := range req.Permissions {
+		if !domain.IsAPIKeyPermission(permission) {
+			return NewError("permissions", "permiso no válido: "+permission+" (válidos: "+strings.Join(domain.APIKeyPermissions, ", ")+")")
+		}
+	}
+
+	if req.RateLimit != nil && *req.RateLimit <= 0 {
+		return NewError("rate_limit_per_minute", "debe ser mayor que 0")
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return NewError("expires_at", "debe ser una fecha futura")
+	}
+
+	return nil
+}
@@ -152,3 +152,11 @@ func ValidateUpdateCompany(req *domain.UpdateCompanyRequest) error {
 
 	return nil
 }
+
+// ValidateTwoFactorPolicy valida la solicitud de política de 2FA de la empresa
+func ValidateTwoFactorPolicy(req *domain.TwoFactorPolicyRequest) error {
+	if req.RequireTwoFactor == nil {
+		return NewError("require_two_factor", "es requerido")
+	}
+	return nil
+}
@@ -38,6 +38,28 @@ func ValidateCreateInvoice(req *domain.CreateInvoiceRequest) error {
 		}
 	}
 
+	// Validar cuotas y anticipos (las sumas contra el total se validan en el service)
+	for i, installment := range req.Installments {
+		if installment.DueDate == "" {
+			return fmt.Errorf("due_date es requerido en la cuota %d", i+1)
+		}
+		if installment.Amount <= 0 {
+			return fmt.Errorf("amount debe ser mayor a 0 en la cuota %d", i+1)
+		}
+	}
+
+	for i, prepayment := range req.Prepayments {
+		if prepayment.ReceivedDate == "" {
+			return fmt.Errorf("received_date es requerido en el anticipo %d", i+1)
+		}
+		if prepayment.Amount <= 0 {
+			return fmt.Errorf("amount debe ser mayor a 0 en el anticipo %d", i+1)
+		}
+		if prepayment.Reference != nil && len(*prepayment.Reference) > 100 {
+			return fmt.Errorf("reference no puede superar 100 caracteres en el anticipo %d", i+1)
+		}
+	}
+
 	return nil
 }
 
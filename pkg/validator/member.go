@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"apidian-go/internal/domain"
+	"strings"
+)
+
+// ValidateInviteMember valida la solicitud de invitación a una empresa
+func ValidateInviteMember(req *domain.InviteMemberRequest) error {
+	if strings.TrimSpace(req.Email) == "" {
+		return NewError("email", "es requerido")
+	}
+	if !IsValidEmail(strings.TrimSpace(req.Email)) {
+		return NewError("email", "formato de email inválido")
+	}
+	return validateAssignableRole(req.Role)
+}
+
+// ValidateUpdateMember valida la solicitud de cambio de rol de un miembro
+func ValidateUpdateMember(req *domain.UpdateMemberRequest) error {
+	return validateAssignableRole(req.Role)
+}
+
+// validateAssignableRole valida que el rol exista y no sea owner (el dueño es quien crea la empresa)
+func validateAssignableRole(role string) error {
+	if role == "" {
+		return NewError("role", "es requerido")
+	}
+	if role == domain.MemberRoleOwner {
+		return NewError("role", "el rol owner no se puede asignar")
+	}
+	for _, r := range domain.MemberRoles {
+		if r == role {
+			return nil
+		}
+	}
+	return NewError("role", "rol no válido: "+role+" (válidos: "+strings.Join(domain.MemberRoles[1:], ", ")+")")
+}
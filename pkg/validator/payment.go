@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"apidian-go/internal/domain"
+	"fmt"
+)
+
+// ValidateCreatePayment valida la solicitud de registro de pago
+func ValidateCreatePayment(req *domain.CreatePaymentRequest) error {
+	if req.DocumentID <= 0 {
+		return fmt.Errorf("document_id es requerido")
+	}
+
+	if req.PaymentDate == "" {
+		return fmt.Errorf("payment_date es requerido")
+	}
+
+	if req.Amount <= 0 {
+		return fmt.Errorf("amount debe ser mayor a 0")
+	}
+
+	if req.PaymentMethodID != nil && *req.PaymentMethodID <= 0 {
+		return fmt.Errorf("payment_method_id no es válido")
+	}
+
+	if req.Reference != nil && len(*req.Reference) > 100 {
+		return fmt.Errorf("reference no puede superar 100 caracteres")
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"apidian-go/internal/domain"
+	"strings"
+)
+
+// ValidateCreateDocumentLink valida la solicitud de enlace firmado de un documento; sin
+// expires_in_hours (0) se usa PORTAL_LINK_HOURS
+func ValidateCreateDocumentLink(req *domain.CreateDocumentLinkRequest) error {
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > 8760 {
+		return NewError("expires_in_hours", "debe estar entre 1 y 8760 (un año), o omitirse para usar la vigencia por defecto")
+	}
+	return nil
+}
+
+// ValidateRegisterDocumentEvent valida el registro de un evento RADIAN desde el portal
+func ValidateRegisterDocumentEvent(req *domain.RegisterDocumentEventRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.IdentificationNumber = strings.TrimSpace(req.IdentificationNumber)
+	req.Notes = strings.TrimSpace(req.Notes)
+
+	if _, ok := domain.EventPrerequisites(req.Event); !ok {
+		return NewError("event", "evento no válido (válidos: "+strings.Join(domain.BuyerEvents, ", ")+")")
+	}
+	if req.Event == domain.EventClaim && strings.TrimSpace(req.RejectionCode) == "" {
+		return NewError("rejection_code", "es requerido para el reclamo")
+	}
+
+	if req.Name == "" {
+		return NewError("name", "es requerido")
+	}
+	if len(req.Name) > 255 {
+		return NewError("name", "debe tener máximo 255 caracteres")
+	}
+	if req.IdentificationNumber == "" {
+		return NewError("identification_number", "es requerido")
+	}
+	if len(req.IdentificationNumber) > 50 {
+		return NewError("identification_number", "debe tener máximo 50 caracteres")
+	}
+	if len(req.Notes) > 1000 {
+		return NewError("notes", "debe tener máximo 1000 caracteres")
+	}
+	return nil
+}
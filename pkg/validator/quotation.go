@@ -0,0 +1,50 @@
+package validator
+
+import (
+	"apidian-go/internal/domain"
+	"fmt"
+)
+
+// ValidateCreateQuotation valida la solicitud de creación de cotización o pedido
+func ValidateCreateQuotation(req *domain.CreateQuotationRequest) error {
+	if req.CompanyID <= 0 {
+		return fmt.Errorf("company_id es requerido")
+	}
+
+	if req.CustomerID <= 0 {
+		return fmt.Errorf("customer_id es requerido")
+	}
+
+	if req.Kind != "" && req.Kind != domain.QuotationKindQuotation && req.Kind != domain.QuotationKindOrder {
+		return fmt.Errorf("kind debe ser 'quotation' u 'order'")
+	}
+
+	if req.IssueDate == "" {
+		return fmt.Errorf("issue_date es requerido")
+	}
+
+	if req.CurrencyCodeID <= 0 {
+		return fmt.Errorf("currency_code_id es requerido")
+	}
+
+	if len(req.Lines) == 0 {
+		return fmt.Errorf("debe incluir al menos una línea")
+	}
+
+	// Las líneas siguen las mismas reglas que las de factura
+	for i, line := range req.Lines {
+		if err := ValidateCreateInvoiceLine(&line, i+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateConvertQuotation valida la solicitud de conversión de cotización a factura
+func ValidateConvertQuotation(req *domain.ConvertQuotationRequest) error {
+	if req.ResolutionID <= 0 {
+		return fmt.Errorf("resolution_id es requerido")
+	}
+	return nil
+}
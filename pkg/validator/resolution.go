@@ -2,6 +2,7 @@ package validator
 
 import (
 	"apidian-go/internal/domain"
+	"strings"
 	"time"
 )
 
@@ -68,5 +69,30 @@ func ValidateCreateResolution(req *domain.CreateResolutionRequest) error {
 		return NewError("date_from", "debe ser menor o igual a date_to")
 	}
 
+	// Continuación y umbrales de alerta (opcionales)
+	if req.FollowsResolutionID != nil && *req.FollowsResolutionID <= 0 {
+		return NewError("follows_resolution_id", "debe ser mayor a 0")
+	}
+	if req.AlertRemaining != nil && *req.AlertRemaining < 0 {
+		return NewError("alert_remaining", "no puede ser negativo")
+	}
+	if req.AlertDays != nil && *req.AlertDays < 0 {
+		return NewError("alert_days", "no puede ser negativo")
+	}
+
+	return nil
+}
+
+// ValidateAnnulNumber valida la solicitud de anulación de un consecutivo
+func ValidateAnnulNumber(req *domain.AnnulNumberRequest) error {
+	if req.Consecutive <= 0 {
+		return NewError("consecutive", "debe ser mayor a 0")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return NewError("reason", "es requerido")
+	}
+	if len(req.Reason) > 500 {
+		return NewError("reason", "debe tener máximo 500 caracteres")
+	}
 	return nil
 }
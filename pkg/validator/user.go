@@ -50,6 +50,15 @@ func ValidateLogin(req *domain.LoginRequest) error {
 	return nil
 }
 
+// ValidateRefreshToken valida la solicitud de renovación del access token
+func ValidateRefreshToken(req *domain.RefreshTokenRequest) error {
+	if req.RefreshToken == "" {
+		return fmt.Errorf("el refresh token es requerido")
+	}
+
+	return nil
+}
+
 // ValidateUpdateUser valida la solicitud de actualización de usuario
 func ValidateUpdateUser(req *domain.UpdateUserRequest) error {
 	if req.Name != nil {
@@ -76,6 +85,11 @@ func ValidateUpdateUser(req *domain.UpdateUserRequest) error {
 	return nil
 }
 
+// ValidateUpdateProfile valida la solicitud de actualización del perfil propio
+func ValidateUpdateProfile(req *domain.UpdateProfileRequest) error {
+	return ValidateUpdateUser(&domain.UpdateUserRequest{Name: req.Name, Email: req.Email})
+}
+
 // ValidateChangePassword valida la solicitud de cambio de contraseña
 func ValidateChangePassword(req *domain.ChangePasswordRequest) error {
 	if req.CurrentPassword == "" {
@@ -95,3 +109,91 @@ func ValidateChangePassword(req *domain.ChangePasswordRequest) error {
 
 	return nil
 }
+
+// ValidateVerifyEmail valida la solicitud de verificación de email
+func ValidateVerifyEmail(req *domain.VerifyEmailRequest) error {
+	if req.Token == "" {
+		return fmt.Errorf("el token es requerido")
+	}
+
+	return nil
+}
+
+// ValidateUnlockAccount valida la solicitud de desbloqueo de cuenta
+func ValidateUnlockAccount(req *domain.UnlockAccountRequest) error {
+	if req.Token == "" {
+		return fmt.Errorf("el token es requerido")
+	}
+
+	return nil
+}
+
+// ValidateEmailRequest valida las solicitudes que solo llevan un email
+func ValidateEmailRequest(req *domain.EmailRequest) error {
+	if req.Email == "" {
+		return fmt.Errorf("el email es requerido")
+	}
+	if !IsValidEmail(req.Email) {
+		return fmt.Errorf("el email no es válido")
+	}
+
+	return nil
+}
+
+// ValidateResetPassword valida la solicitud de restablecimiento de contraseña
+func ValidateResetPassword(req *domain.ResetPasswordRequest) error {
+	if req.Token == "" {
+		return fmt.Errorf("el token es requerido")
+	}
+
+	if req.NewPassword == "" {
+		return fmt.Errorf("la nueva contraseña es requerida")
+	}
+	if len(req.NewPassword) < 8 {
+		return fmt.Errorf("la nueva contraseña debe tener al menos 8 caracteres")
+	}
+
+	return nil
+}
+
+// ValidateTwoFactorLogin valida el segundo paso del login
+func ValidateTwoFactorLogin(req *domain.TwoFactorLoginRequest) error {
+	if req.ChallengeToken == "" {
+		return fmt.Errorf("el challenge_token es requerido")
+	}
+	if req.Code == "" {
+		return fmt.Errorf("el código es requerido")
+	}
+
+	return nil
+}
+
+// ValidateTwoFactorSetup valida la solicitud de inscripción en 2FA
+func ValidateTwoFactorSetup(req *domain.TwoFactorSetupRequest) error {
+	if req.Password == "" {
+		return fmt.Errorf("la contraseña es requerida")
+	}
+
+	return nil
+}
+
+// ValidateTwoFactorCode valida las solicitudes que solo llevan un código 2FA
+func ValidateTwoFactorCode(req *domain.TwoFactorCodeRequest) error {
+	if req.Code == "" {
+		return fmt.Errorf("el código es requerido")
+	}
+
+	return nil
+}
+
+// ValidateDisableTwoFactor valida la solicitud para desactivar 2FA
+func ValidateDisableTwoFactor(req *domain.DisableTwoFactorRequest) error {
+	if req.Password == "" {
+		return fmt.Errorf("la contraseña es requerida")
+	}
+	if req.Code == "" {
+		return fmt.Errorf("el código es requerido")
+	}
+
+	return nil
+}
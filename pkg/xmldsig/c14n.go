@@ -0,0 +1,211 @@
+package xmldsig
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Canonicalize serializa el elemento con Canonical XML 1.0 inclusivo sin comentarios
+// (http://www.w3.org/TR/2001/REC-xml-c14n-20010315), el algoritmo que usa DIAN.
+// Si el elemento no es la raíz se trata como subconjunto del documento: se incluyen los
+// espacios de nombres y atributos xml:* heredados de sus ancestros.
+// skip (opcional) se omite de la salida; sirve para la transformación enveloped-signature.
+func Canonicalize(el *Element, skip *Element) []byte {
+	var buf bytes.Buffer
+
+	// Espacios de nombres en alcance en el ápice (ancestros + propios, el más cercano gana)
+	inScope := map[string]string{}
+	var chain []*Element
+	for a := el; a != nil; a = a.Parent {
+		chain = append(chain, a)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, a := range chain[i].Attrs {
+			if prefix, ok := namespaceDecl(a); ok {
+				inScope[prefix] = a.Value
+			}
+		}
+	}
+
+	// Atributos xml:* heredados (xml:lang, xml:space) que el ápice no redefine
+	var inherited []Attr
+	if el.Parent != nil {
+		seen := map[string]bool{}
+		for _, a := range el.Attrs {
+			if a.Prefix == "xml" {
+				seen[a.Local] = true
+			}
+		}
+		for a := el.Parent; a != nil; a = a.Parent {
+			for _, attr := range a.Attrs {
+				if attr.Prefix == "xml" && !seen[attr.Local] {
+					seen[attr.Local] = true
+					inherited = append(inherited, attr)
+				}
+			}
+		}
+	}
+
+	// En el ápice se declaran todos los espacios de nombres en alcance
+	rendered := map[string]string{}
+	writeElement(&buf, el, skip, inScope, rendered, inherited, true)
+	return buf.Bytes()
+}
+
+func writeElement(buf *bytes.Buffer, el, skip *Element, inScope, rendered map[string]string, extraAttrs []Attr, apex bool) {
+	// Espacios de nombres en alcance de este elemento
+	scope := inScope
+	if !apex {
+		scope = copyMap(inScope)
+		for _, a := range el.Attrs {
+			if prefix, ok := namespaceDecl(a); ok {
+				scope[prefix] = a.Value
+			}
+		}
+	}
+
+	// Declaraciones a emitir: las que difieren de lo ya emitido por el ancestro de salida
+	var decls []Attr
+	for prefix, uri := range scope {
+		if prefix == "" {
+			if uri == rendered[""] {
+				continue
+			}
+			decls = append(decls, Attr{Local: "xmlns", Value: uri})
+			continue
+		}
+		if uri == "" {
+			continue // xmlns:p="" no es válido en XML 1.0
+		}
+		if current, ok := rendered[prefix]; ok && current == uri {
+			continue
+		}
+		decls = append(decls, Attr{Prefix: "xmlns", Local: prefix, Value: uri})
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		// xmlns por defecto primero, luego por prefijo
+		if decls[i].Prefix == "" {
+			return true
+		}
+		if decls[j].Prefix == "" {
+			return false
+		}
+		return decls[i].Local < decls[j].Local
+	})
+
+	childRendered := rendered
+	if len(decls) > 0 {
+		childRendered = copyMap(rendered)
+		for _, d := range decls {
+			if d.Prefix == "" {
+				childRendered[""] = d.Value
+			} else {
+				childRendered[d.Local] = d.Value
+			}
+		}
+	}
+
+	// Atributos ordenados por (URI del espacio de nombres, nombre local)
+	type sortedAttr struct {
+		attr Attr
+		uri  string
+	}
+	var attrs []sortedAttr
+	for _, a := range append(append([]Attr{}, el.Attrs...), extraAttrs...) {
+		if _, ok := namespaceDecl(a); ok {
+			continue
+		}
+		uri := ""
+		if a.Prefix == "xml" {
+			uri = xmlNamespace
+		} else if a.Prefix != "" {
+			uri = scope[a.Prefix]
+		}
+		attrs = append(attrs, sortedAttr{attr: a, uri: uri})
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].uri != attrs[j].uri {
+			return attrs[i].uri < attrs[j].uri
+		}
+		return attrs[i].attr.Local < attrs[j].attr.Local
+	})
+
+	name := qualifiedName(el.Prefix, el.Local)
+	buf.WriteByte('<')
+	buf.WriteString(name)
+	for _, d := range decls {
+		buf.WriteByte(' ')
+		buf.WriteString(qualifiedName(d.Prefix, d.Local))
+		buf.WriteString(`="`)
+		buf.WriteString(escapeAttr(d.Value))
+		buf.WriteByte('"')
+	}
+	for _, a := range attrs {
+		buf.WriteByte(' ')
+		buf.WriteString(qualifiedName(a.attr.Prefix, a.attr.Local))
+		buf.WriteString(`="`)
+		buf.WriteString(escapeAttr(a.attr.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	for _, child := range el.Children {
+		switch c := child.(type) {
+		case *Element:
+			if c == skip {
+				continue
+			}
+			writeElement(buf, c, skip, scope, childRendered, nil, false)
+		case Text:
+			buf.WriteString(escapeText(string(c)))
+		case ProcInst:
+			buf.WriteString("<?")
+			buf.WriteString(c.Target)
+			if c.Inst != "" {
+				buf.WriteByte(' ')
+				buf.WriteString(c.Inst)
+			}
+			buf.WriteString("?>")
+		}
+	}
+
+	buf.WriteString("</")
+	buf.WriteString(name)
+	buf.WriteByte('>')
+}
+
+// namespaceDecl indica si el atributo es una declaración xmlns y retorna su prefijo ("" = por defecto)
+func namespaceDecl(a Attr) (string, bool) {
+	if a.Prefix == "" && a.Local == "xmlns" {
+		return "", true
+	}
+	if a.Prefix == "xmlns" {
+		return a.Local, true
+	}
+	return "", false
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
+
+func copyMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m)+2)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
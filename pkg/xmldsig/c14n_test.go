@@ -0,0 +1,88 @@
+package xmldsig
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/c14n_expected.xml se generó con `xmllint --c14n testdata/c14n_input.xml` (libxml2),
+// una implementación independiente de Canonical XML 1.0.
+func TestCanonicalizeMatchesLibxml2(t *testing.T) {
+	input, err := os.ReadFile("testdata/c14n_input.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("testdata/c14n_expected.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := string(Canonicalize(root, nil)); got != string(expected) {
+		t.Errorf("canonical form differs from libxml2\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestCanonicalizeSubset(t *testing.T) {
+	input, err := os.ReadFile("testdata/c14n_input.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		el   *Element
+		want string
+	}{
+		{
+			// El ápice declara todos los espacios de nombres heredados, ordenados
+			name: "inherits namespaces",
+			el:   root.Child("LegalMonetaryTotal"),
+			want: `<cac:LegalMonetaryTotal xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" ` +
+				`xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" ` +
+				`xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" ` +
+				`xmlns:unused="urn:example:unused">` + "\n    " +
+				`<cbc:PayableAmount a="1" b="2" currencyID="COP">1190.00</cbc:PayableAmount>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Canonicalize(tt.el, nil)); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Canonicalize =\n%s\nwant prefix\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeSubsetInheritsXMLAttributes(t *testing.T) {
+	root, err := Parse([]byte(`<a xmlns="urn:a" xml:lang="es" xml:space="preserve"><b xml:space="default"><c>x</c></b></a>`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	got := string(Canonicalize(root.Child("b"), nil))
+	if want := `<b xmlns="urn:a" xml:lang="es" xml:space="default"><c>x</c></b>`; got != want {
+		t.Errorf("Canonicalize = %s, want %s", got, want)
+	}
+}
+
+func TestCanonicalizeSkip(t *testing.T) {
+	root, err := Parse([]byte(`<a xmlns="urn:a"><b>1</b><c><d/></c><e>2</e></a>`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	got := string(Canonicalize(root, root.Child("c")))
+	if want := `<a xmlns="urn:a"><b>1</b><e>2</e></a>`; got != want {
+		t.Errorf("Canonicalize with skip = %s, want %s", got, want)
+	}
+}
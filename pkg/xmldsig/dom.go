@@ -0,0 +1,346 @@
+package xmldsig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Element es un nodo de un árbol XML mínimo que conserva los prefijos y las declaraciones
+// xmlns tal como vienen en el documento, necesario para canonicalizar (C14N) sin alterar
+// los digest de la firma.
+type Element struct {
+	Prefix   string
+	Local    string
+	Attrs    []Attr
+	Children []Node
+	Parent   *Element
+}
+
+// Attr es un atributo sin resolver (prefijo crudo); incluye las declaraciones xmlns
+type Attr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+// Node es un hijo de un elemento: *Element, Text o ProcInst
+type Node interface{}
+
+// Text es contenido de texto (ya sin entidades)
+type Text string
+
+// ProcInst es una instrucción de procesamiento dentro del elemento raíz
+type ProcInst struct {
+	Target string
+	Inst   string
+}
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Parse construye el árbol del documento y retorna el elemento raíz. Los comentarios se
+// descartan (C14N sin comentarios) y se ignora lo que esté fuera del elemento raíz.
+func Parse(data []byte) (*Element, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+
+	var root, current *Element
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &Element{Prefix: t.Name.Space, Local: t.Name.Local, Parent: current}
+			raw := rawAttrValues(data[start:dec.InputOffset()])
+			for i, a := range t.Attr {
+				value := a.Value
+				if len(raw) == len(t.Attr) {
+					value = normalizeAttrValue(raw[i])
+				}
+				el.Attrs = append(el.Attrs, Attr{Prefix: a.Name.Space, Local: a.Name.Local, Value: value})
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("invalid XML: multiple root elements")
+				}
+				root = el
+			} else {
+				current.Children = append(current.Children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || current.Prefix != t.Name.Space || current.Local != t.Name.Local {
+				return nil, fmt.Errorf("invalid XML: unexpected end element %s", t.Name.Local)
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.Children = append(current.Children, Text(string(t)))
+			}
+		case xml.ProcInst:
+			if current != nil {
+				current.Children = append(current.Children, ProcInst{Target: t.Target, Inst: string(t.Inst)})
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("invalid XML: no root element")
+	}
+	if current != nil {
+		return nil, errors.New("invalid XML: unclosed elements")
+	}
+	return root, nil
+}
+
+// normalizeAttrValue aplica la normalización de valores de atributo de XML 1.0 sobre el valor
+// crudo: los saltos de línea y tabulaciones literales se convierten en espacios, pero los que
+// vienen de referencias de carácter (&#9;, &#xA;, &#xD;) se conservan, como exige C14N.
+func normalizeAttrValue(raw string) string {
+	raw = strings.ReplaceAll(raw, "\r\n", " ")
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case '\t', '\n', '\r':
+			b.WriteByte(' ')
+		case '&':
+			end := strings.IndexByte(raw[i:], ';')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(decodeReference(raw[i+1 : i+end]))
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// decodeReference resuelve una entidad predefinida o una referencia de carácter (sin & ni ;)
+func decodeReference(ref string) string {
+	switch ref {
+	case "lt":
+		return "<"
+	case "gt":
+		return ">"
+	case "amp":
+		return "&"
+	case "quot":
+		return `"`
+	case "apos":
+		return "'"
+	}
+	if strings.HasPrefix(ref, "#x") {
+		if n, err := strconv.ParseUint(ref[2:], 16, 32); err == nil {
+			return string(rune(n))
+		}
+	} else if strings.HasPrefix(ref, "#") {
+		if n, err := strconv.ParseUint(ref[1:], 10, 32); err == nil {
+			return string(rune(n))
+		}
+	}
+	return "&" + ref + ";"
+}
+
+// rawAttrValues extrae, en orden, los valores sin decodificar de los atributos de una etiqueta
+// de inicio. encoding/xml entrega los valores ya decodificados, lo que impide distinguir una
+// tabulación literal de &#9; al normalizar.
+func rawAttrValues(tag []byte) []string {
+	var values []string
+	i := bytes.IndexAny(tag, " \t\r\n")
+	if i < 0 {
+		return nil
+	}
+	for i < len(tag) {
+		// Separador '=' y comilla de apertura del siguiente atributo
+		eq := bytes.IndexByte(tag[i:], '=')
+		if eq < 0 {
+			break
+		}
+		i += eq + 1
+		for i < len(tag) && (tag[i] == ' ' || tag[i] == '\t' || tag[i] == '\r' || tag[i] == '\n') {
+			i++
+		}
+		if i >= len(tag) || (tag[i] != '"' && tag[i] != '\'') {
+			return nil
+		}
+		quote := tag[i]
+		end := bytes.IndexByte(tag[i+1:], quote)
+		if end < 0 {
+			return nil
+		}
+		values = append(values, string(tag[i+1:i+1+end]))
+		i += end + 2
+	}
+	return values
+}
+
+// LookupNamespace resuelve un prefijo ("" = espacio de nombres por defecto) en el contexto del elemento
+func (e *Element) LookupNamespace(prefix string) string {
+	if prefix == "xml" {
+		return xmlNamespace
+	}
+	for el := e; el != nil; el = el.Parent {
+		for _, a := range el.Attrs {
+			if prefix == "" && a.Prefix == "" && a.Local == "xmlns" {
+				return a.Value
+			}
+			if prefix != "" && a.Prefix == "xmlns" && a.Local == prefix {
+				return a.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Namespace retorna el URI del espacio de nombres del elemento
+func (e *Element) Namespace() string {
+	return e.LookupNamespace(e.Prefix)
+}
+
+// Is indica si el elemento tiene el nombre local y espacio de nombres dados ("" = cualquiera)
+func (e *Element) Is(namespace, local string) bool {
+	return e.Local == local && (namespace == "" || e.Namespace() == namespace)
+}
+
+// Attr retorna el valor del atributo sin prefijo con ese nombre local
+func (e *Element) Attr(local string) string {
+	for _, a := range e.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Text retorna el texto directo del elemento, sin espacios al inicio ni al final
+func (e *Element) Text() string {
+	var b strings.Builder
+	for _, child := range e.Children {
+		if t, ok := child.(Text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ChildElements retorna los hijos de tipo elemento
+func (e *Element) ChildElements() []*Element {
+	var out []*Element
+	for _, child := range e.Children {
+		if el, ok := child.(*Element); ok {
+			out = append(out, el)
+		}
+	}
+	return out
+}
+
+// Child retorna el primer hijo directo con el nombre local dado
+func (e *Element) Child(local string) *Element {
+	for _, el := range e.ChildElements() {
+		if el.Local == local {
+			return el
+		}
+	}
+	return nil
+}
+
+// Path recorre hijos directos por nombre local (p. ej. "LegalMonetaryTotal", "PayableAmount")
+func (e *Element) Path(locals ...string) *Element {
+	el := e
+	for _, local := range locals {
+		if el = el.Child(local); el == nil {
+			return nil
+		}
+	}
+	return el
+}
+
+// XPath retorna la ruta absoluta del elemento con los prefijos del documento y la posición
+// entre hermanos del mismo nombre cuando hay varios (p. ej. /Invoice/cac:InvoiceLine[2]/cbc:ID)
+func (e *Element) XPath() string {
+	step := qualifiedName(e.Prefix, e.Local)
+	if e.Parent == nil {
+		return "/" + step
+	}
+
+	count, position := 0, 0
+	for _, sibling := range e.Parent.ChildElements() {
+		if sibling.Prefix == e.Prefix && sibling.Local == e.Local {
+			count++
+			if sibling == e {
+				position = count
+			}
+		}
+	}
+	if count > 1 {
+		step += fmt.Sprintf("[%d]", position)
+	}
+	return e.Parent.XPath() + "/" + step
+}
+
+// FindAll retorna todos los descendientes (incluido el propio elemento) que cumplen Is
+func (e *Element) FindAll(namespace, local string) []*Element {
+	var out []*Element
+	var walk func(*Element)
+	walk = func(el *Element) {
+		if el.Is(namespace, local) {
+			out = append(out, el)
+		}
+		for _, child := range el.ChildElements() {
+			walk(child)
+		}
+	}
+	walk(e)
+	return out
+}
+
+// Find retorna el primer descendiente que cumple Is, o nil
+func (e *Element) Find(namespace, local string) *Element {
+	if all := e.FindAll(namespace, local); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+// FindByID busca el elemento cuyo atributo Id, ID o id tiene el valor dado. Retorna nil si
+// ninguno o más de uno lo tienen: con un Id duplicado la referencia firmada podría resolverse
+// a un nodo distinto del que procesa quien lee el documento.
+func (e *Element) FindByID(id string) *Element {
+	if all := e.FindAllByID(id); len(all) == 1 {
+		return all[0]
+	}
+	return nil
+}
+
+// FindAllByID retorna todos los elementos cuyo atributo Id, ID o id tiene el valor dado
+func (e *Element) FindAllByID(id string) []*Element {
+	var out []*Element
+	var walk func(*Element)
+	walk = func(el *Element) {
+		for _, name := range []string{"Id", "ID", "id"} {
+			if el.Attr(name) == id {
+				out = append(out, el)
+				break
+			}
+		}
+		for _, child := range el.ChildElements() {
+			walk(child)
+		}
+	}
+	walk(e)
+	return out
+}
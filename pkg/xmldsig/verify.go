@@ -0,0 +1,455 @@
+package xmldsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Espacios de nombres de XMLDSig y XAdES
+const (
+	NamespaceDSig   = "http://www.w3.org/2000/09/xmldsig#"
+	NamespaceXAdES  = "http://uri.etsi.org/01903/v1.3.2#"
+	NamespaceXAdES4 = "http://uri.etsi.org/01903/v1.4.1#"
+	// NamespaceUBLExtensions es el de ext:UBLExtensions, donde DIAN ubica la firma
+	NamespaceUBLExtensions = "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
+)
+
+// Algoritmos soportados
+const (
+	algC14N         = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+	algC14NComments = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments"
+	algEnvelopedSig = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+)
+
+var digestAlgorithms = map[string]crypto.Hash{
+	"http://www.w3.org/2000/09/xmldsig#sha1":        crypto.SHA1,
+	"http://www.w3.org/2001/04/xmlenc#sha256":       crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmlenc#sha512":       crypto.SHA512,
+}
+
+var signatureAlgorithms = map[string]crypto.Hash{
+	"http://www.w3.org/2000/09/xmldsig#rsa-sha1":          crypto.SHA1,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha384":   crypto.SHA384,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   crypto.SHA512,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512": crypto.SHA512,
+}
+
+// ErrNoSignature se retorna cuando el documento no tiene ds:Signature
+var ErrNoSignature = errors.New("document has no XML signature")
+
+// ReferenceResult es el resultado de comprobar el digest de una ds:Reference
+type ReferenceResult struct {
+	URI      string `json:"uri"`
+	Type     string `json:"type,omitempty"`
+	Expected string `json:"expected_digest"`
+	Computed string `json:"computed_digest,omitempty"`
+	Valid    bool   `json:"valid"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result resume la verificación de una firma XAdES
+type Result struct {
+	SignatureMethod string            `json:"signature_method"`
+	SignatureValid  bool              `json:"signature_valid"`
+	References      []ReferenceResult `json:"references"`
+	DigestsValid    bool              `json:"digests_valid"`
+	// DocumentCovered indica que una referencia URI="" con transformación enveloped-signature
+	// cubre el documento completo; sin ella la firma puede cubrir solo sus propias propiedades
+	DocumentCovered bool `json:"document_covered"`
+	// XAdES
+	SigningTime             *time.Time `json:"signing_time,omitempty"`
+	SigningCertificateValid bool       `json:"signing_certificate_valid"` // CertDigest coincide con el certificado de KeyInfo
+	SignedPropertiesCovered bool       `json:"signed_properties_covered"` // Una referencia válida apunta al Id de SignedProperties
+	PolicyIdentifier        string     `json:"policy_identifier,omitempty"`
+	// Certificado del firmante (KeyInfo) y su vigencia al momento de la firma
+	Certificate               *x509.Certificate `json:"-"`
+	CertificateValidAtSigning *bool             `json:"certificate_valid_at_signing,omitempty"`
+	Errors                    []string          `json:"errors,omitempty"`
+}
+
+// Valid indica si firma, digests y propiedades firmadas son consistentes y cubren el documento.
+// Sin SigningTime no se puede comprobar la vigencia del certificado, así que la firma no es válida.
+func (r *Result) Valid() bool {
+	return r.SignatureValid && r.DigestsValid && r.DocumentCovered &&
+		r.SignedPropertiesCovered && r.SigningCertificateValid &&
+		r.CertificateValidAtSigning != nil && *r.CertificateValidAtSigning
+}
+
+func (r *Result) addError(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// Verify comprueba la firma XAdES envuelta (enveloped) del documento: digest de cada
+// referencia, que una de ellas cubra el documento completo, SignatureValue con la llave pública
+// del certificado en KeyInfo, CertDigest de las propiedades firmadas y vigencia del certificado
+// en SigningTime. Solo retorna error si el documento no tiene firma o su estructura no permite
+// verificarla; las discrepancias se reportan en Result.
+func Verify(root *Element) (*Result, error) {
+	sig, err := findEnvelopedSignature(root)
+	if err != nil {
+		return nil, err
+	}
+	signedInfo := findChild(sig, NamespaceDSig, "SignedInfo")
+	if signedInfo == nil {
+		return nil, errors.New("signature has no SignedInfo")
+	}
+
+	result := &Result{}
+
+	// Certificado del firmante (KeyInfo/X509Data/X509Certificate)
+	var certEl *Element
+	if keyInfo := findChild(sig, NamespaceDSig, "KeyInfo"); keyInfo != nil {
+		if x509Data := findChild(keyInfo, NamespaceDSig, "X509Data"); x509Data != nil {
+			certEl = findChild(x509Data, NamespaceDSig, "X509Certificate")
+		}
+	}
+	if certEl != nil {
+		der, err := base64.StdEncoding.DecodeString(compactBase64(certEl.Text()))
+		if err == nil {
+			result.Certificate, err = x509.ParseCertificate(der)
+		}
+		if err != nil {
+			result.addError("invalid X509Certificate in KeyInfo: %v", err)
+		}
+	} else {
+		result.addError("signature has no X509Certificate in KeyInfo")
+	}
+
+	// Canonicalización de SignedInfo
+	c14nMethod := findChild(signedInfo, NamespaceDSig, "CanonicalizationMethod")
+	if c14nMethod == nil || !isSupportedC14N(c14nMethod.Attr("Algorithm")) {
+		return nil, fmt.Errorf("unsupported canonicalization method")
+	}
+
+	// Referencias
+	result.DigestsValid = true
+	for _, ref := range signedInfo.ChildElements() {
+		if !ref.Is(NamespaceDSig, "Reference") {
+			continue
+		}
+		rr := verifyReference(root, sig, ref)
+		if !rr.Valid {
+			result.DigestsValid = false
+			result.addError("reference %q: %s", rr.URI, rr.Error)
+		}
+		if rr.Valid && rr.URI == "" && hasTransform(ref, algEnvelopedSig) {
+			result.DocumentCovered = true
+		}
+		result.References = append(result.References, rr)
+	}
+	if len(result.References) == 0 {
+		result.DigestsValid = false
+		result.addError("signature has no references")
+	}
+	if !result.DocumentCovered {
+		result.addError("signature has no reference covering the whole document (URI=\"\" with enveloped-signature transform)")
+	}
+
+	// SignatureValue
+	sigMethod := findChild(signedInfo, NamespaceDSig, "SignatureMethod")
+	if sigMethod != nil {
+		result.SignatureMethod = sigMethod.Attr("Algorithm")
+	}
+	sigValueEl := findChild(sig, NamespaceDSig, "SignatureValue")
+	switch {
+	case sigValueEl == nil:
+		result.addError("signature has no SignatureValue")
+	case result.Certificate == nil:
+		result.addError("cannot verify SignatureValue without the signer certificate")
+	default:
+		sigValue, err := base64.StdEncoding.DecodeString(compactBase64(sigValueEl.Text()))
+		if err != nil {
+			result.addError("invalid SignatureValue encoding: %v", err)
+			break
+		}
+		if err := verifySignatureValue(result.Certificate, result.SignatureMethod, Canonicalize(signedInfo, nil), sigValue); err != nil {
+			result.addError("SignatureValue does not match: %v", err)
+		} else {
+			result.SignatureValid = true
+		}
+	}
+
+	verifyXAdES(sig, result)
+
+	return result, nil
+}
+
+func verifyReference(root, sig, ref *Element) ReferenceResult {
+	rr := ReferenceResult{URI: ref.Attr("URI"), Type: ref.Attr("Type")}
+	if dv := findChild(ref, NamespaceDSig, "DigestValue"); dv != nil {
+		rr.Expected = compactBase64(dv.Text())
+	}
+
+	method := findChild(ref, NamespaceDSig, "DigestMethod")
+	if method == nil {
+		rr.Error = "missing DigestMethod"
+		return rr
+	}
+	hash, ok := digestAlgorithms[method.Attr("Algorithm")]
+	if !ok {
+		rr.Error = "unsupported digest method " + method.Attr("Algorithm")
+		return rr
+	}
+
+	// Nodo referenciado: "" es el documento completo, "#id" un elemento con ese Id
+	var target *Element
+	switch {
+	case rr.URI == "":
+		target = root
+	case strings.HasPrefix(rr.URI, "#"):
+		matches := root.FindAllByID(strings.TrimPrefix(rr.URI, "#"))
+		if len(matches) > 1 {
+			rr.Error = "referenced Id is not unique in the document"
+			return rr
+		}
+		if len(matches) == 1 {
+			target = matches[0]
+		}
+	default:
+		rr.Error = "external references are not supported"
+		return rr
+	}
+	if target == nil {
+		rr.Error = "referenced element not found"
+		return rr
+	}
+
+	var skip *Element
+	if transforms := findChild(ref, NamespaceDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.ChildElements() {
+			switch alg := t.Attr("Algorithm"); {
+			case alg == algEnvelopedSig:
+				skip = sig
+			case isSupportedC14N(alg):
+			default:
+				rr.Error = "unsupported transform " + alg
+				return rr
+			}
+		}
+	}
+
+	h := hash.New()
+	h.Write(Canonicalize(target, skip))
+	rr.Computed = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	rr.Valid = rr.Computed == rr.Expected
+	if !rr.Valid {
+		rr.Error = "digest mismatch: the signed content was modified"
+	}
+	return rr
+}
+
+func verifySignatureValue(cert *x509.Certificate, method string, signedInfo, sigValue []byte) error {
+	hash, ok := signatureAlgorithms[method]
+	if !ok {
+		return fmt.Errorf("unsupported signature method %s", method)
+	}
+	h := hash.New()
+	h.Write(signedInfo)
+	digest := h.Sum(nil)
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, hash, digest, sigValue)
+	case *ecdsa.PublicKey:
+		// XMLDSig codifica la firma ECDSA como r || s
+		half := len(sigValue) / 2
+		r := new(big.Int).SetBytes(sigValue[:half])
+		s := new(big.Int).SetBytes(sigValue[half:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("ecdsa verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+}
+
+// verifyXAdES revisa SigningTime, SigningCertificate (CertDigest) y la política de firma
+func verifyXAdES(sig *Element, result *Result) {
+	signedProps := findXAdES(sig, "SignedProperties")
+	if signedProps == nil {
+		result.addError("signature has no XAdES SignedProperties")
+		return
+	}
+
+	// Una referencia válida al Id de SignedProperties debe existir para que estén cubiertas por la firma
+	if id := signedProps.Attr("Id"); id != "" {
+		for _, rr := range result.References {
+			if rr.Valid && rr.URI == "#"+id {
+				result.SignedPropertiesCovered = true
+			}
+		}
+	}
+	if !result.SignedPropertiesCovered {
+		result.addError("XAdES SignedProperties are not referenced by the signature")
+	}
+
+	if st := findXAdES(signedProps, "SigningTime"); st != nil {
+		if t, err := time.Parse(time.RFC3339Nano, st.Text()); err == nil {
+			result.SigningTime = &t
+		} else {
+			result.addError("invalid SigningTime %q", st.Text())
+		}
+	} else {
+		result.addError("XAdES SigningTime is missing")
+	}
+
+	if id := findXAdES(signedProps, "SigPolicyId"); id != nil {
+		if identifier := findXAdES(id, "Identifier"); identifier != nil {
+			result.PolicyIdentifier = identifier.Text()
+		}
+	}
+
+	// CertDigest: alguno de los certificados listados debe ser el de KeyInfo
+	if result.Certificate != nil {
+		for _, certDigest := range findAllXAdES(signedProps, "CertDigest") {
+			method := findChild(certDigest, NamespaceDSig, "DigestMethod")
+			value := findChild(certDigest, NamespaceDSig, "DigestValue")
+			if method == nil || value == nil {
+				continue
+			}
+			hash, ok := digestAlgorithms[method.Attr("Algorithm")]
+			if !ok {
+				continue
+			}
+			if base64.StdEncoding.EncodeToString(hashBytes(hash, result.Certificate.Raw)) == compactBase64(value.Text()) {
+				result.SigningCertificateValid = true
+				break
+			}
+		}
+		if !result.SigningCertificateValid {
+			result.addError("XAdES SigningCertificate does not match the KeyInfo certificate")
+		}
+
+		if result.SigningTime == nil {
+			result.addError("cannot check certificate validity without a valid SigningTime")
+		} else {
+			valid := !result.SigningTime.Before(result.Certificate.NotBefore) && !result.SigningTime.After(result.Certificate.NotAfter)
+			result.CertificateValidAtSigning = &valid
+			if !valid {
+				result.addError("certificate was not valid at signing time (valid %s to %s)",
+					result.Certificate.NotBefore.Format(time.RFC3339), result.Certificate.NotAfter.Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+// findEnvelopedSignature ubica la ds:Signature como hijo directo de
+// UBLExtensions/UBLExtension/ExtensionContent del elemento raíz, donde la exige el anexo técnico
+// de DIAN. Una firma en otra posición, o más de una, no se acepta.
+func findEnvelopedSignature(root *Element) (*Element, error) {
+	var found []*Element
+	if extensions := findChild(root, NamespaceUBLExtensions, "UBLExtensions"); extensions != nil {
+		for _, ext := range extensions.ChildElements() {
+			if !ext.Is(NamespaceUBLExtensions, "UBLExtension") {
+				continue
+			}
+			content := findChild(ext, NamespaceUBLExtensions, "ExtensionContent")
+			if content == nil {
+				continue
+			}
+			for _, child := range content.ChildElements() {
+				if child.Is(NamespaceDSig, "Signature") {
+					found = append(found, child)
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case len(found) > 1:
+		return nil, errors.New("document has more than one signature in UBLExtensions")
+	case root.Find(NamespaceDSig, "Signature") != nil:
+		return nil, errors.New("signature is not a direct child of UBLExtensions/UBLExtension/ExtensionContent")
+	default:
+		return nil, ErrNoSignature
+	}
+}
+
+func hasTransform(ref *Element, algorithm string) bool {
+	transforms := findChild(ref, NamespaceDSig, "Transforms")
+	if transforms == nil {
+		return false
+	}
+	for _, t := range transforms.ChildElements() {
+		if t.Is(NamespaceDSig, "Transform") && t.Attr("Algorithm") == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+func findChild(el *Element, namespace, local string) *Element {
+	for _, child := range el.ChildElements() {
+		if child.Is(namespace, local) {
+			return child
+		}
+	}
+	return nil
+}
+
+// findXAdES busca un elemento XAdES en cualquiera de las versiones del espacio de nombres
+func findXAdES(el *Element, local string) *Element {
+	if all := findAllXAdES(el, local); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+func findAllXAdES(el *Element, local string) []*Element {
+	var out []*Element
+	for _, candidate := range el.FindAll("", local) {
+		if ns := candidate.Namespace(); ns == NamespaceXAdES || ns == NamespaceXAdES4 {
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
+
+func isSupportedC14N(alg string) bool {
+	return alg == algC14N || alg == algC14NComments
+}
+
+func hashBytes(hash crypto.Hash, data []byte) []byte {
+	switch hash {
+	case crypto.SHA1:
+		sum := sha1.Sum(data)
+		return sum[:]
+	case crypto.SHA256:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	default:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+}
+
+// compactBase64 elimina los saltos de línea y espacios que algunos firmadores insertan
+func compactBase64(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,413 @@
+package xmldsig
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSigID          = "xmldsig-test"
+	testKeyInfoID      = testSigID + "-keyinfo"
+	testSignedPropsID  = testSigID + "-signedprops"
+	testPolicy         = "https://facturaelectronica.dian.gov.co/politicadefirma/v2/politicadefirmav2.pdf"
+	testSigningTime    = "2026-02-05T22:47:18-05:00"
+	testSignaturePlace = "{{SIGNATURE}}"
+)
+
+// testInvoice reproduce la estructura que genera ubl21-dian: DianExtensions en la primera
+// UBLExtension y la firma en la segunda
+const testInvoice = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+  xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+  xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+  xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
+  xmlns:sts="dian:gov:co:facturaelectronica:Structures-2-1"
+  xmlns:xades="http://uri.etsi.org/01903/v1.3.2#"
+  xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>
+        <sts:DianExtensions>
+          <sts:InvoiceControl><sts:InvoiceAuthorization>18760000001</sts:InvoiceAuthorization></sts:InvoiceControl>
+        </sts:DianExtensions>
+      </ext:ExtensionContent>
+    </ext:UBLExtension>
+    <ext:UBLExtension>
+      <ext:ExtensionContent>{{SIGNATURE}}</ext:ExtensionContent>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>UBL 2.1</cbc:UBLVersionID>
+  <cbc:ID>SETP990000002</cbc:ID>
+  <cbc:UUID schemeName="CUFE-SHA384">0a1b2c</cbc:UUID>
+  <cbc:Note>Café &amp; té</cbc:Note>
+  <cac:LegalMonetaryTotal>
+    <cbc:PayableAmount currencyID="COP">1190.00</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+</Invoice>`
+
+type testSigner struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+var (
+	signerOnce sync.Once
+	signer     testSigner
+)
+
+// newTestSigner genera una vez un certificado autofirmado vigente de 2024 a 2030
+func newTestSigner(t *testing.T) testSigner {
+	t.Helper()
+	signerOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(6382356),
+			Subject:      pkix.Name{CommonName: "EMPRESA DE PRUEBAS SAS", Country: []string{"CO"}},
+			NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			NotAfter:     time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		if err != nil {
+			t.Fatalf("CreateCertificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("ParseCertificate: %v", err)
+		}
+		signer = testSigner{key: key, cert: cert}
+	})
+	if signer.key == nil {
+		t.Fatal("test signer not initialized")
+	}
+	return signer
+}
+
+// signOptions describe variaciones de la firma para los casos de ataque
+type signOptions struct {
+	skipDocumentReference bool   // sin referencia URI="" (solo KeyInfo y SignedProperties)
+	signingTime           string // "" usa testSigningTime; "-" omite SigningTime
+	outsideExtensions     bool   // firma como hija directa de Invoice
+}
+
+// sign firma testInvoice en dos pasadas: calcula los digest de las referencias sobre el
+// documento con la firma en su lugar y luego el SignatureValue sobre SignedInfo.
+func (s testSigner) sign(t *testing.T, opts signOptions) string {
+	t.Helper()
+
+	build := func(digests map[string]string, signatureValue string) string {
+		sig := s.signatureXML(opts, digests, signatureValue)
+		if opts.outsideExtensions {
+			doc := strings.Replace(testInvoice, testSignaturePlace, "", 1)
+			return strings.Replace(doc, "</Invoice>", sig+"</Invoice>", 1)
+		}
+		return strings.Replace(testInvoice, testSignaturePlace, sig, 1)
+	}
+
+	root := mustParse(t, build(nil, ""))
+	sig := root.Find(NamespaceDSig, "Signature")
+	digests := map[string]string{
+		"":                      digest(Canonicalize(root, sig)),
+		"#" + testKeyInfoID:     digest(Canonicalize(root.FindByID(testKeyInfoID), nil)),
+		"#" + testSignedPropsID: digest(Canonicalize(root.FindByID(testSignedPropsID), nil)),
+	}
+
+	root = mustParse(t, build(digests, ""))
+	signedInfo := root.Find(NamespaceDSig, "SignedInfo")
+	hashed := sha256.Sum256(Canonicalize(signedInfo, nil))
+	value, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15: %v", err)
+	}
+
+	return build(digests, base64.StdEncoding.EncodeToString(value))
+}
+
+func (s testSigner) signatureXML(opts signOptions, digests map[string]string, signatureValue string) string {
+	reference := func(uri, refType string, enveloped bool) string {
+		transforms := ""
+		if enveloped {
+			transforms = `<ds:Transforms><ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/></ds:Transforms>`
+		}
+		if refType != "" {
+			refType = ` Type="` + refType + `"`
+		}
+		return fmt.Sprintf(`<ds:Reference URI="%s"%s>%s<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><ds:DigestValue>%s</ds:DigestValue></ds:Reference>`,
+			uri, refType, transforms, digests[uri])
+	}
+
+	var refs strings.Builder
+	if !opts.skipDocumentReference {
+		refs.WriteString(reference("", "", true))
+	}
+	refs.WriteString(reference("#"+testKeyInfoID, "", false))
+	refs.WriteString(reference("#"+testSignedPropsID, "http://uri.etsi.org/01903#SignedProperties", false))
+
+	signingTime := ""
+	switch opts.signingTime {
+	case "":
+		signingTime = "<xades:SigningTime>" + testSigningTime + "</xades:SigningTime>"
+	case "-":
+	default:
+		signingTime = "<xades:SigningTime>" + opts.signingTime + "</xades:SigningTime>"
+	}
+
+	certDigest := sha256.Sum256(s.cert.Raw)
+
+	return `<ds:Signature Id="` + testSigID + `">` +
+		`<ds:SignedInfo>` +
+		`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"/>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>` +
+		refs.String() +
+		`</ds:SignedInfo>` +
+		`<ds:SignatureValue>` + signatureValue + `</ds:SignatureValue>` +
+		`<ds:KeyInfo Id="` + testKeyInfoID + `"><ds:X509Data><ds:X509Certificate>` +
+		base64.StdEncoding.EncodeToString(s.cert.Raw) +
+		`</ds:X509Certificate></ds:X509Data></ds:KeyInfo>` +
+		`<ds:Object><xades:QualifyingProperties Target="#` + testSigID + `">` +
+		`<xades:SignedProperties Id="` + testSignedPropsID + `"><xades:SignedSignatureProperties>` +
+		signingTime +
+		`<xades:SigningCertificate><xades:Cert><xades:CertDigest>` +
+		`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(certDigest[:]) + `</ds:DigestValue>` +
+		`</xades:CertDigest></xades:Cert></xades:SigningCertificate>` +
+		`<xades:SignaturePolicyIdentifier><xades:SignaturePolicyId><xades:SigPolicyId>` +
+		`<xades:Identifier>` + testPolicy + `</xades:Identifier>` +
+		`</xades:SigPolicyId></xades:SignaturePolicyId></xades:SignaturePolicyIdentifier>` +
+		`</xades:SignedSignatureProperties></xades:SignedProperties>` +
+		`</xades:QualifyingProperties></ds:Object>` +
+		`</ds:Signature>`
+}
+
+func TestVerifyValidSignature(t *testing.T) {
+	doc := newTestSigner(t).sign(t, signOptions{})
+
+	result, err := Verify(mustParse(t, doc))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Valid() {
+		t.Fatalf("expected valid signature, errors: %v", result.Errors)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+	if len(result.References) != 3 || !result.DocumentCovered || !result.SignedPropertiesCovered {
+		t.Errorf("references = %+v, document covered %v, signed properties covered %v",
+			result.References, result.DocumentCovered, result.SignedPropertiesCovered)
+	}
+	if result.PolicyIdentifier != testPolicy {
+		t.Errorf("PolicyIdentifier = %q", result.PolicyIdentifier)
+	}
+	if result.SigningTime == nil || result.SigningTime.Format(time.RFC3339) != testSigningTime {
+		t.Errorf("SigningTime = %v", result.SigningTime)
+	}
+	if result.Certificate == nil || result.Certificate.Subject.CommonName != "EMPRESA DE PRUEBAS SAS" {
+		t.Errorf("Certificate = %v", result.Certificate)
+	}
+}
+
+func TestVerifyRejectsTamperedOrIncompleteSignatures(t *testing.T) {
+	s := newTestSigner(t)
+	valid := s.sign(t, signOptions{})
+
+	tests := []struct {
+		name      string
+		doc       string
+		wantError string
+		check     func(t *testing.T, r *Result)
+	}{
+		{
+			name:      "invoice amount modified after signing",
+			doc:       strings.Replace(valid, ">1190.00<", ">9190.00<", 1),
+			wantError: `reference "": digest mismatch`,
+			check: func(t *testing.T, r *Result) {
+				if r.DigestsValid || r.DocumentCovered {
+					t.Errorf("DigestsValid = %v, DocumentCovered = %v; want false, false", r.DigestsValid, r.DocumentCovered)
+				}
+				if !r.SignatureValid {
+					t.Error("SignedInfo was not modified, SignatureValue should still verify")
+				}
+			},
+		},
+		{
+			name:      "signing time modified after signing",
+			doc:       strings.Replace(valid, testSigningTime, "2026-02-06T10:00:00-05:00", 1),
+			wantError: "digest mismatch",
+			check: func(t *testing.T, r *Result) {
+				if r.SignedPropertiesCovered {
+					t.Error("SignedProperties with a bad digest must not count as covered")
+				}
+			},
+		},
+		{
+			name:      "digest value modified in SignedInfo",
+			doc:       strings.Replace(valid, "<ds:DigestValue>", "<ds:DigestValue>AA", 1),
+			wantError: "SignatureValue does not match",
+		},
+		{
+			name:      "signature covers only KeyInfo and SignedProperties",
+			doc:       s.sign(t, signOptions{skipDocumentReference: true}),
+			wantError: "no reference covering the whole document",
+			check: func(t *testing.T, r *Result) {
+				if !r.SignatureValid || !r.DigestsValid || r.DocumentCovered {
+					t.Errorf("SignatureValid = %v, DigestsValid = %v, DocumentCovered = %v; want true, true, false",
+						r.SignatureValid, r.DigestsValid, r.DocumentCovered)
+				}
+			},
+		},
+		{
+			name:      "missing signing time",
+			doc:       s.sign(t, signOptions{signingTime: "-"}),
+			wantError: "SigningTime is missing",
+			check: func(t *testing.T, r *Result) {
+				if r.CertificateValidAtSigning != nil {
+					t.Errorf("CertificateValidAtSigning = %v, want nil", *r.CertificateValidAtSigning)
+				}
+			},
+		},
+		{
+			name:      "unparseable signing time",
+			doc:       s.sign(t, signOptions{signingTime: "05/02/2026"}),
+			wantError: "invalid SigningTime",
+		},
+		{
+			name:      "signed after certificate expiry",
+			doc:       s.sign(t, signOptions{signingTime: "2031-01-01T00:00:00-05:00"}),
+			wantError: "certificate was not valid at signing time",
+			check: func(t *testing.T, r *Result) {
+				if r.CertificateValidAtSigning == nil || *r.CertificateValidAtSigning {
+					t.Error("CertificateValidAtSigning should be false")
+				}
+			},
+		},
+		{
+			// Signature wrapping: una copia de SignedProperties con el mismo Id fuera del área firmada
+			name: "duplicate SignedProperties Id",
+			doc: strings.Replace(valid, "<ds:Object>",
+				`<ds:Object><xades:SignedProperties Id="`+testSignedPropsID+`"><xades:SignedSignatureProperties/></xades:SignedProperties></ds:Object><ds:Object>`, 1),
+			wantError: "referenced Id is not unique",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Verify(mustParse(t, tt.doc))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if result.Valid() {
+				t.Fatal("expected invalid signature")
+			}
+			if !containsError(result.Errors, tt.wantError) {
+				t.Errorf("errors %v do not mention %q", result.Errors, tt.wantError)
+			}
+			if tt.check != nil {
+				tt.check(t, result)
+			}
+		})
+	}
+}
+
+func TestVerifySignaturePlacement(t *testing.T) {
+	s := newTestSigner(t)
+	valid := s.sign(t, signOptions{})
+	sigStart := strings.Index(valid, "<ds:Signature ")
+	sigEnd := strings.Index(valid, "</ds:Signature>") + len("</ds:Signature>")
+	signature := valid[sigStart:sigEnd]
+
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{
+			name:    "unsigned document",
+			doc:     strings.Replace(testInvoice, testSignaturePlace, "", 1),
+			wantErr: ErrNoSignature.Error(),
+		},
+		{
+			name:    "signature outside UBLExtensions",
+			doc:     s.sign(t, signOptions{outsideExtensions: true}),
+			wantErr: "not a direct child of UBLExtensions",
+		},
+		{
+			name:    "signature nested deeper in ExtensionContent",
+			doc:     strings.Replace(valid, signature, "<sts:Wrapper>"+signature+"</sts:Wrapper>", 1),
+			wantErr: "not a direct child of UBLExtensions",
+		},
+		{
+			name: "two signatures in UBLExtensions",
+			doc: strings.Replace(valid, "</ext:UBLExtensions>",
+				"<ext:UBLExtension><ext:ExtensionContent>"+signature+"</ext:ExtensionContent></ext:UBLExtension></ext:UBLExtensions>", 1),
+			wantErr: "more than one signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(mustParse(t, tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := Verify(mustParse(t, strings.Replace(testInvoice, testSignaturePlace, "", 1))); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("unsigned document error = %v, want ErrNoSignature", err)
+	}
+}
+
+func TestFindByIDRejectsDuplicates(t *testing.T) {
+	root := mustParse(t, `<a><b Id="x"/><c><d ID="x"/></c><e id="y"/></a>`)
+
+	if got := root.FindByID("x"); got != nil {
+		t.Errorf("FindByID(duplicate) = <%s>, want nil", got.Local)
+	}
+	if got := len(root.FindAllByID("x")); got != 2 {
+		t.Errorf("FindAllByID(x) returned %d elements, want 2", got)
+	}
+	if got := root.FindByID("y"); got == nil || got.Local != "e" {
+		t.Errorf("FindByID(y) = %v, want <e>", got)
+	}
+	if got := root.FindByID("missing"); got != nil {
+		t.Errorf("FindByID(missing) = <%s>, want nil", got.Local)
+	}
+}
+
+func mustParse(t *testing.T, doc string) *Element {
+	t.Helper()
+	root, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return root
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func containsError(errs []string, substr string) bool {
+	for _, e := range errs {
+		if strings.Contains(e, substr) {
+			return true
+		}
+	}
+	return false
+}